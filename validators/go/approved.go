@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// approvedModelsTTL is how long a fetched registry of approved models is
+// used before it is fetched again
+const approvedModelsTTL = time.Hour
+
+// approvedModelsTimeout limits fetching a registry of approved models
+const approvedModelsTimeout = 10 * time.Second
+
+// approvedModelsSizeLimit is the largest registry of approved models
+// fetched, in bytes
+const approvedModelsSizeLimit = 1 << 20
+
+// ApprovedModels is an organization's allowlist of the models
+// specifications may declare, identified by provider and name
+type ApprovedModels struct {
+	// Source is the URL or file the list was loaded from
+	Source string          `yaml:"-"`
+	Models []ApprovedModel `yaml:"models"`
+}
+
+// ApprovedModel is an entry of the allowlist
+type ApprovedModel struct {
+	Provider string `yaml:"provider"`
+	Name     string `yaml:"name"`
+}
+
+// LoadApprovedModels loads the approved models from a local YAML or JSON
+// file, or from an http(s) URL. Fetched lists are cached in the user cache
+// directory for approvedModelsTTL; a stale copy is used when fetching
+// fails. With remote false, only the cached copy of a URL is used.
+func LoadApprovedModels(source string, remote bool) (*ApprovedModels, error) {
+	return LoadApprovedModelsContext(context.Background(), source, remote)
+}
+
+// LoadApprovedModelsContext loads the approved models like
+// LoadApprovedModels, abandoning a fetch with the context's error once ctx
+// is done rather than falling back to a stale cached copy
+func LoadApprovedModelsContext(ctx context.Context, source string, remote bool) (*ApprovedModels, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		content, err := ioutil.ReadFile(source)
+		if err != nil {
+			return nil, fmt.Errorf("approved models not found: %s", source)
+		}
+		return parseApprovedModels(content, source)
+	}
+
+	cachePath, cacheErr := approvedModelsCachePath(source)
+	var cached []byte
+	fresh := false
+	if cacheErr == nil {
+		if info, err := os.Stat(cachePath); err == nil {
+			cached, _ = ioutil.ReadFile(cachePath)
+			fresh = time.Since(info.ModTime()) < approvedModelsTTL
+		}
+	}
+	if !remote || fresh {
+		if cached == nil {
+			return nil, fmt.Errorf("no cached copy of approved models %s (fetch it once without --no-remote)", source)
+		}
+		return parseApprovedModels(cached, source)
+	}
+
+	content, err := fetchApprovedModels(ctx, source)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("cannot fetch approved models %s: %w", source, ctx.Err())
+		}
+		if cached != nil {
+			return parseApprovedModels(cached, source)
+		}
+		return nil, fmt.Errorf("cannot fetch approved models %s: %v", source, err)
+	}
+	models, err := parseApprovedModels(content, source)
+	if err != nil {
+		return nil, err
+	}
+	if cacheErr == nil && os.MkdirAll(filepath.Dir(cachePath), 0755) == nil {
+		ioutil.WriteFile(cachePath, content, 0644)
+	}
+	return models, nil
+}
+
+// approvedModelsCachePath returns the file caching the list fetched from a URL
+func approvedModelsCachePath(source string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(source))
+	return filepath.Join(dir, "apai", "approved-models", hex.EncodeToString(sum[:])+".yaml"), nil
+}
+
+// fetchApprovedModels downloads a list of approved models
+func fetchApprovedModels(ctx context.Context, source string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, approvedModelsTimeout)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode >= 400 {
+		return nil, fmt.Errorf("HTTP %d", response.StatusCode)
+	}
+	content, err := ioutil.ReadAll(io.LimitReader(response.Body, approvedModelsSizeLimit+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(content) > approvedModelsSizeLimit {
+		return nil, fmt.Errorf("response exceeds %d bytes", approvedModelsSizeLimit)
+	}
+	return content, nil
+}
+
+// parseApprovedModels decodes a list of approved models, YAML or JSON
+func parseApprovedModels(content []byte, source string) (*ApprovedModels, error) {
+	models := &ApprovedModels{}
+	if err := yaml.Unmarshal(content, models); err != nil {
+		return nil, fmt.Errorf("invalid approved models %s: %v", source, err)
+	}
+	for i, model := range models.Models {
+		if model.Provider == "" || model.Name == "" {
+			return nil, fmt.Errorf("invalid approved models %s: entry %d needs a provider and a name", source, i)
+		}
+	}
+	models.Source = source
+	return models, nil
+}
+
+// Approves reports whether a model is on the list, comparing providers and
+// names ignoring case
+func (a *ApprovedModels) Approves(provider, name string) bool {
+	for _, model := range a.Models {
+		if strings.EqualFold(model.Provider, provider) && strings.EqualFold(model.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateApprovedModels reports the models of a specification that are
+// not on the configured list of approved models
+func (v *APAIValidator) validateApprovedModels(spec map[string]interface{}) {
+	if v.approvedModels == nil {
+		return
+	}
+	models, _ := spec["models"].([]interface{})
+	for index, model := range models {
+		modelMap, ok := model.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		provider, _ := modelMap["provider"].(string)
+		name, _ := modelMap["name"].(string)
+		if provider == "" || name == "" || v.approvedModels.Approves(provider, name) {
+			continue
+		}
+		v.addError("UNAPPROVED_MODEL", fmt.Sprintf("Model %s uses %s/%s, which is not in the approved models of %s", elementName(index, modelMap), provider, name, v.approvedModels.Source))
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestApprovedModels(t *testing.T) {
+	approved, err := LoadApprovedModels("testdata/approved-models.yaml", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec := map[string]interface{}{"models": []interface{}{
+		map[string]interface{}{"id": "main_model", "type": "LLM", "provider": "OpenAI", "name": "GPT-4o", "purpose": "support"},
+		map[string]interface{}{"id": "backup", "type": "LLM", "provider": "google", "name": "gemini-pro", "purpose": "support"},
+	}}
+
+	validator := NewAPAIValidator(WithApprovedModels(approved))
+	validator.ValidateSpec(spec)
+	want := "Model backup uses google/gemini-pro, which is not in the approved models of testdata/approved-models.yaml"
+	if !containsString(validator.Errors, want) {
+		t.Errorf("missing %q in %v", want, validator.Errors)
+	}
+	if rule, _ := MatchRule(want); rule.Code != "UNAPPROVED_MODEL" {
+		t.Errorf("expected UNAPPROVED_MODEL, got %q", rule.Code)
+	}
+	for _, message := range validator.Errors {
+		if strings.Contains(message, "main_model") {
+			t.Errorf("unexpected %q", message)
+		}
+	}
+
+	if _, err := parseApprovedModels([]byte("models:\n  - provider: openai\n"), "list.yaml"); err == nil || !strings.Contains(err.Error(), "entry 0 needs a provider and a name") {
+		t.Errorf("expected an invalid entry error, got %v", err)
+	}
+}
+
+func TestApprovedModelsRemoteCache(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprint(w, `{"models": [{"provider": "openai", "name": "gpt-4o"}]}`)
+	}))
+	url := server.URL + "/approved.json"
+
+	if _, err := LoadApprovedModels(url, false); err == nil || !strings.Contains(err.Error(), "no cached copy") {
+		t.Errorf("expected a missing cache error with --no-remote, got %v", err)
+	}
+
+	approved, err := LoadApprovedModels(url, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !approved.Approves("openai", "gpt-4o") || approved.Approves("openai", "gpt-4") {
+		t.Errorf("unexpected list: %+v", approved.Models)
+	}
+
+	// The cached copy is fresh, and is the only one used offline
+	if _, err := LoadApprovedModels(url, true); err != nil || requests != 1 {
+		t.Errorf("expected the cached copy, got %v after %d requests", err, requests)
+	}
+	server.Close()
+	if approved, err := LoadApprovedModels(url, false); err != nil || !approved.Approves("openai", "gpt-4o") {
+		t.Errorf("expected the cached copy with --no-remote, got %v", err)
+	}
+}
+
+func TestApprovedModelsFetchLimits(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow.json" {
+			<-r.Context().Done()
+			return
+		}
+		fmt.Fprintf(w, "models: []\n# %s\n", strings.Repeat("x", approvedModelsSizeLimit))
+	}))
+	defer server.Close()
+
+	if _, err := LoadApprovedModels(server.URL+"/large.yaml", true); err == nil || !strings.Contains(err.Error(), "response exceeds") {
+		t.Errorf("expected a size limit error, got %v", err)
+	}
+
+	// A cancelled fetch ends with the context's error, well before the timeout
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	if _, err := LoadApprovedModelsContext(ctx, server.URL+"/slow.json", true); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected a cancelled fetch, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= approvedModelsTimeout {
+		t.Errorf("cancelled fetch took %s", elapsed)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+)
+
+// BaselineEntry identifies a finding accepted by a baseline
+type BaselineEntry struct {
+	File     string `json:"file"`
+	Severity string `json:"severity"`
+	Code     string `json:"code,omitempty"`
+	Message  string `json:"message"`
+}
+
+// Baseline records existing findings so that later runs report only new
+// ones, which lets new rules be adopted incrementally on existing specs
+type Baseline struct {
+	Entries []BaselineEntry `json:"entries"`
+}
+
+// NewBaseline returns an empty baseline
+func NewBaseline() *Baseline {
+	return &Baseline{Entries: make([]BaselineEntry, 0)}
+}
+
+// LoadBaseline reads a baseline written by Write
+func LoadBaseline(filePath string) (*Baseline, error) {
+	content, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("baseline not found: %s", filePath)
+	}
+
+	baseline := NewBaseline()
+	if err := json.Unmarshal(content, baseline); err != nil {
+		return nil, fmt.Errorf("invalid baseline %s: %v", filePath, err)
+	}
+	return baseline, nil
+}
+
+// Write saves the baseline as JSON, sorted so that it diffs cleanly
+func (b *Baseline) Write(filePath string) error {
+	sort.SliceStable(b.Entries, func(i, j int) bool {
+		if b.Entries[i].File != b.Entries[j].File {
+			return b.Entries[i].File < b.Entries[j].File
+		}
+		return b.Entries[i].Message < b.Entries[j].Message
+	})
+
+	content, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filePath, append(content, '\n'), 0644)
+}
+
+// Add records every finding of a file's validation result
+func (b *Baseline) Add(file string, result ValidationResult) {
+	for _, message := range result.Errors {
+		b.Entries = append(b.Entries, newBaselineEntry(file, result.issue("error", message)))
+	}
+	for _, message := range result.Warnings {
+		b.Entries = append(b.Entries, newBaselineEntry(file, result.issue("warning", message)))
+	}
+}
+
+// Contains reports whether a finding of a file is in the baseline
+func (b *Baseline) Contains(file string, issue Issue) bool {
+	entry := newBaselineEntry(file, issue)
+	for _, existing := range b.Entries {
+		if existing == entry {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter returns a file's validation result without the baselined findings
+// and the number of findings it suppressed
+func (b *Baseline) Filter(file string, result ValidationResult) (ValidationResult, int) {
+	filtered := ValidationResult{Errors: make([]string, 0), Warnings: make([]string, 0), codes: result.codes}
+	for _, message := range result.Errors {
+		if !b.Contains(file, result.issue("error", message)) {
+			filtered.Errors = append(filtered.Errors, message)
+		}
+	}
+	for _, message := range result.Warnings {
+		if !b.Contains(file, result.issue("warning", message)) {
+			filtered.Warnings = append(filtered.Warnings, message)
+		}
+	}
+	filtered.Valid = len(filtered.Errors) == 0
+
+	suppressed := len(result.Errors) + len(result.Warnings) - len(filtered.Errors) - len(filtered.Warnings)
+	return filtered, suppressed
+}
+
+// newBaselineEntry identifies a finding by file, severity, code and message,
+// which includes the location within the specification
+func newBaselineEntry(file string, issue Issue) BaselineEntry {
+	return BaselineEntry{
+		File:     filepath.ToSlash(filepath.Clean(file)),
+		Severity: issue.Severity,
+		Code:     issue.Code,
+		Message:  issue.Message,
+	}
+}
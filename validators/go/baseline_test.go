@@ -0,0 +1,44 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestBaselineSuppressesRecordedFindings(t *testing.T) {
+	recorded := ValidationResult{
+		Errors:   []string{`models[0].parameters.temperature must be a number, got string "0.3" (remove the quotes)`},
+		Warnings: []string{"context.memory is recommended"},
+	}
+	baseline := NewBaseline()
+	baseline.Add("specs/./app.yaml", recorded)
+
+	baselinePath := filepath.Join(t.TempDir(), "baseline.json")
+	if err := baseline.Write(baselinePath); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	loaded, err := LoadBaseline(baselinePath)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if len(loaded.Entries) != 2 || loaded.Entries[1].Code != "NUMERIC_STRING" || loaded.Entries[1].File != "specs/app.yaml" {
+		t.Errorf("unexpected entries: %+v", loaded.Entries)
+	}
+
+	current := ValidationResult{
+		Errors:   append([]string{"Missing required section: evaluation"}, recorded.Errors...),
+		Warnings: recorded.Warnings,
+	}
+	filtered, suppressed := loaded.Filter("specs/app.yaml", current)
+	if suppressed != 2 {
+		t.Errorf("expected 2 suppressed findings, got %d", suppressed)
+	}
+	if !reflect.DeepEqual(filtered.Errors, []string{"Missing required section: evaluation"}) || len(filtered.Warnings) != 0 || filtered.Valid {
+		t.Errorf("unexpected filtered result: %+v", filtered)
+	}
+
+	if _, suppressed := loaded.Filter("specs/other.yaml", current); suppressed != 0 {
+		t.Errorf("baseline applied to another file: %d suppressed", suppressed)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+// SpecBuilder constructs a typed specification step by step
+type SpecBuilder struct {
+	spec Spec
+}
+
+// NewSpec starts building a specification for the given APAI version
+func NewSpec(version string) *SpecBuilder {
+	return &SpecBuilder{spec: Spec{APAI: version}}
+}
+
+// Info sets the system metadata
+func (b *SpecBuilder) Info(info Info) *SpecBuilder {
+	b.spec.Info = &info
+	return b
+}
+
+// Inherits adds parent specifications to inherit from
+func (b *SpecBuilder) Inherits(paths ...string) *SpecBuilder {
+	b.spec.Inherits = append(b.spec.Inherits, paths...)
+	return b
+}
+
+// AddModel appends a model
+func (b *SpecBuilder) AddModel(model Model) *SpecBuilder {
+	b.spec.Models = append(b.spec.Models, model)
+	return b
+}
+
+// AddPrompt appends a prompt
+func (b *SpecBuilder) AddPrompt(prompt Prompt) *SpecBuilder {
+	b.spec.Prompts = append(b.spec.Prompts, prompt)
+	return b
+}
+
+// AddConstraint appends a constraint
+func (b *SpecBuilder) AddConstraint(constraint Constraint) *SpecBuilder {
+	b.spec.Constraints = append(b.spec.Constraints, constraint)
+	return b
+}
+
+// AddTask appends a task
+func (b *SpecBuilder) AddTask(task Task) *SpecBuilder {
+	b.spec.Tasks = append(b.spec.Tasks, task)
+	return b
+}
+
+// Context sets the context section
+func (b *SpecBuilder) Context(context Context) *SpecBuilder {
+	b.spec.Context = &context
+	return b
+}
+
+// AddMCPServer appends an MCP server to the context section
+func (b *SpecBuilder) AddMCPServer(server MCPServer) *SpecBuilder {
+	if b.spec.Context == nil {
+		b.spec.Context = &Context{}
+	}
+	b.spec.Context.MCPServers = append(b.spec.Context.MCPServers, server)
+	return b
+}
+
+// Evaluation sets the evaluation section
+func (b *SpecBuilder) Evaluation(evaluation Evaluation) *SpecBuilder {
+	b.spec.Evaluation = &evaluation
+	return b
+}
+
+// AddMetric appends an evaluation metric
+func (b *SpecBuilder) AddMetric(metric Metric) *SpecBuilder {
+	if b.spec.Evaluation == nil {
+		b.spec.Evaluation = &Evaluation{}
+	}
+	b.spec.Evaluation.Metrics = append(b.spec.Evaluation.Metrics, metric)
+	return b
+}
+
+// Build returns the specification, defaulting context.memory to an empty
+// object and evaluation to an empty stub when they were not set
+func (b *SpecBuilder) Build() *Spec {
+	spec := b.spec
+
+	context := Context{}
+	if spec.Context != nil {
+		context = *spec.Context
+	}
+	if context.Memory == nil {
+		context.Memory = Object{}
+	}
+	spec.Context = &context
+
+	if spec.Evaluation == nil {
+		spec.Evaluation = &Evaluation{}
+	}
+
+	return &spec
+}
+
+// BuildAndValidate builds the specification and validates it
+func (b *SpecBuilder) BuildAndValidate(opts ...Option) (*Spec, ValidationResult, error) {
+	spec := b.Build()
+	specMap, err := spec.ToMap()
+	if err != nil {
+		return nil, ValidationResult{}, err
+	}
+
+	validator := NewAPAIValidator(opts...)
+	validator.ValidateSpec(specMap)
+	return spec, validator.GetResults(), nil
+}
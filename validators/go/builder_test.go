@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func minimalBuilder() *SpecBuilder {
+	return NewSpec("0.1.0").
+		Info(Info{Title: "Support Bot", Version: "1.0.0", Description: "Answers questions", Author: "AI Team", License: "MIT"}).
+		AddModel(Model{ID: "main_model", Type: "LLM", Provider: "openai", Name: "gpt-4", Purpose: "conversation"}).
+		AddPrompt(Prompt{ID: "system_prompt", Role: "system", Template: "You are a helpful assistant"}).
+		AddConstraint(Constraint{ID: "safety", Rule: "output NOT contains harmful_content", Severity: "critical"}).
+		AddTask(Task{ID: "handle_query", Description: "Process user queries"})
+}
+
+func TestBuilderProducesValidSpec(t *testing.T) {
+	spec, result, err := minimalBuilder().BuildAndValidate()
+	if err != nil {
+		t.Fatalf("build failed: %v", err)
+	}
+	if !result.Valid {
+		t.Fatalf("expected valid spec, got errors: %v", result.Errors)
+	}
+	if spec.Context == nil || spec.Context.Memory == nil {
+		t.Error("context.memory default not applied")
+	}
+	if spec.Evaluation == nil {
+		t.Error("evaluation default not applied")
+	}
+}
+
+func TestBuilderWritesCanonicalYAML(t *testing.T) {
+	var buf bytes.Buffer
+	if err := minimalBuilder().Build().WriteYAML(&buf); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	output := buf.String()
+	if !strings.HasPrefix(output, "apai: 0.1.0\ninfo:\n") {
+		t.Errorf("unexpected section order:\n%s", output)
+	}
+	if !strings.Contains(output, "memory: {}") {
+		t.Errorf("empty context.memory not written:\n%s", output)
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+// BundleResult represents the validation result of one root specification in a bundle
+type BundleResult struct {
+	Root string `json:"root"`
+	ValidationResult
+}
+
+// isBundle reports whether a file is a zip bundle of specifications
+func isBundle(filePath string) bool {
+	return strings.ToLower(path.Ext(filePath)) == ".zip"
+}
+
+// ValidateBundle validates the root specifications of a zip archive,
+// resolving inherits against the archive's internal paths. When root is
+// empty, every specification not inherited by another entry is validated.
+func (v *APAIValidator) ValidateBundle(bundlePath, root string) ([]BundleResult, error) {
+	return v.ValidateBundleContext(context.Background(), bundlePath, root)
+}
+
+// ValidateBundleContext validates a zip bundle like ValidateBundle,
+// stopping with the context's error, wrapped, once ctx is done
+func (v *APAIValidator) ValidateBundleContext(ctx context.Context, bundlePath, root string) ([]BundleResult, error) {
+	reader, err := zip.OpenReader(bundlePath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open bundle %s: %v", bundlePath, err)
+	}
+	defer reader.Close()
+
+	// Archive paths must not share caches with files read before
+	previous := v.fsys
+	v.fsys = reader
+	v.inheritedSpecs = make(map[string]map[string]interface{})
+	v.mergeCache = make(map[string]map[string]interface{})
+	defer func() {
+		v.fsys = previous
+		v.inheritedSpecs = make(map[string]map[string]interface{})
+		v.mergeCache = make(map[string]map[string]interface{})
+	}()
+
+	entries, err := v.bundleEntries(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	roots := make([]string, 0)
+	if root != "" {
+		root = path.Clean(strings.TrimPrefix(root, "/"))
+		if !containsString(entries, root) {
+			return nil, fmt.Errorf("root %s not found in bundle %s", root, bundlePath)
+		}
+		roots = append(roots, root)
+	} else {
+		roots = v.bundleRoots(entries)
+		if len(roots) == 0 {
+			return nil, fmt.Errorf("no specifications found in bundle %s", bundlePath)
+		}
+	}
+
+	results := make([]BundleResult, 0, len(roots))
+	for _, entry := range roots {
+		if _, err := v.ValidateWithInheritanceContext(ctx, entry); err != nil {
+			return nil, err
+		}
+		results = append(results, BundleResult{Root: entry, ValidationResult: v.GetResults()})
+	}
+	return results, nil
+}
+
+// bundleEntries lists the specification files in the archive, sorted by path
+func (v *APAIValidator) bundleEntries(ctx context.Context) ([]string, error) {
+	entries := make([]string, 0)
+	err := fs.WalkDir(v.fsys, ".", func(entryPath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		switch strings.ToLower(path.Ext(entryPath)) {
+		case ".yaml", ".yml", ".json":
+			entries = append(entries, entryPath)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot read bundle: %w", err)
+	}
+
+	sort.Strings(entries)
+	return entries, nil
+}
+
+// bundleRoots returns the entries that no other entry inherits from
+func (v *APAIValidator) bundleRoots(entries []string) []string {
+	inherited := make(map[string]bool)
+	for _, entry := range entries {
+		spec, err := v.loadSpec(entry)
+		if err != nil {
+			continue
+		}
+		inheritsSlice, _ := spec["inherits"].([]interface{})
+		for _, inheritPath := range inheritsSlice {
+			if inheritPathStr, ok := inheritPath.(string); ok {
+				if resolvedPath, err := v.resolveInheritancePath(inheritPathStr, entry); err == nil {
+					inherited[resolvedPath] = true
+				}
+			}
+		}
+	}
+
+	roots := make([]string, 0)
+	for _, entry := range entries {
+		if !inherited[entry] {
+			roots = append(roots, entry)
+		}
+	}
+	return roots
+}
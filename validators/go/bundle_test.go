@@ -0,0 +1,89 @@
+package main
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeBundle writes a zip archive holding the given entries
+func writeBundle(t *testing.T, entries map[string][]byte) string {
+	t.Helper()
+	bundlePath := filepath.Join(t.TempDir(), "bundle.zip")
+	file, err := os.Create(bundlePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer := zip.NewWriter(file)
+	for name, content := range entries {
+		entry, err := writer.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := entry.Write(content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := file.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return bundlePath
+}
+
+func TestValidateBundle(t *testing.T) {
+	base, err := embeddedSpecs.ReadFile("testdata/embedded/org/base.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	app, err := embeddedSpecs.ReadFile("testdata/embedded/team/app.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundlePath := writeBundle(t, map[string][]byte{
+		"org/base.yaml":   base,
+		"team/app.yaml":   app,
+		"team/broken.yml": []byte("apai: \"0.1.0\"\ninherits: [\"../org/missing.yaml\"]\n"),
+		"README.md":       []byte("# Specs\n"),
+	})
+
+	validator := NewAPAIValidator()
+	results, err := validator.ValidateBundle(bundlePath, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := make([]string, 0, len(results))
+	for _, result := range results {
+		roots = append(roots, result.Root)
+	}
+	if strings.Join(roots, ",") != "team/app.yaml,team/broken.yml" {
+		t.Fatalf("expected the entries no other entry inherits as roots, got %v", roots)
+	}
+	if !results[0].Valid {
+		t.Errorf("expected team/app.yaml, inheriting an archive entry, to be valid: %v", results[0].Errors)
+	}
+	if results[1].Valid || !containsString(results[1].Errors, "Inherited specification not found: ../org/missing.yaml") {
+		t.Errorf("expected the missing parent of team/broken.yml to be reported: %v", results[1].Errors)
+	}
+
+	results, err = validator.ValidateBundle(bundlePath, "/team/app.yaml")
+	if err != nil || len(results) != 1 || results[0].Root != "team/app.yaml" {
+		t.Errorf("expected --root to select team/app.yaml, got %v %v", results, err)
+	}
+
+	if _, err := validator.ValidateBundle(bundlePath, "team/other.yaml"); err == nil || !strings.Contains(err.Error(), "root team/other.yaml not found in bundle") {
+		t.Errorf("expected an unknown root to be an error, got %v", err)
+	}
+	if _, err := validator.ValidateBundle(filepath.Join(t.TempDir(), "missing.zip"), ""); err == nil {
+		t.Error("expected a missing bundle to be an error")
+	}
+
+	// Archive paths are not resolved once the bundle is closed
+	if validator.fsys != nil || len(validator.inheritedSpecs) != 0 {
+		t.Errorf("bundle filesystem or parents left behind: %v %d", validator.fsys, len(validator.inheritedSpecs))
+	}
+}
@@ -0,0 +1,389 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// specCacheFormat changes whenever the layout of cache entries does, so
+// that entries written by other validators are never read
+const specCacheFormat = 1
+
+// The kinds of cache entries, each kept in a subdirectory of the cache:
+// specifications as parsed from a file, and inherited specifications merged
+// with their own parents
+const (
+	parsedCacheKind = "parsed"
+	mergedCacheKind = "merged"
+)
+
+var specCacheKinds = []string{parsedCacheKind, mergedCacheKind}
+
+// SpecCache keeps parsed and merged specifications in a directory, so that
+// repeated runs skip parsing and merging the files that did not change.
+// Entries are keyed by the content of the files they come from and by the
+// validator version; entries that cannot be read are parsed again.
+type SpecCache struct {
+	dir     string
+	version string
+
+	// lookups counts the loads of each kind of entry
+	lookups map[string]*cacheLookups
+}
+
+// cacheLookups counts the loads of a kind of entry that found one and
+// that did not
+type cacheLookups struct {
+	hits, misses atomic.Uint64
+}
+
+// CacheStats counts the entries of a cache by kind, parsed or merged
+type CacheStats struct {
+	Dir     string         `json:"dir"`
+	Entries map[string]int `json:"entries"`
+	Bytes   int64          `json:"bytes"`
+}
+
+// DefaultCacheDir returns the directory of the cache when none is
+// configured, under the user cache directory
+func DefaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("no user cache directory: %v", err)
+	}
+	return filepath.Join(dir, "apai", "specs"), nil
+}
+
+// NewSpecCache returns the cache kept in dir, or in DefaultCacheDir when dir
+// is empty. The directory is created when the first entry is stored.
+func NewSpecCache(dir string) (*SpecCache, error) {
+	if dir == "" {
+		var err error
+		if dir, err = DefaultCacheDir(); err != nil {
+			return nil, err
+		}
+	}
+	lookups := make(map[string]*cacheLookups, len(specCacheKinds))
+	for _, kind := range specCacheKinds {
+		lookups[kind] = &cacheLookups{}
+	}
+	return &SpecCache{dir: dir, version: validatorVersion(), lookups: lookups}, nil
+}
+
+// Dir returns the directory the cache is kept in
+func (c *SpecCache) Dir() string {
+	return c.dir
+}
+
+var (
+	validatorVersionOnce  sync.Once
+	validatorVersionValue string
+)
+
+// validatorVersion identifies the running validator in cache keys: the
+// module version or VCS revision it was built from, or a digest of the
+// executable for builds without either, such as go run
+func validatorVersion() string {
+	validatorVersionOnce.Do(func() {
+		if info, ok := debug.ReadBuildInfo(); ok {
+			revision, modified := "", ""
+			for _, setting := range info.Settings {
+				switch setting.Key {
+				case "vcs.revision":
+					revision = setting.Value
+				case "vcs.modified":
+					modified = setting.Value
+				}
+			}
+			if revision != "" && modified != "true" {
+				validatorVersionValue = revision
+				return
+			}
+			if info.Main.Version != "" && info.Main.Version != "(devel)" {
+				validatorVersionValue = info.Main.Version
+				return
+			}
+		}
+		validatorVersionValue = "unknown"
+		if executable, err := os.Executable(); err == nil {
+			if file, err := os.Open(executable); err == nil {
+				hash := sha256.New()
+				if _, err := io.Copy(hash, file); err == nil {
+					validatorVersionValue = hex.EncodeToString(hash.Sum(nil))
+				}
+				file.Close()
+			}
+		}
+	})
+	return validatorVersionValue
+}
+
+// key returns the key of an entry of a kind from the parts identifying it
+func (c *SpecCache) key(kind string, parts ...string) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%d\x00%s\x00%s", specCacheFormat, c.version, kind)
+	for _, part := range parts {
+		hash.Write([]byte{0})
+		hash.Write([]byte(part))
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// path returns the file of an entry
+func (c *SpecCache) path(kind, key string) string {
+	return filepath.Join(c.dir, kind, key[:2], key+".json")
+}
+
+// load returns the specification stored under key, or false when there is
+// none. Entries that do not decode are removed.
+func (c *SpecCache) load(kind, key string) (map[string]interface{}, bool) {
+	spec, ok := c.read(kind, key)
+	if lookups := c.lookups[kind]; lookups != nil {
+		if ok {
+			lookups.hits.Add(1)
+		} else {
+			lookups.misses.Add(1)
+		}
+	}
+	return spec, ok
+}
+
+// read returns the specification stored under key, removing entries that
+// do not decode
+func (c *SpecCache) read(kind, key string) (map[string]interface{}, bool) {
+	path := c.path(kind, key)
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	spec, ok := decodeCacheEntry(content)
+	if !ok {
+		os.Remove(path)
+		return nil, false
+	}
+	return spec, true
+}
+
+// Lookups returns how many loads of a kind of entry, parsed or merged,
+// found one and how many did not since the cache was opened
+func (c *SpecCache) Lookups(kind string) (hits, misses uint64) {
+	lookups := c.lookups[kind]
+	if lookups == nil {
+		return 0, 0
+	}
+	return lookups.hits.Load(), lookups.misses.Load()
+}
+
+// store writes a specification under key through a temporary file, so that
+// concurrent runs never read a partial entry. Specifications holding values
+// a cache entry cannot, and directories that cannot be written, are left
+// out of the cache.
+func (c *SpecCache) store(kind, key string, spec map[string]interface{}) {
+	content, ok := encodeCacheEntry(spec)
+	if !ok {
+		return
+	}
+	path := c.path(kind, key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return
+	}
+	file, err := ioutil.TempFile(filepath.Dir(path), key+".*.tmp")
+	if err != nil {
+		return
+	}
+	_, err = file.Write(content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), path)
+	}
+	if err != nil {
+		os.Remove(file.Name())
+	}
+}
+
+// documentsKey holds the documents of a multi-document entry
+const documentsKey = "documents"
+
+// loadDocuments returns the documents of a file stored under key with
+// storeDocuments, or false when there are none
+func (c *SpecCache) loadDocuments(key string) ([]map[string]interface{}, bool) {
+	entry, ok := c.load(parsedCacheKind, key)
+	if !ok {
+		return nil, false
+	}
+	items, ok := entry[documentsKey].([]interface{})
+	if !ok {
+		return nil, false
+	}
+	documents := make([]map[string]interface{}, len(items))
+	for i, item := range items {
+		documents[i], _ = item.(map[string]interface{})
+	}
+	return documents, true
+}
+
+// storeDocuments writes the documents of a file under key
+func (c *SpecCache) storeDocuments(key string, documents []map[string]interface{}) {
+	items := make([]interface{}, len(documents))
+	for i, document := range documents {
+		if document != nil {
+			items[i] = document
+		}
+	}
+	c.store(parsedCacheKind, key, map[string]interface{}{documentsKey: items})
+}
+
+// Stats counts the entries of the cache and the bytes they take
+func (c *SpecCache) Stats() (CacheStats, error) {
+	stats := CacheStats{Dir: c.dir, Entries: make(map[string]int)}
+	for _, kind := range specCacheKinds {
+		stats.Entries[kind] = 0
+		err := filepath.Walk(filepath.Join(c.dir, kind), func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() && filepath.Ext(path) == ".json" {
+				stats.Entries[kind]++
+				stats.Bytes += info.Size()
+			}
+			return nil
+		})
+		if err != nil && !os.IsNotExist(err) {
+			return stats, fmt.Errorf("reading cache %s: %v", c.dir, err)
+		}
+	}
+	return stats, nil
+}
+
+// Clean removes every entry of the cache, returning how many there were.
+// Other files of the directory are left alone.
+func (c *SpecCache) Clean() (int, error) {
+	stats, err := c.Stats()
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	for _, kind := range specCacheKinds {
+		if err := os.RemoveAll(filepath.Join(c.dir, kind)); err != nil {
+			return removed, fmt.Errorf("cleaning cache %s: %v", c.dir, err)
+		}
+		removed += stats.Entries[kind]
+	}
+	return removed, nil
+}
+
+// cachedTimeKey marks the objects standing for timestamps in cache entries;
+// YAML keys cannot hold the NUL it starts with
+const cachedTimeKey = "\x00time"
+
+// encodeCacheEntry encodes a decoded YAML or JSON specification as JSON
+// that keeps integers apart from floats, which always carry a decimal
+// point, and timestamps apart from strings. It reports false for values
+// JSON cannot hold, such as NaN or maps with non-string keys.
+func encodeCacheEntry(spec map[string]interface{}) ([]byte, bool) {
+	var encode func(value interface{}) (interface{}, bool)
+	encode = func(value interface{}) (interface{}, bool) {
+		switch typed := value.(type) {
+		case nil, string, bool, int:
+			return typed, true
+		case float64:
+			if math.IsNaN(typed) || math.IsInf(typed, 0) {
+				return nil, false
+			}
+			text := strconv.FormatFloat(typed, 'g', -1, 64)
+			if !strings.ContainsAny(text, ".e") {
+				text += ".0"
+			}
+			return json.Number(text), true
+		case time.Time:
+			return map[string]interface{}{cachedTimeKey: typed.Format(time.RFC3339Nano)}, true
+		case map[string]interface{}:
+			encoded := make(map[string]interface{}, len(typed))
+			for key, item := range typed {
+				converted, ok := encode(item)
+				if !ok {
+					return nil, false
+				}
+				encoded[key] = converted
+			}
+			return encoded, true
+		case []interface{}:
+			encoded := make([]interface{}, len(typed))
+			for i, item := range typed {
+				converted, ok := encode(item)
+				if !ok {
+					return nil, false
+				}
+				encoded[i] = converted
+			}
+			return encoded, true
+		}
+		return nil, false
+	}
+
+	encoded, ok := encode(spec)
+	if !ok {
+		return nil, false
+	}
+	content, err := json.Marshal(encoded)
+	return content, err == nil
+}
+
+// decodeCacheEntry decodes a specification encoded by encodeCacheEntry
+func decodeCacheEntry(content []byte) (map[string]interface{}, bool) {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	var entry interface{}
+	if err := decoder.Decode(&entry); err != nil {
+		return nil, false
+	}
+
+	var decode func(value interface{}) interface{}
+	decode = func(value interface{}) interface{} {
+		switch typed := value.(type) {
+		case json.Number:
+			if !strings.ContainsAny(string(typed), ".eE") {
+				if number, err := strconv.Atoi(string(typed)); err == nil {
+					return number
+				}
+			}
+			number, _ := typed.Float64()
+			return number
+		case map[string]interface{}:
+			if text, ok := typed[cachedTimeKey].(string); ok && len(typed) == 1 {
+				if parsed, err := time.Parse(time.RFC3339Nano, text); err == nil {
+					return parsed
+				}
+			}
+			for key, item := range typed {
+				typed[key] = decode(item)
+			}
+			return typed
+		case []interface{}:
+			for i, item := range typed {
+				typed[i] = decode(item)
+			}
+			return typed
+		}
+		return value
+	}
+
+	spec, ok := decode(entry).(map[string]interface{})
+	return spec, ok
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeHierarchy writes a synthetic hierarchy of specifications to dir:
+// orgs organization bases, each inherited by teams team specs, each
+// inherited by leaves leaf specs. It returns the paths of the leaves.
+func writeHierarchy(tb testing.TB, dir string, orgs, teams, leaves int) []string {
+	tb.Helper()
+	write := func(path, content string) {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			tb.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	prompts := func(prefix string, count int) string {
+		var builder strings.Builder
+		builder.WriteString("prompts:\n")
+		for i := 0; i < count; i++ {
+			fmt.Fprintf(&builder, "  - id: %s_%d\n    role: system\n    template: |\n      You are the %s assistant %d. Answer {{question}} politely,\n      citing the policy of the organization when it applies.\n    variables:\n      question:\n        type: string\n        required: true\n", prefix, i, prefix, i)
+		}
+		return builder.String()
+	}
+
+	paths := make([]string, 0, orgs*teams*leaves)
+	for o := 0; o < orgs; o++ {
+		org := filepath.Join(dir, fmt.Sprintf("org%d", o), "base.yaml")
+		write(org, fmt.Sprintf(`apai: "0.1.0"
+info:
+  title: Org %d
+  version: 1.0.0
+  description: Organization base
+  author: Platform Team
+  license: MIT
+  contact:
+    email: platform@example.com
+models:
+  - id: primary
+    type: LLM
+    provider: OpenAI
+    name: gpt-4o
+    version: "2024-08-06"
+    purpose: Answers
+    parameters:
+      temperature: 0.2
+      max_tokens: 1024
+constraints:
+  - id: privacy
+    name: Privacy
+    rule: no personal data
+    severity: high
+    description: Never disclose personal data
+`, o)+prompts("org", 20))
+		for t := 0; t < teams; t++ {
+			team := filepath.Join(dir, fmt.Sprintf("org%d", o), fmt.Sprintf("team%d", t), "team.yaml")
+			write(team, "inherits:\n  - ../base.yaml\n"+prompts("team", 10))
+			for l := 0; l < leaves; l++ {
+				leaf := filepath.Join(filepath.Dir(team), fmt.Sprintf("app%d.yaml", l))
+				write(leaf, fmt.Sprintf("inherits:\n  - team.yaml\ninfo:\n  title: App %d\n", l)+prompts("app", 3))
+				paths = append(paths, leaf)
+			}
+		}
+	}
+	return paths
+}
+
+// resolveAll resolves every leaf with one validator, as a CI run would
+func resolveAll(tb testing.TB, validator *APAIValidator, leaves []string) []map[string]interface{} {
+	tb.Helper()
+	resolved := make([]map[string]interface{}, 0, len(leaves))
+	for _, leaf := range leaves {
+		spec, err := validator.ResolveSpecContext(context.Background(), leaf)
+		if err != nil || len(validator.Errors) > 0 {
+			tb.Fatalf("resolving %s: %v %v", leaf, err, validator.Errors)
+		}
+		resolved = append(resolved, spec)
+	}
+	return resolved
+}
+
+func TestSpecCacheResolvesLikeParsing(t *testing.T) {
+	leaves := writeHierarchy(t, t.TempDir(), 2, 2, 2)
+	cache, err := NewSpecCache(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := resolveAll(t, NewAPAIValidator(), leaves)
+	cold := resolveAll(t, NewAPAIValidator(WithSpecCache(cache)), leaves)
+	stats, err := cache.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Parsed files are keyed by content: two orgs, the teams and the two
+	// leaves of each team are alike. Merges are keyed by path too.
+	if stats.Entries[parsedCacheKind] != 5 || stats.Entries[mergedCacheKind] != 4 {
+		t.Errorf("expected 5 parsed and 4 merged entries, got %v", stats.Entries)
+	}
+	if hits, misses := cache.Lookups(mergedCacheKind); hits != 0 || misses != 4 {
+		t.Errorf("expected 4 merged misses on a cold cache, got %d hits and %d misses", hits, misses)
+	}
+	warm := resolveAll(t, NewAPAIValidator(WithSpecCache(cache)), leaves)
+	if hits, misses := cache.Lookups(mergedCacheKind); hits != 4 || misses != 4 {
+		t.Errorf("expected 4 merged hits on a warm cache, got %d hits and %d misses", hits, misses)
+	}
+	if !reflect.DeepEqual(cold, want) || !reflect.DeepEqual(warm, want) {
+		t.Error("expected the cache to resolve the same specifications as parsing")
+	}
+
+	removed, err := cache.Clean()
+	if err != nil || removed != 9 {
+		t.Errorf("expected 9 entries removed, got %d (%v)", removed, err)
+	}
+	if stats, _ := cache.Stats(); stats.Entries[parsedCacheKind] != 0 || stats.Bytes != 0 {
+		t.Errorf("expected an empty cache after cleaning, got %+v", stats)
+	}
+}
+
+func TestSpecCacheInvalidation(t *testing.T) {
+	dir := t.TempDir()
+	leaves := writeHierarchy(t, dir, 1, 1, 1)
+	cache, _ := NewSpecCache(t.TempDir())
+	resolveAll(t, NewAPAIValidator(WithSpecCache(cache)), leaves)
+
+	// A changed grandparent is parsed again, and the team merged again
+	org := filepath.Join(dir, "org0", "base.yaml")
+	content, _ := ioutil.ReadFile(org)
+	changed := strings.Replace(string(content), "Organization base", "Renamed base", 1)
+	if err := ioutil.WriteFile(org, []byte(changed), 0644); err != nil {
+		t.Fatal(err)
+	}
+	spec := resolveAll(t, NewAPAIValidator(WithSpecCache(cache)), leaves)[0]
+	if info, _ := spec["info"].(map[string]interface{}); info["description"] != "Renamed base" {
+		t.Errorf("expected the changed description, got %v", spec["info"])
+	}
+	if stats, _ := cache.Stats(); stats.Entries[parsedCacheKind] != 4 || stats.Entries[mergedCacheKind] != 2 {
+		t.Errorf("expected entries for both versions of the org, got %v", stats.Entries)
+	}
+
+	// So is everything once the validator version changes
+	upgraded := *cache
+	upgraded.version = "next"
+	resolveAll(t, NewAPAIValidator(WithSpecCache(&upgraded)), leaves)
+	if stats, _ := cache.Stats(); stats.Entries[parsedCacheKind] != 7 || stats.Entries[mergedCacheKind] != 3 {
+		t.Errorf("expected new entries for the new version, got %v", stats.Entries)
+	}
+}
+
+func TestSpecCacheCorruptionFallsBackToParsing(t *testing.T) {
+	leaves := writeHierarchy(t, t.TempDir(), 1, 1, 1)
+	cacheDir := t.TempDir()
+	cache, _ := NewSpecCache(cacheDir)
+	want := resolveAll(t, NewAPAIValidator(WithSpecCache(cache)), leaves)
+
+	err := filepath.Walk(cacheDir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			err = ioutil.WriteFile(path, []byte("not a cache entry"), 0644)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resolveAll(t, NewAPAIValidator(WithSpecCache(cache)), leaves); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected corrupted entries to be parsed again, got %v", got)
+	}
+	if stats, _ := cache.Stats(); stats.Entries[parsedCacheKind] != 3 || stats.Entries[mergedCacheKind] != 1 {
+		t.Errorf("expected corrupted entries to be rewritten, got %v", stats.Entries)
+	}
+}
+
+func TestCacheEntryRoundTrip(t *testing.T) {
+	spec := map[string]interface{}{
+		"string": "text", "int": 3, "float": 3.0, "small": 1e-7, "bool": true, "null": nil,
+		"time":  time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		"list":  []interface{}{1, "two", []interface{}{}, map[string]interface{}{}},
+		"empty": map[string]interface{}{},
+	}
+	content, ok := encodeCacheEntry(spec)
+	if !ok {
+		t.Fatal("expected the specification to be cacheable")
+	}
+	if got, ok := decodeCacheEntry(content); !ok || !reflect.DeepEqual(got, spec) {
+		t.Errorf("expected %v, got %v", spec, got)
+	}
+	for _, value := range []interface{}{math.NaN(), map[interface{}]interface{}{1: "x"}} {
+		if _, ok := encodeCacheEntry(map[string]interface{}{"value": value}); ok {
+			t.Errorf("expected %v to be left out of the cache", value)
+		}
+	}
+}
+
+// BenchmarkResolveHierarchy resolves the 450 leaves of a 500-file hierarchy
+// with a new validator per iteration, as separate CI runs do, without a
+// cache and with a warm one
+func BenchmarkResolveHierarchy(b *testing.B) {
+	leaves := writeHierarchy(b, b.TempDir(), 5, 9, 10)
+	b.Run("uncached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			resolveAll(b, NewAPAIValidator(), leaves)
+		}
+	})
+	b.Run("warm", func(b *testing.B) {
+		cache, _ := NewSpecCache(b.TempDir())
+		resolveAll(b, NewAPAIValidator(WithSpecCache(cache)), leaves)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			resolveAll(b, NewAPAIValidator(WithSpecCache(cache)), leaves)
+		}
+	})
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// capabilityMatrixData is the vocabulary of model capabilities and what
+// task steps require of their models
+//
+//go:embed data/capabilities.yaml
+var capabilityMatrixData []byte
+
+// capabilityRequirement restricts a capability or step setting to the
+// model types listed, or requires the model to have a capability. Aliases
+// are other spellings accepted for a capability.
+type capabilityRequirement struct {
+	Types      []string `yaml:"types"`
+	Capability string   `yaml:"capability"`
+	Aliases    []string `yaml:"aliases"`
+}
+
+// capabilityMatrix is the content of data/capabilities.yaml
+type capabilityMatrix struct {
+	Capabilities    map[string]capabilityRequirement `yaml:"capabilities"`
+	ResponseFormats map[string]capabilityRequirement `yaml:"response_formats"`
+	InputModalities map[string]capabilityRequirement `yaml:"input_modalities"`
+
+	// aliases maps each lowercased alias to the capability it names
+	aliases map[string]string
+}
+
+// modelCapabilities is the built-in capability matrix
+var modelCapabilities = parseCapabilityMatrix(capabilityMatrixData)
+
+// parseCapabilityMatrix decodes the capability matrix, panicking when the
+// embedded file is invalid
+func parseCapabilityMatrix(content []byte) *capabilityMatrix {
+	matrix := &capabilityMatrix{}
+	if err := yaml.Unmarshal(content, matrix); err != nil {
+		panic(fmt.Sprintf("invalid capability matrix: %v", err))
+	}
+	for _, requirements := range []map[string]capabilityRequirement{matrix.Capabilities, matrix.ResponseFormats, matrix.InputModalities} {
+		for name, requirement := range requirements {
+			for _, modelType := range requirement.Types {
+				if !containsString(modelTypes, modelType) {
+					panic(fmt.Sprintf("invalid capability matrix: %s lists unknown model type %s", name, modelType))
+				}
+			}
+			if _, ok := matrix.Capabilities[requirement.Capability]; requirement.Capability != "" && !ok {
+				panic(fmt.Sprintf("invalid capability matrix: %s requires unknown capability %s", name, requirement.Capability))
+			}
+		}
+	}
+
+	matrix.aliases = make(map[string]string)
+	for name, requirement := range matrix.Capabilities {
+		for _, alias := range requirement.Aliases {
+			key := strings.ToLower(alias)
+			if _, ok := canonicalEnumValue(requirementNames(matrix.Capabilities), alias); ok {
+				panic(fmt.Sprintf("invalid capability matrix: alias %s of %s is itself a capability", alias, name))
+			}
+			if other, ok := matrix.aliases[key]; ok {
+				panic(fmt.Sprintf("invalid capability matrix: alias %s names both %s and %s", alias, other, name))
+			}
+			matrix.aliases[key] = name
+		}
+	}
+	return matrix
+}
+
+// canonicalCapability returns the vocabulary name of a capability written
+// in any casing or as one of its aliases
+func canonicalCapability(capability string) (string, bool) {
+	if canonical, ok := canonicalEnumValue(requirementNames(modelCapabilities.Capabilities), capability); ok {
+		return canonical, true
+	}
+	canonical, ok := modelCapabilities.aliases[strings.ToLower(capability)]
+	return canonical, ok
+}
+
+// requirementNames returns the names of a set of requirements in sorted order
+func requirementNames(requirements map[string]capabilityRequirement) []string {
+	names := make([]string, 0, len(requirements))
+	for name := range requirements {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// modelCapabilityList returns the capabilities of a model and whether they
+// are written as an array of strings
+func modelCapabilityList(modelMap map[string]interface{}) ([]string, bool) {
+	list, ok := modelMap["capabilities"].([]interface{})
+	if !ok {
+		return nil, false
+	}
+	capabilities := make([]string, 0, len(list))
+	for _, item := range list {
+		capability, ok := item.(string)
+		if !ok {
+			return nil, false
+		}
+		capabilities = append(capabilities, capability)
+	}
+	return capabilities, true
+}
+
+// validateModelCapabilities checks the capabilities of a model against the
+// vocabulary and the model types each capability suits
+func (v *APAIValidator) validateModelCapabilities(f *sectionFindings, modelMap map[string]interface{}, modelIndex int) {
+	if _, exists := modelMap["capabilities"]; !exists {
+		return
+	}
+	name := elementName(modelIndex, modelMap)
+	capabilities, ok := modelCapabilityList(modelMap)
+	if !ok {
+		f.addError("INVALID_TYPE", fmt.Sprintf("Model %s capabilities must be an array of strings", name))
+		return
+	}
+
+	typeStr, _ := modelMap["type"].(string)
+	modelType, typeKnown := canonicalEnumValue(modelTypes, typeStr)
+	known := requirementNames(modelCapabilities.Capabilities)
+	for index, capability := range capabilities {
+		if strings.HasPrefix(capability, extensionPrefix) {
+			continue
+		}
+		canonical, ok := matchEnum(f, known, capability, fmt.Sprintf("models[%d].capabilities[%d]", modelIndex, index))
+		if !ok {
+			canonical, ok = canonicalCapability(capability)
+		}
+		if !ok {
+			f.addWarning("UNKNOWN_VALUE", fmt.Sprintf("Model %s has unknown capability: %s", name, capability))
+			continue
+		}
+		types := modelCapabilities.Capabilities[canonical].Types
+		if typeKnown && len(types) > 0 && !containsString(types, modelType) {
+			f.addError("CAPABILITY_TYPE_CONFLICT", fmt.Sprintf("Model %s capability %s is not supported by type %s (expected one of %s)", name, canonical, modelType, strings.Join(types, ", ")))
+		}
+	}
+}
+
+// hasCapability reports whether a capability list includes a capability,
+// in any casing or under one of its aliases
+func hasCapability(capabilities []string, capability string) bool {
+	for _, listed := range capabilities {
+		if canonical, ok := canonicalCapability(listed); ok && canonical == capability {
+			return true
+		}
+	}
+	return false
+}
+
+// validateStepModality checks the response format and input modalities a
+// step declares
+func (v *APAIValidator) validateStepModality(f *sectionFindings, stepMap map[string]interface{}, taskIndex, stepIndex int) {
+	location := fmt.Sprintf("tasks[%d].steps[%d]", taskIndex, stepIndex)
+	if format, exists := stepMap["response_format"]; exists {
+		formatStr, ok := format.(string)
+		if !ok {
+			f.addError("INVALID_TYPE", fmt.Sprintf("Task %d step %d response_format must be a string", taskIndex, stepIndex))
+		} else if _, valid := matchEnum(f, requirementNames(modelCapabilities.ResponseFormats), formatStr, location+".response_format"); !valid {
+			f.addError("INVALID_ENUM", fmt.Sprintf("Task %d step %d invalid response format: %s (expected one of %s)", taskIndex, stepIndex, formatStr, strings.Join(requirementNames(modelCapabilities.ResponseFormats), ", ")))
+		}
+	}
+
+	if modalities, exists := stepMap["input_modalities"]; exists {
+		list, ok := modalities.([]interface{})
+		if !ok {
+			f.addError("INVALID_TYPE", fmt.Sprintf("Task %d step %d input_modalities must be an array of strings", taskIndex, stepIndex))
+			return
+		}
+		known := requirementNames(modelCapabilities.InputModalities)
+		for index, item := range list {
+			modality, ok := item.(string)
+			if !ok {
+				f.addError("INVALID_TYPE", fmt.Sprintf("Task %d step %d input_modalities must be an array of strings", taskIndex, stepIndex))
+				return
+			}
+			if _, valid := matchEnum(f, known, modality, fmt.Sprintf("%s.input_modalities[%d]", location, index)); !valid {
+				f.addError("INVALID_ENUM", fmt.Sprintf("Task %d step %d invalid input modality: %s (expected one of %s)", taskIndex, stepIndex, modality, strings.Join(known, ", ")))
+			}
+		}
+	}
+}
+
+// validateStepModels checks that the model of each step supports what the
+// step asks of it: the capability its response format needs, and the
+// model types its input modalities need
+func (v *APAIValidator) validateStepModels(spec map[string]interface{}) {
+	models := make(map[string]map[string]interface{})
+	objectsAt(spec, "models[]", "", func(modelMap map[string]interface{}, _ string) {
+		if id, ok := modelMap["id"].(string); ok {
+			models[id] = modelMap
+		}
+	})
+
+	objectsAt(spec, "tasks[].steps[]", "", func(step map[string]interface{}, location string) {
+		modelID, _ := step["model"].(string)
+		modelMap, exists := models[modelID]
+		if !exists {
+			return
+		}
+		typeStr, _ := modelMap["type"].(string)
+		modelType, typeKnown := canonicalEnumValue(modelTypes, typeStr)
+
+		if format, ok := step["response_format"].(string); ok {
+			format, _ = canonicalEnumValue(requirementNames(modelCapabilities.ResponseFormats), format)
+			needed := modelCapabilities.ResponseFormats[format].Capability
+			capabilities, _ := modelCapabilityList(modelMap)
+			if needed != "" && !hasCapability(capabilities, needed) {
+				v.addWarning("MISSING_MODEL_CAPABILITY", fmt.Sprintf("Step %s uses response format %s, but model %s does not list the %s capability", location, format, modelID, needed))
+			}
+		}
+
+		modalities, _ := step["input_modalities"].([]interface{})
+		for _, item := range modalities {
+			modality, _ := item.(string)
+			modality, _ = canonicalEnumValue(requirementNames(modelCapabilities.InputModalities), modality)
+			types := modelCapabilities.InputModalities[modality].Types
+			if typeKnown && len(types) > 0 && !containsString(types, modelType) {
+				v.addError("MODALITY_MISMATCH", fmt.Sprintf("Step %s sends %s input to model %s of type %s (expected one of %s)", location, modality, modelID, modelType, strings.Join(types, ", ")))
+			}
+		}
+	})
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestModelCapabilities(t *testing.T) {
+	spec := loadExampleSpecs(t, "automation/mcp-integration.yaml")[0]
+	models := spec["models"].([]interface{})
+	models[0].(map[string]interface{})["capabilities"] = []interface{}{"conversation", "Function_Calling", "telepathy", "x-escalation"}
+	models = append(models, map[string]interface{}{
+		"id": "embedder", "type": "Embedding", "provider": "openai", "name": "text-embedding-3-small", "purpose": "search",
+		"capabilities": []interface{}{"embeddings", "vision"},
+	})
+	spec["models"] = models
+
+	steps := spec["tasks"].([]interface{})[0].(map[string]interface{})["steps"].([]interface{})
+	steps = append(steps,
+		map[string]interface{}{"name": "extract", "action": "generate", "model": "support-llm", "response_format": "json", "input_modalities": []interface{}{"text", "image"}},
+		map[string]interface{}{"name": "embed", "action": "analyze", "model": "embedder", "response_format": "xml", "input_modalities": []interface{}{"video"}},
+	)
+	spec["tasks"].([]interface{})[0].(map[string]interface{})["steps"] = steps
+	last := len(steps) - 1
+
+	validator := NewAPAIValidator()
+	validator.ValidateSpec(spec)
+	wantErrors := []string{
+		"Model embedder capability vision is not supported by type Embedding (expected one of Vision, Multimodal)",
+		"Task 0 step " + strconv.Itoa(last) + " invalid response format: xml (expected one of json, text)",
+		"Task 0 step " + strconv.Itoa(last) + " invalid input modality: video (expected one of audio, image, text)",
+		"Step tasks[0].steps[" + strconv.Itoa(last-1) + "] sends image input to model support-llm of type LLM (expected one of Vision, Multimodal)",
+	}
+	wantWarnings := []string{
+		`Non-canonical casing for models[0].capabilities[1]: "Function_Calling", use "function_calling"`,
+		"Model support-llm has unknown capability: telepathy",
+		"Step tasks[0].steps[" + strconv.Itoa(last-1) + "] uses response format json, but model support-llm does not list the json_mode capability",
+	}
+	for _, want := range wantErrors {
+		if !containsString(validator.Errors, want) {
+			t.Errorf("missing error %q in %v", want, validator.Errors)
+		}
+	}
+	for _, want := range wantWarnings {
+		if !containsString(validator.Warnings, want) {
+			t.Errorf("missing warning %q in %v", want, validator.Warnings)
+		}
+	}
+	for _, warning := range validator.Warnings {
+		if strings.Contains(warning, "x-escalation") {
+			t.Errorf("custom capability reported: %s", warning)
+		}
+	}
+
+	codes := map[string]string{
+		wantErrors[0]:   "CAPABILITY_TYPE_CONFLICT",
+		wantErrors[1]:   "INVALID_ENUM",
+		wantErrors[2]:   "INVALID_ENUM",
+		wantErrors[3]:   "MODALITY_MISMATCH",
+		wantWarnings[1]: "UNKNOWN_VALUE",
+		wantWarnings[2]: "MISSING_MODEL_CAPABILITY",
+	}
+	for message, want := range codes {
+		if rule, ok := MatchRule(message); !ok || rule.Code != want {
+			t.Errorf("expected %s for %q, got %q", want, message, rule.Code)
+		}
+	}
+
+	models[0].(map[string]interface{})["capabilities"] = []interface{}{"json_mode", 4}
+	validator.ValidateSpec(spec)
+	if want := "Model support-llm capabilities must be an array of strings"; !containsString(validator.Errors, want) {
+		t.Errorf("missing %q in %v", want, validator.Errors)
+	}
+}
+
+func TestCapabilityAliases(t *testing.T) {
+	spec := loadExampleSpecs(t, "automation/mcp-integration.yaml")[0]
+	models := spec["models"].([]interface{})
+	models[0].(map[string]interface{})["capabilities"] = []interface{}{"text-generation", "Problem-Solving", "multilingual_support"}
+	spec["models"] = append(models, map[string]interface{}{
+		"id": "embedder", "type": "Embedding", "provider": "openai", "name": "text-embedding-3-small", "purpose": "search",
+		"capabilities": []interface{}{"embeddings", "text-generation"},
+	})
+
+	validator := NewAPAIValidator()
+	validator.ValidateSpec(spec)
+	for _, warning := range validator.Warnings {
+		if strings.Contains(warning, "capabilit") {
+			t.Errorf("alias reported: %s", warning)
+		}
+	}
+	if want := "Model embedder capability text_generation is not supported by type Embedding (expected one of LLM, Multimodal)"; !containsString(validator.Errors, want) {
+		t.Errorf("missing %q in %v", want, validator.Errors)
+	}
+
+	if !hasCapability([]string{"Text-Generation"}, "text_generation") || hasCapability([]string{"text-generation"}, "json_mode") {
+		t.Error("expected hasCapability to resolve aliases")
+	}
+}
+
+func TestCapabilityMatrixIsValid(t *testing.T) {
+	// parseCapabilityMatrix panics on unknown model types and capabilities,
+	// and on aliases that clash
+	matrix := parseCapabilityMatrix(capabilityMatrixData)
+	if len(matrix.Capabilities) == 0 || len(matrix.ResponseFormats) == 0 || len(matrix.InputModalities) == 0 {
+		t.Fatalf("expected a populated matrix, got %+v", matrix)
+	}
+}
@@ -1,11 +1,27 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"gopkg.in/yaml.v3"
 )
 
+// exitInterrupted is the exit status after Ctrl-C cancelled a run
+const exitInterrupted = 130
+
 func main() {
 	args := os.Args[1:]
 
@@ -17,13 +33,59 @@ func main() {
 	command := args[0]
 	options := args[1:]
 
+	// Ctrl-C cancels the context so long runs stop promptly
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
 	switch command {
 	case "validate":
-		handleValidate(options)
+		handleValidate(ctx, options)
 	case "tree":
 		handleTree(options)
 	case "merge":
-		handleMerge(options)
+		handleMerge(ctx, options)
+	case "graph":
+		handleGraph(options)
+	case "migrate":
+		handleMigrate(options)
+	case "normalize":
+		handleNormalize(ctx, options)
+	case "fix":
+		handleFix(options)
+	case "fingerprint":
+		handleFingerprint(options)
+	case "hash":
+		handleHash(options)
+	case "verify":
+		handleVerify(options)
+	case "redact":
+		handleRedact(options)
+	case "preflight":
+		handlePreflight(ctx, options)
+	case "deps":
+		handleDeps(ctx, options)
+	case "cost":
+		handleCost(ctx, options)
+	case "stats":
+		handleStats(ctx, options)
+	case "lint":
+		handleLint(options)
+	case "export":
+		handleExport(ctx, options)
+	case "generate":
+		handleGenerate(ctx, options)
+	case "import":
+		handleImport(options)
+	case "explain", "--explain":
+		handleExplain(options)
+	case "rules", "--rules":
+		handleRules(options)
+	case "cache":
+		handleCache(options)
+	case "serve":
+		handleServe(ctx, options)
+	case "test":
+		handleTest(ctx, options)
 	default:
 		fmt.Printf("Unknown command: %s\n", command)
 		showHelp()
@@ -31,102 +93,718 @@ func main() {
 	}
 }
 
-func handleValidate(options []string) {
-	if len(options) == 0 {
-		fmt.Println("Error: No file specified")
-		fmt.Println("Usage: go run cli.go validate <file> [--hierarchical]")
-		os.Exit(1)
-	}
+func handleValidate(ctx context.Context, options []string) {
+	files := positionalArgs(options)
 
-	filePath := options[0]
 	hierarchical := false
-	for _, opt := range options {
+	baselinePath, writeBaselinePath, since, workspacePath, inputFormat, groupBy := "", "", "", "", "", ""
+	firstOnly := containsString(options, "--first-only")
+	errorsOnly := containsString(options, "--errors-only")
+	changedOnly := containsString(options, "--changed")
+	stream := containsString(options, "--stream")
+	plugins := make([]string, 0)
+	for i, opt := range options {
 		if opt == "--hierarchical" {
 			hierarchical = true
-			break
+		}
+		if i+1 >= len(options) {
+			continue
+		}
+		switch opt {
+		case "--baseline":
+			baselinePath = options[i+1]
+		case "--write-baseline":
+			writeBaselinePath = options[i+1]
+		case "--since":
+			since = options[i+1]
+		case "--plugin":
+			plugins = append(plugins, options[i+1])
+		case "--workspace":
+			workspacePath = options[i+1]
+		case "--input-format":
+			inputFormat = options[i+1]
+		case "--group-by":
+			groupBy = options[i+1]
+		}
+	}
+	if inputFormat != "" && inputFormat != "yaml" && inputFormat != "json" {
+		fmt.Printf("Error: Unsupported input format: %s (expected yaml or json)\n", inputFormat)
+		os.Exit(1)
+	}
+	if groupBy != "" && !containsString(groupByValues, groupBy) {
+		fmt.Printf("Error: Unsupported grouping: %s (expected section or code)\n", groupBy)
+		os.Exit(1)
+	}
+	if hierarchical && containsString(files, "-") {
+		fmt.Println("Error: --hierarchical cannot be used with a specification read from stdin")
+		os.Exit(1)
+	}
+	if len(files) == 0 && since == "" && !changedOnly && workspacePath == "" {
+		fmt.Println("Error: No file specified")
+		fmt.Println("Usage: go run cli.go validate <file> [file2] ... [--hierarchical] [--config <file>]")
+		os.Exit(1)
+	}
+
+	config, err := loadCLIConfig(options)
+	if err != nil {
+		fmt.Printf("❌ Configuration error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Workspace members are validated unless files are given
+	var workspace *Workspace
+	if workspacePath != "" {
+		workspace, err = LoadWorkspace(ctx, workspacePath)
+		if err != nil {
+			fmt.Printf("❌ Configuration error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(files) == 0 {
+			for _, member := range workspace.Members {
+				files = append(files, member.Path)
+			}
+		}
+	}
+
+	if since != "" || changedOnly {
+		if since == "" {
+			since = "HEAD"
+		}
+		files = changedSpecFiles(ctx, config, files, since, changedOnly)
+		if len(files) == 0 {
+			fmt.Printf("✅ No APAI specifications changed since %s\n", since)
+			return
 		}
 	}
 
 	fmt.Printf("Validating APAI specification")
+	if len(files) > 1 {
+		fmt.Printf("s")
+	}
 	if hierarchical {
 		fmt.Printf(" with inheritance")
 	}
-	fmt.Printf(": %s\n", filePath)
+	fmt.Printf(": %s\n", strings.Join(files, ", "))
 	fmt.Println(strings.Repeat("-", 60))
+	if len(config.Relax) > 0 {
+		fmt.Printf("⚠️  DEMOTION ACTIVE: errors with codes %s are reported as warnings\n", strings.Join(config.Relax, ", "))
+	}
 
-	validator := NewAPAIValidator()
-	var isValid bool
-	var err error
+	failLevel, err := resolveFailLevel(options, config.FailOn)
+	if err != nil {
+		fmt.Printf("❌ Configuration error: %v\n", err)
+		os.Exit(1)
+	}
 
-	if hierarchical {
-		isValid, err = validator.ValidateWithInheritance(filePath)
-	} else {
-		isValid, err = validator.ValidateFile(filePath)
+	profiles, err := loadCLICompliance(options)
+	if err != nil {
+		fmt.Printf("❌ Configuration error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Findings in the baseline are suppressed; a new baseline records all
+	baseline := NewBaseline()
+	if baselinePath != "" {
+		baseline, err = LoadBaseline(baselinePath)
+		if err != nil {
+			fmt.Printf("❌ Configuration error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	newBaseline := NewBaseline()
+
+	envLookup, err := loadCLIEnv(options)
+	if err != nil {
+		fmt.Printf("❌ Configuration error: %v\n", err)
+		os.Exit(1)
+	}
+
+	schemas, err := loadCLISchemas(options)
+	if err != nil {
+		fmt.Printf("❌ Configuration error: %v\n", err)
+		os.Exit(1)
+	}
+
+	approvedModels, err := loadCLIApprovedModels(ctx, options, config)
+	if err != nil {
+		fmt.Printf("❌ Configuration error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// On a terminal, findings are printed as soon as they are produced,
+	// unless they are grouped
+	progressive := isTerminal(os.Stdout) && groupBy == ""
+	currentFile := ""
+	validatorOptions := []Option{WithConfig(config), WithFailLevel(failLevel), WithComplianceProfiles(profiles...), WithPlugins(plugins...), WithJSONSchemas(schemas...), WithApprovedModels(approvedModels)}
+	if workspace != nil {
+		validatorOptions = append(validatorOptions, WithWorkspace(workspace))
+	}
+	if envLookup != nil {
+		validatorOptions = append(validatorOptions, WithEnvSubstitution(envLookup, containsString(options, "--allow-missing-env")))
+	}
+	if inputFormat != "" {
+		validatorOptions = append(validatorOptions, WithInputFormat(inputFormat))
+	}
+	if progressive {
+		validatorOptions = append(validatorOptions, WithIssueHandler(func(issue Issue) {
+			if errorsOnly && issue.Severity != "error" {
+				return
+			}
+			if !baseline.Contains(currentFile, issue) {
+				printIssue(issue)
+			}
+		}))
+	}
+	validator := NewAPAIValidator(validatorOptions...)
+
+	if len(files) == 1 && isBundle(files[0]) {
+		handleValidateBundle(ctx, validator, files[0], options, baseline, writeBaselinePath, errorsOnly)
+		return
+	}
+
+	failed := 0
+	suppressed := 0
+	for i, filePath := range files {
+		currentFile = filePath
+		if len(files) > 1 {
+			if i > 0 {
+				fmt.Println("")
+			}
+			fmt.Printf("📄 %s\n", filePath)
+		}
+
+		// JSON files are read an element at a time with --stream; merging
+		// with inherited specs needs them whole
+		streaming := stream && !hierarchical && filePath != "-" &&
+			(inputFormat == "json" || inputFormat == "" && formatOfExtension(filepath.Ext(filePath)) == "json")
+
+		// Each document of a multi-document file is validated on its own
+		// unless --first-only restores validating the first one
+		documents := []map[string]interface{}{nil}
+		if !firstOnly && !streaming {
+			documents, err = loadCLIDocuments(validator, filePath, inputFormat)
+			if err != nil {
+				fmt.Printf("❌ Validation error: %v\n", err)
+				failed++
+				continue
+			}
+		}
+
+		fileFailed := false
+		for index, document := range documents {
+			if len(documents) > 1 {
+				currentFile = documentKey(filePath, index)
+				if index > 0 {
+					fmt.Println("")
+				}
+				fmt.Printf("📄 %s, document %d of %d\n", filePath, index+1, len(documents))
+			}
+
+			switch {
+			case streaming:
+				_, err = validator.ValidateStreamContext(ctx, filePath)
+			case !firstOnly:
+				specPath := filePath
+				if filePath == "-" {
+					specPath = ""
+				}
+				_, err = validator.validateDocument(ctx, document, specPath, hierarchical)
+			case filePath == "-":
+				// Standard input has no extension to infer the format from
+				format := inputFormat
+				if format == "" {
+					format = "yaml"
+				}
+				_, err = validator.ValidateReader(ctx, os.Stdin, format)
+			case hierarchical:
+				_, err = validator.ValidateWithInheritanceContext(ctx, filePath)
+			default:
+				_, err = validator.ValidateFileContext(ctx, filePath)
+			}
+
+			if errors.Is(err, context.Canceled) {
+				fmt.Printf("\n⚠️  Interrupted: validated %d of %d files, %d failed\n", i, len(files), failed)
+				os.Exit(exitInterrupted)
+			}
+			if err != nil {
+				fmt.Printf("❌ Validation error: %v\n", err)
+				fileFailed = true
+				continue
+			}
+
+			newBaseline.Add(currentFile, validator.GetResults())
+			result, count := baseline.Filter(currentFile, validator.GetResults())
+			suppressed += count
+			// --errors-only shapes the output only, so warnings still fail
+			// validation with --fail-on warning
+			if validator.Config.FailOn.Fails(result) {
+				fileFailed = true
+			}
+			if errorsOnly {
+				result = result.ErrorsOnly()
+			}
+
+			switch {
+			case progressive:
+				printValidationSummary(result)
+			case groupBy != "":
+				printGroupedValidationResult(result, groupBy)
+			default:
+				printValidationResult(result)
+			}
+		}
+		if fileFailed {
+			failed++
+		}
+	}
+
+	if len(files) > 1 {
+		fmt.Printf("\nValidated %d files, %d failed\n", len(files), failed)
+	}
+	if suppressed > 0 {
+		fmt.Printf("%d findings suppressed by baseline %s\n", suppressed, baselinePath)
+	}
+	if writeBaselinePath != "" {
+		writeBaseline(newBaseline, writeBaselinePath)
+		return
+	}
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// loadCLIDocuments decodes the documents of a specification file, or of
+// standard input for "-", parsed as YAML unless inputFormat says otherwise
+func loadCLIDocuments(validator *APAIValidator, filePath, inputFormat string) ([]map[string]interface{}, error) {
+	if filePath != "-" {
+		return validator.loadDocuments(filePath)
+	}
+	content, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read specification: %v", err)
+	}
+	if inputFormat == "" {
+		inputFormat = "yaml"
+	}
+	return decodeDocuments(content, inputFormat)
+}
+
+// documentKey names a document of a multi-document file in baselines
+func documentKey(filePath string, index int) string {
+	return fmt.Sprintf("%s (document %d)", filePath, index+1)
+}
+
+// changedSpecFiles narrows the specifications under paths, the current
+// directory when there are none, to those changed since the git ref or
+// inheriting from a changed file
+func changedSpecFiles(ctx context.Context, config Config, paths []string, since string, untracked bool) []string {
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	candidates, err := SpecFiles(ctx, paths)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(1)
+	}
+	changed, err := ChangedFiles(ctx, ".", since)
+	if err == nil && untracked {
+		var added []string
+		added, err = UntrackedFiles(ctx, ".")
+		changed = append(changed, added...)
+	}
+	if err != nil {
+		fmt.Printf("❌ Cannot list files changed since %s: %v\n", since, err)
+		if errors.Is(err, errNotGitRepository) {
+			fmt.Println("   --changed and --since need a git working tree: run validate inside one, or list the specifications to validate instead")
+		}
+		os.Exit(1)
+	}
+
+	affected := NewAPAIValidator(WithConfig(config)).AffectedSpecs(candidates, changed)
+	files := make([]string, 0, len(affected))
+	if len(affected) > 0 {
+		fmt.Printf("🔎 Selected by changes since %s:\n", since)
+	}
+	for _, spec := range affected {
+		fmt.Printf("  • %s: %s\n", spec.Path, spec.Reason())
+		files = append(files, spec.Path)
+	}
+	return files
+}
+
+// writeBaseline saves the findings of a run as a baseline for later runs
+func writeBaseline(baseline *Baseline, filePath string) {
+	if err := baseline.Write(filePath); err != nil {
+		fmt.Printf("❌ Error writing baseline: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("📝 Baseline with %d findings written to %s\n", len(baseline.Entries), filePath)
+}
+
+// loadCLICompliance loads the built-in profiles named by --compliance,
+// comma-separated or repeated, and the profile files given by
+// --compliance-file
+func loadCLICompliance(options []string) ([]*ComplianceProfile, error) {
+	profiles := make([]*ComplianceProfile, 0)
+	for i, opt := range options {
+		if i+1 >= len(options) {
+			break
+		}
+		switch opt {
+		case "--compliance":
+			for _, name := range strings.Split(options[i+1], ",") {
+				profile, err := LoadComplianceProfile(strings.TrimSpace(name))
+				if err != nil {
+					return nil, err
+				}
+				profiles = append(profiles, profile)
+			}
+		case "--compliance-file":
+			profile, err := LoadComplianceProfileFile(options[i+1])
+			if err != nil {
+				return nil, err
+			}
+			profiles = append(profiles, profile)
+		}
+	}
+	return profiles, nil
+}
+
+// loadCLIApprovedModels loads the approved models list configured with
+// --approved-models or approved_models, or returns nil when there is none.
+// --no-remote limits a list fetched from a URL to its cached copy.
+func loadCLIApprovedModels(ctx context.Context, options []string, config Config) (*ApprovedModels, error) {
+	if config.ApprovedModels == "" {
+		return nil, nil
+	}
+	return LoadApprovedModelsContext(ctx, config.ApprovedModels, !containsString(options, "--no-remote"))
+}
+
+// loadCLISchemas loads the JSON Schemas given by --schema
+func loadCLISchemas(options []string) ([]*JSONSchema, error) {
+	schemas := make([]*JSONSchema, 0)
+	for i, opt := range options {
+		if opt == "--schema" && i+1 < len(options) {
+			schema, err := LoadJSONSchema(options[i+1])
+			if err != nil {
+				return nil, err
+			}
+			schemas = append(schemas, schema)
+		}
+	}
+	return schemas, nil
+}
+
+// loadCLIEnv returns the lookup of ${VAR} placeholders for --substitute-env
+// and --env-file, or nil when neither is given. The environment takes
+// precedence over env files, and later env files over earlier ones.
+func loadCLIEnv(options []string) (func(string) (string, bool), error) {
+	substitute := containsString(options, "--substitute-env")
+	fileValues := make(map[string]string)
+	for i, opt := range options {
+		if opt == "--env-file" && i+1 < len(options) {
+			values, err := LoadEnvFile(options[i+1])
+			if err != nil {
+				return nil, err
+			}
+			for name, value := range values {
+				fileValues[name] = value
+			}
+			substitute = true
+		}
+	}
+	if !substitute {
+		return nil, nil
+	}
+	return EnvLookup(fileValues), nil
+}
+
+// valueFlags lists the options that take a value
+var valueFlags = []string{
+	"--config", "--spec-root", "--max-inheritance-depth", "--max-inherited-specs",
+	"--fail-on", "--root", "--format", "--to", "--output", "--baseline", "--write-baseline",
+	"--fingerprint", "--redact", "--invocations", "--compliance", "--compliance-file",
+	"--task", "--out", "--since", "--package", "--plugin", "--env-file",
+	"--into", "--server", "--schema", "--workspace", "--addr", "--relax",
+	"--warn-on", "--error-on",
+	"--input-format", "--approved-models", "--group-by", "--cache-dir",
+	"--grpc", "--grpc-root",
+}
+
+// positionalArgs returns the arguments that are neither options nor option values
+func positionalArgs(options []string) []string {
+	positional := make([]string, 0, len(options))
+	for i := 0; i < len(options); i++ {
+		if containsString(valueFlags, options[i]) {
+			i++
+			continue
+		}
+		if strings.HasPrefix(options[i], "--") {
+			continue
+		}
+		positional = append(positional, options[i])
+	}
+	return positional
+}
+
+// resolveFailLevel decides which findings fail the validate command.
+// Precedence, highest first:
+//  1. --fail-on error|warning|never
+//  2. the deprecated --strict, --warnings-as-errors and --warn-exit-code
+//     flags, each equivalent to --fail-on warning
+//  3. fail_on in the configuration file
+//  4. the default, error
+func resolveFailLevel(options []string, configured FailLevel) (FailLevel, error) {
+	level := configured
+	deprecatedFlags := []string{"--strict", "--warnings-as-errors", "--warn-exit-code"}
+
+	for _, opt := range options {
+		if containsString(deprecatedFlags, opt) {
+			fmt.Printf("⚠️  %s is deprecated, use --fail-on warning\n", opt)
+			level = FailOnWarning
+		}
+	}
+
+	for i, opt := range options {
+		if opt != "--fail-on" {
+			continue
+		}
+		if i+1 >= len(options) {
+			return "", fmt.Errorf("--fail-on requires a value")
+		}
+		return ParseFailLevel(options[i+1])
+	}
+
+	if level == "" {
+		level = FailOnError
+	}
+	return level, nil
+}
+
+func handleValidateBundle(ctx context.Context, validator *APAIValidator, bundlePath string, options []string, baseline *Baseline, writeBaselinePath string, errorsOnly bool) {
+	root, groupBy := "", ""
+	for i, opt := range options {
+		if i+1 >= len(options) {
+			continue
+		}
+		switch opt {
+		case "--root":
+			root = options[i+1]
+		case "--group-by":
+			groupBy = options[i+1]
+		}
 	}
 
+	results, err := validator.ValidateBundleContext(ctx, bundlePath, root)
 	if err != nil {
 		fmt.Printf("❌ Validation error: %v\n", err)
 		os.Exit(1)
 	}
 
-	if isValid {
+	failed := false
+	newBaseline := NewBaseline()
+	for i, result := range results {
+		if i > 0 {
+			fmt.Println("")
+		}
+		fmt.Printf("📄 %s\n", result.Root)
+		newBaseline.Add(result.Root, result.ValidationResult)
+		filtered, _ := baseline.Filter(result.Root, result.ValidationResult)
+		if validator.Config.FailOn.Fails(filtered) {
+			failed = true
+		}
+		if errorsOnly {
+			filtered = filtered.ErrorsOnly()
+		}
+		if groupBy != "" {
+			printGroupedValidationResult(filtered, groupBy)
+		} else {
+			printValidationResult(filtered)
+		}
+	}
+
+	if writeBaselinePath != "" {
+		writeBaseline(newBaseline, writeBaselinePath)
+		return
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func printValidationResult(result ValidationResult) {
+	if result.Valid {
 		fmt.Println("✅ Validation successful!")
 	} else {
 		fmt.Println("❌ Validation failed!")
 		fmt.Println("\nErrors:")
-		for _, error := range validator.Errors {
-			fmt.Printf("  • %s\n", error)
+		for _, error := range result.Errors {
+			fmt.Printf("  • %s%s\n", error, codeSuffix(result.issue("error", error)))
 		}
 	}
 
-	if len(validator.Warnings) > 0 {
+	if len(result.Warnings) > 0 {
 		fmt.Println("\nWarnings:")
-		for _, warning := range validator.Warnings {
-			fmt.Printf("  ⚠️  %s\n", warning)
+		for _, warning := range result.Warnings {
+			fmt.Printf("  ⚠️  %s%s\n", warning, codeSuffix(result.issue("warning", warning)))
 		}
 	}
+}
+
+// printGroupedValidationResult prints the findings of a result grouped by
+// section or code, with the counts of each group
+func printGroupedValidationResult(result ValidationResult, groupBy string) {
+	if result.Valid {
+		fmt.Println("✅ Validation successful!")
+	} else {
+		fmt.Println("❌ Validation failed!")
+	}
 
-	os.Exit(func() int {
-		if isValid {
-			return 0
+	groups, _ := GroupIssues(result, groupBy)
+	for _, group := range groups {
+		fmt.Printf("\n%s (%d errors, %d warnings)\n", group.Name, group.Errors, group.Warnings)
+		for _, issue := range group.Issues {
+			printIssue(issue)
 		}
-		return 1
-	}())
+	}
+}
+
+// printIssue prints a single finding as it is reported
+func printIssue(issue Issue) {
+	code := ""
+	if issue.Code != "" {
+		code = fmt.Sprintf(" [%s]", issue.Code)
+	}
+	if issue.Severity == "error" {
+		fmt.Printf("  • %s%s\n", issue.Message, code)
+	} else {
+		fmt.Printf("  ⚠️  %s%s\n", issue.Message, code)
+	}
+}
+
+// printValidationSummary prints the outcome after findings were printed progressively
+func printValidationSummary(result ValidationResult) {
+	if result.Valid {
+		fmt.Printf("✅ Validation successful! (%d warnings)\n", len(result.Warnings))
+	} else {
+		fmt.Printf("❌ Validation failed! (%d errors, %d warnings)\n", len(result.Errors), len(result.Warnings))
+	}
+}
+
+// isTerminal reports whether a file is an interactive terminal
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// codeSuffix returns the rule code of an issue for display, if it has one
+func codeSuffix(issue Issue) string {
+	if issue.Code != "" {
+		return fmt.Sprintf(" [%s]", issue.Code)
+	}
+	return ""
 }
 
 func handleTree(options []string) {
 	if len(options) == 0 {
 		fmt.Println("Error: No file specified")
-		fmt.Println("Usage: go run cli.go tree <file>")
+		fmt.Println("Usage: go run cli.go tree <file> [--format text|json]")
 		os.Exit(1)
 	}
 
 	filePath := options[0]
+	format := "text"
+	for i, opt := range options {
+		if opt == "--format" && i+1 < len(options) {
+			format = options[i+1]
+		}
+	}
+	if format != "text" && format != "json" {
+		fmt.Printf("Error: Unsupported tree format: %s\n", format)
+		os.Exit(1)
+	}
+
+	config, err := loadCLIConfig(options)
+	if err != nil {
+		fmt.Printf("❌ Configuration error: %v\n", err)
+		os.Exit(1)
+	}
+	validator := NewAPAIValidator(WithConfig(config))
+
+	if format == "json" {
+		tree, err := validator.BuildHierarchyTree(filePath)
+		if err != nil {
+			fmt.Printf("❌ Error loading %s: %v\n", filePath, err)
+			os.Exit(1)
+		}
+		content, err := json.MarshalIndent(tree, "", "  ")
+		if err != nil {
+			fmt.Printf("❌ Building tree failed: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(content))
+		return
+	}
 
 	fmt.Println("APAI Specification Hierarchy Tree")
 	fmt.Println(strings.Repeat("=", 50))
-
-	validator := NewAPAIValidator()
 	validator.PrintHierarchyTree(filePath, 0)
 }
 
-func handleMerge(options []string) {
-	if len(options) < 2 {
+func handleMerge(ctx context.Context, options []string) {
+	positional := positionalArgs(options)
+	force := containsString(options, "--force")
+	validate := containsString(options, "--validate")
+	resolveRefs := containsString(options, "--resolve-refs")
+	inlineTemplates := containsString(options, "--inline-templates")
+
+	if len(positional) < 2 {
 		fmt.Println("Error: Missing required arguments")
-		fmt.Println("Usage: go run cli.go merge <output> <file1> [file2] ...")
+		fmt.Println("Usage: go run cli.go merge <output> <file1> [file2] ... [--force] [--validate] [--resolve-refs] [--inline-templates] [--config <file>] [--relax <codes>] [--error-on <codes>] [--substitute-env --force]")
+		os.Exit(1)
+	}
+
+	// The merged result is validated under the same configuration as
+	// validate, relaxed and promoted codes included
+	config, err := loadCLIConfig(options)
+	if err != nil {
+		fmt.Printf("❌ Configuration error: %v\n", err)
+		os.Exit(1)
+	}
+	approvedModels, err := loadCLIApprovedModels(ctx, options, config)
+	if err != nil {
+		fmt.Printf("❌ Configuration error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Substituted values may be secrets, which must not end up in
+	// artifacts by accident
+	envLookup, err := loadCLIEnv(options)
+	if err != nil {
+		fmt.Printf("❌ Configuration error: %v\n", err)
+		os.Exit(1)
+	}
+	if envLookup != nil && !force {
+		fmt.Println("Error: --substitute-env would write environment values, possibly secrets, into the merged output; combine it with --force to do so")
 		os.Exit(1)
 	}
 
-	outputPath := options[0]
-	inputFiles := options[1:]
+	outputPath := positional[0]
+	inputFiles := positional[1:]
 
 	fmt.Println("Merging APAI specifications...")
 	fmt.Printf("Output: %s\n", outputPath)
 	fmt.Printf("Input files: %s\n", strings.Join(inputFiles, ", "))
 	fmt.Println(strings.Repeat("-", 60))
 
-	validator := NewAPAIValidator()
+	validatorOptions := []Option{WithConfig(config), WithApprovedModels(approvedModels)}
+	if envLookup != nil {
+		validatorOptions = append(validatorOptions, WithEnvSubstitution(envLookup, containsString(options, "--allow-missing-env")))
+	}
+	validator := NewAPAIValidator(validatorOptions...)
 	specs := make([]map[string]interface{}, 0, len(inputFiles))
+	commentTrees := make([]*yaml.Node, 0, len(inputFiles))
+	rejected := false
 
 	for _, file := range inputFiles {
 		if _, err := os.Stat(file); os.IsNotExist(err) {
@@ -139,24 +817,1350 @@ func handleMerge(options []string) {
 			fmt.Printf("❌ Error loading %s: %v\n", file, err)
 			os.Exit(1)
 		}
+		validator.rebaseFileReferences(spec, file)
+
+		if rejection := specRejection(spec); rejection != "" {
+			if !force {
+				fmt.Printf("❌ Not an APAI specification: %s (%s; use --force to merge partial fragments)\n", file, rejection)
+				rejected = true
+				continue
+			}
+			fmt.Printf("⚠️  Merging non-APAI fragment: %s\n", file)
+		}
+
+		// Comments are carried over from the source documents
+		commentTree, err := validator.loadCommentTree(file)
+		if err != nil {
+			fmt.Printf("❌ Error loading %s: %v\n", file, err)
+			os.Exit(1)
+		}
 
 		specs = append(specs, spec)
+		commentTrees = append(commentTrees, commentTree)
 		fmt.Printf("✅ Loaded: %s\n", file)
 	}
 
+	if rejected {
+		os.Exit(1)
+	}
+
 	format := "yaml"
 	if strings.HasSuffix(outputPath, ".json") {
 		format = "json"
 	}
 
-	err := validator.MergeSpecifications(specs, outputPath, format)
+	merged, err := validator.Merge(specs)
 	if err != nil {
 		fmt.Printf("\n❌ Merge failed: %v\n", err)
 		os.Exit(1)
 	}
 
+	if resolveRefs {
+		merged, err = ResolveRefs(merged)
+		if err != nil {
+			fmt.Printf("\n❌ Merge failed: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if inlineTemplates {
+		if err := validator.inlineTemplateFiles(merged); err != nil {
+			fmt.Printf("\n❌ Merge failed: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if envLookup != nil {
+		merged, _ = SubstituteEnv(merged, envLookup)
+	}
+
+	fmt.Println("\nValidating merged specification...")
+	isValid := validator.ValidateSpec(merged)
+	printValidationResult(validator.GetResults())
+
+	if !isValid && !force {
+		fmt.Println("\n❌ Merge failed: merged specification is invalid (use --force to write it anyway)")
+		os.Exit(1)
+	}
+
+	// Referenced files stay relative to the output
+	validator.relativeFileReferences(merged, filepath.Dir(outputPath))
+
+	if err := WriteSpecWithComments(merged, outputPath, format, commentTrees); err != nil {
+		fmt.Printf("\n❌ Merge failed: %v\n", err)
+		os.Exit(1)
+	}
+
+	if !isValid && validate {
+		fmt.Printf("\n❌ Merged specification written to %s but failed validation\n", outputPath)
+		os.Exit(1)
+	}
+
 	fmt.Println("\n✅ Merge completed successfully!")
 	fmt.Printf("Merged specification saved to: %s\n", outputPath)
+	fmt.Printf("Contents: %d models, %d prompts, %d tasks\n",
+		sectionLength(merged, "models"), sectionLength(merged, "prompts"), sectionLength(merged, "tasks"))
+}
+
+func handleGraph(options []string) {
+	if len(options) == 0 {
+		fmt.Println("Error: No file specified")
+		fmt.Println("Usage: go run cli.go graph <file> [--format dot|mermaid] [--hierarchical]")
+		os.Exit(1)
+	}
+
+	filePath := options[0]
+	format := "dot"
+	hierarchical := false
+	for i, opt := range options {
+		if opt == "--format" && i+1 < len(options) {
+			format = options[i+1]
+		}
+		if opt == "--hierarchical" {
+			hierarchical = true
+		}
+	}
+
+	if format != "dot" && format != "mermaid" {
+		fmt.Printf("Error: Unsupported graph format: %s\n", format)
+		os.Exit(1)
+	}
+
+	config, err := loadCLIConfig(options)
+	if err != nil {
+		fmt.Printf("❌ Configuration error: %v\n", err)
+		os.Exit(1)
+	}
+
+	validator := NewAPAIValidator(WithConfig(config))
+	spec, err := validator.loadSpec(filePath)
+	if err != nil {
+		fmt.Printf("❌ Error loading %s: %v\n", filePath, err)
+		os.Exit(1)
+	}
+
+	if hierarchical {
+		spec = validator.mergeInheritedSpecifications(spec, filePath)
+	}
+
+	graph := BuildGraph(spec)
+	if format == "mermaid" {
+		fmt.Print(graph.RenderMermaid())
+	} else {
+		fmt.Print(graph.RenderDOT())
+	}
+}
+
+func handleFingerprint(options []string) {
+	files := positionalArgs(options)
+	if len(files) != 1 {
+		fmt.Println("Error: No file specified")
+		fmt.Println("Usage: go run cli.go fingerprint <file> [--hierarchical]")
+		os.Exit(1)
+	}
+
+	fmt.Println(effectiveFingerprint(files[0], options))
+}
+
+// handleHash prints the hash of the effective specification, merged with
+// its parents, which is its hierarchical fingerprint
+func handleHash(options []string) {
+	files := positionalArgs(options)
+	if len(files) != 1 {
+		fmt.Println("Error: No file specified")
+		fmt.Println("Usage: go run cli.go hash <file>")
+		os.Exit(1)
+	}
+
+	fmt.Println(effectiveFingerprint(files[0], append([]string{"--hierarchical"}, options...)))
+}
+
+func handleVerify(options []string) {
+	files := positionalArgs(options)
+	expected := ""
+	for i, opt := range options {
+		if opt == "--fingerprint" && i+1 < len(options) {
+			expected = options[i+1]
+		}
+	}
+
+	if len(files) != 1 || expected == "" {
+		fmt.Println("Error: Missing required arguments")
+		fmt.Println("Usage: go run cli.go verify <file> --fingerprint <digest> [--hierarchical]")
+		os.Exit(1)
+	}
+
+	actual := effectiveFingerprint(files[0], options)
+	if !strings.EqualFold(actual, expected) {
+		fmt.Printf("❌ Fingerprint mismatch for %s\n", files[0])
+		fmt.Printf("  expected: %s\n", expected)
+		fmt.Printf("  actual:   %s\n", actual)
+		os.Exit(1)
+	}
+	fmt.Printf("✅ Fingerprint matches: %s\n", actual)
+}
+
+// effectiveFingerprint fingerprints a specification, merged with its
+// parents when --hierarchical is given, exiting on failure
+func effectiveFingerprint(filePath string, options []string) string {
+	config, err := loadCLIConfig(options)
+	if err != nil {
+		fmt.Printf("❌ Configuration error: %v\n", err)
+		os.Exit(1)
+	}
+
+	validator := NewAPAIValidator(WithConfig(config))
+	spec, err := validator.loadSpec(filePath)
+	if err != nil {
+		fmt.Printf("❌ Error loading %s: %v\n", filePath, err)
+		os.Exit(1)
+	}
+
+	if containsString(options, "--hierarchical") {
+		validator.rebaseFileReferences(spec, filePath)
+		spec = validator.mergeInheritedSpecifications(spec, filePath)
+		if len(validator.Errors) > 0 {
+			fmt.Printf("❌ Error resolving inherits of %s:\n", filePath)
+			for _, message := range validator.Errors {
+				fmt.Printf("  • %s\n", message)
+			}
+			os.Exit(1)
+		}
+
+		// File paths relative to the spec keep the digest independent of
+		// the working directory
+		validator.relativeFileReferences(spec, filepath.Dir(filePath))
+	}
+
+	fingerprint, err := Fingerprint(spec)
+	if err != nil {
+		fmt.Printf("❌ Error fingerprinting %s: %v\n", filePath, err)
+		os.Exit(1)
+	}
+	return fingerprint
+}
+
+// redactionBreakingCodes are the findings that show a redaction broke the
+// structure of a specification rather than just hiding values
+var redactionBreakingCodes = []string{"MISSING_SECTION", "MISSING_FIELD", "INVALID_TYPE", "DUPLICATE_ID", "UNKNOWN_REFERENCE"}
+
+func handleRedact(options []string) {
+	files := positionalArgs(options)
+	paths := make([]string, 0)
+	for i, opt := range options {
+		if opt == "--redact" && i+1 < len(options) {
+			paths = append(paths, strings.Split(options[i+1], ",")...)
+		}
+	}
+
+	if len(files) != 2 {
+		fmt.Println("Error: Missing required arguments")
+		fmt.Println("Usage: go run cli.go redact <input> <output> [--redact path1,path2]")
+		os.Exit(1)
+	}
+	inputPath, outputPath := files[0], files[1]
+
+	validator := NewAPAIValidator()
+	spec, err := validator.loadSpec(inputPath)
+	if err != nil {
+		fmt.Printf("❌ Error loading %s: %v\n", inputPath, err)
+		os.Exit(1)
+	}
+	validator.ValidateSpec(spec)
+	originalErrors := validator.Errors
+
+	redacted, locations := RedactSpec(spec, paths)
+
+	format := "yaml"
+	if strings.HasSuffix(outputPath, ".json") {
+		format = "json"
+	}
+	if err := WriteSpec(redacted, outputPath, format); err != nil {
+		fmt.Printf("❌ Redaction failed: %v\n", err)
+		os.Exit(1)
+	}
+	if _, err := validator.loadSpec(outputPath); err != nil {
+		fmt.Printf("❌ Redacted specification does not parse: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("✅ Redacted %d values: %s\n", len(locations), outputPath)
+	for _, location := range locations {
+		fmt.Printf("  • %s\n", location)
+	}
+
+	// Findings the redaction introduced are expected unless they break structure
+	validator.ValidateSpec(redacted)
+	broken := false
+	for _, message := range validator.Errors {
+		if containsString(originalErrors, message) {
+			continue
+		}
+		issue := validator.issue("error", message)
+		if containsString(redactionBreakingCodes, issue.Code) {
+			fmt.Printf("❌ Redaction broke the specification: %s [%s]\n", message, issue.Code)
+			broken = true
+			continue
+		}
+		fmt.Printf("⚠️  Caused by redaction: %s%s\n", message, codeSuffix(issue))
+	}
+	if broken {
+		os.Exit(1)
+	}
+}
+
+func handlePreflight(ctx context.Context, options []string) {
+	files := positionalArgs(options)
+	output := "text"
+	for i, opt := range options {
+		if opt == "--output" && i+1 < len(options) {
+			output = options[i+1]
+		}
+	}
+	if len(files) != 1 {
+		fmt.Println("Error: No file specified")
+		fmt.Println("Usage: go run cli.go preflight <file> [--output text|json]")
+		os.Exit(1)
+	}
+	if output != "text" && output != "json" {
+		fmt.Printf("Error: Unsupported preflight output: %s\n", output)
+		os.Exit(1)
+	}
+
+	config, err := loadCLIConfig(options)
+	if err != nil {
+		fmt.Printf("❌ Configuration error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Requirements come from the effective specification, parents included
+	validator := NewAPAIValidator(WithConfig(config))
+	spec := resolveEffectiveSpec(ctx, validator, files[0])
+
+	checks := validator.Preflight(spec)
+	missing := 0
+	for _, check := range checks {
+		if !check.Set {
+			missing++
+		}
+	}
+
+	if output == "json" {
+		content, err := json.MarshalIndent(checks, "", "  ")
+		if err != nil {
+			fmt.Printf("❌ Preflight failed: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(content))
+	} else {
+		for _, check := range checks {
+			if check.Set {
+				fmt.Printf("✅ %s (%s)\n", check.Variable, check.RequiredBy)
+			} else {
+				fmt.Printf("❌ %s is not set (%s)\n", check.Variable, check.RequiredBy)
+			}
+		}
+		if missing == 0 {
+			fmt.Printf("✅ Preflight passed: %d environment variables set\n", len(checks))
+		} else {
+			fmt.Printf("❌ Preflight failed: %d of %d environment variables missing\n", missing, len(checks))
+		}
+	}
+
+	if missing > 0 {
+		os.Exit(1)
+	}
+}
+
+func handleDeps(ctx context.Context, options []string) {
+	files := positionalArgs(options)
+	format := "text"
+	for i, opt := range options {
+		if opt == "--format" && i+1 < len(options) {
+			format = options[i+1]
+		}
+	}
+	if len(files) != 1 {
+		fmt.Println("Error: No file specified")
+		fmt.Println("Usage: go run cli.go deps <file> [--format text|json]")
+		os.Exit(1)
+	}
+	if format != "text" && format != "json" {
+		fmt.Printf("Error: Unsupported deps format: %s\n", format)
+		os.Exit(1)
+	}
+
+	config, err := loadCLIConfig(options)
+	if err != nil {
+		fmt.Printf("❌ Configuration error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Unresolvable parents are listed without a resolved file rather than
+	// failing, since audits want to see them
+	validator := NewAPAIValidator(WithConfig(config))
+	spec, err := validator.ResolveSpecContext(ctx, files[0])
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(exitInterrupted)
+		}
+		fmt.Printf("❌ Error loading %s: %v\n", files[0], err)
+		os.Exit(1)
+	}
+	dependencies := validator.Dependencies(spec, files[0])
+
+	if format == "json" {
+		content, err := json.MarshalIndent(dependencies, "", "  ")
+		if err != nil {
+			fmt.Printf("❌ Error listing dependencies: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(content))
+		return
+	}
+	for _, dependency := range dependencies {
+		location := dependency.Location
+		if dependency.Spec != "" {
+			location = dependency.Spec + " " + location
+		}
+		name := dependency.Name
+		if dependency.Resolved != "" && dependency.Resolved != dependency.Name {
+			name += " -> " + dependency.Resolved
+		}
+		fmt.Printf("%-15s %s (%s)\n", dependency.Type, name, location)
+	}
+	fmt.Printf("%d external dependencies\n", len(dependencies))
+}
+
+func handleCost(ctx context.Context, options []string) {
+	files := positionalArgs(options)
+	output := "text"
+	invocations := 1
+	for i, opt := range options {
+		if i+1 >= len(options) {
+			break
+		}
+		switch opt {
+		case "--output":
+			output = options[i+1]
+		case "--invocations":
+			value, err := strconv.Atoi(options[i+1])
+			if err != nil || value < 1 {
+				fmt.Printf("Error: --invocations must be a positive integer: %s\n", options[i+1])
+				os.Exit(1)
+			}
+			invocations = value
+		}
+	}
+	if len(files) != 1 {
+		fmt.Println("Error: No file specified")
+		fmt.Println("Usage: go run cli.go cost <file> [--invocations N] [--output text|json]")
+		os.Exit(1)
+	}
+	if output != "text" && output != "json" {
+		fmt.Printf("Error: Unsupported cost output: %s\n", output)
+		os.Exit(1)
+	}
+
+	config, err := loadCLIConfig(options)
+	if err != nil {
+		fmt.Printf("❌ Configuration error: %v\n", err)
+		os.Exit(1)
+	}
+
+	validator := NewAPAIValidator(WithConfig(config))
+	spec := resolveEffectiveSpec(ctx, validator, files[0])
+	if err := validator.inlineTemplateFiles(spec); err != nil {
+		fmt.Printf("❌ Cost estimation failed: %v\n", err)
+		os.Exit(1)
+	}
+
+	report, err := EstimateCost(spec, CostOptions{Invocations: invocations, Prices: config.Prices})
+	if err != nil {
+		fmt.Printf("❌ Cost estimation failed: %v\n", err)
+		os.Exit(1)
+	}
+
+	if output == "json" {
+		content, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			fmt.Printf("❌ Cost estimation failed: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(content))
+		return
+	}
+
+	fmt.Printf("💰 Estimated cost of %s for %d invocations (USD)\n", files[0], invocations)
+	for _, task := range report.Tasks {
+		fmt.Printf("  • %s: $%.4f - $%.4f", task.ID, task.Low, task.High)
+		if len(task.Unpriced) > 0 {
+			fmt.Printf(" (unpriced: %s)", strings.Join(task.Unpriced, ", "))
+		}
+		fmt.Println()
+	}
+	fmt.Printf("Total: $%.4f - $%.4f\n", report.Low, report.High)
+	if len(report.Unpriced) > 0 {
+		fmt.Printf("⚠️  Unpriced models, not counted: %s\n", strings.Join(report.Unpriced, ", "))
+	}
+}
+
+func handleStats(ctx context.Context, options []string) {
+	files := positionalArgs(options)
+	output := "text"
+	for i, opt := range options {
+		if opt == "--output" && i+1 < len(options) {
+			output = options[i+1]
+		}
+	}
+	if len(files) != 1 {
+		fmt.Println("Error: No file specified")
+		fmt.Println("Usage: go run cli.go stats <file> [--output text|json]")
+		os.Exit(1)
+	}
+	if output != "text" && output != "json" {
+		fmt.Printf("Error: Unsupported stats output: %s\n", output)
+		os.Exit(1)
+	}
+
+	config, err := loadCLIConfig(options)
+	if err != nil {
+		fmt.Printf("❌ Configuration error: %v\n", err)
+		os.Exit(1)
+	}
+
+	validator := NewAPAIValidator(WithConfig(config))
+	spec := resolveEffectiveSpec(ctx, validator, files[0])
+	complexity := validator.measureComplexity(spec, validator.inheritance.depth)
+
+	if output == "json" {
+		content, err := json.MarshalIndent(complexity, "", "  ")
+		if err != nil {
+			fmt.Printf("❌ Stats failed: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(content))
+		return
+	}
+
+	named := func(value int, task string) string {
+		if task == "" {
+			return fmt.Sprint(value)
+		}
+		return fmt.Sprintf("%d (%s)", value, task)
+	}
+	fmt.Printf("📊 Complexity of %s\n", files[0])
+	for _, section := range complexitySections {
+		fmt.Printf("  • %s: %d\n", section, complexity.Sections[section])
+	}
+	fmt.Printf("  • max steps per task: %s\n", named(complexity.MaxStepsPerTask, complexity.LongestTask))
+	fmt.Printf("  • inheritance depth: %d\n", complexity.InheritanceDepth)
+	fmt.Printf("  • estimated prompt tokens: %d\n", complexity.PromptTokens)
+	fmt.Printf("  • branching factor: %s\n", named(complexity.BranchingFactor, complexity.WidestTask))
+
+	excesses := config.Budget.exceeded(complexity)
+	if len(excesses) == 0 {
+		fmt.Println("✅ Within the complexity budget")
+		return
+	}
+	fmt.Println("⚠️  Over the complexity budget:")
+	for _, excess := range excesses {
+		fmt.Printf("  • %s is %d, over %s of %d by %d\n", excess.metric, excess.value, excess.threshold, excess.limit, excess.value-excess.limit)
+	}
+}
+
+// exportTargets lists the runtimes export renders a task for
+var exportTargets = []string{"openai", "anthropic"}
+
+func handleExport(ctx context.Context, options []string) {
+	args := positionalArgs(options)
+	taskID, outputPath := "", ""
+	forceProvider := containsString(options, "--force-provider")
+	for i, opt := range options {
+		if i+1 >= len(options) {
+			break
+		}
+		switch opt {
+		case "--task":
+			taskID = options[i+1]
+		case "--out":
+			outputPath = options[i+1]
+		}
+	}
+	if len(args) != 2 {
+		fmt.Println("Error: Export requires a target and a file")
+		fmt.Println("Usage: go run cli.go export <openai|anthropic> <file> [--task <id>] [--out <file>] [--force-provider]")
+		os.Exit(1)
+	}
+	target, file := args[0], args[1]
+	if !containsString(exportTargets, target) {
+		fmt.Printf("Error: Unsupported export target: %s (available: %s)\n", target, strings.Join(exportTargets, ", "))
+		os.Exit(1)
+	}
+
+	config, err := loadCLIConfig(options)
+	if err != nil {
+		fmt.Printf("❌ Configuration error: %v\n", err)
+		os.Exit(1)
+	}
+
+	validator := NewAPAIValidator(WithConfig(config))
+	spec := resolveEffectiveSpec(ctx, validator, file)
+	if spec, err = ResolveRefs(spec); err == nil {
+		err = validator.inlineTemplateFiles(spec)
+	}
+	if err != nil {
+		fmt.Printf("❌ Export failed: %v\n", err)
+		os.Exit(1)
+	}
+
+	plan, err := BuildExportPlan(spec, taskID)
+	if err != nil {
+		fmt.Printf("❌ Export failed: %v\n", err)
+		os.Exit(1)
+	}
+	var rendered interface{}
+	var warnings []string
+	switch target {
+	case "openai":
+		rendered, warnings = RenderOpenAIAssistant(plan)
+	case "anthropic":
+		rendered, warnings, err = RenderAnthropicMessages(plan, forceProvider)
+	}
+	if err != nil {
+		fmt.Printf("❌ Export failed: %v\n", err)
+		os.Exit(1)
+	}
+
+	content, err := json.MarshalIndent(rendered, "", "  ")
+	if err != nil {
+		fmt.Printf("❌ Export failed: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Without --out the export goes to stdout, so warnings go to stderr to
+	// keep it parseable
+	messages := os.Stdout
+	if outputPath == "" {
+		fmt.Println(string(content))
+		messages = os.Stderr
+	} else if err := os.WriteFile(outputPath, append(content, '\n'), 0644); err != nil {
+		fmt.Printf("❌ Error writing output file: %v\n", err)
+		os.Exit(1)
+	} else {
+		fmt.Printf("✅ Exported task %s of %s to %s\n", plan.TaskID, file, outputPath)
+	}
+	for _, warning := range warnings {
+		fmt.Fprintf(messages, "⚠️  %s\n", warning)
+	}
+}
+
+func handleGenerate(ctx context.Context, options []string) {
+	args := positionalArgs(options)
+	pkg, outputPath := "aispec", ""
+	for i, opt := range options {
+		if i+1 >= len(options) {
+			break
+		}
+		switch opt {
+		case "--package":
+			pkg = options[i+1]
+		case "--out":
+			outputPath = options[i+1]
+		}
+	}
+	if len(args) != 2 {
+		fmt.Println("Error: Generate requires a language and a file")
+		fmt.Println("Usage: go run cli.go generate go <file> [--package <name>] [--out <file>]")
+		os.Exit(1)
+	}
+	language, file := args[0], args[1]
+	if language != "go" {
+		fmt.Printf("Error: Unsupported generate language: %s (available: go)\n", language)
+		os.Exit(1)
+	}
+
+	config, err := loadCLIConfig(options)
+	if err != nil {
+		fmt.Printf("❌ Configuration error: %v\n", err)
+		os.Exit(1)
+	}
+
+	validator := NewAPAIValidator(WithConfig(config))
+	spec := resolveEffectiveSpec(ctx, validator, file)
+	if spec, err = ResolveRefs(spec); err == nil {
+		err = validator.inlineTemplateFiles(spec)
+	}
+	var content []byte
+	if err == nil {
+		content, err = GenerateGo(spec, pkg, filepath.Base(file))
+	}
+	if err != nil {
+		fmt.Printf("❌ Generation failed: %v\n", err)
+		os.Exit(1)
+	}
+
+	if outputPath == "" {
+		fmt.Print(string(content))
+		return
+	}
+	if err := os.WriteFile(outputPath, content, 0644); err != nil {
+		fmt.Printf("❌ Error writing output file: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✅ Generated %s from %s\n", outputPath, file)
+}
+
+// resolveEffectiveSpec loads a specification merged with its inherited
+// parents, exiting when it cannot be resolved
+func resolveEffectiveSpec(ctx context.Context, validator *APAIValidator, filePath string) map[string]interface{} {
+	spec, err := validator.ResolveSpecContext(ctx, filePath)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(exitInterrupted)
+		}
+		fmt.Printf("❌ Error loading %s: %v\n", filePath, err)
+		os.Exit(1)
+	}
+	if len(validator.Errors) > 0 {
+		fmt.Printf("❌ Error resolving inherits of %s:\n", filePath)
+		for _, message := range validator.Errors {
+			fmt.Printf("  • %s\n", message)
+		}
+		os.Exit(1)
+	}
+	return spec
+}
+
+// importSources lists the formats import converts into APAI specifications
+var importSources = []string{"openai-tools"}
+
+func handleImport(options []string) {
+	args := positionalArgs(options)
+	specPath, serverID := "", ""
+	for i, opt := range options {
+		if i+1 >= len(options) {
+			break
+		}
+		switch opt {
+		case "--into":
+			specPath = options[i+1]
+		case "--server":
+			serverID = options[i+1]
+		}
+	}
+	if len(args) != 2 || !containsString(importSources, args[0]) || specPath == "" {
+		fmt.Println("Error: Missing required arguments")
+		fmt.Printf("Usage: go run cli.go import <%s> <tools.json> --into <spec> [--server <id>] [--overwrite] [--dry-run]\n", strings.Join(importSources, "|"))
+		os.Exit(1)
+	}
+
+	content, err := os.ReadFile(args[1])
+	if err != nil {
+		fmt.Printf("❌ Error loading %s: %v\n", args[1], err)
+		os.Exit(1)
+	}
+	tools, err := ParseOpenAITools(content)
+	if err != nil {
+		fmt.Printf("❌ Error loading %s: %v\n", args[1], err)
+		os.Exit(1)
+	}
+
+	config, err := loadCLIConfig(options)
+	if err != nil {
+		fmt.Printf("❌ Configuration error: %v\n", err)
+		os.Exit(1)
+	}
+	validator := NewAPAIValidator(WithConfig(config))
+	spec, err := validator.loadSpec(specPath)
+	if err != nil {
+		fmt.Printf("❌ Error loading %s: %v\n", specPath, err)
+		os.Exit(1)
+	}
+	commentTree, err := validator.loadCommentTree(specPath)
+	if err != nil {
+		fmt.Printf("❌ Error loading %s: %v\n", specPath, err)
+		os.Exit(1)
+	}
+
+	imported, changes, conflicts := ImportOpenAITools(spec, tools, serverID, containsString(options, "--overwrite"))
+	for _, conflict := range conflicts {
+		fmt.Printf("⚠️  %s\n", conflict)
+	}
+
+	if len(changes) == 0 {
+		fmt.Printf("✅ No tools imported into %s\n", specPath)
+		return
+	}
+
+	// The import must not introduce errors; ones the spec had are kept
+	validator.ValidateSpec(spec)
+	before := validator.GetErrors()
+	validator.ValidateSpec(imported)
+	introduced := make([]string, 0)
+	for _, message := range validator.GetErrors() {
+		if !containsString(before, message) {
+			introduced = append(introduced, message)
+		}
+	}
+	if len(introduced) > 0 {
+		fmt.Printf("❌ Import failed: the result does not validate\n")
+		for _, message := range introduced {
+			fmt.Printf("  • %s\n", message)
+		}
+		os.Exit(1)
+	}
+
+	render := func(document map[string]interface{}) []byte {
+		var rendered []byte
+		if strings.HasSuffix(specPath, ".json") {
+			rendered, err = MarshalCanonicalJSON(document)
+		} else {
+			rendered, err = MarshalYAMLWithComments(document, []*yaml.Node{commentTree})
+		}
+		if err != nil {
+			fmt.Printf("❌ Import failed: %v\n", err)
+			os.Exit(1)
+		}
+		return rendered
+	}
+	output := render(imported)
+
+	if containsString(options, "--dry-run") {
+		os.Stdout.WriteString(UnifiedDiff(specPath, specPath, render(spec), output))
+		return
+	}
+	if err := os.WriteFile(specPath, output, 0644); err != nil {
+		fmt.Printf("❌ Import failed: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✅ Imported %d of %d tools into %s\n", len(tools)-len(conflicts), len(tools), specPath)
+	for _, change := range changes {
+		fmt.Printf("  • %s\n", change)
+	}
+}
+
+func handleFix(options []string) {
+	files := positionalArgs(options)
+	outputPath := ""
+	for i, opt := range options {
+		if opt == "--output" && i+1 < len(options) {
+			outputPath = options[i+1]
+		}
+	}
+	if len(files) != 1 {
+		fmt.Println("Error: No file specified")
+		fmt.Println("Usage: go run cli.go fix <file> [--output <file>]")
+		os.Exit(1)
+	}
+
+	spec, err := NewAPAIValidator().loadSpec(files[0])
+	if err != nil {
+		fmt.Printf("❌ Error loading %s: %v\n", files[0], err)
+		os.Exit(1)
+	}
+
+	fixed, changes := FixEnumCasing(spec)
+	fixed, renames := FixDeprecatedFields(fixed)
+	changes = append(changes, renames...)
+
+	if outputPath == "" {
+		content, err := MarshalCanonicalYAML(fixed)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Fix failed: %v\n", err)
+			os.Exit(1)
+		}
+		for _, change := range changes {
+			fmt.Fprintf(os.Stderr, "  • %s\n", change)
+		}
+		os.Stdout.Write(content)
+		return
+	}
+
+	format := "yaml"
+	if strings.HasSuffix(outputPath, ".json") {
+		format = "json"
+	}
+	if err := WriteSpec(fixed, outputPath, format); err != nil {
+		fmt.Printf("❌ Fix failed: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("✅ Fixed %s: %s (%d changes)\n", files[0], outputPath, len(changes))
+	for _, change := range changes {
+		fmt.Printf("  • %s\n", change)
+	}
+}
+
+func handleMigrate(options []string) {
+	files := positionalArgs(options)
+	target, outputPath := "", ""
+	for i, opt := range options {
+		if i+1 >= len(options) {
+			break
+		}
+		switch opt {
+		case "--to":
+			target = options[i+1]
+		case "--output":
+			outputPath = options[i+1]
+		}
+	}
+
+	if len(files) != 1 || target == "" {
+		fmt.Println("Error: Missing required arguments")
+		fmt.Println("Usage: go run cli.go migrate <file> --to <version> [--output <file>]")
+		os.Exit(1)
+	}
+	if _, _, ok := parseSemver(target); !ok {
+		fmt.Printf("Error: Invalid target version: %s\n", target)
+		os.Exit(1)
+	}
+
+	spec, err := NewAPAIValidator().loadSpec(files[0])
+	if err != nil {
+		fmt.Printf("❌ Error loading %s: %v\n", files[0], err)
+		os.Exit(1)
+	}
+
+	migrated, changes := MigrateSpec(spec, target)
+
+	if outputPath == "" {
+		content, err := MarshalCanonicalYAML(migrated)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Migration failed: %v\n", err)
+			os.Exit(1)
+		}
+		for _, change := range changes {
+			fmt.Fprintf(os.Stderr, "  • %s\n", change)
+		}
+		os.Stdout.Write(content)
+		return
+	}
+
+	format := "yaml"
+	if strings.HasSuffix(outputPath, ".json") {
+		format = "json"
+	}
+	if err := WriteSpec(migrated, outputPath, format); err != nil {
+		fmt.Printf("❌ Migration failed: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("✅ Migrated %s to %s: %s\n", files[0], target, outputPath)
+	for _, change := range changes {
+		fmt.Printf("  • %s\n", change)
+	}
+}
+
+func handleNormalize(ctx context.Context, options []string) {
+	files := positionalArgs(options)
+	outputPath := ""
+	for i, opt := range options {
+		if opt == "--output" && i+1 < len(options) {
+			outputPath = options[i+1]
+		}
+	}
+	if len(files) != 1 {
+		fmt.Println("Error: No file specified")
+		fmt.Println("Usage: go run cli.go normalize <file> [--output <file>]")
+		os.Exit(1)
+	}
+
+	// Defaults fill the effective specification, parents included
+	validator := NewAPAIValidator()
+	spec := resolveEffectiveSpec(ctx, validator, files[0])
+	normalized, filled := normalizeSpec(spec)
+
+	if outputPath == "" {
+		content, err := MarshalCanonicalYAML(normalized)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Normalization failed: %v\n", err)
+			os.Exit(1)
+		}
+		for _, location := range filled {
+			fmt.Fprintf(os.Stderr, "  • %s\n", location)
+		}
+		os.Stdout.Write(content)
+		return
+	}
+
+	format := "yaml"
+	if strings.HasSuffix(outputPath, ".json") {
+		format = "json"
+	}
+	if err := WriteSpec(normalized, outputPath, format); err != nil {
+		fmt.Printf("❌ Normalization failed: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("✅ Filled %d defaults: %s\n", len(filled), outputPath)
+	for _, location := range filled {
+		fmt.Printf("  • %s\n", location)
+	}
+}
+
+func handleServe(ctx context.Context, options []string) {
+	addr, grpcAddr, root := ":8080", "", "."
+	plugins := make([]string, 0)
+	for i, opt := range options {
+		if i+1 >= len(options) {
+			continue
+		}
+		switch opt {
+		case "--addr":
+			addr = options[i+1]
+		case "--grpc":
+			grpcAddr = options[i+1]
+		case "--grpc-root":
+			root = options[i+1]
+		case "--plugin":
+			plugins = append(plugins, options[i+1])
+		}
+	}
+	// With --grpc, HTTP is served only when --addr is given too
+	serveHTTP := grpcAddr == "" || containsString(options, "--addr")
+
+	// The servers are live while the configuration and schemas load, and
+	// ready once the validator using them is set
+	server := NewServer(nil)
+	listen := func(address string) net.Listener {
+		listener, err := net.Listen("tcp", address)
+		if err != nil {
+			fmt.Printf("❌ Server error: %v\n", err)
+			os.Exit(1)
+		}
+		return listener
+	}
+	served := make(chan error, 2)
+	running := 0
+	endpoints := make([]string, 0, 2)
+
+	var httpServer *http.Server
+	if serveHTTP {
+		httpServer = &http.Server{
+			Addr:              addr,
+			Handler:           server.Handler(),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		listener := listen(addr)
+		go func() {
+			served <- httpServer.Serve(listener)
+		}()
+		running++
+		endpoints = append(endpoints, fmt.Sprintf("HTTP on %s (POST /validate, POST /merge, GET /metrics, GET /healthz, GET /readyz)", addr))
+	}
+	var grpcServer *grpc.Server
+	if grpcAddr != "" {
+		grpcServer = grpc.NewServer()
+		server.RegisterGRPC(grpcServer, os.DirFS(root))
+		listener := listen(grpcAddr)
+		go func() {
+			served <- grpcServer.Serve(listener)
+		}()
+		running++
+		endpoints = append(endpoints, fmt.Sprintf("gRPC on %s (apai.v1.Validator, files under %s)", grpcAddr, root))
+	}
+
+	// Ctrl-C stops accepting requests and lets those in flight finish
+	go func() {
+		<-ctx.Done()
+		if httpServer != nil {
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			httpServer.Shutdown(shutdownCtx)
+		}
+		if grpcServer != nil {
+			grpcServer.GracefulStop()
+		}
+	}()
+
+	config, err := loadCLIConfig(options)
+	if err != nil {
+		fmt.Printf("❌ Configuration error: %v\n", err)
+		os.Exit(1)
+	}
+	profiles, err := loadCLICompliance(options)
+	if err != nil {
+		fmt.Printf("❌ Configuration error: %v\n", err)
+		os.Exit(1)
+	}
+	schemas, err := loadCLISchemas(options)
+	if err != nil {
+		fmt.Printf("❌ Configuration error: %v\n", err)
+		os.Exit(1)
+	}
+	approvedModels, err := loadCLIApprovedModels(ctx, options, config)
+	if err != nil {
+		fmt.Printf("❌ Configuration error: %v\n", err)
+		os.Exit(1)
+	}
+
+	server.SetValidator(NewAPAIValidator(WithConfig(config), WithComplianceProfiles(profiles...), WithPlugins(plugins...), WithJSONSchemas(schemas...), WithApprovedModels(approvedModels)))
+
+	fmt.Printf("Serving APAI validation over %s\n", strings.Join(endpoints, " and "))
+	for ; running > 0; running-- {
+		if err := <-served; err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Printf("❌ Server error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	fmt.Println("Server stopped")
+}
+
+func handleTest(ctx context.Context, options []string) {
+	positional := positionalArgs(options)
+	if len(positional) != 1 {
+		fmt.Println("Error: No corpus directory specified")
+		fmt.Println("Usage: go run cli.go test <dir> [--update] [--hierarchical] [--config <file>]")
+		os.Exit(1)
+	}
+	dir := positional[0]
+	update := containsString(options, "--update")
+
+	config, err := loadCLIConfig(options)
+	if err != nil {
+		fmt.Printf("❌ Configuration error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Testing corpus %s against %s\n", dir, CorpusManifest)
+	fmt.Println(strings.Repeat("-", 60))
+
+	validator := NewAPAIValidator(WithConfig(config))
+	results, err := validator.RunCorpus(ctx, dir, containsString(options, "--hierarchical"))
+	if errors.Is(err, context.Canceled) {
+		fmt.Println("\n⚠️  Interrupted")
+		os.Exit(exitInterrupted)
+	}
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(1)
+	}
+
+	failed := 0
+	expectations := make(map[string]CorpusExpectation)
+	for _, result := range results {
+		if result.Found {
+			expectations[result.Path] = result.Actual
+		}
+		if result.Passed() {
+			fmt.Printf("✅ %s\n", result.Path)
+			continue
+		}
+		failed++
+		switch {
+		case update:
+			fmt.Printf("📝 %s\n", result.Path)
+		case !result.Found:
+			fmt.Printf("❌ %s: listed in %s but not found\n", result.Path, CorpusManifest)
+			continue
+		case !result.Listed:
+			fmt.Printf("❌ %s: not listed in %s (run with --update to add it)\n", result.Path, CorpusManifest)
+		default:
+			fmt.Printf("❌ %s\n", result.Path)
+		}
+		if result.Err != nil {
+			fmt.Printf("   %v\n", result.Err)
+		}
+		if result.Found {
+			fmt.Print(result.Diff())
+		}
+	}
+
+	if update {
+		manifestPath := filepath.Join(dir, CorpusManifest)
+		if err := WriteCorpusManifest(manifestPath, expectations); err != nil {
+			fmt.Printf("❌ %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("\n📝 Wrote %d expectations to %s, %d changed\n", len(expectations), manifestPath, failed)
+		return
+	}
+	fmt.Printf("\nTested %d specifications, %d failed\n", len(results), failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+func handleExplain(options []string) {
+	if len(options) == 0 {
+		fmt.Println("Error: No code specified")
+		fmt.Println("Usage: go run cli.go explain <CODE>")
+		os.Exit(1)
+	}
+
+	rule, ok := LookupRule(options[0])
+	if !ok {
+		fmt.Printf("Unknown code: %s\n", options[0])
+		fmt.Printf("Known codes: %s\n", strings.Join(ruleCodes(), ", "))
+		os.Exit(1)
+	}
+
+	fmt.Printf("%s (%s)\n", rule.Code, rule.Severity)
+	fmt.Println(rule.Summary)
+	fmt.Println("\nWhy it matters:")
+	fmt.Printf("  %s\n", rule.Rationale)
+	fmt.Println("\nHow to fix:")
+	for _, line := range strings.Split(rule.Remediation, "\n") {
+		fmt.Printf("  %s\n", line)
+	}
+}
+
+// lintResult is the style findings of one file in lint JSON output
+type lintResult struct {
+	Path     string    `json:"path"`
+	Findings []Finding `json:"findings"`
+	Error    string    `json:"error,omitempty"`
+}
+
+func handleLint(options []string) {
+	files := positionalArgs(options)
+	output := "text"
+	for i, opt := range options {
+		if opt == "--output" && i+1 < len(options) {
+			output = options[i+1]
+		}
+	}
+	if len(files) == 0 {
+		fmt.Println("Error: No files specified")
+		fmt.Println("Usage: go run cli.go lint <files...> [--output text|json]")
+		os.Exit(1)
+	}
+	if output != "text" && output != "json" {
+		fmt.Printf("Error: Unsupported lint output: %s\n", output)
+		os.Exit(1)
+	}
+
+	results := make([]lintResult, 0, len(files))
+	failed := false
+	for _, file := range files {
+		result := lintResult{Path: file, Findings: make([]Finding, 0)}
+		content, err := ioutil.ReadFile(file)
+		if err == nil {
+			result.Findings, err = LintYAML(content)
+		}
+		if err != nil {
+			result.Error = err.Error()
+		}
+		failed = failed || len(result.Findings) > 0 || result.Error != ""
+		results = append(results, result)
+	}
+
+	if output == "json" {
+		content, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			fmt.Printf("❌ Lint failed: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(content))
+	} else {
+		for _, result := range results {
+			for _, finding := range result.Findings {
+				fmt.Printf("%s:%s\n", result.Path, finding)
+			}
+			if result.Error != "" {
+				fmt.Printf("❌ %s: %s\n", result.Path, result.Error)
+			} else if len(result.Findings) == 0 {
+				fmt.Printf("✅ %s: no style issues\n", result.Path)
+			}
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func handleCache(options []string) {
+	args := positionalArgs(options)
+	output := "text"
+	for i, opt := range options {
+		if opt == "--output" && i+1 < len(options) {
+			output = options[i+1]
+		}
+	}
+	if len(args) != 1 || (args[0] != "clean" && args[0] != "stats") {
+		fmt.Println("Error: Missing required arguments")
+		fmt.Println("Usage: go run cli.go cache clean|stats [--cache-dir <dir>] [--output text|json]")
+		os.Exit(1)
+	}
+	if output != "text" && output != "json" {
+		fmt.Printf("Error: Unsupported cache output: %s\n", output)
+		os.Exit(1)
+	}
+
+	config, err := loadCLIConfig(options)
+	if err != nil {
+		fmt.Printf("❌ Configuration error: %v\n", err)
+		os.Exit(1)
+	}
+	cache, err := NewSpecCache(config.CacheDir)
+	if err != nil {
+		fmt.Printf("❌ Cache error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if args[0] == "clean" {
+		removed, err := cache.Clean()
+		if err != nil {
+			fmt.Printf("❌ Cache error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✅ Removed %d cache entries from %s\n", removed, cache.Dir())
+		return
+	}
+
+	stats, err := cache.Stats()
+	if err != nil {
+		fmt.Printf("❌ Cache error: %v\n", err)
+		os.Exit(1)
+	}
+	if output == "json" {
+		content, err := json.MarshalIndent(stats, "", "  ")
+		if err != nil {
+			fmt.Printf("❌ Cache error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(content))
+		return
+	}
+	fmt.Printf("📦 Cache: %s\n", stats.Dir)
+	for _, kind := range specCacheKinds {
+		fmt.Printf("  %s: %d entries\n", kind, stats.Entries[kind])
+	}
+	fmt.Printf("  size: %.1f KiB\n", float64(stats.Bytes)/1024)
+}
+
+func handleRules(options []string) {
+	format := "text"
+	for i, opt := range options {
+		if opt == "--format" && i+1 < len(options) {
+			format = options[i+1]
+		}
+	}
+
+	switch format {
+	case "json":
+		content, err := json.MarshalIndent(rules, "", "  ")
+		if err != nil {
+			fmt.Printf("❌ Listing rules failed: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(content))
+	case "text":
+		width := 0
+		for _, rule := range rules {
+			if len(rule.Code) > width {
+				width = len(rule.Code)
+			}
+		}
+		for _, rule := range rules {
+			fmt.Printf("%-*s  %-7s  %s\n", width, rule.Code, rule.Severity, rule.Summary)
+		}
+	default:
+		fmt.Printf("Error: Unsupported rules format: %s\n", format)
+		os.Exit(1)
+	}
 }
 
 func showHelp() {
@@ -169,21 +2173,109 @@ func showHelp() {
 	fmt.Println("")
 	
 	fmt.Println("COMMANDS:")
-	fmt.Println("  validate <file> [--hierarchical]  Validate APAI specification")
-	fmt.Println("  tree <file>                       Show hierarchy tree for specification")
-	fmt.Println("  merge <output> <files...>         Merge multiple specifications")
+	fmt.Println("  validate <files...> [options]     Validate APAI specifications")
+	fmt.Println("  tree <file> [--format text|json]  Show hierarchy tree for specification")
+	fmt.Println("  merge <output> <files...> [--force]  Merge and validate multiple specifications")
+	fmt.Println("  graph <file> [--format dot|mermaid]  Export task references as a graph")
+	fmt.Println("  migrate <file> --to <version>     Rewrite deprecated fields for a schema version")
+	fmt.Println("  normalize <file> [--output <file>]  Write the effective spec with defaults filled in")
+	fmt.Println("  fix <file> [--output <file>]      Rewrite enum casing and deprecated fields")
+	fmt.Println("  fingerprint <file>                Print the SHA-256 digest of the effective specification")
+	fmt.Println("  verify <file> --fingerprint <digest>  Exit non-zero when the fingerprint differs")
+	fmt.Println("  hash <file>                       Print the content hash of the spec merged with its parents")
+	fmt.Println("  redact <input> <output> [--redact paths]  Write a copy with secrets and selected values redacted")
+	fmt.Println("  preflight <file> [--output json]  Check the environment variables of providers and MCP servers")
+	fmt.Println("  cost <file> [--invocations N]     Estimate the cost of running the tasks")
+	fmt.Println("  stats <file> [--output text|json]  Show complexity metrics and the budget they exceed")
+	fmt.Println("  deps <file> [--format text|json]  List the providers, servers, sources and files a spec depends on")
+	fmt.Println("  export openai|anthropic <file> [--task <id>] [--out <file>]  Export a task for a model runtime")
+	fmt.Println("  generate go <file> [--package <name>] [--out <file>]  Generate typed ids and accessors for a spec")
+	fmt.Println("  import openai-tools <tools.json> --into <spec>  Add OpenAI function definitions as MCP tools and tasks")
+	fmt.Println("  lint <files...> [--output text|json]  Report tabs, indentation, trailing whitespace and section order")
+	fmt.Println("  explain <CODE>                    Describe an error code and how to fix it")
+	fmt.Println("  rules [--format text|json]        List every rule with its code, severity and summary")
+	fmt.Println("  cache clean|stats [--cache-dir <dir>]  Remove or count the entries of the spec cache")
+	fmt.Println("  serve [--addr :8080] [--grpc :9090]  Serve validation over HTTP, with /metrics and /readyz, and over gRPC")
+	fmt.Println("  test <dir> [--update]             Compare the findings of a corpus of specs with its expectations.yaml")
 	fmt.Println("")
 	
 	fmt.Println("OPTIONS:")
 	fmt.Println("  --hierarchical                   Use hierarchical validation with inheritance")
+	fmt.Println("  --validate-parents               With --hierarchical, also validate each inherited file on its own")
+	fmt.Println("  --input-format yaml|json         Parse validated files as this format whatever their extension; - reads stdin")
+	fmt.Println("  --errors-only                    Leave warnings out of the output; they still count for --fail-on")
+	fmt.Println("  --first-only                     Validate only the first document of multi-document YAML files")
+	fmt.Println("  --stream                         Validate JSON files an element at a time, for large specs")
+	fmt.Println("  --compliance <profiles>          Enforce built-in compliance profiles, e.g. eu-ai-act")
+	fmt.Println("  --compliance-file <file>         Enforce a custom compliance profile")
+	fmt.Println("  --plugin <executable>            Run an external rule plugin (repeatable)")
+	fmt.Println("  --schema <file>                  Also validate against a custom JSON Schema (repeatable)")
+	fmt.Println("  --approved-models <url|file>     Reject models missing from an approved models list")
+	fmt.Println("  --no-remote                      Use the cached copy of a remote approved models list only")
+	fmt.Println("  --workspace <dir|file>           Resolve spec-id#entity-id references across the specs of a workspace")
+	fmt.Println("  --substitute-env                 Replace ${VAR} placeholders with environment values before validating")
+	fmt.Println("  --env-file <file>                Also take variables from a .env file (implies --substitute-env)")
+	fmt.Println("  --allow-missing-env              Warn instead of failing on unresolved ${VAR} placeholders")
+	fmt.Println("  --root <entry>                   Entrypoint of a .zip bundle (default: all roots)")
+	fmt.Println("  --force                          Merge non-APAI fragments and write invalid results")
+	fmt.Println("  --force-provider                 Export a model another provider serves, with a warning")
+	fmt.Println("  --validate                       Exit non-zero when the merged result is invalid, even with --force")
+	fmt.Println("  --resolve-refs                   Replace internal $ref pointers with their values in merge output")
+	fmt.Println("  --inline-templates               Replace prompt template_file references with their content in merge output")
+	fmt.Println("  --server <id>                    MCP server imported tools are added to (default: openai-tools)")
+	fmt.Println("  --overwrite                      Replace tasks with the name of an imported tool instead of skipping it")
+	fmt.Println("  --dry-run                        Print the changes import would make as a diff")
+	fmt.Println("  --update                         Rewrite the expectations of a test corpus from the current findings")
+	fmt.Println("  --fail-on error|warning|never    Findings that make validate exit non-zero (default: error)")
+	fmt.Println("  --strict-fields                  Report fields the specification does not define, except x- extensions")
+	fmt.Println("  --relax CODE[,CODE...]           Report the errors with these codes as warnings")
+	fmt.Println("  --warn-on CODE                   Report the errors with this code as warnings, like --relax; repeatable")
+	fmt.Println("  --error-on CODE                  Report the warnings with this code as errors; repeatable")
+	fmt.Println("  --check-files                    Check that referenced datasets and knowledge sources exist")
+	fmt.Println("  --check-urls                     With --check-files, also send a HEAD request to URL sources")
+	fmt.Println("  --changed                        Validate only specs changed in the working tree, untracked ones included, or inheriting from them")
+	fmt.Println("  --since <ref>                    Validate only specs changed since a git ref, or inheriting from changed files")
+	fmt.Println("  --group-by section|code          Print the findings of validate grouped, with counts")
+	fmt.Println("  --baseline <file>                Suppress the findings recorded in a baseline")
+	fmt.Println("  --write-baseline <file>          Record the current findings as a baseline")
+	fmt.Println("  --addr <address>                 Address serve listens on (default: :8080)")
+	fmt.Println("  --grpc <address>                 Address serve runs the gRPC service on, without HTTP unless --addr is given")
+	fmt.Println("  --grpc-root <dir>                Directory the gRPC ResolveSpec and GetStats read files from (default: .)")
+	fmt.Println("  --config <file>                  Load settings from file (default: .apai.yaml)")
+	fmt.Println("  --spec-root <dir>                Registry root for symbolic inherits (repeatable)")
+	fmt.Println("  --cache                          Keep parsed and merged specs in the user cache directory")
+	fmt.Println("  --cache-dir <dir>                Keep parsed and merged specs in dir (implies --cache)")
+	fmt.Println("  --max-inheritance-depth <n>      Maximum levels of inherited specs (default: 10)")
+	fmt.Println("  --max-inherited-specs <n>        Maximum number of inherited specs (default: 100)")
 	fmt.Println("  -h, --help                       Show this help message")
 	fmt.Println("")
 	
 	fmt.Println("EXAMPLES:")
 	fmt.Println("  go run cli.go validate spec.yaml")
 	fmt.Println("  go run cli.go validate spec.yaml --hierarchical")
+	fmt.Println("  go run cli.go validate specs/*.yaml")
+	fmt.Println("  go run cli.go validate bundle.zip --root specs/app.yaml")
+	fmt.Println("  cat spec.txt | go run cli.go validate - --input-format yaml")
+	fmt.Println("  go run cli.go validate specs --since main --hierarchical")
+	fmt.Println("  go run cli.go validate spec.yaml --env-file .env.production")
+	fmt.Println("  go run cli.go validate spec.yaml --group-by code")
+	fmt.Println("  go run cli.go validate specs --hierarchical --cache-dir .apai-cache")
+	fmt.Println("  go run cli.go validate large-spec.json --stream")
 	fmt.Println("  go run cli.go tree spec.yaml")
 	fmt.Println("  go run cli.go merge output.yaml spec1.yaml spec2.yaml")
+	fmt.Println("  go run cli.go graph spec.yaml --format mermaid")
+	fmt.Println("  go run cli.go migrate spec.yaml --to 0.2.0 --output spec-0.2.yaml")
+	fmt.Println("  go run cli.go normalize spec.yaml --output spec.normalized.yaml")
+	fmt.Println("  go run cli.go fingerprint spec.yaml --hierarchical")
+	fmt.Println("  go run cli.go export openai spec.yaml --task summarize --out assistant.json")
+	fmt.Println("  go run cli.go generate go spec.yaml --package aispec --out zz_generated.go")
+	fmt.Println("  go run cli.go import openai-tools tools.json --into spec.yaml --dry-run")
+	fmt.Println("  go run cli.go explain DUPLICATE_ID")
+	fmt.Println("  go run cli.go rules --format json")
+	fmt.Println("  go run cli.go deps spec.yaml --format json")
+	fmt.Println("  go run cli.go serve --addr :8080")
+	fmt.Println("  go run cli.go serve --grpc :9090 --addr :8080")
+	fmt.Println("  go run cli.go test testdata/corpus")
 	fmt.Println("")
 	
 	fmt.Println("For more information, visit: https://github.com/FabioGuin/APAI")
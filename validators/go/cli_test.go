@@ -0,0 +1,265 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// cliHelperEnv asks TestCLIHelper, run in a process of its own by runCLI,
+// to run the command line following -- in its arguments
+const cliHelperEnv = "APAI_CLI_HELPER"
+
+func TestCLIHelper(t *testing.T) {
+	if os.Getenv(cliHelperEnv) == "" {
+		t.Skip("run by runCLI")
+	}
+	os.Args = append([]string{"apai"}, flag.Args()...)
+	main()
+	os.Exit(0)
+}
+
+// runCLI runs the command line interface with args in dir, returning what
+// it printed and its exit code
+func TestMergeUsesConfig(t *testing.T) {
+	spec := strings.Replace(exampleSpec(t, "templates/basic-template.yaml"), "for {{company_name}}", "for {{company_name}} in {{region}}", 1)
+	dir := writeCLIFiles(t, map[string]string{
+		"a.yaml":           spec,
+		".apai.yaml":       "relax: [UNDECLARED_VARIABLE]\n",
+		"strict.apai.yaml": "relax: []\n",
+	})
+
+	// The relaxed error is a warning of the merged result, as with validate
+	output, code := runCLI(t, dir, "merge", "out.yaml", "a.yaml")
+	if code != 0 || !strings.Contains(output, "references undeclared variable: region") || !strings.Contains(output, "Merge completed successfully") {
+		t.Errorf("expected the relaxed merge to succeed, got exit code %d:\n%s", code, output)
+	}
+
+	// Options with values are not inputs, and override the file
+	output, code = runCLI(t, dir, "merge", "strict.yaml", "a.yaml", "--config", "strict.apai.yaml")
+	if code != 1 || !strings.Contains(output, "merged specification is invalid") {
+		t.Errorf("expected the merge to fail without the relaxed code, got exit code %d:\n%s", code, output)
+	}
+	output, code = runCLI(t, dir, "merge", "relaxed.yaml", "a.yaml", "--config", "strict.apai.yaml", "--relax", "UNDECLARED_VARIABLE")
+	if code != 0 || !strings.Contains(output, "Input files: a.yaml\n") {
+		t.Errorf("expected --relax to let the merge succeed, got exit code %d:\n%s", code, output)
+	}
+	output, code = runCLI(t, dir, "merge", "warned.yaml", "a.yaml", "--warn-on", "UNDECLARED_VARIABLE", "--config", "strict.apai.yaml")
+	if code != 0 || !strings.Contains(output, "Input files: a.yaml\n") {
+		t.Errorf("expected --warn-on to let the merge succeed, got exit code %d:\n%s", code, output)
+	}
+
+	dir = writeCLIFiles(t, map[string]string{
+		"a.yaml":        spec,
+		".apai.yaml":    "relax: [UNDECLARED_VARIABLE]\napproved_models: approved.yaml\n",
+		"approved.yaml": "models:\n  - provider: anthropic\n    name: claude-3\n",
+	})
+	output, code = runCLI(t, dir, "merge", "out.yaml", "a.yaml")
+	if code != 1 || !strings.Contains(output, "which is not in the approved models of") {
+		t.Errorf("expected the merge to enforce the approved models, got exit code %d:\n%s", code, output)
+	}
+}
+
+func runCLI(t *testing.T, dir string, args ...string) (string, int) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], append([]string{"-test.run=^TestCLIHelper$", "--"}, args...)...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), cliHelperEnv+"=1")
+	output, err := cmd.CombinedOutput()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return string(output), exitErr.ExitCode()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(output), 0
+}
+
+// writeCLIFiles writes files into a temporary directory, returning it
+func writeCLIFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// exampleSpec returns the content of an example specification
+func exampleSpec(t *testing.T, path string) string {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join("..", "..", "examples", path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+func TestMergeRejectsOtherDocuments(t *testing.T) {
+	dir := writeCLIFiles(t, map[string]string{
+		"base.yaml":       exampleSpec(t, "templates/basic-template.yaml"),
+		"deployment.yaml": "apiVersion: apps/v1\nkind: Deployment\nmetadata: {name: web}\n",
+		"notes.yaml":      "owner: platform\n",
+		"fragment.yaml":   "info:\n  title: Renamed\n",
+	})
+
+	output, code := runCLI(t, dir, "merge", "out.yaml", "base.yaml", "deployment.yaml", "notes.yaml")
+	for _, want := range []string{
+		"Not an APAI specification: deployment.yaml (no 'apai' key, and a top-level 'apiVersion' as in Kubernetes and other manifests; use --force to merge partial fragments)",
+		"Not an APAI specification: notes.yaml (no 'apai' key or known section such as info, models or tasks; use --force to merge partial fragments)",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("missing %q in:\n%s", want, output)
+		}
+	}
+	if code != 1 {
+		t.Errorf("exit code = %d, want 1", code)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "out.yaml")); err == nil {
+		t.Error("rejected inputs were merged")
+	}
+
+	// Known sections without the apai key are a partial fragment, merged
+	output, code = runCLI(t, dir, "merge", "out.yaml", "base.yaml", "fragment.yaml")
+	if code != 0 || strings.Contains(output, "Not an APAI specification") {
+		t.Errorf("expected the fragment to be merged, got exit code %d:\n%s", code, output)
+	}
+}
+
+func TestMergeValidatesItsOutput(t *testing.T) {
+	dir := writeCLIFiles(t, map[string]string{
+		"base.yaml":     exampleSpec(t, "templates/basic-template.yaml"),
+		"override.yaml": "apai: \"0.1.0\"\nmodels:\n  - id: main_model\n    type: Hologram\n",
+	})
+
+	output, code := runCLI(t, dir, "merge", "out.yaml", "base.yaml")
+	if code != 0 || !strings.Contains(output, "Contents: 1 models, 1 prompts, 1 tasks") {
+		t.Errorf("expected the counts of the merged result, got exit code %d:\n%s", code, output)
+	}
+
+	output, code = runCLI(t, dir, "merge", "invalid.yaml", "base.yaml", "override.yaml")
+	if code != 1 || !strings.Contains(output, "Model 0 missing required field: provider") || !strings.Contains(output, "merged specification is invalid") {
+		t.Errorf("expected the invalid merge to fail with its findings, got exit code %d:\n%s", code, output)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "invalid.yaml")); err == nil {
+		t.Error("invalid merge written without --force")
+	}
+
+	output, code = runCLI(t, dir, "merge", "invalid.yaml", "base.yaml", "override.yaml", "--force")
+	if code != 0 || !strings.Contains(output, "Merge completed successfully") {
+		t.Errorf("expected --force to write the invalid merge, got exit code %d:\n%s", code, output)
+	}
+}
+
+func TestFailOn(t *testing.T) {
+	spec := loadExampleSpecs(t, "templates/basic-template.yaml")[0]
+	dir := t.TempDir()
+	if err := WriteSpec(spec, filepath.Join(dir, "clean.yaml"), "yaml"); err != nil {
+		t.Fatal(err)
+	}
+	spec["models"].([]interface{})[0].(map[string]interface{})["capabilities"] = []interface{}{"teleportation"}
+	if err := WriteSpec(spec, filepath.Join(dir, "warned.yaml"), "yaml"); err != nil {
+		t.Fatal(err)
+	}
+	delete(spec, "models")
+	if err := WriteSpec(spec, filepath.Join(dir, "invalid.yaml"), "yaml"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".apai.yaml"), []byte("fail_on: never\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		args []string
+		code int
+	}{
+		{[]string{"validate", "clean.yaml", "--fail-on", "warning"}, 0},
+		{[]string{"validate", "warned.yaml"}, 0},
+		{[]string{"validate", "warned.yaml", "--fail-on", "warning"}, 1},
+		{[]string{"validate", "warned.yaml", "--fail-on", "WARNING"}, 1},
+		{[]string{"validate", "invalid.yaml", "--fail-on", "error"}, 1},
+		{[]string{"validate", "invalid.yaml", "--fail-on", "never"}, 0},
+		// The configuration file is the lowest precedence but the default
+		{[]string{"validate", "invalid.yaml", "--config", ".apai.yaml"}, 0},
+		{[]string{"validate", "warned.yaml", "--config", ".apai.yaml", "--strict"}, 1},
+		{[]string{"validate", "warned.yaml", "--strict", "--fail-on", "error"}, 0},
+		{[]string{"validate", "clean.yaml", "--fail-on", "sometimes"}, 1},
+		// --errors-only hides warnings without changing which ones fail
+		{[]string{"validate", "warned.yaml", "--fail-on", "warning", "--errors-only"}, 1},
+		{[]string{"validate", "warned.yaml", "--errors-only"}, 0},
+	} {
+		output, code := runCLI(t, dir, test.args...)
+		if code != test.code {
+			t.Errorf("%s: exit code = %d, want %d:\n%s", strings.Join(test.args, " "), code, test.code, output)
+		}
+		if containsString(test.args, "--strict") && !strings.Contains(output, "--strict is deprecated, use --fail-on warning") {
+			t.Errorf("%s: missing the deprecation notice:\n%s", strings.Join(test.args, " "), output)
+		}
+		if containsString(test.args, "--errors-only") && strings.Contains(output, "teleportation") {
+			t.Errorf("%s: printed a warning:\n%s", strings.Join(test.args, " "), output)
+		}
+	}
+}
+
+func TestResolveFailLevel(t *testing.T) {
+	for _, test := range []struct {
+		options    []string
+		configured FailLevel
+		want       FailLevel
+	}{
+		{nil, "", FailOnError},
+		{nil, FailNever, FailNever},
+		{[]string{"--warnings-as-errors"}, FailNever, FailOnWarning},
+		{[]string{"--warn-exit-code", "--fail-on", "never"}, FailOnError, FailNever},
+		{[]string{"--fail-on", "warning"}, FailNever, FailOnWarning},
+	} {
+		got, err := resolveFailLevel(test.options, test.configured)
+		if err != nil || got != test.want {
+			t.Errorf("resolveFailLevel(%v, %q) = %q %v, want %q", test.options, test.configured, got, err, test.want)
+		}
+	}
+	if _, err := resolveFailLevel([]string{"--fail-on"}, ""); err == nil {
+		t.Error("expected --fail-on without a value to be an error")
+	}
+
+	result := ValidationResult{Warnings: []string{"warning"}}
+	if FailOnError.Fails(result) || !FailOnWarning.Fails(result) || FailNever.Fails(ValidationResult{Errors: []string{"error"}}) {
+		t.Error("fail levels disagree with their documentation")
+	}
+}
+
+func TestMergeValidateReportsMissingSections(t *testing.T) {
+	spec := loadExampleSpecs(t, "templates/basic-template.yaml")[0]
+	delete(spec, "evaluation")
+	dir := t.TempDir()
+	if err := WriteSpec(spec, filepath.Join(dir, "partial.yaml"), "yaml"); err != nil {
+		t.Fatal(err)
+	}
+
+	output, code := runCLI(t, dir, "merge", "out.yaml", "partial.yaml", "--force", "--validate")
+	if code != 1 || !strings.Contains(output, "written to out.yaml but failed validation") {
+		t.Errorf("expected --validate to fail the written merge, got exit code %d:\n%s", code, output)
+	}
+	// The missing section is reported once, by validation
+	if count := strings.Count(output, "evaluation"); count != 1 || !strings.Contains(output, "Missing required section: evaluation") {
+		t.Errorf("expected the missing section reported once, got %d mentions:\n%s", count, output)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "out.yaml")); err != nil {
+		t.Errorf("--force did not write the merge: %v", err)
+	}
+
+	output, code = runCLI(t, dir, "merge", "out.yaml", "partial.yaml", "--force")
+	if code != 0 || !strings.Contains(output, "Missing required section: evaluation") {
+		t.Errorf("expected --force alone to write the merge and report the section, got exit code %d:\n%s", code, output)
+	}
+}
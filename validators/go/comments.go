@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// loadCommentTree parses a YAML specification into a node tree that keeps
+// its comments. JSON specifications have none and return nil.
+func (v *APAIValidator) loadCommentTree(filePath string) (*yaml.Node, error) {
+	ext := strings.ToLower(filepath.Ext(filePath))
+	if ext != ".yaml" && ext != ".yml" {
+		return nil, nil
+	}
+
+	content, err := v.readFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("file not found: %s", filePath)
+	}
+	var document yaml.Node
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return nil, fmt.Errorf("invalid YAML: %v", err)
+	}
+	return &document, nil
+}
+
+// MarshalYAMLWithComments serializes a merged specification as
+// MarshalCanonicalYAML does, carrying over the comments of sources, the
+// node trees of the merged documents in merge order. Each key keeps the
+// comments of the document that supplied its value, so comments on values
+// that were not overridden survive; nested objects merged from several
+// documents take the comments of the last document commenting a key.
+func MarshalYAMLWithComments(spec map[string]interface{}, sources []*yaml.Node) ([]byte, error) {
+	node, err := canonicalYAMLNode(spec, true)
+	if err != nil {
+		return nil, err
+	}
+
+	document := &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{node}}
+	mappings := make([]*yaml.Node, 0, len(sources))
+	for _, source := range sources {
+		if source == nil || source.Kind != yaml.DocumentNode || len(source.Content) == 0 {
+			continue
+		}
+		if document.HeadComment == "" {
+			document.HeadComment = source.HeadComment
+		}
+		mappings = append(mappings, source.Content[0])
+	}
+	annotateMapping(node, mappings)
+
+	return encodeYAMLNode(document)
+}
+
+// WriteSpecWithComments writes a merged specification like WriteSpec,
+// keeping the comments of sources in YAML output
+func WriteSpecWithComments(spec map[string]interface{}, outputPath, format string, sources []*yaml.Node) error {
+	if format != "yaml" {
+		return WriteSpec(spec, outputPath, format)
+	}
+
+	content, err := MarshalYAMLWithComments(spec, sources)
+	if err != nil {
+		return fmt.Errorf("error marshaling merged specification: %v", err)
+	}
+	if err := ioutil.WriteFile(outputPath, content, 0644); err != nil {
+		return fmt.Errorf("error writing output file: %v", err)
+	}
+	return nil
+}
+
+// annotateMapping copies comments onto the entries of out from the source
+// mappings, in merge order, that define the same keys
+func annotateMapping(out *yaml.Node, sources []*yaml.Node) {
+	for i := 0; i+1 < len(out.Content); i += 2 {
+		key, value := out.Content[i], out.Content[i+1]
+
+		keys, values := make([]*yaml.Node, 0), make([]*yaml.Node, 0)
+		for _, source := range sources {
+			if sourceKey, sourceValue := mappingEntry(source, key.Value); sourceKey != nil {
+				keys, values = append(keys, sourceKey), append(values, sourceValue)
+			}
+		}
+		if len(values) == 0 {
+			continue
+		}
+
+		if value.Kind != yaml.MappingNode {
+			// Arrays and scalars come whole from the last document
+			copyComments(key, keys[len(keys)-1])
+			copyTreeComments(value, values[len(values)-1])
+			continue
+		}
+
+		// Objects merge the trailing run of documents defining an object,
+		// each later document overriding the comments of earlier ones
+		first := len(values)
+		for first > 0 && values[first-1].Kind == yaml.MappingNode {
+			first--
+		}
+		for j := first; j < len(values); j++ {
+			mergeComments(key, keys[j])
+			mergeComments(value, values[j])
+		}
+		annotateMapping(value, values[first:])
+	}
+}
+
+// copyTreeComments copies the comments of a source subtree onto out,
+// matching object entries by key and array items by position
+func copyTreeComments(out, source *yaml.Node) {
+	copyComments(out, source)
+	switch {
+	case out.Kind == yaml.MappingNode && source.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(out.Content); i += 2 {
+			if sourceKey, sourceValue := mappingEntry(source, out.Content[i].Value); sourceKey != nil {
+				copyComments(out.Content[i], sourceKey)
+				copyTreeComments(out.Content[i+1], sourceValue)
+			}
+		}
+	case out.Kind == yaml.SequenceNode && source.Kind == yaml.SequenceNode:
+		for i := 0; i < len(out.Content) && i < len(source.Content); i++ {
+			copyTreeComments(out.Content[i], source.Content[i])
+		}
+	}
+}
+
+// copyComments replaces the comments of out with those of source
+func copyComments(out, source *yaml.Node) {
+	out.HeadComment, out.LineComment, out.FootComment = source.HeadComment, source.LineComment, source.FootComment
+}
+
+// mergeComments sets the comments of source onto out, keeping those of out
+// that source does not have
+func mergeComments(out, source *yaml.Node) {
+	if source.HeadComment != "" {
+		out.HeadComment = source.HeadComment
+	}
+	if source.LineComment != "" {
+		out.LineComment = source.LineComment
+	}
+	if source.FootComment != "" {
+		out.FootComment = source.FootComment
+	}
+}
+
+// mappingEntry returns the key and value nodes of a mapping entry
+func mappingEntry(mapping *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	if mapping.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i], mapping.Content[i+1]
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,183 @@
+package main
+
+import "fmt"
+
+// complexitySections are the sections whose elements Complexity counts
+var complexitySections = []string{"models", "prompts", "constraints", "tasks", "mcp_servers", "metrics"}
+
+// Complexity measures the size of a specification, for reviews and the
+// complexity budget
+type Complexity struct {
+	// Sections counts the elements of models, prompts, constraints, tasks,
+	// context.mcp_servers and evaluation.metrics
+	Sections map[string]int `json:"sections"`
+	// MaxStepsPerTask is the number of steps of LongestTask, the task with
+	// the most steps
+	MaxStepsPerTask int    `json:"max_steps_per_task"`
+	LongestTask     string `json:"longest_task,omitempty"`
+	// InheritanceDepth is the longest chain of parents the specification
+	// inherits from
+	InheritanceDepth int `json:"inheritance_depth"`
+	// PromptTokens estimates the tokens of all prompt templates and their
+	// few-shot examples
+	PromptTokens int `json:"prompt_tokens"`
+	// BranchingFactor is the number of distinct models, prompts and MCP
+	// servers referenced by WidestTask, the task with the most edges in
+	// the reference graph
+	BranchingFactor int    `json:"branching_factor"`
+	WidestTask      string `json:"widest_task,omitempty"`
+}
+
+// Budget sets the thresholds of the complexity budget; a zero threshold is
+// not checked. Exceeded thresholds are warnings, or errors with Enforce.
+type Budget struct {
+	MaxModels           int  `yaml:"max_models"`
+	MaxPrompts          int  `yaml:"max_prompts"`
+	MaxConstraints      int  `yaml:"max_constraints"`
+	MaxTasks            int  `yaml:"max_tasks"`
+	MaxStepsPerTask     int  `yaml:"max_steps_per_task"`
+	MaxInheritanceDepth int  `yaml:"max_inheritance_depth"`
+	MaxPromptTokens     int  `yaml:"max_prompt_tokens"`
+	MaxBranchingFactor  int  `yaml:"max_branching_factor"`
+	Enforce             bool `yaml:"enforce"`
+}
+
+// DefaultBudget returns the complexity budget used when the configuration
+// sets none, generous enough for any hand-written specification
+func DefaultBudget() Budget {
+	return Budget{
+		MaxModels:           20,
+		MaxPrompts:          100,
+		MaxConstraints:      50,
+		MaxTasks:            50,
+		MaxStepsPerTask:     25,
+		MaxInheritanceDepth: 5,
+		MaxPromptTokens:     50000,
+		MaxBranchingFactor:  15,
+	}
+}
+
+// ComputeComplexity measures a specification inheriting from parents
+// inheritanceDepth levels deep. Prompts read from a template_file are
+// counted by their template only once the file is inlined.
+func ComputeComplexity(spec map[string]interface{}, inheritanceDepth int) Complexity {
+	complexity := Complexity{Sections: make(map[string]int), InheritanceDepth: inheritanceDepth}
+
+	contextMap, _ := spec["context"].(map[string]interface{})
+	evaluation, _ := spec["evaluation"].(map[string]interface{})
+	for _, section := range complexitySections {
+		switch section {
+		case "mcp_servers":
+			servers, _ := contextMap["mcp_servers"].([]interface{})
+			complexity.Sections[section] = len(servers)
+		case "metrics":
+			forEachMetric(evaluation["metrics"], func(metricMap map[string]interface{}, location string) {
+				complexity.Sections[section]++
+			})
+		default:
+			items, _ := spec[section].([]interface{})
+			complexity.Sections[section] = len(items)
+		}
+	}
+
+	objectsAt(spec, "tasks[]", "", func(taskMap map[string]interface{}, location string) {
+		steps, _ := taskMap["steps"].([]interface{})
+		if len(steps) > complexity.MaxStepsPerTask {
+			complexity.MaxStepsPerTask = len(steps)
+			complexity.LongestTask, _ = taskMap["id"].(string)
+		}
+	})
+
+	objectsAt(spec, "prompts[]", "", func(promptMap map[string]interface{}, location string) {
+		complexity.PromptTokens += estimateTokens(promptText(promptMap))
+	})
+
+	graph := BuildGraph(spec)
+	edges := make(map[string]int)
+	for _, edge := range graph.Edges {
+		edges[edge.From]++
+	}
+	for _, node := range graph.Nodes {
+		if node.Kind == "task" && edges[node.ID] > complexity.BranchingFactor {
+			complexity.BranchingFactor = edges[node.ID]
+			complexity.WidestTask = node.Label
+		}
+	}
+	return complexity
+}
+
+// measureComplexity measures a specification being validated, counting the
+// templates of prompts read from a template_file too
+func (v *APAIValidator) measureComplexity(spec map[string]interface{}, inheritanceDepth int) Complexity {
+	complexity := ComputeComplexity(spec, inheritanceDepth)
+	objectsAt(spec, "prompts[]", "", func(promptMap map[string]interface{}, location string) {
+		complexity.PromptTokens += v.templateFileTokens(promptMap)
+	})
+	return complexity
+}
+
+// templateFileTokens estimates the tokens of the template a prompt reads
+// from its template_file, or 0 when it has none or an inline template
+func (v *APAIValidator) templateFileTokens(promptMap map[string]interface{}) int {
+	templateFile, ok := promptMap["template_file"].(string)
+	if !ok || templateFile == "" || promptMap["template"] != nil {
+		return 0
+	}
+	if content, err := v.readFile(templateFile); err == nil {
+		return estimateTokens(string(content))
+	}
+	return 0
+}
+
+// budgetExcess is a metric over its complexity budget threshold
+type budgetExcess struct {
+	metric    string
+	value     int
+	threshold string
+	limit     int
+}
+
+// exceeded returns the metrics of a complexity over the thresholds of a
+// budget, in the order of the budget's fields
+func (b Budget) exceeded(complexity Complexity) []budgetExcess {
+	excesses := make([]budgetExcess, 0)
+	check := func(metric string, value int, threshold string, limit int) {
+		if limit > 0 && value > limit {
+			excesses = append(excesses, budgetExcess{metric, value, threshold, limit})
+		}
+	}
+	check("models", complexity.Sections["models"], "max_models", b.MaxModels)
+	check("prompts", complexity.Sections["prompts"], "max_prompts", b.MaxPrompts)
+	check("constraints", complexity.Sections["constraints"], "max_constraints", b.MaxConstraints)
+	check("tasks", complexity.Sections["tasks"], "max_tasks", b.MaxTasks)
+	check(fmt.Sprintf("steps of task %s", complexity.LongestTask), complexity.MaxStepsPerTask, "max_steps_per_task", b.MaxStepsPerTask)
+	check("inheritance depth", complexity.InheritanceDepth, "max_inheritance_depth", b.MaxInheritanceDepth)
+	check("estimated prompt tokens", complexity.PromptTokens, "max_prompt_tokens", b.MaxPromptTokens)
+	check(fmt.Sprintf("branching factor of task %s", complexity.WidestTask), complexity.BranchingFactor, "max_branching_factor", b.MaxBranchingFactor)
+	return excesses
+}
+
+// validateComplexityBudget reports the metrics of a specification over the
+// thresholds of the configured budget
+func (v *APAIValidator) validateComplexityBudget(spec map[string]interface{}) {
+	depth := 0
+	if v.validatingMerged && v.inheritance != nil {
+		depth = v.inheritance.depth
+	}
+	v.checkComplexityBudget(v.measureComplexity(spec, depth))
+}
+
+// checkComplexityBudget reports the metrics of a measured complexity over
+// the thresholds of the configured budget, with the amount they exceed
+// them by
+func (v *APAIValidator) checkComplexityBudget(complexity Complexity) {
+	budget := v.Config.Budget
+	for _, excess := range budget.exceeded(complexity) {
+		message := fmt.Sprintf("Complexity budget exceeded: %s is %d, over %s of %d by %d", excess.metric, excess.value, excess.threshold, excess.limit, excess.value-excess.limit)
+		if budget.Enforce {
+			v.addError("COMPLEXITY_BUDGET", message)
+		} else {
+			v.addWarning("COMPLEXITY_BUDGET", message)
+		}
+	}
+}
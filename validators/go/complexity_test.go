@@ -0,0 +1,99 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestComputeComplexity(t *testing.T) {
+	spec := map[string]interface{}{
+		"models": []interface{}{
+			map[string]interface{}{"id": "main_model"},
+			map[string]interface{}{"id": "judge"},
+		},
+		"prompts": []interface{}{
+			// 40 characters estimate to 10 tokens
+			map[string]interface{}{"id": "answer", "template": strings.Repeat("abcd", 10)},
+			map[string]interface{}{"id": "review", "template": strings.Repeat("abcd", 5)},
+		},
+		"tasks": []interface{}{
+			map[string]interface{}{"id": "short", "steps": []interface{}{
+				map[string]interface{}{"name": "draft", "model": "main_model", "prompt": "answer"},
+			}},
+			map[string]interface{}{"id": "long", "steps": []interface{}{
+				map[string]interface{}{"name": "draft", "model": "main_model", "prompt": "answer"},
+				map[string]interface{}{"name": "review", "model": "judge", "prompt": "review"},
+				map[string]interface{}{"name": "redraft", "model": "main_model", "prompt": "answer"},
+			}},
+		},
+		"evaluation": map[string]interface{}{"metrics": map[string]interface{}{
+			"quality": []interface{}{map[string]interface{}{"name": "accuracy"}},
+		}},
+	}
+
+	complexity := ComputeComplexity(spec, 2)
+	want := Complexity{
+		Sections:         map[string]int{"models": 2, "prompts": 2, "constraints": 0, "tasks": 2, "mcp_servers": 0, "metrics": 1},
+		MaxStepsPerTask:  3,
+		LongestTask:      "long",
+		InheritanceDepth: 2,
+		PromptTokens:     15,
+		BranchingFactor:  4,
+		WidestTask:       "long",
+	}
+	if !reflect.DeepEqual(complexity, want) {
+		t.Errorf("got %+v, want %+v", complexity, want)
+	}
+
+	budget := Budget{MaxTasks: 1, MaxStepsPerTask: 3, MaxPromptTokens: 10, MaxInheritanceDepth: 1}
+	validator := NewAPAIValidator(WithConfig(Config{Budget: budget}))
+	validator.ValidateSpec(spec)
+	for _, want := range []string{
+		"Complexity budget exceeded: tasks is 2, over max_tasks of 1 by 1",
+		"Complexity budget exceeded: estimated prompt tokens is 15, over max_prompt_tokens of 10 by 5",
+	} {
+		if !containsString(validator.Warnings, want) {
+			t.Errorf("missing %q in %v", want, validator.Warnings)
+		}
+	}
+	for _, message := range append(validator.Warnings, validator.Errors...) {
+		if strings.Contains(message, "max_steps_per_task") || strings.Contains(message, "max_inheritance_depth") {
+			t.Errorf("unexpected %q", message)
+		}
+	}
+
+	// Within the default budget, nothing is reported
+	validator = NewAPAIValidator()
+	validator.ValidateSpec(spec)
+	for _, message := range append(validator.Warnings, validator.Errors...) {
+		if strings.HasPrefix(message, "Complexity budget exceeded") {
+			t.Errorf("unexpected %q", message)
+		}
+	}
+}
+
+func TestEnforcedBudgetFromConfig(t *testing.T) {
+	config, err := LoadConfig("testdata/budget.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Budget.MaxModels != DefaultBudget().MaxModels {
+		t.Errorf("max_models = %d, want the default %d", config.Budget.MaxModels, DefaultBudget().MaxModels)
+	}
+
+	validator := NewAPAIValidator(WithConfig(config), WithFS(embeddedSpecs))
+	if _, err := validator.ValidateWithInheritance("testdata/embedded/team/app.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	if complexity := validator.measureComplexity(map[string]interface{}{}, validator.inheritance.depth); complexity.InheritanceDepth != 1 {
+		t.Errorf("inheritance depth = %d, want 1", complexity.InheritanceDepth)
+	}
+	found := false
+	for _, message := range validator.Errors {
+		found = found || strings.HasPrefix(message, "Complexity budget exceeded: estimated prompt tokens is ")
+	}
+	if !found {
+		t.Errorf("expected an enforced prompt token budget error in %v", validator.Errors)
+	}
+}
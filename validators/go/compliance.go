@@ -0,0 +1,135 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// builtinProfiles holds the compliance profiles shipped with the validator,
+// one YAML file per profile named after it
+//
+//go:embed profiles/*.yaml
+var builtinProfiles embed.FS
+
+// ComplianceProfile lists the metadata and constraint categories a
+// regulatory or internal regime requires of a specification
+type ComplianceProfile struct {
+	Name         string                  `yaml:"name"`
+	Title        string                  `yaml:"title"`
+	Requirements []ComplianceRequirement `yaml:"requirements"`
+}
+
+// ComplianceRequirement is a single rule of a profile. It requires either a
+// field, optionally restricted to enum values, or a constraint of a type.
+type ComplianceRequirement struct {
+	ID string `yaml:"id"`
+	// Reference points to the source of the rule, e.g. "Art. 14"
+	Reference      string   `yaml:"reference"`
+	Field          string   `yaml:"field"`
+	Enum           []string `yaml:"enum"`
+	ConstraintType string   `yaml:"constraint_type"`
+	Description    string   `yaml:"description"`
+}
+
+// ComplianceProfiles returns the names of the built-in profiles
+func ComplianceProfiles() []string {
+	entries, _ := builtinProfiles.ReadDir("profiles")
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, strings.TrimSuffix(entry.Name(), path.Ext(entry.Name())))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadComplianceProfile returns a built-in profile by name
+func LoadComplianceProfile(name string) (*ComplianceProfile, error) {
+	content, err := builtinProfiles.ReadFile(path.Join("profiles", name+".yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("unknown compliance profile: %s (available: %s)", name, strings.Join(ComplianceProfiles(), ", "))
+	}
+	return parseComplianceProfile(content, name)
+}
+
+// LoadComplianceProfileFile reads a custom profile from a YAML file
+func LoadComplianceProfileFile(filePath string) (*ComplianceProfile, error) {
+	content, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("compliance profile not found: %s", filePath)
+	}
+	return parseComplianceProfile(content, filePath)
+}
+
+// parseComplianceProfile decodes a profile and checks its requirements
+func parseComplianceProfile(content []byte, source string) (*ComplianceProfile, error) {
+	profile := &ComplianceProfile{}
+	if err := yaml.Unmarshal(content, profile); err != nil {
+		return nil, fmt.Errorf("invalid compliance profile %s: %v", source, err)
+	}
+	if profile.Name == "" {
+		return nil, fmt.Errorf("invalid compliance profile %s: missing name", source)
+	}
+
+	ids := make(map[string]bool)
+	for i, requirement := range profile.Requirements {
+		switch {
+		case requirement.ID == "":
+			return nil, fmt.Errorf("invalid compliance profile %s: requirement %d missing id", source, i)
+		case ids[requirement.ID]:
+			return nil, fmt.Errorf("invalid compliance profile %s: duplicate requirement id %s", source, requirement.ID)
+		case (requirement.Field == "") == (requirement.ConstraintType == ""):
+			return nil, fmt.Errorf("invalid compliance profile %s: requirement %s needs either field or constraint_type", source, requirement.ID)
+		}
+		ids[requirement.ID] = true
+	}
+	return profile, nil
+}
+
+// validateCompliance reports the requirements of the configured compliance
+// profiles that the specification does not meet
+func (v *APAIValidator) validateCompliance(spec map[string]interface{}) {
+	for _, profile := range v.compliance {
+		for _, requirement := range profile.Requirements {
+			if problem := requirement.check(spec); problem != "" {
+				source := ""
+				if requirement.Reference != "" {
+					source = fmt.Sprintf(" (%s)", requirement.Reference)
+				}
+				v.addError("COMPLIANCE_VIOLATION", fmt.Sprintf("Compliance %s/%s violated%s: %s", profile.Name, requirement.ID, source, problem))
+			}
+		}
+	}
+}
+
+// check describes how spec fails the requirement, or returns "" when it
+// meets it
+func (r ComplianceRequirement) check(spec map[string]interface{}) string {
+	if r.ConstraintType != "" {
+		constraints, _ := spec["constraints"].([]interface{})
+		for _, constraint := range constraints {
+			if constraintMap, ok := constraint.(map[string]interface{}); ok && constraintMap["type"] == r.ConstraintType {
+				return ""
+			}
+		}
+		return fmt.Sprintf("missing a constraint of type %s", r.ConstraintType)
+	}
+
+	var current interface{} = spec
+	for _, key := range strings.Split(r.Field, ".") {
+		currentMap, _ := current.(map[string]interface{})
+		current = currentMap[key]
+	}
+	if current == nil || isBlankString(current) {
+		return fmt.Sprintf("missing %s", r.Field)
+	}
+	if len(r.Enum) > 0 && !containsString(r.Enum, fmt.Sprint(current)) {
+		return fmt.Sprintf("%s must be one of %s, got %v", r.Field, strings.Join(r.Enum, ", "), current)
+	}
+	return ""
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuiltinComplianceProfiles(t *testing.T) {
+	for _, name := range ComplianceProfiles() {
+		if _, err := LoadComplianceProfile(name); err != nil {
+			t.Errorf("built-in profile %s: %v", name, err)
+		}
+	}
+	if _, err := LoadComplianceProfile("missing"); err == nil || !strings.Contains(err.Error(), "eu-ai-act") {
+		t.Errorf("expected unknown profile error listing available profiles, got %v", err)
+	}
+}
+
+func TestComplianceProfileValidation(t *testing.T) {
+	profile, err := LoadComplianceProfile("eu-ai-act")
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec := loadExampleSpecs(t, "core/customer-support.yaml")[0]
+
+	validator := NewAPAIValidator(WithComplianceProfiles(profile))
+	if validator.ValidateSpec(spec) {
+		t.Fatal("expected the example without compliance metadata to fail")
+	}
+	for _, want := range []string{
+		"Compliance eu-ai-act/human-oversight violated (Art. 14): missing info.ai_metadata.human_oversight",
+		"Compliance eu-ai-act/bias-mitigation violated (Art. 10(2)(f)): missing a constraint of type fairness",
+	} {
+		if !containsString(validator.Errors, want) {
+			t.Errorf("missing %q in %v", want, validator.Errors)
+		}
+	}
+
+	metadata := spec["info"].(map[string]interface{})["ai_metadata"].(map[string]interface{})
+	metadata["risk_level"] = "high"
+	for _, field := range []string{"risk_management", "data_governance", "logging_retention", "transparency_notice", "human_oversight"} {
+		metadata[field] = "Documented in the system card"
+	}
+	spec["constraints"] = append(spec["constraints"].([]interface{}), map[string]interface{}{
+		"id": "fair_treatment", "type": "fairness", "rule": "decision NOT influenced by protected_characteristics", "severity": "high",
+	})
+	if !validator.ValidateSpec(spec) {
+		t.Errorf("expected compliant spec to pass, got %v", validator.Errors)
+	}
+
+	metadata["risk_level"] = "moderate"
+	validator.ValidateSpec(spec)
+	if want := "Compliance eu-ai-act/risk-classification violated (Art. 6 and Annex III): info.ai_metadata.risk_level must be one of minimal, limited, high, unacceptable, got moderate"; !containsString(validator.Errors, want) {
+		t.Errorf("missing %q in %v", want, validator.Errors)
+	}
+}
+
+func TestComplianceProfileFile(t *testing.T) {
+	dir := t.TempDir()
+	custom := filepath.Join(dir, "internal.yaml")
+	content := "name: internal\nrequirements:\n  - id: owner\n    field: info.ai_metadata.owner\n"
+	if err := os.WriteFile(custom, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	profile, err := LoadComplianceProfileFile(custom)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	validator := NewAPAIValidator(WithComplianceProfiles(profile))
+	validator.ValidateSpec(loadExampleSpecs(t, "templates/basic-template.yaml")[0])
+	if want := "Compliance internal/owner violated: missing info.ai_metadata.owner"; !containsString(validator.Errors, want) {
+		t.Errorf("missing %q in %v", want, validator.Errors)
+	}
+
+	invalid := filepath.Join(dir, "invalid.yaml")
+	if err := os.WriteFile(invalid, []byte("name: invalid\nrequirements:\n  - id: both\n    field: info.title\n    constraint_type: privacy\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadComplianceProfileFile(invalid); err == nil {
+		t.Error("expected a requirement with both field and constraint_type to be rejected")
+	}
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultConfigFile is the configuration file looked up in the working directory
+const DefaultConfigFile = ".apai.yaml"
+
+// Config represents validator settings loaded from a configuration file
+type Config struct {
+	// WarnUnusedMCPServers reports MCP servers that no task step references
+	WarnUnusedMCPServers bool `yaml:"warn_unused_mcp_servers"`
+
+	// SpecRoots lists registry directories used to resolve symbolic inherits
+	SpecRoots []string `yaml:"spec_roots"`
+
+	// MaxInheritanceDepth limits how many levels of parents a spec may have
+	MaxInheritanceDepth int `yaml:"max_inheritance_depth"`
+
+	// MaxInheritedSpecs limits the total number of parents loaded for a spec
+	MaxInheritedSpecs int `yaml:"max_inherited_specs"`
+
+	// FailOn selects which findings make validation fail
+	FailOn FailLevel `yaml:"fail_on"`
+
+	// StrictFields reports fields the specification does not define, except
+	// x- extension fields
+	StrictFields bool `yaml:"strict_fields"`
+
+	// CheckFiles verifies that referenced datasets and knowledge sources
+	// exist and that URL sources are well formed
+	CheckFiles bool `yaml:"check_files"`
+
+	// CheckURLs also sends a HEAD request to URL sources during file checks
+	CheckURLs bool `yaml:"check_urls"`
+
+	// ProviderEnv maps model providers to the environment variables
+	// preflight requires, replacing or extending the built-in table
+	ProviderEnv map[string][]string `yaml:"provider_env"`
+
+	// Prices overrides the built-in model price table of cost estimates,
+	// keyed by "provider/name" or by model id
+	Prices map[string]ModelPrice `yaml:"prices"`
+
+	// Relax lists error codes reported as warnings instead, so specs can
+	// migrate to new rules incrementally
+	Relax []string `yaml:"relax"`
+
+	// Promote lists warning codes reported as errors instead, so teams can
+	// make selected warnings fail the build
+	Promote []string `yaml:"promote"`
+
+	// ApprovedModels is the URL or file of the approved models list; models
+	// of a specification missing from it are errors
+	ApprovedModels string `yaml:"approved_models"`
+
+	// ValidateParents also validates each inherited specification on its
+	// own during hierarchical validation, attributing findings to its file
+	ValidateParents bool `yaml:"validate_parents"`
+
+	// Budget sets the complexity thresholds over which specifications are
+	// reported
+	Budget Budget `yaml:"budget"`
+
+	// Cache keeps parsed and merged specifications in CacheDir, or in
+	// DefaultCacheDir when CacheDir is empty; setting CacheDir enables it too
+	Cache    bool   `yaml:"cache"`
+	CacheDir string `yaml:"cache_dir"`
+}
+
+// FailLevel determines which findings make validation fail
+type FailLevel string
+
+const (
+	// FailOnError fails only when there are errors (default)
+	FailOnError FailLevel = "error"
+	// FailOnWarning fails when there are errors or warnings
+	FailOnWarning FailLevel = "warning"
+	// FailNever never fails, reporting findings only
+	FailNever FailLevel = "never"
+)
+
+// ParseFailLevel parses a fail level name
+func ParseFailLevel(s string) (FailLevel, error) {
+	switch level := FailLevel(strings.ToLower(s)); level {
+	case FailOnError, FailOnWarning, FailNever:
+		return level, nil
+	}
+	return "", fmt.Errorf("invalid fail level: %s (expected error, warning or never)", s)
+}
+
+// Fails reports whether a validation result fails at this level
+func (l FailLevel) Fails(result ValidationResult) bool {
+	switch l {
+	case FailNever:
+		return false
+	case FailOnWarning:
+		return len(result.Errors) > 0 || len(result.Warnings) > 0
+	default:
+		return len(result.Errors) > 0
+	}
+}
+
+// DefaultConfig returns the configuration used when no file is present
+func DefaultConfig() Config {
+	return Config{
+		WarnUnusedMCPServers: true,
+		MaxInheritanceDepth:  10,
+		MaxInheritedSpecs:    100,
+		FailOn:               FailOnError,
+		Budget:               DefaultBudget(),
+	}
+}
+
+// LoadConfig loads a configuration file on top of the default configuration
+func LoadConfig(filePath string) (Config, error) {
+	config := DefaultConfig()
+
+	content, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return config, fmt.Errorf("config file not found: %s", filePath)
+	}
+
+	if err := yaml.Unmarshal(content, &config); err != nil {
+		return config, fmt.Errorf("invalid config file %s: %v", filePath, err)
+	}
+
+	failOn, err := ParseFailLevel(string(config.FailOn))
+	if err != nil {
+		return config, fmt.Errorf("invalid config file %s: %v", filePath, err)
+	}
+	config.FailOn = failOn
+
+	if err := checkRelaxCodes(config.Relax); err != nil {
+		return config, fmt.Errorf("invalid config file %s: %v", filePath, err)
+	}
+	if err := checkPromoteCodes(config.Promote, config.Relax); err != nil {
+		return config, fmt.Errorf("invalid config file %s: %v", filePath, err)
+	}
+
+	// Relative registry roots are relative to the config file
+	for i, root := range config.SpecRoots {
+		if !filepath.IsAbs(root) {
+			config.SpecRoots[i] = filepath.Join(filepath.Dir(filePath), root)
+		}
+	}
+
+	// So is a local approved models list
+	if source := config.ApprovedModels; source != "" && !strings.Contains(source, "://") && !filepath.IsAbs(source) {
+		config.ApprovedModels = filepath.Join(filepath.Dir(filePath), source)
+	}
+
+	// And the cache directory
+	if config.CacheDir != "" && !filepath.IsAbs(config.CacheDir) {
+		config.CacheDir = filepath.Join(filepath.Dir(filePath), config.CacheDir)
+	}
+
+	return config, nil
+}
+
+// loadCLIConfig loads the configuration given by --config, falling back to
+// DefaultConfigFile when it exists in the working directory, and applies
+// command-line and environment overrides
+func loadCLIConfig(options []string) (Config, error) {
+	config := DefaultConfig()
+	configPath := ""
+	specRoots := make([]string, 0)
+	relax := make([]string, 0)
+	warnOn := make([]string, 0)
+	promote := make([]string, 0)
+	approvedModels, cacheDir := "", ""
+	codes := func(value string, list []string) []string {
+		for _, code := range strings.Split(value, ",") {
+			if code = strings.TrimSpace(code); code != "" {
+				list = append(list, code)
+			}
+		}
+		return list
+	}
+
+	for i, opt := range options {
+		if i+1 >= len(options) {
+			break
+		}
+		switch opt {
+		case "--config":
+			configPath = options[i+1]
+		case "--spec-root":
+			specRoots = append(specRoots, options[i+1])
+		case "--approved-models":
+			approvedModels = options[i+1]
+		case "--cache-dir":
+			cacheDir = options[i+1]
+		case "--relax":
+			relax = codes(options[i+1], relax)
+		case "--warn-on":
+			warnOn = codes(options[i+1], warnOn)
+		case "--error-on":
+			promote = codes(options[i+1], promote)
+		}
+	}
+	if err := checkRelaxCodes(relax); err != nil {
+		return config, fmt.Errorf("--relax: %v", err)
+	}
+	if err := checkRelaxCodes(warnOn); err != nil {
+		return config, fmt.Errorf("--warn-on: %v", err)
+	}
+	limits, err := parseLimitFlags(options)
+	if err != nil {
+		return config, err
+	}
+
+	if configPath == "" {
+		if _, err := os.Stat(DefaultConfigFile); err == nil {
+			configPath = DefaultConfigFile
+		}
+	}
+
+	if configPath != "" {
+		var err error
+		config, err = LoadConfig(configPath)
+		if err != nil {
+			return config, err
+		}
+	}
+
+	if containsString(options, "--strict-fields") {
+		config.StrictFields = true
+	}
+	if containsString(options, "--check-files") {
+		config.CheckFiles = true
+	}
+	if containsString(options, "--validate-parents") {
+		config.ValidateParents = true
+	}
+	if containsString(options, "--check-urls") {
+		config.CheckFiles = true
+		config.CheckURLs = true
+	}
+	if containsString(options, "--cache") {
+		config.Cache = true
+	}
+	if cacheDir != "" {
+		config.CacheDir = cacheDir
+	}
+
+	config.Relax = append(append(config.Relax, relax...), warnOn...)
+	config.Promote = append(config.Promote, promote...)
+	if err := checkPromoteCodes(config.Promote, config.Relax); err != nil {
+		return config, fmt.Errorf("--error-on: %v", err)
+	}
+	if approvedModels != "" {
+		config.ApprovedModels = approvedModels
+	}
+
+	// Registry roots are searched in flag, config file, environment order
+	config.SpecRoots = append(append(specRoots, config.SpecRoots...), specRootsFromEnv()...)
+
+	for flag, value := range limits {
+		switch flag {
+		case "--max-inheritance-depth":
+			config.MaxInheritanceDepth = value
+		case "--max-inherited-specs":
+			config.MaxInheritedSpecs = value
+		}
+	}
+
+	return config, nil
+}
+
+// checkRelaxCodes verifies that relaxed codes naming a rule name one that
+// reports errors. Relaxed codes matching no rule are reported as warnings
+// by validation instead, like promoted ones.
+func checkRelaxCodes(codes []string) error {
+	for _, code := range codes {
+		rule, ok := LookupRule(code)
+		if !ok {
+			continue
+		}
+		if rule.Severity != "error" {
+			return fmt.Errorf("%s is already a warning", rule.Code)
+		}
+	}
+	return nil
+}
+
+// checkPromoteCodes verifies that no code is both promoted and relaxed.
+// Promoted codes matching no rule are reported as warnings by validation
+// instead, since plugins report codes of their own.
+func checkPromoteCodes(promote, relax []string) error {
+	for _, code := range promote {
+		if containsFold(relax, code) {
+			return fmt.Errorf("%s is both promoted to an error and relaxed to a warning", code)
+		}
+	}
+	return nil
+}
+
+// parseLimitFlags parses the numeric limit flags given on the command line
+func parseLimitFlags(options []string) (map[string]int, error) {
+	limits := make(map[string]int)
+	for i, opt := range options {
+		if opt != "--max-inheritance-depth" && opt != "--max-inherited-specs" {
+			continue
+		}
+		if i+1 >= len(options) {
+			return nil, fmt.Errorf("%s requires a value", opt)
+		}
+		value, err := strconv.Atoi(options[i+1])
+		if err != nil || value < 0 {
+			return nil, fmt.Errorf("%s must be a non-negative integer: %s", opt, options[i+1])
+		}
+		limits[opt] = value
+	}
+	return limits, nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// regexConstraintType is the type of constraints whose rule is a regular
+// expression given in pattern
+const regexConstraintType = "regex"
+
+// validateRegexConstraint checks that a regex constraint declares a
+// pattern that compiles, so it does not first fail at runtime
+func validateRegexConstraint(f *sectionFindings, constraintMap map[string]interface{}, constraintIndex int) {
+	if constraintType, _ := constraintMap["type"].(string); !strings.EqualFold(constraintType, regexConstraintType) {
+		return
+	}
+	name := elementName(constraintIndex, constraintMap)
+	pattern, exists := constraintMap["pattern"]
+	if !exists {
+		f.addError("INVALID_REGEX", fmt.Sprintf("Constraint %s of type regex missing pattern", name))
+		return
+	}
+	patternStr, ok := pattern.(string)
+	if !ok {
+		f.addError("INVALID_TYPE", fmt.Sprintf("Constraint %s pattern must be a string", name))
+		return
+	}
+	if _, err := regexp.Compile(patternStr); err != nil {
+		f.addError("INVALID_REGEX", fmt.Sprintf("Constraint %s pattern is not a valid regular expression: %v", name, err))
+	}
+}
+
+// comparisonPattern matches a simple comparison rule such as
+// "temperature <= 0.3" or "response_time < 2s"
+var comparisonPattern = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_.]*)\s*(<=|>=|==|=|<|>)\s*(-?[0-9]*\.?[0-9]+)(ms|s|m|h)?$`)
+
+// conjunctionPattern separates the comparisons of a rule
+var conjunctionPattern = regexp.MustCompile(`\s+AND\s+`)
+
+// valueRange is the set of values a comparison rule allows for a field
+type valueRange struct {
+	low, high                   float64
+	lowInclusive, highInclusive bool
+	// duration is set when the bounds are durations, in seconds
+	duration bool
+}
+
+// intersect returns the values both ranges allow
+func (r valueRange) intersect(other valueRange) valueRange {
+	result := r
+	if other.low > result.low || (other.low == result.low && !other.lowInclusive) {
+		result.low, result.lowInclusive = other.low, other.lowInclusive
+	}
+	if other.high < result.high || (other.high == result.high && !other.highInclusive) {
+		result.high, result.highInclusive = other.high, other.highInclusive
+	}
+	return result
+}
+
+// empty reports whether the range allows no value
+func (r valueRange) empty() bool {
+	return r.low > r.high || (r.low == r.high && !(r.lowInclusive && r.highInclusive))
+}
+
+// parseRuleRanges parses a rule made of comparisons joined by AND into the
+// range each compared field must fall in. It returns nil for any other
+// rule, such as "output NOT contains pii" or comparisons joined by OR.
+func parseRuleRanges(rule string) map[string]valueRange {
+	ranges := make(map[string]valueRange)
+	for _, comparison := range conjunctionPattern.Split(strings.TrimSpace(rule), -1) {
+		match := comparisonPattern.FindStringSubmatch(strings.TrimSpace(comparison))
+		if match == nil {
+			return nil
+		}
+		field, operator, unit := match[1], match[2], match[4]
+		value, err := strconv.ParseFloat(match[3], 64)
+		if err != nil {
+			return nil
+		}
+		if unit != "" {
+			duration, err := time.ParseDuration(match[3] + unit)
+			if err != nil {
+				return nil
+			}
+			value = duration.Seconds()
+		}
+
+		bound := valueRange{low: math.Inf(-1), high: math.Inf(1), lowInclusive: true, highInclusive: true, duration: unit != ""}
+		switch operator {
+		case "<":
+			bound.high, bound.highInclusive = value, false
+		case "<=":
+			bound.high = value
+		case ">":
+			bound.low, bound.lowInclusive = value, false
+		case ">=":
+			bound.low = value
+		default:
+			bound.low, bound.high = value, value
+		}
+
+		if existing, ok := ranges[field]; ok {
+			if existing.duration != bound.duration {
+				return nil
+			}
+			bound = existing.intersect(bound)
+		}
+		ranges[field] = bound
+	}
+	return ranges
+}
+
+// validateConstraintContradictions warns about pairs of constraints whose
+// simple comparison rules cannot hold at the same time, such as
+// "temperature <= 0.3" and "temperature >= 0.7". Other rules are ignored.
+func validateConstraintContradictions(f *sectionFindings, constraints []interface{}) {
+	type parsedConstraint struct {
+		name   string
+		rule   string
+		ranges map[string]valueRange
+	}
+
+	parsed := make([]parsedConstraint, 0, len(constraints))
+	for i, constraint := range constraints {
+		constraintMap, ok := constraint.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		rule, _ := constraintMap["rule"].(string)
+		if ranges := parseRuleRanges(rule); ranges != nil {
+			parsed = append(parsed, parsedConstraint{name: elementName(i, constraintMap), rule: rule, ranges: ranges})
+		}
+	}
+
+	for i, first := range parsed {
+		for _, second := range parsed[i+1:] {
+			for field, firstRange := range first.ranges {
+				secondRange, ok := second.ranges[field]
+				if !ok || firstRange.duration != secondRange.duration || !firstRange.intersect(secondRange).empty() {
+					continue
+				}
+				f.addWarning("CONTRADICTORY_CONSTRAINTS", fmt.Sprintf("Constraints %s and %s contradict each other: %q and %q cannot both hold",
+					first.name, second.name, first.rule, second.rule))
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestConstraintContradictions(t *testing.T) {
+	constraint := func(id, rule string) map[string]interface{} {
+		return map[string]interface{}{"id": id, "rule": rule, "severity": "medium"}
+	}
+	constraints := []interface{}{
+		constraint("low_temperature", "temperature <= 0.3"),
+		constraint("creative_output", "temperature >= 0.7"),
+		constraint("exact_temperature", "temperature == 0.3"),
+		constraint("fast_response", "response_time < 2s"),
+		constraint("slow_response", "response_time >= 2000ms"),
+		constraint("bounded_latency", "latency > 1 AND latency < 5"),
+		constraint("no_pii", "output NOT contains pii_patterns"),
+		constraint("either_bound", "latency > 10 OR latency < 0"),
+	}
+
+	f := &sectionFindings{}
+	validateConstraintContradictions(f, constraints)
+	want := []string{
+		`Constraints low_temperature and creative_output contradict each other: "temperature <= 0.3" and "temperature >= 0.7" cannot both hold`,
+		`Constraints creative_output and exact_temperature contradict each other: "temperature >= 0.7" and "temperature == 0.3" cannot both hold`,
+		`Constraints fast_response and slow_response contradict each other: "response_time < 2s" and "response_time >= 2000ms" cannot both hold`,
+	}
+	if !reflect.DeepEqual(f.Warnings, want) {
+		t.Errorf("unexpected warnings:\n got %q\nwant %q", f.Warnings, want)
+	}
+
+	if ranges := parseRuleRanges("latency > 1 AND latency < 5"); ranges["latency"].low != 1 || ranges["latency"].high != 5 {
+		t.Errorf("AND rule not parsed into one range: %+v", ranges)
+	}
+	if ranges := parseRuleRanges("latency > 10 OR latency < 0"); ranges != nil {
+		t.Errorf("OR rule should not be parsed, got %+v", ranges)
+	}
+}
+
+func TestRegexConstraints(t *testing.T) {
+	constraint := func(id string, fields map[string]interface{}) map[string]interface{} {
+		constraint := map[string]interface{}{"id": id, "type": "regex", "rule": "output NOT matches pattern", "severity": "high"}
+		for key, value := range fields {
+			constraint[key] = value
+		}
+		return constraint
+	}
+	validator := NewAPAIValidator()
+	f := &sectionFindings{}
+	validator.validateConstraints(f, []interface{}{
+		constraint("card_numbers", map[string]interface{}{"pattern": `\b(?:\d[ -]?){13,16}\b`}),
+		constraint("unbalanced", map[string]interface{}{"pattern": `(secret`}),
+		constraint("reversed", map[string]interface{}{"type": "Regex", "pattern": `[z-a]+`}),
+		constraint("missing", nil),
+		constraint("numeric", map[string]interface{}{"pattern": 42}),
+		constraint("plain", map[string]interface{}{"type": "privacy", "pattern": `(`}),
+	})
+	want := []string{
+		"Constraint unbalanced pattern is not a valid regular expression: error parsing regexp: missing closing ): `(secret`",
+		"Constraint reversed pattern is not a valid regular expression: error parsing regexp: invalid character class range: `z-a`",
+		"Constraint missing of type regex missing pattern",
+		"Constraint numeric pattern must be a string",
+	}
+	if !reflect.DeepEqual(f.Errors, want) {
+		t.Errorf("unexpected errors:\n got %q\nwant %q", f.Errors, want)
+	}
+	for _, message := range want[:3] {
+		if rule, _ := MatchRule(message); rule.Code != "INVALID_REGEX" {
+			t.Errorf("expected INVALID_REGEX for %q, got %q", message, rule.Code)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CorpusManifest is the file of a corpus directory listing the findings
+// expected for each of its specifications
+const CorpusManifest = "expectations.yaml"
+
+// corpusManifestHeader introduces the manifests written by --update
+const corpusManifestHeader = "# Expected findings of each specification, counted by code.\n# Regenerate with: apai test <dir> --update\n"
+
+// CorpusExpectation is the findings of a specification counted by code;
+// LoadError is set when the file cannot be loaded at all
+type CorpusExpectation struct {
+	LoadError bool           `yaml:"load_error,omitempty"`
+	Errors    map[string]int `yaml:"errors,omitempty"`
+	Warnings  map[string]int `yaml:"warnings,omitempty"`
+}
+
+// CorpusResult compares the expected and actual findings of a specification
+type CorpusResult struct {
+	// Path is the slash-separated path of the file in the corpus
+	Path     string
+	Expected CorpusExpectation
+	Actual   CorpusExpectation
+	// Listed reports whether the manifest lists the file, Found whether it exists
+	Listed bool
+	Found  bool
+	// Err is why the file could not be loaded
+	Err error
+}
+
+// Passed reports whether the file is listed and has the expected findings
+func (r CorpusResult) Passed() bool {
+	return r.Listed && r.Found && r.Expected.LoadError == r.Actual.LoadError &&
+		sameCounts(r.Expected.Errors, r.Actual.Errors) && sameCounts(r.Expected.Warnings, r.Actual.Warnings)
+}
+
+// Diff returns the expected and actual findings as a unified diff
+func (r CorpusResult) Diff() string {
+	expected, _ := marshalCorpusYAML(r.Expected)
+	actual, _ := marshalCorpusYAML(r.Actual)
+	return UnifiedDiff("expected", "actual", expected, actual)
+}
+
+// sameCounts reports whether two code counts are equal, empty and missing
+// counts being the same
+func sameCounts(a, b map[string]int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for code, count := range a {
+		if b[code] != count {
+			return false
+		}
+	}
+	return true
+}
+
+// LoadCorpusManifest reads the expected findings of a corpus
+func LoadCorpusManifest(manifestPath string) (map[string]CorpusExpectation, error) {
+	content, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read expectations: %v", err)
+	}
+	expectations := make(map[string]CorpusExpectation)
+	if err := decodeYAML(content, &expectations); err != nil {
+		return nil, fmt.Errorf("invalid expectations %s: %v", manifestPath, err)
+	}
+	return expectations, nil
+}
+
+// WriteCorpusManifest writes the expected findings of a corpus, with the
+// files and codes sorted
+func WriteCorpusManifest(manifestPath string, expectations map[string]CorpusExpectation) error {
+	content, err := marshalCorpusYAML(expectations)
+	if err != nil {
+		return fmt.Errorf("error marshaling expectations: %v", err)
+	}
+	if err := os.WriteFile(manifestPath, append([]byte(corpusManifestHeader), content...), 0644); err != nil {
+		return fmt.Errorf("error writing expectations: %v", err)
+	}
+	return nil
+}
+
+// marshalCorpusYAML serializes expectations with two-space indentation
+func marshalCorpusYAML(value interface{}) ([]byte, error) {
+	var node yaml.Node
+	if err := node.Encode(value); err != nil {
+		return nil, err
+	}
+	return encodeYAMLNode(&node)
+}
+
+// RunCorpus validates the specifications under a corpus directory, with
+// their inherited specifications when hierarchical is set, and compares
+// their findings with its manifest. Files that are neither listed nor
+// specifications, such as $include fragments, are left out; the results
+// are in path order, followed by listed files that do not exist.
+func (v *APAIValidator) RunCorpus(ctx context.Context, dir string, hierarchical bool) ([]CorpusResult, error) {
+	expectations := make(map[string]CorpusExpectation)
+	manifestPath := filepath.Join(dir, CorpusManifest)
+	if _, err := os.Stat(manifestPath); err == nil {
+		if expectations, err = LoadCorpusManifest(manifestPath); err != nil {
+			return nil, err
+		}
+	}
+
+	files, err := SpecFiles(ctx, []string{dir})
+	if err != nil {
+		return nil, err
+	}
+	results := make([]CorpusResult, 0, len(files))
+	found := make(map[string]bool)
+	for _, file := range files {
+		relative, err := filepath.Rel(dir, file)
+		if err != nil || relative == CorpusManifest {
+			continue
+		}
+		relative = filepath.ToSlash(relative)
+		expected, listed := expectations[relative]
+		if spec, err := v.loadSpec(file); !listed && err == nil && !looksLikeSpec(spec) {
+			continue
+		}
+		found[relative] = true
+
+		if hierarchical {
+			_, err = v.ValidateWithInheritanceContext(ctx, file)
+		} else {
+			_, err = v.ValidateFileContext(ctx, file)
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, fmt.Errorf("testing corpus: %w", ctxErr)
+		}
+		result := CorpusResult{Path: relative, Expected: expected, Listed: listed, Found: true}
+		if err != nil {
+			result.Actual.LoadError = true
+			result.Err = err
+		} else {
+			result.Actual = corpusFindings(v.GetResults())
+		}
+		results = append(results, result)
+	}
+
+	missing := make([]string, 0)
+	for relative := range expectations {
+		if !found[relative] {
+			missing = append(missing, relative)
+		}
+	}
+	sort.Strings(missing)
+	for _, relative := range missing {
+		results = append(results, CorpusResult{Path: relative, Expected: expectations[relative], Listed: true})
+	}
+	return results, nil
+}
+
+// corpusFindings counts the findings of a result by code
+func corpusFindings(result ValidationResult) CorpusExpectation {
+	findings := CorpusExpectation{}
+	count := func(severity string, messages []string) map[string]int {
+		if len(messages) == 0 {
+			return nil
+		}
+		counts := make(map[string]int)
+		for _, message := range messages {
+			code := result.issue(severity, message).Code
+			if code == "" {
+				code = uncodedFinding
+			}
+			counts[code]++
+		}
+		return counts
+	}
+	findings.Errors = count("error", result.Errors)
+	findings.Warnings = count("warning", result.Warnings)
+	return findings
+}
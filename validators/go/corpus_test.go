@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestCorpus is the regression corpus of the validator: every file of
+// testdata/corpus must have the findings its expectations.yaml lists.
+// After an intended change, run: go run . test testdata/corpus --update
+func TestCorpus(t *testing.T) {
+	results, err := NewAPAIValidator().RunCorpus(context.Background(), "testdata/corpus", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) == 0 {
+		t.Fatal("expected specifications in the corpus")
+	}
+	for _, result := range results {
+		if !result.Passed() {
+			t.Errorf("%s: findings differ from expectations\n%s", result.Path, result.Diff())
+		}
+	}
+}
+
+func TestCorpusMismatches(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"missing-sections.yaml", "invalid-yaml.yaml"} {
+		content, err := os.ReadFile(filepath.Join("testdata/corpus/broken", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expectations := map[string]CorpusExpectation{
+		"missing-sections.yaml": {Errors: map[string]int{"MISSING_SECTION": 5}},
+		"deleted.yaml":          {},
+	}
+	if err := WriteCorpusManifest(filepath.Join(dir, CorpusManifest), expectations); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := NewAPAIValidator().RunCorpus(context.Background(), dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %+v", results)
+	}
+	unlisted, mismatched, missing := results[0], results[1], results[2]
+
+	if unlisted.Path != "invalid-yaml.yaml" || unlisted.Listed || !unlisted.Actual.LoadError || unlisted.Passed() {
+		t.Errorf("expected the unlisted invalid YAML to fail, got %+v", unlisted)
+	}
+	if mismatched.Path != "missing-sections.yaml" || mismatched.Passed() {
+		t.Errorf("expected different counts to fail, got %+v", mismatched)
+	}
+	if diff := mismatched.Diff(); !strings.Contains(diff, "-  MISSING_SECTION: 5\n+  MISSING_SECTION: 6\n") {
+		t.Errorf("unexpected diff:\n%s", diff)
+	}
+	if missing.Path != "deleted.yaml" || missing.Found || missing.Passed() {
+		t.Errorf("expected the missing file to fail, got %+v", missing)
+	}
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// charsPerToken approximates how many characters of English text make up a
+// token, to estimate prompt tokens from templates
+const charsPerToken = 4
+
+// ModelPrice is the price of a model per 1,000 tokens, in USD
+type ModelPrice struct {
+	InputPer1K  float64 `yaml:"input_per_1k_tokens" json:"input_per_1k_tokens"`
+	OutputPer1K float64 `yaml:"output_per_1k_tokens" json:"output_per_1k_tokens"`
+}
+
+// modelPrices is the built-in price table, keyed by "provider/name" in
+// lower case. Prices change often, so Config.Prices overrides it.
+var modelPrices = map[string]ModelPrice{
+	"openai/gpt-4":                {InputPer1K: 0.03, OutputPer1K: 0.06},
+	"openai/gpt-4-turbo":          {InputPer1K: 0.01, OutputPer1K: 0.03},
+	"openai/gpt-4o":               {InputPer1K: 0.0025, OutputPer1K: 0.01},
+	"openai/gpt-4o-mini":          {InputPer1K: 0.00015, OutputPer1K: 0.0006},
+	"openai/gpt-3.5-turbo":        {InputPer1K: 0.0005, OutputPer1K: 0.0015},
+	"azure-openai/gpt-4":          {InputPer1K: 0.03, OutputPer1K: 0.06},
+	"azure-openai/gpt-4o":         {InputPer1K: 0.0025, OutputPer1K: 0.01},
+	"anthropic/claude-3-opus":     {InputPer1K: 0.015, OutputPer1K: 0.075},
+	"anthropic/claude-3-5-sonnet": {InputPer1K: 0.003, OutputPer1K: 0.015},
+	"anthropic/claude-3-haiku":    {InputPer1K: 0.00025, OutputPer1K: 0.00125},
+	"google/gemini-1.5-pro":       {InputPer1K: 0.00125, OutputPer1K: 0.005},
+	"google/gemini-1.5-flash":     {InputPer1K: 0.000075, OutputPer1K: 0.0003},
+	"mistral/mistral-large":       {InputPer1K: 0.002, OutputPer1K: 0.006},
+	"cohere/command-r-plus":       {InputPer1K: 0.0025, OutputPer1K: 0.01},
+}
+
+// CostOptions configures a cost estimate
+type CostOptions struct {
+	// Invocations is how many times each task runs
+	Invocations int
+	// Prices overrides the built-in price table, keyed by "provider/name"
+	// or by model id
+	Prices map[string]ModelPrice
+}
+
+// CostReport estimates what running the tasks of a specification costs, in
+// USD. Low counts prompt tokens only; High also counts the full max_tokens
+// output of every model step.
+type CostReport struct {
+	Invocations int        `json:"invocations"`
+	Tasks       []TaskCost `json:"tasks"`
+	Low         float64    `json:"low"`
+	High        float64    `json:"high"`
+	// Unpriced lists the ids of models missing from the price table, whose
+	// steps are not counted
+	Unpriced []string `json:"unpriced"`
+}
+
+// TaskCost is the estimated cost of a task over all invocations
+type TaskCost struct {
+	ID       string   `json:"id"`
+	Low      float64  `json:"low"`
+	High     float64  `json:"high"`
+	Unpriced []string `json:"unpriced,omitempty"`
+}
+
+// EstimateCost estimates the cost of the tasks of spec. Each model step
+// sends its prompt template and few-shot examples, estimated at one token
+// per four characters, and receives at most the model's max_tokens, or
+// limits.max_output_tokens without it. Prices are looked up in opts.Prices, then in the model's own
+// cost block, then in the built-in table.
+func EstimateCost(spec map[string]interface{}, opts CostOptions) (CostReport, error) {
+	report := CostReport{Invocations: opts.Invocations, Tasks: make([]TaskCost, 0), Unpriced: make([]string, 0)}
+	if opts.Invocations < 1 {
+		return report, fmt.Errorf("invocations must be a positive integer, got %d", opts.Invocations)
+	}
+
+	models := make(map[string]map[string]interface{})
+	modelsSlice, _ := spec["models"].([]interface{})
+	for _, model := range modelsSlice {
+		if modelMap, ok := model.(map[string]interface{}); ok {
+			if id, ok := modelMap["id"].(string); ok {
+				models[id] = modelMap
+			}
+		}
+	}
+
+	promptTokens := make(map[string]int)
+	prompts, _ := spec["prompts"].([]interface{})
+	for _, prompt := range prompts {
+		if promptMap, ok := prompt.(map[string]interface{}); ok {
+			id, _ := promptMap["id"].(string)
+			promptTokens[id] = estimateTokens(promptText(promptMap))
+		}
+	}
+
+	tasks, _ := spec["tasks"].([]interface{})
+	for index, task := range tasks {
+		taskMap, ok := task.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		taskCost := TaskCost{ID: elementName(index, taskMap)}
+
+		steps, _ := taskMap["steps"].([]interface{})
+		for _, step := range steps {
+			stepMap, ok := step.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			modelID, ok := stepMap["model"].(string)
+			if !ok || modelID == "" {
+				continue
+			}
+			model, exists := models[modelID]
+			if !exists {
+				name, _ := stepMap["name"].(string)
+				return report, fmt.Errorf("task %s step %s references undeclared model %s", taskCost.ID, name, modelID)
+			}
+
+			price, priced := lookupPrice(modelID, model, opts.Prices)
+			if !priced {
+				if !containsString(taskCost.Unpriced, modelID) {
+					taskCost.Unpriced = append(taskCost.Unpriced, modelID)
+				}
+				if !containsString(report.Unpriced, modelID) {
+					report.Unpriced = append(report.Unpriced, modelID)
+				}
+				continue
+			}
+
+			promptID, _ := stepMap["prompt"].(string)
+			input := float64(promptTokens[promptID]) / 1000 * price.InputPer1K
+			output := float64(maxOutputTokens(model)) / 1000 * price.OutputPer1K
+			taskCost.Low += input * float64(opts.Invocations)
+			taskCost.High += (input + output) * float64(opts.Invocations)
+		}
+
+		report.Low += taskCost.Low
+		report.High += taskCost.High
+		report.Tasks = append(report.Tasks, taskCost)
+	}
+	return report, nil
+}
+
+// lookupPrice finds the price of a model by id or "provider/name" in the
+// given prices, then in its cost block, then in the built-in table
+func lookupPrice(modelID string, model map[string]interface{}, prices map[string]ModelPrice) (ModelPrice, bool) {
+	provider, _ := model["provider"].(string)
+	name, _ := model["name"].(string)
+	key := strings.ToLower(provider + "/" + name)
+
+	for candidate, price := range prices {
+		if candidate == modelID || strings.EqualFold(candidate, key) {
+			return price, true
+		}
+	}
+
+	if cost, ok := model["cost"].(map[string]interface{}); ok {
+		input, hasInput := numberValue(cost["input_per_1k_tokens"])
+		output, hasOutput := numberValue(cost["output_per_1k_tokens"])
+		if hasInput || hasOutput {
+			return ModelPrice{InputPer1K: input, OutputPer1K: output}, true
+		}
+	}
+
+	price, ok := modelPrices[key]
+	return price, ok
+}
+
+// maxOutputTokens returns the most tokens a model call may return
+func maxOutputTokens(model map[string]interface{}) int {
+	parameters, _ := model["parameters"].(map[string]interface{})
+	if maxTokens, ok := numberValue(parameters["max_tokens"]); ok {
+		return int(maxTokens)
+	}
+	limits, _ := model["limits"].(map[string]interface{})
+	if maxTokens, ok := numberValue(limits["max_output_tokens"]); ok {
+		return int(maxTokens)
+	}
+	return 0
+}
+
+// promptText returns the text a prompt sends: its template followed by the
+// inputs and outputs of its few-shot examples, objects as JSON
+func promptText(promptMap map[string]interface{}) string {
+	value, _ := currentField(promptMap, "prompts[].template")
+	template, _ := value.(string)
+	parts := []string{template}
+	examples, _ := promptMap["examples"].([]interface{})
+	for _, example := range examples {
+		exampleMap, ok := example.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		inputField, outputField := exampleFields(exampleMap)
+		for _, field := range []string{inputField, outputField} {
+			switch typed := exampleMap[field].(type) {
+			case string:
+				parts = append(parts, typed)
+			case map[string]interface{}:
+				if content, err := json.Marshal(typed); err == nil {
+					parts = append(parts, string(content))
+				}
+			}
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// estimateTokens estimates the number of tokens of a text
+func estimateTokens(text string) int {
+	return int(math.Ceil(float64(utf8.RuneCountInString(text)) / charsPerToken))
+}
+
+// numberValue returns the value of a YAML or JSON number
+func numberValue(value interface{}) (float64, bool) {
+	switch typed := value.(type) {
+	case int:
+		return float64(typed), true
+	case int64:
+		return float64(typed), true
+	case uint64:
+		return float64(typed), true
+	case float64:
+		return typed, true
+	}
+	return 0, false
+}
+
+// costRateFields are the rates a model cost block may declare, per 1,000
+// tokens as their names say
+var costRateFields = []string{"input_per_1k_tokens", "output_per_1k_tokens"}
+
+// currencyPattern matches an ISO 4217 currency code such as USD
+var currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// validateModelCost checks the cost block of a model: numeric, non-negative
+// rates and a currency code. It returns the currency, or "" when the block
+// declares none.
+func validateModelCost(f *sectionFindings, cost interface{}, modelName string) string {
+	costMap, ok := cost.(map[string]interface{})
+	if !ok {
+		f.addError("INVALID_TYPE", fmt.Sprintf("Model %s cost must be an object", modelName))
+		return ""
+	}
+
+	rates := 0
+	for _, field := range costRateFields {
+		value, exists := costMap[field]
+		if !exists {
+			continue
+		}
+		rates++
+		// Quoted numbers are reported by the numeric field check
+		if _, isString := value.(string); isString {
+			continue
+		}
+		rate, ok := numberValue(value)
+		switch {
+		case !ok:
+			f.addError("INVALID_COST", fmt.Sprintf("Invalid cost for model %s: %s must be a number, got %v", modelName, field, value))
+		case rate < 0:
+			f.addError("NEGATIVE_COST", fmt.Sprintf("Negative cost for model %s: %s is %v", modelName, field, value))
+		}
+	}
+	if rates == 0 {
+		f.addError("INVALID_COST", fmt.Sprintf("Invalid cost for model %s: declare %s", modelName, strings.Join(costRateFields, " or ")))
+	}
+
+	currency, exists := costMap["currency"]
+	if !exists {
+		f.addError("INVALID_COST", fmt.Sprintf("Invalid cost for model %s: missing currency", modelName))
+		return ""
+	}
+	currencyStr, ok := currency.(string)
+	if !ok || !currencyPattern.MatchString(currencyStr) {
+		f.addError("INVALID_COST", fmt.Sprintf("Invalid cost for model %s: currency must be an ISO 4217 code such as USD, got %v", modelName, currency))
+		return ""
+	}
+	return currencyStr
+}
+
+// validateCostCurrencies warns when models declare costs in different
+// currencies, which cost estimates add up as if they were the same
+func validateCostCurrencies(f *sectionFindings, currencies map[string][]string) {
+	if len(currencies) < 2 {
+		return
+	}
+	codes := make([]string, 0, len(currencies))
+	for currency := range currencies {
+		codes = append(codes, currency)
+	}
+	sort.Strings(codes)
+
+	groups := make([]string, 0, len(codes))
+	for _, currency := range codes {
+		groups = append(groups, fmt.Sprintf("%s (%s)", currency, strings.Join(currencies[currency], ", ")))
+	}
+	f.addWarning("MIXED_CURRENCIES", fmt.Sprintf("Models declare costs in different currencies: %s; aggregate cost estimates are unreliable", strings.Join(groups, ", ")))
+}
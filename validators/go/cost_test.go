@@ -0,0 +1,106 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEstimateCost(t *testing.T) {
+	spec := map[string]interface{}{
+		"models": []interface{}{
+			map[string]interface{}{"id": "main_model", "provider": "OpenAI", "name": "gpt-4",
+				"parameters": map[string]interface{}{"max_tokens": 500}},
+			map[string]interface{}{"id": "local_model", "provider": "custom", "name": "llama",
+				"limits": map[string]interface{}{"max_output_tokens": 100}},
+			map[string]interface{}{"id": "declared_model", "provider": "custom", "name": "in-house",
+				"cost":       map[string]interface{}{"input_per_1k_tokens": 0.5, "output_per_1k_tokens": 1},
+				"parameters": map[string]interface{}{"max_tokens": 1000}},
+		},
+		"prompts": []interface{}{
+			// 3,960 characters of template and 40 of examples, with their line
+			// breaks, estimate to 1,000 tokens
+			map[string]interface{}{"id": "long_prompt", "template": strings.Repeat("abcd", 990), "examples": []interface{}{
+				map[string]interface{}{"input": strings.Repeat("a", 19), "output": strings.Repeat("b", 19)},
+			}},
+		},
+		"tasks": []interface{}{
+			map[string]interface{}{"id": "answer", "steps": []interface{}{
+				map[string]interface{}{"name": "draft", "model": "main_model", "prompt": "long_prompt"},
+				map[string]interface{}{"name": "lookup", "action": "search"},
+				map[string]interface{}{"name": "review", "model": "local_model", "prompt": "long_prompt"},
+			}},
+			map[string]interface{}{"id": "summarize", "steps": []interface{}{
+				map[string]interface{}{"name": "summary", "model": "declared_model"},
+			}},
+		},
+	}
+	prices := map[string]ModelPrice{"openai/gpt-4": {InputPer1K: 0.01, OutputPer1K: 0.02}}
+
+	report, err := EstimateCost(spec, CostOptions{Invocations: 10, Prices: prices})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	costs := map[string][2]float64{
+		// 10 x (1,000 input tokens x 0.01 + 500 output tokens x 0.02) / 1,000
+		"answer": {0.1, 0.2},
+		// 10 x (no prompt + 1,000 output tokens x 1) / 1,000
+		"summarize": {0, 10},
+	}
+	for _, task := range report.Tasks {
+		want := costs[task.ID]
+		if !closeTo(task.Low, want[0]) || !closeTo(task.High, want[1]) {
+			t.Errorf("task %s: got %v - %v, want %v - %v", task.ID, task.Low, task.High, want[0], want[1])
+		}
+	}
+	if !closeTo(report.Low, 0.1) || !closeTo(report.High, 10.2) {
+		t.Errorf("total: got %v - %v, want 0.1 - 10.2", report.Low, report.High)
+	}
+	if !reflect.DeepEqual(report.Unpriced, []string{"local_model"}) || !reflect.DeepEqual(report.Tasks[0].Unpriced, []string{"local_model"}) {
+		t.Errorf("expected local_model to be reported as unpriced, got %v", report.Unpriced)
+	}
+
+	if _, err := EstimateCost(spec, CostOptions{Invocations: 0}); err == nil {
+		t.Error("expected an error for zero invocations")
+	}
+}
+
+func closeTo(got, want float64) bool {
+	return math.Abs(got-want) < 1e-9
+}
+
+func TestValidateModelCost(t *testing.T) {
+	models := []interface{}{
+		map[string]interface{}{"id": "main", "cost": map[string]interface{}{"input_per_1k_tokens": 0.03, "output_per_1k_tokens": 0.06, "currency": "USD"}},
+		map[string]interface{}{"id": "negative", "cost": map[string]interface{}{"input_per_1k_tokens": -0.01, "currency": "USD"}},
+		map[string]interface{}{"id": "european", "cost": map[string]interface{}{"output_per_1k_tokens": 0.05, "currency": "EUR"}},
+		map[string]interface{}{"id": "unpriced", "cost": map[string]interface{}{"currency": "dollars"}},
+		map[string]interface{}{"id": "flat", "cost": 5},
+	}
+
+	f := &sectionFindings{}
+	currencies := make(map[string][]string)
+	for i, model := range models {
+		modelMap := model.(map[string]interface{})
+		if currency := validateModelCost(f, modelMap["cost"], elementName(i, modelMap)); currency != "" {
+			currencies[currency] = append(currencies[currency], elementName(i, modelMap))
+		}
+	}
+	validateCostCurrencies(f, currencies)
+
+	wantErrors := []string{
+		"Negative cost for model negative: input_per_1k_tokens is -0.01",
+		"Invalid cost for model unpriced: declare input_per_1k_tokens or output_per_1k_tokens",
+		"Invalid cost for model unpriced: currency must be an ISO 4217 code such as USD, got dollars",
+		"Model flat cost must be an object",
+	}
+	if !reflect.DeepEqual(f.Errors, wantErrors) {
+		t.Errorf("errors: got %q, want %q", f.Errors, wantErrors)
+	}
+	wantWarnings := []string{"Models declare costs in different currencies: EUR (european), USD (main, negative); aggregate cost estimates are unreliable"}
+	if !reflect.DeepEqual(f.Warnings, wantWarnings) {
+		t.Errorf("warnings: got %q, want %q", f.Warnings, wantWarnings)
+	}
+}
@@ -0,0 +1,360 @@
+package main
+
+import "fmt"
+
+// stepValues returns the names a step declares in its inputs or outputs,
+// written as an array of names or an object keyed by name, with the types
+// the object form declares. It reports false for any other value.
+func stepValues(value interface{}) ([]string, map[string]string, bool) {
+	names := make([]string, 0)
+	types := make(map[string]string)
+	switch typed := value.(type) {
+	case nil:
+		return names, types, true
+	case []interface{}:
+		for _, item := range typed {
+			name, ok := item.(string)
+			if !ok || name == "" {
+				return nil, nil, false
+			}
+			names = append(names, name)
+		}
+	case map[string]interface{}:
+		names = sortedKeys(typed)
+		for _, name := range names {
+			if declaration, ok := typed[name].(map[string]interface{}); ok {
+				types[name], _ = declaration["type"].(string)
+			}
+		}
+	default:
+		return nil, nil, false
+	}
+	return names, types, true
+}
+
+// stepNode is a node of the flow graph of a task: a single step, or the
+// steps of a parallel group, which start together and are joined before
+// the next node runs
+type stepNode struct {
+	group   string
+	members []int
+}
+
+// stepFlow is the flow graph of the steps of a task
+type stepFlow struct {
+	nodes      []stepNode
+	nodeOf     []int
+	successors [][]int
+}
+
+// parallelGroup returns the parallel group of a step: its parallel_group,
+// or for a step with parallel: true the name of the first of the adjacent
+// parallel steps. It returns "" for a step that runs alone.
+func parallelGroup(steps []map[string]interface{}, index int) string {
+	if group, ok := steps[index]["parallel_group"].(string); ok && group != "" {
+		return group
+	}
+	if parallel, _ := steps[index]["parallel"].(bool); !parallel {
+		return ""
+	}
+	for index > 0 {
+		previous := steps[index-1]
+		if parallel, _ := previous["parallel"].(bool); !parallel || previous["parallel_group"] != nil {
+			break
+		}
+		index--
+	}
+	if name, ok := steps[index]["name"].(string); ok && name != "" {
+		return name
+	}
+	return fmt.Sprintf("%d", index)
+}
+
+// buildStepFlow builds the flow graph of a task. A node flows to the next
+// one, and to the steps named by the then of the conditions of its steps.
+// Parallel groups must be contiguous, must be entered at their first step
+// and must not branch, so that the step after a group joins all of them.
+func buildStepFlow(f *sectionFindings, steps []map[string]interface{}, taskIndex int) *stepFlow {
+	flow := &stepFlow{nodeOf: make([]int, len(steps))}
+	seen := make(map[string]bool)
+	for i := range steps {
+		group := parallelGroup(steps, i)
+		if group != "" && i > 0 && parallelGroup(steps, i-1) == group {
+			node := len(flow.nodes) - 1
+			flow.nodes[node].members = append(flow.nodes[node].members, i)
+			flow.nodeOf[i] = node
+			continue
+		}
+		if group != "" && seen[group] {
+			f.addError("PARALLEL_GROUP_FLOW", fmt.Sprintf("Step tasks[%d].steps[%d] of parallel group %s is not next to the other members", taskIndex, i, group))
+		}
+		seen[group] = true
+		flow.nodeOf[i] = len(flow.nodes)
+		flow.nodes = append(flow.nodes, stepNode{group: group, members: []int{i}})
+	}
+
+	indexes := make(map[string]int)
+	for i, step := range steps {
+		if name, ok := step["name"].(string); ok {
+			if _, seen := indexes[name]; !seen {
+				indexes[name] = i
+			}
+		}
+	}
+	flow.successors = make([][]int, len(flow.nodes))
+	for n, node := range flow.nodes {
+		last := node.members[len(node.members)-1]
+		if last+1 < len(steps) {
+			flow.successors[n] = append(flow.successors[n], flow.nodeOf[last+1])
+		}
+		for _, i := range node.members {
+			conditions, _ := steps[i]["conditions"].([]interface{})
+			for _, condition := range conditions {
+				conditionMap, _ := condition.(map[string]interface{})
+				target, ok := conditionMap["then"].(string)
+				index, exists := indexes[target]
+				if !ok || !exists {
+					continue
+				}
+				if node.group != "" {
+					f.addError("PARALLEL_GROUP_FLOW", fmt.Sprintf("Step tasks[%d].steps[%d] of parallel group %s branches with then, so the join cannot wait on every member", taskIndex, i, node.group))
+				}
+				targetNode := flow.nodes[flow.nodeOf[index]]
+				if targetNode.group != "" && targetNode.members[0] != index {
+					f.addError("PARALLEL_GROUP_FLOW", fmt.Sprintf("Step tasks[%d].steps[%d] jumps into the middle of parallel group %s at step tasks[%d].steps[%d]", taskIndex, i, targetNode.group, taskIndex, index))
+				}
+				if !containsInt(flow.successors[n], flow.nodeOf[index]) {
+					flow.successors[n] = append(flow.successors[n], flow.nodeOf[index])
+				}
+			}
+		}
+	}
+	return flow
+}
+
+// reachable returns, for each node, the nodes that can run after it
+func (flow *stepFlow) reachable() []map[int]bool {
+	reachable := make([]map[int]bool, len(flow.nodes))
+	for n := range flow.nodes {
+		reachable[n] = make(map[int]bool)
+		queue := append([]int{}, flow.successors[n]...)
+		for len(queue) > 0 {
+			next := queue[0]
+			queue = queue[1:]
+			if reachable[n][next] {
+				continue
+			}
+			reachable[n][next] = true
+			queue = append(queue, flow.successors[next]...)
+		}
+	}
+	return reachable
+}
+
+// containsInt reports whether a slice contains the given int
+func containsInt(values []int, value int) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+// validateTaskDataFlow checks the wiring of the named values of a task:
+// every step input must be a task input or the output of a step running
+// before it, on every path when conditions branch, outputs should be
+// consumed, and steps on the same path must agree on the type of an
+// output. The steps of a parallel group run together: none may consume the
+// output of another, and the step after the group sees all their outputs.
+func validateTaskDataFlow(f *sectionFindings, taskMap map[string]interface{}, taskIndex int) {
+	stepsSlice, _ := taskMap["steps"].([]interface{})
+	steps := make([]map[string]interface{}, 0, len(stepsSlice))
+	for _, step := range stepsSlice {
+		if stepMap, ok := step.(map[string]interface{}); ok {
+			steps = append(steps, stepMap)
+		}
+	}
+	if len(steps) == 0 || len(steps) < len(stepsSlice) {
+		return
+	}
+
+	inputs := make([][]string, len(steps))
+	outputs := make([][]string, len(steps))
+	outputTypes := make([]map[string]string, len(steps))
+	declared := false
+	for i, step := range steps {
+		location := fmt.Sprintf("tasks[%d].steps[%d]", taskIndex, i)
+		var ok bool
+		if inputs[i], _, ok = stepValues(step["inputs"]); !ok {
+			f.addError("INVALID_TYPE", fmt.Sprintf("Step %s inputs must be an array of names or an object keyed by name", location))
+		}
+		if outputs[i], outputTypes[i], ok = stepValues(step["outputs"]); !ok {
+			f.addError("INVALID_TYPE", fmt.Sprintf("Step %s outputs must be an array of names or an object keyed by name", location))
+		}
+		declared = declared || len(inputs[i]) > 0 || len(outputs[i]) > 0
+		if value, exists := step["parallel"]; exists {
+			if _, ok := value.(bool); !ok && !isStringValue(value) {
+				f.addError("INVALID_TYPE", fmt.Sprintf("Step %s parallel must be a boolean", location))
+			}
+		}
+		if value, exists := step["parallel_group"]; exists {
+			if _, ok := value.(string); !ok {
+				f.addError("INVALID_TYPE", fmt.Sprintf("Step %s parallel_group must be a string", location))
+			}
+		}
+	}
+
+	flow := buildStepFlow(f, steps, taskIndex)
+	members := make(map[string]int)
+	for _, node := range flow.nodes {
+		members[node.group] += len(node.members)
+	}
+	for _, node := range flow.nodes {
+		if node.group != "" && members[node.group] == 1 {
+			f.addWarning("SINGLE_STEP_PARALLEL_GROUP", fmt.Sprintf("Parallel group %s of task %s has a single step", node.group, elementName(taskIndex, taskMap)))
+		}
+	}
+	if !declared {
+		return
+	}
+
+	taskInputs, _ := taskMap["input"].(map[string]interface{})
+	taskOutputs, _ := taskMap["output"].(map[string]interface{})
+	provided := make(map[string]bool)
+	for name := range taskInputs {
+		provided[name] = true
+	}
+	all := copyNames(provided)
+	for _, names := range outputs {
+		for _, name := range names {
+			all[name] = true
+		}
+	}
+
+	// Values a node produces: the outputs of all its steps, as the next
+	// node joins them
+	produced := make([]map[string]bool, len(flow.nodes))
+	for n, node := range flow.nodes {
+		produced[n] = make(map[string]bool)
+		for _, i := range node.members {
+			for _, name := range outputs[i] {
+				produced[n][name] = true
+			}
+		}
+	}
+
+	// Values available before each node on every path (must) and on some
+	// path (may), iterated to a fixed point over the flow edges
+	predecessors := make([][]int, len(flow.nodes))
+	for from, targets := range flow.successors {
+		for _, to := range targets {
+			predecessors[to] = append(predecessors[to], from)
+		}
+	}
+	reached := make([]bool, len(flow.nodes))
+	must := make([]map[string]bool, len(flow.nodes))
+	may := make([]map[string]bool, len(flow.nodes))
+	for n := range flow.nodes {
+		must[n], may[n] = copyNames(all), make(map[string]bool)
+	}
+	must[0], may[0], reached[0] = copyNames(provided), copyNames(provided), true
+	for changed := true; changed; {
+		changed = false
+		for n := 1; n < len(flow.nodes); n++ {
+			mustIn, mayIn, reachedIn := copyNames(all), make(map[string]bool), false
+			for _, p := range predecessors[n] {
+				if !reached[p] {
+					continue
+				}
+				reachedIn = true
+				for name := range all {
+					if !must[p][name] && !produced[p][name] {
+						delete(mustIn, name)
+					}
+					if may[p][name] || produced[p][name] {
+						mayIn[name] = true
+					}
+				}
+			}
+			if !reachedIn {
+				continue
+			}
+			if !reached[n] || len(mustIn) != len(must[n]) || len(mayIn) != len(may[n]) {
+				changed = true
+			}
+			reached[n], must[n], may[n] = true, mustIn, mayIn
+		}
+	}
+
+	consumed := make(map[string]bool)
+	for name := range taskOutputs {
+		consumed[name] = true
+	}
+	for i := range steps {
+		location := fmt.Sprintf("tasks[%d].steps[%d]", taskIndex, i)
+		n := flow.nodeOf[i]
+		for _, name := range inputs[i] {
+			consumed[name] = true
+			if sibling := siblingProducer(flow.nodes[n], outputs, i, name); sibling >= 0 {
+				f.addError("PARALLEL_DEPENDENCY", fmt.Sprintf("Step %s input %s is produced by step tasks[%d].steps[%d] of the same parallel group %s", location, name, taskIndex, sibling, flow.nodes[n].group))
+				continue
+			}
+			switch {
+			case !reached[n] || must[n][name]:
+			case may[n][name]:
+				f.addWarning("CONDITIONAL_STEP_INPUT", fmt.Sprintf("Step %s input %s is only provided on some paths to it", location, name))
+			default:
+				f.addError("UNPROVIDED_STEP_INPUT", fmt.Sprintf("Step %s input %s is not provided by the task or an earlier step", location, name))
+			}
+		}
+	}
+	for i := range steps {
+		for _, name := range outputs[i] {
+			if !consumed[name] {
+				f.addWarning("UNUSED_STEP_OUTPUT", fmt.Sprintf("Step tasks[%d].steps[%d] output %s is never consumed", taskIndex, i, name))
+			}
+		}
+	}
+
+	// Steps that can run one after the other must agree on output types
+	reachable := flow.reachable()
+	for a := range steps {
+		for b := a + 1; b < len(steps); b++ {
+			nodeA, nodeB := flow.nodeOf[a], flow.nodeOf[b]
+			if nodeA == nodeB || !reachable[nodeA][nodeB] && !reachable[nodeB][nodeA] {
+				continue
+			}
+			for _, name := range outputs[a] {
+				typeA, typeB := outputTypes[a][name], outputTypes[b][name]
+				if typeA != "" && typeB != "" && typeA != typeB {
+					f.addError("CONFLICTING_OUTPUT_TYPES", fmt.Sprintf("Steps tasks[%d].steps[%d] and tasks[%d].steps[%d] produce output %s with different types: %s, %s",
+						taskIndex, a, taskIndex, b, name, typeA, typeB))
+				}
+			}
+		}
+	}
+}
+
+// siblingProducer returns the other step of the parallel group of a step
+// producing a value, or -1 when there is none
+func siblingProducer(node stepNode, outputs [][]string, step int, name string) int {
+	if node.group == "" {
+		return -1
+	}
+	for _, member := range node.members {
+		if member != step && containsString(outputs[member], name) {
+			return member
+		}
+	}
+	return -1
+}
+
+// copyNames returns a copy of a set of names
+func copyNames(names map[string]bool) map[string]bool {
+	set := make(map[string]bool, len(names))
+	for name := range names {
+		set[name] = true
+	}
+	return set
+}
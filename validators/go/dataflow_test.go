@@ -0,0 +1,117 @@
+package main
+
+import "testing"
+
+func TestValidateTaskDataFlow(t *testing.T) {
+	step := func(name string, inputs, outputs interface{}, then string) interface{} {
+		step := map[string]interface{}{"name": name, "action": "generate"}
+		if inputs != nil {
+			step["inputs"] = inputs
+		}
+		if outputs != nil {
+			step["outputs"] = outputs
+		}
+		if then != "" {
+			step["conditions"] = []interface{}{map[string]interface{}{"if": "urgent", "then": then}}
+		}
+		return step
+	}
+	task := map[string]interface{}{
+		"id": "support", "description": "Support",
+		"input":  map[string]interface{}{"message": map[string]interface{}{"type": "string"}},
+		"output": map[string]interface{}{"reply": map[string]interface{}{"type": "string"}},
+		"steps": []interface{}{
+			step("classify", []interface{}{"message"}, []interface{}{"category"}, "answer"),
+			step("lookup", []interface{}{"category"}, map[string]interface{}{"order": map[string]interface{}{"type": "object"}, "notes": nil}, ""),
+			step("answer", []interface{}{"order", "customer"}, map[string]interface{}{"reply": map[string]interface{}{"type": "string"}}, ""),
+			step("rephrase", []interface{}{"reply"}, map[string]interface{}{"reply": map[string]interface{}{"type": "object"}}, ""),
+			step("broken", "order", nil, ""),
+		},
+	}
+
+	f := &sectionFindings{}
+	validateTaskDataFlow(f, task, 0)
+	wantErrors := map[string]string{
+		"Step tasks[0].steps[2] input customer is not provided by the task or an earlier step":                    "UNPROVIDED_STEP_INPUT",
+		"Steps tasks[0].steps[2] and tasks[0].steps[3] produce output reply with different types: string, object": "CONFLICTING_OUTPUT_TYPES",
+		"Step tasks[0].steps[4] inputs must be an array of names or an object keyed by name":                      "INVALID_TYPE",
+	}
+	wantWarnings := map[string]string{
+		"Step tasks[0].steps[2] input order is only provided on some paths to it": "CONDITIONAL_STEP_INPUT",
+		"Step tasks[0].steps[1] output notes is never consumed":                   "UNUSED_STEP_OUTPUT",
+	}
+	for findings, want := range map[*[]string]map[string]string{&f.Errors: wantErrors, &f.Warnings: wantWarnings} {
+		if len(*findings) != len(want) {
+			t.Errorf("expected %d findings, got %v", len(want), *findings)
+		}
+		for message, code := range want {
+			if !containsString(*findings, message) {
+				t.Errorf("missing %q in %v", message, *findings)
+			}
+			if rule, _ := MatchRule(message); rule.Code != code {
+				t.Errorf("expected %s for %q, got %q", code, message, rule.Code)
+			}
+		}
+	}
+
+	// Steps declaring no inputs or outputs are not checked
+	f = &sectionFindings{}
+	validateTaskDataFlow(f, map[string]interface{}{"steps": []interface{}{step("one", nil, nil, ""), step("two", nil, nil, "")}}, 0)
+	if len(f.Errors)+len(f.Warnings) != 0 {
+		t.Errorf("unexpected findings: %v %v", f.Errors, f.Warnings)
+	}
+}
+
+func TestParallelStepGroups(t *testing.T) {
+	for file, wantErrors := range map[string][]string{
+		"testdata/parallel/fan-out.yaml": nil,
+		"testdata/parallel/broken-dependency.yaml": {
+			"Step tasks[0].steps[1] input order is produced by step tasks[0].steps[0] of the same parallel group lookups",
+		},
+	} {
+		validator := NewAPAIValidator()
+		validator.ValidateFile(file)
+		if len(validator.Errors) != len(wantErrors) || len(validator.Warnings) != 0 {
+			t.Errorf("%s: unexpected findings: %v %v", file, validator.Errors, validator.Warnings)
+		}
+		for _, want := range wantErrors {
+			if !containsString(validator.Errors, want) {
+				t.Errorf("%s: missing %q in %v", file, want, validator.Errors)
+			}
+		}
+	}
+
+	step := func(name string, fields ...interface{}) interface{} {
+		step := map[string]interface{}{"name": name, "action": "generate"}
+		for i := 0; i < len(fields); i += 2 {
+			step[fields[i].(string)] = fields[i+1]
+		}
+		return step
+	}
+	task := map[string]interface{}{"id": "triage", "steps": []interface{}{
+		step("route", "conditions", []interface{}{map[string]interface{}{"if": "urgent", "then": "score"}}),
+		step("classify", "parallel", true),
+		step("score", "parallel", true, "conditions", []interface{}{map[string]interface{}{"if": "low", "then": "reply"}}),
+		step("audit", "parallel_group", "checks"),
+		step("reply"),
+		step("log", "parallel_group", "checks"),
+		step("notify", "parallel_group", "alerts"),
+	}}
+
+	f := &sectionFindings{}
+	validateTaskDataFlow(f, task, 0)
+	want := map[string]string{
+		"Step tasks[0].steps[0] jumps into the middle of parallel group classify at step tasks[0].steps[2]":             "PARALLEL_GROUP_FLOW",
+		"Step tasks[0].steps[2] of parallel group classify branches with then, so the join cannot wait on every member": "PARALLEL_GROUP_FLOW",
+		"Step tasks[0].steps[5] of parallel group checks is not next to the other members":                              "PARALLEL_GROUP_FLOW",
+		"Parallel group alerts of task triage has a single step":                                                        "SINGLE_STEP_PARALLEL_GROUP",
+	}
+	for message, code := range want {
+		if !containsString(f.Errors, message) && !containsString(f.Warnings, message) {
+			t.Errorf("missing %q in %v %v", message, f.Errors, f.Warnings)
+		}
+		if rule, _ := MatchRule(message); rule.Code != code {
+			t.Errorf("expected %s for %q, got %q", code, message, rule.Code)
+		}
+	}
+}
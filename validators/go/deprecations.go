@@ -0,0 +1,254 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Deprecation describes a field renamed in a schema version. Paths use
+// dots between fields and [] for every element of an array, e.g.
+// "models[].purpose"; a field is renamed within the same parent object.
+type Deprecation struct {
+	OldPath string `json:"old_path"`
+	NewPath string `json:"new_path"`
+	Since   string `json:"since"`
+}
+
+// deprecations is the registry of renamed fields. It drives both the
+// deprecation warnings raised during validation and the migrate command.
+var deprecations = []Deprecation{
+	{OldPath: "models[].purpose", NewPath: "models[].intended_use", Since: "0.2.0"},
+	{OldPath: "info.ai_metadata.last_updated", NewPath: "info.ai_metadata.updated_at", Since: "0.2.0"},
+	{OldPath: "prompts[].text", NewPath: "prompts[].template", Since: "0.1.0"},
+}
+
+// fieldName returns the last field of a deprecation path
+func fieldName(fieldPath string) string {
+	return fieldPath[strings.LastIndex(fieldPath, ".")+1:]
+}
+
+// parentPath returns the path of the object holding a field
+func parentPath(fieldPath string) string {
+	if dot := strings.LastIndex(fieldPath, "."); dot >= 0 {
+		return fieldPath[:dot]
+	}
+	return ""
+}
+
+// replacementField returns the field that replaces a deprecated one, if any
+func replacementField(fieldPath string) (string, bool) {
+	for _, deprecation := range deprecations {
+		if deprecation.OldPath == fieldPath {
+			return fieldName(deprecation.NewPath), true
+		}
+	}
+	return "", false
+}
+
+// hasReplacement reports whether an object holds the replacement of a
+// deprecated field, which then satisfies a requirement for the old one
+func hasReplacement(object map[string]interface{}, fieldPath string) bool {
+	field, ok := replacementField(fieldPath)
+	if !ok {
+		return false
+	}
+	_, exists := object[field]
+	return exists
+}
+
+// currentField returns the value of a field of an object, or of the
+// deprecated field it replaces when only the old name is set, so that
+// specifications not yet migrated keep working
+func currentField(object map[string]interface{}, fieldPath string) (interface{}, bool) {
+	if value, exists := object[fieldName(fieldPath)]; exists {
+		return value, true
+	}
+	for _, deprecation := range deprecations {
+		if deprecation.NewPath == fieldPath {
+			if value, exists := object[fieldName(deprecation.OldPath)]; exists {
+				return value, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// deprecationApplies reports whether a deprecation is in effect for a
+// specification declaring the given apai version
+func deprecationApplies(deprecation Deprecation, version interface{}) bool {
+	versionStr, _ := version.(string)
+	current, currentPre, ok := parseSemver(versionStr)
+	if !ok {
+		return false
+	}
+	since, sincePre, _ := parseSemver(deprecation.Since)
+	return compareSemver(current, currentPre, since, sincePre) >= 0
+}
+
+// objectsAt calls visit with every object at path in the specification,
+// together with its concrete location such as "models[1]"
+func objectsAt(value interface{}, fieldPath, location string, visit func(map[string]interface{}, string)) {
+	if fieldPath == "" {
+		if object, ok := value.(map[string]interface{}); ok {
+			visit(object, location)
+		}
+		return
+	}
+
+	segment, rest := fieldPath, ""
+	if dot := strings.Index(fieldPath, "."); dot >= 0 {
+		segment, rest = fieldPath[:dot], fieldPath[dot+1:]
+	}
+	name := strings.TrimSuffix(segment, "[]")
+
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return
+	}
+	child, exists := object[name]
+	if !exists {
+		return
+	}
+
+	childLocation := name
+	if location != "" {
+		childLocation = location + "." + name
+	}
+	if !strings.HasSuffix(segment, "[]") {
+		objectsAt(child, rest, childLocation, visit)
+		return
+	}
+
+	items, _ := child.([]interface{})
+	for i, item := range items {
+		objectsAt(item, rest, fmt.Sprintf("%s[%d]", childLocation, i), visit)
+	}
+}
+
+// objectsWithin is objectsAt for a part of the specification: value is
+// the object at elementPath, such as "tasks[]", found at location, and
+// visit is called with the objects at fieldPath inside it. Paths outside
+// elementPath visit nothing; an empty elementPath is the whole specification.
+func objectsWithin(value interface{}, fieldPath, elementPath, location string, visit func(map[string]interface{}, string)) {
+	switch {
+	case elementPath == "":
+		objectsAt(value, fieldPath, location, visit)
+	case fieldPath == elementPath:
+		objectsAt(value, "", location, visit)
+	case strings.HasPrefix(fieldPath, elementPath+"."):
+		objectsAt(value, fieldPath[len(elementPath)+1:], location, visit)
+	}
+}
+
+// validateDeprecations warns about deprecated fields and reports an error
+// when a deprecated field and its replacement disagree
+func (v *APAIValidator) validateDeprecations(spec map[string]interface{}) {
+	v.validateDeprecationsWithin(spec, "", "", spec["apai"])
+}
+
+// validateDeprecationsWithin is validateDeprecations for the part of a
+// specification at elementPath, as in objectsWithin, written for the APAI
+// version apai
+func (v *APAIValidator) validateDeprecationsWithin(value interface{}, elementPath, location string, apai interface{}) {
+	for _, deprecation := range deprecations {
+		if !deprecationApplies(deprecation, apai) {
+			continue
+		}
+
+		oldField, newField := fieldName(deprecation.OldPath), fieldName(deprecation.NewPath)
+		objectsWithin(value, parentPath(deprecation.OldPath), elementPath, location, func(parent map[string]interface{}, location string) {
+			oldValue, exists := parent[oldField]
+			if !exists {
+				return
+			}
+
+			oldLocation, newLocation := joinLocation(location, oldField), joinLocation(location, newField)
+			if newValue, exists := parent[newField]; exists && !reflect.DeepEqual(oldValue, newValue) {
+				v.addError("DEPRECATED_FIELD_CONFLICT", fmt.Sprintf("%s conflicts with %s: deprecated and replacement fields have different values", oldLocation, newLocation))
+				return
+			}
+			v.addWarning("DEPRECATED_FIELD", fmt.Sprintf("%s is deprecated since %s, use %s", oldLocation, deprecation.Since, newField))
+		})
+	}
+}
+
+// joinLocation appends a field to a location
+func joinLocation(location, field string) string {
+	if location == "" {
+		return field
+	}
+	return location + "." + field
+}
+
+// MigrateSpec rewrites deprecated fields to their replacements for every
+// deprecation in effect at the target version and sets apai to it. It
+// returns the migrated copy and a description of each change; fields whose
+// replacement already holds a different value are left for the author.
+func MigrateSpec(spec map[string]interface{}, targetVersion string) (map[string]interface{}, []string) {
+	migrated, _ := copyValue(spec).(map[string]interface{})
+	changes := renameDeprecatedFields(migrated, targetVersion)
+
+	if migrated["apai"] != targetVersion {
+		changes = append(changes, fmt.Sprintf("set apai to %s", targetVersion))
+		migrated["apai"] = targetVersion
+	}
+	return migrated, changes
+}
+
+// FixDeprecatedFields rewrites the deprecated fields in effect at the
+// version a specification declares, leaving apai unchanged. It returns the
+// fixed copy and a description of each change.
+func FixDeprecatedFields(spec map[string]interface{}) (map[string]interface{}, []string) {
+	fixed, _ := copyValue(spec).(map[string]interface{})
+	return fixed, renameDeprecatedFields(fixed, spec["apai"])
+}
+
+// renameDeprecatedFields renames in place the deprecated fields in effect
+// at version and describes each change
+func renameDeprecatedFields(spec map[string]interface{}, version interface{}) []string {
+	changes := make([]string, 0)
+	for _, deprecation := range deprecations {
+		if !deprecationApplies(deprecation, version) {
+			continue
+		}
+
+		oldField, newField := fieldName(deprecation.OldPath), fieldName(deprecation.NewPath)
+		objectsAt(spec, parentPath(deprecation.OldPath), "", func(parent map[string]interface{}, location string) {
+			oldValue, exists := parent[oldField]
+			if !exists {
+				return
+			}
+
+			oldLocation, newLocation := joinLocation(location, oldField), joinLocation(location, newField)
+			if newValue, exists := parent[newField]; exists && !reflect.DeepEqual(oldValue, newValue) {
+				changes = append(changes, fmt.Sprintf("kept %s: %s already has a different value", oldLocation, newLocation))
+				return
+			}
+			parent[newField] = oldValue
+			delete(parent, oldField)
+			changes = append(changes, fmt.Sprintf("renamed %s to %s", oldLocation, newLocation))
+		})
+	}
+	return changes
+}
+
+// copyValue deep-copies maps and arrays of a decoded specification
+func copyValue(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(typed))
+		for key, item := range typed {
+			copied[key] = copyValue(item)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(typed))
+		for i, item := range typed {
+			copied[i] = copyValue(item)
+		}
+		return copied
+	default:
+		return typed
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func deprecatedSpec(t *testing.T, version string, model map[string]interface{}) map[string]interface{} {
+	t.Helper()
+	spec := loadExampleSpecs(t, "templates/basic-template.yaml")[0]
+	spec["apai"] = version
+	spec["models"] = []interface{}{model}
+	return spec
+}
+
+func TestDeprecatedFieldWarns(t *testing.T) {
+	model := map[string]interface{}{"id": "main_model", "type": "LLM", "provider": "openai", "name": "gpt-4", "purpose": "conversation"}
+
+	validator := NewAPAIValidator()
+	if !validator.ValidateSpec(deprecatedSpec(t, "0.2.0", model)) {
+		t.Fatalf("expected valid spec, got errors: %v", validator.Errors)
+	}
+	want := "models[0].purpose is deprecated since 0.2.0, use intended_use"
+	if !containsString(validator.Warnings, want) {
+		t.Errorf("missing %q in %v", want, validator.Warnings)
+	}
+	if !containsString(validator.Warnings, "info.ai_metadata.last_updated is deprecated since 0.2.0, use updated_at") {
+		t.Errorf("missing ai_metadata deprecation in %v", validator.Warnings)
+	}
+
+	// Deprecations only apply from the version that introduced them
+	validator.ValidateSpec(deprecatedSpec(t, "0.1.0", model))
+	if containsString(validator.Warnings, want) {
+		t.Error("deprecation reported for an older schema version")
+	}
+}
+
+func TestDeprecatedFieldConflicts(t *testing.T) {
+	model := map[string]interface{}{"id": "main_model", "type": "LLM", "provider": "openai", "name": "gpt-4",
+		"purpose": "conversation", "intended_use": "summarization"}
+
+	validator := NewAPAIValidator()
+	if validator.ValidateSpec(deprecatedSpec(t, "0.2.0", model)) {
+		t.Fatal("expected conflicting fields to fail validation")
+	}
+	want := "models[0].purpose conflicts with models[0].intended_use: deprecated and replacement fields have different values"
+	if !containsString(validator.Errors, want) {
+		t.Errorf("missing %q in %v", want, validator.Errors)
+	}
+}
+
+func TestMigrateSpecUsesDeprecations(t *testing.T) {
+	model := map[string]interface{}{"id": "main_model", "type": "LLM", "provider": "openai", "name": "gpt-4", "purpose": "conversation"}
+	spec := deprecatedSpec(t, "0.1.0", model)
+
+	migrated, changes := MigrateSpec(spec, "0.2.0")
+	want := []string{
+		"renamed models[0].purpose to models[0].intended_use",
+		"renamed info.ai_metadata.last_updated to info.ai_metadata.updated_at",
+		"set apai to 0.2.0",
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("changes = %v, want %v", changes, want)
+	}
+	if _, exists := model["intended_use"]; exists {
+		t.Error("migration modified its input")
+	}
+
+	// A migrated spec raises no deprecation findings
+	validator := NewAPAIValidator()
+	if !validator.ValidateSpec(migrated) {
+		t.Fatalf("migrated spec invalid: %v", validator.Errors)
+	}
+	for _, warning := range validator.Warnings {
+		if rule, _ := MatchRule(warning); rule.Code == "DEPRECATED_FIELD" {
+			t.Errorf("unexpected deprecation after migration: %s", warning)
+		}
+	}
+}
+
+func TestDeprecatedPromptText(t *testing.T) {
+	spec := loadExampleSpecs(t, "templates/basic-template.yaml")[0]
+	spec["apai"] = "0.1.0"
+	spec["prompts"] = []interface{}{
+		map[string]interface{}{"id": "system_prompt", "role": "system", "text": "You are a helpful assistant."},
+	}
+
+	validator := NewAPAIValidator()
+	if !validator.ValidateSpec(spec) {
+		t.Fatalf("expected the deprecated text to stand in for template, got errors: %v", validator.Errors)
+	}
+	want := "prompts[0].text is deprecated since 0.1.0, use template"
+	if !containsString(validator.Warnings, want) {
+		t.Errorf("missing %q in %v", want, validator.Warnings)
+	}
+
+	fixed, changes := FixDeprecatedFields(spec)
+	if !reflect.DeepEqual(changes, []string{"renamed prompts[0].text to prompts[0].template"}) {
+		t.Errorf("unexpected changes: %v", changes)
+	}
+	if fixed["apai"] != "0.1.0" {
+		t.Errorf("fix changed apai to %v", fixed["apai"])
+	}
+	prompt := fixed["prompts"].([]interface{})[0].(map[string]interface{})
+	if prompt["template"] != "You are a helpful assistant." || prompt["text"] != nil {
+		t.Errorf("unexpected fixed prompt: %v", prompt)
+	}
+
+	// The deprecated field is checked like the one it stands in for
+	spec["prompts"] = []interface{}{
+		map[string]interface{}{"id": "system_prompt", "role": "system", "text": "  "},
+	}
+	if validator.ValidateSpec(spec) {
+		t.Fatal("expected a blank deprecated text to fail validation")
+	}
+	if want := "Prompt 0 required field is empty: template"; !containsString(validator.Errors, want) {
+		t.Errorf("missing %q in %v", want, validator.Errors)
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Dependency is something outside a specification that it reaches out to:
+// a model provider, an MCP server command or URL, a knowledge source, a
+// referenced file or an inherited specification
+type Dependency struct {
+	// Type is model_provider, mcp_command, mcp_url, url, database, api,
+	// file, inherits, registry or remote
+	Type string `json:"type"`
+	// Name identifies the dependency: a provider, command line, URL or path
+	Name string `json:"name"`
+	// Location is where the effective specification declares it, e.g.
+	// models[0].provider; for inherits, the entry of the spec file
+	Location string `json:"location"`
+	// Spec is the file declaring an inherits entry
+	Spec string `json:"spec,omitempty"`
+	// Resolved is the file an inherits entry resolved to, if it did
+	Resolved string `json:"resolved,omitempty"`
+}
+
+// Dependencies lists the external dependencies of a specification: the
+// inherits entries of specPath and of the parents loaded while resolving
+// it, then those the effective spec declares. Call it after
+// ResolveSpecContext with the merged spec it returned. Credentials in
+// URLs and connection strings are left out.
+func (v *APAIValidator) Dependencies(spec map[string]interface{}, specPath string) []Dependency {
+	dependencies := make([]Dependency, 0)
+	add := func(dependency Dependency) {
+		for _, existing := range dependencies {
+			if existing == dependency {
+				return
+			}
+		}
+		dependencies = append(dependencies, dependency)
+	}
+
+	// The merged spec keeps only the inherits of the spec itself
+	declaring := []string{specPath}
+	parents := make([]string, 0, len(v.inheritedSpecs))
+	for parentPath := range v.inheritedSpecs {
+		parents = append(parents, parentPath)
+	}
+	sort.Strings(parents)
+	declaring = append(declaring, parents...)
+	for _, declaringPath := range declaring {
+		declared := spec
+		if declaringPath != specPath {
+			declared = v.inheritedSpecs[declaringPath]
+		}
+		inherits, _ := declared["inherits"].([]interface{})
+		for i, entry := range inherits {
+			ref, ok := entry.(string)
+			if !ok {
+				continue
+			}
+			dependency := Dependency{Type: "inherits", Name: ref, Location: fmt.Sprintf("inherits[%d]", i), Spec: declaringPath}
+			switch {
+			case isValidURL(ref):
+				dependency.Type, dependency.Name = "remote", withoutCredentials(ref)
+			case isRegistryReference(ref):
+				dependency.Type = "registry"
+			}
+			if dependency.Type != "remote" {
+				if resolved, err := v.resolveInheritancePath(ref, declaringPath); err == nil && v.fileExists(resolved) {
+					dependency.Resolved = resolved
+				}
+			}
+			add(dependency)
+		}
+	}
+
+	models, _ := spec["models"].([]interface{})
+	for index, model := range models {
+		modelMap, _ := model.(map[string]interface{})
+		if provider, ok := modelMap["provider"].(string); ok && provider != "" {
+			add(Dependency{Type: "model_provider", Name: provider, Location: fmt.Sprintf("models[%d].provider", index)})
+		}
+	}
+
+	objectsAt(spec, "context.mcp_servers[].transport", "", func(transport map[string]interface{}, location string) {
+		if command, ok := transport["command"].(string); ok && command != "" {
+			line := []string{command}
+			args, _ := transport["args"].([]interface{})
+			for _, arg := range args {
+				line = append(line, fmt.Sprint(arg))
+			}
+			add(Dependency{Type: "mcp_command", Name: strings.Join(line, " "), Location: joinLocation(location, "command")})
+		}
+		if rawURL, ok := transport["url"].(string); ok && rawURL != "" {
+			add(Dependency{Type: "mcp_url", Name: withoutCredentials(rawURL), Location: joinLocation(location, "url")})
+		}
+	})
+
+	for _, path := range urlReferences {
+		objectsAt(spec, path, "", func(source map[string]interface{}, location string) {
+			if rawURL, ok := source["url"].(string); ok && rawURL != "" {
+				add(Dependency{Type: "url", Name: withoutCredentials(rawURL), Location: joinLocation(location, "url")})
+			}
+		})
+	}
+	objectsAt(spec, "context.knowledge_base.sources[]", "", func(source map[string]interface{}, location string) {
+		for _, field := range []string{"connection", "endpoint"} {
+			if value, ok := source[field].(string); ok && value != "" {
+				dependencyType := "database"
+				if field == "endpoint" {
+					dependencyType = "api"
+				}
+				add(Dependency{Type: dependencyType, Name: withoutCredentials(value), Location: joinLocation(location, field)})
+			}
+		}
+	})
+
+	fileReferencesOf(spec, func(object map[string]interface{}, field, location, filePath string) {
+		add(Dependency{Type: "file", Name: filePath, Location: location})
+	})
+	return dependencies
+}
+
+// withoutCredentials removes the user and password of a URL or connection
+// string, returning other values unchanged
+func withoutCredentials(raw string) string {
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.User == nil {
+		return raw
+	}
+	parsed.User = nil
+	return parsed.String()
+}
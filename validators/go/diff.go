@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around changes
+const diffContext = 3
+
+// UnifiedDiff returns the changes from a to b as a unified diff of their
+// lines, labelled fromName and toName, or "" when they are equal
+func UnifiedDiff(fromName, toName string, a, b []byte) string {
+	from, to := diffLines(a), diffLines(b)
+	edits := lineEdits(from, to)
+
+	// Changes closer than twice the context share a hunk
+	changed := make([]int, 0)
+	for index, edit := range edits {
+		if edit.op != ' ' {
+			changed = append(changed, index)
+		}
+	}
+
+	var out strings.Builder
+	for next := 0; next < len(changed); {
+		last := next
+		for last+1 < len(changed) && changed[last+1]-changed[last] <= 2*diffContext+1 {
+			last++
+		}
+		first, end := changed[next]-diffContext, changed[last]+diffContext+1
+		if first < 0 {
+			first = 0
+		}
+		if end > len(edits) {
+			end = len(edits)
+		}
+		next = last + 1
+
+		if out.Len() == 0 {
+			fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+		}
+		fromCount, toCount := 0, 0
+		for _, edit := range edits[first:end] {
+			if edit.op != '+' {
+				fromCount++
+			}
+			if edit.op != '-' {
+				toCount++
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(edits[first].fromLine, fromCount), hunkRange(edits[first].toLine, toCount))
+		for _, edit := range edits[first:end] {
+			fmt.Fprintf(&out, "%c%s\n", edit.op, edit.text)
+		}
+	}
+	return out.String()
+}
+
+// lineEdit is a line kept (' '), removed ('-') or added ('+'), with the
+// zero-based positions of the lines before it in both inputs
+type lineEdit struct {
+	op               byte
+	text             string
+	fromLine, toLine int
+}
+
+// diffLines splits content into lines without their terminators
+func diffLines(content []byte) []string {
+	text := strings.TrimSuffix(string(content), "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
+
+// lineEdits computes a shortest edit script from a longest common
+// subsequence of the lines, after skipping the common prefix and suffix
+func lineEdits(from, to []string) []lineEdit {
+	prefix := 0
+	for prefix < len(from) && prefix < len(to) && from[prefix] == to[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(from)-prefix && suffix < len(to)-prefix && from[len(from)-1-suffix] == to[len(to)-1-suffix] {
+		suffix++
+	}
+	a, b := from[prefix:len(from)-suffix], to[prefix:len(to)-suffix]
+
+	// common[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	common := make([][]int32, len(a)+1)
+	for i := range common {
+		common[i] = make([]int32, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else if common[i+1][j] >= common[i][j+1] {
+				common[i][j] = common[i+1][j]
+			} else {
+				common[i][j] = common[i][j+1]
+			}
+		}
+	}
+
+	edits := make([]lineEdit, 0, len(from)+len(to))
+	i, j := 0, 0
+	add := func(op byte, text string) {
+		edits = append(edits, lineEdit{op: op, text: text, fromLine: i, toLine: j})
+	}
+	for ; i < prefix; i, j = i+1, j+1 {
+		add(' ', from[i])
+	}
+	for ai, bj := 0, 0; ai < len(a) || bj < len(b); {
+		switch {
+		case ai < len(a) && bj < len(b) && a[ai] == b[bj]:
+			add(' ', a[ai])
+			ai, bj, i, j = ai+1, bj+1, i+1, j+1
+		case bj < len(b) && (ai == len(a) || common[ai][bj+1] > common[ai+1][bj]):
+			add('+', b[bj])
+			bj, j = bj+1, j+1
+		default:
+			add('-', a[ai])
+			ai, i = ai+1, i+1
+		}
+	}
+	for ; i < len(from); i, j = i+1, j+1 {
+		add(' ', from[i])
+	}
+	return edits
+}
+
+// hunkRange formats the start and length of a hunk as unified diffs do,
+// with one-based line numbers
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
@@ -0,0 +1,29 @@
+package main
+
+import "testing"
+
+func TestUnifiedDiff(t *testing.T) {
+	a := []byte("a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\n")
+	b := []byte("a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\n")
+	want := `--- old
++++ new
+@@ -1,5 +1,5 @@
+ a
+-b
++B
+ c
+ d
+ e
+@@ -10,3 +10,4 @@
+ j
+ k
+ l
++m
+`
+	if got := UnifiedDiff("old", "new", a, b); got != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, got)
+	}
+	if got := UnifiedDiff("old", "new", a, a); got != "" {
+		t.Errorf("expected no diff for equal inputs, got\n%s", got)
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// DocumentResult is the validation result of one document of a file
+type DocumentResult struct {
+	// Index is the position of the document in the file, from 0
+	Index int `json:"index"`
+	ValidationResult
+	Err error `json:"-"`
+}
+
+// loadDocuments reads the specifications of a file: each document of a
+// YAML file separated by ---, or the one of a JSON file. A file without a
+// document holds one empty specification.
+func (v *APAIValidator) loadDocuments(filePath string) ([]map[string]interface{}, error) {
+	content, err := v.readFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("file not found: %s", filePath)
+	}
+
+	format := v.inputFormat
+	if format == "" {
+		ext := strings.ToLower(filepath.Ext(filePath))
+		if format = formatOfExtension(ext); format == "" {
+			return nil, fmt.Errorf("unsupported file format: %s", ext)
+		}
+	}
+	cacheKey := ""
+	if v.cache != nil {
+		cacheKey = v.cache.key(parsedCacheKind, format+" documents", string(content))
+		if documents, ok := v.cache.loadDocuments(cacheKey); ok {
+			return documents, nil
+		}
+	}
+	documents, err := decodeDocuments(content, format)
+	if err != nil && v.inputFormat != "" {
+		return nil, fmt.Errorf("%s: %v (input format forced to %s)", filePath, err, format)
+	}
+	if err == nil && cacheKey != "" {
+		v.cache.storeDocuments(cacheKey, documents)
+	}
+	return documents, err
+}
+
+// decodeDocuments parses the specifications of content in format, yaml or
+// json, as loadDocuments does
+func decodeDocuments(content []byte, format string) ([]map[string]interface{}, error) {
+	if format != "yaml" {
+		spec, err := decodeSpec(content, format)
+		if err != nil {
+			return nil, err
+		}
+		return []map[string]interface{}{spec}, nil
+	}
+
+	documents, err := decodeYAMLDocuments(content)
+	if err != nil {
+		return nil, fmt.Errorf("YAML parsing error: %v", err)
+	}
+	if len(documents) == 0 {
+		documents = append(documents, nil)
+	}
+	return documents, nil
+}
+
+// validateDocument validates a specification decoded from filePath, with
+// the specifications it inherits when hierarchical is set
+func (v *APAIValidator) validateDocument(ctx context.Context, spec map[string]interface{}, filePath string, hierarchical bool) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, fmt.Errorf("validating %s: %w", filePath, err)
+	}
+	if v.workspace != nil {
+		v.workspaceMember = v.workspace.memberID(filePath)
+		defer func() { v.workspaceMember = "" }()
+	}
+
+	spec, err := v.resolveIncludes(spec, filePath)
+	if err != nil {
+		return false, err
+	}
+	v.rebaseFileReferences(spec, filePath)
+
+	if hierarchical {
+		// The documents of a file share its path, under which the merge of
+		// another document may be cached
+		delete(v.mergeCache, filePath)
+		merged, err := v.resolveSpec(ctx, spec, filePath)
+		if err != nil {
+			return false, err
+		}
+		return v.validateResolved(ctx, merged, filePath)
+	}
+	valid, err := v.ValidateSpecContext(ctx, spec)
+	if err != nil {
+		return false, fmt.Errorf("validating %s: %w", filePath, err)
+	}
+	return valid, nil
+}
+
+// ValidateDocumentsContext validates each document of a YAML file
+// separated by --- on its own, with the specifications it inherits when
+// hierarchical is set, and returns their results in file order. A JSON
+// file is a single document. Documents that cannot be validated, such as
+// one with a missing include, have Err set; the returned error covers
+// unreadable files and a done ctx.
+func (v *APAIValidator) ValidateDocumentsContext(ctx context.Context, filePath string, hierarchical bool) ([]DocumentResult, error) {
+	documents, err := v.loadDocuments(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]DocumentResult, 0, len(documents))
+	for index, spec := range documents {
+		_, err := v.validateDocument(ctx, spec, filePath, hierarchical)
+		if ctx.Err() != nil {
+			return results, err
+		}
+		result := DocumentResult{Index: index, Err: err}
+		if err == nil {
+			result.ValidationResult = v.GetResults()
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"os"
+	"testing"
+	"testing/fstest"
+)
+
+func TestValidateDocuments(t *testing.T) {
+	base, err := os.ReadFile("../../examples/templates/basic-template.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys := fstest.MapFS{
+		"specs/base.yaml":   {Data: base},
+		"specs/agents.yaml": {Data: []byte("---\ninherits: [base.yaml]\ninfo:\n  title: Child\n---\n---\napai: \"0.1.0\"\ninfo:\n  title: Incomplete\n")},
+		"specs/single.json": {Data: []byte(`{"apai": "0.1.0"}`)},
+		"specs/broken.yaml": {Data: []byte("apai: \"0.1.0\"\n---\n- not\n- a spec\n")},
+	}
+	validator := NewAPAIValidator(WithFS(fsys))
+
+	// Empty documents are skipped; each other one is validated on its own
+	results, err := validator.ValidateDocumentsContext(context.Background(), "specs/agents.yaml", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Index != 0 || results[1].Index != 1 {
+		t.Fatalf("expected two documents, got %+v", results)
+	}
+	if !results[0].Valid || results[0].Err != nil {
+		t.Errorf("expected the first document to inherit a valid spec, got %+v", results[0])
+	}
+	if results[1].Valid || !containsString(results[1].Errors, "Missing required section: models") {
+		t.Errorf("expected the second document to be validated without the first, got %+v", results[1])
+	}
+
+	// Without inheritance the first document misses what it inherits
+	results, err = validator.ValidateDocumentsContext(context.Background(), "specs/agents.yaml", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Valid {
+		t.Errorf("expected the first document alone to be invalid, got %+v", results)
+	}
+
+	results, err = validator.ValidateDocumentsContext(context.Background(), "specs/single.json", false)
+	if err != nil || len(results) != 1 {
+		t.Errorf("expected a JSON file to be one document, got %+v, %v", results, err)
+	}
+
+	if _, err := validator.ValidateDocumentsContext(context.Background(), "specs/broken.yaml", false); err == nil {
+		t.Error("expected a document that is not a mapping to fail")
+	}
+
+	// ValidateFile keeps validating the first document only
+	if valid, err := validator.ValidateFile("specs/agents.yaml"); err != nil || valid {
+		t.Errorf("expected the first document to be validated alone, got %v, %v", valid, err)
+	}
+	if !containsString(validator.Errors, "Missing required field in info: version") {
+		t.Errorf("expected the findings of the first document, got %v", validator.Errors)
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Values of the enum fields, in their canonical casing
+var (
+	complexityLevels     = []string{"low", "medium", "high"}
+	modelTypes           = []string{"LLM", "Vision", "Audio", "Multimodal", "Classification", "Embedding"}
+	promptRoles          = []string{"system", "user", "assistant"}
+	constraintSeverities = []string{"low", "medium", "high", "critical"}
+	stepActions          = []string{"analyze", "generate", "validate", "search", "escalate", "classify", "mcp_tool", "mcp_resource"}
+	transportTypes       = []string{"stdio", "sse", "websocket"}
+	authenticationTypes  = []string{"none", "api_key", "oauth", "custom"}
+	knowledgeSourceTypes = []string{"url", "file", "database", "api"}
+	persistenceStores    = []string{"redis", "postgres", "memory", "file"}
+	experimentStatuses   = []string{"draft", "active", "paused", "completed"}
+	// real_time and quarterly predate cron schedules and stay valid
+	evaluationFrequencies = []string{"on_demand", "hourly", "daily", "weekly", "monthly", "cron", "real_time", "quarterly"}
+)
+
+// enumFields maps the paths of enum fields to their values
+var enumFields = []struct {
+	Path   string
+	Values []string
+}{
+	{"info.ai_metadata.complexity", complexityLevels},
+	{"info.ai_metadata.hierarchy_info.level", hierarchyLevels},
+	{"models[].type", modelTypes},
+	{"prompts[].role", promptRoles},
+	{"constraints[].severity", constraintSeverities},
+	{"tasks[].steps[].action", stepActions},
+	{"tasks[].steps[].response_format", requirementNames(modelCapabilities.ResponseFormats)},
+	{"context.mcp_servers[].transport.type", transportTypes},
+	{"context.mcp_servers[].authentication.type", authenticationTypes},
+	{"context.knowledge_base.sources[].type", knowledgeSourceTypes},
+	{"context.persistence.store", persistenceStores},
+	{"context.session.store", persistenceStores},
+	{"evaluation.experiments[].status", experimentStatuses},
+	{"evaluation.metrics[].measurement.frequency", evaluationFrequencies},
+	{"evaluation.metrics[].direction", metricDirections},
+}
+
+// canonicalEnumValue returns the value of values that value spells,
+// preferring an exact match to one ignoring case
+func canonicalEnumValue(values []string, value string) (string, bool) {
+	if containsString(values, value) {
+		return value, true
+	}
+	for _, candidate := range values {
+		if strings.EqualFold(candidate, value) {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+// matchEnum checks an enum value, warning when it only matches ignoring
+// case. It returns the canonical value, or false when none matches.
+func matchEnum(f *sectionFindings, values []string, value, location string) (string, bool) {
+	canonical, ok := canonicalEnumValue(values, value)
+	if ok && canonical != value {
+		f.addWarning("ENUM_CASING", fmt.Sprintf("Non-canonical casing for %s: %q, use %q", location, value, canonical))
+	}
+	return canonical, ok
+}
+
+// FixEnumCasing rewrites enum values that match only ignoring case to
+// their canonical casing. It returns the fixed copy and a description of
+// each change.
+func FixEnumCasing(spec map[string]interface{}) (map[string]interface{}, []string) {
+	fixed, _ := copyValue(spec).(map[string]interface{})
+	changes := make([]string, 0)
+
+	for _, enum := range enumFields {
+		field := fieldName(enum.Path)
+		objectsAt(fixed, parentPath(enum.Path), "", func(parent map[string]interface{}, location string) {
+			value, ok := parent[field].(string)
+			if !ok {
+				return
+			}
+			if canonical, ok := canonicalEnumValue(enum.Values, value); ok && canonical != value {
+				parent[field] = canonical
+				changes = append(changes, fmt.Sprintf("changed %s from %q to %q", joinLocation(location, field), value, canonical))
+			}
+		})
+	}
+	return fixed, changes
+}
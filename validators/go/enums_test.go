@@ -0,0 +1,60 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEnumCasingWarnsAndFixes(t *testing.T) {
+	spec := loadExampleSpecs(t, "automation/mcp-integration.yaml")[0]
+	prompt := spec["prompts"].([]interface{})[0].(map[string]interface{})
+	prompt["role"] = "System"
+	model := spec["models"].([]interface{})[0].(map[string]interface{})
+	model["type"] = "llm"
+	server := spec["context"].(map[string]interface{})["mcp_servers"].([]interface{})[0].(map[string]interface{})
+	server["transport"].(map[string]interface{})["type"] = "STDIO"
+
+	validator := NewAPAIValidator()
+	if !validator.ValidateSpec(spec) {
+		t.Fatalf("expected case-insensitive enum values to be valid, got errors: %v", validator.Errors)
+	}
+	for _, want := range []string{
+		`Non-canonical casing for models[0].type: "llm", use "LLM"`,
+		`Non-canonical casing for prompts[0].role: "System", use "system"`,
+		`Non-canonical casing for context.mcp_servers[0].transport.type: "STDIO", use "stdio"`,
+	} {
+		if !containsString(validator.Warnings, want) {
+			t.Errorf("missing %q in %v", want, validator.Warnings)
+		}
+	}
+
+	fixed, changes := FixEnumCasing(spec)
+	wantChanges := []string{
+		`changed models[0].type from "llm" to "LLM"`,
+		`changed prompts[0].role from "System" to "system"`,
+		`changed context.mcp_servers[0].transport.type from "STDIO" to "stdio"`,
+	}
+	if !reflect.DeepEqual(changes, wantChanges) {
+		t.Errorf("got changes %q, want %q", changes, wantChanges)
+	}
+	if prompt["role"] != "System" {
+		t.Error("FixEnumCasing modified its input")
+	}
+
+	validator.ValidateSpec(fixed)
+	for _, warning := range validator.Warnings {
+		if rule, _ := MatchRule(warning); rule.Code == "ENUM_CASING" {
+			t.Errorf("unexpected casing warning after fix: %s", warning)
+		}
+	}
+}
+
+func TestCanonicalEnumValue(t *testing.T) {
+	values := []string{"low", "Low", "high"}
+	for value, want := range map[string]string{"Low": "Low", "LOW": "low", "high": "high", "extreme": ""} {
+		got, ok := canonicalEnumValue(values, value)
+		if got != want || ok != (want != "") {
+			t.Errorf("canonicalEnumValue(%q) = %q, %v, want %q", value, got, ok, want)
+		}
+	}
+}
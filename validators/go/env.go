@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// envPlaceholderPattern matches a ${VAR} placeholder
+var envPlaceholderPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// envNamePattern matches the names of environment variables
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// EnvReference is a ${VAR} placeholder in a string value of a specification
+type EnvReference struct {
+	Name string
+	// Location is the path of the value, e.g. context.mcp_servers[0].transport.url
+	Location string
+	Resolved bool
+}
+
+// SubstituteEnv returns a copy of spec in which every ${VAR} placeholder in
+// a string value is replaced with the value lookup returns for VAR, and the
+// placeholders found, in document order with keys sorted. Placeholders
+// lookup does not resolve are left as they are.
+func SubstituteEnv(spec map[string]interface{}, lookup func(string) (string, bool)) (map[string]interface{}, []EnvReference) {
+	references := make([]EnvReference, 0)
+	substituted, _ := substituteEnvValue(spec, "", lookup, &references).(map[string]interface{})
+	return substituted, references
+}
+
+// substituteEnvValue substitutes the placeholders of a value and its children
+func substituteEnvValue(value interface{}, location string, lookup func(string) (string, bool), references *[]EnvReference) interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(typed))
+		keys := make([]string, 0, len(typed))
+		for key := range typed {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			result[key] = substituteEnvValue(typed[key], joinLocation(location, key), lookup, references)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(typed))
+		for i, item := range typed {
+			result[i] = substituteEnvValue(item, fmt.Sprintf("%s[%d]", location, i), lookup, references)
+		}
+		return result
+	case string:
+		return envPlaceholderPattern.ReplaceAllStringFunc(typed, func(placeholder string) string {
+			name := envPlaceholderPattern.FindStringSubmatch(placeholder)[1]
+			replacement, ok := lookup(name)
+			*references = append(*references, EnvReference{Name: name, Location: location, Resolved: ok})
+			if !ok {
+				return placeholder
+			}
+			return replacement
+		})
+	}
+	return value
+}
+
+// LoadEnvFile reads KEY=VALUE lines from a .env file. Blank lines, comments
+// starting with # and an "export " prefix are ignored; values may be
+// enclosed in single or double quotes.
+func LoadEnvFile(filePath string) (map[string]string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read env file: %v", err)
+	}
+	defer file.Close()
+
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		name, value, found := strings.Cut(line, "=")
+		name = strings.TrimSpace(name)
+		if !found || !envNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid env file %s line %d: expected KEY=VALUE", filePath, lineNumber)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+			if unquoted, err := strconv.Unquote(value); err == nil {
+				value = unquoted
+			}
+		} else if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+			value = value[1 : len(value)-1]
+		}
+		values[name] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read env file: %v", err)
+	}
+	return values, nil
+}
+
+// EnvLookup looks variables up in the environment, then in fallback, such
+// as the values of an env file
+func EnvLookup(fallback map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		if value, ok := os.LookupEnv(name); ok {
+			return value, true
+		}
+		value, ok := fallback[name]
+		return value, ok
+	}
+}
+
+// substituteEnv replaces the placeholders of spec for validation, reporting
+// unresolved ones as errors, or warnings when missing variables are allowed
+func (v *APAIValidator) substituteEnv(spec map[string]interface{}) map[string]interface{} {
+	substituted, references := SubstituteEnv(spec, v.envLookup)
+	v.envSubstituted = make(map[string]bool)
+	for _, reference := range references {
+		if reference.Resolved {
+			v.envSubstituted[reference.Location] = true
+			continue
+		}
+		message := fmt.Sprintf("Unresolved environment variable ${%s} in %s", reference.Name, reference.Location)
+		if v.allowMissingEnv {
+			v.addWarning("UNRESOLVED_ENV", message)
+		} else {
+			v.addError("UNRESOLVED_ENV", message)
+		}
+	}
+	return substituted
+}
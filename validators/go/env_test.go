@@ -0,0 +1,107 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSubstituteEnv(t *testing.T) {
+	spec := map[string]interface{}{
+		"models": []interface{}{
+			map[string]interface{}{"id": "main", "name": "${MODEL_NAME}"},
+		},
+		"context": map[string]interface{}{
+			"mcp_servers": []interface{}{
+				map[string]interface{}{"id": "files", "transport": map[string]interface{}{"url": "https://${HOST}:${PORT}/mcp"}},
+			},
+		},
+	}
+	lookup := func(name string) (string, bool) {
+		value, ok := map[string]string{"MODEL_NAME": "gpt-4", "HOST": "mcp.example.com"}[name]
+		return value, ok
+	}
+
+	substituted, references := SubstituteEnv(spec, lookup)
+	if name := substituted["models"].([]interface{})[0].(map[string]interface{})["name"]; name != "gpt-4" {
+		t.Errorf("expected model name gpt-4, got %v", name)
+	}
+	transport := substituted["context"].(map[string]interface{})["mcp_servers"].([]interface{})[0].(map[string]interface{})["transport"].(map[string]interface{})
+	if url := transport["url"]; url != "https://mcp.example.com:${PORT}/mcp" {
+		t.Errorf("expected unresolved placeholders to stay, got %v", url)
+	}
+	if name := spec["models"].([]interface{})[0].(map[string]interface{})["name"]; name != "${MODEL_NAME}" {
+		t.Errorf("the original specification was modified: %v", name)
+	}
+
+	want := []EnvReference{
+		{Name: "HOST", Location: "context.mcp_servers[0].transport.url", Resolved: true},
+		{Name: "PORT", Location: "context.mcp_servers[0].transport.url", Resolved: false},
+		{Name: "MODEL_NAME", Location: "models[0].name", Resolved: true},
+	}
+	if !reflect.DeepEqual(references, want) {
+		t.Errorf("expected %+v, got %+v", want, references)
+	}
+}
+
+func TestEnvSubstitutionDuringValidation(t *testing.T) {
+	spec := map[string]interface{}{
+		"context": map[string]interface{}{
+			"mcp_servers": []interface{}{
+				map[string]interface{}{"id": "files", "authentication": map[string]interface{}{
+					"type": "api_key", "api_key": "${MCP_API_KEY}", "token": "${MCP_TOKEN}",
+				}},
+			},
+		},
+	}
+	lookup := func(name string) (string, bool) {
+		if name == "MCP_API_KEY" {
+			return "sk-live-1234", true
+		}
+		return "", false
+	}
+	unresolved := "Unresolved environment variable ${MCP_TOKEN} in context.mcp_servers[0].authentication.token"
+
+	validator := NewAPAIValidator(WithEnvSubstitution(lookup, false))
+	validator.ValidateSpec(spec)
+	if !containsString(validator.Errors, unresolved) {
+		t.Errorf("expected %q in %v", unresolved, validator.Errors)
+	}
+	// Substituted credentials come from the environment, not the spec
+	for _, warning := range validator.Warnings {
+		if rule, _ := MatchRule(warning); rule.Code == "HARDCODED_SECRET" {
+			t.Errorf("unexpected %q", warning)
+		}
+	}
+
+	validator = NewAPAIValidator(WithEnvSubstitution(lookup, true))
+	validator.ValidateSpec(spec)
+	if containsString(validator.Errors, unresolved) || !containsString(validator.Warnings, unresolved) {
+		t.Errorf("expected %q as a warning, got errors %v and warnings %v", unresolved, validator.Errors, validator.Warnings)
+	}
+}
+
+func TestLoadEnvFile(t *testing.T) {
+	envPath := filepath.Join(t.TempDir(), ".env")
+	content := "# production\nMODEL_NAME=gpt-4\nexport HOST=\"mcp.example.com\"\nGREETING='Hello, ${NAME}'\n\nEMPTY=\n"
+	if err := os.WriteFile(envPath, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	values, err := LoadEnvFile(envPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"MODEL_NAME": "gpt-4", "HOST": "mcp.example.com", "GREETING": "Hello, ${NAME}", "EMPTY": ""}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("expected %v, got %v", want, values)
+	}
+
+	if err := os.WriteFile(envPath, []byte("not a variable\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadEnvFile(envPath); err == nil {
+		t.Error("expected an error for a line without =")
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// experimentTrafficTolerance is how far the traffic of the variants of an
+// experiment may sum from 100, absorbing rounding in splits such as thirds
+const experimentTrafficTolerance = 0.01
+
+// validateExperiments validates the shape and arithmetic of the A/B
+// experiments of evaluation.experiments: unique ids, a success metric, at
+// least two variants each running a prompt or a model, and traffic
+// percentages summing to 100. References are checked by crossValidate.
+func validateExperiments(f *sectionFindings, experiments interface{}) {
+	experimentsSlice, ok := experiments.([]interface{})
+	if !ok {
+		f.addError("INVALID_TYPE", "evaluation.experiments must be an array")
+		return
+	}
+
+	ids := make(map[string]bool)
+	for index, experiment := range experimentsSlice {
+		experimentMap, ok := experiment.(map[string]interface{})
+		if !ok {
+			f.addError("INVALID_TYPE", fmt.Sprintf("Experiment %d must be an object", index))
+			continue
+		}
+		name := elementName(index, experimentMap)
+		if id, ok := experimentMap["id"].(string); ok && id != "" {
+			if ids[id] {
+				f.addError("DUPLICATE_ID", fmt.Sprintf("Duplicate experiment ID: %s", id))
+			}
+			ids[id] = true
+		}
+
+		if status, exists := experimentMap["status"]; exists {
+			statusStr, ok := status.(string)
+			if !ok {
+				f.addError("INVALID_TYPE", fmt.Sprintf("Experiment %s status must be a string", name))
+			} else if _, valid := matchEnum(f, experimentStatuses, statusStr, fmt.Sprintf("evaluation.experiments[%d].status", index)); !valid {
+				f.addError("INVALID_ENUM", fmt.Sprintf("Invalid experiment status: %s in experiment %s (expected %s)", statusStr, name, strings.Join(experimentStatuses, ", ")))
+			}
+		}
+		if metric, ok := experimentMap["metric"].(string); !ok || metric == "" {
+			f.addError("MISSING_FIELD", fmt.Sprintf("Experiment %s missing required field: metric", name))
+		}
+
+		variants, exists := experimentMap["variants"]
+		if !exists {
+			f.addError("MISSING_FIELD", fmt.Sprintf("Experiment %s missing required field: variants", name))
+			continue
+		}
+		variantsSlice, ok := variants.([]interface{})
+		if !ok {
+			f.addError("INVALID_TYPE", fmt.Sprintf("Experiment %s variants must be an array", name))
+			continue
+		}
+		if len(variantsSlice) < 2 {
+			f.addError("EXPERIMENT_TOO_FEW_VARIANTS", fmt.Sprintf("Experiment %s needs at least two variants, got %d", name, len(variantsSlice)))
+		}
+
+		total, split := 0.0, true
+		for variantIndex, variant := range variantsSlice {
+			variantMap, ok := variant.(map[string]interface{})
+			if !ok {
+				f.addError("INVALID_TYPE", fmt.Sprintf("Experiment %s variant %d must be an object", name, variantIndex))
+				split = false
+				continue
+			}
+			variantName := elementName(variantIndex, variantMap)
+			if variantMap["prompt"] == nil && variantMap["model"] == nil {
+				f.addError("MISSING_FIELD", fmt.Sprintf("Experiment %s variant %s missing required field: prompt or model", name, variantName))
+			}
+
+			traffic, exists := variantMap["traffic"]
+			if !exists {
+				f.addError("MISSING_FIELD", fmt.Sprintf("Experiment %s variant %s missing required field: traffic", name, variantName))
+				split = false
+				continue
+			}
+			percentage, ok := numberValue(traffic)
+			if !ok || percentage < 0 || percentage > 100 {
+				f.addError("INVALID_TRAFFIC_SPLIT", fmt.Sprintf("Invalid traffic split for experiment %s: variant %s traffic must be a number between 0 and 100, got %v", name, variantName, traffic))
+				split = false
+				continue
+			}
+			total += percentage
+		}
+		if split && len(variantsSlice) > 0 && math.Abs(total-100) > experimentTrafficTolerance {
+			f.addError("INVALID_TRAFFIC_SPLIT", fmt.Sprintf("Invalid traffic split for experiment %s: variant traffic sums to %v, not 100", name, total))
+		}
+	}
+}
+
+// experimentActive reports whether an experiment is running: its status is
+// active or, without a status, it is assumed to be
+func experimentActive(experimentMap map[string]interface{}) bool {
+	status, ok := experimentMap["status"].(string)
+	return !ok || strings.EqualFold(status, "active")
+}
+
+// validateExperimentReferences checks that the variants of experiments run
+// declared prompts and models, that their success metric is declared in
+// evaluation.metrics, and warns when active experiments vary the same prompt
+func (v *APAIValidator) validateExperimentReferences(spec map[string]interface{}) {
+	evaluation, _ := spec["evaluation"].(map[string]interface{})
+	experiments, _ := evaluation["experiments"].([]interface{})
+	if len(experiments) == 0 {
+		return
+	}
+
+	declared := map[string]map[string]bool{"prompt": {}, "model": {}}
+	for field, section := range map[string]string{"prompt": "prompts", "model": "models"} {
+		items, _ := spec[section].([]interface{})
+		for _, item := range items {
+			itemMap, _ := item.(map[string]interface{})
+			if id, ok := itemMap["id"].(string); ok {
+				declared[field][id] = true
+			}
+		}
+	}
+	metrics := make(map[string]bool)
+	forEachMetric(evaluation["metrics"], func(metricMap map[string]interface{}, location string) {
+		if name, ok := metricMap["name"].(string); ok {
+			metrics[name] = true
+		}
+	})
+
+	// Prompts varied by each active experiment, in experiment order
+	varied := make([][]string, len(experiments))
+	for index, experiment := range experiments {
+		experimentMap, ok := experiment.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name := elementName(index, experimentMap)
+		if metric, ok := experimentMap["metric"].(string); ok && metric != "" && !metrics[metric] {
+			v.addError("UNKNOWN_REFERENCE", fmt.Sprintf("Experiment %s references unknown metric: %s", name, metric))
+		}
+		variants, _ := experimentMap["variants"].([]interface{})
+		for _, variant := range variants {
+			variantMap, _ := variant.(map[string]interface{})
+			for _, field := range []string{"prompt", "model"} {
+				id, ok := variantMap[field].(string)
+				if !ok {
+					continue
+				}
+				if !declared[field][id] {
+					v.addError("UNKNOWN_REFERENCE", fmt.Sprintf("Experiment %s references unknown %s: %s", name, field, id))
+				}
+				if field == "prompt" && experimentActive(experimentMap) && !containsString(varied[index], id) {
+					varied[index] = append(varied[index], id)
+				}
+			}
+		}
+	}
+
+	for a := range experiments {
+		for b := a + 1; b < len(experiments); b++ {
+			for _, prompt := range varied[a] {
+				if containsString(varied[b], prompt) {
+					nameA := elementName(a, experiments[a].(map[string]interface{}))
+					nameB := elementName(b, experiments[b].(map[string]interface{}))
+					v.addWarning("OVERLAPPING_EXPERIMENTS", fmt.Sprintf("Active experiments %s and %s both vary prompt %s", nameA, nameB, prompt))
+				}
+			}
+		}
+	}
+}
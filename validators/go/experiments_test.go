@@ -0,0 +1,77 @@
+package main
+
+import "testing"
+
+func TestExperiments(t *testing.T) {
+	spec := loadExampleSpecs(t, "templates/basic-template.yaml")[0]
+	evaluation := spec["evaluation"].(map[string]interface{})
+	evaluation["experiments"] = []interface{}{
+		map[string]interface{}{"id": "tone", "metric": "response_accuracy", "variants": []interface{}{
+			map[string]interface{}{"id": "control", "prompt": "system_prompt", "traffic": 33.33},
+			map[string]interface{}{"id": "short", "prompt": "system_prompt_short", "traffic": 33.33},
+			map[string]interface{}{"id": "cheap", "model": "main_model", "traffic": 33.34},
+		}},
+		map[string]interface{}{"id": "length", "status": "Active", "metric": "satisfaction", "variants": []interface{}{
+			map[string]interface{}{"id": "control", "prompt": "system_prompt", "traffic": 60},
+			map[string]interface{}{"id": "long", "model": "large_model", "traffic": "50"},
+		}},
+		map[string]interface{}{"id": "solo", "status": "paused", "metric": "response_time", "variants": []interface{}{
+			map[string]interface{}{"id": "control", "prompt": "system_prompt", "traffic": 90},
+		}},
+	}
+
+	validator := NewAPAIValidator()
+	validator.ValidateSpec(spec)
+	wantErrors := map[string]string{
+		"Experiment tone references unknown prompt: system_prompt_short":                                               "UNKNOWN_REFERENCE",
+		"Experiment length references unknown metric: satisfaction":                                                    "UNKNOWN_REFERENCE",
+		"Experiment length references unknown model: large_model":                                                      "UNKNOWN_REFERENCE",
+		"Invalid traffic split for experiment length: variant long traffic must be a number between 0 and 100, got 50": "INVALID_TRAFFIC_SPLIT",
+		"Experiment solo needs at least two variants, got 1":                                                           "EXPERIMENT_TOO_FEW_VARIANTS",
+		"Invalid traffic split for experiment solo: variant traffic sums to 90, not 100":                               "INVALID_TRAFFIC_SPLIT",
+	}
+	wantWarnings := map[string]string{
+		"Active experiments tone and length both vary prompt system_prompt": "OVERLAPPING_EXPERIMENTS",
+	}
+	for findings, want := range map[*[]string]map[string]string{&validator.Errors: wantErrors, &validator.Warnings: wantWarnings} {
+		for message, code := range want {
+			if !containsString(*findings, message) {
+				t.Errorf("missing %q in %v", message, *findings)
+			}
+			if rule, _ := MatchRule(message); rule.Code != code {
+				t.Errorf("expected %s for %q, got %q", code, message, rule.Code)
+			}
+		}
+	}
+	for _, message := range validator.Errors {
+		if _, expected := wantErrors[message]; !expected {
+			t.Errorf("unexpected error: %q", message)
+		}
+	}
+
+	// Shape checks run without evaluation.metrics
+	f := &sectionFindings{}
+	NewAPAIValidator().validateEvaluation(f, map[string]interface{}{"experiments": []interface{}{
+		map[string]interface{}{"id": "a", "status": "running", "variants": []interface{}{
+			map[string]interface{}{"id": "x", "traffic": 50},
+			map[string]interface{}{"id": "y", "prompt": "p"},
+		}},
+		map[string]interface{}{"id": "a", "metric": "m", "variants": "x"},
+	}})
+	want := []string{
+		"Invalid experiment status: running in experiment a (expected draft, active, paused, completed)",
+		"Experiment a missing required field: metric",
+		"Experiment a variant x missing required field: prompt or model",
+		"Experiment a variant y missing required field: traffic",
+		"Duplicate experiment ID: a",
+		"Experiment a variants must be an array",
+	}
+	if len(f.Errors) != len(want) {
+		t.Errorf("unexpected errors: %v", f.Errors)
+	}
+	for _, message := range want {
+		if !containsString(f.Errors, message) {
+			t.Errorf("missing %q in %v", message, f.Errors)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// inputReferencePattern matches a step parameter bound to a task input, as
+// in "${input.customer_id}"
+var inputReferencePattern = regexp.MustCompile(`^\$\{input\.([A-Za-z_][A-Za-z0-9_]*)\}$`)
+
+// toolNameInvalidChars matches the characters tool names may not contain
+var toolNameInvalidChars = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+// ExportPlan is the target-independent description of a task that
+// exporters render into the configuration format of a runtime
+type ExportPlan struct {
+	TaskID      string
+	Name        string
+	Description string
+	Model       ExportModel
+	// Instructions is the system prompt template, with its {{variables}}
+	// left as placeholders
+	Instructions string
+	Tools        []ExportTool
+	// Warnings lists the parts of the spec the plan cannot carry
+	Warnings []string
+}
+
+// ExportModel is the model a task runs on and its sampling parameters;
+// parameters the spec does not set are nil
+type ExportModel struct {
+	ID          string
+	Provider    string
+	Name        string
+	Temperature *float64
+	TopP        *float64
+	MaxTokens   *int
+}
+
+// ExportTool is an MCP tool a task calls, described as a function
+type ExportTool struct {
+	Name        string
+	Description string
+	// Parameters is the JSON schema of the tool arguments, derived from the
+	// task inputs the step binds to them
+	Parameters map[string]interface{}
+}
+
+// BuildExportPlan describes a task of spec for export: the first task
+// when taskID is empty. The model is the one of the task's first model
+// step, and the instructions come from the system prompt its steps use.
+func BuildExportPlan(spec map[string]interface{}, taskID string) (*ExportPlan, error) {
+	tasks, _ := spec["tasks"].([]interface{})
+	var task map[string]interface{}
+	for index, candidate := range tasks {
+		candidateMap, ok := candidate.(map[string]interface{})
+		if ok && (taskID == "" || elementName(index, candidateMap) == taskID) {
+			task = candidateMap
+			taskID = elementName(index, candidateMap)
+			break
+		}
+	}
+	if task == nil {
+		if taskID == "" {
+			return nil, fmt.Errorf("specification has no tasks to export")
+		}
+		return nil, fmt.Errorf("task not found: %s", taskID)
+	}
+
+	plan := &ExportPlan{TaskID: taskID, Tools: make([]ExportTool, 0), Warnings: make([]string, 0)}
+	plan.Name, _ = task["name"].(string)
+	if plan.Name == "" {
+		plan.Name = taskID
+	}
+	plan.Description, _ = task["description"].(string)
+
+	inputs, _ := task["input"].(map[string]interface{})
+	steps, _ := task["steps"].([]interface{})
+	modelID, promptIDs := "", make([]string, 0)
+	for _, step := range steps {
+		stepMap, ok := step.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := stepMap["name"].(string)
+		if model, ok := stepMap["model"].(string); ok && model != "" {
+			if modelID == "" {
+				modelID = model
+			} else if model != modelID {
+				plan.Warnings = append(plan.Warnings, fmt.Sprintf("step %s uses model %s; only %s is exported", name, model, modelID))
+			}
+		}
+		if prompt, ok := stepMap["prompt"].(string); ok && prompt != "" && !containsString(promptIDs, prompt) {
+			promptIDs = append(promptIDs, prompt)
+		}
+
+		switch action, _ := stepMap["action"].(string); action {
+		case "mcp_tool":
+			plan.addTool(spec, stepMap, inputs)
+		case "mcp_resource":
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("step %s reads an MCP resource, which has no tool equivalent", name))
+		}
+	}
+
+	if err := plan.setModel(spec, modelID); err != nil {
+		return nil, err
+	}
+	plan.setInstructions(spec, promptIDs)
+
+	if constraints, _ := spec["constraints"].([]interface{}); len(constraints) > 0 {
+		names := make([]string, 0, len(constraints))
+		for index, constraint := range constraints {
+			constraintMap, _ := constraint.(map[string]interface{})
+			names = append(names, elementName(index, constraintMap))
+		}
+		plan.Warnings = append(plan.Warnings, fmt.Sprintf("constraints are not exported and must be enforced separately: %s", strings.Join(names, ", ")))
+	}
+	if _, exists := spec["evaluation"]; exists {
+		plan.Warnings = append(plan.Warnings, "evaluation is not exported")
+	}
+	return plan, nil
+}
+
+// setModel exports the model with the given id, or the first model of the
+// spec when the task has no model step
+func (p *ExportPlan) setModel(spec map[string]interface{}, modelID string) error {
+	models, _ := spec["models"].([]interface{})
+	for index, model := range models {
+		modelMap, ok := model.(map[string]interface{})
+		if !ok || (modelID != "" && elementName(index, modelMap) != modelID) {
+			continue
+		}
+
+		p.Model.ID = elementName(index, modelMap)
+		p.Model.Provider, _ = modelMap["provider"].(string)
+		p.Model.Name, _ = modelMap["name"].(string)
+		parameters, _ := modelMap["parameters"].(map[string]interface{})
+		if value, ok := numberValue(parameters["temperature"]); ok {
+			p.Model.Temperature = &value
+		}
+		if value, ok := numberValue(parameters["top_p"]); ok {
+			p.Model.TopP = &value
+		}
+		if value, ok := numberValue(parameters["max_tokens"]); ok {
+			maxTokens := int(value)
+			p.Model.MaxTokens = &maxTokens
+		}
+		return nil
+	}
+	if modelID != "" {
+		return fmt.Errorf("task %s references undeclared model %s", p.TaskID, modelID)
+	}
+	return fmt.Errorf("specification has no models to export")
+}
+
+// setInstructions exports the first system prompt the task's steps use,
+// or the first system prompt of the spec when they use none
+func (p *ExportPlan) setInstructions(spec map[string]interface{}, promptIDs []string) {
+	prompts, _ := spec["prompts"].([]interface{})
+	exported := ""
+	for index, prompt := range prompts {
+		promptMap, ok := prompt.(map[string]interface{})
+		if !ok || promptMap["role"] != "system" {
+			continue
+		}
+		if id := elementName(index, promptMap); len(promptIDs) == 0 || containsString(promptIDs, id) {
+			template, _ := currentField(promptMap, "prompts[].template")
+			p.Instructions, _ = template.(string)
+			exported = id
+			break
+		}
+	}
+
+	if exported == "" {
+		p.Warnings = append(p.Warnings, "no system prompt found; instructions are empty")
+	}
+	for _, id := range promptIDs {
+		if id != exported {
+			p.Warnings = append(p.Warnings, fmt.Sprintf("prompt %s is not exported; only the system prompt becomes instructions", id))
+		}
+	}
+}
+
+// addTool exports the MCP tool a step calls as a function whose arguments
+// are the task inputs bound to its parameters. Parameters with fixed values
+// are set by the spec and are not arguments.
+func (p *ExportPlan) addTool(spec map[string]interface{}, step map[string]interface{}, inputs map[string]interface{}) {
+	toolName, _ := step["mcp_tool"].(string)
+	serverID, _ := step["mcp_server"].(string)
+	name := toolNameInvalidChars.ReplaceAllString(toolName, "_")
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	for _, tool := range p.Tools {
+		if tool.Name == name {
+			return
+		}
+	}
+
+	description := fmt.Sprintf("Calls the %s tool of the %s MCP server", toolName, serverID)
+	contextMap, _ := spec["context"].(map[string]interface{})
+	servers, _ := contextMap["mcp_servers"].([]interface{})
+	for index, server := range servers {
+		serverMap, ok := server.(map[string]interface{})
+		if !ok || elementName(index, serverMap) != serverID {
+			continue
+		}
+		if serverDescription, ok := serverMap["description"].(string); ok && serverDescription != "" {
+			description = fmt.Sprintf("%s: %s", description, strings.TrimSuffix(serverDescription, "."))
+		}
+	}
+
+	properties := make(map[string]interface{})
+	required := make([]interface{}, 0)
+	parameters, _ := step["mcp_parameters"].(map[string]interface{})
+	for _, parameter := range sortedKeys(parameters) {
+		value, _ := parameters[parameter].(string)
+		match := inputReferencePattern.FindStringSubmatch(value)
+		if match == nil {
+			continue
+		}
+		input, _ := inputs[match[1]].(map[string]interface{})
+		property := map[string]interface{}{"type": "string"}
+		if inputType, ok := input["type"].(string); ok && inputType != "" {
+			property["type"] = inputType
+		}
+		if inputDescription, ok := input["description"].(string); ok && inputDescription != "" {
+			property["description"] = inputDescription
+		}
+		properties[parameter] = property
+		if input["required"] == true {
+			required = append(required, parameter)
+		}
+	}
+
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	p.Tools = append(p.Tools, ExportTool{Name: name, Description: description, Parameters: schema})
+}
+
+// sortedKeys returns the keys of a map in alphabetical order
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
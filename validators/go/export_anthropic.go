@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// anthropicNameSeparators matches the spaces, dots and underscores authors
+// write in model names where Anthropic model ids use dashes
+var anthropicNameSeparators = regexp.MustCompile(`[\s._]+`)
+
+// anthropicModelIDs maps the short model names specs use to Anthropic API
+// model ids
+var anthropicModelIDs = map[string]string{
+	"claude-3-opus":     "claude-3-opus-20240229",
+	"claude-3-sonnet":   "claude-3-sonnet-20240229",
+	"claude-3-haiku":    "claude-3-haiku-20240307",
+	"claude-3-5-sonnet": "claude-3-5-sonnet-latest",
+	"claude-3-5-haiku":  "claude-3-5-haiku-latest",
+	"claude-3-7-sonnet": "claude-3-7-sonnet-latest",
+	"claude-sonnet-4":   "claude-sonnet-4-0",
+	"claude-opus-4":     "claude-opus-4-0",
+}
+
+// defaultAnthropicMaxTokens is the max_tokens of exports whose model does
+// not set one; the Messages API requires it
+const defaultAnthropicMaxTokens = 4096
+
+// anthropicModelID maps a model name to its Anthropic model id, reporting
+// whether the name is a known model
+func anthropicModelID(name string) (string, bool) {
+	normalized := anthropicNameSeparators.ReplaceAllString(strings.ToLower(strings.TrimSpace(name)), "-")
+	if id, ok := anthropicModelIDs[normalized]; ok {
+		return id, true
+	}
+	for _, id := range anthropicModelIDs {
+		if normalized == id {
+			return id, true
+		}
+	}
+	// Dated ids such as claude-3-opus-20240229 pass through
+	for short := range anthropicModelIDs {
+		if strings.HasPrefix(normalized, short+"-") {
+			return normalized, true
+		}
+	}
+	return normalized, false
+}
+
+// RenderAnthropicMessages renders an export plan as the request body of
+// the Anthropic Messages API, without messages, with the warnings of the
+// plan and of the rendering. Plans whose model another provider serves are
+// an error unless forceProvider is set, which makes them a warning.
+func RenderAnthropicMessages(plan *ExportPlan, forceProvider bool) (map[string]interface{}, []string, error) {
+	warnings := append(make([]string, 0, len(plan.Warnings)+2), plan.Warnings...)
+
+	if provider := strings.ToLower(plan.Model.Provider); provider != "anthropic" {
+		if !forceProvider {
+			return nil, nil, fmt.Errorf("model %s is provided by %s, not Anthropic; use --force-provider to export it anyway", plan.Model.ID, plan.Model.Provider)
+		}
+		warnings = append(warnings, fmt.Sprintf("model %s is provided by %s, not Anthropic", plan.Model.ID, plan.Model.Provider))
+	}
+
+	model, known := anthropicModelID(plan.Model.Name)
+	if !known {
+		warnings = append(warnings, fmt.Sprintf("model name %s is not a known Anthropic model; check the exported model id %s", plan.Model.Name, model))
+	}
+
+	request := map[string]interface{}{
+		"model":  model,
+		"system": plan.Instructions,
+	}
+	if plan.Model.MaxTokens != nil {
+		request["max_tokens"] = *plan.Model.MaxTokens
+	} else {
+		request["max_tokens"] = defaultAnthropicMaxTokens
+		warnings = append(warnings, fmt.Sprintf("model %s sets no max_tokens; exported with %d", plan.Model.ID, defaultAnthropicMaxTokens))
+	}
+	if plan.Model.Temperature != nil {
+		request["temperature"] = *plan.Model.Temperature
+	}
+	if plan.Model.TopP != nil {
+		request["top_p"] = *plan.Model.TopP
+	}
+
+	tools := make([]interface{}, 0, len(plan.Tools))
+	for _, tool := range plan.Tools {
+		tools = append(tools, map[string]interface{}{
+			"name":         tool.Name,
+			"description":  tool.Description,
+			"input_schema": tool.Parameters,
+		})
+	}
+	request["tools"] = tools
+
+	return request, warnings, nil
+}
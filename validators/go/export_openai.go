@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxAssistantDescription is the longest description the Assistants API accepts
+const maxAssistantDescription = 512
+
+// RenderOpenAIAssistant renders an export plan as the request body of the
+// OpenAI Assistants create-assistant endpoint, with the warnings of the
+// plan and of the rendering. The Assistants API has no max_tokens field,
+// so it is recorded in metadata for the runs to use.
+func RenderOpenAIAssistant(plan *ExportPlan) (map[string]interface{}, []string) {
+	warnings := append(make([]string, 0, len(plan.Warnings)+1), plan.Warnings...)
+
+	assistant := map[string]interface{}{
+		"model":        strings.ToLower(plan.Model.Name),
+		"name":         plan.Name,
+		"instructions": plan.Instructions,
+		"metadata":     map[string]interface{}{"apai_task": plan.TaskID, "apai_model": plan.Model.ID},
+	}
+	if plan.Description != "" {
+		description := plan.Description
+		if len(description) > maxAssistantDescription {
+			description = description[:maxAssistantDescription]
+			warnings = append(warnings, fmt.Sprintf("description truncated to %d characters", maxAssistantDescription))
+		}
+		assistant["description"] = description
+	}
+	if plan.Model.Temperature != nil {
+		assistant["temperature"] = *plan.Model.Temperature
+	}
+	if plan.Model.TopP != nil {
+		assistant["top_p"] = *plan.Model.TopP
+	}
+	if plan.Model.MaxTokens != nil {
+		assistant["metadata"].(map[string]interface{})["max_tokens"] = fmt.Sprintf("%d", *plan.Model.MaxTokens)
+		warnings = append(warnings, "max_tokens has no assistant field; it is stored in metadata, pass it as max_completion_tokens when creating runs")
+	}
+	if provider := strings.ToLower(plan.Model.Provider); provider != "openai" && provider != "azure-openai" {
+		warnings = append(warnings, fmt.Sprintf("model %s is provided by %s, not OpenAI", plan.Model.ID, plan.Model.Provider))
+	}
+
+	tools := make([]interface{}, 0, len(plan.Tools))
+	for _, tool := range plan.Tools {
+		tools = append(tools, map[string]interface{}{
+			"type": "function",
+			"function": map[string]interface{}{
+				"name":        tool.Name,
+				"description": tool.Description,
+				"parameters":  tool.Parameters,
+			},
+		})
+	}
+	assistant["tools"] = tools
+
+	return assistant, warnings
+}
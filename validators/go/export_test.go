@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRenderOpenAIAssistantMatchesGolden(t *testing.T) {
+	spec := loadExampleSpecs(t, "automation/mcp-integration.yaml")[0]
+	plan, err := BuildExportPlan(spec, "customer-inquiry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assistant, _ := RenderOpenAIAssistant(plan)
+
+	content, err := json.MarshalIndent(assistant, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden, err := os.ReadFile(filepath.Join("testdata", "export", "openai-mcp-integration.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(append(content, '\n'), golden) {
+		t.Errorf("export differs from golden file:\n%s", content)
+	}
+
+	// The golden file decodes back to the rendered payload
+	var decoded, rendered map[string]interface{}
+	if err := json.Unmarshal(golden, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(content, &rendered); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, rendered) {
+		t.Errorf("golden file does not round-trip: got %v, want %v", decoded, rendered)
+	}
+}
+
+func TestBuildExportPlan(t *testing.T) {
+	spec := map[string]interface{}{
+		"models": []interface{}{
+			map[string]interface{}{"id": "fast", "provider": "OpenAI", "name": "gpt-4o-mini",
+				"parameters": map[string]interface{}{"temperature": 0.2, "max_tokens": 300}},
+			map[string]interface{}{"id": "slow", "provider": "OpenAI", "name": "gpt-4o"},
+		},
+		"prompts": []interface{}{
+			map[string]interface{}{"id": "system", "role": "system", "template": "Help {{customer_name}}."},
+			map[string]interface{}{"id": "question", "role": "user", "template": "{{question}}"},
+		},
+		"context": map[string]interface{}{"mcp_servers": []interface{}{
+			map[string]interface{}{"id": "crm", "description": "Customer records."},
+		}},
+		"constraints": []interface{}{
+			map[string]interface{}{"id": "no_pii", "rule": "output NOT contains pii"},
+		},
+		"tasks": []interface{}{
+			map[string]interface{}{"id": "answer", "name": "Answer",
+				"input": map[string]interface{}{
+					"customer_id": map[string]interface{}{"type": "string", "required": true, "description": "Customer ID"},
+				},
+				"steps": []interface{}{
+					map[string]interface{}{"name": "lookup", "action": "mcp_tool", "mcp_server": "crm", "mcp_tool": "crm.get customer",
+						"mcp_parameters": map[string]interface{}{"id": "${input.customer_id}", "fields": "all"}},
+					map[string]interface{}{"name": "draft", "model": "fast", "prompt": "question"},
+					map[string]interface{}{"name": "polish", "model": "slow", "prompt": "system"},
+				}},
+		},
+	}
+
+	plan, err := BuildExportPlan(spec, "answer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Model.Name != "gpt-4o-mini" || *plan.Model.Temperature != 0.2 || *plan.Model.MaxTokens != 300 || plan.Model.TopP != nil {
+		t.Errorf("unexpected model: %+v", plan.Model)
+	}
+	if plan.Instructions != "Help {{customer_name}}." {
+		t.Errorf("expected the system prompt with its placeholders, got %q", plan.Instructions)
+	}
+
+	wantTool := ExportTool{
+		Name:        "crm_get_customer",
+		Description: "Calls the crm.get customer tool of the crm MCP server: Customer records",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"id": map[string]interface{}{"type": "string", "description": "Customer ID"},
+			},
+			"required": []interface{}{"id"},
+		},
+	}
+	if len(plan.Tools) != 1 || !reflect.DeepEqual(plan.Tools[0], wantTool) {
+		t.Errorf("unexpected tools: %+v", plan.Tools)
+	}
+
+	for _, want := range []string{"step polish uses model slow", "prompt question is not exported", "separately: no_pii"} {
+		found := false
+		for _, warning := range plan.Warnings {
+			found = found || strings.Contains(warning, want)
+		}
+		if !found {
+			t.Errorf("expected a warning containing %q, got %v", want, plan.Warnings)
+		}
+	}
+
+	if _, err := BuildExportPlan(spec, "missing"); err == nil || !strings.Contains(err.Error(), "task not found") {
+		t.Errorf("expected an unknown task error, got %v", err)
+	}
+}
+
+func TestRenderAnthropicMessages(t *testing.T) {
+	temperature, maxTokens := 0.5, 1000
+	plan := &ExportPlan{
+		TaskID:       "answer",
+		Model:        ExportModel{ID: "claude", Provider: "Anthropic", Name: "Claude 3.5 Sonnet", Temperature: &temperature, MaxTokens: &maxTokens},
+		Instructions: "Help {{customer_name}}.",
+		Tools: []ExportTool{{Name: "get_customer", Description: "Looks up a customer",
+			Parameters: map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}}},
+	}
+
+	request, warnings, err := RenderAnthropicMessages(plan, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"model":       "claude-3-5-sonnet-latest",
+		"system":      "Help {{customer_name}}.",
+		"max_tokens":  1000,
+		"temperature": 0.5,
+		"tools": []interface{}{map[string]interface{}{"name": "get_customer", "description": "Looks up a customer",
+			"input_schema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}}},
+	}
+	if !reflect.DeepEqual(request, want) || len(warnings) != 0 {
+		t.Errorf("got %v with warnings %v, want %v", request, warnings, want)
+	}
+
+	plan.Model.Provider, plan.Model.Name, plan.Model.MaxTokens = "openai", "gpt-4", nil
+	if _, _, err := RenderAnthropicMessages(plan, false); err == nil || !strings.Contains(err.Error(), "--force-provider") {
+		t.Errorf("expected a provider error, got %v", err)
+	}
+	request, warnings, err = RenderAnthropicMessages(plan, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if request["max_tokens"] != defaultAnthropicMaxTokens || len(warnings) != 3 || !strings.Contains(warnings[0], "not Anthropic") {
+		t.Errorf("expected provider, model name and max_tokens warnings, got %v for %v", warnings, request)
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// extensionPrefix marks fields that carry custom metadata, such as
+// "x-cost-center", which are allowed in every object
+const extensionPrefix = "x-"
+
+// reservedExtensionPrefix is the extension namespace reserved for APAI tooling
+const reservedExtensionPrefix = "x-apai-"
+
+// toolingExtensions lists the reserved extension fields our own commands
+// write, which are accepted
+var toolingExtensions = []string{redactionsKey}
+
+// knownFields lists the fields the specification defines for each object,
+// by path as in the deprecation registry. With strict field checking any
+// other field, except extensions, is an error. Free-form objects such as
+// parameters, variables and memory are not listed and accept any field.
+var knownFields = []struct {
+	path   string
+	fields []string
+}{
+	{"", []string{"apai", "inherits", "info", "models", "prompts", "constraints", "tasks", "automations", "context", "evaluation", "extensions", "validation", "governance", "definitions", "components"}},
+	{"info", []string{"id", "title", "version", "description", "author", "license", "contact", "owners", "ai_metadata"}},
+	{"info.ai_metadata", []string{"domain", "complexity", "deployment", "last_updated", "updated_at", "supported_languages", "tags", "hierarchy_info",
+		"risk_level", "risk_management", "data_governance", "logging_retention", "transparency_notice", "human_oversight"}},
+	{"models[]", []string{"id", "type", "provider", "name", "version", "purpose", "intended_use", "capabilities", "context_window", "parameters", "limits", "rate_limit", "quota", "cost", "performance", "fallback", "routing"}},
+	{"models[].routing", []string{"primary", "candidates", "strategy"}},
+	{"prompts[]", []string{"id", "role", "style", "language", "template", "text", "template_file", "variables", "config", "parameters", "examples", "chain", "next", "translations", "variants"}},
+	{"constraints[]", []string{"id", "name", "type", "rule", "pattern", "severity", "enforcement", "description", "actions"}},
+	{"tasks[]", []string{"id", "name", "description", "type", "priority", "abstract", "input", "output", "input_schema", "output_schema", "steps"}},
+	{"tasks[].steps[]", []string{"name", "action", "model", "prompt", "task", "source", "constraints", "conditions", "mcp_server", "mcp_tool", "mcp_resource", "mcp_parameters", "automation", "automation_parameters", "check_automation", "retry", "timeout", "response_format", "input_modalities", "inputs", "outputs", "parallel", "parallel_group"}},
+	{"context", []string{"memory", "conversation", "business_context", "variables", "mcp_servers", "knowledge_base", "persistence", "session"}},
+	{"context.persistence", persistenceFields},
+	{"context.session", persistenceFields},
+	{"context.knowledge_base", []string{"sources", "retrieval", "description"}},
+	{"context.knowledge_base.sources[]", []string{"name", "type", "description", "url", "file", "connection", "endpoint"}},
+	{"context.knowledge_base.retrieval", []string{"top_k", "similarity_threshold", "chunk_size", "chunk_overlap"}},
+	{"context.mcp_servers[]", []string{"id", "name", "description", "version", "transport", "capabilities", "authentication", "security", "health_check", "metadata"}},
+	{"context.mcp_servers[].transport", []string{"type", "command", "args", "url", "headers"}},
+	{"context.mcp_servers[].authentication", []string{"type", "api_key", "token", "custom_auth"}},
+	{"evaluation", []string{"metrics", "test_cases", "performance_tests", "datasets", "experiments"}},
+	{"evaluation.experiments[]", []string{"id", "name", "description", "status", "metric", "variants"}},
+	{"evaluation.experiments[].variants[]", []string{"id", "description", "prompt", "model", "traffic"}},
+	{"evaluation.metrics[]", []string{"name", "description", "type", "direction", "target", "threshold", "measurement", "method", "applies_to", "constraint"}},
+}
+
+// validateExtensions reports fields in the reserved extension namespace,
+// literal credentials in extension fields and, with strict field checking,
+// fields the specification does not define
+func (v *APAIValidator) validateExtensions(spec map[string]interface{}) {
+	v.validateExtensionsWithin(spec, "", "")
+}
+
+// validateExtensionsWithin is validateExtensions for the part of a
+// specification at elementPath, as in objectsWithin
+func (v *APAIValidator) validateExtensionsWithin(value interface{}, elementPath, location string) {
+	reservedExtensions(value, location, func(location string) {
+		v.addError("RESERVED_EXTENSION", fmt.Sprintf("Reserved extension field: %s (the %s prefix is reserved for APAI tooling)", location, reservedExtensionPrefix))
+	})
+	extensionSecrets(value, location, false, func(location string) {
+		if !v.envSubstituted[location] {
+			v.addWarning("HARDCODED_SECRET", fmt.Sprintf("Extension field %s looks like a literal secret, use an ${ENV} reference or vault:// placeholder", location))
+		}
+	})
+
+	if !v.Config.StrictFields {
+		return
+	}
+	for _, known := range knownFields {
+		objectsWithin(value, known.path, elementPath, location, func(object map[string]interface{}, location string) {
+			unknown := make([]string, 0)
+			for field := range object {
+				if !strings.HasPrefix(field, extensionPrefix) && !containsString(known.fields, field) {
+					unknown = append(unknown, field)
+				}
+			}
+			sort.Strings(unknown)
+			for _, field := range unknown {
+				v.addError("UNKNOWN_FIELD", fmt.Sprintf("Unknown field: %s", joinLocation(location, field)))
+			}
+		})
+	}
+}
+
+// reservedExtensions calls report with the location of every field in the
+// reserved extension namespace, at any depth
+func reservedExtensions(value interface{}, location string, report func(string)) {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		fields := make([]string, 0, len(typed))
+		for field := range typed {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		for _, field := range fields {
+			fieldLocation := joinLocation(location, field)
+			if strings.HasPrefix(field, reservedExtensionPrefix) && !containsString(toolingExtensions, field) {
+				report(fieldLocation)
+				continue
+			}
+			reservedExtensions(typed[field], fieldLocation, report)
+		}
+	case []interface{}:
+		for i, item := range typed {
+			reservedExtensions(item, fmt.Sprintf("%s[%d]", location, i), report)
+		}
+	}
+}
+
+// extensionSecrets calls report with the location of every literal
+// credential within an extension field, at any depth: a string value of a
+// field named like a credential, such as x-deploy-token or api_key
+func extensionSecrets(value interface{}, location string, inExtension bool, report func(string)) {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		fields := make([]string, 0, len(typed))
+		for field := range typed {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		for _, field := range fields {
+			fieldLocation := joinLocation(location, field)
+			extension := inExtension || strings.HasPrefix(field, extensionPrefix)
+			if secret, ok := typed[field].(string); ok && extension && isCredentialName(field) && secret != "" && !isSecretReference(secret) {
+				report(fieldLocation)
+				continue
+			}
+			extensionSecrets(typed[field], fieldLocation, extension, report)
+		}
+	case []interface{}:
+		for i, item := range typed {
+			extensionSecrets(item, fmt.Sprintf("%s[%d]", location, i), inExtension, report)
+		}
+	}
+}
+
+// isCredentialName reports whether a field is named like a credential: one
+// of credentialFields, or ending with one, with or without the extension
+// prefix and with dashes for underscores
+func isCredentialName(field string) bool {
+	name := strings.ReplaceAll(strings.TrimPrefix(strings.ToLower(field), extensionPrefix), "-", "_")
+	for _, credential := range credentialFields {
+		if name == credential || strings.HasSuffix(name, "_"+credential) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStrictFieldsAllowExtensions(t *testing.T) {
+	validator := NewAPAIValidator(WithStrictFields(true))
+
+	spec := loadExampleSpecs(t, "templates/basic-template.yaml")[0]
+	model := spec["models"].([]interface{})[0].(map[string]interface{})
+	model["x-team"] = "payments"
+	if !validator.ValidateSpec(spec) {
+		t.Fatalf("expected extension field to pass strict fields, got errors: %v", validator.Errors)
+	}
+
+	model["x-apai-something"] = 1
+	model["cost_center"] = "payments"
+	if validator.ValidateSpec(spec) {
+		t.Fatal("expected reserved extension and unknown field to fail")
+	}
+	for _, want := range []string{
+		"Reserved extension field: models[0].x-apai-something (the x-apai- prefix is reserved for APAI tooling)",
+		"Unknown field: models[0].cost_center",
+	} {
+		if !containsString(validator.Errors, want) {
+			t.Errorf("missing %q in %v", want, validator.Errors)
+		}
+	}
+}
+
+func TestUnknownFieldsAllowedByDefault(t *testing.T) {
+	spec := loadExampleSpecs(t, "templates/basic-template.yaml")[0]
+	spec["models"].([]interface{})[0].(map[string]interface{})["cost_center"] = "payments"
+
+	validator := NewAPAIValidator()
+	if !validator.ValidateSpec(spec) {
+		t.Errorf("expected unknown field to pass without strict fields, got errors: %v", validator.Errors)
+	}
+}
+
+func TestExtensionSecrets(t *testing.T) {
+	spec := loadExampleSpecs(t, "templates/basic-template.yaml")[0]
+	model := spec["models"].([]interface{})[0].(map[string]interface{})
+	model["x-deploy-token"] = "ghp_abc123"
+	model["x-billing"] = map[string]interface{}{"api_key": "sk-live-abc", "account": "payments"}
+	model["x-vault-token"] = "${DEPLOY_TOKEN}"
+	model["x-owner"] = "payments"
+	model["token"] = "not an extension"
+
+	validator := NewAPAIValidator()
+	validator.ValidateSpec(spec)
+	secrets := make([]string, 0)
+	for _, warning := range validator.Warnings {
+		if validator.issue("warning", warning).Code == "HARDCODED_SECRET" {
+			secrets = append(secrets, warning)
+		}
+	}
+	want := []string{
+		"Extension field models[0].x-billing.api_key looks like a literal secret, use an ${ENV} reference or vault:// placeholder",
+		"Extension field models[0].x-deploy-token looks like a literal secret, use an ${ENV} reference or vault:// placeholder",
+	}
+	if !reflect.DeepEqual(secrets, want) {
+		t.Errorf("secret warnings = %v, want %v", secrets, want)
+	}
+}
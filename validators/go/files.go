@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// fileReferences lists the fields that hold paths of local files, relative
+// to the specification declaring them, by object path as in the
+// deprecation registry
+var fileReferences = []struct {
+	path  string
+	field string
+}{
+	{"prompts[]", "template_file"},
+	{"evaluation.datasets[]", "path"},
+	{"context.knowledge_base.sources[]", "file"},
+	{"context.business_context.knowledge_base.sources[]", "file"},
+}
+
+// urlReferences lists the objects whose url field references a remote
+// source, checked alongside the local files
+var urlReferences = []string{
+	"context.knowledge_base.sources[]",
+	"context.business_context.knowledge_base.sources[]",
+}
+
+// urlCheckTimeout bounds each HEAD request made with URL checks
+const urlCheckTimeout = 10 * time.Second
+
+// fileReferencesOf calls visit with every file path referenced by spec
+func fileReferencesOf(spec map[string]interface{}, visit func(object map[string]interface{}, field, location, filePath string)) {
+	for _, reference := range fileReferences {
+		objectsAt(spec, reference.path, "", func(object map[string]interface{}, location string) {
+			if filePath, ok := object[reference.field].(string); ok && filePath != "" {
+				visit(object, reference.field, joinLocation(location, reference.field), filePath)
+			}
+		})
+	}
+}
+
+// rebaseFileReferences joins the relative file paths a specification
+// references to its directory, so they stay valid when it is validated or
+// merged with specifications from other directories
+func (v *APAIValidator) rebaseFileReferences(spec map[string]interface{}, specPath string) {
+	fileReferencesOf(spec, func(object map[string]interface{}, field, location, filePath string) {
+		if v.fsys == nil && filepath.IsAbs(filePath) {
+			return
+		}
+		object[field] = v.joinPath(v.dirPath(specPath), filePath)
+	})
+}
+
+// relativeFileReferences makes rebased file paths relative to dir, where a
+// merged specification is written
+func (v *APAIValidator) relativeFileReferences(spec map[string]interface{}, dir string) {
+	fileReferencesOf(spec, func(object map[string]interface{}, field, location, filePath string) {
+		if filepath.IsAbs(filePath) {
+			return
+		}
+		if relative, err := filepath.Rel(dir, filePath); err == nil {
+			object[field] = filepath.ToSlash(relative)
+		}
+	})
+}
+
+// validateFileReferences checks that the evaluation datasets and knowledge
+// sources a specification references exist, and that URL sources are well
+// formed; with URL checks they must also answer a HEAD request
+func (v *APAIValidator) validateFileReferences(ctx context.Context, spec map[string]interface{}) {
+	fileReferencesOf(spec, func(object map[string]interface{}, field, location, filePath string) {
+		// Prompt template files are checked with the prompts
+		if field == "template_file" {
+			return
+		}
+		// A file pattern must match at least one file
+		if strings.ContainsAny(filePath, "*?[") {
+			if matches, err := v.glob(filePath); err != nil || len(matches) == 0 {
+				v.addError("FILE_NOT_FOUND", fmt.Sprintf("%s file not found: %s", location, filePath))
+			}
+			return
+		}
+		content, err := v.readFile(filePath)
+		if err != nil {
+			v.addError("FILE_NOT_FOUND", fmt.Sprintf("%s file not found: %s", location, filePath))
+		} else if len(strings.TrimSpace(string(content))) == 0 {
+			v.addWarning("EMPTY_FILE", fmt.Sprintf("%s file is empty: %s", location, filePath))
+		}
+	})
+
+	for _, path := range urlReferences {
+		objectsAt(spec, path, "", func(object map[string]interface{}, location string) {
+			rawURL, ok := object["url"].(string)
+			if !ok {
+				return
+			}
+			location = joinLocation(location, "url")
+
+			parsed, err := url.Parse(rawURL)
+			if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+				v.addError("INVALID_SOURCE_URL", fmt.Sprintf("%s is malformed: %s", location, rawURL))
+				return
+			}
+			// Once ctx is done, remaining URLs are not checked
+			if v.Config.CheckURLs && ctx.Err() == nil {
+				if err := checkURL(ctx, rawURL); err != nil {
+					v.addWarning("UNREACHABLE_URL", fmt.Sprintf("%s is unreachable: %v", location, err))
+				}
+			}
+		})
+	}
+}
+
+// checkURL sends a HEAD request to a URL and fails on error statuses
+func checkURL(ctx context.Context, rawURL string) error {
+	ctx, cancel := context.WithTimeout(ctx, urlCheckTimeout)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodHead, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	if response.StatusCode >= 400 {
+		return fmt.Errorf("HTTP %d", response.StatusCode)
+	}
+	return nil
+}
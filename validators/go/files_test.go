@@ -0,0 +1,60 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFileChecks(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	spec := loadExampleSpecs(t, "templates/basic-template.yaml")[0]
+	spec["evaluation"].(map[string]interface{})["datasets"] = []interface{}{
+		map[string]interface{}{"path": "data/eval.jsonl"},
+		map[string]interface{}{"path": "data/empty.jsonl"},
+		map[string]interface{}{"path": "data/missing.jsonl"},
+	}
+	spec["context"].(map[string]interface{})["knowledge_base"] = map[string]interface{}{
+		"sources": []interface{}{
+			map[string]interface{}{"name": "faq", "type": "url", "url": "docs.example.com/faq"},
+			map[string]interface{}{"name": "help", "type": "url", "url": server.URL + "/faq"},
+			map[string]interface{}{"name": "manuals", "type": "file", "file": "docs/*.md"},
+		},
+	}
+	spec["models"] = append(spec["models"].([]interface{}), map[string]interface{}{
+		"id": "embedder", "type": "Embedding", "provider": "openai", "name": "text-embedding-3-small", "purpose": "knowledge base indexing",
+	})
+	fsys := fstest.MapFS{
+		"data/eval.jsonl":  {Data: []byte("{\"input\": \"hi\"}\n")},
+		"data/empty.jsonl": {Data: []byte("\n")},
+	}
+
+	validator := NewAPAIValidator(WithFS(fsys))
+	if !validator.ValidateSpec(spec) {
+		t.Fatalf("expected references to be ignored without file checks, got errors: %v", validator.Errors)
+	}
+
+	validator = NewAPAIValidator(WithFS(fsys), WithFileChecks(true))
+	validator.ValidateSpec(spec)
+	for _, want := range []string{
+		"evaluation.datasets[2].path file not found: data/missing.jsonl",
+		"context.knowledge_base.sources[0].url is malformed: docs.example.com/faq",
+		"context.knowledge_base.sources[2].file file not found: docs/*.md",
+	} {
+		if !containsString(validator.Errors, want) {
+			t.Errorf("missing %q in %v", want, validator.Errors)
+		}
+	}
+	if want := "evaluation.datasets[1].path file is empty: data/empty.jsonl"; !containsString(validator.Warnings, want) {
+		t.Errorf("missing %q in %v", want, validator.Warnings)
+	}
+
+	validator = NewAPAIValidator(WithFS(fsys), WithFileChecks(true), WithURLChecks(true))
+	validator.ValidateSpec(spec)
+	if want := "context.knowledge_base.sources[1].url is unreachable: HTTP 404"; !containsString(validator.Warnings, want) {
+		t.Errorf("missing %q in %v", want, validator.Warnings)
+	}
+}
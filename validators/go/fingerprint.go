@@ -0,0 +1,78 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Fingerprint returns the SHA-256 digest of the canonical form of a
+// specification, in hex. References are resolved, keys are sorted, numbers
+// compare by value and line endings are normalized, so formatting-only
+// edits keep the fingerprint while any change of content alters it.
+func Fingerprint(spec map[string]interface{}) (string, error) {
+	resolved, err := ResolveRefs(spec)
+	if err != nil {
+		return "", err
+	}
+
+	return canonicalDigest(resolved), nil
+}
+
+// SpecHash returns the fingerprint of a specification for caches and drift
+// detection, which need a hash for every spec: one whose references do not
+// resolve is hashed with its $ref pointers as written.
+func SpecHash(spec map[string]interface{}) string {
+	if fingerprint, err := Fingerprint(spec); err == nil {
+		return fingerprint
+	}
+	return canonicalDigest(spec)
+}
+
+// canonicalDigest returns the SHA-256 digest of the canonical JSON form of
+// a value, in hex
+func canonicalDigest(value interface{}) string {
+	// encoding/json writes map keys in sorted order, and canonical values
+	// hold nothing it cannot encode
+	content, _ := json.Marshal(canonicalValue(value))
+	digest := sha256.Sum256(content)
+	return hex.EncodeToString(digest[:])
+}
+
+// canonicalValue normalizes the representation of a decoded value: every
+// number becomes a float64, so 1 and 1.0 are equal, strings use \n, and
+// the non-finite numbers YAML allows, such as .nan, become strings
+func canonicalValue(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		canonical := make(map[string]interface{}, len(typed))
+		for key, item := range typed {
+			canonical[key] = canonicalValue(item)
+		}
+		return canonical
+	case []interface{}:
+		canonical := make([]interface{}, len(typed))
+		for i, item := range typed {
+			canonical[i] = canonicalValue(item)
+		}
+		return canonical
+	case string:
+		return strings.ReplaceAll(typed, "\r\n", "\n")
+	case int:
+		return float64(typed)
+	case int64:
+		return float64(typed)
+	case uint64:
+		return float64(typed)
+	case float64:
+		if math.IsNaN(typed) || math.IsInf(typed, 0) {
+			return strconv.FormatFloat(typed, 'g', -1, 64)
+		}
+		return typed
+	default:
+		return typed
+	}
+}
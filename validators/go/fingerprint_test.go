@@ -0,0 +1,86 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func fingerprintYAML(t *testing.T, content string) string {
+	t.Helper()
+	var spec map[string]interface{}
+	if err := decodeYAML([]byte(content), &spec); err != nil {
+		t.Fatal(err)
+	}
+	fingerprint, err := Fingerprint(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fingerprint
+}
+
+func TestFingerprintIgnoresFormatting(t *testing.T) {
+	original := fingerprintYAML(t, `apai: "0.1.0"
+definitions:
+  greeting: "Hello"
+models:
+  - id: main_model
+    parameters:
+      temperature: 1
+prompts:
+  - id: system_prompt
+    template:
+      $ref: "#/definitions/greeting"
+`)
+
+	reformatted := fingerprintYAML(t, "# Reordered, requoted and with CRLF line endings\r\n"+
+		"prompts: [{template: {$ref: '#/definitions/greeting'}, id: 'system_prompt'}]\r\n"+
+		"models:\r\n"+
+		"  - parameters: {temperature: 1.0}\r\n"+
+		"    id: \"main_model\"\r\n"+
+		"apai: '0.1.0'\r\n"+
+		"definitions: {greeting: Hello}\r\n")
+	if reformatted != original {
+		t.Errorf("formatting changed the fingerprint: %s != %s", reformatted, original)
+	}
+
+	changed := fingerprintYAML(t, `apai: "0.1.0"
+definitions:
+  greeting: "Hello"
+models:
+  - id: main_model
+    parameters:
+      temperature: 0.7
+prompts:
+  - id: system_prompt
+    template:
+      $ref: "#/definitions/greeting"
+`)
+	if changed == original {
+		t.Error("a semantic change kept the fingerprint")
+	}
+}
+
+func TestSpecHash(t *testing.T) {
+	var fromYAML, fromJSON map[string]interface{}
+	if err := decodeYAML([]byte("apai: \"0.1.0\"\nmodels:\n  - {id: main_model, parameters: {temperature: 1}}\n"), &fromYAML); err != nil {
+		t.Fatal(err)
+	}
+	if err := decodeYAML([]byte(`{"models": [{"parameters": {"temperature": 1.0}, "id": "main_model"}], "apai": "0.1.0"}`), &fromJSON); err != nil {
+		t.Fatal(err)
+	}
+	if SpecHash(fromYAML) != SpecHash(fromJSON) {
+		t.Error("identical content in YAML and JSON hashed differently")
+	}
+	if hash, _ := Fingerprint(fromYAML); SpecHash(fromYAML) != hash {
+		t.Errorf("SpecHash differs from Fingerprint: %s != %s", SpecHash(fromYAML), hash)
+	}
+
+	// Specs with dangling references or non-finite numbers still hash
+	broken := map[string]interface{}{
+		"prompts": []interface{}{map[string]interface{}{"$ref": "#/definitions/missing"}},
+		"models":  []interface{}{map[string]interface{}{"parameters": map[string]interface{}{"temperature": math.NaN()}}},
+	}
+	if hash := SpecHash(broken); len(hash) != 64 || hash != SpecHash(broken) {
+		t.Errorf("expected a stable SHA-256 hex digest, got %q", hash)
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"go/token"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// goInitialisms are the words Go names spell in capitals
+var goInitialisms = map[string]bool{
+	"ai": true, "api": true, "db": true, "http": true, "id": true, "json": true,
+	"llm": true, "mcp": true, "pii": true, "sql": true, "ui": true, "url": true,
+}
+
+// generatedKind describes the elements of a section that get typed ids
+type generatedKind struct {
+	// Name is the Go type of the elements; their ids are of type NameID
+	Name    string
+	Section string
+	// Fields maps the Go fields of the element type to spec fields
+	Fields []generatedField
+}
+
+// generatedField is a string field copied from the spec into generated code
+type generatedField struct {
+	Name string
+	Key  string
+}
+
+// generatedKinds lists the sections generate go emits constants for
+var generatedKinds = []generatedKind{
+	{"Model", "models", []generatedField{{"Provider", "provider"}, {"Name", "name"}, {"Type", "type"}}},
+	{"Prompt", "prompts", []generatedField{{"Role", "role"}, {"Template", "template"}}},
+	{"Constraint", "constraints", []generatedField{{"Type", "type"}, {"Rule", "rule"}, {"Severity", "severity"}}},
+	{"Task", "tasks", []generatedField{{"Name", "name"}, {"Description", "description"}}},
+	{"MCPServer", "context.mcp_servers", []generatedField{{"Name", "name"}, {"Description", "description"}}},
+}
+
+// generatedElement is an element with a typed id constant
+type generatedElement struct {
+	Const  string
+	ID     string
+	Values []string
+}
+
+// generatedSection is a kind and its elements, in spec order
+type generatedSection struct {
+	generatedKind
+	Elements []generatedElement
+}
+
+// goSourceTemplate is the generated file before gofmt
+var goSourceTemplate = template.Must(template.New("go").Parse(`// Code generated by apai generate go from {{.Source}}; DO NOT EDIT.
+
+// Package {{.Package}} holds the ids and content of {{.Source}}.
+package {{.Package}}
+{{range .Sections}}
+// {{.Name}}ID identifies an element of {{.Section}}
+type {{.Name}}ID string
+
+// {{.Name}}ID constants, one per element of {{.Section}}
+const (
+{{- $kind := .Name}}
+{{- range .Elements}}
+	{{.Const}} {{$kind}}ID = {{printf "%q" .ID}}
+{{- end}}
+)
+
+// {{.Name}} is an element of {{.Section}}
+type {{.Name}} struct {
+	ID {{.Name}}ID
+{{- range .Fields}}
+	{{.Name}} string
+{{- end}}
+}
+
+// Lookup{{.Name}} returns the element of {{.Section}} with the given id
+func Lookup{{.Name}}(id {{.Name}}ID) ({{.Name}}, bool) {
+	for _, element := range Spec.{{.Name}}s {
+		if element.ID == id {
+			return element, true
+		}
+	}
+	return {{.Name}}{}, false
+}
+{{end}}
+// PromptTemplate returns the template of a prompt, or "" for an unknown id
+func PromptTemplate(id PromptID) string {
+	prompt, _ := LookupPrompt(id)
+	return prompt.Template
+}
+
+// Spec holds the content of the specification
+var Spec = struct {
+{{- range .Sections}}
+	{{.Name}}s []{{.Name}}
+{{- end}}
+}{
+{{- range .Sections}}
+{{- $section := .}}
+	{{.Name}}s: []{{.Name}}{
+{{- range .Elements}}
+		{ID: {{.Const}}{{range $i, $value := .Values}}, {{(index $section.Fields $i).Name}}: {{printf "%q" $value}}{{end}}},
+{{- end}}
+	},
+{{- end}}
+}
+
+// specJSON is the effective specification in canonical JSON
+const specJSON = {{printf "%q" .JSON}}
+
+// SpecJSON returns the effective specification in canonical JSON
+func SpecJSON() []byte {
+	return []byte(specJSON)
+}
+`))
+
+// GenerateGo generates a Go file in package pkg with a typed id constant
+// for every model, prompt, constraint, task and MCP server of spec, the
+// content of those elements and accessors such as PromptTemplate. The
+// output is gofmt-formatted and depends only on spec, pkg and sourceName,
+// the name of the specification file recorded in the header.
+func GenerateGo(spec map[string]interface{}, pkg, sourceName string) ([]byte, error) {
+	if !token.IsIdentifier(pkg) || token.IsKeyword(pkg) {
+		return nil, fmt.Errorf("invalid package name: %s", pkg)
+	}
+
+	// Constants must not collide with each other or the generated names
+	used := map[string]bool{"Spec": true, "SpecJSON": true, "PromptTemplate": true}
+	for _, kind := range generatedKinds {
+		used[kind.Name], used[kind.Name+"ID"], used["Lookup"+kind.Name] = true, true, true
+	}
+
+	sections := make([]generatedSection, 0, len(generatedKinds))
+	for _, kind := range generatedKinds {
+		section := generatedSection{generatedKind: kind, Elements: make([]generatedElement, 0)}
+		objectsAt(spec, kind.Section+"[]", "", func(element map[string]interface{}, _ string) {
+			id, ok := element["id"].(string)
+			if !ok || id == "" {
+				return
+			}
+			constName := kind.Name + goIdentifier(id)
+			for suffix := 2; used[constName]; suffix++ {
+				constName = fmt.Sprintf("%s%s%d", kind.Name, goIdentifier(id), suffix)
+			}
+			used[constName] = true
+
+			values := make([]string, len(kind.Fields))
+			for i, field := range kind.Fields {
+				if value, ok := element[field.Key]; ok && value != nil {
+					values[i] = fmt.Sprint(value)
+				}
+			}
+			section.Elements = append(section.Elements, generatedElement{Const: constName, ID: id, Values: values})
+		})
+		sections = append(sections, section)
+	}
+
+	canonical, err := MarshalCanonicalJSON(spec)
+	if err != nil {
+		return nil, err
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, canonical); err != nil {
+		return nil, err
+	}
+
+	var source bytes.Buffer
+	err = goSourceTemplate.Execute(&source, map[string]interface{}{
+		"Package":  pkg,
+		"Source":   sourceName,
+		"Sections": sections,
+		"JSON":     compact.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return format.Source(source.Bytes())
+}
+
+// goIdentifier turns an id such as "customer_service_llm" into the
+// exported Go name CustomerServiceLLM
+func goIdentifier(id string) string {
+	words := strings.FieldsFunc(id, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var name strings.Builder
+	for _, word := range words {
+		if goInitialisms[strings.ToLower(word)] {
+			name.WriteString(strings.ToUpper(word))
+			continue
+		}
+		runes := []rune(word)
+		name.WriteString(strings.ToUpper(string(runes[0])) + string(runes[1:]))
+	}
+	if name.Len() == 0 {
+		return "X"
+	}
+	return name.String()
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// generatedUsage exercises the generated API the way services call it
+const generatedUsage = `package aispec
+
+var _ string = PromptTemplate(PromptID("any"))
+var _, _ = LookupModel(ModelID("any"))
+var _ []byte = SpecJSON()
+`
+
+func TestGenerateGoCompilesForExamples(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("..", "..", "examples", "*", "*.yaml"))
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no example specs found: %v", err)
+	}
+
+	for _, specPath := range paths {
+		t.Run(filepath.Base(specPath), func(t *testing.T) {
+			validator := NewAPAIValidator()
+			spec, err := validator.loadSpec(specPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			content, err := GenerateGo(spec, "aispec", filepath.Base(specPath))
+			if err != nil {
+				t.Fatal(err)
+			}
+			again, _ := GenerateGo(spec, "aispec", filepath.Base(specPath))
+			if !bytes.Equal(content, again) {
+				t.Error("generated code differs between runs")
+			}
+			if formatted, err := format.Source(content); err != nil || !bytes.Equal(formatted, content) {
+				t.Errorf("generated code is not gofmt-clean: %v", err)
+			}
+			if !strings.HasPrefix(string(content), "// Code generated by apai generate go from ") ||
+				!strings.Contains(strings.SplitN(string(content), "\n", 2)[0], "DO NOT EDIT.") {
+				t.Error("generated code lacks the generated header")
+			}
+
+			typeCheck(t, map[string]string{"zz_generated.go": string(content), "usage.go": generatedUsage})
+		})
+	}
+}
+
+func TestGenerateGoNames(t *testing.T) {
+	spec := map[string]interface{}{
+		"models": []interface{}{
+			map[string]interface{}{"id": "customer_service_llm", "name": "gpt-4"},
+			map[string]interface{}{"id": "customer-service-llm"},
+			map[string]interface{}{"id": "id"},
+		},
+		"prompts": []interface{}{
+			map[string]interface{}{"id": "template", "template": "Hello {{name}}"},
+		},
+	}
+
+	content, err := GenerateGo(spec, "aispec", "spec.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`ModelCustomerServiceLLM  ModelID = "customer_service_llm"`,
+		`ModelCustomerServiceLLM2 ModelID = "customer-service-llm"`,
+		`ModelID2                 ModelID = "id"`,
+		`PromptTemplate2 PromptID = "template"`,
+		`Template: "Hello {{name}}"`,
+	} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("expected generated code to contain %q:\n%s", want, content)
+		}
+	}
+	typeCheck(t, map[string]string{"zz_generated.go": string(content)})
+
+	if _, err := GenerateGo(spec, "func", "spec.yaml"); err == nil {
+		t.Error("expected an error for a keyword package name")
+	}
+}
+
+// typeCheck compiles Go source files of one package, failing the test on
+// any error
+func typeCheck(t *testing.T, files map[string]string) {
+	t.Helper()
+	fset := token.NewFileSet()
+	parsed := make([]*ast.File, 0, len(files))
+	for name, source := range files {
+		file, err := parser.ParseFile(fset, name, source, parser.ParseComments)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed = append(parsed, file)
+	}
+	config := types.Config{Importer: importer.Default()}
+	if _, err := config.Check("aispec", fset, parsed, nil); err != nil {
+		t.Fatalf("generated code does not compile: %v", err)
+	}
+}
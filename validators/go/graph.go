@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// GraphNode represents an entity of the specification in the reference graph
+type GraphNode struct {
+	ID      string
+	Kind    string
+	Label   string
+	Missing bool
+}
+
+// GraphEdge represents a reference from a task to another entity
+type GraphEdge struct {
+	From string
+	To   string
+}
+
+// SpecGraph represents the reference structure of a specification
+type SpecGraph struct {
+	Nodes []GraphNode
+	Edges []GraphEdge
+}
+
+// graphStepReferences maps step fields to the kind of entity they reference
+var graphStepReferences = []struct {
+	field string
+	kind  string
+}{
+	{"model", "model"},
+	{"prompt", "prompt"},
+	{"mcp_server", "mcp_server"},
+}
+
+// BuildGraph builds the reference graph of a specification
+func BuildGraph(spec map[string]interface{}) *SpecGraph {
+	graph := &SpecGraph{}
+	declared := make(map[string]bool)
+
+	addNode := func(kind, id string, missing bool) string {
+		nodeID := kind + ":" + id
+		if !declared[nodeID] {
+			declared[nodeID] = true
+			graph.Nodes = append(graph.Nodes, GraphNode{ID: nodeID, Kind: kind, Label: id, Missing: missing})
+		}
+		return nodeID
+	}
+
+	for _, id := range sectionIds(spec["models"]) {
+		addNode("model", id, false)
+	}
+	for _, id := range sectionIds(spec["prompts"]) {
+		addNode("prompt", id, false)
+	}
+	if contextMap, ok := spec["context"].(map[string]interface{}); ok {
+		for _, id := range sectionIds(contextMap["mcp_servers"]) {
+			addNode("mcp_server", id, false)
+		}
+	}
+
+	tasksSlice, _ := spec["tasks"].([]interface{})
+	for _, task := range tasksSlice {
+		taskMap, ok := task.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		taskID, ok := taskMap["id"].(string)
+		if !ok {
+			continue
+		}
+		taskNode := addNode("task", taskID, false)
+
+		edges := make(map[string]bool)
+		stepsSlice, _ := taskMap["steps"].([]interface{})
+		for _, step := range stepsSlice {
+			stepMap, ok := step.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			for _, ref := range graphStepReferences {
+				target, ok := stepMap[ref.field].(string)
+				if !ok {
+					continue
+				}
+				targetNode := ref.kind + ":" + target
+				if !declared[targetNode] {
+					addNode(ref.kind, target, true)
+				}
+				if !edges[targetNode] {
+					edges[targetNode] = true
+					graph.Edges = append(graph.Edges, GraphEdge{From: taskNode, To: targetNode})
+				}
+			}
+		}
+	}
+
+	return graph
+}
+
+// sectionIds returns the ids declared in an array section, in order
+func sectionIds(section interface{}) []string {
+	ids := make([]string, 0)
+	sectionSlice, ok := section.([]interface{})
+	if !ok {
+		return ids
+	}
+
+	for _, item := range sectionSlice {
+		if itemMap, ok := item.(map[string]interface{}); ok {
+			if id, ok := itemMap["id"].(string); ok {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+// RenderDOT renders the graph in Graphviz DOT format
+func (g *SpecGraph) RenderDOT() string {
+	shapes := map[string]string{
+		"task":       "box",
+		"model":      "ellipse",
+		"prompt":     "note",
+		"mcp_server": "component",
+	}
+
+	var b strings.Builder
+	b.WriteString("digraph apai {\n")
+	b.WriteString("  rankdir=LR;\n")
+	for _, node := range g.Nodes {
+		attrs := fmt.Sprintf("label=%q, shape=%s", node.Kind+": "+node.Label, shapes[node.Kind])
+		if node.Missing {
+			attrs += ", style=dashed, color=red"
+		}
+		fmt.Fprintf(&b, "  %q [%s];\n", node.ID, attrs)
+	}
+	for _, edge := range g.Edges {
+		fmt.Fprintf(&b, "  %q -> %q;\n", edge.From, edge.To)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// RenderMermaid renders the graph as a Mermaid flowchart
+func (g *SpecGraph) RenderMermaid() string {
+	// Mermaid ids must be plain identifiers, so nodes are numbered
+	ids := make(map[string]string, len(g.Nodes))
+	for i, node := range g.Nodes {
+		ids[node.ID] = fmt.Sprintf("n%d", i)
+	}
+
+	var b strings.Builder
+	b.WriteString("graph LR\n")
+	for _, node := range g.Nodes {
+		label := node.Kind + ": " + node.Label
+		if node.Missing {
+			label += " (missing)"
+		}
+		label = strings.ReplaceAll(label, `"`, "#quot;")
+		fmt.Fprintf(&b, "  %s[\"%s\"]\n", ids[node.ID], label)
+	}
+	for _, edge := range g.Edges {
+		fmt.Fprintf(&b, "  %s --> %s\n", ids[edge.From], ids[edge.To])
+	}
+	return b.String()
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestBuildGraph(t *testing.T) {
+	var spec map[string]interface{}
+	err := yaml.Unmarshal([]byte(`
+models:
+  - id: main_model
+prompts:
+  - id: "support \"v2\""
+context:
+  mcp_servers:
+    - id: crm
+tasks:
+  - id: answer
+    steps:
+      - model: main_model
+        prompt: "support \"v2\""
+      - model: main_model
+        mcp_server: crm
+      - model: ghost
+  - steps:
+      - model: main_model
+`), &spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	graph := BuildGraph(spec)
+	wantNodes := []GraphNode{
+		{ID: "model:main_model", Kind: "model", Label: "main_model"},
+		{ID: `prompt:support "v2"`, Kind: "prompt", Label: `support "v2"`},
+		{ID: "mcp_server:crm", Kind: "mcp_server", Label: "crm"},
+		{ID: "task:answer", Kind: "task", Label: "answer"},
+		{ID: "model:ghost", Kind: "model", Label: "ghost", Missing: true},
+	}
+	wantEdges := []GraphEdge{
+		{From: "task:answer", To: "model:main_model"},
+		{From: "task:answer", To: `prompt:support "v2"`},
+		{From: "task:answer", To: "mcp_server:crm"},
+		{From: "task:answer", To: "model:ghost"},
+	}
+	if !reflect.DeepEqual(graph.Nodes, wantNodes) {
+		t.Errorf("nodes = %v, want %v", graph.Nodes, wantNodes)
+	}
+	if !reflect.DeepEqual(graph.Edges, wantEdges) {
+		t.Errorf("edges = %v, want %v", graph.Edges, wantEdges)
+	}
+
+	wantDOT := `digraph apai {
+  rankdir=LR;
+  "model:main_model" [label="model: main_model", shape=ellipse];
+  "prompt:support \"v2\"" [label="prompt: support \"v2\"", shape=note];
+  "mcp_server:crm" [label="mcp_server: crm", shape=component];
+  "task:answer" [label="task: answer", shape=box];
+  "model:ghost" [label="model: ghost", shape=ellipse, style=dashed, color=red];
+  "task:answer" -> "model:main_model";
+  "task:answer" -> "prompt:support \"v2\"";
+  "task:answer" -> "mcp_server:crm";
+  "task:answer" -> "model:ghost";
+}
+`
+	if got := graph.RenderDOT(); got != wantDOT {
+		t.Errorf("DOT =\n%s\nwant\n%s", got, wantDOT)
+	}
+
+	wantMermaid := `graph LR
+  n0["model: main_model"]
+  n1["prompt: support #quot;v2#quot;"]
+  n2["mcp_server: crm"]
+  n3["task: answer"]
+  n4["model: ghost (missing)"]
+  n3 --> n0
+  n3 --> n1
+  n3 --> n2
+  n3 --> n4
+`
+	if got := graph.RenderMermaid(); got != wantMermaid {
+		t.Errorf("Mermaid =\n%s\nwant\n%s", got, wantMermaid)
+	}
+}
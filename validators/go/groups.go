@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// groupByValues are the ways findings can be grouped
+var groupByValues = []string{"section", "code"}
+
+// IssueGroup is the findings of a validation result sharing a section of
+// the specification or a rule code
+type IssueGroup struct {
+	Name     string  `json:"name"`
+	Errors   int     `json:"errors"`
+	Warnings int     `json:"warnings"`
+	Issues   []Issue `json:"issues"`
+}
+
+// otherSection names the group of findings not tied to a section, and
+// uncodedGroup that of findings no rule matches
+const (
+	otherSection = "other"
+	uncodedGroup = "uncoded"
+)
+
+// topLevelSections are the top-level fields of a specification
+var topLevelSections = []string{"apai", "inherits", "info", "models", "prompts", "constraints", "tasks", "automations", "context", "evaluation", "extensions", "validation", "governance", "definitions", "components"}
+
+// sectionPathPattern matches a location in a specification, such as
+// tasks[0].steps or context.memory
+var sectionPathPattern = regexp.MustCompile(`(?:^|[\s(])([a-z_]+)(?:\[|\.|\s|$)`)
+
+// sectionKeywords map words of findings that name no location to the
+// section they concern, in the order they are looked for
+var sectionKeywords = []struct {
+	pattern *regexp.Regexp
+	section string
+}{
+	{regexp.MustCompile(`(?i)\b(task|step)s?\b`), "tasks"},
+	{regexp.MustCompile(`(?i)\bprompts?\b`), "prompts"},
+	{regexp.MustCompile(`(?i)\bmodels?\b`), "models"},
+	{regexp.MustCompile(`(?i)\bconstraints?\b`), "constraints"},
+	{regexp.MustCompile(`(?i)\b(mcp|knowledge|retrieval|persistence|session|memory|variables?)\b`), "context"},
+	{regexp.MustCompile(`(?i)\b(experiments?|metrics?|test case)\b`), "evaluation"},
+	{regexp.MustCompile(`(?i)\b(inherit\w*|parent|hierarchy)\b`), "inherits"},
+}
+
+// findingSection returns the top-level section a finding concerns: the
+// first section its message locates it in, else the section of the first
+// word that names one, else other
+func findingSection(message string) string {
+	message = unattributed(message)
+	for _, match := range sectionPathPattern.FindAllStringSubmatch(message, -1) {
+		if containsString(topLevelSections, match[1]) {
+			return match[1]
+		}
+	}
+
+	section, first := otherSection, len(message)
+	for _, keyword := range sectionKeywords {
+		if location := keyword.pattern.FindStringIndex(message); location != nil && location[0] < first {
+			section, first = keyword.section, location[0]
+		}
+	}
+	return section
+}
+
+// GroupIssues groups the findings of a result by section or by rule code.
+// Groups with the most findings come first, then by name; findings keep
+// their order, errors before warnings.
+func GroupIssues(result ValidationResult, by string) ([]IssueGroup, error) {
+	if !containsString(groupByValues, by) {
+		return nil, fmt.Errorf("unsupported grouping: %s (expected %s)", by, strings.Join(groupByValues, " or "))
+	}
+
+	groups := make(map[string]*IssueGroup)
+	add := func(issue Issue) {
+		name := issue.Code
+		if by == "section" {
+			name = findingSection(issue.Message)
+		} else if name == "" {
+			name = uncodedGroup
+		}
+		group, ok := groups[name]
+		if !ok {
+			group = &IssueGroup{Name: name, Issues: make([]Issue, 0)}
+			groups[name] = group
+		}
+		if issue.Severity == "error" {
+			group.Errors++
+		} else {
+			group.Warnings++
+		}
+		group.Issues = append(group.Issues, issue)
+	}
+	for _, message := range result.Errors {
+		add(result.issue("error", message))
+	}
+	for _, message := range result.Warnings {
+		add(result.issue("warning", message))
+	}
+
+	grouped := make([]IssueGroup, 0, len(groups))
+	for _, group := range groups {
+		grouped = append(grouped, *group)
+	}
+	sort.Slice(grouped, func(i, j int) bool {
+		if len(grouped[i].Issues) != len(grouped[j].Issues) {
+			return len(grouped[i].Issues) > len(grouped[j].Issues)
+		}
+		return grouped[i].Name < grouped[j].Name
+	})
+	return grouped, nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFindingSection(t *testing.T) {
+	cases := map[string]string{
+		`models[0].parameters.temperature must be a number, got string "0.3" (remove the quotes)`:          "models",
+		"Task references unknown model: missing":                                                           "tasks",
+		"Duplicate prompt ID: greeting":                                                                    "prompts",
+		"Invalid persistence store: cassandra in context.session (expected redis, postgres, memory, file)": "context",
+		"MCP server files has no tools":                                                                    "context",
+		"Experiment tone references unknown metric: satisfaction":                                          "evaluation",
+		"In parent base.yaml: Constraint 0 missing required field: rule":                                   "constraints",
+		"Unsupported APAI version: 0.2.0":                                                                  "other",
+	}
+	for message, want := range cases {
+		if got := findingSection(message); got != want {
+			t.Errorf("findingSection(%q) = %q, want %q", message, got, want)
+		}
+	}
+}
+
+func TestGroupIssues(t *testing.T) {
+	result := ValidationResult{
+		Errors: []string{
+			"Task references unknown model: missing",
+			"Task references unknown prompt: missing",
+			"Duplicate prompt ID: greeting",
+		},
+		Warnings: []string{"Prompt welcome is never used"},
+	}
+
+	groups, err := GroupIssues(result, "section")
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0, len(groups))
+	for _, group := range groups {
+		names = append(names, group.Name)
+	}
+	if want := []string{"prompts", "tasks"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("expected groups %v, got %v", want, names)
+	}
+	if prompts := groups[0]; prompts.Errors != 1 || prompts.Warnings != 1 || prompts.Issues[0].Severity != "error" {
+		t.Errorf("unexpected prompts group: %+v", prompts)
+	}
+
+	groups, err = GroupIssues(result, "code")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if groups[0].Name != "UNKNOWN_REFERENCE" || groups[0].Errors != 2 {
+		t.Errorf("expected the unknown references first, got %+v", groups)
+	}
+
+	if _, err := GroupIssues(result, "file"); err == nil {
+		t.Error("expected an unsupported grouping to fail")
+	}
+}
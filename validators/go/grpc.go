@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/FabioGuin/APAI/validators/go/internal/apaipb"
+)
+
+// grpcService serves the Validator gRPC service with the validator,
+// readiness and metrics of a Server, reading the files ResolveSpec and
+// GetStats name from root
+type grpcService struct {
+	apaipb.UnimplementedValidatorServer
+	server *Server
+	root   fs.FS
+}
+
+// RegisterGRPC registers the Validator gRPC service on registrar. It
+// validates like the HTTP endpoints, sharing their validator, readiness
+// and metrics; ResolveSpec and GetStats read files from root and fail
+// when root is nil.
+func (s *Server) RegisterGRPC(registrar grpc.ServiceRegistrar, root fs.FS) {
+	apaipb.RegisterValidatorServer(registrar, &grpcService{server: s, root: root})
+}
+
+// ValidateSpec validates a specification, as POST /validate does
+func (g *grpcService) ValidateSpec(ctx context.Context, request *apaipb.ValidateSpecRequest) (*apaipb.ValidationResult, error) {
+	validator, err := g.readyValidator()
+	if err != nil {
+		return nil, err
+	}
+	return g.validate(ctx, validator, request.GetSpec(), request.GetErrorsOnly())
+}
+
+// ValidateSpecs validates the specifications of a stream as they arrive,
+// as many at a time as Go runs threads, and responds with their results
+// in the order they completed. A specification that cannot be parsed or
+// validated has an error instead of a result; it does not end the stream.
+func (g *grpcService) ValidateSpecs(stream apaipb.Validator_ValidateSpecsServer) error {
+	validator, err := g.readyValidator()
+	if err != nil {
+		return err
+	}
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results = make([]*apaipb.FileResult, 0)
+	)
+	slots := make(chan struct{}, runtime.GOMAXPROCS(0))
+	for {
+		request, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			wg.Wait()
+			return err
+		}
+
+		slots <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			fileResult := &apaipb.FileResult{Path: request.GetPath()}
+			result, err := g.validate(stream.Context(), validator, request.GetSpec(), request.GetErrorsOnly())
+			if err != nil {
+				fileResult.Error = status.Convert(err).Message()
+			} else {
+				fileResult.Result = result
+			}
+			mu.Lock()
+			results = append(results, fileResult)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return stream.SendAndClose(&apaipb.ValidateSpecsResponse{Results: results})
+}
+
+// MergeSpecs merges specifications, later ones overriding earlier ones, as
+// POST /merge does
+func (g *grpcService) MergeSpecs(ctx context.Context, request *apaipb.MergeSpecsRequest) (*apaipb.Document, error) {
+	validator, err := g.readyValidator()
+	if err != nil {
+		return nil, err
+	}
+	specs := make([]map[string]interface{}, 0, len(request.GetSpecs()))
+	for index, document := range request.GetSpecs() {
+		spec, err := decodeDocument(document)
+		if err != nil || !looksLikeSpec(spec) {
+			return nil, status.Errorf(codes.InvalidArgument, "specification %d is not an APAI specification", index)
+		}
+		specs = append(specs, spec)
+	}
+
+	merged, err := validator.Merge(specs)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return encodeDocument(merged, request.GetFormat())
+}
+
+// ResolveSpec loads a specification under the root and merges its
+// inherited specifications into it
+func (g *grpcService) ResolveSpec(ctx context.Context, request *apaipb.ResolveSpecRequest) (*apaipb.ResolveSpecResponse, error) {
+	run, spec, err := g.resolve(ctx, request.GetPath())
+	if err != nil {
+		return nil, err
+	}
+	document, err := encodeDocument(spec, request.GetFormat())
+	if err != nil {
+		return nil, err
+	}
+	return &apaipb.ResolveSpecResponse{Spec: document, Result: validationResult(run.GetResults())}, nil
+}
+
+// GetStats measures the effective specification of a file under the
+// root, as the stats command does
+func (g *grpcService) GetStats(ctx context.Context, request *apaipb.GetStatsRequest) (*apaipb.Stats, error) {
+	run, spec, err := g.resolve(ctx, request.GetPath())
+	if err != nil {
+		return nil, err
+	}
+	if len(run.Errors) > 0 {
+		return nil, status.Errorf(codes.FailedPrecondition, "cannot resolve the inherits of %s: %s", request.GetPath(), strings.Join(run.Errors, "; "))
+	}
+
+	complexity := run.measureComplexity(spec, run.inheritance.depth)
+	stats := &apaipb.Stats{
+		Sections:         make(map[string]int32, len(complexity.Sections)),
+		MaxStepsPerTask:  int32(complexity.MaxStepsPerTask),
+		LongestTask:      complexity.LongestTask,
+		InheritanceDepth: int32(complexity.InheritanceDepth),
+		PromptTokens:     int32(complexity.PromptTokens),
+		BranchingFactor:  int32(complexity.BranchingFactor),
+		WidestTask:       complexity.WidestTask,
+	}
+	for section, count := range complexity.Sections {
+		stats.Sections[section] = int32(count)
+	}
+	return stats, nil
+}
+
+// readyValidator returns the validator of the server, or an Unavailable
+// error until the server is ready
+func (g *grpcService) readyValidator() (*APAIValidator, error) {
+	validator := g.server.validator.Load()
+	if validator == nil {
+		return nil, status.Error(codes.Unavailable, "the server is not ready")
+	}
+	return validator, nil
+}
+
+// validate validates a document with validator, recording the validation
+// in the metrics of the server
+func (g *grpcService) validate(ctx context.Context, validator *APAIValidator, document *apaipb.Document, errorsOnly bool) (*apaipb.ValidationResult, error) {
+	spec, err := decodeDocument(document)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	start := time.Now()
+	result, err := validator.Validate(ctx, spec)
+	g.server.metrics.observeValidation(result, err, len(document.GetContent()), time.Since(start))
+	if err != nil {
+		return nil, status.FromContextError(err).Err()
+	}
+	if errorsOnly {
+		result = result.ErrorsOnly()
+	}
+	return validationResult(result), nil
+}
+
+// resolve loads the specification at path under the root and merges its
+// parents with a run of the server's validator, which holds the problems
+// found with them
+func (g *grpcService) resolve(ctx context.Context, path string) (*APAIValidator, map[string]interface{}, error) {
+	validator, err := g.readyValidator()
+	if err != nil {
+		return nil, nil, err
+	}
+	if g.root == nil {
+		return nil, nil, status.Error(codes.FailedPrecondition, "the server has no root to read specifications from")
+	}
+	if !fs.ValidPath(path) {
+		return nil, nil, status.Errorf(codes.InvalidArgument, "invalid path: %s (expected a slash-separated path under the root)", path)
+	}
+	if _, err := fs.Stat(g.root, path); err != nil {
+		return nil, nil, status.Errorf(codes.NotFound, "file not found: %s", path)
+	}
+
+	run := validator.newRun()
+	run.SetFS(g.root)
+	spec, err := run.ResolveSpecContext(ctx, path)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, nil, status.FromContextError(err).Err()
+	}
+	if err != nil {
+		return nil, nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return run, spec, nil
+}
+
+// decodeDocument decodes a document into a specification
+func decodeDocument(document *apaipb.Document) (map[string]interface{}, error) {
+	var spec map[string]interface{}
+	var err error
+	if document.GetFormat() == apaipb.Format_FORMAT_JSON {
+		err = json.Unmarshal(document.GetContent(), &spec)
+	} else {
+		err = decodeYAML(document.GetContent(), &spec)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse the specification: %v", err)
+	}
+	if spec == nil {
+		return nil, errors.New("the document is not an APAI specification")
+	}
+	return spec, nil
+}
+
+// encodeDocument encodes a specification in canonical order, as YAML
+// unless JSON is asked for
+func encodeDocument(spec map[string]interface{}, format apaipb.Format) (*apaipb.Document, error) {
+	if format != apaipb.Format_FORMAT_JSON {
+		format = apaipb.Format_FORMAT_YAML
+	}
+	content, err := MarshalCanonicalYAML(spec)
+	if format == apaipb.Format_FORMAT_JSON {
+		content, err = MarshalCanonicalJSON(spec)
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &apaipb.Document{Content: content, Format: format}, nil
+}
+
+// validationResult converts a result of the validator, adding its
+// findings as issues with their codes
+func validationResult(result ValidationResult) *apaipb.ValidationResult {
+	converted := &apaipb.ValidationResult{
+		Valid:    result.Valid,
+		Errors:   result.Errors,
+		Warnings: result.Warnings,
+		Issues:   make([]*apaipb.Issue, 0, len(result.Errors)+len(result.Warnings)),
+	}
+	add := func(severity string, messages []string) {
+		for _, message := range messages {
+			issue := result.issue(severity, message)
+			converted.Issues = append(converted.Issues, &apaipb.Issue{Severity: issue.Severity, Code: issue.Code, Message: issue.Message})
+		}
+	}
+	add("error", result.Errors)
+	add("warning", result.Warnings)
+	return converted
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"gopkg.in/yaml.v3"
+
+	"github.com/FabioGuin/APAI/validators/go/internal/apaipb"
+)
+
+// dialGRPC serves the gRPC service of server, reading files from root,
+// over an in-memory connection and returns a client of it
+func dialGRPC(t *testing.T, server *Server, root string) apaipb.ValidatorClient {
+	t.Helper()
+	listener := bufconn.Listen(1 << 20)
+	grpcServer := grpc.NewServer()
+	server.RegisterGRPC(grpcServer, os.DirFS(root))
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return apaipb.NewValidatorClient(conn)
+}
+
+func TestGRPCValidate(t *testing.T) {
+	content, err := os.ReadFile("../../examples/core/customer-support.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer(NewAPAIValidator())
+	client := dialGRPC(t, server, t.TempDir())
+	ctx := context.Background()
+
+	result, err := client.ValidateSpec(ctx, &apaipb.ValidateSpecRequest{Spec: &apaipb.Document{Content: content}})
+	if err != nil || !result.Valid {
+		t.Fatalf("expected a valid result, got %v (%v)", result, err)
+	}
+
+	result, err = client.ValidateSpec(ctx, &apaipb.ValidateSpecRequest{
+		Spec: &apaipb.Document{Content: []byte(`{"apai": "0.1.0"}`), Format: apaipb.Format_FORMAT_JSON},
+	})
+	if err != nil || result.Valid {
+		t.Fatalf("expected an invalid result, got %v (%v)", result, err)
+	}
+	if len(result.Issues) != len(result.Errors)+len(result.Warnings) {
+		t.Errorf("expected an issue for each finding, got %v", result.Issues)
+	}
+	if issue := result.Issues[0]; issue.Severity != "error" || issue.Code != "MISSING_SECTION" || issue.Message != result.Errors[0] {
+		t.Errorf("expected a coded issue for %q, got %v", result.Errors[0], issue)
+	}
+
+	_, err = client.ValidateSpec(ctx, &apaipb.ValidateSpecRequest{Spec: &apaipb.Document{Content: []byte(`{"apai": `), Format: apaipb.Format_FORMAT_JSON}})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument for an unparseable spec, got %v", err)
+	}
+
+	// A stream is validated concurrently; unparseable specifications get
+	// an error of their own
+	stream, err := client.ValidateSpecs(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	requests := []*apaipb.ValidateSpecsRequest{
+		{Path: "first.yaml", Spec: &apaipb.Document{Content: content}},
+		{Path: "broken.yaml", Spec: &apaipb.Document{Content: []byte("apai: [")}},
+		{Path: "empty.json", Spec: &apaipb.Document{Content: []byte(`{"apai": "0.1.0"}`), Format: apaipb.Format_FORMAT_JSON}, ErrorsOnly: true},
+		{Path: "second.yaml", Spec: &apaipb.Document{Content: content}},
+	}
+	for _, request := range requests {
+		if err := stream.Send(request); err != nil {
+			t.Fatal(err)
+		}
+	}
+	response, err := stream.CloseAndRecv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := make(map[string]*apaipb.FileResult)
+	for _, fileResult := range response.Results {
+		results[fileResult.Path] = fileResult
+	}
+	if len(results) != len(requests) {
+		t.Fatalf("expected a result for each of %d specs, got %v", len(requests), response.Results)
+	}
+	if !results["first.yaml"].Result.GetValid() || !results["second.yaml"].Result.GetValid() {
+		t.Errorf("expected the example to be valid, got %v", response.Results)
+	}
+	if !strings.HasPrefix(results["broken.yaml"].Error, "cannot parse the specification") || results["broken.yaml"].Result != nil {
+		t.Errorf("expected a parse error, got %v", results["broken.yaml"])
+	}
+	if empty := results["empty.json"].Result; empty.GetValid() || len(empty.GetErrors()) == 0 || len(empty.GetWarnings()) != 0 {
+		t.Errorf("expected errors only, got %v", empty)
+	}
+
+	// Every validation counts in the metrics the HTTP endpoints serve
+	series := scrapeMetrics(t, server.Handler())
+	if count := series[`apai_validations_total{outcome="valid"}`] + series[`apai_validations_total{outcome="invalid"}`]; count != 5 {
+		t.Errorf("expected 5 validations in the metrics, got %v", count)
+	}
+}
+
+func TestGRPCMerge(t *testing.T) {
+	client := dialGRPC(t, NewServer(NewAPAIValidator()), t.TempDir())
+	ctx := context.Background()
+
+	merged, err := client.MergeSpecs(ctx, &apaipb.MergeSpecsRequest{
+		Specs: []*apaipb.Document{
+			{Content: []byte("apai: \"0.1.0\"\ninfo:\n  title: Base\n  version: 1.0.0\n")},
+			{Content: []byte(`{"info": {"title": "Override"}}`), Format: apaipb.Format_FORMAT_JSON},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var spec map[string]interface{}
+	if err := yaml.Unmarshal(merged.Content, &spec); err != nil || merged.Format != apaipb.Format_FORMAT_YAML {
+		t.Fatalf("expected a YAML document, got %s (%v)", merged.Content, err)
+	}
+	if info := spec["info"].(map[string]interface{}); info["title"] != "Override" || info["version"] != "1.0.0" {
+		t.Errorf("expected the override merged into the base, got %v", info)
+	}
+
+	_, err = client.MergeSpecs(ctx, &apaipb.MergeSpecsRequest{Specs: []*apaipb.Document{{Content: []byte("- apai")}}})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument for a non-spec, got %v", err)
+	}
+}
+
+func TestGRPCResolveAndStats(t *testing.T) {
+	root := t.TempDir()
+	leaves := writeHierarchy(t, root, 1, 1, 1)
+	leaf, err := filepath.Rel(root, leaves[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf = filepath.ToSlash(leaf)
+	client := dialGRPC(t, NewServer(NewAPAIValidator()), root)
+	ctx := context.Background()
+
+	validator := NewAPAIValidator()
+	want, err := validator.ResolveSpecContext(ctx, leaves[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolved, err := client.ResolveSpec(ctx, &apaipb.ResolveSpecRequest{Path: leaf, Format: apaipb.Format_FORMAT_JSON})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wantContent, _ := MarshalCanonicalJSON(want); string(resolved.Spec.Content) != string(wantContent) || !resolved.Result.Valid {
+		t.Errorf("expected the spec resolved as from the file system, got %s", resolved.Spec.Content)
+	}
+
+	stats, err := client.GetStats(ctx, &apaipb.GetStatsRequest{Path: leaf})
+	if err != nil {
+		t.Fatal(err)
+	}
+	complexity := validator.measureComplexity(want, validator.inheritance.depth)
+	if stats.InheritanceDepth != 2 || int(stats.InheritanceDepth) != complexity.InheritanceDepth || int(stats.PromptTokens) != complexity.PromptTokens {
+		t.Errorf("expected %+v, got %v", complexity, stats)
+	}
+	if len(stats.Sections) != len(complexity.Sections) {
+		t.Errorf("expected the sections of %v, got %v", complexity.Sections, stats.Sections)
+	}
+	for section, count := range stats.Sections {
+		if int(count) != complexity.Sections[section] {
+			t.Errorf("expected %d %s, got %d", complexity.Sections[section], section, count)
+		}
+	}
+
+	for path, want := range map[string]codes.Code{
+		"../outside.yaml": codes.InvalidArgument,
+		"/etc/passwd":     codes.InvalidArgument,
+		"missing.yaml":    codes.NotFound,
+	} {
+		if _, err := client.ResolveSpec(ctx, &apaipb.ResolveSpecRequest{Path: path}); status.Code(err) != want {
+			t.Errorf("expected %v for %s, got %v", want, path, err)
+		}
+	}
+}
+
+func TestGRPCNotReady(t *testing.T) {
+	server := NewServer(nil)
+	client := dialGRPC(t, server, t.TempDir())
+	request := &apaipb.ValidateSpecRequest{Spec: &apaipb.Document{Content: []byte("apai: \"0.1.0\"\n")}}
+
+	if _, err := client.ValidateSpec(context.Background(), request); status.Code(err) != codes.Unavailable {
+		t.Errorf("expected Unavailable before the validator is set, got %v", err)
+	}
+	server.SetValidator(NewAPAIValidator())
+	if _, err := client.ValidateSpec(context.Background(), request); err != nil {
+		t.Errorf("expected the server to be ready, got %v", err)
+	}
+}
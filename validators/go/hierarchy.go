@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// hierarchyLevels is the order of hierarchy_info levels, broadest first. A
+// specification inherits from specifications of the level directly above
+// its own.
+var hierarchyLevels = []string{"global", "regional", "department", "team", "sprint", "feature", "environment"}
+
+// HierarchyNode is a specification of an inheritance tree, with the
+// specifications it inherits from as children in inherits order
+type HierarchyNode struct {
+	Title string `json:"title,omitempty"`
+	Level string `json:"level,omitempty"`
+	Scope string `json:"scope,omitempty"`
+	Path  string `json:"path"`
+	// Error is why the specification could not be resolved or loaded; such
+	// nodes have no other fields or children
+	Error    string           `json:"error,omitempty"`
+	Children []*HierarchyNode `json:"children"`
+}
+
+// BuildHierarchyTree loads a specification and, recursively, those it
+// inherits from. Parents that cannot be loaded, or that inherit back from
+// a specification of their own branch, become nodes with an error.
+func (v *APAIValidator) BuildHierarchyTree(specPath string) (*HierarchyNode, error) {
+	spec, err := v.loadSpec(specPath)
+	if err != nil {
+		return nil, err
+	}
+	return v.buildHierarchyNode(spec, specPath, []string{absolutePath(specPath)}), nil
+}
+
+// buildHierarchyNode builds the node of a loaded specification; ancestors
+// holds the absolute paths of its branch
+func (v *APAIValidator) buildHierarchyNode(spec map[string]interface{}, specPath string, ancestors []string) *HierarchyNode {
+	node := &HierarchyNode{Path: specPath, Children: make([]*HierarchyNode, 0)}
+	if info, ok := spec["info"].(map[string]interface{}); ok {
+		node.Title, _ = info["title"].(string)
+	}
+	hierarchyInfo := v.getHierarchyInfo(spec)
+	node.Level, _ = hierarchyInfo["level"].(string)
+	node.Scope, _ = hierarchyInfo["scope"].(string)
+
+	inherits, _ := spec["inherits"].([]interface{})
+	for _, inheritPath := range inherits {
+		inheritPathStr, ok := inheritPath.(string)
+		if !ok {
+			continue
+		}
+		resolvedPath, err := v.resolveInheritancePath(inheritPathStr, specPath)
+		if err != nil {
+			node.Children = append(node.Children, &HierarchyNode{Path: inheritPathStr, Error: err.Error(), Children: make([]*HierarchyNode, 0)})
+			continue
+		}
+		if containsString(ancestors, absolutePath(resolvedPath)) {
+			node.Children = append(node.Children, &HierarchyNode{Path: resolvedPath, Error: "circular inheritance", Children: make([]*HierarchyNode, 0)})
+			continue
+		}
+		parent, err := v.loadSpec(resolvedPath)
+		if err != nil {
+			node.Children = append(node.Children, &HierarchyNode{Path: resolvedPath, Error: err.Error(), Children: make([]*HierarchyNode, 0)})
+			continue
+		}
+		branch := append(append(make([]string, 0, len(ancestors)+1), ancestors...), absolutePath(resolvedPath))
+		node.Children = append(node.Children, v.buildHierarchyNode(parent, resolvedPath, branch))
+	}
+	return node
+}
+
+// hierarchyLevelRank returns the position of a spec's declared level in
+// hierarchyLevels, or -1 when it declares none or one outside the ordering
+func (v *APAIValidator) hierarchyLevelRank(spec map[string]interface{}) (string, int) {
+	level, _ := v.getHierarchyInfo(spec)["level"].(string)
+	canonical, _ := canonicalEnumValue(hierarchyLevels, level)
+	for rank, known := range hierarchyLevels {
+		if canonical == known {
+			return level, rank
+		}
+	}
+	return level, -1
+}
+
+// validateHierarchyInfo checks info.ai_metadata.hierarchy_info: level is
+// one of hierarchyLevels (other levels, such as agent, only warn), scope a
+// non-empty string, and parent and extends strings
+func (v *APAIValidator) validateHierarchyInfo(f *sectionFindings, hierarchyInfo interface{}) {
+	const location = "info.ai_metadata.hierarchy_info"
+	infoMap, ok := hierarchyInfo.(map[string]interface{})
+	if !ok {
+		f.addError("INVALID_TYPE", location+" must be an object")
+		return
+	}
+
+	if level, exists := infoMap["level"]; exists {
+		levelStr, ok := level.(string)
+		if !ok {
+			f.addError("INVALID_TYPE", location+".level must be a string")
+		} else if _, valid := matchEnum(f, hierarchyLevels, levelStr, location+".level"); !valid {
+			f.addWarning("UNKNOWN_VALUE", fmt.Sprintf("Unknown hierarchy level: %s (expected one of %s)", levelStr, strings.Join(hierarchyLevels, ", ")))
+		}
+	}
+
+	if scope, exists := infoMap["scope"]; exists {
+		if _, ok := scope.(string); !ok {
+			f.addError("INVALID_TYPE", location+".scope must be a string")
+		} else if isBlankString(scope) {
+			f.addError("EMPTY_FIELD", "Field is empty: "+location+".scope")
+		}
+	}
+
+	for _, field := range []string{"parent", "extends"} {
+		if value, exists := infoMap[field]; exists {
+			if _, ok := value.(string); !ok {
+				f.addError("INVALID_TYPE", fmt.Sprintf("%s.%s must be a string", location, field))
+			}
+		}
+	}
+}
+
+// validateBroadestLevel warns when a spec declaring the broadest level
+// inherits from other specs, which can only be at its level or narrower.
+// Hierarchical validation compares the levels of the parents instead.
+func (v *APAIValidator) validateBroadestLevel(spec map[string]interface{}) {
+	if v.validatingMerged {
+		return
+	}
+	level, rank := v.hierarchyLevelRank(spec)
+	if rank != 0 {
+		return
+	}
+	inherits, _ := spec["inherits"].([]interface{})
+	for _, parent := range inherits {
+		if parentPath, ok := parent.(string); ok {
+			v.addWarning("HIERARCHY_LEVEL_INVERSION", fmt.Sprintf("Hierarchy level inversion: the spec (%s) inherits from %s, but %s is the broadest level", level, parentPath, level))
+		}
+	}
+}
+
+// validateHierarchyLevels warns when the level a spec declares is not
+// directly below the level of a parent it inherits from
+func (v *APAIValidator) validateHierarchyLevels(spec map[string]interface{}, specPath string, parent map[string]interface{}, parentPath string) {
+	level, rank := v.hierarchyLevelRank(spec)
+	parentLevel, parentRank := v.hierarchyLevelRank(parent)
+	if rank < 0 || (parentRank < 0 && rank > 0) {
+		return
+	}
+
+	var code, warning string
+	switch {
+	case parentRank < 0:
+		code = "HIERARCHY_LEVEL_INVERSION"
+		warning = fmt.Sprintf("Hierarchy level inversion: %s (%s) inherits from %s, but %s is the broadest level", specPath, level, parentPath, level)
+	case parentRank >= rank:
+		code = "HIERARCHY_LEVEL_INVERSION"
+		warning = fmt.Sprintf("Hierarchy level inversion: %s (%s) inherits from %s (%s)", specPath, level, parentPath, parentLevel)
+	case parentRank < rank-1:
+		code = "HIERARCHY_LEVEL_SKIP"
+		warning = fmt.Sprintf("Hierarchy level skip: %s (%s) inherits from %s (%s), skipping %s",
+			specPath, level, parentPath, parentLevel, strings.Join(hierarchyLevels[parentRank+1:rank], ", "))
+	default:
+		return
+	}
+	if !v.hasFinding(warning) {
+		v.addWarning(code, warning)
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+)
+
+// defaultImportServer is the MCP server imported tools are added to when
+// none is chosen
+const defaultImportServer = "openai-tools"
+
+// openAIToolNamePattern matches the function names the OpenAI tools API accepts
+var openAIToolNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// OpenAITool is a function definition of the OpenAI tools API
+type OpenAITool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Parameters  map[string]interface{} `json:"parameters"`
+}
+
+// ParseOpenAITools reads function definitions in the formats of the OpenAI
+// tools API: an array of {"type": "function", "function": {...}} entries or
+// of bare functions, or an object holding such an array under "tools" or
+// the legacy "functions"
+func ParseOpenAITools(content []byte) ([]OpenAITool, error) {
+	var document interface{}
+	if err := json.Unmarshal(content, &document); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %v", err)
+	}
+	if object, ok := document.(map[string]interface{}); ok {
+		if tools, exists := object["tools"]; exists {
+			document = tools
+		} else {
+			document = object["functions"]
+		}
+	}
+	entries, ok := document.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected an array of tools, or an object with a tools or functions array")
+	}
+
+	tools := make([]OpenAITool, 0, len(entries))
+	for index, entry := range entries {
+		entryMap, ok := entry.(map[string]interface{})
+		if function, isWrapped := entryMap["function"].(map[string]interface{}); isWrapped {
+			entryMap = function
+		}
+		// Round-trip through JSON to decode the definition into the struct
+		encoded, _ := json.Marshal(entryMap)
+		var tool OpenAITool
+		if err := json.Unmarshal(encoded, &tool); err != nil || !ok {
+			return nil, fmt.Errorf("tool %d is not a function definition", index)
+		}
+		if !openAIToolNamePattern.MatchString(tool.Name) {
+			return nil, fmt.Errorf("tool %d has an invalid name: %q", index, tool.Name)
+		}
+		tools = append(tools, tool)
+	}
+	return tools, nil
+}
+
+// ImportOpenAITools returns a copy of spec in which every tool is declared
+// by the MCP server serverID, scaffolded when the spec has none, and called
+// by a new task of the same id whose inputs are the tool's parameters. It
+// also returns a description of each change and of each tool skipped
+// because its task or MCP tool already exists; with overwrite those tasks
+// are replaced instead.
+func ImportOpenAITools(spec map[string]interface{}, tools []OpenAITool, serverID string, overwrite bool) (map[string]interface{}, []string, []string) {
+	imported, _ := copyValue(spec).(map[string]interface{})
+	changes, conflicts := make([]string, 0), make([]string, 0)
+	if serverID == "" {
+		serverID = defaultImportServer
+	}
+
+	contextMap, ok := imported["context"].(map[string]interface{})
+	if !ok {
+		contextMap = make(map[string]interface{})
+		imported["context"] = contextMap
+	}
+	servers, _ := contextMap["mcp_servers"].([]interface{})
+	var server map[string]interface{}
+	for index, candidate := range servers {
+		if candidateMap, ok := candidate.(map[string]interface{}); ok && elementName(index, candidateMap) == serverID {
+			server = candidateMap
+		}
+	}
+	if server == nil {
+		server = scaffoldMCPServer(serverID)
+		contextMap["mcp_servers"] = append(servers, server)
+		changes = append(changes, fmt.Sprintf("added MCP server %s; configure its transport", serverID))
+	}
+	capabilities, ok := server["capabilities"].(map[string]interface{})
+	if !ok {
+		capabilities = make(map[string]interface{})
+		server["capabilities"] = capabilities
+	}
+	declared, _ := capabilities["tools"].([]interface{})
+
+	tasks, _ := imported["tasks"].([]interface{})
+	for _, tool := range tools {
+		taskIndex := -1
+		for index, task := range tasks {
+			if taskMap, ok := task.(map[string]interface{}); ok && elementName(index, taskMap) == tool.Name {
+				taskIndex = index
+			}
+		}
+		toolDeclared := false
+		for _, name := range declared {
+			toolDeclared = toolDeclared || name == tool.Name
+		}
+
+		if !overwrite && (taskIndex >= 0 || toolDeclared) {
+			what := "task"
+			if toolDeclared {
+				what = fmt.Sprintf("tool of MCP server %s", serverID)
+			}
+			conflicts = append(conflicts, fmt.Sprintf("skipped %s: a %s with that name already exists (use --overwrite to replace it)", tool.Name, what))
+			continue
+		}
+
+		if !toolDeclared {
+			declared = append(declared, tool.Name)
+		}
+		task := importedTask(tool, serverID)
+		if taskIndex >= 0 {
+			tasks[taskIndex] = task
+			changes = append(changes, fmt.Sprintf("replaced task %s", tool.Name))
+		} else {
+			tasks = append(tasks, task)
+			changes = append(changes, fmt.Sprintf("added task %s calling tool %s of MCP server %s", tool.Name, tool.Name, serverID))
+		}
+	}
+	capabilities["tools"] = declared
+	imported["tasks"] = tasks
+	return imported, changes, conflicts
+}
+
+// importedTask is a task calling a tool, with an input per parameter bound
+// to the argument of the same name
+func importedTask(tool OpenAITool, serverID string) map[string]interface{} {
+	description := tool.Description
+	if description == "" {
+		description = fmt.Sprintf("Calls the %s tool", tool.Name)
+	}
+
+	properties, _ := tool.Parameters["properties"].(map[string]interface{})
+	required, _ := tool.Parameters["required"].([]interface{})
+	inputs := make(map[string]interface{}, len(properties))
+	arguments := make(map[string]interface{}, len(properties))
+	for _, parameter := range sortedKeys(properties) {
+		property, _ := properties[parameter].(map[string]interface{})
+		input := map[string]interface{}{"type": schemaType(property), "required": false}
+		for _, name := range required {
+			if name == parameter {
+				input["required"] = true
+			}
+		}
+		if parameterDescription, ok := property["description"].(string); ok && parameterDescription != "" {
+			input["description"] = parameterDescription
+		}
+		inputs[parameter] = input
+		arguments[parameter] = fmt.Sprintf("${input.%s}", parameter)
+	}
+
+	step := map[string]interface{}{
+		"name":       "call-" + tool.Name,
+		"action":     "mcp_tool",
+		"mcp_server": serverID,
+		"mcp_tool":   tool.Name,
+	}
+	task := map[string]interface{}{
+		"id":          tool.Name,
+		"name":        tool.Name,
+		"description": description,
+		"steps":       []interface{}{step},
+	}
+	if len(inputs) > 0 {
+		task["input"] = inputs
+		step["mcp_parameters"] = arguments
+	}
+	return task
+}
+
+// schemaType returns the type of a JSON schema property, the first one
+// other than null for a list of types, or "string" when it has none
+func schemaType(property map[string]interface{}) string {
+	switch typed := property["type"].(type) {
+	case string:
+		return typed
+	case []interface{}:
+		for _, candidate := range typed {
+			if name, ok := candidate.(string); ok && name != "null" {
+				return name
+			}
+		}
+	}
+	return "string"
+}
+
+// scaffoldMCPServer is an MCP server with the fields validation requires,
+// to be completed by hand
+func scaffoldMCPServer(serverID string) map[string]interface{} {
+	return map[string]interface{}{
+		"id":          serverID,
+		"name":        serverID,
+		"description": "Tools imported from OpenAI function definitions",
+		"version":     "1.0.0",
+		"transport": map[string]interface{}{
+			"type":    "stdio",
+			"command": serverID,
+		},
+		"capabilities": map[string]interface{}{
+			"tools":     []interface{}{},
+			"resources": []interface{}{},
+			"prompts":   []interface{}{},
+		},
+		"authentication": map[string]interface{}{"type": "none"},
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseOpenAITools(t *testing.T) {
+	formats := map[string]string{
+		"tools":     `{"tools": [{"type": "function", "function": {"name": "get_weather", "parameters": {"type": "object"}}}]}`,
+		"array":     `[{"type": "function", "function": {"name": "get_weather", "parameters": {"type": "object"}}}]`,
+		"functions": `{"functions": [{"name": "get_weather", "parameters": {"type": "object"}}]}`,
+	}
+	for format, content := range formats {
+		tools, err := ParseOpenAITools([]byte(content))
+		if err != nil || len(tools) != 1 || tools[0].Name != "get_weather" {
+			t.Errorf("%s: got %+v, %v", format, tools, err)
+		}
+	}
+
+	for _, content := range []string{`{"model": "gpt-4"}`, `["get_weather"]`, `[{"name": "get weather"}]`} {
+		if _, err := ParseOpenAITools([]byte(content)); err == nil {
+			t.Errorf("expected an error for %s", content)
+		}
+	}
+}
+
+func TestImportOpenAITools(t *testing.T) {
+	spec := loadExampleSpecs(t, "automation/mcp-integration.yaml")[0]
+	tools, err := ParseOpenAITools([]byte(`[
+		{"type": "function", "function": {"name": "get_weather", "description": "Get the weather for a city",
+			"parameters": {"type": "object", "properties": {"city": {"type": "string", "description": "City name"}, "days": {"type": ["integer", "null"]}}, "required": ["city"]}}},
+		{"type": "function", "function": {"name": "get_customer", "parameters": {"type": "object"}}}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	imported, changes, conflicts := ImportOpenAITools(spec, tools, "", false)
+	wantChanges := []string{
+		"added MCP server openai-tools; configure its transport",
+		"added task get_weather calling tool get_weather of MCP server openai-tools",
+		"added task get_customer calling tool get_customer of MCP server openai-tools",
+	}
+	if !reflect.DeepEqual(changes, wantChanges) || len(conflicts) != 0 {
+		t.Errorf("expected changes %v and no conflicts, got %v and %v", wantChanges, changes, conflicts)
+	}
+
+	tasks := imported["tasks"].([]interface{})
+	weather := tasks[len(tasks)-2].(map[string]interface{})
+	wantInput := map[string]interface{}{
+		"city": map[string]interface{}{"type": "string", "required": true, "description": "City name"},
+		"days": map[string]interface{}{"type": "integer", "required": false},
+	}
+	if !reflect.DeepEqual(weather["input"], wantInput) {
+		t.Errorf("expected input %v, got %v", wantInput, weather["input"])
+	}
+	step := weather["steps"].([]interface{})[0].(map[string]interface{})
+	wantArguments := map[string]interface{}{"city": "${input.city}", "days": "${input.days}"}
+	if step["action"] != "mcp_tool" || step["mcp_server"] != "openai-tools" || !reflect.DeepEqual(step["mcp_parameters"], wantArguments) {
+		t.Errorf("unexpected step %v", step)
+	}
+
+	// The result validates, and exports back to the same tool
+	validator := NewAPAIValidator()
+	if !validator.ValidateSpec(imported) {
+		t.Errorf("imported spec does not validate: %v", validator.Errors)
+	}
+	plan, err := BuildExportPlan(imported, "get_weather")
+	if err != nil || len(plan.Tools) != 1 || plan.Tools[0].Parameters["required"].([]interface{})[0] != "city" {
+		t.Errorf("unexpected export plan %+v, %v", plan, err)
+	}
+	if len(spec["tasks"].([]interface{})) != 1 {
+		t.Error("the original specification was modified")
+	}
+
+	// Existing tools and tasks are skipped unless overwritten
+	_, changes, conflicts = ImportOpenAITools(spec, tools, "customer-db-server", false)
+	wantConflicts := []string{"skipped get_customer: a tool of MCP server customer-db-server with that name already exists (use --overwrite to replace it)"}
+	if !reflect.DeepEqual(conflicts, wantConflicts) || len(changes) != 1 {
+		t.Errorf("expected conflicts %v and one change, got %v and %v", wantConflicts, conflicts, changes)
+	}
+	_, changes, conflicts = ImportOpenAITools(imported, tools, "", true)
+	if want := []string{"replaced task get_weather", "replaced task get_customer"}; !reflect.DeepEqual(changes, want) || len(conflicts) != 0 {
+		t.Errorf("expected changes %v, got %v and conflicts %v", want, changes, conflicts)
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// includeKey is the directive that inlines a fragment file, as in
+// "models: {$include: shared-models.yaml}"
+const includeKey = "$include"
+
+// includeTag is the YAML tag form of the directive, as in
+// "models: !include shared-models.yaml"
+const includeTag = "!include"
+
+// decodeYAML unmarshals YAML content, turning !include tags into $include
+// directives so both forms are resolved the same way
+func decodeYAML(content []byte, out interface{}) error {
+	var document yaml.Node
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return err
+	}
+	if document.Kind == 0 {
+		return nil
+	}
+	rewriteIncludeTags(&document)
+	return document.Decode(out)
+}
+
+// decodeYAMLDocuments unmarshals each document of YAML content separated
+// by ---, skipping empty ones, as decodeYAML does the first
+func decodeYAMLDocuments(content []byte) ([]map[string]interface{}, error) {
+	documents := make([]map[string]interface{}, 0, 1)
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	for {
+		var document yaml.Node
+		err := decoder.Decode(&document)
+		if err == io.EOF {
+			return documents, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		rewriteIncludeTags(&document)
+		var spec map[string]interface{}
+		if err := document.Decode(&spec); err != nil {
+			return nil, err
+		}
+		if spec != nil {
+			documents = append(documents, spec)
+		}
+	}
+}
+
+// rewriteIncludeTags replaces every !include scalar with an $include mapping
+func rewriteIncludeTags(node *yaml.Node) {
+	if node.Tag == includeTag && node.Kind == yaml.ScalarNode {
+		target := node.Value
+		*node = yaml.Node{
+			Kind: yaml.MappingNode,
+			Tag:  "!!map",
+			Content: []*yaml.Node{
+				{Kind: yaml.ScalarNode, Tag: "!!str", Value: includeKey},
+				{Kind: yaml.ScalarNode, Tag: "!!str", Value: target},
+			},
+		}
+		return
+	}
+	for _, child := range node.Content {
+		rewriteIncludeTags(child)
+	}
+}
+
+// resolveIncludes inlines the fragments referenced by $include directives
+// in a specification loaded from filePath
+func (v *APAIValidator) resolveIncludes(spec map[string]interface{}, filePath string) (map[string]interface{}, error) {
+	resolved, err := v.resolveIncludeValue(spec, filePath, []string{v.joinPath(filePath)})
+	if err != nil {
+		return nil, err
+	}
+	resolvedMap, ok := resolved.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must include an object at the top level", filePath)
+	}
+	return resolvedMap, nil
+}
+
+// resolveIncludeValue resolves the directives of a decoded value. chain
+// holds the files being included, outermost first, to detect cycles.
+func (v *APAIValidator) resolveIncludeValue(value interface{}, filePath string, chain []string) (interface{}, error) {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for key, item := range typed {
+			if key == includeKey {
+				continue
+			}
+			resolved, err := v.resolveIncludeValue(item, filePath, chain)
+			if err != nil {
+				return nil, err
+			}
+			typed[key] = resolved
+		}
+
+		target, exists := typed[includeKey]
+		if !exists {
+			return typed, nil
+		}
+		fragment, err := v.includeFragment(target, filePath, chain)
+		if err != nil {
+			return nil, err
+		}
+		if len(typed) == 1 {
+			return fragment, nil
+		}
+
+		// Keys next to the directive override those of the fragment
+		fragmentMap, ok := fragment.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s: %s with sibling keys must include an object", filePath, includeKey)
+		}
+		for key, item := range typed {
+			if key != includeKey {
+				fragmentMap[key] = item
+			}
+		}
+		return fragmentMap, nil
+	case []interface{}:
+		for i, item := range typed {
+			resolved, err := v.resolveIncludeValue(item, filePath, chain)
+			if err != nil {
+				return nil, err
+			}
+			typed[i] = resolved
+		}
+		return typed, nil
+	default:
+		return typed, nil
+	}
+}
+
+// includeFragment loads the fragment named by an $include directive,
+// relative to the including file, and resolves its own directives
+func (v *APAIValidator) includeFragment(target interface{}, filePath string, chain []string) (interface{}, error) {
+	targetPath, ok := target.(string)
+	if !ok || targetPath == "" {
+		return nil, fmt.Errorf("%s: %s must be a file path", filePath, includeKey)
+	}
+
+	fragmentPath := targetPath
+	if v.fsys != nil || !filepath.IsAbs(targetPath) {
+		fragmentPath = v.joinPath(v.dirPath(filePath), targetPath)
+	}
+
+	for i, included := range chain {
+		if included == fragmentPath {
+			cycle := append(append([]string{}, chain[i:]...), fragmentPath)
+			return nil, fmt.Errorf("include cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+
+	content, err := v.readFile(fragmentPath)
+	if err != nil {
+		return nil, fmt.Errorf("%s: included file not found: %s", filePath, targetPath)
+	}
+
+	var fragment interface{}
+	ext := strings.ToLower(filepath.Ext(fragmentPath))
+	switch ext {
+	case ".yaml", ".yml":
+		err = decodeYAML(content, &fragment)
+	case ".json":
+		err = json.Unmarshal(content, &fragment)
+	default:
+		return nil, fmt.Errorf("%s: unsupported include format: %s", filePath, ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid fragment %s: %v", filePath, targetPath, err)
+	}
+
+	return v.resolveIncludeValue(fragment, fragmentPath, append(chain[:len(chain):len(chain)], fragmentPath))
+}
@@ -0,0 +1,5 @@
+// Package apaipb holds the Go code generated from validator.proto, the
+// gRPC interface of the validation service run by apai serve --grpc.
+package apaipb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative validator.proto
@@ -0,0 +1,235 @@
+// Package cron parses standard five-field cron expressions, such as
+// "0 3 * * 1", and the @daily-style aliases, and computes when they fire.
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// field describes one of the five fields of an expression
+type field struct {
+	name     string
+	min, max int
+	names    []string // names of the values from min, e.g. JAN for months
+}
+
+var fields = []field{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: []string{"JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}},
+	{name: "day of week", min: 0, max: 7, names: []string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"}},
+}
+
+// aliases are the expressions the @ shorthands stand for
+var aliases = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// maxSearch bounds how far ahead Next looks for a firing time; schedules
+// such as "0 0 30 2 *" never fire
+const maxSearch = 5 * 366 * 24 * time.Hour
+
+// Schedule is a parsed cron expression
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+	// Whether the day of month and day of week fields are *, which
+	// decides how the two combine
+	domAny, dowAny bool
+}
+
+// FieldError is an error in one field of an expression
+type FieldError struct {
+	// Field is minute, hour, day of month, month or day of week
+	Field string
+	// Value is the text of the field
+	Value  string
+	Reason string
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s field %q: %s", e.Field, e.Value, e.Reason)
+}
+
+// Parse parses a five-field cron expression or an alias such as @daily.
+// Fields hold *, values, ranges (1-5), steps (*/15, 0-30/10) and lists of
+// them; months and days of the week may also be written as names (JAN,
+// MON). Errors in a field are *FieldError.
+func Parse(expression string) (*Schedule, error) {
+	expression = strings.TrimSpace(expression)
+	if strings.HasPrefix(expression, "@") {
+		expanded, ok := aliases[strings.ToLower(expression)]
+		if !ok {
+			return nil, fmt.Errorf("unknown alias %s (expected @yearly, @annually, @monthly, @weekly, @daily, @midnight or @hourly)", expression)
+		}
+		expression = expanded
+	}
+
+	parts := strings.Fields(expression)
+	if len(parts) != len(fields) {
+		return nil, fmt.Errorf("expected 5 fields (minute hour day-of-month month day-of-week), got %d", len(parts))
+	}
+	sets := make([]uint64, len(fields))
+	for i, part := range parts {
+		set, err := parseField(part, fields[i])
+		if err != nil {
+			return nil, err
+		}
+		sets[i] = set
+	}
+
+	// Sunday is 0 or 7
+	dow := sets[4]
+	if dow&(1<<7) != 0 {
+		dow = dow&^(1<<7) | 1
+	}
+	return &Schedule{
+		minute: sets[0], hour: sets[1], dom: sets[2], month: sets[3], dow: dow,
+		domAny: parts[2] == "*" || parts[2] == "?",
+		dowAny: parts[4] == "*" || parts[4] == "?",
+	}, nil
+}
+
+// parseField parses a field into the set of values it matches
+func parseField(text string, f field) (uint64, error) {
+	fail := func(format string, args ...interface{}) (uint64, error) {
+		return 0, &FieldError{Field: f.name, Value: text, Reason: fmt.Sprintf(format, args...)}
+	}
+
+	var set uint64
+	for _, item := range strings.Split(text, ",") {
+		if item == "" {
+			return fail("empty list item")
+		}
+		rangeText, step := item, 1
+		if slash := strings.Index(item, "/"); slash >= 0 {
+			rangeText = item[:slash]
+			n, err := strconv.Atoi(item[slash+1:])
+			if err != nil || n <= 0 {
+				return fail("step %q is not a positive number", item[slash+1:])
+			}
+			step = n
+		}
+
+		low, high := f.min, f.max
+		switch {
+		case rangeText == "*" || rangeText == "?":
+		case strings.Contains(rangeText, "-"):
+			bounds := strings.SplitN(rangeText, "-", 2)
+			var err error
+			if low, err = f.value(bounds[0]); err != nil {
+				return fail("%v", err)
+			}
+			if high, err = f.value(bounds[1]); err != nil {
+				return fail("%v", err)
+			}
+			if low > high {
+				return fail("range %s starts after it ends", rangeText)
+			}
+		default:
+			value, err := f.value(rangeText)
+			if err != nil {
+				return fail("%v", err)
+			}
+			// A single value with a step runs from the value to the maximum
+			low, high = value, value
+			if strings.Contains(item, "/") {
+				high = f.max
+			}
+		}
+
+		for value := low; value <= high; value += step {
+			set |= 1 << uint(value)
+		}
+	}
+	return set, nil
+}
+
+// value parses a number or name of the field, checking its range
+func (f field) value(text string) (int, error) {
+	for i, name := range f.names {
+		if strings.EqualFold(text, name) {
+			return f.min + i, nil
+		}
+	}
+	value, err := strconv.Atoi(text)
+	if err != nil {
+		if len(f.names) > 0 {
+			return 0, fmt.Errorf("%q is not a number or name", text)
+		}
+		return 0, fmt.Errorf("%q is not a number", text)
+	}
+	if value < f.min || value > f.max {
+		return 0, fmt.Errorf("%d is outside %d-%d", value, f.min, f.max)
+	}
+	return value, nil
+}
+
+// matchesDay reports whether the schedule fires on the day of t: when
+// both day fields are restricted, on days matching either of them
+func (s *Schedule) matchesDay(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domAny || s.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// Next returns the first time after t the schedule fires, at a whole
+// minute in the location of t, or the zero time when it does not fire
+// within five years
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(maxSearch)
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.matchesDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// MinInterval returns the shortest time between two consecutive firings
+// of the schedule, or zero when it fires at most once in five years.
+// Every hour the schedule fires in, it fires at each of its minutes, so
+// the gaps between its first 1440 firings include the shortest one.
+func (s *Schedule) MinInterval() time.Duration {
+	var shortest time.Duration
+	previous := s.Next(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC))
+	if previous.IsZero() {
+		return 0
+	}
+	for i := 0; i < 24*60; i++ {
+		next := s.Next(previous)
+		if next.IsZero() {
+			break
+		}
+		if gap := next.Sub(previous); shortest == 0 || gap < shortest {
+			shortest = gap
+		}
+		if shortest == time.Minute {
+			break
+		}
+		previous = next
+	}
+	return shortest
+}
@@ -0,0 +1,89 @@
+package cron
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	valid := []string{"0 3 * * 1", "*/15 * * * *", "0 9-17/2 * * MON-FRI", "30 4 1,15 * *", "0 0 * JAN,jul ?", "5/20 * * * 7", "@daily", "@Hourly"}
+	for _, expression := range valid {
+		if _, err := Parse(expression); err != nil {
+			t.Errorf("Parse(%q): %v", expression, err)
+		}
+	}
+
+	invalid := map[string]string{
+		"61 * * * *":     "minute",
+		"0 24 * * *":     "hour",
+		"0 0 0 * *":      "day of month",
+		"0 0 * 13 *":     "month",
+		"0 0 * * FUNDAY": "day of week",
+		"*/0 * * * *":    "minute",
+		"0 5-2 * * *":    "hour",
+		"0 0 1,,2 * *":   "day of month",
+	}
+	for expression, want := range invalid {
+		_, err := Parse(expression)
+		var fieldErr *FieldError
+		if !errors.As(err, &fieldErr) || fieldErr.Field != want {
+			t.Errorf("Parse(%q) = %v, want an error in the %s field", expression, err, want)
+		}
+	}
+
+	for _, expression := range []string{"0 3 * *", "0 3 * * 1 2026", "@reboot", ""} {
+		if _, err := Parse(expression); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", expression)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	// Monday 2026-10-12 10:20 UTC
+	from := time.Date(2026, 10, 12, 10, 20, 30, 0, time.UTC)
+	cases := map[string]time.Time{
+		"0 3 * * 1":     time.Date(2026, 10, 19, 3, 0, 0, 0, time.UTC),
+		"*/15 * * * *":  time.Date(2026, 10, 12, 10, 30, 0, 0, time.UTC),
+		"@monthly":      time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC),
+		"0 0 * * 7":     time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC),
+		"0 12 13 * FRI": time.Date(2026, 10, 12, 12, 0, 0, 0, time.UTC).AddDate(0, 0, 1),
+		"0 0 29 2 *":    time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC),
+	}
+	for expression, want := range cases {
+		schedule, err := Parse(expression)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := schedule.Next(from); !got.Equal(want) {
+			t.Errorf("%q: Next = %v, want %v", expression, got, want)
+		}
+	}
+
+	never, _ := Parse("0 0 30 2 *")
+	if got := never.Next(from); !got.IsZero() {
+		t.Errorf("expected February 30 never to fire, got %v", got)
+	}
+}
+
+func TestMinInterval(t *testing.T) {
+	cases := map[string]time.Duration{
+		"* * * * *":     time.Minute,
+		"0,30 9 * * *":  30 * time.Minute,
+		"@hourly":       time.Hour,
+		"0 */2 * * *":   2 * time.Hour,
+		"50 9,10 * * *": time.Hour,
+		"0 0,23 * * *":  time.Hour,
+		"@daily":        24 * time.Hour,
+		"0 0 30 2 *":    0,
+	}
+	for expression, want := range cases {
+		schedule, err := Parse(expression)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := schedule.MinInterval(); got != want {
+			t.Errorf("%q: MinInterval = %v, want %v", expression, got, want)
+		}
+	}
+}
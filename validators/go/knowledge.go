@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// knowledgeSourceFields maps each knowledge source type to the field
+// locating its data
+var knowledgeSourceFields = map[string]string{
+	"url":      "url",
+	"file":     "file",
+	"database": "connection",
+	"api":      "endpoint",
+}
+
+// maxRecommendedTopK is the number of retrieved chunks above which the
+// retrieved context usually crowds out the rest of the prompt
+const maxRecommendedTopK = 50
+
+// validateKnowledgeBase validates context.knowledge_base: its sources and
+// its retrieval settings
+func validateKnowledgeBase(f *sectionFindings, knowledgeBase interface{}) {
+	knowledgeBaseMap, ok := knowledgeBase.(map[string]interface{})
+	if !ok {
+		f.addError("INVALID_TYPE", "context.knowledge_base must be an object")
+		return
+	}
+
+	if sources, exists := knowledgeBaseMap["sources"]; exists {
+		validateKnowledgeSources(f, sources)
+	} else {
+		f.addError("MISSING_FIELD", "context.knowledge_base missing required field: sources")
+	}
+
+	if retrieval, exists := knowledgeBaseMap["retrieval"]; exists {
+		retrievalMap, ok := retrieval.(map[string]interface{})
+		if !ok {
+			f.addError("INVALID_TYPE", "context.knowledge_base.retrieval must be an object")
+			return
+		}
+		validateRetrievalSettings(f, retrievalMap)
+	}
+}
+
+// validateKnowledgeSources checks that each knowledge source has a unique
+// name, a known type and the field its type locates data with
+func validateKnowledgeSources(f *sectionFindings, sources interface{}) {
+	sourcesSlice, ok := sources.([]interface{})
+	if !ok {
+		f.addError("INVALID_TYPE", "context.knowledge_base.sources must be an array")
+		return
+	}
+
+	names := make(map[string]bool)
+	for index, source := range sourcesSlice {
+		sourceMap, ok := source.(map[string]interface{})
+		if !ok {
+			f.addError("INVALID_TYPE", fmt.Sprintf("Knowledge source %d must be an object", index))
+			continue
+		}
+		name, _ := sourceMap["name"].(string)
+		if name == "" {
+			f.addError("MISSING_FIELD", fmt.Sprintf("Knowledge source %d missing required field: name", index))
+			name = fmt.Sprintf("%d", index)
+		} else if names[name] {
+			f.addError("DUPLICATE_ID", fmt.Sprintf("Duplicate knowledge source name: %s", name))
+		}
+		names[name] = true
+
+		value, exists := sourceMap["type"]
+		if !exists {
+			f.addError("MISSING_FIELD", fmt.Sprintf("Knowledge source %s missing required field: type", name))
+			continue
+		}
+		sourceType, ok := value.(string)
+		if !ok {
+			f.addError("INVALID_TYPE", fmt.Sprintf("Knowledge source %s type must be a string", name))
+			continue
+		}
+		canonical, valid := matchEnum(f, knowledgeSourceTypes, sourceType, fmt.Sprintf("context.knowledge_base.sources[%d].type", index))
+		if !valid {
+			f.addError("INVALID_ENUM", fmt.Sprintf("Knowledge source %s invalid source type: %s (expected %s)", name, sourceType, strings.Join(knowledgeSourceTypes, ", ")))
+			continue
+		}
+		field := knowledgeSourceFields[canonical]
+		if _, exists := sourceMap[field]; !exists {
+			f.addError("MISSING_FIELD", fmt.Sprintf("Knowledge source %s missing required field: %s", name, field))
+		} else if isBlankString(sourceMap[field]) {
+			f.addError("EMPTY_FIELD", fmt.Sprintf("Knowledge source %s required field is empty: %s", name, field))
+		}
+	}
+}
+
+// validateRetrievalSettings checks the retrieval settings of a knowledge
+// base: top_k and the chunk sizes are positive integers, the overlap is
+// smaller than the chunks and the similarity threshold is a ratio
+func validateRetrievalSettings(f *sectionFindings, retrieval map[string]interface{}) {
+	for _, field := range []string{"top_k", "chunk_size", "chunk_overlap"} {
+		value, exists := retrieval[field]
+		if !exists {
+			continue
+		}
+		if number, ok := numberValue(value); !ok || number <= 0 || number != math.Trunc(number) {
+			f.addError("INVALID_RETRIEVAL_SETTING", fmt.Sprintf("Invalid retrieval setting: %s must be a positive integer, got %v", field, value))
+		}
+	}
+	if topK, ok := numberValue(retrieval["top_k"]); ok && topK > maxRecommendedTopK {
+		f.addWarning("LARGE_RETRIEVAL_TOP_K", fmt.Sprintf("Retrieval top_k of %v is above %d", topK, maxRecommendedTopK))
+	}
+	if value, exists := retrieval["similarity_threshold"]; exists {
+		if threshold, ok := numberValue(value); !ok || threshold < 0 || threshold > 1 {
+			f.addError("INVALID_RETRIEVAL_SETTING", fmt.Sprintf("Invalid retrieval setting: similarity_threshold must be between 0 and 1, got %v", value))
+		}
+	}
+	size, sized := numberValue(retrieval["chunk_size"])
+	overlap, overlapped := numberValue(retrieval["chunk_overlap"])
+	if sized && overlapped && size > 0 && overlap >= size {
+		f.addError("INVALID_RETRIEVAL_SETTING", fmt.Sprintf("Invalid retrieval setting: chunk_overlap %v must be less than chunk_size %v", overlap, size))
+	}
+}
+
+// validateKnowledgeReferences checks that a specification declaring a
+// knowledge base has an embedding model to index it, and that search steps
+// name one of its sources
+func (v *APAIValidator) validateKnowledgeReferences(spec map[string]interface{}) {
+	contextMap, _ := spec["context"].(map[string]interface{})
+	knowledgeBase, ok := contextMap["knowledge_base"].(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	embedding := false
+	models, _ := spec["models"].([]interface{})
+	for _, model := range models {
+		modelMap, _ := model.(map[string]interface{})
+		modelType, _ := modelMap["type"].(string)
+		if canonical, _ := canonicalEnumValue(modelTypes, modelType); canonical == "Embedding" {
+			embedding = true
+		}
+	}
+	if !embedding {
+		v.addError("MISSING_EMBEDDING_MODEL", "Knowledge base requires a model of type Embedding, but none is defined")
+	}
+
+	sources := make(map[string]bool)
+	sourcesSlice, _ := knowledgeBase["sources"].([]interface{})
+	for _, source := range sourcesSlice {
+		sourceMap, _ := source.(map[string]interface{})
+		if name, ok := sourceMap["name"].(string); ok {
+			sources[name] = true
+		}
+	}
+	tasks, _ := spec["tasks"].([]interface{})
+	for _, task := range tasks {
+		taskMap, _ := task.(map[string]interface{})
+		steps, _ := taskMap["steps"].([]interface{})
+		for _, step := range steps {
+			stepMap, _ := step.(map[string]interface{})
+			action, _ := stepMap["action"].(string)
+			source, ok := stepMap["source"].(string)
+			if !ok || !strings.EqualFold(action, "search") || sources[source] {
+				continue
+			}
+			v.addError("UNKNOWN_REFERENCE", fmt.Sprintf("Task references unknown knowledge source: %s", source))
+		}
+	}
+}
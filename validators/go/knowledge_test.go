@@ -0,0 +1,67 @@
+package main
+
+import "testing"
+
+func TestKnowledgeBase(t *testing.T) {
+	spec := loadExampleSpecs(t, "templates/basic-template.yaml")[0]
+	spec["context"].(map[string]interface{})["knowledge_base"] = map[string]interface{}{
+		"sources": []interface{}{
+			map[string]interface{}{"name": "faq", "type": "url", "url": "https://docs.example.com/faq"},
+			map[string]interface{}{"name": "orders", "type": "Database"},
+			map[string]interface{}{"name": "faq", "type": "api", "endpoint": "https://api.example.com/search"},
+			map[string]interface{}{"name": "wiki", "type": "ftp"},
+			map[string]interface{}{"type": "file", "file": "docs/*.md"},
+		},
+		"retrieval": map[string]interface{}{"top_k": 80, "similarity_threshold": 1.5, "chunk_size": 500, "chunk_overlap": 500},
+	}
+	tasks := spec["tasks"].([]interface{})
+	task := tasks[0].(map[string]interface{})
+	task["steps"] = append(task["steps"].([]interface{}),
+		map[string]interface{}{"name": "lookup_faq", "action": "search", "source": "faq"},
+		map[string]interface{}{"name": "lookup_manual", "action": "search", "source": "manual"},
+	)
+
+	validator := NewAPAIValidator()
+	validator.ValidateSpec(spec)
+	wantErrors := map[string]string{
+		"Knowledge source orders missing required field: connection":                         "MISSING_FIELD",
+		"Duplicate knowledge source name: faq":                                               "DUPLICATE_ID",
+		"Knowledge source wiki invalid source type: ftp (expected url, file, database, api)": "INVALID_ENUM",
+		"Knowledge source 4 missing required field: name":                                    "MISSING_FIELD",
+		"Invalid retrieval setting: similarity_threshold must be between 0 and 1, got 1.5":   "INVALID_RETRIEVAL_SETTING",
+		"Invalid retrieval setting: chunk_overlap 500 must be less than chunk_size 500":      "INVALID_RETRIEVAL_SETTING",
+		"Knowledge base requires a model of type Embedding, but none is defined":             "MISSING_EMBEDDING_MODEL",
+		"Task references unknown knowledge source: manual":                                   "UNKNOWN_REFERENCE",
+	}
+	wantWarnings := map[string]string{
+		"Retrieval top_k of 80 is above 50": "LARGE_RETRIEVAL_TOP_K",
+		`Non-canonical casing for context.knowledge_base.sources[1].type: "Database", use "database"`: "ENUM_CASING",
+	}
+	for findings, want := range map[*[]string]map[string]string{&validator.Errors: wantErrors, &validator.Warnings: wantWarnings} {
+		for message, code := range want {
+			if !containsString(*findings, message) {
+				t.Errorf("missing %q in %v", message, *findings)
+			}
+			if rule, _ := MatchRule(message); rule.Code != code {
+				t.Errorf("expected %s for %q, got %q", code, message, rule.Code)
+			}
+		}
+	}
+	if containsString(validator.Errors, "Task references unknown knowledge source: faq") {
+		t.Errorf("unexpected unknown source faq in %v", validator.Errors)
+	}
+
+	f := &sectionFindings{}
+	validateRetrievalSettings(f, map[string]interface{}{"top_k": 0, "chunk_size": 2.5, "similarity_threshold": 0.8, "chunk_overlap": 1})
+	for _, want := range []string{
+		"Invalid retrieval setting: top_k must be a positive integer, got 0",
+		"Invalid retrieval setting: chunk_size must be a positive integer, got 2.5",
+	} {
+		if !containsString(f.Errors, want) {
+			t.Errorf("missing %q in %v", want, f.Errors)
+		}
+	}
+	if len(f.Errors) != 2 || len(f.Warnings) != 0 {
+		t.Errorf("unexpected findings: %v %v", f.Errors, f.Warnings)
+	}
+}
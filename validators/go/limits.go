@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// rateLimitFields are the limits a model rate_limit block may declare, both
+// per minute as their names say
+var rateLimitFields = []string{"requests_per_minute", "tokens_per_minute"}
+
+// quotaFields are the limits a model quota block may declare
+var quotaFields = []string{"daily_budget"}
+
+// ratePattern matches a rate written with a unit, such as "60/min"
+var ratePattern = regexp.MustCompile(`^\s*(\d+(?:\.\d+)?)\s*/\s*(s|sec|second|m|min|minute|h|hr|hour|d|day)\s*$`)
+
+// perMinuteRate converts a rate written with a unit, such as "2/s", to a
+// number per minute
+func perMinuteRate(value string) (float64, bool) {
+	match := ratePattern.FindStringSubmatch(value)
+	if match == nil {
+		return 0, false
+	}
+	amount, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return 0, false
+	}
+	switch match[2] {
+	case "s", "sec", "second":
+		return amount * 60, true
+	case "h", "hr", "hour":
+		return amount / 60, true
+	case "d", "day":
+		return amount / 1440, true
+	}
+	return amount, true
+}
+
+// validateModelLimits checks the rate_limit and quota blocks of a model:
+// positive numbers, and a token rate that admits a request of max_tokens
+func validateModelLimits(f *sectionFindings, modelMap map[string]interface{}, modelIndex int) {
+	modelName := elementName(modelIndex, modelMap)
+	limits := map[string][]string{"rate_limit": rateLimitFields, "quota": quotaFields}
+	for _, block := range []string{"rate_limit", "quota"} {
+		value, exists := modelMap[block]
+		if !exists {
+			continue
+		}
+		blockMap, ok := value.(map[string]interface{})
+		if !ok {
+			f.addError("INVALID_TYPE", fmt.Sprintf("Model %s %s must be an object", modelName, block))
+			continue
+		}
+		for _, field := range limits[block] {
+			value, exists := blockMap[field]
+			if !exists {
+				continue
+			}
+			if str, isString := value.(string); isString {
+				f.addError("NUMERIC_STRING", numericStringError(fmt.Sprintf("models[%d].%s.%s", modelIndex, block, field), str))
+				continue
+			}
+			if number, ok := numberValue(value); !ok || number <= 0 {
+				f.addError("INVALID_LIMIT", fmt.Sprintf("Invalid limit for model %s: %s.%s must be a positive number, got %v", modelName, block, field, value))
+			}
+		}
+	}
+
+	rateLimit, _ := modelMap["rate_limit"].(map[string]interface{})
+	tokensPerMinute, ok := numberValue(rateLimit["tokens_per_minute"])
+	if !ok || tokensPerMinute <= 0 {
+		return
+	}
+	parameters, _ := modelMap["parameters"].(map[string]interface{})
+	if maxTokens, ok := numberValue(parameters["max_tokens"]); ok && tokensPerMinute < maxTokens {
+		f.addError("INVALID_LIMIT", fmt.Sprintf("Invalid limit for model %s: rate_limit.tokens_per_minute (%v) is smaller than parameters.max_tokens (%v), so a single request can exceed it", modelName, rateLimit["tokens_per_minute"], parameters["max_tokens"]))
+	}
+}
+
+// validateSharedRateLimits warns when models of the same provider and
+// name declare different rate limits: they draw on the same real quota
+func validateSharedRateLimits(f *sectionFindings, models []interface{}) {
+	limits := make(map[string]map[string]string)
+	names := make(map[string][]string)
+	keys := make([]string, 0)
+	for index, model := range models {
+		modelMap, ok := model.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		rateLimit, ok := modelMap["rate_limit"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		provider, _ := modelMap["provider"].(string)
+		name, _ := modelMap["name"].(string)
+		if provider == "" || name == "" {
+			continue
+		}
+
+		key := strings.ToLower(provider + "/" + name)
+		if _, seen := names[key]; !seen {
+			keys = append(keys, key)
+			limits[key] = make(map[string]string)
+		}
+		names[key] = append(names[key], elementName(index, modelMap))
+		for _, field := range rateLimitFields {
+			if value, exists := rateLimit[field]; exists {
+				limits[key][elementName(index, modelMap)+"."+field] = fmt.Sprint(value)
+			}
+		}
+	}
+
+	sort.Strings(keys)
+	for _, key := range keys {
+		if len(names[key]) < 2 {
+			continue
+		}
+		for _, field := range rateLimitFields {
+			values := make(map[string]bool)
+			for _, name := range names[key] {
+				if value, exists := limits[key][name+"."+field]; exists {
+					values[value] = true
+				}
+			}
+			if len(values) > 1 {
+				f.addWarning("CONFLICTING_RATE_LIMITS", fmt.Sprintf("Models %s use %s but declare different rate_limit.%s; they share the same provider quota", strings.Join(names[key], ", "), key, field))
+			}
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import "testing"
+
+func TestModelLimits(t *testing.T) {
+	model := func(id string, rateLimit, quota interface{}) map[string]interface{} {
+		model := map[string]interface{}{"id": id, "type": "LLM", "provider": "OpenAI", "name": "gpt-4o", "purpose": "support",
+			"parameters": map[string]interface{}{"max_tokens": 2000}}
+		if rateLimit != nil {
+			model["rate_limit"] = rateLimit
+		}
+		if quota != nil {
+			model["quota"] = quota
+		}
+		return model
+	}
+	spec := map[string]interface{}{"models": []interface{}{
+		model("primary", map[string]interface{}{"requests_per_minute": 60, "tokens_per_minute": 1000}, map[string]interface{}{"daily_budget": 0}),
+		model("fallback", map[string]interface{}{"requests_per_minute": "2/s", "tokens_per_minute": 90000}, map[string]interface{}{"daily_budget": "50 USD"}),
+		model("batch", map[string]interface{}{"requests_per_minute": 120, "tokens_per_minute": "fast"}, "unlimited"),
+	}}
+
+	validator := NewAPAIValidator()
+	validator.ValidateSpec(spec)
+	wantErrors := map[string]string{
+		"Invalid limit for model primary: rate_limit.tokens_per_minute (1000) is smaller than parameters.max_tokens (2000), so a single request can exceed it": "INVALID_LIMIT",
+		"Invalid limit for model primary: quota.daily_budget must be a positive number, got 0":                                                                 "INVALID_LIMIT",
+		`models[1].rate_limit.requests_per_minute must be a number, got string "2/s" (write 120, the rate per minute)`:                                         "NUMERIC_STRING",
+		`models[1].quota.daily_budget must be a number, got string "50 USD"`:                                                                                   "NUMERIC_STRING",
+		`models[2].rate_limit.tokens_per_minute must be a number, got string "fast"`:                                                                           "NUMERIC_STRING",
+		"Model batch quota must be an object": "INVALID_TYPE",
+	}
+	for want, code := range wantErrors {
+		if !containsString(validator.Errors, want) {
+			t.Errorf("missing %q in %v", want, validator.Errors)
+		}
+		if rule, _ := MatchRule(want); rule.Code != code {
+			t.Errorf("expected %s for %q, got %q", code, want, rule.Code)
+		}
+	}
+
+	want := "Models primary, fallback, batch use openai/gpt-4o but declare different rate_limit.requests_per_minute; they share the same provider quota"
+	if !containsString(validator.Warnings, want) {
+		t.Errorf("missing %q in %v", want, validator.Warnings)
+	}
+	if rule, _ := MatchRule(want); rule.Code != "CONFLICTING_RATE_LIMITS" {
+		t.Errorf("expected CONFLICTING_RATE_LIMITS, got %q", rule.Code)
+	}
+
+	// Models of the same provider model with the same limits do not conflict
+	spec["models"] = []interface{}{
+		model("primary", map[string]interface{}{"requests_per_minute": 60}, nil),
+		model("fallback", map[string]interface{}{"requests_per_minute": 60}, nil),
+	}
+	validator.ValidateSpec(spec)
+	for _, warning := range validator.Warnings {
+		if rule, _ := MatchRule(warning); rule.Code == "CONFLICTING_RATE_LIMITS" {
+			t.Errorf("unexpected %q", warning)
+		}
+	}
+}
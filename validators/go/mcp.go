@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"os/exec"
+)
+
+// MCPServerCheck represents the connectivity check result of one MCP server
+type MCPServerCheck struct {
+	ID        string `json:"id"`
+	Reachable bool   `json:"reachable"`
+	Error     string `json:"error,omitempty"`
+}
+
+// defaultPorts maps MCP transport URL schemes to their default ports
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"ws":    "80",
+	"https": "443",
+	"wss":   "443",
+}
+
+// CheckMCPServersContext checks that the MCP servers declared in
+// context.mcp_servers are reachable: stdio commands must be installed and
+// sse/websocket hosts must accept connections. It stops once ctx is done,
+// returning the checks completed so far with the context's error, wrapped.
+func (v *APAIValidator) CheckMCPServersContext(ctx context.Context, spec map[string]interface{}) ([]MCPServerCheck, error) {
+	contextMap, _ := spec["context"].(map[string]interface{})
+	servers, _ := contextMap["mcp_servers"].([]interface{})
+
+	checks := make([]MCPServerCheck, 0, len(servers))
+	for index, server := range servers {
+		serverMap, ok := server.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		id, _ := serverMap["id"].(string)
+		if id == "" {
+			id = fmt.Sprintf("%d", index)
+		}
+		if err := ctx.Err(); err != nil {
+			return checks, fmt.Errorf("checking MCP server %s: %w", id, err)
+		}
+
+		check := MCPServerCheck{ID: id}
+		if err := checkMCPTransport(ctx, serverMap["transport"]); err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return checks, fmt.Errorf("checking MCP server %s: %w", id, ctxErr)
+			}
+			check.Error = err.Error()
+		} else {
+			check.Reachable = true
+		}
+		checks = append(checks, check)
+	}
+	return checks, nil
+}
+
+// checkMCPTransport checks that a single MCP transport can be reached
+func checkMCPTransport(ctx context.Context, transport interface{}) error {
+	transportMap, ok := transport.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("transport must be an object")
+	}
+
+	transportType, _ := transportMap["type"].(string)
+	switch transportType {
+	case "stdio":
+		command, _ := transportMap["command"].(string)
+		if command == "" {
+			return fmt.Errorf("stdio transport missing command")
+		}
+		if _, err := exec.LookPath(command); err != nil {
+			return fmt.Errorf("command %s not found", command)
+		}
+		return nil
+	case "sse", "websocket":
+		rawURL, _ := transportMap["url"].(string)
+		parsed, err := url.Parse(rawURL)
+		if err != nil || parsed.Hostname() == "" {
+			return fmt.Errorf("invalid url: %s", rawURL)
+		}
+
+		port := parsed.Port()
+		if port == "" {
+			port = defaultPorts[parsed.Scheme]
+		}
+		if port == "" {
+			return fmt.Errorf("unsupported url scheme: %s", parsed.Scheme)
+		}
+
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(parsed.Hostname(), port))
+		if err != nil {
+			return fmt.Errorf("cannot connect to %s: %v", rawURL, err)
+		}
+		return conn.Close()
+	default:
+		return fmt.Errorf("unsupported transport type: %s", transportType)
+	}
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/FabioGuin/APAI/validators/go/internal/cron"
+)
+
+// metricDirections are the directions in which a metric improves
+var metricDirections = []string{"maximize", "minimize"}
+
+// metricType is a kind of evaluation metric: the direction in which it
+// improves, the range its values fall in, and the words of metric names
+// that imply it when no type is declared
+type metricType struct {
+	name      string
+	direction string
+	low, high float64
+	aliases   []string
+}
+
+// metricTypes are the metric types whose direction and domain are checked
+var metricTypes = []metricType{
+	{"accuracy", "maximize", 0, 1, []string{"accuracy"}},
+	{"precision", "maximize", 0, 1, []string{"precision"}},
+	{"recall", "maximize", 0, 1, []string{"recall"}},
+	{"f1", "maximize", 0, 1, []string{"f1", "f1_score"}},
+	{"error_rate", "minimize", 0, 1, []string{"error_rate", "failure_rate"}},
+	{"latency", "minimize", 0, math.Inf(1), []string{"latency", "response_time", "duration"}},
+	{"cost", "minimize", 0, math.Inf(1), []string{"cost"}},
+	{"throughput", "maximize", 0, math.Inf(1), []string{"throughput"}},
+}
+
+// metricLinkFields are the metric fields naming what a metric measures,
+// with the section declaring the ids they reference
+var metricLinkFields = []struct {
+	field, section, kind string
+}{
+	{"applies_to", "tasks", "task"},
+	{"constraint", "constraints", "constraint"},
+}
+
+// judgePurposeWords are words of a model purpose marking it as a model
+// that can judge the outputs of others
+var judgePurposeWords = []string{"evaluat", "judg"}
+
+// metricThresholdFields are the metric fields holding the value a metric
+// is gated on
+var metricThresholdFields = []string{"target", "threshold"}
+
+// forEachMetric calls visit with each metric of evaluation.metrics and its
+// location. Metrics are listed, or grouped in lists by category, visited
+// in the order of their category names.
+func forEachMetric(metrics interface{}, visit func(metricMap map[string]interface{}, location string)) {
+	groups := map[string]interface{}{"evaluation.metrics": metrics}
+	if metricsMap, ok := metrics.(map[string]interface{}); ok {
+		groups = make(map[string]interface{})
+		for category, group := range metricsMap {
+			groups["evaluation.metrics."+category] = group
+		}
+	}
+	for _, location := range sortedKeys(groups) {
+		group, _ := groups[location].([]interface{})
+		for i, metric := range group {
+			if metricMap, ok := metric.(map[string]interface{}); ok {
+				visit(metricMap, fmt.Sprintf("%s[%d]", location, i))
+			}
+		}
+	}
+}
+
+// metricTypeOf returns the type a metric declares or, without a type, the
+// one the words of its name imply
+func metricTypeOf(metricMap map[string]interface{}) (metricType, bool) {
+	if declared, ok := metricMap["type"].(string); ok {
+		for _, candidate := range metricTypes {
+			if strings.EqualFold(candidate.name, declared) {
+				return candidate, true
+			}
+		}
+		return metricType{}, false
+	}
+	name, _ := metricMap["name"].(string)
+	words := "_" + strings.NewReplacer("-", "_", " ", "_").Replace(strings.ToLower(name)) + "_"
+	for _, candidate := range metricTypes {
+		for _, alias := range candidate.aliases {
+			if strings.Contains(words, "_"+alias+"_") {
+				return candidate, true
+			}
+		}
+	}
+	return metricType{}, false
+}
+
+// validateMetric checks the direction and thresholds of an evaluation
+// metric at location against its type
+func validateMetric(f *sectionFindings, metricMap map[string]interface{}, location string) {
+	name, ok := metricMap["name"].(string)
+	if !ok || name == "" {
+		name = location
+	}
+
+	if measurement, ok := metricMap["measurement"].(map[string]interface{}); ok {
+		validateMeasurementSchedule(f, name, measurement, location+".measurement")
+	}
+	for _, link := range metricLinkFields {
+		if value, exists := metricMap[link.field]; exists {
+			if _, ok := metricLinks(value); !ok {
+				f.addError("INVALID_TYPE", fmt.Sprintf("Metric %s %s must be a string or an array of %s ids", name, link.field, link.kind))
+			}
+		}
+	}
+
+	kind, typed := metricTypeOf(metricMap)
+	if declared, ok := metricMap["type"].(string); ok && !typed {
+		f.addWarning("UNKNOWN_VALUE", fmt.Sprintf("Metric %s has unknown type: %s", name, declared))
+	}
+
+	if value, exists := metricMap["direction"]; exists {
+		direction, ok := value.(string)
+		if !ok {
+			f.addError("INVALID_TYPE", fmt.Sprintf("Metric %s direction must be a string", name))
+		} else if canonical, valid := matchEnum(f, metricDirections, direction, location+".direction"); !valid {
+			f.addWarning("UNKNOWN_VALUE", fmt.Sprintf("Metric %s unknown direction: %s (expected maximize or minimize)", name, direction))
+		} else if typed && canonical != kind.direction {
+			f.addWarning("METRIC_DIRECTION_MISMATCH", fmt.Sprintf("Metric %s direction %s contradicts its %s type, which should %s", name, canonical, kind.name, kind.direction))
+		}
+	}
+
+	if !typed {
+		return
+	}
+	for _, field := range metricThresholdFields {
+		value, ok := numberValue(metricMap[field])
+		if !ok || value >= kind.low && value <= kind.high {
+			continue
+		}
+		domain := fmt.Sprintf("%v to %v", kind.low, kind.high)
+		if math.IsInf(kind.high, 1) {
+			domain = fmt.Sprintf("at least %v", kind.low)
+		}
+		f.addWarning("METRIC_THRESHOLD_OUT_OF_DOMAIN", fmt.Sprintf("Metric %s %s %v is outside the domain of %s metrics (%s)", name, field, metricMap[field], kind.name, domain))
+	}
+}
+
+// humanReviewMethods are words of measurement methods that need a person
+// to review results
+var humanReviewMethods = []string{"manual", "human"}
+
+// validateMeasurementSchedule validates how often a metric is measured: a
+// known frequency and, for the cron frequency, a cron schedule. Human
+// review scheduled more often than hourly produces a warning.
+func validateMeasurementSchedule(f *sectionFindings, name string, measurement map[string]interface{}, location string) {
+	frequency := ""
+	if value, exists := measurement["frequency"]; exists {
+		text, ok := value.(string)
+		if !ok {
+			// Numeric frequencies such as summary_frequency are counts
+			return
+		}
+		canonical, valid := matchEnum(f, evaluationFrequencies, text, location+".frequency")
+		if !valid {
+			f.addError("INVALID_ENUM", fmt.Sprintf("Invalid evaluation frequency: %s in metric %s (expected %s)", text, name, strings.Join(evaluationFrequencies, ", ")))
+			return
+		}
+		frequency = canonical
+	}
+
+	var interval time.Duration
+	switch value, exists := measurement["schedule"]; {
+	case frequency == "cron" && !exists:
+		f.addError("MISSING_FIELD", fmt.Sprintf("Metric %s measurement missing required field: schedule (for the cron frequency)", name))
+		return
+	case !exists || frequency != "" && frequency != "cron":
+		if frequency == "real_time" {
+			interval = time.Minute
+		}
+	default:
+		text, ok := value.(string)
+		if !ok {
+			f.addError("INVALID_TYPE", fmt.Sprintf("Metric %s measurement schedule must be a string", name))
+			return
+		}
+		schedule, err := cron.Parse(text)
+		if err != nil {
+			f.addError("INVALID_CRON_SCHEDULE", fmt.Sprintf("Invalid cron schedule for metric %s: %q: %v", name, text, err))
+			return
+		}
+		interval = schedule.MinInterval()
+	}
+
+	method, _ := measurement["method"].(string)
+	if interval <= 0 || interval >= time.Hour {
+		return
+	}
+	for _, word := range humanReviewMethods {
+		if strings.Contains(strings.ToLower(method), word) {
+			f.addWarning("FREQUENT_HUMAN_REVIEW", fmt.Sprintf("Metric %s is measured more often than hourly by human review method %s", name, method))
+			return
+		}
+	}
+}
+
+// metricLinks returns the ids a link field of a metric names: one id, or
+// an array of them
+func metricLinks(value interface{}) ([]string, bool) {
+	switch typed := value.(type) {
+	case string:
+		return []string{typed}, true
+	case []interface{}:
+		ids := make([]string, 0, len(typed))
+		for _, item := range typed {
+			id, ok := item.(string)
+			if !ok {
+				return nil, false
+			}
+			ids = append(ids, id)
+		}
+		return ids, true
+	}
+	return nil, false
+}
+
+// metricMethod returns how a metric is measured: its method, or the
+// method of its measurement
+func metricMethod(metricMap map[string]interface{}) string {
+	if method, ok := metricMap["method"].(string); ok {
+		return method
+	}
+	measurement, _ := metricMap["measurement"].(map[string]interface{})
+	method, _ := measurement["method"].(string)
+	return method
+}
+
+// validateMetricLinks checks the tasks and constraints metrics declare
+// they measure, warns about critical constraints no metric measures, and
+// about metrics judged by a model when no model has a judging purpose
+func (v *APAIValidator) validateMetricLinks(spec map[string]interface{}) {
+	evaluation, _ := spec["evaluation"].(map[string]interface{})
+
+	ids := make(map[string]map[string]bool)
+	for _, link := range metricLinkFields {
+		ids[link.section] = make(map[string]bool)
+		items, _ := spec[link.section].([]interface{})
+		for _, item := range items {
+			itemMap, _ := item.(map[string]interface{})
+			if id, ok := itemMap["id"].(string); ok {
+				ids[link.section][id] = true
+			}
+		}
+	}
+	judges := false
+	models, _ := spec["models"].([]interface{})
+	for _, model := range models {
+		modelMap, _ := model.(map[string]interface{})
+		purpose, _ := modelMap["purpose"].(string)
+		for _, word := range judgePurposeWords {
+			judges = judges || strings.Contains(strings.ToLower(purpose), word)
+		}
+	}
+
+	measured := make(map[string]bool)
+	forEachMetric(evaluation["metrics"], func(metricMap map[string]interface{}, location string) {
+		name, ok := metricMap["name"].(string)
+		if !ok || name == "" {
+			name = location
+		}
+		for _, link := range metricLinkFields {
+			targets, _ := metricLinks(metricMap[link.field])
+			for _, target := range targets {
+				if !ids[link.section][target] {
+					v.addError("UNKNOWN_REFERENCE", fmt.Sprintf("Metric %s references unknown %s: %s", name, link.kind, target))
+				} else if link.section == "constraints" {
+					measured[target] = true
+				}
+			}
+		}
+		if strings.EqualFold(metricMethod(metricMap), "llm_judge") && !judges {
+			v.addWarning("MISSING_JUDGE_MODEL", fmt.Sprintf("Metric %s uses method llm_judge but no model has an evaluation or judging purpose", name))
+		}
+	})
+
+	constraints, _ := spec["constraints"].([]interface{})
+	for index, constraint := range constraints {
+		constraintMap, _ := constraint.(map[string]interface{})
+		severity, _ := constraintMap["severity"].(string)
+		id, _ := constraintMap["id"].(string)
+		if strings.EqualFold(severity, "critical") && !measured[id] {
+			v.addWarning("UNMEASURED_CONSTRAINT", fmt.Sprintf("Critical constraint %s has no metric linked to it", elementName(index, constraintMap)))
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import "testing"
+
+func TestValidateMetrics(t *testing.T) {
+	metric := func(fields ...interface{}) interface{} {
+		metric := map[string]interface{}{"description": "Metric"}
+		for i := 0; i < len(fields); i += 2 {
+			metric[fields[i].(string)] = fields[i+1]
+		}
+		return metric
+	}
+	evaluation := map[string]interface{}{"metrics": []interface{}{
+		metric("name", "response_accuracy", "target", 5.0, "direction", "maximize"),
+		metric("name", "response_time", "direction", "maximize", "target", "< 2s"),
+		metric("name", "spend", "type", "cost", "direction", "Minimize", "threshold", -1),
+		metric("name", "satisfaction", "type", "happiness", "direction", "upward"),
+		metric("name", "f1_score", "target", 0.8, "direction", "maximize"),
+	}}
+
+	f := &sectionFindings{}
+	NewAPAIValidator().validateEvaluation(f, evaluation)
+	want := map[string]string{
+		"Metric response_accuracy target 5 is outside the domain of accuracy metrics (0 to 1)":        "METRIC_THRESHOLD_OUT_OF_DOMAIN",
+		"Metric response_time direction maximize contradicts its latency type, which should minimize": "METRIC_DIRECTION_MISMATCH",
+		"Metric spend threshold -1 is outside the domain of cost metrics (at least 0)":                "METRIC_THRESHOLD_OUT_OF_DOMAIN",
+		`Non-canonical casing for evaluation.metrics[2].direction: "Minimize", use "minimize"`:        "ENUM_CASING",
+		"Metric satisfaction has unknown type: happiness":                                             "UNKNOWN_VALUE",
+		"Metric satisfaction unknown direction: upward (expected maximize or minimize)":               "UNKNOWN_VALUE",
+	}
+	if len(f.Errors) != 0 || len(f.Warnings) != len(want) {
+		t.Errorf("unexpected findings: %v %v", f.Errors, f.Warnings)
+	}
+	for message, code := range want {
+		if !containsString(f.Warnings, message) {
+			t.Errorf("missing %q in %v", message, f.Warnings)
+		}
+		if rule, _ := MatchRule(message); rule.Code != code {
+			t.Errorf("expected %s for %q, got %q", code, message, rule.Code)
+		}
+	}
+
+	// Metrics grouped by category are checked too
+	f = &sectionFindings{}
+	NewAPAIValidator().validateEvaluation(f, map[string]interface{}{"metrics": map[string]interface{}{
+		"quality": []interface{}{metric("type", "recall", "target", 95)},
+	}})
+	wantGrouped := "Metric evaluation.metrics.quality[0] target 95 is outside the domain of recall metrics (0 to 1)"
+	if !containsString(f.Warnings, wantGrouped) {
+		t.Errorf("missing %q in %v", wantGrouped, f.Warnings)
+	}
+}
+
+func TestMeasurementSchedules(t *testing.T) {
+	metric := func(name string, measurement map[string]interface{}) interface{} {
+		return map[string]interface{}{"name": name, "description": "Metric", "target": 1, "measurement": measurement}
+	}
+	evaluation := map[string]interface{}{"metrics": []interface{}{
+		metric("weekly_review", map[string]interface{}{"method": "automated", "frequency": "cron", "schedule": "0 3 * * 1"}),
+		metric("nightly", map[string]interface{}{"method": "automated", "frequency": "Cron", "schedule": "@daily"}),
+		metric("broken", map[string]interface{}{"method": "automated", "frequency": "cron", "schedule": "0 25 * * 1"}),
+		metric("unscheduled", map[string]interface{}{"method": "automated", "frequency": "cron"}),
+		metric("fortnightly", map[string]interface{}{"method": "automated", "frequency": "biweekly"}),
+		metric("panel", map[string]interface{}{"method": "human_evaluation", "frequency": "cron", "schedule": "*/30 9-17 * * *"}),
+		metric("live_review", map[string]interface{}{"method": "manual_review", "frequency": "real_time"}),
+		metric("hourly_review", map[string]interface{}{"method": "manual_review", "schedule": "@hourly"}),
+	}}
+
+	f := &sectionFindings{}
+	NewAPAIValidator().validateEvaluation(f, evaluation)
+	wantErrors := map[string]string{
+		`Invalid cron schedule for metric broken: "0 25 * * 1": hour field "25": 25 is outside 0-23`:                                                    "INVALID_CRON_SCHEDULE",
+		"Metric unscheduled measurement missing required field: schedule (for the cron frequency)":                                                      "MISSING_FIELD",
+		"Invalid evaluation frequency: biweekly in metric fortnightly (expected on_demand, hourly, daily, weekly, monthly, cron, real_time, quarterly)": "INVALID_ENUM",
+	}
+	wantWarnings := map[string]string{
+		`Non-canonical casing for evaluation.metrics[1].measurement.frequency: "Cron", use "cron"`:   "ENUM_CASING",
+		"Metric panel is measured more often than hourly by human review method human_evaluation":    "FREQUENT_HUMAN_REVIEW",
+		"Metric live_review is measured more often than hourly by human review method manual_review": "FREQUENT_HUMAN_REVIEW",
+	}
+	for findings, want := range map[*[]string]map[string]string{&f.Errors: wantErrors, &f.Warnings: wantWarnings} {
+		if len(*findings) != len(want) {
+			t.Errorf("unexpected findings: %v", *findings)
+		}
+		for message, code := range want {
+			if !containsString(*findings, message) {
+				t.Errorf("missing %q in %v", message, *findings)
+			}
+			if rule, _ := MatchRule(message); rule.Code != code {
+				t.Errorf("expected %s for %q, got %q", code, message, rule.Code)
+			}
+		}
+	}
+}
+
+func TestMetricLinks(t *testing.T) {
+	spec := loadExampleSpecs(t, "templates/basic-template.yaml")[0]
+	spec["constraints"] = append(spec["constraints"].([]interface{}), map[string]interface{}{
+		"id": "no_hallucination", "name": "No hallucination", "type": "content_safety", "rule": "output grounded in sources", "severity": "critical",
+	})
+	evaluation := spec["evaluation"].(map[string]interface{})
+	evaluation["metrics"] = append(evaluation["metrics"].([]interface{}),
+		map[string]interface{}{"name": "triage_quality", "description": "Triage graded by a judge", "target": 0.8,
+			"applies_to": []interface{}{"handle_request", "task-triage"}, "method": "llm_judge"},
+		map[string]interface{}{"name": "grounding", "description": "Grounded answers", "target": 0.95,
+			"constraint": "no-hallucination", "measurement": map[string]interface{}{"method": "LLM_Judge"}},
+		map[string]interface{}{"name": "coverage", "description": "Coverage", "target": 0.9, "applies_to": 3},
+	)
+
+	validator := NewAPAIValidator()
+	validator.ValidateSpec(spec)
+	wantErrors := map[string]string{
+		"Metric triage_quality references unknown task: task-triage":          "UNKNOWN_REFERENCE",
+		"Metric grounding references unknown constraint: no-hallucination":    "UNKNOWN_REFERENCE",
+		"Metric coverage applies_to must be a string or an array of task ids": "INVALID_TYPE",
+	}
+	wantWarnings := map[string]string{
+		"Critical constraint no_hallucination has no metric linked to it":                               "UNMEASURED_CONSTRAINT",
+		"Metric triage_quality uses method llm_judge but no model has an evaluation or judging purpose": "MISSING_JUDGE_MODEL",
+		"Metric grounding uses method llm_judge but no model has an evaluation or judging purpose":      "MISSING_JUDGE_MODEL",
+	}
+	for findings, want := range map[*[]string]map[string]string{&validator.Errors: wantErrors, &validator.Warnings: wantWarnings} {
+		if len(*findings) != len(want) {
+			t.Errorf("unexpected findings: %v", *findings)
+		}
+		for message, code := range want {
+			if !containsString(*findings, message) {
+				t.Errorf("missing %q in %v", message, *findings)
+			}
+			if rule, _ := MatchRule(message); rule.Code != code {
+				t.Errorf("expected %s for %q, got %q", code, message, rule.Code)
+			}
+		}
+	}
+
+	// A model with a judging purpose grades llm_judge metrics
+	spec["models"] = append(spec["models"].([]interface{}), map[string]interface{}{
+		"id": "judge", "type": "LLM", "provider": "openai", "name": "gpt-4o", "purpose": "Judges triage answers",
+	})
+	validator = NewAPAIValidator()
+	validator.ValidateSpec(spec)
+	for _, message := range validator.Warnings {
+		if rule, _ := MatchRule(message); rule.Code == "MISSING_JUDGE_MODEL" {
+			t.Errorf("unexpected warning with a judge model: %q", message)
+		}
+	}
+}
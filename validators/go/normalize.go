@@ -0,0 +1,67 @@
+package main
+
+import "strings"
+
+// specDefault is the value an optional field takes when a specification
+// leaves it out
+type specDefault struct {
+	// path selects the objects holding the field, as in objectsAt
+	path string
+	// field is the field, dotted when it sits in a nested object
+	field string
+	value interface{}
+}
+
+// specDefaults are the documented defaults of optional fields, in the order
+// Normalize applies them. Required fields, such as the severity of a
+// constraint, have no default.
+var specDefaults = []specDefault{
+	{"tasks[]", "priority", "medium"},
+	{"tasks[]", "abstract", false},
+	{"tasks[].steps[]", "response_format", "text"},
+	{"tasks[].steps[]", "input_modalities", []interface{}{"text"}},
+	{"context.persistence", "enabled", true},
+	{"context.session", "enabled", true},
+	{"context.mcp_servers[]", "authentication.type", "none"},
+	{"context.mcp_servers[]", "security.timeout", 30},
+	{"evaluation.test_cases[]", "priority", "medium"},
+	{"evaluation.experiments[]", "status", "active"},
+}
+
+// Normalize returns a copy of spec with the documented defaults filled in
+// for the optional fields it leaves out, so runtimes see the same shape
+// whatever the author wrote. Sections and elements are never added, and
+// fields already set, even to values of the wrong type, are kept.
+func Normalize(spec map[string]interface{}) map[string]interface{} {
+	normalized, _ := normalizeSpec(spec)
+	return normalized
+}
+
+// normalizeSpec is Normalize, also returning the locations of the fields
+// it filled in
+func normalizeSpec(spec map[string]interface{}) (map[string]interface{}, []string) {
+	normalized, _ := copyValue(spec).(map[string]interface{})
+	filled := make([]string, 0)
+	for _, fieldDefault := range specDefaults {
+		objectsAt(normalized, fieldDefault.path, "", func(object map[string]interface{}, location string) {
+			names := strings.Split(fieldDefault.field, ".")
+			for _, name := range names[:len(names)-1] {
+				child, exists := object[name]
+				if !exists {
+					child = make(map[string]interface{})
+					object[name] = child
+				}
+				if object, exists = child.(map[string]interface{}); !exists {
+					return
+				}
+			}
+			name := names[len(names)-1]
+			if _, exists := object[name]; exists {
+				return
+			}
+			object[name] = copyValue(fieldDefault.value)
+			filled = append(filled, joinLocation(location, fieldDefault.field))
+		})
+	}
+	return normalized, filled
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	spec := map[string]interface{}{
+		"tasks": []interface{}{
+			map[string]interface{}{"id": "triage", "description": "Triage", "priority": "high", "steps": []interface{}{
+				map[string]interface{}{"name": "classify", "action": "classify", "response_format": "json"},
+			}},
+		},
+		"context": map[string]interface{}{
+			"mcp_servers": []interface{}{
+				map[string]interface{}{"id": "files", "security": map[string]interface{}{"allowed_operations": []interface{}{"read"}}},
+				map[string]interface{}{"id": "broken", "authentication": "none"},
+			},
+		},
+		"constraints": []interface{}{
+			map[string]interface{}{"id": "no_pii", "rule": "no personal data"},
+		},
+	}
+
+	normalized, filled := normalizeSpec(spec)
+	want := []string{
+		"tasks[0].abstract",
+		"tasks[0].steps[0].input_modalities",
+		"context.mcp_servers[0].authentication.type",
+		"context.mcp_servers[0].security.timeout",
+		"context.mcp_servers[1].security.timeout",
+	}
+	if !reflect.DeepEqual(filled, want) {
+		t.Errorf("expected filled defaults %v, got %v", want, filled)
+	}
+
+	task := normalized["tasks"].([]interface{})[0].(map[string]interface{})
+	if task["priority"] != "high" || task["abstract"] != false {
+		t.Errorf("expected priority kept and abstract defaulted, got %v", task)
+	}
+	step := task["steps"].([]interface{})[0].(map[string]interface{})
+	if step["response_format"] != "json" || !reflect.DeepEqual(step["input_modalities"], []interface{}{"text"}) {
+		t.Errorf("expected response_format kept and input_modalities defaulted, got %v", step)
+	}
+	servers := normalized["context"].(map[string]interface{})["mcp_servers"].([]interface{})
+	security := servers[0].(map[string]interface{})["security"].(map[string]interface{})
+	if security["timeout"] != 30 || security["allowed_operations"] == nil {
+		t.Errorf("expected security.timeout filled next to allowed_operations, got %v", security)
+	}
+	// Values of the wrong type are left to validation
+	if servers[1].(map[string]interface{})["authentication"] != "none" {
+		t.Errorf("expected authentication kept, got %v", servers[1])
+	}
+	// Required fields are never filled, and sections are never added
+	constraint := normalized["constraints"].([]interface{})[0].(map[string]interface{})
+	if _, exists := constraint["severity"]; exists {
+		t.Errorf("expected required severity left out, got %v", constraint)
+	}
+	if _, exists := normalized["evaluation"]; exists {
+		t.Errorf("expected no evaluation section, got %v", normalized["evaluation"])
+	}
+
+	// The original is not modified
+	original := spec["tasks"].([]interface{})[0].(map[string]interface{})
+	if _, exists := original["abstract"]; exists {
+		t.Errorf("expected the original spec unchanged, got %v", original)
+	}
+	if !reflect.DeepEqual(Normalize(normalized), normalized) {
+		t.Error("expected normalizing twice to change nothing")
+	}
+}
+
+func TestNormalizeKeepsExamplesValid(t *testing.T) {
+	paths := []string{"templates/basic-template.yaml", "core/customer-support.yaml", "automation/mcp-integration.yaml", "templates/security-template.yaml"}
+	for i, spec := range loadExampleSpecs(t, paths...) {
+		before := NewAPAIValidator()
+		before.ValidateSpec(spec)
+		after := NewAPAIValidator()
+		after.ValidateSpec(Normalize(spec))
+		if !reflect.DeepEqual(after.Errors, before.Errors) {
+			t.Errorf("%s: normalizing changed the errors from %v to %v", paths[i], before.Errors, after.Errors)
+		}
+		if len(after.Warnings) > len(before.Warnings) {
+			t.Errorf("%s: normalizing added warnings: %v, before %v", paths[i], after.Warnings, before.Warnings)
+		}
+	}
+}
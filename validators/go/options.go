@@ -0,0 +1,187 @@
+package main
+
+import (
+	"io/fs"
+	"time"
+)
+
+// Option configures an APAIValidator
+type Option func(*APAIValidator)
+
+// WithConfig replaces the validator configuration; options given after it
+// adjust the replaced configuration
+func WithConfig(config Config) Option {
+	return func(v *APAIValidator) {
+		v.Config = config
+	}
+}
+
+// WithMaxInheritanceDepth sets how many levels of parents a spec may have
+func WithMaxInheritanceDepth(depth int) Option {
+	return func(v *APAIValidator) {
+		v.Config.MaxInheritanceDepth = depth
+	}
+}
+
+// WithMaxInheritedSpecs sets the total number of parents loaded for a spec
+func WithMaxInheritedSpecs(count int) Option {
+	return func(v *APAIValidator) {
+		v.Config.MaxInheritedSpecs = count
+	}
+}
+
+// WithFailLevel sets which findings make validation fail
+func WithFailLevel(level FailLevel) Option {
+	return func(v *APAIValidator) {
+		v.Config.FailOn = level
+	}
+}
+
+// WithStrictFields sets whether fields the specification does not define
+// are errors. Extension fields starting with x- are always allowed.
+func WithStrictFields(enabled bool) Option {
+	return func(v *APAIValidator) {
+		v.Config.StrictFields = enabled
+	}
+}
+
+// WithFileChecks sets whether the datasets and knowledge sources a
+// specification references must exist, relative to the specification file
+func WithFileChecks(enabled bool) Option {
+	return func(v *APAIValidator) {
+		v.Config.CheckFiles = enabled
+	}
+}
+
+// WithURLChecks sets whether file checks also send a HEAD request to URL
+// sources; it has no effect without WithFileChecks
+func WithURLChecks(enabled bool) Option {
+	return func(v *APAIValidator) {
+		v.Config.CheckURLs = enabled
+	}
+}
+
+// WithParentValidation sets whether ValidateWithInheritance also validates
+// each inherited specification, merged with its own parents, reporting
+// its findings prefixed with "In parent <file>: "
+func WithParentValidation(enabled bool) Option {
+	return func(v *APAIValidator) {
+		v.Config.ValidateParents = enabled
+	}
+}
+
+// WithComplianceProfiles enforces the requirements of the given profiles,
+// loaded with LoadComplianceProfile or LoadComplianceProfileFile
+func WithComplianceProfiles(profiles ...*ComplianceProfile) Option {
+	return func(v *APAIValidator) {
+		v.compliance = append(v.compliance, profiles...)
+	}
+}
+
+// WithPlugins runs the given executables on every specification as
+// external rules; see PluginIssue for the protocol
+func WithPlugins(paths ...string) Option {
+	return func(v *APAIValidator) {
+		v.plugins = append(v.plugins, paths...)
+	}
+}
+
+// WithPluginTimeout sets how long each plugin may run on a specification
+// (default: 10s)
+func WithPluginTimeout(timeout time.Duration) Option {
+	return func(v *APAIValidator) {
+		v.pluginTimeout = timeout
+	}
+}
+
+// WithEnvSubstitution replaces ${VAR} placeholders in string values with
+// the values lookup returns, such as EnvLookup(nil), before validating.
+// Unresolved placeholders are errors, or warnings when allowMissing is set.
+func WithEnvSubstitution(lookup func(string) (string, bool), allowMissing bool) Option {
+	return func(v *APAIValidator) {
+		v.envLookup = lookup
+		v.allowMissingEnv = allowMissing
+	}
+}
+
+// WithJSONSchemas validates specifications against external JSON Schemas,
+// loaded with LoadJSONSchema, in addition to the built-in checks
+func WithJSONSchemas(schemas ...*JSONSchema) Option {
+	return func(v *APAIValidator) {
+		v.schemas = append(v.schemas, schemas...)
+	}
+}
+
+// WithApprovedModels reports the models of a specification missing from a
+// list loaded with LoadApprovedModels
+func WithApprovedModels(models *ApprovedModels) Option {
+	return func(v *APAIValidator) {
+		v.approvedModels = models
+	}
+}
+
+// WithWorkspace resolves spec-id#entity-id references against the members
+// of a workspace loaded with LoadWorkspace
+func WithWorkspace(workspace *Workspace) Option {
+	return func(v *APAIValidator) {
+		v.workspace = workspace
+	}
+}
+
+// WithRelaxedCodes reports the errors with the given codes as warnings,
+// like relax in the configuration file
+func WithRelaxedCodes(codes ...string) Option {
+	return func(v *APAIValidator) {
+		v.Config.Relax = append(v.Config.Relax, codes...)
+	}
+}
+
+// WithPromotedCodes reports the warnings with the given codes as errors,
+// like promote in the configuration file
+func WithPromotedCodes(codes ...string) Option {
+	return func(v *APAIValidator) {
+		v.Config.Promote = append(v.Config.Promote, codes...)
+	}
+}
+
+// WithInputFormat parses the specification files given to ValidateFile
+// and ValidateWithInheritance as format, yaml or json, whatever their
+// extension. Inherited files are still parsed by extension.
+func WithInputFormat(format string) Option {
+	return func(v *APAIValidator) {
+		v.inputFormat = format
+	}
+}
+
+// WithParallelValidation sets whether section validators run concurrently
+func WithParallelValidation(enabled bool) Option {
+	return func(v *APAIValidator) {
+		v.parallel = enabled
+	}
+}
+
+// WithFS reads specifications and inherited files from fsys instead of the
+// OS filesystem. Paths, including inherits, are resolved with slash
+// semantics as fs.FS requires.
+func WithFS(fsys fs.FS) Option {
+	return func(v *APAIValidator) {
+		v.fsys = fsys
+	}
+}
+
+// WithSpecCache keeps parsed and merged specifications in cache, so that
+// later validators skip parsing and merging the files that did not change
+func WithSpecCache(cache *SpecCache) Option {
+	return func(v *APAIValidator) {
+		v.cache = cache
+	}
+}
+
+// WithIssueHandler delivers each finding to handler as soon as it is
+// produced. The handler is called from the goroutine running the
+// validation, never concurrently, and does not affect the final result.
+func WithIssueHandler(handler func(Issue)) Option {
+	return func(v *APAIValidator) {
+		v.issueHandler = handler
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// overrideSections are the sections whose elements are identified by id.
+// The merge does not match them by id: a section replaces the same
+// section of earlier parents as a whole.
+var overrideSections = []string{"models", "prompts", "constraints", "tasks"}
+
+// sectionDefinition is an id-keyed section and the specification that
+// defines it, with its elements by id
+type sectionDefinition struct {
+	path     string
+	elements map[string]map[string]interface{}
+}
+
+// inheritedParent is a loaded parent of a specification
+type inheritedParent struct {
+	spec map[string]interface{}
+	path string
+}
+
+// loadedParents returns the parents of a specification that were loaded
+// for the current run, in inherits order, as mergeInheritedChain merges them
+func (v *APAIValidator) loadedParents(spec map[string]interface{}, specPath string, chain []string) []inheritedParent {
+	inherits, _ := spec["inherits"].([]interface{})
+	duplicates := v.duplicateInherits(inherits, specPath)
+	parents := make([]inheritedParent, 0, len(inherits))
+	for i, inheritPath := range inherits {
+		inheritPathStr, ok := inheritPath.(string)
+		if !ok || duplicates[i] {
+			continue
+		}
+		resolvedPath, err := v.resolveInheritancePath(inheritPathStr, specPath)
+		if err != nil || containsString(chain, resolvedPath) {
+			continue
+		}
+		if parentSpec, loaded := v.inheritedSpecs[resolvedPath]; loaded {
+			parents = append(parents, inheritedParent{spec: parentSpec, path: resolvedPath})
+		}
+	}
+	return parents
+}
+
+// sectionDefinitions returns the id-keyed sections a specification ends up
+// with after merging its parents, along with the file each comes from.
+// Like the merge, a section replaces the same section of earlier parents
+// as a whole.
+func (v *APAIValidator) sectionDefinitions(spec map[string]interface{}, specPath string, chain []string, memo map[string]map[string]sectionDefinition) map[string]sectionDefinition {
+	if definitions, exists := memo[specPath]; exists {
+		return definitions
+	}
+	definitions := make(map[string]sectionDefinition)
+	for _, parent := range v.loadedParents(spec, specPath, chain) {
+		nextChain := append(append(make([]string, 0, len(chain)+1), chain...), parent.path)
+		for section, definition := range v.sectionDefinitions(parent.spec, parent.path, nextChain, memo) {
+			definitions[section] = definition
+		}
+	}
+	for section, definition := range ownSections(spec, specPath) {
+		definitions[section] = definition
+	}
+	memo[specPath] = definitions
+	return definitions
+}
+
+// ownSections returns the id-keyed sections a specification declares itself
+func ownSections(spec map[string]interface{}, specPath string) map[string]sectionDefinition {
+	definitions := make(map[string]sectionDefinition)
+	for _, section := range overrideSections {
+		elements, ok := spec[section].([]interface{})
+		if !ok {
+			continue
+		}
+		definition := sectionDefinition{path: specPath, elements: make(map[string]map[string]interface{})}
+		for _, element := range elements {
+			elementMap, ok := element.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if id, ok := elementMap["id"].(string); ok && id != "" {
+				definition.elements[id] = elementMap
+			}
+		}
+		definitions[section] = definition
+	}
+	return definitions
+}
+
+// validateOverrides reports hazards of merging a hierarchy: sections a
+// specification defines that drop elements of the inherited ones, sections
+// of two parents of which the later drops or redefines elements of the
+// earlier, and constraints whose severity a specification lowers. Each
+// specification of the hierarchy is checked against its own parents, and
+// findings about parents shared by several of them are reported once.
+func (v *APAIValidator) validateOverrides(spec map[string]interface{}, specPath string, chain []string, memo map[string]map[string]sectionDefinition) {
+	parents := v.loadedParents(spec, specPath, chain)
+	parentDefinitions := make([]map[string]sectionDefinition, 0, len(parents))
+	for _, parent := range parents {
+		nextChain := append(append(make([]string, 0, len(chain)+1), chain...), parent.path)
+		parentDefinitions = append(parentDefinitions, v.sectionDefinitions(parent.spec, parent.path, nextChain, memo))
+	}
+
+	own := ownSections(spec, specPath)
+	findings := sectionFindings{}
+	for _, section := range overrideSections {
+		inherited := make([]sectionDefinition, 0, len(parentDefinitions))
+		for _, definitions := range parentDefinitions {
+			if definition, exists := definitions[section]; exists {
+				inherited = append(inherited, definition)
+			}
+		}
+		if len(inherited) == 0 {
+			continue
+		}
+		applied := inherited[len(inherited)-1]
+
+		ownDefinition, defined := own[section]
+		if !defined {
+			// The section of the last parent supplying it replaces those of
+			// the others; parents sharing an ancestor supply the same one
+			for _, earlier := range inherited[:len(inherited)-1] {
+				if earlier.path == applied.path {
+					continue
+				}
+				if conflict := sectionConflict(section, earlier, applied); conflict != "" {
+					findings.addError("MERGE_CONFLICT", conflict)
+				}
+			}
+			continue
+		}
+
+		dropped := make([]string, 0)
+		seen := make(map[string]bool)
+		for _, definition := range inherited {
+			for _, id := range sortedElementIDs(definition.elements) {
+				entry := fmt.Sprintf("'%s' (%s)", id, definition.path)
+				if _, kept := ownDefinition.elements[id]; kept || seen[entry] {
+					continue
+				}
+				seen[entry] = true
+				dropped = append(dropped, entry)
+			}
+		}
+		if len(dropped) > 0 {
+			findings.addWarning("SHADOWED_SECTION", fmt.Sprintf("Shadowed section %s in %s: it replaces the inherited section as a whole, dropping %s", section, specPath, strings.Join(dropped, ", ")))
+		}
+
+		if section == "constraints" {
+			for _, id := range sortedElementIDs(ownDefinition.elements) {
+				parentElement, exists := applied.elements[id]
+				if !exists {
+					continue
+				}
+				severity, _ := ownDefinition.elements[id]["severity"].(string)
+				parentSeverity, _ := parentElement["severity"].(string)
+				if severityRank(severity) >= 0 && severityRank(severity) < severityRank(parentSeverity) {
+					findings.addWarning("SEVERITY_DOWNGRADE", fmt.Sprintf("Severity downgrade of constraint '%s' in %s: %s lowers it from %s (set in %s)", id, specPath, severity, parentSeverity, applied.path))
+				}
+			}
+		}
+	}
+	for _, conflict := range findings.Errors {
+		if !v.hasFinding(conflict) {
+			v.addError(findings.Codes[conflict], conflict)
+		}
+	}
+	for _, warning := range findings.Warnings {
+		if !v.hasFinding(warning) {
+			v.addWarning(findings.Codes[warning], warning)
+		}
+	}
+
+	for _, parent := range parents {
+		nextChain := append(append(make([]string, 0, len(chain)+1), chain...), parent.path)
+		v.validateOverrides(parent.spec, parent.path, nextChain, memo)
+	}
+}
+
+// sectionConflict describes the elements of an earlier parent's section
+// that the section of a later parent drops or defines differently, or
+// returns an empty string when it keeps them all
+func sectionConflict(section string, earlier, later sectionDefinition) string {
+	dropped := make([]string, 0)
+	redefined := make([]string, 0)
+	for _, id := range sortedElementIDs(earlier.elements) {
+		laterElement, exists := later.elements[id]
+		if !exists {
+			dropped = append(dropped, fmt.Sprintf("'%s'", id))
+		} else if !reflect.DeepEqual(earlier.elements[id], laterElement) {
+			redefined = append(redefined, fmt.Sprintf("'%s'", id))
+		}
+	}
+	changes := make([]string, 0, 2)
+	if len(dropped) > 0 {
+		changes = append(changes, "dropping "+strings.Join(dropped, ", "))
+	}
+	if len(redefined) > 0 {
+		changes = append(changes, "redefining "+strings.Join(redefined, ", "))
+	}
+	if len(changes) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("Merge conflict for %s between %s and %s: %s replaces the section as a whole, %s", section, earlier.path, later.path, later.path, strings.Join(changes, " and "))
+}
+
+// severityRank returns the position of a constraint severity from lowest
+// to highest, or -1 when it is unknown
+func severityRank(severity string) int {
+	canonical, ok := canonicalEnumValue(constraintSeverities, severity)
+	if !ok {
+		return -1
+	}
+	for rank, candidate := range constraintSeverities {
+		if candidate == canonical {
+			return rank
+		}
+	}
+	return -1
+}
+
+// sortedElementIDs returns the ids of the elements of a section in sorted order
+func sortedElementIDs(elements map[string]map[string]interface{}) []string {
+	ids := make([]string, 0, len(elements))
+	for id := range elements {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
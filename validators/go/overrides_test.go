@@ -0,0 +1,53 @@
+package main
+
+import "testing"
+
+func TestMergeOverrides(t *testing.T) {
+	tests := []struct {
+		path     string
+		errors   []string
+		warnings []string
+	}{
+		// The section of the later parent replaces that of the earlier, which
+		// loses the elements it does not list or defines differently
+		{"app.yaml", []string{"Merge conflict for constraints between testdata/overrides/security.yaml and testdata/overrides/privacy.yaml: testdata/overrides/privacy.yaml replaces the section as a whole, dropping 'no-secrets' and redefining 'no-pii'"}, nil},
+		{"team.yaml", nil, []string{
+			"Shadowed section prompts in testdata/overrides/team.yaml: it replaces the inherited section as a whole, dropping 'closing' (testdata/overrides/base.yaml)",
+			"Severity downgrade of constraint 'tone' in testdata/overrides/team.yaml: low lowers it from high (set in testdata/overrides/base.yaml)",
+		}},
+		// A section listing the elements of every parent drops none of them,
+		// so the copies it needs are not reported
+		{"resolved.yaml", nil, nil},
+	}
+	validator := NewAPAIValidator()
+	for _, tt := range tests {
+		if _, err := validator.ValidateWithInheritance("testdata/overrides/" + tt.path); err != nil {
+			t.Fatalf("%s: %v", tt.path, err)
+		}
+		check := func(findings, want []string) {
+			for _, message := range want {
+				if !containsString(findings, message) {
+					t.Errorf("%s: missing %q in %v", tt.path, message, findings)
+				}
+			}
+			for _, finding := range findings {
+				code := newIssue("warning", finding).Code
+				if (code == "MERGE_CONFLICT" || code == "SHADOWED_SECTION" || code == "SEVERITY_DOWNGRADE") && !containsString(want, finding) {
+					t.Errorf("%s: unexpected %q", tt.path, finding)
+				}
+			}
+		}
+		check(validator.Errors, tt.errors)
+		check(validator.Warnings, tt.warnings)
+	}
+
+	for message, code := range map[string]string{
+		"Merge conflict for constraints between a.yaml and b.yaml: b.yaml replaces the section as a whole, dropping 'no-pii'": "MERGE_CONFLICT",
+		"Shadowed section tasks in app.yaml: it replaces the inherited section as a whole, dropping 'triage' (b.yaml)":        "SHADOWED_SECTION",
+		"Severity downgrade of constraint 'tone' in app.yaml: low lowers it from high (set in b.yaml)":                        "SEVERITY_DOWNGRADE",
+	} {
+		if rule, ok := MatchRule(message); !ok || rule.Code != code {
+			t.Errorf("expected %s for %q, got %q", code, message, rule.Code)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// parameterRange is the interval of values a generation parameter accepts
+type parameterRange struct {
+	field     string
+	low, high float64
+}
+
+// parameterRanges are the generation parameters with a bounded range, as
+// providers accept them
+var parameterRanges = []parameterRange{
+	{"temperature", 0, 2},
+	{"top_p", 0, 1},
+	{"frequency_penalty", -2, 2},
+	{"presence_penalty", -2, 2},
+	{"max_tokens", 1, math.Inf(1)},
+}
+
+// promptParameterFields are the prompt fields overriding the generation
+// parameters of the model
+var promptParameterFields = []string{"config", "parameters"}
+
+// validateParameterRanges checks the generation parameters in block against
+// parameterRanges, naming owner and the block in errors. Values that are not
+// numbers are left to the numeric field checks.
+func validateParameterRanges(f *sectionFindings, parameters map[string]interface{}, owner, block string) {
+	for _, bound := range parameterRanges {
+		value, ok := numberValue(parameters[bound.field])
+		if !ok || value >= bound.low && value <= bound.high {
+			continue
+		}
+		expected := fmt.Sprintf("between %v and %v", bound.low, bound.high)
+		if math.IsInf(bound.high, 1) {
+			expected = fmt.Sprintf("at least %v", bound.low)
+		}
+		f.addError("PARAMETER_OUT_OF_RANGE", fmt.Sprintf("Parameter out of range for %s: %s.%s must be %s, got %v", owner, block, bound.field, expected, parameters[bound.field]))
+	}
+}
+
+// validateModelParameters checks the range of the generation parameters of
+// a model
+func validateModelParameters(f *sectionFindings, modelMap map[string]interface{}, modelIndex int) {
+	value, exists := modelMap["parameters"]
+	if !exists {
+		return
+	}
+	name := elementName(modelIndex, modelMap)
+	parameters, ok := value.(map[string]interface{})
+	if !ok {
+		f.addError("INVALID_TYPE", fmt.Sprintf("Model %s parameters must be an object", name))
+		return
+	}
+	validateParameterRanges(f, parameters, "model "+name, "parameters")
+}
+
+// validatePromptParameters checks the generation parameters a prompt
+// overrides in config or parameters with the same ranges as models
+func validatePromptParameters(f *sectionFindings, promptMap map[string]interface{}, promptIndex int) {
+	name := elementName(promptIndex, promptMap)
+	for _, field := range promptParameterFields {
+		value, exists := promptMap[field]
+		if !exists {
+			continue
+		}
+		parameters, ok := value.(map[string]interface{})
+		if !ok {
+			f.addError("INVALID_TYPE", fmt.Sprintf("Prompt %s %s must be an object", name, field))
+			continue
+		}
+		validateParameterRanges(f, parameters, "prompt "+name, field)
+	}
+}
+
+// validateContextWindows checks the max_tokens requested from models that
+// declare a context_window: in their own parameters, and through the prompt
+// overrides of the steps running them. A request beyond the window fails at
+// runtime.
+func (v *APAIValidator) validateContextWindows(spec map[string]interface{}) {
+	windows := make(map[string]float64)
+	objectsAt(spec, "models[]", "", func(modelMap map[string]interface{}, _ string) {
+		id, _ := modelMap["id"].(string)
+		window, ok := numberValue(modelMap["context_window"])
+		if id == "" || !ok || window <= 0 {
+			return
+		}
+		windows[id] = window
+		parameters, _ := modelMap["parameters"].(map[string]interface{})
+		if maxTokens, ok := numberValue(parameters["max_tokens"]); ok && maxTokens > window {
+			v.addError("MAX_TOKENS_EXCEEDS_CONTEXT_WINDOW", fmt.Sprintf("Model %s requests max_tokens %v, beyond its context_window of %v", id, maxTokens, window))
+		}
+	})
+	if len(windows) == 0 {
+		return
+	}
+
+	prompts := make(map[string]map[string]interface{})
+	objectsAt(spec, "prompts[]", "", func(promptMap map[string]interface{}, _ string) {
+		if id, ok := promptMap["id"].(string); ok {
+			prompts[id] = promptMap
+		}
+	})
+	objectsAt(spec, "tasks[].steps[]", "", func(step map[string]interface{}, location string) {
+		modelID, _ := step["model"].(string)
+		window, ok := windows[modelID]
+		if !ok {
+			return
+		}
+		promptID, _ := step["prompt"].(string)
+		for _, field := range promptParameterFields {
+			parameters, _ := prompts[promptID][field].(map[string]interface{})
+			if maxTokens, ok := numberValue(parameters["max_tokens"]); ok && maxTokens > window {
+				v.addError("MAX_TOKENS_EXCEEDS_CONTEXT_WINDOW", fmt.Sprintf("Step %s requests max_tokens %v from model %s through prompt %s %s, beyond its context_window of %v", location, maxTokens, modelID, promptID, field, window))
+			}
+		}
+	})
+}
@@ -0,0 +1,97 @@
+package main
+
+import "testing"
+
+func TestParameterRanges(t *testing.T) {
+	spec := map[string]interface{}{
+		"models": []interface{}{
+			map[string]interface{}{"id": "primary", "type": "LLM", "provider": "OpenAI", "name": "gpt-4o", "purpose": "support",
+				"parameters": map[string]interface{}{"temperature": 2.5, "top_p": 0.9, "max_tokens": 0}},
+		},
+		"prompts": []interface{}{
+			map[string]interface{}{"id": "greeting", "role": "system", "template": "Hello",
+				"config": map[string]interface{}{"temperature": 0.7, "top_p": 1.5}},
+			map[string]interface{}{"id": "creative", "role": "system", "template": "Write",
+				"parameters": map[string]interface{}{"temperature": -1, "presence_penalty": 3}},
+			map[string]interface{}{"id": "broken", "role": "system", "template": "Hi", "config": "hot"},
+		},
+	}
+
+	validator := NewAPAIValidator()
+	validator.ValidateSpec(spec)
+	want := map[string]string{
+		"Parameter out of range for model primary: parameters.temperature must be between 0 and 2, got 2.5":       "PARAMETER_OUT_OF_RANGE",
+		"Parameter out of range for model primary: parameters.max_tokens must be at least 1, got 0":               "PARAMETER_OUT_OF_RANGE",
+		"Parameter out of range for prompt greeting: config.top_p must be between 0 and 1, got 1.5":               "PARAMETER_OUT_OF_RANGE",
+		"Parameter out of range for prompt creative: parameters.temperature must be between 0 and 2, got -1":      "PARAMETER_OUT_OF_RANGE",
+		"Parameter out of range for prompt creative: parameters.presence_penalty must be between -2 and 2, got 3": "PARAMETER_OUT_OF_RANGE",
+		"Prompt broken config must be an object":                                                                  "INVALID_TYPE",
+	}
+	for message, code := range want {
+		if !containsString(validator.Errors, message) {
+			t.Errorf("missing %q in %v", message, validator.Errors)
+		}
+		if rule, _ := MatchRule(message); rule.Code != code {
+			t.Errorf("expected %s for %q, got %q", code, message, rule.Code)
+		}
+	}
+	// Values within range, such as temperature 0.7 and top_p 0.9, pass
+	count := 0
+	for _, message := range validator.Errors {
+		if rule, _ := MatchRule(message); rule.Code == "PARAMETER_OUT_OF_RANGE" {
+			count++
+		}
+	}
+	if count != 5 {
+		t.Errorf("expected 5 out of range parameters, got %d in %v", count, validator.Errors)
+	}
+}
+
+func TestContextWindows(t *testing.T) {
+	spec := map[string]interface{}{
+		"models": []interface{}{
+			map[string]interface{}{"id": "fast", "type": "LLM", "provider": "OpenAI", "name": "gpt-4o-mini", "purpose": "support",
+				"context_window": 8192, "parameters": map[string]interface{}{"max_tokens": 16000}},
+			map[string]interface{}{"id": "large", "type": "LLM", "provider": "OpenAI", "name": "gpt-4o", "purpose": "support",
+				"context_window": 128000},
+		},
+		"prompts": []interface{}{
+			map[string]interface{}{"id": "summary", "role": "system", "template": "Summarize",
+				"config": map[string]interface{}{"max_tokens": 10000}},
+			map[string]interface{}{"id": "short", "role": "system", "template": "Answer",
+				"parameters": map[string]interface{}{"max_tokens": 512}},
+		},
+		"tasks": []interface{}{
+			map[string]interface{}{"id": "digest", "description": "Digest", "steps": []interface{}{
+				map[string]interface{}{"action": "generate", "model": "fast", "prompt": "short"},
+				map[string]interface{}{"action": "generate", "model": "fast", "prompt": "summary"},
+				map[string]interface{}{"action": "generate", "model": "large", "prompt": "summary"},
+			}},
+		},
+	}
+
+	validator := NewAPAIValidator()
+	validator.ValidateSpec(spec)
+	want := []string{
+		"Model fast requests max_tokens 16000, beyond its context_window of 8192",
+		"Step tasks[0].steps[1] requests max_tokens 10000 from model fast through prompt summary config, beyond its context_window of 8192",
+	}
+	for _, message := range want {
+		if !containsString(validator.Errors, message) {
+			t.Errorf("missing %q in %v", message, validator.Errors)
+		}
+		if rule, _ := MatchRule(message); rule.Code != "MAX_TOKENS_EXCEEDS_CONTEXT_WINDOW" {
+			t.Errorf("expected MAX_TOKENS_EXCEEDS_CONTEXT_WINDOW for %q, got %q", message, rule.Code)
+		}
+	}
+	// Requests within the window, such as 10000 tokens from large, pass
+	count := 0
+	for _, message := range validator.Errors {
+		if rule, _ := MatchRule(message); rule.Code == "MAX_TOKENS_EXCEEDS_CONTEXT_WINDOW" {
+			count++
+		}
+	}
+	if count != len(want) {
+		t.Errorf("expected %d requests beyond the context window, got %d in %v", len(want), count, validator.Errors)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// parentFindingPattern matches the prefix attributing a finding to an
+// inherited specification: "In parent base.yaml: message"
+var parentFindingPattern = regexp.MustCompile(`^In parent .+?: `)
+
+// unattributed returns a finding without the prefix naming the parent
+// specification it was found in, as its rule reports it
+func unattributed(message string) string {
+	return parentFindingPattern.ReplaceAllString(message, "")
+}
+
+// validateParents validates each specification the last resolved spec
+// inherits from, merged with its own parents, and adds their findings
+// prefixed with the parent file. Findings of resolving the inheritance of
+// the spec itself, such as a missing grandparent, are not repeated.
+func (v *APAIValidator) validateParents(ctx context.Context, inheritanceErrors, inheritanceWarnings []string) error {
+	parents := make([]string, 0, len(v.inheritance.explored))
+	for parentPath := range v.inheritance.explored {
+		parents = append(parents, parentPath)
+	}
+	// Nearest parents first
+	sort.Slice(parents, func(i, j int) bool {
+		depthI, depthJ := v.inheritance.explored[parents[i]], v.inheritance.explored[parents[j]]
+		if depthI != depthJ {
+			return depthI < depthJ
+		}
+		return parents[i] < parents[j]
+	})
+
+	for _, parentPath := range parents {
+		run := *v
+		run.inheritedSpecs = make(map[string]map[string]interface{})
+		run.mergeCache = make(map[string]map[string]interface{})
+		run.issueHandler = nil
+		run.inputFormat = ""
+		run.envSubstituted = nil
+		run.Config.ValidateParents = false
+
+		if _, err := run.ValidateWithInheritanceContext(ctx, parentPath); err != nil {
+			if ctx.Err() != nil {
+				return fmt.Errorf("validating parent %s: %w", parentPath, err)
+			}
+			v.Errors = append(v.Errors, fmt.Sprintf("In parent %s: %v", parentPath, err))
+			continue
+		}
+		v.mergeCodes(run.codes)
+		for _, message := range run.Errors {
+			if !containsString(inheritanceErrors, message) {
+				v.Errors = append(v.Errors, fmt.Sprintf("In parent %s: %s", parentPath, message))
+			}
+		}
+		for _, message := range run.Warnings {
+			if !containsString(inheritanceWarnings, message) {
+				v.Warnings = append(v.Warnings, fmt.Sprintf("In parent %s: %s", parentPath, message))
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestValidateParents(t *testing.T) {
+	base, err := embeddedSpecs.ReadFile("testdata/embedded/org/base.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys := fstest.MapFS{
+		"org/base.yaml": {Data: base},
+		"team/team.yaml": {Data: []byte("inherits: [\"../org/base.yaml\"]\n" +
+			"info:\n  ai_metadata:\n    complexity: \"extreme\"\n" +
+			"constraints: \"none\"\n")},
+		"team/app.yaml": {Data: []byte("inherits: [\"team.yaml\"]\n" +
+			"constraints:\n  - id: \"safety_constraint\"\n    name: \"Content Safety\"\n    type: \"content_safety\"\n" +
+			"    rule: \"output NOT contains harmful_content\"\n    severity: \"critical\"\n")},
+	}
+
+	// Without parent validation, the constraints the child replaces go unnoticed
+	validator := NewAPAIValidator(WithFS(fsys))
+	if _, err := validator.ValidateWithInheritance("team/app.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	complexity := "Invalid complexity: extreme"
+	if !containsString(validator.Errors, complexity) || len(validator.Errors) != 1 {
+		t.Errorf("expected only %q, got %v", complexity, validator.Errors)
+	}
+
+	validator = NewAPAIValidator(WithFS(fsys), WithParentValidation(true))
+	if _, err := validator.ValidateWithInheritance("team/app.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		complexity,
+		"In parent team/team.yaml: " + complexity,
+		"In parent team/team.yaml: constraints must be an array",
+	} {
+		if !containsString(validator.Errors, want) {
+			t.Errorf("missing %q in %v", want, validator.Errors)
+		}
+	}
+	if len(validator.Errors) != 3 {
+		t.Errorf("expected no findings in the valid base, got %v", validator.Errors)
+	}
+	if issue := newIssue("error", "In parent team/team.yaml: "+complexity); issue.Code != "INVALID_ENUM" {
+		t.Errorf("expected the code of the finding, got %q", issue.Code)
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// persistenceBlocks are the context blocks configuring where conversation
+// state is stored
+var persistenceBlocks = []string{"persistence", "session"}
+
+// persistenceFields are the fields of a persistence block
+var persistenceFields = []string{"enabled", "store", "dsn", "url", "path", "ttl"}
+
+// maxPrivacyRetention is the longest ttl a specification with a privacy
+// constraint on retention keeps state for without a warning
+const maxPrivacyRetention = 90 * 24 * time.Hour
+
+// dayDurationPattern matches a duration in days or weeks, such as "30d",
+// which time.ParseDuration does not accept
+var dayDurationPattern = regexp.MustCompile(`^(\d+(?:\.\d+)?)(d|w)$`)
+
+// persistenceTTLPaths are the ttl fields that accept a duration string as
+// well as the number of seconds ttl fields hold elsewhere
+var persistenceTTLPaths = []string{"context.persistence.ttl", "context.session.ttl"}
+
+// parseRetention parses a ttl given in seconds, written as a Go duration
+// such as "24h", or in days or weeks such as "30d" or "2w"
+func parseRetention(ttl interface{}) (time.Duration, bool) {
+	if seconds, ok := numberValue(ttl); ok {
+		return time.Duration(seconds * float64(time.Second)), true
+	}
+	value, _ := ttl.(string)
+	if match := dayDurationPattern.FindStringSubmatch(value); match != nil {
+		amount, err := strconv.ParseFloat(match[1], 64)
+		if err != nil {
+			return 0, false
+		}
+		if match[2] == "w" {
+			amount *= 7
+		}
+		return time.Duration(amount * float64(24*time.Hour)), true
+	}
+	duration, err := time.ParseDuration(value)
+	return duration, err == nil
+}
+
+// persistenceEnabled reports whether a persistence block stores state: it
+// does unless enabled is false
+func persistenceEnabled(block map[string]interface{}) bool {
+	enabled, ok := block["enabled"].(bool)
+	return !ok || enabled
+}
+
+// validatePersistence validates context.persistence and context.session:
+// a known store with the connection settings it needs, a positive ttl, no
+// literal password in a DSN, and a memory type other than none when state
+// is persisted
+func (v *APAIValidator) validatePersistence(f *sectionFindings, contextMap map[string]interface{}) {
+	memory, _ := contextMap["memory"].(map[string]interface{})
+	memoryType, _ := memory["type"].(string)
+
+	for _, name := range persistenceBlocks {
+		value, exists := contextMap[name]
+		if !exists {
+			continue
+		}
+		location := "context." + name
+		block, ok := value.(map[string]interface{})
+		if !ok {
+			f.addError("INVALID_TYPE", fmt.Sprintf("%s must be an object", location))
+			continue
+		}
+
+		if enabled, exists := block["enabled"]; exists {
+			if _, ok := enabled.(bool); !ok && !isStringValue(enabled) {
+				f.addError("INVALID_TYPE", fmt.Sprintf("%s enabled must be a boolean", location))
+			}
+		}
+		if persistenceEnabled(block) && strings.EqualFold(memoryType, "none") {
+			f.addError("PERSISTENCE_WITHOUT_MEMORY", fmt.Sprintf("%s is enabled but context.memory.type is none", location))
+		}
+
+		if ttl, exists := block["ttl"]; exists {
+			if duration, ok := parseRetention(ttl); !ok || duration <= 0 {
+				f.addError("INVALID_TTL", fmt.Sprintf("%s ttl must be a positive duration such as \"24h\" or \"30d\", or a number of seconds, got %v", location, ttl))
+			}
+		}
+
+		for _, field := range []string{"dsn", "url"} {
+			connection, ok := block[field].(string)
+			fieldLocation := joinLocation(location, field)
+			if !ok || isSecretReference(connection) || v.envSubstituted[fieldLocation] {
+				continue
+			}
+			if parsed, err := url.Parse(connection); err == nil && parsed.User != nil {
+				if password, hasPassword := parsed.User.Password(); hasPassword && !isSecretReference(password) {
+					f.addWarning("HARDCODED_SECRET", fmt.Sprintf("%s %s embeds a literal password, use an ${ENV} reference or vault:// placeholder", location, field))
+				}
+			}
+		}
+
+		storeValue, exists := block["store"]
+		if !exists {
+			f.addError("MISSING_FIELD", fmt.Sprintf("%s missing required field: store", location))
+			continue
+		}
+		store, ok := storeValue.(string)
+		if !ok {
+			f.addError("INVALID_TYPE", fmt.Sprintf("%s store must be a string", location))
+			continue
+		}
+		canonical, valid := matchEnum(f, persistenceStores, store, joinLocation(location, "store"))
+		if !valid {
+			f.addError("INVALID_ENUM", fmt.Sprintf("Invalid persistence store: %s in %s (expected %s)", store, location, strings.Join(persistenceStores, ", ")))
+			continue
+		}
+		set := func(field string) bool {
+			value, ok := block[field].(string)
+			return ok && strings.TrimSpace(value) != ""
+		}
+		switch canonical {
+		case "redis", "postgres":
+			if !set("dsn") && !set("url") {
+				f.addError("MISSING_FIELD", fmt.Sprintf("%s missing required field: dsn or url (for the %s store)", location, canonical))
+			}
+		case "file":
+			if !set("path") {
+				f.addError("MISSING_FIELD", fmt.Sprintf("%s missing required field: path (for the file store)", location))
+			}
+		}
+	}
+}
+
+// validatePersistenceRetention warns when persisted state outlives 90 days
+// while a privacy constraint of the specification concerns retention
+func (v *APAIValidator) validatePersistenceRetention(spec map[string]interface{}) {
+	constraint := ""
+	constraints, _ := spec["constraints"].([]interface{})
+	for index, item := range constraints {
+		constraintMap, _ := item.(map[string]interface{})
+		constraintType, _ := constraintMap["type"].(string)
+		if !strings.EqualFold(constraintType, "privacy") {
+			continue
+		}
+		for _, field := range []string{"name", "rule", "description"} {
+			text, _ := constraintMap[field].(string)
+			if strings.Contains(strings.ToLower(text), "retention") {
+				constraint = elementName(index, constraintMap)
+			}
+		}
+		if constraint != "" {
+			break
+		}
+	}
+	if constraint == "" {
+		return
+	}
+
+	contextMap, _ := spec["context"].(map[string]interface{})
+	for _, name := range persistenceBlocks {
+		block, ok := contextMap[name].(map[string]interface{})
+		if !ok || !persistenceEnabled(block) {
+			continue
+		}
+		ttl := block["ttl"]
+		if duration, ok := parseRetention(ttl); ok && duration > maxPrivacyRetention {
+			v.addWarning("LONG_RETENTION", fmt.Sprintf("context.%s ttl of %v exceeds 90 days while privacy constraint %s concerns retention", name, ttl, constraint))
+		}
+	}
+}
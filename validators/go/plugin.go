@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultPluginTimeout is how long a plugin may run on a specification
+const defaultPluginTimeout = 10 * time.Second
+
+// pluginOutputLimit is the most output a plugin may write, in bytes
+const pluginOutputLimit = 1 << 20
+
+// pluginCodePattern matches the codes plugin issues may use
+var pluginCodePattern = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+
+// pluginMessagePattern matches the findings of plugins, which carry their
+// own code: "[ORG_RULE] models[0].name: message (plugin org-rules)"
+var pluginMessagePattern = regexp.MustCompile(`^\[([A-Z][A-Z0-9_]*)\] .* \(plugin [^()]+\)$`)
+
+// PluginIssue is a finding reported by a plugin
+type PluginIssue struct {
+	Code     string `json:"code"`
+	Severity string `json:"severity"`
+	// Path locates the finding in the specification, e.g. models[0].name
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// pluginCode returns the code of a plugin finding, or "" for other messages
+func pluginCode(message string) string {
+	if match := pluginMessagePattern.FindStringSubmatch(message); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+// runPlugins runs every plugin on the specification concurrently and adds
+// their findings, in plugin order. A plugin that fails, times out or
+// answers with anything but a JSON array of issues is reported as an error.
+func (v *APAIValidator) runPlugins(ctx context.Context, spec map[string]interface{}) {
+	input, err := MarshalCanonicalJSON(spec)
+	if err != nil {
+		v.addError("PLUGIN_FAILED", fmt.Sprintf("Plugins not run: %v", err))
+		return
+	}
+
+	findings := make([]sectionFindings, len(v.plugins))
+	var wg sync.WaitGroup
+	for i, plugin := range v.plugins {
+		wg.Add(1)
+		go func(i int, plugin string) {
+			defer wg.Done()
+			findings[i] = v.runPlugin(ctx, plugin, input)
+		}(i, plugin)
+	}
+	wg.Wait()
+
+	for _, f := range findings {
+		v.appendFindings(f)
+	}
+}
+
+// runPlugin runs a single plugin, writing the specification to its stdin
+// and reading its issues from stdout
+func (v *APAIValidator) runPlugin(ctx context.Context, plugin string, input []byte) sectionFindings {
+	f := sectionFindings{}
+	name := filepath.Base(plugin)
+	timeout := v.pluginTimeout
+	if timeout <= 0 {
+		timeout = defaultPluginTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	stdout, stderr, err := runLimited(ctx, plugin, input, pluginOutputLimit)
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		f.addError("PLUGIN_FAILED", fmt.Sprintf("Plugin %s failed: timed out after %s", name, timeout))
+		return f
+	case stdout.exceeded:
+		f.addError("PLUGIN_FAILED", fmt.Sprintf("Plugin %s failed: output exceeds %d bytes", name, pluginOutputLimit))
+		return f
+	case err != nil:
+		if detail := strings.TrimSpace(stderr.String()); detail != "" {
+			err = fmt.Errorf("%v: %s", err, detail)
+		}
+		f.addError("PLUGIN_FAILED", fmt.Sprintf("Plugin %s failed: %v", name, err))
+		return f
+	}
+
+	var issues []PluginIssue
+	if err := json.Unmarshal(stdout.Bytes(), &issues); err != nil {
+		f.addError("PLUGIN_FAILED", fmt.Sprintf("Plugin %s failed: invalid output: %v", name, err))
+		return f
+	}
+	for _, issue := range issues {
+		if !pluginCodePattern.MatchString(issue.Code) || issue.Message == "" {
+			f.addError("PLUGIN_FAILED", fmt.Sprintf("Plugin %s failed: invalid issue %+v: code must be upper case and message non-empty", name, issue))
+			continue
+		}
+		location := ""
+		if issue.Path != "" {
+			location = issue.Path + ": "
+		}
+		message := fmt.Sprintf("[%s] %s%s (plugin %s)", issue.Code, location, issue.Message, name)
+		switch issue.Severity {
+		case "error":
+			f.addError(issue.Code, message)
+		case "warning":
+			f.addWarning(issue.Code, message)
+		default:
+			f.addError("PLUGIN_FAILED", fmt.Sprintf("Plugin %s failed: invalid severity %q for %s", name, issue.Severity, issue.Code))
+		}
+	}
+	return f
+}
+
+// runLimited runs an executable with input on stdin, collecting at most
+// limit bytes of its stdout and stderr. It reads through its own pipes so
+// that it returns once ctx is done, even when the process left children
+// holding them open.
+func runLimited(ctx context.Context, executable string, input []byte, limit int) (*limitedBuffer, *limitedBuffer, error) {
+	stdout, stderr := &limitedBuffer{limit: limit}, &limitedBuffer{limit: limit}
+	cmd := exec.CommandContext(ctx, executable)
+	cmd.Stdin = bytes.NewReader(input)
+
+	var readers, writers [2]*os.File
+	for i := range readers {
+		reader, writer, err := os.Pipe()
+		if err != nil {
+			return stdout, stderr, err
+		}
+		defer reader.Close()
+		defer writer.Close()
+		readers[i], writers[i] = reader, writer
+	}
+	cmd.Stdout, cmd.Stderr = writers[0], writers[1]
+
+	if err := cmd.Start(); err != nil {
+		return stdout, stderr, err
+	}
+	// The process holds the write ends now; closing ours lets reads end
+	for _, writer := range writers {
+		writer.Close()
+	}
+
+	var wg sync.WaitGroup
+	for i, buffer := range []*limitedBuffer{stdout, stderr} {
+		wg.Add(1)
+		go func(reader *os.File, buffer *limitedBuffer) {
+			defer wg.Done()
+			// Past the limit, closing the pipe stops the writer
+			if _, err := io.Copy(buffer, reader); err != nil {
+				reader.Close()
+			}
+		}(readers[i], buffer)
+	}
+
+	err := cmd.Wait()
+	copied := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(copied)
+	}()
+	select {
+	case <-copied:
+	case <-ctx.Done():
+		for _, reader := range readers {
+			reader.Close()
+		}
+		<-copied
+	}
+	return stdout, stderr, err
+}
+
+// limitedBuffer collects output up to a limit, failing writes beyond it so
+// a runaway process is stopped
+type limitedBuffer struct {
+	bytes.Buffer
+	limit    int
+	exceeded bool
+}
+
+// Write appends p unless the buffer would grow past its limit
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > b.limit {
+		b.exceeded = true
+		return 0, fmt.Errorf("output exceeds %d bytes", b.limit)
+	}
+	return b.Buffer.Write(p)
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// buildExamplePlugin compiles the example plugin of the test fixtures
+func buildExamplePlugin(t *testing.T) string {
+	t.Helper()
+	goTool := filepath.Join(runtime.GOROOT(), "bin", "go")
+	plugin := filepath.Join(t.TempDir(), "provider-allowlist")
+	if runtime.GOOS == "windows" {
+		plugin += ".exe"
+	}
+	cmd := exec.Command(goTool, "build", "-o", plugin, "./testdata/plugins/provider-allowlist")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Skipf("cannot build the example plugin: %v\n%s", err, output)
+	}
+	return plugin
+}
+
+// writeScriptPlugin writes a shell script plugin
+func writeScriptPlugin(t *testing.T, name, script string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("script plugins need a POSIX shell")
+	}
+	plugin := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(plugin, []byte("#!/bin/sh\n"+script+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return plugin
+}
+
+func TestPluginFindingsMergeIntoResult(t *testing.T) {
+	plugin := buildExamplePlugin(t)
+	warner := writeScriptPlugin(t, "warner", `cat > /dev/null
+echo '[{"code": "ORG_NAMING", "severity": "warning", "message": "use kebab-case ids"}]'`)
+
+	spec := loadExampleSpecs(t, "core/customer-support.yaml")[0]
+	validator := NewAPAIValidator(WithPlugins(plugin, warner))
+	if validator.ValidateSpec(spec) {
+		t.Fatal("expected the plugin error to fail validation")
+	}
+
+	wantError := "[ORG_PROVIDER_NOT_APPROVED] models[1].provider: provider huggingface of model sentiment_analyzer is not approved (plugin provider-allowlist)"
+	if !containsString(validator.Errors, wantError) {
+		t.Errorf("missing %q in %v", wantError, validator.Errors)
+	}
+	wantWarning := "[ORG_NAMING] use kebab-case ids (plugin warner)"
+	if !containsString(validator.Warnings, wantWarning) {
+		t.Errorf("missing %q in %v", wantWarning, validator.Warnings)
+	}
+	if issue := newIssue("error", wantError); issue.Code != "ORG_PROVIDER_NOT_APPROVED" {
+		t.Errorf("expected the plugin code, got %q", issue.Code)
+	}
+
+	// Plugin findings are suppressed by baselines like any other
+	baseline := NewBaseline()
+	baseline.Add("spec.yaml", validator.GetResults())
+	if filtered, _ := baseline.Filter("spec.yaml", validator.GetResults()); len(filtered.Errors)+len(filtered.Warnings) != 0 {
+		t.Errorf("expected every finding to be suppressed, got %v", filtered)
+	}
+}
+
+func TestFailingPluginsAreErrors(t *testing.T) {
+	plugins := map[string]string{
+		"crash":   writeScriptPlugin(t, "crash", "echo boom >&2; exit 3"),
+		"hang":    writeScriptPlugin(t, "hang", "sleep 5"),
+		"garbage": writeScriptPlugin(t, "garbage", "cat > /dev/null; echo not json"),
+		"missing": filepath.Join(t.TempDir(), "missing"),
+	}
+	wants := map[string]string{
+		"crash":   "Plugin crash failed: exit status 3: boom",
+		"hang":    "Plugin hang failed: timed out after 200ms",
+		"garbage": "Plugin garbage failed: invalid output: ",
+		"missing": "Plugin missing failed: ",
+	}
+
+	spec := loadExampleSpecs(t, "core/customer-support.yaml")[0]
+	for name, plugin := range plugins {
+		t.Run(name, func(t *testing.T) {
+			validator := NewAPAIValidator(WithPlugins(plugin), WithPluginTimeout(200*time.Millisecond))
+			start := time.Now()
+			validator.ValidateSpec(spec)
+			if elapsed := time.Since(start); elapsed > 3*time.Second {
+				t.Errorf("plugin %s blocked validation for %v", name, elapsed)
+			}
+
+			found := false
+			for _, message := range validator.Errors {
+				found = found || strings.HasPrefix(message, wants[name])
+			}
+			if !found {
+				t.Errorf("missing %q in %v", wants[name], validator.Errors)
+			}
+			for _, message := range validator.Errors {
+				if strings.HasPrefix(message, "Plugin ") {
+					if rule, _ := MatchRule(message); rule.Code != "PLUGIN_FAILED" {
+						t.Errorf("expected PLUGIN_FAILED for %q", message)
+					}
+				}
+			}
+		})
+	}
+}
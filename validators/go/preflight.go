@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// providerEnvVars maps model providers to the environment variables their
+// SDKs conventionally read credentials from. Config.ProviderEnv replaces the
+// variables of a provider or adds providers.
+var providerEnvVars = map[string][]string{
+	"openai":       {"OPENAI_API_KEY"},
+	"anthropic":    {"ANTHROPIC_API_KEY"},
+	"azure-openai": {"AZURE_OPENAI_ENDPOINT", "AZURE_OPENAI_API_KEY"},
+	"google":       {"GOOGLE_API_KEY"},
+	"cohere":       {"COHERE_API_KEY"},
+	"mistral":      {"MISTRAL_API_KEY"},
+	"huggingface":  {"HF_TOKEN"},
+}
+
+// PreflightCheck is an environment variable a deployment of the
+// specification requires, and whether it is set
+type PreflightCheck struct {
+	Variable string `json:"variable"`
+	// RequiredBy names what needs the variable, e.g. "model main_model, provider openai"
+	RequiredBy string `json:"required_by"`
+	Set        bool   `json:"set"`
+}
+
+// Preflight lists the environment variables the models and MCP servers of
+// spec require and checks that each is set to a non-empty value: the
+// credentials of each model provider, and every ${VAR} an MCP server or an
+// enabled persistence block references. Providers without known variables need none.
+func (v *APAIValidator) Preflight(spec map[string]interface{}) []PreflightCheck {
+	checks := make([]PreflightCheck, 0)
+	add := func(variable, requiredBy string) {
+		for _, check := range checks {
+			if check.Variable == variable && check.RequiredBy == requiredBy {
+				return
+			}
+		}
+		value, _ := os.LookupEnv(variable)
+		checks = append(checks, PreflightCheck{Variable: variable, RequiredBy: requiredBy, Set: value != ""})
+	}
+
+	models, _ := spec["models"].([]interface{})
+	for index, model := range models {
+		modelMap, ok := model.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		provider, _ := modelMap["provider"].(string)
+		for _, variable := range v.providerEnv(provider) {
+			add(variable, fmt.Sprintf("model %s, provider %s", elementName(index, modelMap), provider))
+		}
+	}
+
+	contextMap, _ := spec["context"].(map[string]interface{})
+	servers, _ := contextMap["mcp_servers"].([]interface{})
+	for index, server := range servers {
+		serverMap, ok := server.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for _, variable := range envReferences(serverMap) {
+			add(variable, fmt.Sprintf("MCP server %s", elementName(index, serverMap)))
+		}
+	}
+
+	for _, name := range persistenceBlocks {
+		block, ok := contextMap[name].(map[string]interface{})
+		if !ok || !persistenceEnabled(block) {
+			continue
+		}
+		for _, variable := range envReferences(block) {
+			add(variable, "context."+name)
+		}
+	}
+	return checks
+}
+
+// providerEnv returns the environment variables of a provider, ignoring
+// case, taking the configured table over the built-in one
+func (v *APAIValidator) providerEnv(provider string) []string {
+	for name, variables := range v.Config.ProviderEnv {
+		if strings.EqualFold(name, provider) {
+			return variables
+		}
+	}
+	return providerEnvVars[strings.ToLower(provider)]
+}
+
+// envReferences returns the sorted names of the ${VAR} references in the
+// strings of a value
+func envReferences(value interface{}) []string {
+	names := make([]string, 0)
+	var collect func(interface{})
+	collect = func(value interface{}) {
+		switch typed := value.(type) {
+		case map[string]interface{}:
+			for _, item := range typed {
+				collect(item)
+			}
+		case []interface{}:
+			for _, item := range typed {
+				collect(item)
+			}
+		case string:
+			for _, match := range secretReferencePattern.FindAllString(typed, -1) {
+				if name := match[2 : len(match)-1]; !containsString(names, name) {
+					names = append(names, name)
+				}
+			}
+		}
+	}
+	collect(value)
+	sort.Strings(names)
+	return names
+}
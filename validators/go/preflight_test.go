@@ -0,0 +1,48 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPreflight(t *testing.T) {
+	spec := map[string]interface{}{
+		"models": []interface{}{
+			map[string]interface{}{"id": "main_model", "provider": "OpenAI"},
+			map[string]interface{}{"id": "backup_model", "provider": "azure-openai"},
+			map[string]interface{}{"id": "local_model", "provider": "custom"},
+		},
+		"context": map[string]interface{}{
+			"mcp_servers": []interface{}{
+				map[string]interface{}{
+					"id":             "crm",
+					"transport":      map[string]interface{}{"type": "sse", "url": "https://${CRM_HOST}/mcp"},
+					"authentication": map[string]interface{}{"type": "api_key", "api_key": "${CRM_API_KEY}"},
+				},
+			},
+		},
+	}
+	t.Setenv("OPENAI_API_KEY", "sk-test")
+	t.Setenv("AZURE_OPENAI_ENDPOINT", "")
+	t.Setenv("CRM_HOST", "crm.example.com")
+
+	checks := NewAPAIValidator().Preflight(spec)
+	want := []PreflightCheck{
+		{Variable: "OPENAI_API_KEY", RequiredBy: "model main_model, provider OpenAI", Set: true},
+		{Variable: "AZURE_OPENAI_ENDPOINT", RequiredBy: "model backup_model, provider azure-openai", Set: false},
+		{Variable: "AZURE_OPENAI_API_KEY", RequiredBy: "model backup_model, provider azure-openai", Set: false},
+		{Variable: "CRM_API_KEY", RequiredBy: "MCP server crm", Set: false},
+		{Variable: "CRM_HOST", RequiredBy: "MCP server crm", Set: true},
+	}
+	if !reflect.DeepEqual(checks, want) {
+		t.Errorf("unexpected checks:\n got %v\nwant %v", checks, want)
+	}
+
+	// The configured table overrides and extends the built-in one
+	config := DefaultConfig()
+	config.ProviderEnv = map[string][]string{"openai": {"OPENAI_KEY"}, "custom": {"LOCAL_MODEL_URL"}}
+	checks = NewAPAIValidator(WithConfig(config)).Preflight(spec)
+	if checks[0].Variable != "OPENAI_KEY" || checks[3].Variable != "LOCAL_MODEL_URL" {
+		t.Errorf("configured provider variables not used: %v", checks)
+	}
+}
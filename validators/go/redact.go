@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// redactedValue replaces redacted values
+const redactedValue = "<REDACTED>"
+
+// redactionsKey records the locations a redacted specification had redacted
+const redactionsKey = "x-apai-redactions"
+
+// credentialFields are redacted wherever they hold a literal secret rather
+// than an ${ENV} reference or vault:// placeholder
+var credentialFields = []string{"api_key", "token", "password", "secret", "client_secret"}
+
+// redactionKeeps lists fields that identify elements or link them to each
+// other; they are never redacted so the redacted spec keeps its structure
+var redactionKeeps = []string{
+	"apai", "id", "type", "role", "severity", "action",
+	"model", "prompt", "mcp_server", "mcp_tool", "mcp_resource",
+}
+
+// RedactSpec returns a copy of spec in which every authentication block,
+// every literal credential and the values at paths are replaced with
+// "<REDACTED>", and the list of redacted locations. Paths are dotted field
+// names, such as "prompts.template" or "context.mcp_servers.*.security",
+// where * matches every field or element, a number selects one element and
+// arrays are otherwise traversed implicitly. The locations are also
+// recorded in the copy under x-apai-redactions.
+func RedactSpec(spec map[string]interface{}, paths []string) (map[string]interface{}, []string) {
+	redacted, _ := copyValue(spec).(map[string]interface{})
+	delete(redacted, redactionsKey)
+
+	locations := make([]string, 0)
+	redact := func(container interface{}, key string, location string) {
+		if containsString(redactionKeeps, key) || containsString(locations, location) {
+			return
+		}
+		switch typed := container.(type) {
+		case map[string]interface{}:
+			typed[key] = redactValue(typed[key])
+		case []interface{}:
+			index, _ := strconv.Atoi(key)
+			typed[index] = redactValue(typed[index])
+		}
+		locations = append(locations, location)
+	}
+
+	defaultRedactions(redacted, "", redact)
+	for _, path := range paths {
+		if path = strings.TrimSpace(path); path != "" {
+			redactPath(redacted, strings.Split(path, "."), "", redact)
+		}
+	}
+
+	sort.Strings(locations)
+	if len(locations) > 0 {
+		recorded := make([]interface{}, len(locations))
+		for i, location := range locations {
+			recorded[i] = location
+		}
+		redacted[redactionsKey] = recorded
+	}
+	return redacted, locations
+}
+
+// defaultRedactions selects the authentication blocks and literal
+// credentials at any depth
+func defaultRedactions(value interface{}, location string, redact func(interface{}, string, string)) {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(typed))
+		for key := range typed {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			keyLocation := joinLocation(location, key)
+			secret, isString := typed[key].(string)
+			switch {
+			case key == "authentication":
+				redact(typed, key, keyLocation)
+			case containsString(credentialFields, key) && isString && secret != "" && !isSecretReference(secret):
+				redact(typed, key, keyLocation)
+			default:
+				defaultRedactions(typed[key], keyLocation, redact)
+			}
+		}
+	case []interface{}:
+		for i, item := range typed {
+			defaultRedactions(item, fmt.Sprintf("%s[%d]", location, i), redact)
+		}
+	}
+}
+
+// redactPath selects the values matching a path's segments
+func redactPath(value interface{}, segments []string, location string, redact func(interface{}, string, string)) {
+	segment, last := segments[0], len(segments) == 1
+
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(typed))
+		if segment == "*" {
+			for key := range typed {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+		} else if _, exists := typed[segment]; exists {
+			keys = append(keys, segment)
+		}
+		for _, key := range keys {
+			if last {
+				redact(typed, key, joinLocation(location, key))
+			} else {
+				redactPath(typed[key], segments[1:], joinLocation(location, key), redact)
+			}
+		}
+	case []interface{}:
+		index, err := strconv.Atoi(segment)
+		for i, item := range typed {
+			itemLocation := fmt.Sprintf("%s[%d]", location, i)
+			switch {
+			case segment == "*" || (err == nil && i == index):
+				if last {
+					redact(typed, strconv.Itoa(i), itemLocation)
+				} else {
+					redactPath(item, segments[1:], itemLocation, redact)
+				}
+			case err != nil:
+				// Arrays are traversed implicitly, as in "prompts.template"
+				redactPath(item, segments, itemLocation, redact)
+			}
+		}
+	}
+}
+
+// redactValue replaces a scalar, or every scalar within an object or array
+// except the fields in redactionKeeps
+func redactValue(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for key, item := range typed {
+			if !containsString(redactionKeeps, key) {
+				typed[key] = redactValue(item)
+			}
+		}
+		return typed
+	case []interface{}:
+		for i, item := range typed {
+			typed[i] = redactValue(item)
+		}
+		return typed
+	default:
+		return redactedValue
+	}
+}
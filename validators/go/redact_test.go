@@ -0,0 +1,69 @@
+package main
+
+import "testing"
+
+func TestRedactSpec(t *testing.T) {
+	spec := loadExampleSpecs(t, "automation/mcp-integration.yaml")[0]
+
+	redacted, locations := RedactSpec(spec, []string{"prompts.template", "context.mcp_servers.*.security"})
+
+	for _, location := range []string{
+		"context.mcp_servers[0].authentication",
+		"context.mcp_servers[0].security",
+		"prompts[0].template",
+	} {
+		if !containsString(locations, location) {
+			t.Errorf("expected %s to be redacted, got %v", location, locations)
+		}
+	}
+
+	servers := redacted["context"].(map[string]interface{})["mcp_servers"].([]interface{})
+	authentication := servers[0].(map[string]interface{})["authentication"].(map[string]interface{})
+	if authentication["type"] != "api_key" || authentication["api_key"] != redactedValue {
+		t.Errorf("authentication not redacted as expected: %v", authentication)
+	}
+	prompt := redacted["prompts"].([]interface{})[0].(map[string]interface{})
+	if prompt["template"] != redactedValue || prompt["id"] == redactedValue {
+		t.Errorf("prompt not redacted as expected: %v", prompt)
+	}
+	if recorded, _ := redacted[redactionsKey].([]interface{}); len(recorded) != len(locations) {
+		t.Errorf("expected %d recorded redactions, got %v", len(locations), redacted[redactionsKey])
+	}
+
+	// The original is left untouched
+	originalPrompt := spec["prompts"].([]interface{})[0].(map[string]interface{})
+	if originalPrompt["template"] == redactedValue {
+		t.Error("RedactSpec modified its input")
+	}
+
+	validator := NewAPAIValidator()
+	if !validator.ValidateSpec(redacted) {
+		t.Errorf("redacted spec should stay valid, got %v", validator.Errors)
+	}
+	for _, warning := range validator.Warnings {
+		if code := newIssue("warning", warning).Code; code == "HARDCODED_SECRET" {
+			t.Errorf("redacted values should not look like secrets: %s", warning)
+		}
+	}
+}
+
+func TestRedactSpecLiteralCredentials(t *testing.T) {
+	spec := map[string]interface{}{
+		"apai": "0.1.0",
+		"models": []interface{}{
+			map[string]interface{}{"id": "main_model", "api_key": "sk-live-123", "token": "${MODEL_TOKEN}"},
+		},
+	}
+
+	redacted, locations := RedactSpec(spec, nil)
+	model := redacted["models"].([]interface{})[0].(map[string]interface{})
+	if model["api_key"] != redactedValue {
+		t.Errorf("expected literal api_key to be redacted, got %v", model["api_key"])
+	}
+	if model["token"] != "${MODEL_TOKEN}" {
+		t.Errorf("expected env reference to be kept, got %v", model["token"])
+	}
+	if len(locations) != 1 || locations[0] != "models[0].api_key" {
+		t.Errorf("unexpected locations: %v", locations)
+	}
+}
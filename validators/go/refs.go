@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// refKey is the key of an object replaced by the value it points to, as in
+// "template: {$ref: '#/definitions/support_template'}"
+const refKey = "$ref"
+
+// RefError reports a $ref that cannot be resolved
+type RefError struct {
+	// Location is where the $ref appears, e.g. "prompts[0].template"
+	Location string
+	// Ref is the pointer of the $ref
+	Ref string
+	// Cycle lists the pointers of a circular reference, empty otherwise
+	Cycle []string
+}
+
+func (e *RefError) Error() string {
+	if len(e.Cycle) > 0 {
+		return fmt.Sprintf("Circular $ref at %s: %s", e.Location, strings.Join(e.Cycle, " -> "))
+	}
+	return fmt.Sprintf("Unresolved $ref at %s: %s", e.Location, e.Ref)
+}
+
+// ResolveRefs returns a copy of spec in which every {$ref: pointer} object
+// is replaced by the value the JSON pointer designates within the same
+// spec, such as "#/definitions/support_template" or "#/prompts/0/template".
+// Keys next to $ref override those of a referenced object and are ignored
+// otherwise. It returns a *RefError for the first dangling or circular
+// reference.
+func ResolveRefs(spec map[string]interface{}) (map[string]interface{}, error) {
+	resolved, errs := resolveSpecRefs(spec)
+	if len(errs) > 0 {
+		return nil, errs[0]
+	}
+	return resolved, nil
+}
+
+// resolveSpecRefs resolves the references of a specification, collecting
+// every failure. References that fail are left in place.
+func resolveSpecRefs(spec map[string]interface{}) (map[string]interface{}, []*RefError) {
+	resolver := &refResolver{root: spec}
+	resolved, _ := resolver.resolve(spec, "", nil).(map[string]interface{})
+	return resolved, resolver.errs
+}
+
+// refResolver resolves references against the original specification
+type refResolver struct {
+	root map[string]interface{}
+	errs []*RefError
+}
+
+// resolve returns a copy of value with its references resolved. stack holds
+// the pointers being resolved, outermost first, to detect cycles.
+func (r *refResolver) resolve(value interface{}, location string, stack []string) interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		if ref, ok := typed[refKey].(string); ok {
+			return r.resolveRef(typed, ref, location, stack)
+		}
+		resolved := make(map[string]interface{}, len(typed))
+		for key, item := range typed {
+			resolved[key] = r.resolve(item, joinLocation(location, key), stack)
+		}
+		return resolved
+	case []interface{}:
+		resolved := make([]interface{}, len(typed))
+		for i, item := range typed {
+			resolved[i] = r.resolve(item, fmt.Sprintf("%s[%d]", location, i), stack)
+		}
+		return resolved
+	default:
+		return typed
+	}
+}
+
+// resolveRef resolves a single {$ref: ref} object found at location
+func (r *refResolver) resolveRef(object map[string]interface{}, ref, location string, stack []string) interface{} {
+	for i, pointer := range stack {
+		if pointer == ref {
+			cycle := append(append([]string{}, stack[i:]...), ref)
+			r.report(&RefError{Location: location, Ref: ref, Cycle: cycle})
+			return object
+		}
+	}
+
+	target, targetLocation, ok := r.lookup(ref)
+	if !ok {
+		r.report(&RefError{Location: location, Ref: ref})
+		return object
+	}
+	resolved := r.resolve(target, targetLocation, append(stack[:len(stack):len(stack)], ref))
+	if len(object) == 1 {
+		return resolved
+	}
+
+	// Keys next to the reference override those of a referenced object
+	resolvedMap, ok := resolved.(map[string]interface{})
+	if !ok {
+		return resolved
+	}
+	for key, item := range object {
+		if key != refKey {
+			resolvedMap[key] = r.resolve(item, joinLocation(location, key), stack)
+		}
+	}
+	return resolvedMap
+}
+
+// report records a failure once, even when the value holding the reference
+// is itself referenced several times
+func (r *refResolver) report(refErr *RefError) {
+	for _, existing := range r.errs {
+		if existing.Location == refErr.Location && existing.Ref == refErr.Ref {
+			return
+		}
+	}
+	r.errs = append(r.errs, refErr)
+}
+
+// lookup returns the value a "#/..." pointer designates in the original
+// specification, together with its location
+func (r *refResolver) lookup(ref string) (interface{}, string, bool) {
+	if !strings.HasPrefix(ref, "#/") {
+		return nil, "", false
+	}
+
+	var current interface{} = r.root
+	location := ""
+	for _, token := range strings.Split(ref[2:], "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		switch typed := current.(type) {
+		case map[string]interface{}:
+			value, exists := typed[token]
+			if !exists {
+				return nil, "", false
+			}
+			current, location = value, joinLocation(location, token)
+		case []interface{}:
+			index, err := strconv.Atoi(token)
+			if err != nil || index < 0 || index >= len(typed) {
+				return nil, "", false
+			}
+			current, location = typed[index], fmt.Sprintf("%s[%d]", location, index)
+		default:
+			return nil, "", false
+		}
+	}
+	return current, location, true
+}
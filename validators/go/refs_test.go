@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestResolveRefs(t *testing.T) {
+	spec := loadExampleSpecs(t, "templates/basic-template.yaml")[0]
+	spec["definitions"] = map[string]interface{}{
+		"support_template": "You are a support assistant for {{company_name}}",
+	}
+	prompts := spec["prompts"].([]interface{})
+	prompts[0].(map[string]interface{})["template"] = map[string]interface{}{refKey: "#/definitions/support_template"}
+	prompts = append(prompts, map[string]interface{}{refKey: "#/prompts/0", "id": "user_prompt", "role": "user"})
+	spec["prompts"] = prompts
+
+	resolved, err := ResolveRefs(spec)
+	if err != nil {
+		t.Fatalf("resolve failed: %v", err)
+	}
+	resolvedPrompts := resolved["prompts"].([]interface{})
+	copied := resolvedPrompts[1].(map[string]interface{})
+	if copied["template"] != "You are a support assistant for {{company_name}}" || copied["id"] != "user_prompt" {
+		t.Errorf("unexpected resolved prompt: %v", copied)
+	}
+	if _, ok := prompts[0].(map[string]interface{})["template"].(map[string]interface{}); !ok {
+		t.Error("ResolveRefs modified its input")
+	}
+
+	validator := NewAPAIValidator()
+	if !validator.ValidateSpec(spec) {
+		t.Errorf("expected spec with refs to be valid, got errors: %v", validator.Errors)
+	}
+}
+
+func TestResolveRefsReportsDanglingAndCircularRefs(t *testing.T) {
+	spec := map[string]interface{}{
+		"definitions": map[string]interface{}{
+			"a": map[string]interface{}{refKey: "#/definitions/b"},
+			"b": map[string]interface{}{refKey: "#/definitions/a"},
+		},
+		"prompts": []interface{}{
+			map[string]interface{}{"id": "p", "template": map[string]interface{}{refKey: "#/definitions/missing"}},
+		},
+	}
+
+	_, err := ResolveRefs(spec)
+	var refErr *RefError
+	if !errors.As(err, &refErr) {
+		t.Fatalf("expected a RefError, got %v", err)
+	}
+
+	validator := NewAPAIValidator()
+	validator.ValidateSpec(spec)
+	for _, want := range []string{
+		"Unresolved $ref at prompts[0].template: #/definitions/missing",
+		"Circular $ref at definitions.a: #/definitions/b -> #/definitions/a -> #/definitions/b",
+	} {
+		if !containsString(validator.Errors, want) {
+			t.Errorf("missing %q in %v", want, validator.Errors)
+		}
+	}
+}
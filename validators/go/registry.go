@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// SpecPathEnv is the environment variable listing registry roots
+const SpecPathEnv = "APAI_SPEC_PATH"
+
+// registryExtensions are the file extensions accepted for registry entries
+var registryExtensions = []string{".yaml", ".yml"}
+
+// isRegistryReference reports whether an inherits entry is a symbolic
+// registry reference (e.g. "org/base@1.2.0") rather than a file path
+func isRegistryReference(ref string) bool {
+	if ref == "" || filepath.IsAbs(ref) {
+		return false
+	}
+	if strings.HasPrefix(ref, "./") || strings.HasPrefix(ref, "../") {
+		return false
+	}
+
+	switch strings.ToLower(filepath.Ext(strings.SplitN(ref, "@", 2)[0])) {
+	case ".yaml", ".yml", ".json":
+		return false
+	}
+	return true
+}
+
+// resolveRegistryReference resolves a symbolic reference against the registry roots
+func (v *APAIValidator) resolveRegistryReference(ref string) (string, error) {
+	roots := v.Config.SpecRoots
+	if len(roots) == 0 {
+		return "", fmt.Errorf("cannot resolve %s: no spec roots configured (use --spec-root, spec_roots or %s)", ref, SpecPathEnv)
+	}
+
+	name, version := ref, ""
+	if at := strings.LastIndex(ref, "@"); at >= 0 {
+		name, version = ref[:at], ref[at+1:]
+	}
+
+	for _, root := range roots {
+		entryDir := v.joinPath(root, name)
+
+		if version != "" {
+			for _, ext := range registryExtensions {
+				candidate := v.joinPath(entryDir, version+ext)
+				if v.fileExists(candidate) {
+					return candidate, nil
+				}
+			}
+			continue
+		}
+
+		if latest := v.latestRegistryVersion(entryDir); latest != "" {
+			return latest, nil
+		}
+	}
+
+	return "", fmt.Errorf("cannot resolve %s (searched: %s)", ref, strings.Join(roots, ", "))
+}
+
+// latestRegistryVersion returns the entry file with the highest semantic version in dir
+func (v *APAIValidator) latestRegistryVersion(dir string) string {
+	entries, err := v.readDir(dir)
+	if err != nil {
+		return ""
+	}
+
+	best := ""
+	var bestVersion []int
+	var bestPre string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		ext := filepath.Ext(entry.Name())
+		accepted := false
+		for _, registryExt := range registryExtensions {
+			if strings.EqualFold(ext, registryExt) {
+				accepted = true
+			}
+		}
+		if !accepted {
+			continue
+		}
+
+		version, pre, ok := parseSemver(strings.TrimSuffix(entry.Name(), ext))
+		if !ok {
+			continue
+		}
+		if best == "" || compareSemver(version, pre, bestVersion, bestPre) > 0 {
+			best = v.joinPath(dir, entry.Name())
+			bestVersion, bestPre = version, pre
+		}
+	}
+	return best
+}
+
+// parseSemver parses a MAJOR.MINOR.PATCH version with an optional
+// leading "v" and pre-release suffix
+func parseSemver(s string) ([]int, string, bool) {
+	s = strings.TrimPrefix(s, "v")
+	if plus := strings.Index(s, "+"); plus >= 0 {
+		s = s[:plus]
+	}
+
+	pre := ""
+	if dash := strings.Index(s, "-"); dash >= 0 {
+		s, pre = s[:dash], s[dash+1:]
+	}
+
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 {
+		return nil, "", false
+	}
+
+	version := make([]int, 3)
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return nil, "", false
+		}
+		version[i] = n
+	}
+	return version, pre, true
+}
+
+// compareSemver compares two parsed versions, returning -1, 0 or 1
+func compareSemver(a []int, aPre string, b []int, bPre string) int {
+	for i := range a {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	// A release has higher precedence than its pre-releases
+	switch {
+	case aPre == bPre:
+		return 0
+	case aPre == "":
+		return 1
+	case bPre == "":
+		return -1
+	}
+	return comparePrerelease(aPre, bPre)
+}
+
+// comparePrerelease compares two pre-release versions identifier by
+// identifier, returning -1, 0 or 1: numeric identifiers compare as numbers
+// and below alphanumeric ones, which compare as strings, and a version
+// with more identifiers wins when all others are equal, so rc.2 < rc.10
+func comparePrerelease(a, b string) int {
+	aIDs, bIDs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(aIDs) && i < len(bIDs); i++ {
+		aNum, aErr := strconv.ParseUint(aIDs[i], 10, 64)
+		bNum, bErr := strconv.ParseUint(bIDs[i], 10, 64)
+		switch {
+		case aErr == nil && bErr == nil:
+			if aNum != bNum {
+				if aNum < bNum {
+					return -1
+				}
+				return 1
+			}
+		case aErr == nil:
+			return -1
+		case bErr == nil:
+			return 1
+		case aIDs[i] != bIDs[i]:
+			if aIDs[i] < bIDs[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case len(aIDs) < len(bIDs):
+		return -1
+	case len(aIDs) > len(bIDs):
+		return 1
+	}
+	return 0
+}
+
+// specRootsFromEnv returns the registry roots listed in APAI_SPEC_PATH
+func specRootsFromEnv() []string {
+	roots := make([]string, 0)
+	for _, root := range strings.Split(os.Getenv(SpecPathEnv), string(os.PathListSeparator)) {
+		if root != "" {
+			roots = append(roots, root)
+		}
+	}
+	return roots
+}
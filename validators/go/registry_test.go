@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestResolveRegistryReference(t *testing.T) {
+	entry := &fstest.MapFile{Data: []byte("apai: \"0.1.0\"\n")}
+	fsys := fstest.MapFS{
+		"team-registry/org/base/1.2.0.yaml":      entry,
+		"org-registry/org/base/1.2.0.yaml":       entry,
+		"org-registry/org/lib/1.10.0.yaml":       entry,
+		"org-registry/org/lib/1.9.3.yml":         entry,
+		"org-registry/org/lib/notes.txt":         entry,
+		"org-registry/org/lib/draft.yaml":        entry,
+		"org-registry/org/rc/2.0.0-rc.2.yaml":    entry,
+		"org-registry/org/rc/2.0.0-rc.10.yaml":   entry,
+		"org-registry/org/rc/2.0.0-beta.11.yaml": entry,
+		"org-registry/org/rc/1.0.0.yaml":         entry,
+		"app/spec.yaml":                          {Data: []byte("inherits: [\"org/rc\"]\n")},
+	}
+	config := DefaultConfig()
+	config.SpecRoots = []string{"team-registry", "org-registry"}
+	validator := NewAPAIValidator(WithFS(fsys), WithConfig(config))
+
+	for ref, want := range map[string]string{
+		// The first root holding an entry wins
+		"org/base@1.2.0": "team-registry/org/base/1.2.0.yaml",
+		"org/base":       "team-registry/org/base/1.2.0.yaml",
+		"org/lib":        "org-registry/org/lib/1.10.0.yaml",
+		"org/lib@1.9.3":  "org-registry/org/lib/1.9.3.yml",
+		"org/rc":         "org-registry/org/rc/2.0.0-rc.10.yaml",
+	} {
+		if got, err := validator.resolveRegistryReference(ref); err != nil || got != want {
+			t.Errorf("resolving %s: got %q %v, want %q", ref, got, err, want)
+		}
+	}
+
+	_, err := validator.resolveRegistryReference("org/base@3.0.0")
+	if err == nil || err.Error() != "cannot resolve org/base@3.0.0 (searched: team-registry, org-registry)" {
+		t.Errorf("expected an error listing the roots searched, got %v", err)
+	}
+	_, err = NewAPAIValidator(WithFS(fsys)).resolveRegistryReference("org/base")
+	if err == nil || !strings.Contains(err.Error(), "no spec roots configured") {
+		t.Errorf("expected an error without roots, got %v", err)
+	}
+
+	if _, err := validator.ResolveSpecContext(context.Background(), "app/spec.yaml"); err != nil {
+		t.Errorf("resolving a spec inheriting a registry entry: %v", err)
+	}
+}
+
+func TestIsRegistryReference(t *testing.T) {
+	for ref, want := range map[string]bool{
+		"org/base@1.2.0":         true,
+		"team/support-base":      true,
+		"base.yaml":              false,
+		"../shared/base.yml":     false,
+		"./base":                 false,
+		"/etc/apai/base":         false,
+		"org/base.json@1.0.0":    false,
+		"":                       false,
+		"registry/base@2.0.0-rc": true,
+	} {
+		if got := isRegistryReference(ref); got != want {
+			t.Errorf("isRegistryReference(%q) = %v, want %v", ref, got, want)
+		}
+	}
+}
+
+func TestCompareSemver(t *testing.T) {
+	// Each version has lower precedence than the next, as in semver 11
+	ordered := []string{
+		"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta",
+		"1.0.0-beta.2", "1.0.0-beta.11", "1.0.0-rc.1", "1.0.0-rc.2", "1.0.0-rc.10",
+		"1.0.0", "1.2.0", "1.10.0", "v2.0.0",
+	}
+	for i := range ordered {
+		for j := range ordered {
+			a, aPre, aOK := parseSemver(ordered[i])
+			b, bPre, bOK := parseSemver(ordered[j])
+			if !aOK || !bOK {
+				t.Fatalf("parsing %s or %s failed", ordered[i], ordered[j])
+			}
+			want := 0
+			if i < j {
+				want = -1
+			} else if i > j {
+				want = 1
+			}
+			if got := compareSemver(a, aPre, b, bPre); got != want {
+				t.Errorf("compareSemver(%s, %s) = %d, want %d", ordered[i], ordered[j], got, want)
+			}
+		}
+	}
+
+	for _, invalid := range []string{"1.2", "1.2.x", "latest", "1.-2.0"} {
+		if _, _, ok := parseSemver(invalid); ok {
+			t.Errorf("expected %s not to parse as a version", invalid)
+		}
+	}
+}
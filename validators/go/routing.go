@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// modelReference is a reference from a model to another model by id, with
+// the field holding it
+type modelReference struct {
+	field string
+	id    string
+}
+
+// modelRouteReferences returns the models a model falls back or routes
+// to: its fallback, one id or several, then the primary and candidates of
+// its routing policy. Values that are not strings are left out.
+func modelRouteReferences(modelMap map[string]interface{}) []modelReference {
+	references := make([]modelReference, 0)
+	switch fallback := modelMap["fallback"].(type) {
+	case string:
+		references = append(references, modelReference{"fallback", fallback})
+	case []interface{}:
+		for i, item := range fallback {
+			if id, ok := item.(string); ok {
+				references = append(references, modelReference{fmt.Sprintf("fallback[%d]", i), id})
+			}
+		}
+	}
+
+	routing, _ := modelMap["routing"].(map[string]interface{})
+	if primary, ok := routing["primary"].(string); ok {
+		references = append(references, modelReference{"routing.primary", primary})
+	}
+	candidates, _ := routing["candidates"].([]interface{})
+	for i, item := range candidates {
+		if id, ok := item.(string); ok {
+			references = append(references, modelReference{fmt.Sprintf("routing.candidates[%d]", i), id})
+		}
+	}
+	return references
+}
+
+// validateModelRouting checks the shape of the fallback and routing fields
+// of a model; the models they reference are checked by validateModelRoutes
+func validateModelRouting(f *sectionFindings, modelMap map[string]interface{}, modelIndex int) {
+	name := elementName(modelIndex, modelMap)
+	if fallback, exists := modelMap["fallback"]; exists {
+		if _, ok := fallback.(string); !ok && !isStringArray(fallback) {
+			f.addError("INVALID_TYPE", fmt.Sprintf("Model %s fallback must be a string or an array of model IDs", name))
+		}
+	}
+
+	routing, exists := modelMap["routing"]
+	if !exists {
+		return
+	}
+	routingMap, ok := routing.(map[string]interface{})
+	if !ok {
+		f.addError("INVALID_TYPE", fmt.Sprintf("Model %s routing must be an object", name))
+		return
+	}
+	for _, field := range []string{"primary", "strategy"} {
+		if value, exists := routingMap[field]; exists {
+			if _, ok := value.(string); !ok {
+				f.addError("INVALID_TYPE", fmt.Sprintf("Model %s routing.%s must be a string", name, field))
+			}
+		}
+	}
+	if candidates, exists := routingMap["candidates"]; exists && !isStringArray(candidates) {
+		f.addError("INVALID_TYPE", fmt.Sprintf("Model %s routing.candidates must be an array of model IDs", name))
+	}
+}
+
+// isStringArray reports whether a value is an array of strings
+func isStringArray(value interface{}) bool {
+	items, ok := value.([]interface{})
+	if !ok {
+		return false
+	}
+	for _, item := range items {
+		if _, ok := item.(string); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// validateModelRoutes checks that the fallback and routing models of each
+// model are declared, and that no model falls back to itself
+func (v *APAIValidator) validateModelRoutes(spec map[string]interface{}) {
+	modelIDs := make(map[string]bool)
+	objectsAt(spec, "models[]", "", func(modelMap map[string]interface{}, _ string) {
+		if id, ok := modelMap["id"].(string); ok {
+			modelIDs[id] = true
+		}
+	})
+
+	models, _ := spec["models"].([]interface{})
+	for index, model := range models {
+		modelMap, ok := model.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name := elementName(index, modelMap)
+		for _, reference := range modelRouteReferences(modelMap) {
+			switch {
+			case !modelIDs[reference.id]:
+				v.addError("UNKNOWN_REFERENCE", fmt.Sprintf("Model %s %s references unknown model: %s", name, reference.field, reference.id))
+			case reference.id == name && strings.HasPrefix(reference.field, "fallback"):
+				v.addError("SELF_FALLBACK", fmt.Sprintf("Model %s lists itself as its own fallback", name))
+			}
+		}
+	}
+}
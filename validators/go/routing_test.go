@@ -0,0 +1,47 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestModelRoutes(t *testing.T) {
+	model := func(id string, fields map[string]interface{}) map[string]interface{} {
+		model := map[string]interface{}{"id": id, "type": "LLM", "provider": "OpenAI", "name": "gpt-4o", "purpose": "support"}
+		for field, value := range fields {
+			model[field] = value
+		}
+		return model
+	}
+	spec := map[string]interface{}{"models": []interface{}{
+		model("primary", map[string]interface{}{"fallback": "backup"}),
+		model("backup", map[string]interface{}{"fallback": []interface{}{"backup", "bakcup"}}),
+		model("router", map[string]interface{}{"routing": map[string]interface{}{
+			"primary": "primary", "candidates": []interface{}{"backup", "cheap"}, "strategy": "cost",
+		}}),
+		model("broken", map[string]interface{}{"fallback": 3, "routing": map[string]interface{}{"candidates": "backup"}}),
+	}}
+
+	validator := NewAPAIValidator()
+	validator.ValidateSpec(spec)
+	wantErrors := map[string]string{
+		"Model backup lists itself as its own fallback":                      "SELF_FALLBACK",
+		"Model backup fallback[1] references unknown model: bakcup":          "UNKNOWN_REFERENCE",
+		"Model router routing.candidates[1] references unknown model: cheap": "UNKNOWN_REFERENCE",
+		"Model broken fallback must be a string or an array of model IDs":    "INVALID_TYPE",
+		"Model broken routing.candidates must be an array of model IDs":      "INVALID_TYPE",
+	}
+	for want, code := range wantErrors {
+		if !containsString(validator.Errors, want) {
+			t.Errorf("missing %q in %v", want, validator.Errors)
+		}
+		if rule, _ := MatchRule(want); rule.Code != code {
+			t.Errorf("expected %s for %q, got %q", code, want, rule.Code)
+		}
+	}
+	for _, err := range validator.Errors {
+		if strings.HasPrefix(err, "Model ") && wantErrors[err] == "" {
+			t.Errorf("unexpected %q", err)
+		}
+	}
+}
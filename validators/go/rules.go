@@ -0,0 +1,942 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Rule documents a validation check and the code of the findings it reports
+type Rule struct {
+	Code        string `json:"code"`
+	Severity    string `json:"severity"`
+	Summary     string `json:"summary"`
+	Rationale   string `json:"rationale"`
+	Remediation string `json:"remediation"`
+
+	// pattern matches the messages reported by the rule, for findings whose
+	// code was not recorded where they were raised
+	pattern *regexp.Regexp
+}
+
+// rules is the registry of validation rules. Findings carry the code of
+// their rule from where they are raised; messages without one are matched
+// against the patterns in order, so specific rules come before general ones.
+var rules = []Rule{
+	{
+		Code:        "MISSING_SECTION",
+		Severity:    "error",
+		Summary:     "A required top-level section is missing.",
+		Rationale:   "Every APAI specification declares apai, info, models, prompts, constraints, tasks, context and evaluation so tools can rely on their presence.",
+		Remediation: "evaluation:\n  metrics:\n    - name: \"accuracy\"\n      target: 0.9",
+		pattern:     regexp.MustCompile(`^Missing required section: `),
+	},
+	{
+		Code:        "NUMERIC_STRING",
+		Severity:    "error",
+		Summary:     "A numeric field holds a string.",
+		Rationale:   "Quoted numbers such as \"0.7\" are strings in YAML; runtimes either reject them or silently fall back to defaults.",
+		Remediation: "parameters:\n  temperature: 0.7    # not \"0.7\"",
+		pattern:     regexp.MustCompile(` must be a number, got string `),
+	},
+	{
+		Code:        "BOOLEAN_STRING",
+		Severity:    "error",
+		Summary:     "A boolean field holds a string.",
+		Rationale:   "Quoted booleans such as \"true\" or \"yes\" are strings in YAML; runtimes may read any of them as false and silently misconfigure the spec.",
+		Remediation: "tasks:\n  - id: \"base_task\"\n    abstract: true    # not \"true\" or \"yes\"",
+		pattern:     regexp.MustCompile(` must be a boolean, got string `),
+	},
+	{
+		Code:        "INVALID_TYPE",
+		Severity:    "error",
+		Summary:     "A section or field has the wrong type.",
+		Rationale:   "Sections are objects or arrays with a fixed shape; a scalar in their place means the rest of the section cannot be checked.",
+		Remediation: "models:            # an array, not an object\n  - id: \"main_model\"",
+		pattern:     regexp.MustCompile(` must be (a string|an object|an array|a boolean)`),
+	},
+	{
+		Code:        "MISSING_FIELD",
+		Severity:    "error",
+		Summary:     "A required field is missing.",
+		Rationale:   "Required fields identify and configure an element; without them references and runtimes cannot use it.",
+		Remediation: "prompts:\n  - id: \"system_prompt\"\n    role: \"system\"\n    template: \"You are a helpful assistant\"",
+		pattern:     regexp.MustCompile(`[Mm]issing required field|missing (mcp_server|mcp_tool|mcp_resource) field$|transport missing (command|url)$`),
+	},
+	{
+		Code:        "EMPTY_FIELD",
+		Severity:    "error",
+		Summary:     "A required or descriptive field is present but empty.",
+		Rationale:   "A blank value passes a presence check but carries no information, which usually means a template was never filled in.",
+		Remediation: "tasks:\n  - id: \"handle_query\"\n    description: \"Answer customer questions about orders\"",
+		pattern:     regexp.MustCompile(`[Rr]equired field (in info )?is empty: |^Field is empty: | example \d+ field is empty: `),
+	},
+	{
+		Code:        "MISSING_MODEL",
+		Severity:    "error",
+		Summary:     "The models section is empty.",
+		Rationale:   "Tasks run on models; a specification without any model cannot execute anything.",
+		Remediation: "models:\n  - id: \"main_model\"\n    type: \"LLM\"\n    provider: \"openai\"\n    name: \"gpt-4\"\n    purpose: \"conversation\"",
+		pattern:     regexp.MustCompile(`^At least one model is required$`),
+	},
+	{
+		Code:        "DUPLICATE_ID",
+		Severity:    "error",
+		Summary:     "Two elements of the same section share an ID.",
+		Rationale:   "Task steps and tools reference elements by ID; duplicates make those references ambiguous.",
+		Remediation: "prompts:\n  - id: \"greeting\"\n  - id: \"greeting_formal\"    # rename one of the duplicates",
+		pattern:     regexp.MustCompile(`^Duplicate .*ID: |^Duplicate knowledge source name: `),
+	},
+	{
+		Code:        "INVALID_ENUM",
+		Severity:    "error",
+		Summary:     "A field holds a value outside its allowed set.",
+		Rationale:   "Fields like prompt role, constraint severity and transport type drive runtime behaviour and only accept documented values.",
+		Remediation: "prompts:\n  - id: \"system_prompt\"\n    role: \"system\"    # system, user or assistant",
+		pattern:     regexp.MustCompile(`^Invalid (complexity|constraint severity|prompt role): |invalid (transport|authentication) type: |invalid (response format|input modality|source type): |^Invalid (persistence store|experiment status|evaluation frequency): `),
+	},
+	{
+		Code:        "INVALID_OPERATION_SETTING",
+		Severity:    "error",
+		Summary:     "A step retry count or timeout is malformed.",
+		Rationale:   "Runtimes read retry as a count and timeout as a duration; other values are rejected or silently replaced by defaults.",
+		Remediation: "steps:\n  - name: \"lookup\"\n    action: \"search\"\n    retry: 3\n    timeout: \"30s\"",
+		pattern:     regexp.MustCompile(`(retry must be a non-negative integer|timeout must be a positive duration)`),
+	},
+	{
+		Code:        "UNUSUAL_OPERATION_SETTING",
+		Severity:    "warning",
+		Summary:     "A step retries more than 10 times or times out after more than an hour.",
+		Rationale:   "Such values are usually unit mistakes, e.g. \"300m\" for 300 seconds, and keep failing work alive for a long time.",
+		Remediation: "steps:\n  - name: \"lookup\"\n    action: \"search\"\n    retry: 3\n    timeout: \"5m\"",
+		pattern:     regexp.MustCompile(`(retry of \d+|timeout of \S+) is unusually `),
+	},
+	{
+		Code:        "UNKNOWN_VALUE",
+		Severity:    "warning",
+		Summary:     "A field holds a value the validator does not recognise.",
+		Rationale:   "Unrecognised model types, step actions, model capabilities and hierarchy levels are allowed for forward compatibility but are often typos.",
+		Remediation: "steps:\n  - name: \"answer\"\n    action: \"generate\"",
+		pattern:     regexp.MustCompile(`^Unknown (model type|hierarchy level): |unknown (action|direction): |has unknown (capability|type): `),
+	},
+	{
+		Code:        "METRIC_DIRECTION_MISMATCH",
+		Severity:    "warning",
+		Summary:     "A metric's direction contradicts its type, such as a latency metric to maximize.",
+		Rationale:   "Evaluation gates compare results in the metric's direction; the wrong one passes regressions and fails improvements.",
+		Remediation: "metrics:\n  - name: \"response_time\"\n    type: \"latency\"\n    direction: \"minimize\"",
+		pattern:     regexp.MustCompile(`^Metric \S+ direction \S+ contradicts its \S+ type`),
+	},
+	{
+		Code:        "METRIC_THRESHOLD_OUT_OF_DOMAIN",
+		Severity:    "warning",
+		Summary:     "A metric target or threshold lies outside the values its type can take.",
+		Rationale:   "An accuracy threshold of 5 can never be met; it is usually a percentage written for a ratio or a misplaced unit.",
+		Remediation: "metrics:\n  - name: \"response_accuracy\"\n    type: \"accuracy\"\n    target: 0.9    # a ratio between 0 and 1, not 90",
+		pattern:     regexp.MustCompile(`^Metric \S+ (target|threshold) \S+ is outside the domain of `),
+	},
+	{
+		Code:        "CAPABILITY_TYPE_CONFLICT",
+		Severity:    "error",
+		Summary:     "A model declares a capability its type cannot have.",
+		Rationale:   "An embedding model cannot see images and a vision model does not call functions; the declaration is wrong or the type is.",
+		Remediation: "models:\n  - id: \"reader\"\n    type: \"Multimodal\"    # not Embedding\n    capabilities: [\"vision\"]",
+		pattern:     regexp.MustCompile(`^Model \S+ capability \S+ is not supported by type `),
+	},
+	{
+		Code:        "MISSING_MODEL_CAPABILITY",
+		Severity:    "warning",
+		Summary:     "A step needs a capability its model does not list.",
+		Rationale:   "Models without json_mode often return prose around the JSON, breaking whatever parses the step output.",
+		Remediation: "models:\n  - id: \"main_model\"\n    capabilities: [\"text_generation\", \"json_mode\"]",
+		pattern:     regexp.MustCompile(`^Step \S+ uses response format \S+, but model \S+ does not list the `),
+	},
+	{
+		Code:        "MODALITY_MISMATCH",
+		Severity:    "error",
+		Summary:     "A step sends input of a modality its model's type does not accept.",
+		Rationale:   "A text-only model rejects image and audio content at runtime.",
+		Remediation: "steps:\n  - name: \"describe\"\n    action: \"analyze\"\n    model: \"vision_model\"    # type: Vision or Multimodal\n    input_modalities: [\"image\"]",
+		pattern:     regexp.MustCompile(`^Step \S+ sends \S+ input to model `),
+	},
+	{
+		Code:        "UNSUPPORTED_VERSION",
+		Severity:    "warning",
+		Summary:     "The apai version may not be supported.",
+		Rationale:   "The validator implements version 0.1.x of the specification; other versions may use fields it does not know.",
+		Remediation: "apai: \"0.1.0\"",
+		pattern:     regexp.MustCompile(`^Version .* may not be supported$`),
+	},
+	{
+		Code:        "RECOMMENDED_FIELD",
+		Severity:    "warning",
+		Summary:     "A recommended field is missing.",
+		Rationale:   "Recommended fields are optional but make the specification easier to operate and evaluate.",
+		Remediation: "context:\n  memory:\n    type: \"session\"",
+		pattern:     regexp.MustCompile(` is recommended$`),
+	},
+	{
+		Code:        "INVALID_CONTACT",
+		Severity:    "warning",
+		Summary:     "An email address or URL is malformed.",
+		Rationale:   "Contact details are how users reach the owners of a system; malformed values are unreachable.",
+		Remediation: "contact:\n  email: \"team@example.com\"\n  url: \"https://example.com\"",
+		pattern:     regexp.MustCompile(`\.(email|url) is not a valid `),
+	},
+	{
+		Code:        "MCP_AUTH_INCOMPLETE",
+		Severity:    "warning",
+		Summary:     "MCP authentication lacks its credential field.",
+		Rationale:   "api_key and oauth authentication need the credential to connect to the server.",
+		Remediation: "authentication:\n  type: \"api_key\"\n  api_key: \"${MCP_API_KEY}\"",
+		pattern:     regexp.MustCompile(`authentication missing (api_key|token) field$`),
+	},
+	{
+		Code:        "HARDCODED_SECRET",
+		Severity:    "warning",
+		Summary:     "An MCP api_key or token, the password of a persistence DSN, or a credential in an extension field holds a literal value.",
+		Rationale:   "Credentials committed with a specification leak to everyone who can read the repository; reference them instead.",
+		Remediation: "authentication:\n  type: \"api_key\"\n  api_key: \"${MCP_API_KEY}\"    # or \"vault://mcp/api_key\"",
+		pattern:     regexp.MustCompile(`authentication (api_key|token) looks like a literal secret| (dsn|url) embeds a literal password|^Extension field \S+ looks like a literal secret`),
+	},
+	{
+		Code:        "SCHEMA_VIOLATION",
+		Severity:    "error",
+		Summary:     "The document violates an external JSON Schema passed with --schema.",
+		Rationale:   "Organizations layer stricter rules on APAI in their own schemas; a violation breaks one of those rules.",
+		Remediation: "# change the field named in the finding to satisfy the schema keyword it reports\nmodels:\n  - id: \"main_model\"\n    provider: \"openai\"",
+		pattern:     regexp.MustCompile(`^Schema violation at `),
+	},
+	{
+		Code:        "UNRESOLVED_ENV",
+		Severity:    "error",
+		Summary:     "A ${VAR} placeholder names a variable that is not set, with environment substitution enabled.",
+		Rationale:   "The deployed specification would keep the literal placeholder, so URLs, credentials or model names would be wrong at runtime.",
+		Remediation: "# set the variable, add it to the --env-file, or pass --allow-missing-env\nexport MCP_API_KEY=...",
+		pattern:     regexp.MustCompile(`^Unresolved environment variable \$\{`),
+	},
+	{
+		Code:        "TEMPLATE_FILE_NOT_FOUND",
+		Severity:    "error",
+		Summary:     "A prompt's template_file cannot be read.",
+		Rationale:   "The prompt has no template without its file; paths are relative to the specification that declares the prompt.",
+		Remediation: "prompts:\n  - id: \"system_prompt\"\n    role: \"system\"\n    template_file: \"prompts/system-triage.md\"    # relative to this file",
+		pattern:     regexp.MustCompile(`template_file (not found|must be a file path)`),
+	},
+	{
+		Code:        "EMPTY_TEMPLATE_FILE",
+		Severity:    "error",
+		Summary:     "A prompt's template_file is empty.",
+		Rationale:   "An empty template file gives the model no instructions, which usually means the file was never written.",
+		Remediation: "# prompts/system-triage.md\nYou are a triage assistant for {{company_name}}.",
+		pattern:     regexp.MustCompile(`template_file is empty: `),
+	},
+	{
+		Code:        "TEMPLATE_CONFLICT",
+		Severity:    "error",
+		Summary:     "A prompt declares both template and template_file.",
+		Rationale:   "It is ambiguous which template applies.",
+		Remediation: "prompts:\n  - id: \"system_prompt\"\n    role: \"system\"\n    template_file: \"prompts/system-triage.md\"    # remove template",
+		pattern:     regexp.MustCompile(`has both template and template_file$`),
+	},
+	{
+		Code:        "EMPTY_EXAMPLES",
+		Severity:    "warning",
+		Summary:     "A prompt declares an empty examples array.",
+		Rationale:   "An empty examples array is usually a leftover; remove it or add few-shot examples.",
+		Remediation: "examples:\n  - input: \"Where is my order?\"\n    output: \"Let me check your order status.\"",
+		pattern:     regexp.MustCompile(`examples is empty$`),
+	},
+	{
+		Code:        "EXAMPLE_MISSING_OUTPUT",
+		Severity:    "warning",
+		Summary:     "A few-shot example has no output.",
+		Rationale:   "Examples teach the model the expected answer; without an output they only add tokens.",
+		Remediation: "examples:\n  - input: \"Where is my order?\"\n    output: \"Let me check your order status.\"",
+		pattern:     regexp.MustCompile(`example \d+ missing output$`),
+	},
+	{
+		Code:        "TOO_MANY_EXAMPLES",
+		Severity:    "warning",
+		Summary:     "A prompt declares more than 20 few-shot examples.",
+		Rationale:   "Every example is sent with each request, so a long list multiplies token usage and cost while adding little over a few well-chosen examples.",
+		Remediation: "examples:    # keep a handful of representative examples\n  - input: \"Where is my order?\"\n    output: \"Let me check your order status.\"",
+		pattern:     regexp.MustCompile(`^Prompt \S+ has \d+ examples `),
+	},
+	{
+		Code:        "UNUSED_EXAMPLES",
+		Severity:    "warning",
+		Summary:     "A system prompt carries few-shot examples but no task step or prompt chain uses it.",
+		Rationale:   "The examples are never sent, so they are either dead weight or meant for a prompt that a task should reference.",
+		Remediation: "tasks:\n  - id: \"support\"\n    steps:\n      - name: \"answer\"\n        prompt: \"system_prompt\"",
+		pattern:     regexp.MustCompile(`has examples but is a system prompt no task uses$`),
+	},
+	{
+		Code:        "INVALID_LANGUAGE_TAG",
+		Severity:    "error",
+		Summary:     "A prompt translations or variants key is not a BCP-47 language tag.",
+		Rationale:   "Runtimes pick the variant by matching the user's language tag; a key like english never matches.",
+		Remediation: "translations:\n  it: \"Sei un assistente per {{company_name}}\"\n  de-CH: \"Du bist ein Assistent für {{company_name}}\"",
+		pattern:     regexp.MustCompile(`(translations|variants) key is not a BCP-47 language tag: `),
+	},
+	{
+		Code:        "UNKNOWN_LANGUAGE_TAG",
+		Severity:    "warning",
+		Summary:     "A prompt translations or variants key is a well-formed BCP-47 tag with a subtag no registry defines.",
+		Rationale:   "Runtimes match the key against the user's language tag; a key like xx-YY is well-formed but matches no one.",
+		Remediation: "translations:\n  pt-BR: \"...\"    # registered language and region subtags",
+		pattern:     regexp.MustCompile(`(translations|variants) key has an unknown subtag \S+: `),
+	},
+	{
+		Code:        "MIXED_LANGUAGE_TAGS",
+		Severity:    "warning",
+		Summary:     "A prompt's translations mix language tags with and without a region.",
+		Rationale:   "With both de and de-CH, which variant a Swiss or Austrian user gets depends on the runtime's fallback rules; consistent tags make the choice explicit.",
+		Remediation: "translations:\n  de-DE: \"...\"\n  de-CH: \"...\"",
+		pattern:     regexp.MustCompile(`mix language tags with and without a region: `),
+	},
+	{
+		Code:        "TRANSLATION_VARIABLE_MISMATCH",
+		Severity:    "error",
+		Summary:     "A translated prompt template does not use the same variables as the default template.",
+		Rationale:   "A missing placeholder drops information from the localized prompt, and an extra one is never filled in by callers written against the default template.",
+		Remediation: "template: \"You are an assistant for {{company_name}}\"\ntranslations:\n  it: \"Sei un assistente per {{company_name}}\"",
+		pattern:     regexp.MustCompile(`^Prompt \S+ translation \S+ (is missing variables of|uses variables) the default template`),
+	},
+	{
+		Code:        "MISSING_TRANSLATION",
+		Severity:    "warning",
+		Summary:     "A translated prompt lacks a language other prompts of the specification are translated into.",
+		Rationale:   "Users of that language get a mix of localized prompts and prompts in the default language.",
+		Remediation: "translations:\n  it: \"...\"\n  de: \"...\"    # the languages of the other prompts",
+		pattern:     regexp.MustCompile(`lacks translations other prompts have: `),
+	},
+	{
+		Code:        "INVALID_PROMPT_LANGUAGE",
+		Severity:    "warning",
+		Summary:     "A prompt language is not a BCP-47 tag, or has a subtag no registry defines.",
+		Rationale:   "Runtimes and translation tools match the language against the user's; a tag like english or xx matches no one.",
+		Remediation: "language: \"en\"    # or pt-BR, zh-Hant-TW, auto",
+		pattern:     regexp.MustCompile(`^Prompt \S+ language (is not a well-formed BCP-47 tag|has an unknown subtag \S+): `),
+	},
+	{
+		Code:        "INCONSISTENT_CHAIN_LANGUAGES",
+		Severity:    "warning",
+		Summary:     "Prompts composed through chain or next declare different languages.",
+		Rationale:   "The prompts run in one conversation, which would switch language midway.",
+		Remediation: "prompts:\n  - id: \"draft\"\n    language: \"it\"\n    next: \"review\"\n  - id: \"review\"\n    language: \"it\"",
+		pattern:     regexp.MustCompile(`^Chained prompts declare different languages: `),
+	},
+	{
+		Code:        "UNDECLARED_VARIABLE",
+		Severity:    "error",
+		Summary:     "A prompt template or few-shot example uses a variable that is neither declared by the prompt, global, nor a task input.",
+		Rationale:   "Undeclared variables are never substituted, so the example does not match what the model sees at runtime.",
+		Remediation: "variables:\n  order_id:\n    type: \"string\"\n    required: true",
+		pattern:     regexp.MustCompile(`references undeclared variable: `),
+	},
+	{
+		Code:        "UNUSED_VARIABLE",
+		Severity:    "warning",
+		Summary:     "A global context variable is not used by any prompt.",
+		Rationale:   "Variables nothing substitutes are usually leftovers or misspelled in the templates meant to use them.",
+		Remediation: "context:\n  variables:\n    company_name:\n      type: \"string\"\nprompts:\n  - id: \"system\"\n    template: \"You work for {{company_name}}.\"",
+		pattern:     regexp.MustCompile(`^Context variable \S+ is not used by any prompt$`),
+	},
+	{
+		Code:        "UNKNOWN_REFERENCE",
+		Severity:    "error",
+		Summary:     "A task step, prompt chain or model fallback or routing policy references a model, prompt, task, MCP server, knowledge source or workspace spec that is not declared.",
+		Rationale:   "The step cannot run because the element it names does not exist.",
+		Remediation: "steps:\n  - name: \"answer\"\n    action: \"generate\"\n    model: \"main_model\"    # an id declared in models",
+		pattern:     regexp.MustCompile(`^Task references unknown (model|prompt|task|MCP server|spec|knowledge source): |^Prompt \S+ references unknown prompt: |^Model \S+ \S+ references unknown model: |^Test case \S+ references unknown task: |^Experiment \S+ references unknown (prompt|model|metric): |^Metric \S+ references unknown (task|constraint): `),
+	},
+	{
+		Code:        "MISSING_EMBEDDING_MODEL",
+		Severity:    "error",
+		Summary:     "A knowledge base is declared but no model of type Embedding is.",
+		Rationale:   "Knowledge sources are indexed and searched by similarity of embeddings; without an embedding model retrieval cannot run.",
+		Remediation: "models:\n  - id: \"embedder\"\n    type: \"Embedding\"\n    provider: \"openai\"\n    name: \"text-embedding-3-small\"\n    purpose: \"knowledge base indexing\"",
+		pattern:     regexp.MustCompile(`^Knowledge base requires a model of type Embedding`),
+	},
+	{
+		Code:        "INVALID_RETRIEVAL_SETTING",
+		Severity:    "error",
+		Summary:     "A knowledge base retrieval setting is out of range.",
+		Rationale:   "top_k and chunk sizes are counts, the similarity threshold is a ratio, and chunks overlapping by their whole size never advance.",
+		Remediation: "knowledge_base:\n  retrieval:\n    top_k: 5\n    similarity_threshold: 0.75\n    chunk_size: 800\n    chunk_overlap: 100",
+		pattern:     regexp.MustCompile(`^Invalid retrieval setting: `),
+	},
+	{
+		Code:        "LARGE_RETRIEVAL_TOP_K",
+		Severity:    "warning",
+		Summary:     "A knowledge base retrieves more than 50 chunks per query.",
+		Rationale:   "Many retrieved chunks crowd out the rest of the prompt, add cost and latency, and dilute the relevant passages.",
+		Remediation: "knowledge_base:\n  retrieval:\n    top_k: 10",
+		pattern:     regexp.MustCompile(`^Retrieval top_k of \S+ is above `),
+	},
+	{
+		Code:        "INVALID_TTL",
+		Severity:    "error",
+		Summary:     "A persistence or session ttl is not a positive duration.",
+		Rationale:   "Stores expire state by its ttl; a value they cannot parse keeps state forever or drops it immediately.",
+		Remediation: "context:\n  persistence:\n    store: \"redis\"\n    url: \"${REDIS_URL}\"\n    ttl: \"30d\"    # or \"24h\"",
+		pattern:     regexp.MustCompile(`^context\.\w+ ttl must be a positive duration`),
+	},
+	{
+		Code:        "LONG_RETENTION",
+		Severity:    "warning",
+		Summary:     "Conversation state is kept for more than 90 days while a privacy constraint concerns retention.",
+		Rationale:   "Retention policies usually cap how long personal data is stored; a long ttl likely breaks the policy the constraint states.",
+		Remediation: "context:\n  persistence:\n    ttl: \"30d\"    # within the retention policy",
+		pattern:     regexp.MustCompile(`^context\.\w+ ttl of \S+ exceeds 90 days while privacy constraint `),
+	},
+	{
+		Code:        "PERSISTENCE_WITHOUT_MEMORY",
+		Severity:    "error",
+		Summary:     "Persistence or sessions are enabled while the memory type is none.",
+		Rationale:   "With no memory there is no conversation state to persist; one of the two settings is wrong.",
+		Remediation: "context:\n  memory:\n    type: \"persistent\"\n  persistence:\n    store: \"postgres\"\n    dsn: \"${DATABASE_URL}\"",
+		pattern:     regexp.MustCompile(`^context\.\w+ is enabled but context\.memory\.type is none$`),
+	},
+	{
+		Code:        "EXPERIMENT_TOO_FEW_VARIANTS",
+		Severity:    "error",
+		Summary:     "An experiment defines fewer than two variants.",
+		Rationale:   "An A/B experiment compares variants; with a single one there is nothing to compare and all traffic goes to it.",
+		Remediation: "experiments:\n  - id: \"greeting_tone\"\n    metric: \"satisfaction\"\n    variants:\n      - { id: \"control\", prompt: \"greeting\", traffic: 50 }\n      - { id: \"friendly\", prompt: \"greeting_friendly\", traffic: 50 }",
+		pattern:     regexp.MustCompile(`^Experiment \S+ needs at least two variants`),
+	},
+	{
+		Code:        "INVALID_TRAFFIC_SPLIT",
+		Severity:    "error",
+		Summary:     "The traffic of the variants of an experiment is not a percentage or does not sum to 100.",
+		Rationale:   "Traffic is split between variants by percentage; a split that does not add up to 100 leaves requests unassigned or over-assigned.",
+		Remediation: "variants:\n  - { id: \"control\", prompt: \"greeting\", traffic: 70 }\n  - { id: \"friendly\", prompt: \"greeting_friendly\", traffic: 30 }",
+		pattern:     regexp.MustCompile(`^Invalid traffic split for experiment `),
+	},
+	{
+		Code:        "OVERLAPPING_EXPERIMENTS",
+		Severity:    "warning",
+		Summary:     "Two active experiments vary the same prompt.",
+		Rationale:   "Requests to a prompt in two experiments at once are counted by both, so neither result can be attributed to its own variants.",
+		Remediation: "experiments:\n  - id: \"greeting_tone\"\n    status: \"paused\"    # run one experiment per prompt at a time",
+		pattern:     regexp.MustCompile(`^Active experiments \S+ and \S+ both vary prompt `),
+	},
+	{
+		Code:        "INVALID_CRON_SCHEDULE",
+		Severity:    "error",
+		Summary:     "A cron measurement schedule is not a valid five-field cron expression.",
+		Rationale:   "Schedulers reject a broken cron expression only when the evaluation job is due to run, so the metric silently stops being measured.",
+		Remediation: "measurement:\n  frequency: \"cron\"\n  schedule: \"0 3 * * 1\"    # minute hour day-of-month month day-of-week, or @daily",
+		pattern:     regexp.MustCompile(`^Invalid cron schedule for metric `),
+	},
+	{
+		Code:        "FREQUENT_HUMAN_REVIEW",
+		Severity:    "warning",
+		Summary:     "A metric measured by human review is scheduled more often than hourly.",
+		Rationale:   "Reviewers cannot keep up with evaluations due every few minutes; runs pile up unreviewed or are skipped.",
+		Remediation: "measurement:\n  method: \"manual_review\"\n  frequency: \"daily\"",
+		pattern:     regexp.MustCompile(`^Metric \S+ is measured more often than hourly by human review method `),
+	},
+	{
+		Code:        "UNMEASURED_CONSTRAINT",
+		Severity:    "warning",
+		Summary:     "A critical constraint has no evaluation metric linked to it.",
+		Rationale:   "Without a metric measuring it there is no way to know whether the guardrail holds in production.",
+		Remediation: "evaluation:\n  metrics:\n    - name: \"hallucination_rate\"\n      description: \"Share of answers with unsupported claims\"\n      target: 0.01\n      constraint: \"no_hallucination\"",
+		pattern:     regexp.MustCompile(`^Critical constraint \S+ has no metric linked to it$`),
+	},
+	{
+		Code:        "MISSING_JUDGE_MODEL",
+		Severity:    "warning",
+		Summary:     "A metric is judged by a model but no model has an evaluation or judging purpose.",
+		Rationale:   "llm_judge metrics need a model to grade outputs; without one declared for it, the metric is graded by the model under test or not at all.",
+		Remediation: "models:\n  - id: \"judge\"\n    purpose: \"Evaluate responses for the llm_judge metrics\"",
+		pattern:     regexp.MustCompile(`^Metric \S+ uses method llm_judge but no model has an evaluation or judging purpose$`),
+	},
+	{
+		Code:        "MISAPPLIED_STEP_FIELD",
+		Severity:    "warning",
+		Summary:     "A step sets fields its action does not use: a model or prompt on an MCP action, or MCP fields on another action.",
+		Rationale:   "MCP actions call a server and never a model, and other actions never call a server; the ignored fields usually mean the step has the wrong action.",
+		Remediation: "steps:\n  - name: \"lookup\"\n    action: \"mcp_tool\"\n    mcp_server: \"crm\"\n    mcp_tool: \"find_customer\"    # no model or prompt",
+		pattern:     regexp.MustCompile(`^Task \d+ step \d+ \S+ action sets \S+, which (MCP actions ignore|only MCP actions use)$`),
+	},
+	{
+		Code:        "NO_CONTACT",
+		Severity:    "warning",
+		Summary:     "None of info.author, info.contact or info.owners has an email or URL.",
+		Rationale:   "Names alone do not tell users or operators how to reach the people responsible when the system misbehaves.",
+		Remediation: "info:\n  author: \"Support Team\"\n  contact:\n    email: \"support@example.com\"",
+		pattern:     regexp.MustCompile(`^No email or url in info\.author, info\.contact or info\.owners `),
+	},
+	{
+		Code:        "COMPLEXITY_BUDGET",
+		Severity:    "warning",
+		Summary:     "A complexity metric of the specification exceeds the budget of the configuration; an error with budget.enforce.",
+		Rationale:   "Specifications with dozens of tasks, very long tasks or huge prompts are hard to review and to evaluate; the budget makes growth a deliberate decision.",
+		Remediation: "# .apai.yaml\nbudget:\n  max_tasks: 60             # raise a threshold deliberately\n  max_steps_per_task: 15\n  max_prompt_tokens: 8000",
+		pattern:     regexp.MustCompile(`^Complexity budget exceeded: `),
+	},
+	{
+		Code:        "TAB_INDENTATION",
+		Severity:    "warning",
+		Summary:     "A line of a YAML file is indented with a tab; reported by lint.",
+		Rationale:   "YAML forbids tabs in indentation, so the file fails to parse or, inside block scalars, renders differently across editors.",
+		Remediation: "tasks:\n  - id: \"answer\"    # two spaces, not a tab",
+		pattern:     regexp.MustCompile(`^Tab used for indentation$`),
+	},
+	{
+		Code:        "INCONSISTENT_INDENTATION",
+		Severity:    "warning",
+		Summary:     "A nested block is indented by a different width than the rest of the file; reported by lint.",
+		Rationale:   "Mixed indentation widths parse, but make the nesting of a long specification hard to follow and diffs noisy.",
+		Remediation: "context:\n  memory:          # the same width at every level\n    type: \"session\"",
+		pattern:     regexp.MustCompile(` is indented by \d+ spaces, unlike the \d+ of the rest of the file$`),
+	},
+	{
+		Code:        "TRAILING_WHITESPACE",
+		Severity:    "warning",
+		Summary:     "A line of a YAML file ends with spaces or tabs; reported by lint.",
+		Rationale:   "Invisible trailing whitespace ends up in block scalars such as prompt templates and produces noisy diffs.",
+		Remediation: "Remove the spaces and tabs at the end of the line, including on blank lines.",
+		pattern:     regexp.MustCompile(`^Trailing whitespace$`),
+	},
+	{
+		Code:        "SECTION_ORDER",
+		Severity:    "warning",
+		Summary:     "Top-level sections are out of canonical order; reported by lint.",
+		Rationale:   "Specifications read alike when sections follow the order of the schema: apai, info, models, prompts, constraints, tasks, context, evaluation.",
+		Remediation: "apai: \"0.1.0\"\ninfo: {...}\nmodels: [...]\nprompts: [...]    # before constraints and tasks",
+		pattern:     regexp.MustCompile(`^Section \S+ comes after \S+, out of the canonical order `),
+	},
+	{
+		Code:        "UNKNOWN_PROMOTED_CODE",
+		Severity:    "warning",
+		Summary:     "A code promoted to an error with promote or --error-on matches no rule.",
+		Rationale:   "A misspelled code silently promotes nothing, so the warnings it was meant to make fail the build keep passing.",
+		Remediation: "# .apai.yaml\npromote:\n  - UNUSED_MCP_SERVER    # a code listed by the rules command",
+		pattern:     regexp.MustCompile(`^Promoted code matches no rule: `),
+	},
+	{
+		Code:        "UNKNOWN_RELAXED_CODE",
+		Severity:    "warning",
+		Summary:     "A code relaxed to a warning with relax, --relax or --warn-on matches no rule.",
+		Rationale:   "A misspelled code silently relaxes nothing, so the errors it was meant to demote keep failing the build.",
+		Remediation: "# .apai.yaml\nrelax:\n  - MISSING_SECTION    # a code listed by the rules command",
+		pattern:     regexp.MustCompile(`^Relaxed code matches no rule: `),
+	},
+	{
+		Code:        "PARAMETER_OUT_OF_RANGE",
+		Severity:    "error",
+		Summary:     "A model parameter or a prompt override of it is outside the range providers accept.",
+		Rationale:   "Providers reject requests with out-of-range parameters such as a temperature above 2 or a top_p above 1.",
+		Remediation: "prompts:\n  - id: \"creative\"\n    config:\n      temperature: 1.2    # between 0 and 2\n      top_p: 0.9          # between 0 and 1",
+		pattern:     regexp.MustCompile(`^Parameter out of range for (model|prompt) \S+: `),
+	},
+	{
+		Code:        "MAX_TOKENS_EXCEEDS_CONTEXT_WINDOW",
+		Severity:    "error",
+		Summary:     "A model or a step running it requests more max_tokens than the model's context_window.",
+		Rationale:   "A request for more tokens than the model's context window holds is rejected by the provider at runtime.",
+		Remediation: "models:\n  - id: \"fast\"\n    context_window: 8192\n    parameters:\n      max_tokens: 4096    # at most context_window",
+		pattern:     regexp.MustCompile(`^(Model|Step) \S+ requests max_tokens .*, beyond its context_window of `),
+	},
+	{
+		Code:        "UNAPPROVED_MODEL",
+		Severity:    "error",
+		Summary:     "A model is not in the organization's approved models list.",
+		Rationale:   "Governance policies only allow models that passed review; others must not reach production.",
+		Remediation: "Use a provider and name from the approved models list, or have the model approved and added to it.",
+		pattern:     regexp.MustCompile(`^Model \S+ uses \S+, which is not in the approved models of `),
+	},
+	{
+		Code:        "SELF_FALLBACK",
+		Severity:    "error",
+		Summary:     "A model lists itself as its own fallback.",
+		Rationale:   "When the model fails, retrying the same model is not a fallback and the failure is never handled.",
+		Remediation: "models:\n  - id: \"main_model\"\n    fallback: \"backup_model\"    # a different declared model",
+		pattern:     regexp.MustCompile(`lists itself as its own fallback$`),
+	},
+	{
+		Code:        "TASK_WITHOUT_STEPS",
+		Severity:    "warning",
+		Summary:     "A task has no steps and is not marked abstract.",
+		Rationale:   "A task without steps does nothing when run; unless it is a template for inheriting specs, it is usually incomplete.",
+		Remediation: "tasks:\n  - id: \"base_support\"\n    description: \"Template for support tasks\"\n    abstract: true",
+		pattern:     regexp.MustCompile(`^Task \S+ has no steps; `),
+	},
+	{
+		Code:        "UNPROVIDED_STEP_INPUT",
+		Severity:    "error",
+		Summary:     "A step input is neither a task input nor the output of an earlier step.",
+		Rationale:   "The step reads a value nothing produces before it runs, so it fails or works on an empty value.",
+		Remediation: "steps:\n  - name: \"lookup\"\n    action: \"retrieve\"\n    outputs: [\"order\"]\n  - name: \"answer\"\n    action: \"generate\"\n    inputs: [\"order\"]    # produced by lookup",
+		pattern:     regexp.MustCompile(`^Step \S+ input \S+ is not provided by the task or an earlier step$`),
+	},
+	{
+		Code:        "CONDITIONAL_STEP_INPUT",
+		Severity:    "warning",
+		Summary:     "A step input is only produced on some of the paths leading to the step.",
+		Rationale:   "When a condition skips the step producing the value, the step reading it runs without it.",
+		Remediation: "Produce the value on every branch before the step, or declare it as a task input.",
+		pattern:     regexp.MustCompile(`^Step \S+ input \S+ is only provided on some paths to it$`),
+	},
+	{
+		Code:        "UNUSED_STEP_OUTPUT",
+		Severity:    "warning",
+		Summary:     "A step output is never read by a later step or the task output.",
+		Rationale:   "An output nothing consumes is either dead work or a sign that a consumer names it differently.",
+		Remediation: "Read the output in a later step's inputs, list it in the task output, or remove it.",
+		pattern:     regexp.MustCompile(`^Step \S+ output \S+ is never consumed$`),
+	},
+	{
+		Code:        "CONFLICTING_OUTPUT_TYPES",
+		Severity:    "error",
+		Summary:     "Two steps on the same path produce an output with different types.",
+		Rationale:   "Steps reading the value cannot know which type they get, depending on which step wrote it last.",
+		Remediation: "outputs:\n  summary:\n    type: \"string\"    # the same type in every step producing summary",
+		pattern:     regexp.MustCompile(`^Steps \S+ and \S+ produce output \S+ with different types: `),
+	},
+	{
+		Code:        "INVALID_TASK_SCHEMA",
+		Severity:    "error",
+		Summary:     "A task input_schema or output_schema is not a valid JSON Schema.",
+		Rationale:   "An invalid schema cannot check the values the task exchanges; runtimes either reject it or silently skip validation.",
+		Remediation: "input_schema:\n  type: \"object\"           # array, boolean, integer, null, number, object or string\n  properties:\n    order_id:\n      type: \"string\"\n  required: [\"order_id\"]     # names declared in properties",
+		pattern:     regexp.MustCompile(`^Task \S+ (input|output)_schema\S* (is not a valid JSON Schema|is not a JSON Schema type: |lists \S+, which is not in properties$)`),
+	},
+	{
+		Code:        "TASK_SCHEMA_MISMATCH",
+		Severity:    "error",
+		Summary:     "An input does not conform to the input_schema of its task.",
+		Rationale:   "A test case whose input the task would reject tests nothing the task can actually receive.",
+		Remediation: "Fix the test case input, or the task input_schema if the input is right.",
+		pattern:     regexp.MustCompile(`does not match the input_schema of task \S+ at `),
+	},
+	{
+		Code:        "PARALLEL_DEPENDENCY",
+		Severity:    "error",
+		Summary:     "A step consumes the output of another step of its parallel group.",
+		Rationale:   "Steps of a parallel group start together, so the output is not there yet when the consuming step runs.",
+		Remediation: "Move the consuming step after the group, where it joins the outputs of every member.",
+		pattern:     regexp.MustCompile(`^Step \S+ input \S+ is produced by step \S+ of the same parallel group `),
+	},
+	{
+		Code:        "PARALLEL_GROUP_FLOW",
+		Severity:    "error",
+		Summary:     "A parallel group is split, entered in its middle or branches out before its join.",
+		Rationale:   "A group runs as one unit: all members start together and the next step waits on all of them, which flow edges into or out of single members break.",
+		Remediation: "steps:\n  - name: \"fetch_orders\"\n    parallel_group: \"fetch\"    # adjacent members, entered at the first\n  - name: \"fetch_profile\"\n    parallel_group: \"fetch\"\n  - name: \"answer\"            # joins both",
+		pattern:     regexp.MustCompile(`of parallel group \S+ (is not next to the other members|branches with then)|jumps into the middle of parallel group `),
+	},
+	{
+		Code:        "SINGLE_STEP_PARALLEL_GROUP",
+		Severity:    "warning",
+		Summary:     "A parallel group has a single step.",
+		Rationale:   "A group of one runs nothing in parallel; a member was probably given a different group name or moved.",
+		Remediation: "Add the steps that should run alongside it to the group, or remove parallel_group.",
+		pattern:     regexp.MustCompile(`^Parallel group \S+ of task \S+ has a single step$`),
+	},
+	{
+		Code:        "ABSTRACT_TASK_RUN",
+		Severity:    "error",
+		Summary:     "A task step runs a task marked abstract.",
+		Rationale:   "Abstract tasks are templates for inheriting specs and are not meant to be executed.",
+		Remediation: "steps:\n  - name: \"handle\"\n    action: \"generate\"\n    task: \"customer_support\"    # a concrete task, not a template",
+		pattern:     regexp.MustCompile(`^Abstract task \S+ cannot be run by `),
+	},
+	{
+		Code:        "UNUSED_MCP_SERVER",
+		Severity:    "warning",
+		Summary:     "An MCP server is declared but no task step uses it.",
+		Rationale:   "Unused servers widen the attack surface and configuration burden without benefit.",
+		Remediation: "# reference it from a step, or remove it from context.mcp_servers\nsteps:\n  - name: \"lookup\"\n    action: \"mcp_tool\"\n    mcp_server: \"orders\"\n    mcp_tool: \"get_order\"",
+		pattern:     regexp.MustCompile(`is declared but never used$`),
+	},
+	{
+		Code:        "UNREFERENCED_SPEC",
+		Severity:    "warning",
+		Summary:     "A workspace spec is not referenced by any other spec of the workspace.",
+		Rationale:   "Members that nothing runs are often leftovers; entry points should be listed as roots of the workspace.",
+		Remediation: "# workspace.yaml\nmembers:\n  - agents/*.yaml\nroots:\n  - triage    # entry points nothing else references",
+		pattern:     regexp.MustCompile(`^Spec \S+ is not referenced by any other spec in the workspace$`),
+	},
+	{
+		Code:        "UNRESOLVED_REF",
+		Severity:    "error",
+		Summary:     "A $ref pointer does not designate any value in the specification.",
+		Rationale:   "The referenced block was renamed, moved or never defined, so the field has no value.",
+		Remediation: "definitions:\n  support_template: \"You are a helpful assistant\"\nprompts:\n  - id: \"system_prompt\"\n    template:\n      $ref: \"#/definitions/support_template\"",
+		pattern:     regexp.MustCompile(`^Unresolved \$ref at `),
+	},
+	{
+		Code:        "CIRCULAR_REF",
+		Severity:    "error",
+		Summary:     "$ref pointers refer to each other in a cycle.",
+		Rationale:   "A cycle never reaches a concrete value, so the fields involved are undefined.",
+		Remediation: "definitions:\n  base: \"You are a helpful assistant\"\n  support:\n    $ref: \"#/definitions/base\"    # must not point back to support",
+		pattern:     regexp.MustCompile(`^Circular \$ref at `),
+	},
+	{
+		Code:        "FILE_NOT_FOUND",
+		Severity:    "error",
+		Summary:     "A referenced dataset or knowledge source file does not exist (file checks only).",
+		Rationale:   "Broken paths otherwise surface only when the pipeline runs; paths are relative to the specification file.",
+		Remediation: "evaluation:\n  datasets:\n    - path: \"data/eval.jsonl\"    # relative to this file",
+		pattern:     regexp.MustCompile(` file not found: `),
+	},
+	{
+		Code:        "EMPTY_FILE",
+		Severity:    "warning",
+		Summary:     "A referenced dataset or knowledge source file is empty (file checks only).",
+		Rationale:   "An empty dataset evaluates nothing and an empty source adds no knowledge.",
+		Remediation: "# fill the file, or remove the reference",
+		pattern:     regexp.MustCompile(` file is empty: `),
+	},
+	{
+		Code:        "INVALID_SOURCE_URL",
+		Severity:    "error",
+		Summary:     "A knowledge source URL is malformed (file checks only).",
+		Rationale:   "Sources are fetched over HTTP(S); a URL without scheme or host cannot be fetched.",
+		Remediation: "sources:\n  - url: \"https://docs.example.com/faq\"",
+		pattern:     regexp.MustCompile(`\.url is malformed: `),
+	},
+	{
+		Code:        "UNREACHABLE_URL",
+		Severity:    "warning",
+		Summary:     "A knowledge source URL did not answer a HEAD request (URL checks only).",
+		Rationale:   "The source may have moved or require credentials the pipeline does not have.",
+		Remediation: "sources:\n  - url: \"https://docs.example.com/faq\"    # check the address is still served",
+		pattern:     regexp.MustCompile(`\.url is unreachable: `),
+	},
+	{
+		Code:        "UNKNOWN_FIELD",
+		Severity:    "error",
+		Summary:     "A field is not defined by the specification (strict fields only).",
+		Rationale:   "Unknown fields are often misspelled known fields that tools silently ignore; custom metadata belongs in x- extension fields.",
+		Remediation: "models:\n  - id: \"main_model\"\n    x-cost-center: \"payments\"    # instead of cost_center",
+		pattern:     regexp.MustCompile(`^Unknown field: `),
+	},
+	{
+		Code:        "RESERVED_EXTENSION",
+		Severity:    "error",
+		Summary:     "An extension field uses the reserved x-apai- prefix.",
+		Rationale:   "The x-apai- namespace is reserved for APAI tooling so its fields never clash with custom metadata.",
+		Remediation: "x-team: \"payments\"    # not x-apai-team",
+		pattern:     regexp.MustCompile(`^Reserved extension field: `),
+	},
+	{
+		Code:        "DEPRECATED_FIELD",
+		Severity:    "warning",
+		Summary:     "A field was renamed in the declared schema version.",
+		Rationale:   "Deprecated names keep working for now but will be removed; the migrate command rewrites them.",
+		Remediation: "models:\n  - id: \"main_model\"\n    intended_use: \"conversation\"    # was purpose",
+		pattern:     regexp.MustCompile(` is deprecated since \S+, use `),
+	},
+	{
+		Code:        "DEPRECATED_FIELD_CONFLICT",
+		Severity:    "error",
+		Summary:     "A deprecated field and its replacement are both set with different values.",
+		Rationale:   "It is ambiguous which value applies; tools reading the old and new name would behave differently.",
+		Remediation: "models:\n  - id: \"main_model\"\n    intended_use: \"conversation\"    # remove purpose",
+		pattern:     regexp.MustCompile(` conflicts with \S+: deprecated and replacement fields have different values$`),
+	},
+	{
+		Code:        "CONTRADICTORY_CONSTRAINTS",
+		Severity:    "warning",
+		Summary:     "Two constraints allow disjoint ranges for the same field.",
+		Rationale:   "No value satisfies both rules, so one of them is always violated; usually a bound was mistyped or copied from another constraint.",
+		Remediation: "constraints:\n  - id: \"low_temperature\"\n    rule: \"temperature <= 0.3\"\n  - id: \"creative_output\"\n    rule: \"temperature <= 0.7\"    # not >= 0.7",
+		pattern:     regexp.MustCompile(`^Constraints .+ contradict each other: `),
+	},
+	{
+		Code:        "NEGATIVE_COST",
+		Severity:    "error",
+		Summary:     "A model cost rate is negative.",
+		Rationale:   "Budgeting tools and cost estimates multiply rates by token counts; a negative rate turns spend into savings and hides the real cost.",
+		Remediation: "models:\n  - id: \"main_model\"\n    cost:\n      input_per_1k_tokens: 0.03    # not -0.03\n      output_per_1k_tokens: 0.06\n      currency: \"USD\"",
+		pattern:     regexp.MustCompile(`^Negative cost for model `),
+	},
+	{
+		Code:        "INVALID_COST",
+		Severity:    "error",
+		Summary:     "A model cost block lacks numeric rates or a currency code.",
+		Rationale:   "Cost metadata is only usable for budgeting when every rate is a number per 1,000 tokens and the currency is known.",
+		Remediation: "models:\n  - id: \"main_model\"\n    cost:\n      input_per_1k_tokens: 0.03\n      output_per_1k_tokens: 0.06\n      currency: \"USD\"",
+		pattern:     regexp.MustCompile(`^Invalid cost for model `),
+	},
+	{
+		Code:        "INVALID_REGEX",
+		Severity:    "error",
+		Summary:     "A regex constraint has no pattern or one that does not compile.",
+		Rationale:   "An invalid pattern only surfaces when the constraint is first enforced, at runtime.",
+		Remediation: "constraints:\n  - id: \"no_card_numbers\"\n    type: \"regex\"\n    rule: \"output NOT matches pattern\"\n    pattern: \"\\\\b(?:\\\\d[ -]?){13,16}\\\\b\"\n    severity: \"critical\"",
+		pattern:     regexp.MustCompile(`^Constraint \S+ (of type regex missing pattern|pattern is not a valid regular expression: )`),
+	},
+	{
+		Code:        "INVALID_LIMIT",
+		Severity:    "error",
+		Summary:     "A model rate limit or quota is not a positive number, or admits no request of max_tokens.",
+		Rationale:   "Gateways and schedulers enforce these limits as numbers; zero or a token rate below max_tokens blocks every full-length request.",
+		Remediation: "models:\n  - id: \"main_model\"\n    parameters:\n      max_tokens: 2000\n    rate_limit:\n      requests_per_minute: 60\n      tokens_per_minute: 90000\n    quota:\n      daily_budget: 50",
+		pattern:     regexp.MustCompile(`^Invalid limit for model `),
+	},
+	{
+		Code:        "CONFLICTING_RATE_LIMITS",
+		Severity:    "warning",
+		Summary:     "Models of the same provider and name declare different rate limits.",
+		Rationale:   "Entries for the same provider model draw on the same account quota, so at most one of the limits is right.",
+		Remediation: "# declare the same rate_limit on every entry of openai/gpt-4o",
+		pattern:     regexp.MustCompile(`^Models .+ declare different rate_limit\.\S+; they share the same provider quota$`),
+	},
+	{
+		Code:        "MIXED_CURRENCIES",
+		Severity:    "warning",
+		Summary:     "Models declare costs in different currencies.",
+		Rationale:   "Aggregate cost estimates add up rates as they are; mixing currencies makes the totals meaningless.",
+		Remediation: "models:\n  - id: \"main_model\"\n    cost:\n      currency: \"USD\"\n  - id: \"fallback_model\"\n    cost:\n      currency: \"USD\"    # not EUR",
+		pattern:     regexp.MustCompile(`^Models declare costs in different currencies: `),
+	},
+	{
+		Code:        "ENUM_CASING",
+		Severity:    "warning",
+		Summary:     "An enum value matches an allowed value only when ignoring case.",
+		Rationale:   "Values such as System or High are accepted, but tools comparing them exactly may not recognize them; the fix command rewrites them in their canonical casing.",
+		Remediation: "prompts:\n  - id: \"system_prompt\"\n    role: \"system\"    # not System",
+		pattern:     regexp.MustCompile(`^Non-canonical casing for `),
+	},
+	{
+		Code:        "COMPLIANCE_VIOLATION",
+		Severity:    "error",
+		Summary:     "A requirement of a selected compliance profile is not met.",
+		Rationale:   "Profiles such as eu-ai-act require documented risk classification, oversight and safeguards before a system may be deployed; the message names the profile rule and its legal reference.",
+		Remediation: "info:\n  ai_metadata:\n    risk_level: \"high\"\n    human_oversight: \"Agents review every escalated conversation\"",
+		pattern:     regexp.MustCompile(`^Compliance \S+ violated`),
+	},
+	{
+		Code:        "PLUGIN_FAILED",
+		Severity:    "error",
+		Summary:     "A rule plugin crashed, timed out or answered with invalid output.",
+		Rationale:   "The plugin's rules were not checked, so the specification cannot be considered valid; plugins must exit zero within the timeout and print a JSON array of issues.",
+		Remediation: "[{\"code\": \"ORG_MODEL_NOT_APPROVED\", \"severity\": \"error\", \"path\": \"models[0].provider\", \"message\": \"provider acme is not approved\"}]",
+		pattern:     regexp.MustCompile(`^Plugin \S+ failed: |^Plugins not run: `),
+	},
+	{
+		Code:        "CIRCULAR_PROMPT_CHAIN",
+		Severity:    "error",
+		Summary:     "Prompts refer to each other through chain or next in a cycle.",
+		Rationale:   "Following the chain never ends, so a runtime executing it loops forever.",
+		Remediation: "prompts:\n  - id: \"draft\"\n    next: \"review\"\n  - id: \"review\"    # no next pointing back to draft",
+		pattern:     regexp.MustCompile(`^Circular prompt chain: `),
+	},
+	{
+		Code:        "CIRCULAR_INHERITANCE",
+		Severity:    "error",
+		Summary:     "Specifications inherit from each other in a cycle.",
+		Rationale:   "A cycle has no base to merge from, so the effective specification is undefined.",
+		Remediation: "# base.yaml must not inherit from a file that inherits from it\ninherits:\n  - \"../base.yaml\"",
+		pattern:     regexp.MustCompile(`^Circular inheritance: `),
+	},
+	{
+		Code:        "SELF_INHERITANCE",
+		Severity:    "error",
+		Summary:     "A specification lists itself in inherits.",
+		Rationale:   "A specification cannot be its own parent; the entry is usually a copy-paste mistake for the intended base.",
+		Remediation: "# app.yaml\ninherits:\n  - \"../base.yaml\"    # not \"app.yaml\"",
+		pattern:     regexp.MustCompile(`^Specification inherits from itself: `),
+	},
+	{
+		Code:        "DUPLICATE_INHERITS",
+		Severity:    "warning",
+		Summary:     "The same parent is listed more than once in inherits.",
+		Rationale:   "The parent is merged only once, at its last position, so earlier entries have no effect and obscure the intended precedence.",
+		Remediation: "inherits:\n  - \"../base.yaml\"\n  - \"../team.yaml\"    # list each parent once",
+		pattern:     regexp.MustCompile(`^Duplicate inherits entry in `),
+	},
+	{
+		Code:        "INHERITANCE_LIMIT",
+		Severity:    "error",
+		Summary:     "The inheritance hierarchy exceeds the depth or size limit.",
+		Rationale:   "Very deep or wide hierarchies are hard to reason about and slow to resolve.",
+		Remediation: "# .apai.yaml\nmax_inheritance_depth: 20\nmax_inherited_specs: 200",
+		pattern:     regexp.MustCompile(`^(Inheritance depth|Inherited specification) limit of \d+ exceeded: `),
+	},
+	{
+		Code:        "INHERITANCE_NOT_FOUND",
+		Severity:    "error",
+		Summary:     "An inherited specification cannot be found.",
+		Rationale:   "The parent's sections cannot be merged, so the specification is incomplete.",
+		Remediation: "inherits:\n  - \"../base.yaml\"    # relative to this file\n  - \"org/base@1.2.0\"  # resolved from --spec-root",
+		pattern:     regexp.MustCompile(`^Inherited specification not found: `),
+	},
+	{
+		Code:        "HIERARCHY_LEVEL_INVERSION",
+		Severity:    "warning",
+		Summary:     "A specification inherits from one at the same or a narrower hierarchy level.",
+		Rationale:   "Broader levels set the defaults that narrower ones refine; a team spec inheriting from a feature spec turns that around, so hierarchy_info no longer describes the chain.",
+		Remediation: "# team/apai.yaml declares level: team\ninherits:\n  - \"../apai-department.yaml\"    # level: department",
+		pattern:     regexp.MustCompile(`^Hierarchy level inversion: `),
+	},
+	{
+		Code:        "HIERARCHY_LEVEL_SKIP",
+		Severity:    "warning",
+		Summary:     "A specification inherits from one more than one hierarchy level above it.",
+		Rationale:   "Skipped levels are bypassed, so policies set there silently do not apply.",
+		Remediation: "# feature/apai.yaml declares level: feature\ninherits:\n  - \"../apai-sprint.yaml\"    # level: sprint, not global",
+		pattern:     regexp.MustCompile(`^Hierarchy level skip: `),
+	},
+	{
+		Code:        "SHADOWED_SECTION",
+		Severity:    "warning",
+		Summary:     "A specification's models, prompts, constraints or tasks replace an inherited section that has elements it does not list.",
+		Rationale:   "Sections are not merged by id: the inherited section is replaced as a whole, so the elements left out silently stop applying.",
+		Remediation: "inherits:\n  - \"../org/base.yaml\"\nconstraints:\n  - id: \"no-pii\"    # copied from base.yaml to keep it\n    rule: \"output NOT contains pii\"\n    severity: \"critical\"\n  - id: \"tone\"\n    rule: \"output NOT contains profanity\"\n    severity: \"medium\"",
+		pattern:     regexp.MustCompile(`^Shadowed section (models|prompts|constraints|tasks) in `),
+	},
+	{
+		Code:        "MERGE_CONFLICT",
+		Severity:    "error",
+		Summary:     "Two parents of a specification define the same models, prompts, constraints or tasks section, and the later drops or redefines elements of the earlier.",
+		Rationale:   "The section of the last parent replaces the others as a whole, so which elements apply depends only on the order of inherits.",
+		Remediation: "# define the section in the inheriting specification with the elements of both parents\nconstraints:\n  - id: \"no-pii\"\n    rule: \"output NOT contains pii\"\n    severity: \"critical\"\n  - id: \"no-secrets\"\n    rule: \"output NOT contains api_key\"\n    severity: \"critical\"",
+		pattern:     regexp.MustCompile(`^Merge conflict for (models|prompts|constraints|tasks) between `),
+	},
+	{
+		Code:        "SEVERITY_DOWNGRADE",
+		Severity:    "warning",
+		Summary:     "A specification overrides an inherited constraint with a lower severity.",
+		Rationale:   "Broader levels set constraint severities as policy; lowering one in a narrower spec weakens it without review.",
+		Remediation: "constraints:\n  - id: \"no-pii\"\n    severity: \"critical\"    # as inherited",
+		pattern:     regexp.MustCompile(`^Severity downgrade of constraint `),
+	},
+}
+
+// LookupRule returns the rule with the given code, ignoring case
+func LookupRule(code string) (Rule, bool) {
+	for _, rule := range rules {
+		if strings.EqualFold(rule.Code, code) {
+			return rule, true
+		}
+	}
+	return Rule{}, false
+}
+
+// MatchRule returns the rule whose pattern matches the given message
+func MatchRule(message string) (Rule, bool) {
+	for _, rule := range rules {
+		if rule.pattern.MatchString(message) {
+			return rule, true
+		}
+	}
+	return Rule{}, false
+}
+
+// ruleCodes returns the codes of all rules in registry order
+func ruleCodes() []string {
+	codes := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		codes = append(codes, rule.Code)
+	}
+	return codes
+}
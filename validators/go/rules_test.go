@@ -0,0 +1,111 @@
+package main
+
+import "testing"
+
+func TestEveryFindingHasARule(t *testing.T) {
+	spec := map[string]interface{}{
+		"apai": "0.2.0",
+		"info": map[string]interface{}{
+			"title":   "Broken",
+			"author":  map[string]interface{}{"email": "not-an-email"},
+			"contact": map[string]interface{}{"url": "ftp://example.com"},
+			"ai_metadata": map[string]interface{}{
+				"complexity": "extreme",
+			},
+		},
+		"models": []interface{}{
+			map[string]interface{}{"id": "m", "type": "Quantum", "provider": "x", "name": "y", "purpose": "z",
+				"parameters": map[string]interface{}{"temperature": "0.7"},
+				"cost":       map[string]interface{}{"input_per_1k_tokens": -1, "output_per_1k_tokens": true, "currency": "EUR"}},
+			map[string]interface{}{"id": "m", "cost": map[string]interface{}{"input_per_1k_tokens": 1, "currency": "USD"}},
+		},
+		"prompts": []interface{}{
+			map[string]interface{}{"id": "p", "role": "narrator", "template": "Hi", "next": "q",
+				"examples": []interface{}{map[string]interface{}{"input": "{{name}}"}}},
+			map[string]interface{}{"id": "q", "role": "System", "template": "Hi", "examples": []interface{}{},
+				"chain": []interface{}{"p", "ghost"}},
+			map[string]interface{}{"id": "r", "role": "user", "template": "Hi", "chain": "p"},
+		},
+		"constraints": "none",
+		"tasks": []interface{}{
+			map[string]interface{}{"id": "t", "description": "Do it", "steps": []interface{}{
+				map[string]interface{}{"name": "s", "action": "teleport", "model": "missing"},
+				map[string]interface{}{"name": "u", "action": "mcp_tool"},
+				map[string]interface{}{"name": "b", "action": "generate", "task": "base"},
+			}},
+			map[string]interface{}{"id": "base", "description": "Template", "abstract": true},
+			map[string]interface{}{"id": "empty", "description": "Unfinished", "abstract": "yes"},
+		},
+		"context": map[string]interface{}{
+			"mcp_servers": []interface{}{
+				map[string]interface{}{"id": "files", "name": "Files", "description": "d", "version": "1",
+					"capabilities":   map[string]interface{}{},
+					"transport":      map[string]interface{}{"type": "stdio"},
+					"authentication": map[string]interface{}{"type": "api_key"}},
+			},
+		},
+	}
+
+	validator := NewAPAIValidator()
+	validator.ValidateSpec(spec)
+	if len(validator.Errors) == 0 || len(validator.Warnings) == 0 {
+		t.Fatal("expected both errors and warnings")
+	}
+
+	for _, message := range append(validator.GetErrors(), validator.GetWarnings()...) {
+		code, ok := validator.codes[message]
+		if !ok {
+			t.Errorf("no code recorded for %q", message)
+			continue
+		}
+		if _, ok := LookupRule(code); !ok {
+			t.Errorf("%q is recorded with unknown code %s", message, code)
+		}
+		// Baselined findings are matched to their rule by pattern
+		if rule, _ := MatchRule(message); rule.Code != code {
+			t.Errorf("%q is recorded as %s but matches %s", message, code, rule.Code)
+		}
+	}
+}
+
+func TestRecordedCodeOutlivesPattern(t *testing.T) {
+	message := "The evaluation section is missing"
+	if issue := newIssue("error", message); issue.Code != "" {
+		t.Fatalf("a rule pattern matches %q", message)
+	}
+
+	validator := NewAPAIValidator()
+	validator.addError("MISSING_SECTION", message)
+	for _, finding := range []string{message, "In parent base.yaml: " + message} {
+		if issue := validator.issue("error", finding); issue.Code != "MISSING_SECTION" {
+			t.Errorf("issue(%q) has code %q, want MISSING_SECTION", finding, issue.Code)
+		}
+	}
+	if issue := validator.GetResults().issue("error", message); issue.Code != "MISSING_SECTION" {
+		t.Errorf("result issue has code %q, want MISSING_SECTION", issue.Code)
+	}
+}
+
+func TestLookupRule(t *testing.T) {
+	rule, ok := LookupRule("duplicate_id")
+	if !ok || rule.Code != "DUPLICATE_ID" {
+		t.Fatalf("LookupRule(duplicate_id) = %v, %v", rule.Code, ok)
+	}
+	if _, ok := LookupRule("NOT_A_CODE"); ok {
+		t.Error("unknown code was found")
+	}
+
+	seen := make(map[string]bool)
+	for _, rule := range rules {
+		if seen[rule.Code] {
+			t.Errorf("duplicate rule code %s", rule.Code)
+		}
+		seen[rule.Code] = true
+		if rule.Summary == "" || rule.Rationale == "" || rule.Remediation == "" {
+			t.Errorf("rule %s is not fully documented", rule.Code)
+		}
+		if rule.Severity != "error" && rule.Severity != "warning" {
+			t.Errorf("rule %s has unknown severity %q", rule.Code, rule.Severity)
+		}
+	}
+}
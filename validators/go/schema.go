@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// JSONSchema is an external JSON Schema, such as an organization's stricter
+// variant of the APAI schema, that specifications must also satisfy
+type JSONSchema struct {
+	// Name identifies the schema in findings, the base name of its file
+	Name   string
+	schema *jsonschema.Schema
+}
+
+// LoadJSONSchema compiles the JSON Schema in a file. The schema may use any
+// draft from 4 to 2020-12, declared with $schema (default: 2020-12), and
+// $ref other schema files relative to it.
+func LoadJSONSchema(filePath string) (*JSONSchema, error) {
+	absolute, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema %s: %v", filePath, err)
+	}
+	schema, err := jsonschema.NewCompiler().Compile(absolute)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema %s: %v", filePath, err)
+	}
+	return &JSONSchema{Name: filepath.Base(filePath), schema: schema}, nil
+}
+
+// validateJSONSchemas validates the document as written against the
+// external schemas, reporting each violation as an error
+func (v *APAIValidator) validateJSONSchemas(spec map[string]interface{}) {
+	document, err := jsonDocument(spec)
+	if err != nil {
+		v.addError("SCHEMA_VIOLATION", fmt.Sprintf("Schema violation at root: cannot convert the specification to JSON: %v", err))
+		return
+	}
+
+	for _, schema := range v.schemas {
+		err := schema.schema.Validate(document)
+		var validationErr *jsonschema.ValidationError
+		if errors.As(err, &validationErr) {
+			for _, cause := range schemaViolations(validationErr) {
+				v.addError("SCHEMA_VIOLATION", fmt.Sprintf("Schema violation at %s: %s (%s)", schemaLocation(cause.InstanceLocation), cause.Message, schema.Name))
+			}
+		} else if err != nil {
+			v.addError("SCHEMA_VIOLATION", fmt.Sprintf("Schema violation at root: %v (%s)", err, schema.Name))
+		}
+	}
+}
+
+// jsonDocument converts a decoded value to the form the schema library
+// expects, as encoding/json decodes it
+func jsonDocument(value interface{}) (interface{}, error) {
+	var buf bytes.Buffer
+	if err := writeCanonicalJSON(&buf, value, false); err != nil {
+		return nil, err
+	}
+	var document interface{}
+	decoder := json.NewDecoder(&buf)
+	decoder.UseNumber()
+	if err := decoder.Decode(&document); err != nil {
+		return nil, err
+	}
+	return document, nil
+}
+
+// schemaViolations returns the innermost causes of a validation error,
+// which name the keywords that failed
+func schemaViolations(err *jsonschema.ValidationError) []*jsonschema.ValidationError {
+	if len(err.Causes) == 0 {
+		return []*jsonschema.ValidationError{err}
+	}
+	violations := make([]*jsonschema.ValidationError, 0, len(err.Causes))
+	for _, cause := range err.Causes {
+		violations = append(violations, schemaViolations(cause)...)
+	}
+	return violations
+}
+
+// schemaLocation turns a JSON pointer such as /models/0/name into the
+// location models[0].name, or "root" for the whole document
+func schemaLocation(pointer string) string {
+	location := ""
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		if token == "" {
+			continue
+		}
+		if _, err := strconv.Atoi(token); err == nil {
+			location = fmt.Sprintf("%s[%s]", location, token)
+			continue
+		}
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		location = joinLocation(location, token)
+	}
+	if location == "" {
+		return "root"
+	}
+	return location
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestJSONSchemaValidation(t *testing.T) {
+	schema, err := LoadJSONSchema("testdata/schemas/org-strict.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec := loadExampleSpecs(t, "core/customer-support.yaml")[0]
+
+	validator := NewAPAIValidator(WithJSONSchemas(schema))
+	validator.ValidateSpec(spec)
+	want := []string{
+		"Schema violation at models[0].parameters.temperature: must be <= 0.5 but found 0.7 (org-strict.json)",
+		`Schema violation at models[1].provider: value must be one of "openai", "anthropic" (org-strict.json)`,
+	}
+	for _, message := range want {
+		if !containsString(validator.Errors, message) {
+			t.Errorf("expected %q in %v", message, validator.Errors)
+		}
+		if rule, _ := MatchRule(message); rule.Code != "SCHEMA_VIOLATION" {
+			t.Errorf("expected %q to match SCHEMA_VIOLATION, got %s", message, rule.Code)
+		}
+	}
+
+	validator = NewAPAIValidator()
+	validator.ValidateSpec(spec)
+	for _, message := range want {
+		if containsString(validator.Errors, message) {
+			t.Errorf("unexpected %q without a custom schema", message)
+		}
+	}
+}
+
+func TestLoadJSONSchemaErrors(t *testing.T) {
+	if _, err := LoadJSONSchema(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("expected an error for a missing schema")
+	}
+
+	invalidPath := filepath.Join(t.TempDir(), "invalid.json")
+	if err := os.WriteFile(invalidPath, []byte(`{"type": 12}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadJSONSchema(invalidPath); err == nil {
+		t.Error("expected an error for an invalid schema")
+	}
+}
+
+func TestSchemaLocation(t *testing.T) {
+	cases := map[string]string{
+		"":                         "root",
+		"/models/0/name":           "models[0].name",
+		"/context/mcp_servers/1":   "context.mcp_servers[1]",
+		"/extensions/x-team~1name": "extensions.x-team/name",
+	}
+	for pointer, want := range cases {
+		if got := schemaLocation(pointer); got != want {
+			t.Errorf("schemaLocation(%q) = %q, want %q", pointer, got, want)
+		}
+	}
+}
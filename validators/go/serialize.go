@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// sectionOrder lists the known top-level sections in schema order
+var sectionOrder = []string{
+	"apai", "info", "models", "prompts",
+	"constraints", "tasks", "context", "evaluation",
+}
+
+// leadingFields lists the fields placed first within nested objects
+var leadingFields = []string{"id", "name", "description"}
+
+// canonicalKeys returns the keys of a map in canonical serialization order:
+// known sections (or leading fields in nested objects) first, then the
+// remaining keys alphabetically
+func canonicalKeys(m map[string]interface{}, topLevel bool) []string {
+	leading := leadingFields
+	if topLevel {
+		leading = sectionOrder
+	}
+
+	keys := make([]string, 0, len(m))
+	placed := make(map[string]bool, len(leading))
+	for _, key := range leading {
+		if _, exists := m[key]; exists {
+			keys = append(keys, key)
+			placed[key] = true
+		}
+	}
+
+	rest := make([]string, 0, len(m)-len(keys))
+	for key := range m {
+		if !placed[key] {
+			rest = append(rest, key)
+		}
+	}
+	sort.Strings(rest)
+
+	return append(keys, rest...)
+}
+
+// MarshalCanonicalYAML serializes a specification as YAML in canonical key order
+func MarshalCanonicalYAML(spec map[string]interface{}) ([]byte, error) {
+	node, err := canonicalYAMLNode(spec, true)
+	if err != nil {
+		return nil, err
+	}
+	return encodeYAMLNode(node)
+}
+
+// encodeYAMLNode serializes a YAML node with two-space indentation
+func encodeYAMLNode(node *yaml.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(node); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// canonicalYAMLNode converts a value to a YAML node with ordered mapping keys
+func canonicalYAMLNode(value interface{}, topLevel bool) (*yaml.Node, error) {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, key := range canonicalKeys(typed, topLevel) {
+			valueNode, err := canonicalYAMLNode(typed[key], false)
+			if err != nil {
+				return nil, err
+			}
+			keyNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}
+			node.Content = append(node.Content, keyNode, valueNode)
+		}
+		return node, nil
+	case []interface{}:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, item := range typed {
+			itemNode, err := canonicalYAMLNode(item, false)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, itemNode)
+		}
+		return node, nil
+	default:
+		node := &yaml.Node{}
+		if err := node.Encode(typed); err != nil {
+			return nil, err
+		}
+		return node, nil
+	}
+}
+
+// MarshalCanonicalJSON serializes a specification as indented JSON in canonical key order
+func MarshalCanonicalJSON(spec map[string]interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := writeCanonicalJSON(&buf, spec, true); err != nil {
+		return nil, err
+	}
+
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, buf.Bytes(), "", "  "); err != nil {
+		return nil, err
+	}
+	return indented.Bytes(), nil
+}
+
+// writeCanonicalJSON writes a value as compact JSON with ordered object keys
+func writeCanonicalJSON(buf *bytes.Buffer, value interface{}, topLevel bool) error {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		buf.WriteByte('{')
+		for i, key := range canonicalKeys(typed, topLevel) {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			keyJSON, err := json.Marshal(key)
+			if err != nil {
+				return err
+			}
+			buf.Write(keyJSON)
+			buf.WriteByte(':')
+			if err := writeCanonicalJSON(buf, typed[key], false); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, item := range typed {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeCanonicalJSON(buf, item, false); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	default:
+		content, err := json.Marshal(typed)
+		if err != nil {
+			return err
+		}
+		buf.Write(content)
+	}
+	return nil
+}
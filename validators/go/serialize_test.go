@@ -0,0 +1,105 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestMarshalCanonicalYAMLKeyOrder(t *testing.T) {
+	spec := map[string]interface{}{
+		"x-team":     "payments",
+		"evaluation": map[string]interface{}{},
+		"models": []interface{}{
+			map[string]interface{}{
+				"purpose": "conversation",
+				"type":    "LLM",
+				"name":    "gpt-4",
+				"id":      "main",
+			},
+		},
+		"info": map[string]interface{}{"title": "Test"},
+		"apai": "0.1.0",
+		"extensions": map[string]interface{}{
+			"zeta":  true,
+			"alpha": true,
+		},
+	}
+
+	content, err := MarshalCanonicalYAML(spec)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+
+	expected := []string{
+		"apai:", "info:", "models:", "id: main", "name: gpt-4", "purpose:", "type:",
+		"evaluation:", "extensions:", "alpha:", "zeta:", "x-team:",
+	}
+	output := string(content)
+	last := -1
+	for _, fragment := range expected {
+		index := strings.Index(output, fragment)
+		if index < 0 {
+			t.Fatalf("output missing %q:\n%s", fragment, output)
+		}
+		if index < last {
+			t.Errorf("%q out of canonical order:\n%s", fragment, output)
+		}
+		last = index
+	}
+}
+
+func TestMarshalYAMLWithComments(t *testing.T) {
+	base := `# Organization base
+apai: "0.1.0"
+info:
+  title: "Base"  # replaced by the team
+  version: "1.0.0"  # kept
+models:
+  # Approved models only
+  - id: "main_model"
+    provider: "openai"  # the base provider
+`
+	override := `info:
+  title: "Team"
+models:
+  - id: "main_model"
+    provider: "anthropic"  # switched provider
+`
+
+	validator := NewAPAIValidator()
+	specs := make([]map[string]interface{}, 0, 2)
+	trees := make([]*yaml.Node, 0, 2)
+	for _, content := range []string{base, override} {
+		var spec map[string]interface{}
+		var tree yaml.Node
+		if err := yaml.Unmarshal([]byte(content), &spec); err != nil {
+			t.Fatal(err)
+		}
+		if err := yaml.Unmarshal([]byte(content), &tree); err != nil {
+			t.Fatal(err)
+		}
+		specs, trees = append(specs, spec), append(trees, &tree)
+	}
+	merged, err := validator.Merge(specs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := MarshalYAMLWithComments(merged, trees)
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := string(content)
+	for _, want := range []string{"# Organization base", "version: 1.0.0 # kept", "provider: anthropic # switched provider"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("missing %q in merged output:\n%s", want, output)
+		}
+	}
+	for _, overridden := range []string{"replaced by the team", "the base provider", "Approved models only"} {
+		if strings.Contains(output, overridden) {
+			t.Errorf("comment %q of an overridden value survived:\n%s", overridden, output)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// defaultMaxRequestBody bounds the request bodies the server reads
+const defaultMaxRequestBody = 1 << 20
+
+// Server serves validation and merging of specifications over HTTP:
+// POST /validate and POST /merge, with GET /metrics for Prometheus, and
+// GET /healthz and GET /readyz for liveness and readiness
+type Server struct {
+	// validator is nil until the schemas it validates with are loaded
+	validator atomic.Pointer[APAIValidator]
+	metrics   *serverMetrics
+	// MaxRequestBody is the largest request body accepted, in bytes
+	MaxRequestBody int64
+}
+
+// NewServer creates a server validating with validator, which may be
+// shared by concurrent requests since the server only calls Validate. A
+// server created with a nil validator is live but not ready until
+// SetValidator is called.
+func NewServer(validator *APAIValidator) *Server {
+	s := &Server{MaxRequestBody: defaultMaxRequestBody}
+	s.metrics = newServerMetrics(s)
+	if validator != nil {
+		s.SetValidator(validator)
+	}
+	return s
+}
+
+// SetValidator makes the server ready, validating with validator from
+// then on
+func (s *Server) SetValidator(validator *APAIValidator) {
+	s.validator.Store(validator)
+}
+
+// Registry returns the registry holding the metrics of the server, for
+// hosts that expose them on a handler of their own
+func (s *Server) Registry() *prometheus.Registry {
+	return s.metrics.registry
+}
+
+// Handler returns the HTTP handler of the server
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/validate", s.handleValidate)
+	mux.HandleFunc("/merge", s.handleMerge)
+	mux.Handle("/metrics", promhttp.HandlerFor(s.metrics.registry, promhttp.HandlerOpts{}))
+	mux.HandleFunc("/healthz", s.handleHealth)
+	mux.HandleFunc("/readyz", s.handleReady)
+	return mux
+}
+
+// handleHealth reports that the server is live, whether or not it is ready
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// handleReady reports whether the server is ready to validate, that is
+// whether its schemas are loaded
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	if s.validator.Load() == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "loading schemas"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ready"})
+}
+
+// readyValidator returns the validator of the server, writing the error
+// response and returning false when the server is not ready yet
+func (s *Server) readyValidator(w http.ResponseWriter) (*APAIValidator, bool) {
+	validator := s.validator.Load()
+	if validator == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "the server is not ready")
+		return nil, false
+	}
+	return validator, true
+}
+
+// groupedResult is a ValidationResult with its findings grouped, the
+// response to /validate?group_by=section|code. With errors_only=true the
+// warnings are left out of both.
+type groupedResult struct {
+	ValidationResult
+	Groups []IssueGroup `json:"groups"`
+}
+
+// handleValidate validates the specification in the request body and
+// responds with its ValidationResult; invalid specifications are not
+// request errors
+func (s *Server) handleValidate(w http.ResponseWriter, r *http.Request) {
+	var spec map[string]interface{}
+	size, ok := s.decodeBody(w, r, &spec)
+	if !ok {
+		return
+	}
+	if spec == nil {
+		writeJSONError(w, http.StatusBadRequest, "the body is not an APAI specification")
+		return
+	}
+	groupBy := r.URL.Query().Get("group_by")
+	if groupBy != "" && !containsString(groupByValues, groupBy) {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("unsupported group_by: %s (expected section or code)", groupBy))
+		return
+	}
+	errorsOnly := false
+	if value := r.URL.Query().Get("errors_only"); value != "" {
+		var err error
+		if errorsOnly, err = strconv.ParseBool(value); err != nil {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("unsupported errors_only: %s (expected true or false)", value))
+			return
+		}
+	}
+
+	validator, ok := s.readyValidator(w)
+	if !ok {
+		return
+	}
+	start := time.Now()
+	result, err := validator.Validate(r.Context(), spec)
+	s.metrics.observeValidation(result, err, size, time.Since(start))
+	if err != nil {
+		writeJSONError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	if errorsOnly {
+		result = result.ErrorsOnly()
+	}
+	if groupBy != "" {
+		groups, _ := GroupIssues(result, groupBy)
+		writeJSON(w, http.StatusOK, groupedResult{ValidationResult: result, Groups: groups})
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// handleMerge merges the array of specifications in the request body,
+// later ones overriding earlier ones, and responds with the merged
+// specification in the format of the request
+func (s *Server) handleMerge(w http.ResponseWriter, r *http.Request) {
+	var documents []interface{}
+	if _, ok := s.decodeBody(w, r, &documents); !ok {
+		return
+	}
+	validator, ok := s.readyValidator(w)
+	if !ok {
+		return
+	}
+	specs := make([]map[string]interface{}, 0, len(documents))
+	for index, document := range documents {
+		spec, ok := document.(map[string]interface{})
+		if !ok || !looksLikeSpec(spec) {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("specification %d is not an APAI specification", index))
+			return
+		}
+		specs = append(specs, spec)
+	}
+
+	merged, err := validator.Merge(specs)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	content, err := MarshalCanonicalJSON(merged)
+	if mediaType != "application/json" {
+		content, err = MarshalCanonicalYAML(merged)
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", mediaType)
+	w.WriteHeader(http.StatusOK)
+	w.Write(content)
+}
+
+// decodeBody decodes a POST body as JSON or YAML according to its content
+// type into out and returns its size, writing the error response and
+// returning false when it cannot
+func (s *Server) decodeBody(w http.ResponseWriter, r *http.Request, out interface{}) (int, bool) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSONError(w, http.StatusMethodNotAllowed, "use POST")
+		return 0, false
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	var decode func([]byte, interface{}) error
+	switch mediaType {
+	case "application/json":
+		decode = json.Unmarshal
+	case "application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml":
+		decode = decodeYAML
+	default:
+		writeJSONError(w, http.StatusUnsupportedMediaType, "the content type must be application/json or application/yaml")
+		return 0, false
+	}
+
+	content, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.MaxRequestBody))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeJSONError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("the body exceeds %d bytes", s.MaxRequestBody))
+		return 0, false
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("cannot read the body: %v", err))
+		return 0, false
+	}
+	if err := decode(content, out); err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("cannot parse the body: %v", err))
+		return 0, false
+	}
+	return len(content), true
+}
+
+// writeJSON writes value as a JSON response
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+// writeJSONError writes an error response, {"error": message}
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestServerValidate(t *testing.T) {
+	content, err := os.ReadFile("../../examples/core/customer-support.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer(NewAPAIValidator())
+	server.MaxRequestBody = int64(len(content))
+	handler := server.Handler()
+
+	cases := []struct {
+		name, method, contentType, body string
+		status                          int
+		valid                           bool
+	}{
+		{"valid YAML", http.MethodPost, "application/yaml", string(content), http.StatusOK, true},
+		{"invalid spec", http.MethodPost, "application/json; charset=utf-8", `{"apai": "0.1.0"}`, http.StatusOK, false},
+		{"unparseable", http.MethodPost, "application/json", `{"apai": `, http.StatusBadRequest, false},
+		{"not an object", http.MethodPost, "application/yaml", "- apai", http.StatusBadRequest, false},
+		{"unknown content type", http.MethodPost, "text/plain", "apai: 0.1.0", http.StatusUnsupportedMediaType, false},
+		{"too large", http.MethodPost, "application/yaml", string(content) + "\n", http.StatusRequestEntityTooLarge, false},
+		{"wrong method", http.MethodGet, "", "", http.StatusMethodNotAllowed, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(tc.method, "/validate", strings.NewReader(tc.body))
+			request.Header.Set("Content-Type", tc.contentType)
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, request)
+
+			if response.Code != tc.status {
+				t.Fatalf("expected status %d, got %d: %s", tc.status, response.Code, response.Body)
+			}
+			if tc.status != http.StatusOK {
+				return
+			}
+			var result ValidationResult
+			if err := json.Unmarshal(response.Body.Bytes(), &result); err != nil {
+				t.Fatal(err)
+			}
+			if result.Valid != tc.valid {
+				t.Errorf("expected valid %v, got %+v", tc.valid, result)
+			}
+		})
+	}
+}
+
+func TestServerMerge(t *testing.T) {
+	handler := NewServer(NewAPAIValidator()).Handler()
+	body := `[{"apai": "0.1.0", "info": {"title": "Base", "version": "1.0.0"}}, {"apai": "0.1.0", "info": {"version": "2.0.0"}}]`
+	request := httptest.NewRequest(http.MethodPost, "/merge", strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+
+	if response.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", response.Code, response.Body)
+	}
+	var merged map[string]interface{}
+	if err := json.Unmarshal(response.Body.Bytes(), &merged); err != nil {
+		t.Fatal(err)
+	}
+	info := merged["info"].(map[string]interface{})
+	if info["title"] != "Base" || info["version"] != "2.0.0" {
+		t.Errorf("expected the merged info, got %v", info)
+	}
+
+	request = httptest.NewRequest(http.MethodPost, "/merge", strings.NewReader(`[{"owner": "support"}]`))
+	request.Header.Set("Content-Type", "application/json")
+	response = httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+	if response.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for a non-APAI document, got %d", response.Code)
+	}
+}
+
+func TestServerValidateGroupBy(t *testing.T) {
+	handler := NewServer(NewAPAIValidator()).Handler()
+	validate := func(query string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPost, "/validate"+query, strings.NewReader(`{"apai": "0.1.0"}`))
+		request.Header.Set("Content-Type", "application/json")
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, request)
+		return response
+	}
+
+	response := validate("?group_by=code")
+	if response.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", response.Code, response.Body)
+	}
+	var result groupedResult
+	if err := json.Unmarshal(response.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	grouped := 0
+	for _, group := range result.Groups {
+		grouped += group.Errors
+	}
+	if len(result.Groups) == 0 || grouped != len(result.Errors) {
+		t.Errorf("expected every error in a group, got %+v", result)
+	}
+
+	if response := validate("?group_by=file"); response.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for an unsupported grouping, got %d", response.Code)
+	}
+}
+
+func TestServerValidateErrorsOnly(t *testing.T) {
+	handler := NewServer(NewAPAIValidator()).Handler()
+	validate := func(query string) (*httptest.ResponseRecorder, ValidationResult) {
+		request := httptest.NewRequest(http.MethodPost, "/validate"+query, strings.NewReader(`{"apai": "0.1.0", "info": {"author": "AI Team"}}`))
+		request.Header.Set("Content-Type", "application/json")
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, request)
+		var result ValidationResult
+		if response.Code == http.StatusOK {
+			if err := json.Unmarshal(response.Body.Bytes(), &result); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return response, result
+	}
+
+	_, full := validate("")
+	if len(full.Warnings) == 0 || len(full.Errors) == 0 {
+		t.Fatalf("expected errors and warnings by default, got %+v", full)
+	}
+	response, result := validate("?errors_only=true")
+	if response.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", response.Code, response.Body)
+	}
+	if result.Warnings == nil || len(result.Warnings) > 0 || !reflect.DeepEqual(result.Errors, full.Errors) {
+		t.Errorf("expected the errors and an empty warnings array, got %s", response.Body)
+	}
+
+	if response, _ := validate("?errors_only=maybe"); response.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for an invalid errors_only, got %d", response.Code)
+	}
+}
+
+func TestValidateConcurrently(t *testing.T) {
+	specs := loadExampleSpecs(t, "core/customer-support.yaml", "automation/mcp-integration.yaml")
+	validator := NewAPAIValidator()
+	want := make([]ValidationResult, len(specs))
+	for i, spec := range specs {
+		validator.ValidateSpec(spec)
+		want[i] = validator.GetResults()
+	}
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < 8; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			index := worker % len(specs)
+			result, err := validator.Validate(context.Background(), specs[index])
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if len(result.Errors) != len(want[index].Errors) || len(result.Warnings) != len(want[index].Warnings) {
+				t.Errorf("expected %+v, got %+v", want[index], result)
+			}
+		}(worker)
+	}
+	wg.Wait()
+}
+
+func TestServerReadiness(t *testing.T) {
+	server := NewServer(nil)
+	handler := server.Handler()
+	get := func(path string) int {
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, httptest.NewRequest(http.MethodGet, path, nil))
+		return response.Code
+	}
+	validate := func() int {
+		request := httptest.NewRequest(http.MethodPost, "/validate", strings.NewReader(`{"apai": "0.1.0"}`))
+		request.Header.Set("Content-Type", "application/json")
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, request)
+		return response.Code
+	}
+
+	// Live while the schemas load, but not ready to validate
+	if status := get("/healthz"); status != http.StatusOK {
+		t.Errorf("expected /healthz to be 200 while loading, got %d", status)
+	}
+	if status := get("/readyz"); status != http.StatusServiceUnavailable {
+		t.Errorf("expected /readyz to be 503 while loading, got %d", status)
+	}
+	if status := validate(); status != http.StatusServiceUnavailable {
+		t.Errorf("expected /validate to be 503 while loading, got %d", status)
+	}
+
+	server.SetValidator(NewAPAIValidator())
+	if status := get("/readyz"); status != http.StatusOK {
+		t.Errorf("expected /readyz to be 200 once loaded, got %d", status)
+	}
+	if status := validate(); status != http.StatusOK {
+		t.Errorf("expected /validate to be 200 once loaded, got %d", status)
+	}
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// errNotGitRepository is returned, wrapped, when a directory is outside any
+// git working tree
+var errNotGitRepository = errors.New("not inside a git repository")
+
+// ChangedFiles lists the files of the git repository around dir that
+// changed since the merge base of ref and HEAD, committed or not, as
+// absolute paths. Deleted files are left out.
+func ChangedFiles(ctx context.Context, dir, ref string) ([]string, error) {
+	root, err := gitRoot(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	base, err := gitOutput(ctx, dir, "merge-base", ref, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	diff, err := gitOutput(ctx, dir, "diff", "--name-only", "-z", "--diff-filter=d", strings.TrimSpace(base))
+	if err != nil {
+		return nil, err
+	}
+	return gitPaths(root, diff), nil
+}
+
+// UntrackedFiles lists the files of the git repository around dir that are
+// neither tracked nor ignored, as absolute paths
+func UntrackedFiles(ctx context.Context, dir string) ([]string, error) {
+	root, err := gitRoot(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	untracked, err := gitOutput(ctx, root, "ls-files", "--others", "--exclude-standard", "-z")
+	if err != nil {
+		return nil, err
+	}
+	return gitPaths(root, untracked), nil
+}
+
+// gitRoot returns the top-level directory of the git repository around dir
+func gitRoot(ctx context.Context, dir string) (string, error) {
+	root, err := gitOutput(ctx, dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", errNotGitRepository, err)
+	}
+	return strings.TrimSpace(root), nil
+}
+
+// gitPaths converts the NUL-separated repository paths git prints with -z
+// to absolute paths
+func gitPaths(root, output string) []string {
+	paths := make([]string, 0)
+	for _, name := range strings.Split(output, "\x00") {
+		if name != "" {
+			paths = append(paths, filepath.Join(root, filepath.FromSlash(name)))
+		}
+	}
+	return paths
+}
+
+// gitOutput runs a git command in dir and returns its output
+func gitOutput(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("git %s: %s", args[0], strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("git %s: %v", args[0], err)
+	}
+	return string(output), nil
+}
+
+// SpecFiles lists the YAML and JSON files among paths, walking directories
+// except hidden ones such as .git, until ctx is done
+func SpecFiles(ctx context.Context, paths []string) ([]string, error) {
+	files := make([]string, 0)
+	for _, root := range paths {
+		err := filepath.WalkDir(root, func(filePath string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if entry.IsDir() {
+				if filePath != root && strings.HasPrefix(entry.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			switch strings.ToLower(filepath.Ext(filePath)) {
+			case ".yaml", ".yml", ".json":
+				files = append(files, filePath)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("cannot list specifications: %w", err)
+		}
+	}
+	return files, nil
+}
+
+// AffectedSpec is a specification selected for validation by changed files
+type AffectedSpec struct {
+	Path string `json:"path"`
+	// Changed is set when the specification itself changed. Otherwise it
+	// inherits from Parent, which changed or inherits from Source in turn.
+	Changed bool   `json:"changed"`
+	Parent  string `json:"parent,omitempty"`
+	Source  string `json:"source,omitempty"`
+}
+
+// Reason tells why the specification was selected
+func (a AffectedSpec) Reason() string {
+	switch {
+	case a.Changed:
+		return "changed"
+	case a.Parent == a.Source:
+		return fmt.Sprintf("inherits from changed %s", a.Source)
+	default:
+		return fmt.Sprintf("inherits from changed %s through %s", a.Source, a.Parent)
+	}
+}
+
+// SpecsAffectedBy returns the specifications among candidates that are in
+// changed, or inherit from a file in changed directly or through other
+// candidates. Candidates that are not APAI specifications are left out,
+// unless they changed and cannot be parsed, so their errors are reported.
+func (v *APAIValidator) SpecsAffectedBy(candidates, changed []string) []string {
+	files := make([]string, 0)
+	for _, affected := range v.AffectedSpecs(candidates, changed) {
+		files = append(files, affected.Path)
+	}
+	return files
+}
+
+// AffectedSpecs is SpecsAffectedBy telling why each specification was
+// selected. Parents are named as in candidates, or by their absolute path
+// when they are not among them.
+func (v *APAIValidator) AffectedSpecs(candidates, changed []string) []AffectedSpec {
+	specs := make(map[string]bool)
+	dependents := make(map[string][]string)
+	names := make(map[string]string)
+	for _, candidate := range candidates {
+		candidatePath := absolutePath(candidate)
+		names[candidatePath] = candidate
+		spec, err := v.loadSpec(candidate)
+		if err != nil {
+			specs[candidatePath] = true
+			continue
+		}
+		if !looksLikeSpec(spec) {
+			continue
+		}
+		specs[candidatePath] = true
+
+		inheritsSlice, _ := spec["inherits"].([]interface{})
+		for _, inheritPath := range inheritsSlice {
+			if inheritPathStr, ok := inheritPath.(string); ok {
+				if resolvedPath, err := v.resolveInheritancePath(inheritPathStr, candidate); err == nil {
+					parent := absolutePath(resolvedPath)
+					dependents[parent] = append(dependents[parent], candidatePath)
+				}
+			}
+		}
+	}
+
+	// Walk from the changed files down to every spec inheriting from them,
+	// recording the parent each was first reached through
+	name := func(filePath string) string {
+		if candidate, ok := names[filePath]; ok {
+			return candidate
+		}
+		return filePath
+	}
+	reached := make(map[string]AffectedSpec)
+	queue := make([]string, 0, len(changed))
+	for _, changedPath := range changed {
+		changedPath = absolutePath(changedPath)
+		if _, seen := reached[changedPath]; !seen {
+			reached[changedPath] = AffectedSpec{Changed: true}
+			queue = append(queue, changedPath)
+		}
+	}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		source := reached[current].Source
+		if reached[current].Changed {
+			source = name(current)
+		}
+		for _, dependent := range dependents[current] {
+			if _, seen := reached[dependent]; !seen {
+				reached[dependent] = AffectedSpec{Parent: name(current), Source: source}
+				queue = append(queue, dependent)
+			}
+		}
+	}
+
+	affected := make([]AffectedSpec, 0)
+	for _, candidate := range candidates {
+		candidatePath := absolutePath(candidate)
+		if spec, ok := reached[candidatePath]; ok && specs[candidatePath] {
+			spec.Path = candidate
+			affected = append(affected, spec)
+		}
+	}
+	return affected
+}
+
+// absolutePath returns the absolute form of a path with symlinks resolved,
+// as git reports it, so paths from both sources compare equal
+func absolutePath(filePath string) string {
+	absolute, err := filepath.Abs(filePath)
+	if err != nil {
+		return filepath.Clean(filePath)
+	}
+	if resolved, err := filepath.EvalSymlinks(absolute); err == nil {
+		return resolved
+	}
+	return absolute
+}
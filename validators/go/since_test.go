@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSpecsAffectedBy(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"org/base.yaml":      "apai: \"0.1.0\"\nmodels: []\n",
+		"team/app.yaml":      "apai: \"0.1.0\"\ninherits:\n  - \"../org/base.yaml\"\n",
+		"team/feature.yaml":  "apai: \"0.1.0\"\ninherits:\n  - \"app.yaml\"\n",
+		"other/service.yaml": "apai: \"0.1.0\"\n",
+		"ci/pipeline.yaml":   "jobs: {}\n",
+	}
+	for name, content := range files {
+		filePath := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	candidates, err := SpecFiles(context.Background(), []string{dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	validator := NewAPAIValidator()
+
+	// A changed base affects every spec inheriting from it, transitively
+	changed := []string{filepath.Join(dir, "org/base.yaml"), filepath.Join(dir, "ci/pipeline.yaml")}
+	want := []string{filepath.Join(dir, "org/base.yaml"), filepath.Join(dir, "team/app.yaml"), filepath.Join(dir, "team/feature.yaml")}
+	if got := validator.SpecsAffectedBy(candidates, changed); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	reasons := make([]string, 0)
+	for _, affected := range validator.AffectedSpecs(candidates, changed) {
+		reasons = append(reasons, affected.Reason())
+	}
+	base := filepath.Join(dir, "org/base.yaml")
+	wantReasons := []string{"changed", "inherits from changed " + base, "inherits from changed " + base + " through " + filepath.Join(dir, "team/app.yaml")}
+	if !reflect.DeepEqual(reasons, wantReasons) {
+		t.Errorf("got %v, want %v", reasons, wantReasons)
+	}
+
+	changed = []string{filepath.Join(dir, "team/feature.yaml")}
+	want = []string{filepath.Join(dir, "team/feature.yaml")}
+	if got := validator.SpecsAffectedBy(candidates, changed); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestChangedFiles(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = dir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, output)
+		}
+	}
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	git("init", "-q")
+	write("base.yaml", "apai: \"0.1.0\"\n")
+	write("removed.yaml", "apai: \"0.1.0\"\n")
+	git("add", "-A")
+	git("commit", "-q", "-m", "base")
+	git("branch", "baseline")
+
+	write("base.yaml", "apai: \"0.2.0\"\n")
+	git("rm", "-q", "removed.yaml")
+	git("commit", "-q", "-am", "change")
+	write("new.yaml", "apai: \"0.1.0\"\n")
+	git("add", "new.yaml")
+
+	changed, err := ChangedFiles(context.Background(), dir, "baseline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := absolutePath(dir)
+	want := []string{filepath.Join(root, "base.yaml"), filepath.Join(root, "new.yaml")}
+	if !reflect.DeepEqual(changed, want) {
+		t.Errorf("got %v, want %v", changed, want)
+	}
+
+	if _, err := ChangedFiles(context.Background(), dir, "missing"); err == nil {
+		t.Error("expected an error for an unknown ref")
+	}
+
+	write("draft.yaml", "apai: \"0.1.0\"\n")
+	untracked, err := UntrackedFiles(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{filepath.Join(root, "draft.yaml")}; !reflect.DeepEqual(untracked, want) {
+		t.Errorf("got %v, want %v", untracked, want)
+	}
+
+	outside := t.TempDir()
+	if _, err := ChangedFiles(context.Background(), outside, "HEAD"); !errors.Is(err, errNotGitRepository) {
+		t.Errorf("expected an error outside a repository, got %v", err)
+	}
+}
@@ -0,0 +1,353 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Spec represents a typed APAI specification. Fields not modelled by the
+// structs are retained in Extra so that decoding and encoding round-trips.
+type Spec struct {
+	APAI        string                 `yaml:"apai,omitempty" json:"apai,omitempty"`
+	Inherits    []string               `yaml:"inherits,omitempty" json:"inherits,omitempty"`
+	Info        *Info                  `yaml:"info,omitempty" json:"info,omitempty"`
+	Models      []Model                `yaml:"models,omitempty" json:"models,omitempty"`
+	Prompts     []Prompt               `yaml:"prompts,omitempty" json:"prompts,omitempty"`
+	Constraints []Constraint           `yaml:"constraints,omitempty" json:"constraints,omitempty"`
+	Tasks       []Task                 `yaml:"tasks,omitempty" json:"tasks,omitempty"`
+	Context     *Context               `yaml:"context,omitempty" json:"context,omitempty"`
+	Evaluation  *Evaluation            `yaml:"evaluation,omitempty" json:"evaluation,omitempty"`
+	Extra       map[string]interface{} `yaml:",inline" json:"-"`
+}
+
+// Object is a free-form object. Unlike a plain map, an explicitly empty
+// object is kept when encoding, so `headers: {}` survives a round-trip.
+type Object map[string]interface{}
+
+// IsZero reports whether the object is absent
+func (o Object) IsZero() bool { return o == nil }
+
+// Info represents the system metadata section
+type Info struct {
+	Title       string                 `yaml:"title,omitempty" json:"title,omitempty"`
+	Version     string                 `yaml:"version,omitempty" json:"version,omitempty"`
+	Description string                 `yaml:"description,omitempty" json:"description,omitempty"`
+	Author      interface{}            `yaml:"author,omitempty" json:"author,omitempty"`
+	License     string                 `yaml:"license,omitempty" json:"license,omitempty"`
+	Contact     Object                 `yaml:"contact,omitempty" json:"contact,omitempty"`
+	AIMetadata  *AIMetadata            `yaml:"ai_metadata,omitempty" json:"ai_metadata,omitempty"`
+	Extra       map[string]interface{} `yaml:",inline" json:"-"`
+}
+
+// AIMetadata represents the AI-specific metadata in info
+type AIMetadata struct {
+	Domain             string                 `yaml:"domain,omitempty" json:"domain,omitempty"`
+	Complexity         string                 `yaml:"complexity,omitempty" json:"complexity,omitempty"`
+	Deployment         string                 `yaml:"deployment,omitempty" json:"deployment,omitempty"`
+	LastUpdated        string                 `yaml:"last_updated,omitempty" json:"last_updated,omitempty"`
+	SupportedLanguages []string               `yaml:"supported_languages,omitempty" json:"supported_languages,omitempty"`
+	Tags               []string               `yaml:"tags,omitempty" json:"tags,omitempty"`
+	HierarchyInfo      Object                 `yaml:"hierarchy_info,omitempty" json:"hierarchy_info,omitempty"`
+	Extra              map[string]interface{} `yaml:",inline" json:"-"`
+}
+
+// Model represents an entry of the models section
+type Model struct {
+	ID           string                 `yaml:"id,omitempty" json:"id,omitempty"`
+	Type         string                 `yaml:"type,omitempty" json:"type,omitempty"`
+	Provider     string                 `yaml:"provider,omitempty" json:"provider,omitempty"`
+	Name         string                 `yaml:"name,omitempty" json:"name,omitempty"`
+	Version      string                 `yaml:"version,omitempty" json:"version,omitempty"`
+	Purpose      string                 `yaml:"purpose,omitempty" json:"purpose,omitempty"`
+	Capabilities []string               `yaml:"capabilities,omitempty" json:"capabilities,omitempty"`
+	Parameters   Object                 `yaml:"parameters,omitempty" json:"parameters,omitempty"`
+	Extra        map[string]interface{} `yaml:",inline" json:"-"`
+}
+
+// Prompt represents an entry of the prompts section
+type Prompt struct {
+	ID           string                 `yaml:"id,omitempty" json:"id,omitempty"`
+	Role         string                 `yaml:"role,omitempty" json:"role,omitempty"`
+	Style        string                 `yaml:"style,omitempty" json:"style,omitempty"`
+	Language     string                 `yaml:"language,omitempty" json:"language,omitempty"`
+	Template     string                 `yaml:"template,omitempty" json:"template,omitempty"`
+	TemplateFile string                 `yaml:"template_file,omitempty" json:"template_file,omitempty"`
+	Variables    Object                 `yaml:"variables,omitempty" json:"variables,omitempty"`
+	Config       Object                 `yaml:"config,omitempty" json:"config,omitempty"`
+	Extra        map[string]interface{} `yaml:",inline" json:"-"`
+}
+
+// Constraint represents an entry of the constraints section
+type Constraint struct {
+	ID          string                 `yaml:"id,omitempty" json:"id,omitempty"`
+	Name        string                 `yaml:"name,omitempty" json:"name,omitempty"`
+	Type        string                 `yaml:"type,omitempty" json:"type,omitempty"`
+	Rule        string                 `yaml:"rule,omitempty" json:"rule,omitempty"`
+	Severity    string                 `yaml:"severity,omitempty" json:"severity,omitempty"`
+	Enforcement string                 `yaml:"enforcement,omitempty" json:"enforcement,omitempty"`
+	Description string                 `yaml:"description,omitempty" json:"description,omitempty"`
+	Actions     []string               `yaml:"actions,omitempty" json:"actions,omitempty"`
+	Extra       map[string]interface{} `yaml:",inline" json:"-"`
+}
+
+// Task represents an entry of the tasks section
+type Task struct {
+	ID          string                 `yaml:"id,omitempty" json:"id,omitempty"`
+	Name        string                 `yaml:"name,omitempty" json:"name,omitempty"`
+	Description string                 `yaml:"description,omitempty" json:"description,omitempty"`
+	Type        string                 `yaml:"type,omitempty" json:"type,omitempty"`
+	Priority    string                 `yaml:"priority,omitempty" json:"priority,omitempty"`
+	Input       Object                 `yaml:"input,omitempty" json:"input,omitempty"`
+	Output      Object                 `yaml:"output,omitempty" json:"output,omitempty"`
+	Steps       []Step                 `yaml:"steps,omitempty" json:"steps,omitempty"`
+	Extra       map[string]interface{} `yaml:",inline" json:"-"`
+}
+
+// Step represents a step of a task
+type Step struct {
+	Name          string                 `yaml:"name,omitempty" json:"name,omitempty"`
+	Action        string                 `yaml:"action,omitempty" json:"action,omitempty"`
+	Model         string                 `yaml:"model,omitempty" json:"model,omitempty"`
+	Prompt        string                 `yaml:"prompt,omitempty" json:"prompt,omitempty"`
+	Source        string                 `yaml:"source,omitempty" json:"source,omitempty"`
+	MCPServer     string                 `yaml:"mcp_server,omitempty" json:"mcp_server,omitempty"`
+	MCPTool       string                 `yaml:"mcp_tool,omitempty" json:"mcp_tool,omitempty"`
+	MCPResource   string                 `yaml:"mcp_resource,omitempty" json:"mcp_resource,omitempty"`
+	MCPParameters Object                 `yaml:"mcp_parameters,omitempty" json:"mcp_parameters,omitempty"`
+	Constraints   []string               `yaml:"constraints,omitempty" json:"constraints,omitempty"`
+	Conditions    []interface{}          `yaml:"conditions,omitempty" json:"conditions,omitempty"`
+	Extra         map[string]interface{} `yaml:",inline" json:"-"`
+}
+
+// Context represents the context section
+type Context struct {
+	Memory          Object                 `yaml:"memory,omitempty" json:"memory,omitempty"`
+	Conversation    Object                 `yaml:"conversation,omitempty" json:"conversation,omitempty"`
+	BusinessContext Object                 `yaml:"business_context,omitempty" json:"business_context,omitempty"`
+	MCPServers      []MCPServer            `yaml:"mcp_servers,omitempty" json:"mcp_servers,omitempty"`
+	Extra           map[string]interface{} `yaml:",inline" json:"-"`
+}
+
+// MCPServer represents an entry of context.mcp_servers
+type MCPServer struct {
+	ID             string                 `yaml:"id,omitempty" json:"id,omitempty"`
+	Name           string                 `yaml:"name,omitempty" json:"name,omitempty"`
+	Description    string                 `yaml:"description,omitempty" json:"description,omitempty"`
+	Version        string                 `yaml:"version,omitempty" json:"version,omitempty"`
+	Transport      *Transport             `yaml:"transport,omitempty" json:"transport,omitempty"`
+	Capabilities   Object                 `yaml:"capabilities,omitempty" json:"capabilities,omitempty"`
+	Authentication *Authentication        `yaml:"authentication,omitempty" json:"authentication,omitempty"`
+	Security       Object                 `yaml:"security,omitempty" json:"security,omitempty"`
+	Extra          map[string]interface{} `yaml:",inline" json:"-"`
+}
+
+// Transport represents the transport configuration of an MCP server
+type Transport struct {
+	Type    string                 `yaml:"type,omitempty" json:"type,omitempty"`
+	Command string                 `yaml:"command,omitempty" json:"command,omitempty"`
+	Args    []string               `yaml:"args,omitempty" json:"args,omitempty"`
+	URL     string                 `yaml:"url,omitempty" json:"url,omitempty"`
+	Headers Object                 `yaml:"headers,omitempty" json:"headers,omitempty"`
+	Extra   map[string]interface{} `yaml:",inline" json:"-"`
+}
+
+// Authentication represents the authentication configuration of an MCP server
+type Authentication struct {
+	Type   string                 `yaml:"type,omitempty" json:"type,omitempty"`
+	APIKey string                 `yaml:"api_key,omitempty" json:"api_key,omitempty"`
+	Token  string                 `yaml:"token,omitempty" json:"token,omitempty"`
+	Extra  map[string]interface{} `yaml:",inline" json:"-"`
+}
+
+// Evaluation represents the evaluation section. Metrics may also be
+// grouped by category, in which case they are held in MetricGroups.
+type Evaluation struct {
+	Metrics      []Metric                 `yaml:"metrics,omitempty" json:"metrics,omitempty"`
+	MetricGroups map[string][]Metric      `yaml:"-" json:"-"`
+	TestCases    []map[string]interface{} `yaml:"test_cases,omitempty" json:"test_cases,omitempty"`
+	Extra        map[string]interface{}   `yaml:",inline" json:"-"`
+}
+
+// UnmarshalYAML decodes the evaluation section, accepting metrics either as
+// a list or as a map of category to list
+func (e *Evaluation) UnmarshalYAML(node *yaml.Node) error {
+	type plain Evaluation
+	if node.Kind == yaml.MappingNode {
+		content := make([]*yaml.Node, 0, len(node.Content))
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if key.Value == "metrics" && value.Kind == yaml.MappingNode {
+				if err := value.Decode(&e.MetricGroups); err != nil {
+					return err
+				}
+				continue
+			}
+			content = append(content, key, value)
+		}
+		stripped := *node
+		stripped.Content = content
+		node = &stripped
+	}
+	return node.Decode((*plain)(e))
+}
+
+// MarshalYAML encodes the evaluation section, restoring grouped metrics
+func (e Evaluation) MarshalYAML() (interface{}, error) {
+	type plain Evaluation
+	if e.MetricGroups == nil {
+		return plain(e), nil
+	}
+
+	node := &yaml.Node{}
+	if err := node.Encode(plain(e)); err != nil {
+		return nil, err
+	}
+	groups := &yaml.Node{}
+	if err := groups.Encode(e.MetricGroups); err != nil {
+		return nil, err
+	}
+	key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "metrics"}
+	node.Content = append([]*yaml.Node{key, groups}, node.Content...)
+	return node, nil
+}
+
+// Metric represents an entry of evaluation.metrics
+type Metric struct {
+	Name        string                 `yaml:"name,omitempty" json:"name,omitempty"`
+	Description string                 `yaml:"description,omitempty" json:"description,omitempty"`
+	Target      interface{}            `yaml:"target,omitempty" json:"target,omitempty"`
+	Measurement Object                 `yaml:"measurement,omitempty" json:"measurement,omitempty"`
+	Extra       map[string]interface{} `yaml:",inline" json:"-"`
+}
+
+// DecodeSpec decodes a specification map into the typed model
+func DecodeSpec(spec map[string]interface{}) (*Spec, error) {
+	content, err := yaml.Marshal(spec)
+	if err != nil {
+		return nil, fmt.Errorf("cannot encode specification: %v", err)
+	}
+
+	var decoded Spec
+	if err := yaml.Unmarshal(content, &decoded); err != nil {
+		return nil, fmt.Errorf("cannot decode specification: %v", err)
+	}
+	return &decoded, nil
+}
+
+// ParseSpecFile parses a YAML or JSON specification file into the typed model
+func ParseSpecFile(filePath string) (*Spec, error) {
+	spec, err := NewAPAIValidator().loadSpec(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeSpec(spec)
+}
+
+// ToMap converts the typed model back into a specification map
+func (s *Spec) ToMap() (map[string]interface{}, error) {
+	content, err := yaml.Marshal(s)
+	if err != nil {
+		return nil, fmt.Errorf("cannot encode specification: %v", err)
+	}
+
+	spec := make(map[string]interface{})
+	if err := yaml.Unmarshal(content, &spec); err != nil {
+		return nil, fmt.Errorf("cannot decode specification: %v", err)
+	}
+	return spec, nil
+}
+
+// WriteYAML writes the specification as YAML in canonical key order
+func (s *Spec) WriteYAML(w io.Writer) error {
+	spec, err := s.ToMap()
+	if err != nil {
+		return err
+	}
+
+	content, err := MarshalCanonicalYAML(spec)
+	if err != nil {
+		return fmt.Errorf("cannot encode specification: %v", err)
+	}
+	_, err = w.Write(content)
+	return err
+}
+
+// WriteJSON writes the specification as indented JSON in canonical key order
+func (s *Spec) WriteJSON(w io.Writer) error {
+	spec, err := s.ToMap()
+	if err != nil {
+		return err
+	}
+
+	content, err := MarshalCanonicalJSON(spec)
+	if err != nil {
+		return fmt.Errorf("cannot encode specification: %v", err)
+	}
+	_, err = w.Write(content)
+	return err
+}
+
+// marshalJSONViaYAML encodes a typed value as JSON through its YAML form,
+// which keeps the inline Extra fields
+func marshalJSONViaYAML(value interface{}) ([]byte, error) {
+	content, err := yaml.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var generic interface{}
+	if err := yaml.Unmarshal(content, &generic); err != nil {
+		return nil, err
+	}
+	return json.Marshal(generic)
+}
+
+// unmarshalJSONViaYAML decodes JSON into a typed value through its YAML
+// form, which collects unknown fields into Extra
+func unmarshalJSONViaYAML(data []byte, value interface{}) error {
+	var generic interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return err
+	}
+
+	content, err := yaml.Marshal(generic)
+	if err != nil {
+		return err
+	}
+	return yaml.Unmarshal(content, value)
+}
+
+// The JSON encoding of every typed section goes through YAML so that Extra
+// fields are flattened into the object rather than dropped.
+
+func (s Spec) MarshalJSON() ([]byte, error)               { return marshalJSONViaYAML(s) }
+func (s *Spec) UnmarshalJSON(data []byte) error           { return unmarshalJSONViaYAML(data, s) }
+func (i Info) MarshalJSON() ([]byte, error)               { return marshalJSONViaYAML(i) }
+func (i *Info) UnmarshalJSON(data []byte) error           { return unmarshalJSONViaYAML(data, i) }
+func (m AIMetadata) MarshalJSON() ([]byte, error)         { return marshalJSONViaYAML(m) }
+func (m *AIMetadata) UnmarshalJSON(data []byte) error     { return unmarshalJSONViaYAML(data, m) }
+func (m Model) MarshalJSON() ([]byte, error)              { return marshalJSONViaYAML(m) }
+func (m *Model) UnmarshalJSON(data []byte) error          { return unmarshalJSONViaYAML(data, m) }
+func (p Prompt) MarshalJSON() ([]byte, error)             { return marshalJSONViaYAML(p) }
+func (p *Prompt) UnmarshalJSON(data []byte) error         { return unmarshalJSONViaYAML(data, p) }
+func (c Constraint) MarshalJSON() ([]byte, error)         { return marshalJSONViaYAML(c) }
+func (c *Constraint) UnmarshalJSON(data []byte) error     { return unmarshalJSONViaYAML(data, c) }
+func (t Task) MarshalJSON() ([]byte, error)               { return marshalJSONViaYAML(t) }
+func (t *Task) UnmarshalJSON(data []byte) error           { return unmarshalJSONViaYAML(data, t) }
+func (s Step) MarshalJSON() ([]byte, error)               { return marshalJSONViaYAML(s) }
+func (s *Step) UnmarshalJSON(data []byte) error           { return unmarshalJSONViaYAML(data, s) }
+func (c Context) MarshalJSON() ([]byte, error)            { return marshalJSONViaYAML(c) }
+func (c *Context) UnmarshalJSON(data []byte) error        { return unmarshalJSONViaYAML(data, c) }
+func (s MCPServer) MarshalJSON() ([]byte, error)          { return marshalJSONViaYAML(s) }
+func (s *MCPServer) UnmarshalJSON(data []byte) error      { return unmarshalJSONViaYAML(data, s) }
+func (t Transport) MarshalJSON() ([]byte, error)          { return marshalJSONViaYAML(t) }
+func (t *Transport) UnmarshalJSON(data []byte) error      { return unmarshalJSONViaYAML(data, t) }
+func (a Authentication) MarshalJSON() ([]byte, error)     { return marshalJSONViaYAML(a) }
+func (a *Authentication) UnmarshalJSON(data []byte) error { return unmarshalJSONViaYAML(data, a) }
+func (e Evaluation) MarshalJSON() ([]byte, error)         { return marshalJSONViaYAML(e) }
+func (e *Evaluation) UnmarshalJSON(data []byte) error     { return unmarshalJSONViaYAML(data, e) }
+func (m Metric) MarshalJSON() ([]byte, error)             { return marshalJSONViaYAML(m) }
+func (m *Metric) UnmarshalJSON(data []byte) error         { return unmarshalJSONViaYAML(data, m) }
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func exampleSpecFiles(t *testing.T) []string {
+	t.Helper()
+
+	files, err := filepath.Glob(filepath.Join("..", "..", "examples", "*", "*.yaml"))
+	if err != nil {
+		t.Fatalf("listing examples: %v", err)
+	}
+	jsonFiles, err := filepath.Glob(filepath.Join("..", "..", "examples", "*.json"))
+	if err != nil {
+		t.Fatalf("listing examples: %v", err)
+	}
+	files = append(files, jsonFiles...)
+	if len(files) == 0 {
+		t.Fatal("no example specifications found")
+	}
+	return files
+}
+
+// normalizeJSON re-encodes a value through JSON so numbers compare equal
+// regardless of whether they were decoded as ints or floats
+func normalizeJSON(t *testing.T, value interface{}) interface{} {
+	t.Helper()
+
+	content, err := json.Marshal(value)
+	if err != nil {
+		t.Fatalf("encoding JSON: %v", err)
+	}
+	var normalized interface{}
+	if err := json.Unmarshal(content, &normalized); err != nil {
+		t.Fatalf("decoding JSON: %v", err)
+	}
+	return normalized
+}
+
+func TestParseSpecFileDecodesExamples(t *testing.T) {
+	for _, file := range exampleSpecFiles(t) {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			spec, err := ParseSpecFile(file)
+			if err != nil {
+				t.Fatalf("parse failed: %v", err)
+			}
+
+			if spec.APAI == "" {
+				t.Error("apai version not decoded")
+			}
+			if spec.Info == nil || spec.Info.Title == "" || spec.Info.Author == nil {
+				t.Error("info not decoded")
+			}
+			if len(spec.Models) == 0 || spec.Models[0].ID == "" || spec.Models[0].Type == "" {
+				t.Error("models not decoded")
+			}
+			if len(spec.Prompts) == 0 || spec.Prompts[0].Template == "" {
+				t.Error("prompts not decoded")
+			}
+			if len(spec.Constraints) == 0 || spec.Constraints[0].Rule == "" {
+				t.Error("constraints not decoded")
+			}
+			if len(spec.Tasks) == 0 || spec.Tasks[0].Description == "" {
+				t.Error("tasks not decoded")
+			}
+			for _, task := range spec.Tasks {
+				for _, step := range task.Steps {
+					if step.Action == "" {
+						t.Errorf("task %s step %s missing action", task.ID, step.Name)
+					}
+				}
+			}
+			if spec.Context == nil {
+				t.Error("context not decoded")
+			}
+			if spec.Evaluation == nil || len(spec.Evaluation.Metrics)+len(spec.Evaluation.MetricGroups) == 0 {
+				t.Error("evaluation not decoded")
+			}
+			if spec.Context != nil {
+				for _, server := range spec.Context.MCPServers {
+					if server.ID == "" || server.Transport == nil || server.Transport.Type == "" {
+						t.Errorf("MCP server %q not decoded", server.ID)
+					}
+					if server.Authentication == nil || server.Authentication.Type == "" {
+						t.Errorf("MCP server %s authentication not decoded", server.ID)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeSpecRoundTrips(t *testing.T) {
+	validator := NewAPAIValidator()
+	for _, file := range exampleSpecFiles(t) {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			original, err := validator.loadSpec(file)
+			if err != nil {
+				t.Fatalf("load failed: %v", err)
+			}
+
+			spec, err := DecodeSpec(original)
+			if err != nil {
+				t.Fatalf("decode failed: %v", err)
+			}
+
+			roundTripped, err := spec.ToMap()
+			if err != nil {
+				t.Fatalf("encode failed: %v", err)
+			}
+			if !reflect.DeepEqual(normalizeJSON(t, original), normalizeJSON(t, roundTripped)) {
+				t.Error("map round-trip lost data")
+			}
+
+			content, err := json.Marshal(spec)
+			if err != nil {
+				t.Fatalf("JSON encode failed: %v", err)
+			}
+			var fromJSON Spec
+			if err := json.Unmarshal(content, &fromJSON); err != nil {
+				t.Fatalf("JSON decode failed: %v", err)
+			}
+			if !reflect.DeepEqual(normalizeJSON(t, original), normalizeJSON(t, fromJSON)) {
+				t.Error("JSON round-trip lost data")
+			}
+		})
+	}
+}
+
+func TestDecodeSpecKeepsUnknownFields(t *testing.T) {
+	spec, err := DecodeSpec(map[string]interface{}{
+		"apai":       "0.1.0",
+		"extensions": map[string]interface{}{"multilingual": true},
+		"models": []interface{}{
+			map[string]interface{}{
+				"id":     "main",
+				"limits": map[string]interface{}{"requests_per_minute": 100},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+
+	if _, exists := spec.Extra["extensions"]; !exists {
+		t.Error("unknown top-level field not retained")
+	}
+	if _, exists := spec.Models[0].Extra["limits"]; !exists {
+		t.Error("unknown model field not retained")
+	}
+}
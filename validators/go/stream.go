@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Issue represents a single validation finding
+type Issue struct {
+	Severity string `json:"severity"`
+	Code     string `json:"code,omitempty"`
+	Message  string `json:"message"`
+}
+
+// issueCount counts the errors and warnings of a run
+type issueCount struct {
+	errors   int
+	warnings int
+}
+
+// FileResult represents the validation result of one file in a batch
+type FileResult struct {
+	Path string `json:"path"`
+	ValidationResult
+	Err error `json:"-"`
+}
+
+// newIssue creates an issue for a finding whose code was not recorded where
+// it was raised, such as one read back from a baseline, attaching the code
+// of the rule whose pattern matches it
+func newIssue(severity, message string) Issue {
+	issue := Issue{Severity: severity, Message: message}
+	// Plugin findings carry their own code; findings in parents have the
+	// code of the finding
+	message = unattributed(message)
+	if code := pluginCode(message); code != "" {
+		issue.Code = code
+	} else if rule, ok := MatchRule(message); ok {
+		issue.Code = rule.Code
+	}
+	return issue
+}
+
+// codedIssue creates an issue, attaching the code recorded in codes for the
+// finding, or for the parent finding it repeats, where it was raised
+func codedIssue(codes map[string]string, severity, message string) Issue {
+	if code, ok := codes[unattributed(message)]; ok {
+		return Issue{Severity: severity, Code: code, Message: message}
+	}
+	return newIssue(severity, message)
+}
+
+// issue creates an issue for a finding of the current run
+func (v *APAIValidator) issue(severity, message string) Issue {
+	return codedIssue(v.codes, severity, message)
+}
+
+// reportIssues delivers the findings produced since the last call to the
+// issue handler, errors before warnings, in the order they appear in the
+// final result. Their severity was resolved as they were added.
+func (v *APAIValidator) reportIssues() {
+	if v.issueHandler == nil {
+		return
+	}
+	for ; v.reported.errors < len(v.Errors); v.reported.errors++ {
+		v.issueHandler(v.issue("error", v.Errors[v.reported.errors]))
+	}
+	for ; v.reported.warnings < len(v.Warnings); v.reported.warnings++ {
+		v.issueHandler(v.issue("warning", v.Warnings[v.reported.warnings]))
+	}
+}
+
+// resolveSeverity returns the severity of a finding reported by the rule
+// with the given code: errors whose code is listed in Config.Relax are
+// warnings, and warnings whose code is listed in Config.Promote errors
+func (v *APAIValidator) resolveSeverity(severity, code string) string {
+	switch {
+	case code == "":
+		return severity
+	case severity == "error" && containsFold(v.Config.Relax, code):
+		return "warning"
+	case severity == "warning" && containsFold(v.Config.Promote, code):
+		return "error"
+	}
+	return severity
+}
+
+// validateReclassifiedCodes warns about relaxed and promoted codes that no
+// rule reports. With plugins, which report codes of their own, any code
+// may match.
+func (v *APAIValidator) validateReclassifiedCodes() {
+	if len(v.plugins) > 0 {
+		return
+	}
+	for _, code := range v.Config.Relax {
+		if _, ok := LookupRule(code); !ok {
+			v.addWarning("UNKNOWN_RELAXED_CODE", fmt.Sprintf("Relaxed code matches no rule: %s", code))
+		}
+	}
+	for _, code := range v.Config.Promote {
+		if _, ok := LookupRule(code); !ok {
+			v.addWarning("UNKNOWN_PROMOTED_CODE", fmt.Sprintf("Promoted code matches no rule: %s", code))
+		}
+	}
+}
+
+// containsFold reports whether a slice contains a string, ignoring case
+func containsFold(slice []string, s string) bool {
+	for _, item := range slice {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateFilesStream validates files one after another, sending each
+// result on the returned channel as soon as it is ready. The channel is
+// closed after the last file; the validator must not be used until then.
+func (v *APAIValidator) ValidateFilesStream(paths []string) <-chan FileResult {
+	return v.ValidateFilesStreamContext(context.Background(), paths)
+}
+
+// ValidateFilesStreamContext is ValidateFilesStream stopping once ctx is
+// done: the result of the file in progress carries the context's error,
+// wrapped, and the channel is closed without results for the rest.
+func (v *APAIValidator) ValidateFilesStreamContext(ctx context.Context, paths []string) <-chan FileResult {
+	results := make(chan FileResult)
+	go func() {
+		defer close(results)
+		for _, filePath := range paths {
+			result := FileResult{Path: filePath}
+			if _, err := v.ValidateFileContext(ctx, filePath); err != nil {
+				result.Err = err
+			} else {
+				result.ValidationResult = v.GetResults()
+			}
+			results <- result
+			if ctx.Err() != nil {
+				return
+			}
+		}
+	}()
+	return results
+}
@@ -0,0 +1,644 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// streamedPaths are the arrays ValidateStreamContext reads an element at a
+// time: the sections that grow with the size of a specification
+var streamedPaths = []string{"models[]", "prompts[]", "constraints[]", "tasks[]", "evaluation.test_cases[]"}
+
+// errNotStreamable marks JSON documents that cannot be read an element at
+// a time, such as those repeating a field, which are validated whole
+var errNotStreamable = errors.New("document cannot be streamed")
+
+// ValidateStreamContext validates a JSON specification file reading the
+// elements of its models, prompts, constraints, tasks and evaluation test
+// cases one at a time, so that memory does not grow with their number. A
+// first pass over the file collects the ids elements reference, a second
+// validates each element with them, reporting the findings of whole-map
+// validation, in a possibly different order, except for the checks that
+// compare elements beyond their ids, such as prompt chains, step routing
+// or test case inputs. Files that are not JSON, documents with $ref or
+// $include directives, and validators using environment substitution,
+// external schemas, plugins, compliance profiles, approved models, a
+// workspace or file checks need the whole specification and are validated
+// as by ValidateFileContext.
+func (v *APAIValidator) ValidateStreamContext(ctx context.Context, filePath string) (bool, error) {
+	if !v.streamable(filePath) {
+		return v.ValidateFileContext(ctx, filePath)
+	}
+	index, err := v.indexJSONSpec(ctx, filePath)
+	if err := ctx.Err(); err != nil {
+		return false, fmt.Errorf("validating %s: %w", filePath, err)
+	}
+	// Unreadable and malformed files are reported as whole-map validation
+	// reports them
+	if err != nil || index.directives {
+		return v.ValidateFileContext(ctx, filePath)
+	}
+
+	v.Errors = make([]string, 0)
+	v.Warnings = make([]string, 0)
+	v.reported = issueCount{}
+	v.codes = nil
+	v.variables = index.variables
+	v.taskSchemas = make(map[string]*jsonschema.Schema)
+
+	// The fields read whole are checked first, the elements as they come
+	skeleton := index.skeleton
+	v.rebaseFileReferences(skeleton, filePath)
+	run := v.newStreamRun(index, filePath)
+	v.collectFindings(&run.deprecations, func() { v.validateDeprecations(skeleton) })
+	v.collectFindings(&run.extensions, func() { v.validateExtensions(skeleton) })
+	v.collectFindings(&run.numeric, func() { v.validateNumericFields(skeleton, "") })
+	v.collectFindings(&run.booleans, func() { v.validateBooleanFields(skeleton, "") })
+
+	file, err := v.openFile(filePath)
+	if err != nil {
+		return false, fmt.Errorf("file not found: %s", filePath)
+	}
+	_, _, err = walkJSONSpec(ctx, file, run.element)
+	file.Close()
+	if err := ctx.Err(); err != nil {
+		return false, fmt.Errorf("validating %s: %w", filePath, err)
+	}
+	if err != nil {
+		return false, fmt.Errorf("validating %s: %v", filePath, err)
+	}
+
+	// Findings are reported in the order of ValidateSpecContext
+	v.validateReclassifiedCodes()
+	v.reportIssues()
+	v.validateRequiredSections(skeleton)
+	v.reportIssues()
+	v.appendFindings(run.deprecations)
+	v.reportIssues()
+	v.appendFindings(run.extensions)
+	v.reportIssues()
+	for _, check := range sectionValidators {
+		var findings sectionFindings
+		if elements, streamed := run.sections[check.section]; streamed && index.streamed[check.section+"[]"] {
+			findings = elements.result()
+		} else if value, exists := skeleton[check.section]; exists {
+			check.validate(v, &findings, value)
+		}
+		v.appendFindings(findings)
+		v.reportIssues()
+	}
+	v.appendFindings(run.numeric)
+	v.appendFindings(run.booleans)
+	v.reportIssues()
+
+	if err := ctx.Err(); err != nil {
+		return false, fmt.Errorf("cross-validation: %w", err)
+	}
+	for _, findings := range run.references() {
+		v.appendFindings(findings)
+	}
+	v.reportIssues()
+
+	v.checkComplexityBudget(run.complexity())
+	v.reportIssues()
+
+	return len(v.Errors) == 0, nil
+}
+
+// streamable reports whether a file can be validated by streaming it: it
+// is JSON and the validator uses no feature needing the whole specification
+func (v *APAIValidator) streamable(filePath string) bool {
+	format := v.inputFormat
+	if format == "" {
+		format = formatOfExtension(filepath.Ext(filePath))
+	}
+	return format == "json" && v.envLookup == nil && len(v.schemas) == 0 && len(v.plugins) == 0 &&
+		len(v.compliance) == 0 && v.approvedModels == nil && v.workspace == nil && !v.Config.CheckFiles
+}
+
+// collectFindings runs check, moving the findings it adds to the validator
+// into f with their codes
+func (v *APAIValidator) collectFindings(f *sectionFindings, check func()) {
+	errorCount, warningCount := len(v.Errors), len(v.Warnings)
+	check()
+	for _, message := range v.Errors[errorCount:] {
+		f.addError(v.codes[message], message)
+	}
+	for _, message := range v.Warnings[warningCount:] {
+		f.addWarning(v.codes[message], message)
+	}
+	v.Errors, v.Warnings = v.Errors[:errorCount], v.Warnings[:warningCount]
+}
+
+// appendFindings adds findings to those of the validator, with the
+// severity their codes resolve to
+func (v *APAIValidator) appendFindings(f sectionFindings) {
+	for _, message := range f.Errors {
+		v.addFinding("error", f.Codes[message], message)
+	}
+	for _, message := range f.Warnings {
+		v.addFinding("warning", f.Codes[message], message)
+	}
+}
+
+// walkJSONSpec reads a JSON specification from r, passing each element of
+// the arrays of streamedPaths to element as soon as it is decoded. It
+// returns the rest of the document, where streamed arrays are left empty,
+// and the paths of the arrays it streamed.
+func walkJSONSpec(ctx context.Context, r io.Reader, element func(path string, index int, item interface{})) (map[string]interface{}, map[string]bool, error) {
+	walker := &jsonSpecWalker{ctx: ctx, decoder: json.NewDecoder(bufio.NewReader(r)), element: element, streamed: make(map[string]bool)}
+	token, err := walker.decoder.Token()
+	if err != nil {
+		return nil, nil, err
+	}
+	if token != json.Delim('{') {
+		return nil, nil, errNotStreamable
+	}
+	value, err := walker.read(token, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	// Anything after the document is an error, as for json.Unmarshal
+	if _, err := walker.decoder.Token(); err != io.EOF {
+		return nil, nil, errNotStreamable
+	}
+	spec, _ := value.(map[string]interface{})
+	return spec, walker.streamed, nil
+}
+
+// jsonSpecWalker holds the state of walkJSONSpec
+type jsonSpecWalker struct {
+	ctx      context.Context
+	decoder  *json.Decoder
+	element  func(path string, index int, item interface{})
+	streamed map[string]bool
+}
+
+// read reads the value at path, as in objectsAt, whose first token has
+// been read, streaming the arrays of streamedPaths inside it
+func (w *jsonSpecWalker) read(token json.Token, path string) (interface{}, error) {
+	switch token {
+	case json.Delim('['):
+		streamed := containsString(streamedPaths, path+"[]")
+		if streamed {
+			w.streamed[path+"[]"] = true
+		}
+		items := make([]interface{}, 0)
+		for index := 0; w.decoder.More(); index++ {
+			if err := w.ctx.Err(); err != nil {
+				return nil, err
+			}
+			var item interface{}
+			if err := w.decoder.Decode(&item); err != nil {
+				return nil, err
+			}
+			if streamed {
+				w.element(path+"[]", index, item)
+			} else {
+				items = append(items, item)
+			}
+		}
+		_, err := w.decoder.Token()
+		return items, err
+	case json.Delim('{'):
+		object := make(map[string]interface{})
+		for w.decoder.More() {
+			token, err := w.decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			key, _ := token.(string)
+			if _, exists := object[key]; exists {
+				return nil, errNotStreamable
+			}
+			fieldPath := joinLocation(path, key)
+			var value interface{}
+			if streamsWithin(fieldPath) {
+				if token, err = w.decoder.Token(); err == nil {
+					value, err = w.read(token, fieldPath)
+				}
+			} else {
+				err = w.decoder.Decode(&value)
+			}
+			if err != nil {
+				return nil, err
+			}
+			object[key] = value
+		}
+		_, err := w.decoder.Token()
+		return object, err
+	}
+	return token, nil
+}
+
+// streamsWithin reports whether the value at path is, or holds, an array
+// of streamedPaths
+func streamsWithin(path string) bool {
+	for _, streamed := range streamedPaths {
+		if streamed == path+"[]" || strings.HasPrefix(streamed, path+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// hasDirective reports whether a value holds a $ref or $include directive,
+// which only whole-map validation resolves
+func hasDirective(value interface{}) bool {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for key, item := range typed {
+			if key == refKey || key == includeKey || hasDirective(item) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, item := range typed {
+			if hasDirective(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// streamIndex is what the first pass of a streaming validation learns of
+// the whole specification: the fields read whole, the ids elements
+// reference and the variables prompts share
+type streamIndex struct {
+	skeleton map[string]interface{}
+	streamed map[string]bool
+	// models and prompts hold the declared ids; tasks maps the id of each
+	// task to whether it is abstract, and taskNames holds the names test
+	// cases refer to tasks by
+	models     map[string]bool
+	prompts    map[string]bool
+	tasks      map[string]bool
+	taskNames  map[string]bool
+	variables  *variableScope
+	directives bool
+}
+
+// indexJSONSpec reads a JSON specification file a first time, keeping only
+// what the second pass of ValidateStreamContext needs
+func (v *APAIValidator) indexJSONSpec(ctx context.Context, filePath string) (*streamIndex, error) {
+	file, err := v.openFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	index := &streamIndex{
+		models:    make(map[string]bool),
+		prompts:   make(map[string]bool),
+		tasks:     make(map[string]bool),
+		taskNames: make(map[string]bool),
+		variables: newVariableScope(nil),
+	}
+	index.skeleton, index.streamed, err = walkJSONSpec(ctx, file, func(path string, i int, item interface{}) {
+		if hasDirective(item) {
+			index.directives = true
+		}
+		itemMap, ok := item.(map[string]interface{})
+		if !ok {
+			return
+		}
+		id, hasID := itemMap["id"].(string)
+		switch path {
+		case "models[]":
+			if hasID {
+				index.models[id] = true
+			}
+		case "prompts[]":
+			if hasID {
+				index.prompts[id] = true
+			}
+		case "tasks[]":
+			index.taskNames[elementName(i, itemMap)] = true
+			if hasID {
+				index.tasks[id], _ = itemMap["abstract"].(bool)
+			}
+			index.variables.addTask(itemMap)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if hasDirective(index.skeleton) {
+		index.directives = true
+	}
+	index.variables.global = newVariableScope(index.skeleton).global
+	return index, nil
+}
+
+// streamedSection validates the elements of a streamed array section one
+// at a time, then what depends on all of them
+type streamedSection struct {
+	findings sectionFindings
+	count    int
+	element  func(f *sectionFindings, item interface{}, index int)
+	// finish, when set, runs once every element is validated
+	finish func(f *sectionFindings, count int)
+}
+
+// streamRun holds the findings of the second pass of a streaming
+// validation by the step of ValidateSpecContext that reports them, so
+// they come in the same order, and what the steps after the sections need
+type streamRun struct {
+	v        *APAIValidator
+	index    *streamIndex
+	filePath string
+	sections map[string]*streamedSection
+
+	// summaries holds, by section, the fields of each streamed element the
+	// checks comparing elements read, such as its id
+	summaries map[string][]interface{}
+
+	deprecations, extensions, numeric, booleans sectionFindings
+
+	// Findings of the cross-validation, by check
+	modelRefs, promptRefs, serverRefs, taskRefs, taskSchemas, testCases sectionFindings
+
+	// servers holds the declared MCP server ids, nil when the specification
+	// declares none, and usedServers those steps reference
+	servers     map[string]bool
+	usedServers map[string]bool
+
+	// measured is the complexity of the elements read so far; taskEdges
+	// counts the distinct references of the tasks of taskOrder
+	measured  Complexity
+	taskEdges map[string]int
+	taskOrder []string
+}
+
+// newStreamRun prepares the second pass of a streaming validation
+func (v *APAIValidator) newStreamRun(index *streamIndex, filePath string) *streamRun {
+	run := &streamRun{
+		v:           v,
+		index:       index,
+		filePath:    filePath,
+		summaries:   make(map[string][]interface{}),
+		usedServers: make(map[string]bool),
+		measured:    ComputeComplexity(index.skeleton, 0),
+		taskEdges:   make(map[string]int),
+	}
+	if contextMap, ok := index.skeleton["context"].(map[string]interface{}); ok {
+		if servers, exists := contextMap["mcp_servers"]; exists {
+			run.servers = make(map[string]bool)
+			for _, id := range sectionIds(servers) {
+				run.servers[id] = true
+			}
+		}
+	}
+
+	modelIds, currencies := make(map[string]bool), make(map[string][]string)
+	promptIds, constraintIds, taskIds := make(map[string]bool), make(map[string]bool), make(map[string]bool)
+	run.sections = map[string]*streamedSection{
+		"models": {
+			element: func(f *sectionFindings, item interface{}, i int) {
+				v.validateModel(f, item, i, modelIds, currencies)
+				run.summarize("models", elementSummary(item, "id", "type", "provider", "name", "purpose", "rate_limit"))
+			},
+			finish: func(f *sectionFindings, count int) {
+				if count == 0 {
+					f.addError("MISSING_MODEL", "At least one model is required")
+					return
+				}
+				validateCostCurrencies(f, currencies)
+				validateSharedRateLimits(f, run.summaries["models"])
+			},
+		},
+		"prompts": {
+			element: func(f *sectionFindings, item interface{}, i int) {
+				v.validatePrompt(f, item, i, promptIds)
+				run.summarize("prompts", translationSummary(item))
+			},
+			finish: func(f *sectionFindings, count int) {
+				validateTranslationCoverage(f, run.summaries["prompts"])
+				validateChainLanguages(f, run.summaries["prompts"])
+			},
+		},
+		"constraints": {
+			element: func(f *sectionFindings, item interface{}, i int) {
+				v.validateConstraint(f, item, i, constraintIds)
+				run.summarize("constraints", elementSummary(item, "id", "name", "type", "rule", "severity", "description"))
+			},
+			finish: func(f *sectionFindings, count int) {
+				validateConstraintContradictions(f, run.summaries["constraints"])
+			},
+		},
+		"tasks": {
+			element: func(f *sectionFindings, item interface{}, i int) {
+				v.validateTask(f, item, i, taskIds)
+				run.summarize("tasks", elementSummary(item, "id"))
+			},
+		},
+	}
+	return run
+}
+
+// summarize adds the summary of the next element of a section
+func (r *streamRun) summarize(section string, summary interface{}) {
+	r.summaries[section] = append(r.summaries[section], summary)
+}
+
+// summarySpec returns the fields read whole with the summaries of the
+// streamed elements in place of their sections, for the checks relating
+// sections that only read what summaries keep
+func (r *streamRun) summarySpec() map[string]interface{} {
+	spec := make(map[string]interface{}, len(r.index.skeleton))
+	for field, value := range r.index.skeleton {
+		spec[field] = value
+	}
+	for section, summaries := range r.summaries {
+		if r.index.streamed[section+"[]"] {
+			spec[section] = summaries
+		}
+	}
+	return spec
+}
+
+// result returns the findings of a streamed section once all its
+// elements are validated
+func (s *streamedSection) result() sectionFindings {
+	if s.finish != nil {
+		s.finish(&s.findings, s.count)
+	}
+	return s.findings
+}
+
+// element validates one element of a streamed array, at path
+func (r *streamRun) element(path string, index int, item interface{}) {
+	v := r.v
+	location := fmt.Sprintf("%s[%d]", strings.TrimSuffix(path, "[]"), index)
+	v.rebaseFileReferences(specWithElement(path, item), r.filePath)
+
+	v.collectFindings(&r.deprecations, func() { v.validateDeprecationsWithin(item, path, location, r.index.skeleton["apai"]) })
+	v.collectFindings(&r.extensions, func() { v.validateExtensionsWithin(item, path, location) })
+	if section := r.sections[strings.TrimSuffix(path, "[]")]; section != nil {
+		section.element(&section.findings, item, section.count)
+		section.count++
+	}
+	v.collectFindings(&r.numeric, func() { v.validateNumericFields(item, location) })
+	v.collectFindings(&r.booleans, func() { v.validateBooleanFields(item, location) })
+
+	itemMap, ok := item.(map[string]interface{})
+	if !ok {
+		return
+	}
+	switch path {
+	case "prompts[]":
+		r.measured.PromptTokens += estimateTokens(promptText(itemMap)) + v.templateFileTokens(itemMap)
+	case "tasks[]":
+		r.task(itemMap, index, location)
+	case "evaluation.test_cases[]":
+		v.collectFindings(&r.testCases, func() { v.validateTestCaseTask(itemMap, location, r.index.taskNames) })
+	}
+}
+
+// task checks the references of the steps of a task to the ids of the
+// index, and measures it
+func (r *streamRun) task(taskMap map[string]interface{}, index int, location string) {
+	v, skeleton := r.v, r.index.skeleton
+	_, hasModels := skeleton["models"]
+	_, hasPrompts := skeleton["prompts"]
+	edges := make(map[string]bool)
+	objectsAt(taskMap, "steps[]", location, func(step map[string]interface{}, stepLocation string) {
+		if model, ok := step["model"].(string); ok && hasModels && !r.index.models[model] {
+			r.modelRefs.addError("UNKNOWN_REFERENCE", fmt.Sprintf("Task references unknown model: %s", model))
+		}
+		if prompt, ok := step["prompt"].(string); ok && hasPrompts && !r.index.prompts[prompt] {
+			r.promptRefs.addError("UNKNOWN_REFERENCE", fmt.Sprintf("Task references unknown prompt: %s", prompt))
+		}
+		if server, ok := step["mcp_server"].(string); ok && r.servers != nil {
+			r.usedServers[server] = true
+			if !r.servers[server] && !isWorkspaceReference(server) {
+				r.serverRefs.addError("UNKNOWN_REFERENCE", fmt.Sprintf("Task references unknown MCP server: %s", server))
+			}
+		}
+		if taskID, ok := step["task"].(string); ok && !isWorkspaceReference(taskID) {
+			if abstract, exists := r.index.tasks[taskID]; !exists {
+				r.taskRefs.addError("UNKNOWN_REFERENCE", fmt.Sprintf("Task references unknown task: %s", taskID))
+			} else if abstract {
+				r.taskRefs.addError("ABSTRACT_TASK_RUN", fmt.Sprintf("Abstract task %s cannot be run by %s", taskID, stepLocation))
+			}
+		}
+		for _, ref := range graphStepReferences {
+			if target, ok := step[ref.field].(string); ok {
+				edges[ref.kind+":"+target] = true
+			}
+		}
+	})
+	v.collectFindings(&r.taskSchemas, func() { v.compileTaskSchemas(taskMap, elementName(index, taskMap)) })
+
+	steps, _ := taskMap["steps"].([]interface{})
+	if len(steps) > r.measured.MaxStepsPerTask {
+		r.measured.MaxStepsPerTask = len(steps)
+		r.measured.LongestTask, _ = taskMap["id"].(string)
+	}
+	if id, ok := taskMap["id"].(string); ok {
+		if _, seen := r.taskEdges[id]; !seen {
+			r.taskOrder = append(r.taskOrder, id)
+		}
+		r.taskEdges[id] += len(edges)
+	}
+}
+
+// references returns the findings of the cross-validation by id, in the
+// order crossValidate reports them
+func (r *streamRun) references() []sectionFindings {
+	if r.servers != nil && r.v.Config.WarnUnusedMCPServers {
+		contextMap, _ := r.index.skeleton["context"].(map[string]interface{})
+		for _, id := range sectionIds(contextMap["mcp_servers"]) {
+			if !r.usedServers[id] && !r.v.serverUsedByWorkspace(id) {
+				r.serverRefs.addWarning("UNUSED_MCP_SERVER", fmt.Sprintf("MCP server '%s' is declared but never used", id))
+			}
+		}
+	}
+	var summarized sectionFindings
+	spec := r.summarySpec()
+	r.v.collectFindings(&summarized, func() {
+		r.v.validatePersistenceRetention(spec)
+		r.v.validateExperimentReferences(spec)
+		r.v.validateMetricLinks(spec)
+		r.v.validateBroadestLevel(spec)
+	})
+	return []sectionFindings{r.modelRefs, r.promptRefs, r.serverRefs, r.taskRefs, r.taskSchemas, r.testCases, summarized}
+}
+
+// complexity returns the complexity of the specification streamed, as
+// measureComplexity measures it
+func (r *streamRun) complexity() Complexity {
+	complexity := r.measured
+	for _, section := range []string{"models", "prompts", "constraints", "tasks"} {
+		if r.index.streamed[section+"[]"] {
+			complexity.Sections[section] = r.sections[section].count
+		}
+	}
+	for _, id := range r.taskOrder {
+		if r.taskEdges[id] > complexity.BranchingFactor {
+			complexity.BranchingFactor = r.taskEdges[id]
+			complexity.WidestTask = id
+		}
+	}
+	return complexity
+}
+
+// specWithElement returns a specification holding item as the only
+// element of the array at path, for the checks that take a specification
+func specWithElement(path string, item interface{}) map[string]interface{} {
+	var value interface{} = []interface{}{item}
+	names := strings.Split(strings.TrimSuffix(path, "[]"), ".")
+	for i := len(names) - 1; i >= 0; i-- {
+		value = map[string]interface{}{names[i]: value}
+	}
+	spec, _ := value.(map[string]interface{})
+	return spec
+}
+
+// elementSummary returns the fields of an element the checks comparing
+// elements with each other read, nil when it is not an object
+func elementSummary(item interface{}, fields ...string) interface{} {
+	itemMap, ok := item.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	summary := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		if value, exists := itemMap[field]; exists {
+			summary[field] = value
+		}
+	}
+	return summary
+}
+
+// translationSummary returns the fields of a prompt the translation
+// coverage and chain language checks read, with the languages of its
+// variants but not their templates
+func translationSummary(item interface{}) interface{} {
+	summary, ok := elementSummary(item, "id", "language", "chain", "next").(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	itemMap, _ := item.(map[string]interface{})
+	for _, field := range translationFields {
+		variants, ok := itemMap[field].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		languages := make(map[string]interface{}, len(variants))
+		for language := range variants {
+			languages[language] = nil
+		}
+		summary[field] = languages
+	}
+	return summary
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// writeJSONSpec writes a specification as JSON to a file of dir
+func writeJSONSpec(tb testing.TB, dir, name string, spec map[string]interface{}) string {
+	tb.Helper()
+	content, err := json.Marshal(spec)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, content, 0644); err != nil {
+		tb.Fatal(err)
+	}
+	return path
+}
+
+// sortedFindings returns the errors and warnings of a validator, sorted, as
+// streaming validation may report them in a different order
+func sortedFindings(v *APAIValidator) ([]string, []string) {
+	errs := append([]string{}, v.Errors...)
+	warnings := append([]string{}, v.Warnings...)
+	sort.Strings(errs)
+	sort.Strings(warnings)
+	return errs, warnings
+}
+
+// compareStreamed validates a JSON file whole and streamed with validators
+// built from options, and fails when their findings differ
+func compareStreamed(t *testing.T, path string, options ...Option) *APAIValidator {
+	t.Helper()
+	whole := NewAPAIValidator(options...)
+	wholeValid, err := whole.ValidateFileContext(context.Background(), path)
+	if err != nil {
+		t.Fatalf("validating %s whole: %v", path, err)
+	}
+	streamed := NewAPAIValidator(options...)
+	streamedValid, err := streamed.ValidateStreamContext(context.Background(), path)
+	if err != nil {
+		t.Fatalf("streaming %s: %v", path, err)
+	}
+
+	wholeErrors, wholeWarnings := sortedFindings(whole)
+	streamedErrors, streamedWarnings := sortedFindings(streamed)
+	if wholeValid != streamedValid || !reflect.DeepEqual(wholeErrors, streamedErrors) || !reflect.DeepEqual(wholeWarnings, streamedWarnings) {
+		t.Errorf("%s: streamed findings differ\nwhole:    %v %v\nstreamed: %v %v", path, wholeErrors, wholeWarnings, streamedErrors, streamedWarnings)
+	}
+	return streamed
+}
+
+func TestValidateStreamMatchesExamples(t *testing.T) {
+	paths := []string{
+		"templates/basic-template.yaml", "templates/security-template.yaml",
+		"core/customer-support.yaml", "core/content-moderator.yaml", "core/multilingual-chatbot.yaml",
+		"agents/sentiment-analyzer.yaml", "automation/ecommerce-automation.yaml", "automation/mcp-integration.yaml",
+		"multi-agent/multi-agent-customer-support.yaml",
+	}
+	dir := t.TempDir()
+	for i, spec := range loadExampleSpecs(t, paths...) {
+		compareStreamed(t, writeJSONSpec(t, dir, fmt.Sprintf("spec-%d.json", i), spec))
+	}
+	compareStreamed(t, filepath.Join("..", "..", "examples", "apai-0.1-example.json"))
+}
+
+func TestValidateStreamReportsElementFindings(t *testing.T) {
+	spec := loadExampleSpecs(t, "templates/basic-template.yaml")[0]
+	spec["apai"] = "0.2.0"
+	task := copyValue(spec["tasks"].([]interface{})[0]).(map[string]interface{})
+	task["x-apai-owner"] = "platform"
+	task["retries"] = 3
+	task["steps"] = []interface{}{
+		map[string]interface{}{"name": "answer", "action": "generate", "model": "missing_model", "prompt": "missing_prompt"},
+		map[string]interface{}{"name": "delegate", "action": "run_task", "task": "template"},
+	}
+	abstract := map[string]interface{}{"id": "template", "description": "Template", "abstract": true}
+	spec["tasks"] = append(spec["tasks"].([]interface{}), task, abstract)
+
+	model := spec["models"].([]interface{})[0].(map[string]interface{})
+	model["parameters"].(map[string]interface{})["temperature"] = "0.7"
+	evaluation := spec["evaluation"].(map[string]interface{})
+	evaluation["test_cases"] = append(evaluation["test_cases"].([]interface{}), map[string]interface{}{"id": "orphan", "task": "missing_task"})
+
+	path := writeJSONSpec(t, t.TempDir(), "spec.json", spec)
+	config := DefaultConfig()
+	config.StrictFields = true
+	streamed := compareStreamed(t, path, WithConfig(config))
+	if index, err := streamed.indexJSONSpec(context.Background(), path); err != nil || index.directives || !streamed.streamable(path) {
+		t.Fatalf("expected %s to be streamed", path)
+	}
+
+	findings := strings.Join(append(streamed.Errors, streamed.Warnings...), "\n")
+	for _, want := range []string{
+		"Duplicate task ID: handle_request",
+		"Task references unknown model: missing_model",
+		"Task references unknown prompt: missing_prompt",
+		"Abstract task template cannot be run by tasks[1].steps[1]",
+		"Test case orphan references unknown task: missing_task",
+		"models[0].parameters.temperature",
+		"models[0].purpose is deprecated",
+		"Reserved extension field: tasks[1].x-apai-owner",
+		"Unknown field: tasks[1].retries",
+	} {
+		if !strings.Contains(findings, want) {
+			t.Errorf("expected a finding with %q, got:\n%s", want, findings)
+		}
+	}
+}
+
+func TestValidateStreamFallsBack(t *testing.T) {
+	dir := t.TempDir()
+	spec := loadExampleSpecs(t, "templates/basic-template.yaml")[0]
+	spec["definitions"] = map[string]interface{}{"model": spec["models"].([]interface{})[0]}
+	spec["models"] = []interface{}{map[string]interface{}{"$ref": "#/definitions/model"}}
+	compareStreamed(t, writeJSONSpec(t, dir, "refs.json", spec))
+
+	// A repeated field is validated as json.Unmarshal decodes it
+	repeated := filepath.Join(dir, "repeated.json")
+	if err := ioutil.WriteFile(repeated, []byte(`{"apai": "0.1.0", "tasks": [], "tasks": [{"id": "a"}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	compareStreamed(t, repeated)
+
+	// Environment substitution needs the whole specification
+	spec = loadExampleSpecs(t, "templates/basic-template.yaml")[0]
+	spec["info"].(map[string]interface{})["title"] = "${TITLE}"
+	compareStreamed(t, writeJSONSpec(t, dir, "env.json", spec), WithEnvSubstitution(func(string) (string, bool) { return "", false }, false))
+
+	malformed := filepath.Join(dir, "malformed.json")
+	if err := ioutil.WriteFile(malformed, []byte(`{"apai": "0.1.0", "models": [`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, streamErr := NewAPAIValidator().ValidateStreamContext(context.Background(), malformed)
+	_, wholeErr := NewAPAIValidator().ValidateFileContext(context.Background(), malformed)
+	if streamErr == nil || wholeErr == nil || streamErr.Error() != wholeErr.Error() {
+		t.Errorf("expected the whole-map error %v for a malformed file, got %v", wholeErr, streamErr)
+	}
+}
+
+func TestWalkJSONSpec(t *testing.T) {
+	content := `{"apai": "0.1.0", "tasks": [{"id": "a"}, {"id": "b"}], "evaluation": {"metrics": [], "test_cases": [{"id": "c"}]}, "models": "none"}`
+	elements := make([]string, 0)
+	spec, streamed, err := walkJSONSpec(context.Background(), strings.NewReader(content), func(path string, index int, item interface{}) {
+		elements = append(elements, fmt.Sprintf("%s %d %v", path, index, item.(map[string]interface{})["id"]))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"tasks[] 0 a", "tasks[] 1 b", "evaluation.test_cases[] 0 c"}
+	if !reflect.DeepEqual(elements, want) {
+		t.Errorf("expected elements %v, got %v", want, elements)
+	}
+	if !reflect.DeepEqual(streamed, map[string]bool{"tasks[]": true, "evaluation.test_cases[]": true}) {
+		t.Errorf("expected tasks and test cases streamed, got %v", streamed)
+	}
+	wantSpec := map[string]interface{}{
+		"apai":       "0.1.0",
+		"tasks":      []interface{}{},
+		"evaluation": map[string]interface{}{"metrics": []interface{}{}, "test_cases": []interface{}{}},
+		"models":     "none",
+	}
+	if !reflect.DeepEqual(spec, wantSpec) {
+		t.Errorf("expected the rest of the document %v, got %v", wantSpec, spec)
+	}
+
+	for _, content := range []string{`{"tasks": [], "tasks": []}`, `{"apai": "0.1.0"} {}`, `[]`} {
+		_, _, err := walkJSONSpec(context.Background(), strings.NewReader(content), func(string, int, interface{}) {})
+		if !errors.Is(err, errNotStreamable) {
+			t.Errorf("%s: expected errNotStreamable, got %v", content, err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := walkJSONSpec(ctx, strings.NewReader(content), func(string, int, interface{}) {}); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the context error, got %v", err)
+	}
+}
+
+// writeLargeJSONSpec writes the basic template with count tasks and as
+// many test cases
+func writeLargeJSONSpec(tb testing.TB, path string, count int) {
+	tb.Helper()
+	spec, err := NewAPAIValidator().loadSpec(filepath.Join("..", "..", "examples", "templates", "basic-template.yaml"))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	base := spec["tasks"].([]interface{})[0]
+	evaluation := spec["evaluation"].(map[string]interface{})
+	tasks, testCases := make([]interface{}, 0, count), make([]interface{}, 0, count)
+	for i := 0; i < count; i++ {
+		task := copyValue(base).(map[string]interface{})
+		task["id"] = fmt.Sprintf("task_%d", i)
+		tasks = append(tasks, task)
+		testCases = append(testCases, map[string]interface{}{
+			"id": fmt.Sprintf("case_%d", i), "task": task["id"],
+			"input":           map[string]interface{}{"user_message": fmt.Sprintf("Question %d about an order", i)},
+			"expected_output": "A helpful answer",
+		})
+	}
+	spec["tasks"] = tasks
+	evaluation["test_cases"] = testCases
+	writeJSONSpec(tb, filepath.Dir(path), filepath.Base(path), spec)
+}
+
+// streamMemoryHelperEnv asks TestStreamMemoryHelper, run in a process of
+// its own by BenchmarkValidateLargeJSON, to validate a file and print the
+// peak resident set size of the process
+const streamMemoryHelperEnv = "APAI_STREAM_MEMORY_HELPER"
+
+func TestStreamMemoryHelper(t *testing.T) {
+	mode, path, ok := strings.Cut(os.Getenv(streamMemoryHelperEnv), ":")
+	if !ok {
+		t.Skip("run by BenchmarkValidateLargeJSON")
+	}
+	validator := NewAPAIValidator()
+	validate := validator.ValidateFileContext
+	if mode == "stream" {
+		validate = validator.ValidateStreamContext
+	}
+	if _, err := validate(context.Background(), path); err != nil {
+		t.Fatal(err)
+	}
+	status, err := ioutil.ReadFile("/proc/self/status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(string(status), "\n") {
+		if strings.HasPrefix(line, "VmHWM:") {
+			fmt.Println(line)
+		}
+	}
+}
+
+// BenchmarkValidateLargeJSON compares the peak RSS of validating a 20000
+// task JSON specification whole and streamed, each in a process of its own
+// since peak RSS never goes down. Peak RSS is read from /proc, on Linux.
+func BenchmarkValidateLargeJSON(b *testing.B) {
+	if _, err := os.Stat("/proc/self/status"); err != nil {
+		b.Skip("peak RSS is read from /proc/self/status")
+	}
+	path := filepath.Join(b.TempDir(), "large.json")
+	writeLargeJSONSpec(b, path, 20000)
+
+	for _, mode := range []string{"whole", "stream"} {
+		b.Run(mode, func(b *testing.B) {
+			peak := 0
+			for i := 0; i < b.N; i++ {
+				cmd := exec.Command(os.Args[0], "-test.run=^TestStreamMemoryHelper$", "-test.v")
+				cmd.Env = append(os.Environ(), streamMemoryHelperEnv+"="+mode+":"+path)
+				output, err := cmd.CombinedOutput()
+				if err != nil {
+					b.Fatalf("%v: %s", err, output)
+				}
+				for _, line := range strings.Split(string(output), "\n") {
+					fields := strings.Fields(line)
+					if len(fields) == 3 && fields[0] == "VmHWM:" {
+						kilobytes, _ := strconv.Atoi(fields[1])
+						if kilobytes > peak {
+							peak = kilobytes
+						}
+					}
+				}
+			}
+			b.ReportMetric(float64(peak)/1024, "peak-RSS-MB")
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Finding is a style issue at a line and column of a YAML file
+type Finding struct {
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%d:%d: %s %s", f.Line, f.Column, f.Code, f.Message)
+}
+
+// StyleCheck inspects a parsed YAML document for style issues validation
+// cannot see: nested blocks indented by a different width than the first
+// one of the document, and top-level sections out of canonical order.
+// Sequences written at the column of their key are accepted.
+func StyleCheck(node *yaml.Node) []Finding {
+	findings := make([]Finding, 0)
+	if node == nil {
+		return findings
+	}
+	if node.Kind == yaml.DocumentNode {
+		if len(node.Content) == 0 {
+			return findings
+		}
+		node = node.Content[0]
+	}
+
+	if node.Kind == yaml.MappingNode {
+		ranks := make(map[string]int, len(sectionOrder))
+		for rank, section := range sectionOrder {
+			ranks[section] = rank
+		}
+		previous, previousRank := "", -1
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			rank, known := ranks[key.Value]
+			if !known {
+				continue
+			}
+			if rank < previousRank {
+				findings = append(findings, Finding{
+					Line: key.Line, Column: key.Column, Code: "SECTION_ORDER",
+					Message: fmt.Sprintf("Section %s comes after %s, out of the canonical order %s", key.Value, previous, strings.Join(sectionOrder, ", ")),
+				})
+				continue
+			}
+			previous, previousRank = key.Value, rank
+		}
+	}
+
+	width := 0
+	var walk func(node *yaml.Node, location string)
+	walk = func(node *yaml.Node, location string) {
+		if node.Style&yaml.FlowStyle != 0 {
+			return
+		}
+		switch node.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				key, value := node.Content[i], node.Content[i+1]
+				valueLocation := joinLocation(location, key.Value)
+				nested := (value.Kind == yaml.MappingNode || value.Kind == yaml.SequenceNode) && value.Style&yaml.FlowStyle == 0
+				if nested && value.Line > key.Line {
+					step := value.Column - key.Column
+					switch {
+					case step == 0 && value.Kind == yaml.SequenceNode:
+					case width == 0 && step > 0:
+						width = step
+					case step != width:
+						findings = append(findings, Finding{
+							Line: value.Line, Column: value.Column, Code: "INCONSISTENT_INDENTATION",
+							Message: fmt.Sprintf("%s is indented by %d spaces, unlike the %d of the rest of the file", valueLocation, step, width),
+						})
+					}
+				}
+				walk(value, valueLocation)
+			}
+		case yaml.SequenceNode:
+			for i, item := range node.Content {
+				walk(item, fmt.Sprintf("%s[%d]", location, i))
+			}
+		}
+	}
+	walk(node, "")
+	return findings
+}
+
+// styleCheckLines reports the style issues of the text of a YAML file:
+// tabs in indentation, which YAML forbids, and trailing whitespace
+func styleCheckLines(content []byte) []Finding {
+	findings := make([]Finding, 0)
+	for i, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		indentation := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if column := strings.IndexByte(indentation, '\t'); column >= 0 && len(indentation) < len(line) {
+			findings = append(findings, Finding{Line: i + 1, Column: column + 1, Code: "TAB_INDENTATION", Message: "Tab used for indentation"})
+		}
+		if trimmed := strings.TrimRight(line, " \t"); len(trimmed) < len(line) {
+			findings = append(findings, Finding{Line: i + 1, Column: len(trimmed) + 1, Code: "TRAILING_WHITESPACE", Message: "Trailing whitespace"})
+		}
+	}
+	return findings
+}
+
+// LintYAML reports the style issues of a YAML file, of each of its
+// documents, in line order. The text is checked even when it does not
+// parse, in which case the parse error is returned with its findings.
+func LintYAML(content []byte) ([]Finding, error) {
+	findings := styleCheckLines(content)
+
+	var parseErr error
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	for {
+		var document yaml.Node
+		if err := decoder.Decode(&document); err != nil {
+			if !errors.Is(err, io.EOF) {
+				parseErr = fmt.Errorf("invalid YAML: %v", err)
+			}
+			break
+		}
+		findings = append(findings, StyleCheck(&document)...)
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Line != findings[j].Line {
+			return findings[i].Line < findings[j].Line
+		}
+		return findings[i].Column < findings[j].Column
+	})
+	return findings, parseErr
+}
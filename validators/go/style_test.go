@@ -0,0 +1,62 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLintYAML(t *testing.T) {
+	content := []byte("apai: \"0.1.0\"\n" +
+		"models:\n" +
+		"  - id: \"main_model\"   \n" +
+		"    parameters:\n" +
+		"        temperature: 0.7\n" +
+		"info:\n" +
+		"  title: \"Support\"\n" +
+		"tasks:\n" +
+		"- id: \"answer\"\n" +
+		"  steps: [{name: \"draft\"}]\n")
+
+	findings, err := LintYAML(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Finding{
+		{Line: 3, Column: 21, Code: "TRAILING_WHITESPACE", Message: "Trailing whitespace"},
+		{Line: 5, Column: 9, Code: "INCONSISTENT_INDENTATION", Message: "models[0].parameters is indented by 4 spaces, unlike the 2 of the rest of the file"},
+		{Line: 6, Column: 1, Code: "SECTION_ORDER", Message: "Section info comes after models, out of the canonical order apai, info, models, prompts, constraints, tasks, context, evaluation"},
+	}
+	if !reflect.DeepEqual(findings, want) {
+		t.Errorf("got %v, want %v", findings, want)
+	}
+	for _, finding := range findings {
+		if rule, ok := MatchRule(finding.Message); !ok || rule.Code != finding.Code {
+			t.Errorf("finding %q is not reported by rule %s", finding.Message, finding.Code)
+		}
+	}
+}
+
+func TestLintYAMLWithTabs(t *testing.T) {
+	findings, err := LintYAML([]byte("info:\n\ttitle: \"Support\"\n"))
+	if err == nil {
+		t.Error("expected a parse error for tab indentation")
+	}
+	want := []Finding{{Line: 2, Column: 1, Code: "TAB_INDENTATION", Message: "Tab used for indentation"}}
+	if !reflect.DeepEqual(findings, want) {
+		t.Errorf("got %v, want %v", findings, want)
+	}
+}
+
+func TestStyleCheckExamples(t *testing.T) {
+	validator := NewAPAIValidator()
+	for _, path := range []string{"core/customer-support.yaml", "agents/sentiment-analyzer.yaml"} {
+		document, err := validator.loadCommentTree(filepath.Join("..", "..", "examples", path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if findings := StyleCheck(document); len(findings) > 0 {
+			t.Errorf("%s: unexpected findings %v", path, findings)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// taskSchemaFields are the task fields holding inline JSON Schemas of the
+// values the task accepts and returns
+var taskSchemaFields = []string{"input_schema", "output_schema"}
+
+// jsonSchemaTypes are the type names JSON Schema defines
+var jsonSchemaTypes = []string{"array", "boolean", "integer", "null", "number", "object", "string"}
+
+// schemaMapKeywords hold objects mapping names to subschemas, and
+// schemaListKeywords arrays of subschemas; other keywords holding objects
+// are subschemas themselves, except those holding data
+var (
+	schemaMapKeywords  = []string{"properties", "patternProperties", "$defs", "definitions", "dependentSchemas"}
+	schemaListKeywords = []string{"allOf", "anyOf", "oneOf", "prefixItems"}
+	schemaDataKeywords = []string{"const", "default", "enum", "examples"}
+)
+
+// validateTaskSchemas compiles the input_schema and output_schema of each
+// task as JSON Schema 2020-12 and checks the input of every evaluation test
+// case naming a task against its input_schema. The compiled input schemas
+// are kept for ValidateAgainstTaskSchema.
+func (v *APAIValidator) validateTaskSchemas(spec map[string]interface{}) {
+	v.taskSchemas = make(map[string]*jsonschema.Schema)
+	taskIDs := make(map[string]bool)
+	tasks, _ := spec["tasks"].([]interface{})
+	for index, task := range tasks {
+		taskMap, ok := task.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name := elementName(index, taskMap)
+		taskIDs[name] = true
+		if schema := v.compileTaskSchemas(taskMap, name); schema != nil {
+			v.taskSchemas[name] = schema
+		}
+	}
+
+	objectsAt(spec, "evaluation.test_cases[]", "", func(testCase map[string]interface{}, location string) {
+		v.validateTestCaseTask(testCase, location, taskIDs)
+	})
+}
+
+// compileTaskSchemas compiles the schemas of the task named name and
+// returns its input_schema, if it compiles
+func (v *APAIValidator) compileTaskSchemas(taskMap map[string]interface{}, name string) *jsonschema.Schema {
+	var input *jsonschema.Schema
+	for _, field := range taskSchemaFields {
+		fragment, exists := taskMap[field]
+		if !exists {
+			continue
+		}
+		schema, problems := compileTaskSchema(fragment, name, field)
+		v.appendFindings(problems)
+		if schema != nil && field == "input_schema" {
+			input = schema
+		}
+	}
+	return input
+}
+
+// validateTestCaseTask checks that the test case at location names one of
+// taskIDs and that its input matches the compiled input_schema of the task
+func (v *APAIValidator) validateTestCaseTask(testCase map[string]interface{}, location string, taskIDs map[string]bool) {
+	taskID, ok := testCase["task"].(string)
+	if !ok {
+		return
+	}
+	name := location
+	if id, ok := testCase["id"].(string); ok && id != "" {
+		name = id
+	}
+	if !taskIDs[taskID] {
+		v.addError("UNKNOWN_REFERENCE", fmt.Sprintf("Test case %s references unknown task: %s", name, taskID))
+		return
+	}
+	schema, exists := v.taskSchemas[taskID]
+	input, hasInput := testCase["input"]
+	if !exists || !hasInput {
+		return
+	}
+	for _, violation := range taskSchemaViolations(schema, input) {
+		v.addError("TASK_SCHEMA_MISMATCH", fmt.Sprintf("Test case %s input does not match the input_schema of task %s at %s", name, taskID, violation))
+	}
+}
+
+// ValidateAgainstTaskSchema checks an input against the input_schema of a
+// task as compiled by the last validation run, so that runtimes can check
+// the inputs they receive without compiling the schema again
+func (v *APAIValidator) ValidateAgainstTaskSchema(taskID string, input map[string]interface{}) []Issue {
+	schema, exists := v.taskSchemas[taskID]
+	if !exists {
+		return []Issue{{Severity: "error", Code: "INVALID_TASK_SCHEMA", Message: fmt.Sprintf("Task %s has no valid input_schema", taskID)}}
+	}
+	issues := make([]Issue, 0)
+	for _, violation := range taskSchemaViolations(schema, input) {
+		issues = append(issues, Issue{Severity: "error", Code: "TASK_SCHEMA_MISMATCH", Message: fmt.Sprintf("Input does not match the input_schema of task %s at %s", taskID, violation)})
+	}
+	return issues
+}
+
+// compileTaskSchema checks and compiles a schema fragment of a task. Types
+// JSON Schema does not define and required properties missing from
+// properties are reported first; the fragment is only compiled, against
+// the 2020-12 metaschema, once they are fixed.
+func compileTaskSchema(fragment interface{}, taskName, field string) (*jsonschema.Schema, sectionFindings) {
+	problems := sectionFindings{}
+	if _, ok := fragment.(map[string]interface{}); !ok {
+		if _, ok := fragment.(bool); !ok {
+			problems.addError("INVALID_TYPE", fmt.Sprintf("Task %s %s must be an object", taskName, field))
+			return nil, problems
+		}
+	}
+
+	walkSchema(fragment, field, func(schema map[string]interface{}, location string) {
+		for _, problem := range schemaProblems(schema, taskName, location) {
+			problems.addError("INVALID_TASK_SCHEMA", problem)
+		}
+	})
+	if len(problems.Errors) > 0 {
+		return nil, problems
+	}
+
+	var content bytes.Buffer
+	if err := writeCanonicalJSON(&content, fragment, false); err != nil {
+		problems.addError("INVALID_TASK_SCHEMA", fmt.Sprintf("Task %s %s is not a valid JSON Schema: %v", taskName, field, err))
+		return nil, problems
+	}
+	url := fmt.Sprintf("apai:///tasks/%s/%s.json", taskName, field)
+	compiler := jsonschema.NewCompiler()
+	compiler.Draft = jsonschema.Draft2020
+	if err := compiler.AddResource(url, &content); err != nil {
+		problems.addError("INVALID_TASK_SCHEMA", fmt.Sprintf("Task %s %s is not a valid JSON Schema: %v", taskName, field, err))
+		return nil, problems
+	}
+	schema, err := compiler.Compile(url)
+	if err == nil {
+		return schema, problems
+	}
+
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		problems.addError("INVALID_TASK_SCHEMA", fmt.Sprintf("Task %s %s is not a valid JSON Schema: %v", taskName, field, err))
+		return nil, problems
+	}
+	for _, cause := range schemaViolations(validationErr) {
+		location := field
+		if pointer := schemaLocation(cause.InstanceLocation); pointer != "root" {
+			location = joinLocation(field, pointer)
+		}
+		problems.addError("INVALID_TASK_SCHEMA", fmt.Sprintf("Task %s %s is not a valid JSON Schema: %s", taskName, location, cause.Message))
+	}
+	return nil, problems
+}
+
+// walkSchema calls visit with every schema object in a schema, the schema
+// itself included, together with its location
+func walkSchema(value interface{}, location string, visit func(map[string]interface{}, string)) {
+	schema, ok := value.(map[string]interface{})
+	if !ok {
+		return
+	}
+	visit(schema, location)
+	for _, keyword := range sortedKeys(schema) {
+		keywordLocation := joinLocation(location, keyword)
+		switch {
+		case containsString(schemaDataKeywords, keyword):
+		case containsString(schemaMapKeywords, keyword):
+			subschemas, _ := schema[keyword].(map[string]interface{})
+			for _, name := range sortedKeys(subschemas) {
+				walkSchema(subschemas[name], joinLocation(keywordLocation, name), visit)
+			}
+		case containsString(schemaListKeywords, keyword):
+			subschemas, _ := schema[keyword].([]interface{})
+			for i, subschema := range subschemas {
+				walkSchema(subschema, fmt.Sprintf("%s[%d]", keywordLocation, i), visit)
+			}
+		default:
+			walkSchema(schema[keyword], keywordLocation, visit)
+		}
+	}
+}
+
+// schemaProblems reports the types of a schema object JSON Schema does not
+// define and the required properties it does not declare in properties
+func schemaProblems(schema map[string]interface{}, taskName, location string) []string {
+	problems := make([]string, 0)
+	types := []interface{}{schema["type"]}
+	if list, ok := schema["type"].([]interface{}); ok {
+		types = list
+	}
+	if _, exists := schema["type"]; exists {
+		for _, value := range types {
+			if name, ok := value.(string); !ok || !containsString(jsonSchemaTypes, name) {
+				problems = append(problems, fmt.Sprintf("Task %s %s.type is not a JSON Schema type: %v", taskName, location, value))
+			}
+		}
+	}
+
+	properties, hasProperties := schema["properties"].(map[string]interface{})
+	required, _ := schema["required"].([]interface{})
+	for _, value := range required {
+		if name, ok := value.(string); ok && hasProperties {
+			if _, declared := properties[name]; !declared {
+				problems = append(problems, fmt.Sprintf("Task %s %s.required lists %s, which is not in properties", taskName, location, name))
+			}
+		}
+	}
+	return problems
+}
+
+// taskSchemaViolations validates a value against a compiled task schema
+// and describes each violation with its location in the value
+func taskSchemaViolations(schema *jsonschema.Schema, value interface{}) []string {
+	document, err := jsonDocument(value)
+	if err != nil {
+		return []string{fmt.Sprintf("root: cannot convert the value to JSON: %v", err)}
+	}
+	err = schema.Validate(document)
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		if err != nil {
+			return []string{fmt.Sprintf("root: %v", err)}
+		}
+		return nil
+	}
+	violations := make([]string, 0)
+	for _, cause := range schemaViolations(validationErr) {
+		violations = append(violations, fmt.Sprintf("%s: %s", schemaLocation(cause.InstanceLocation), cause.Message))
+	}
+	return violations
+}
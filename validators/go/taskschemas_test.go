@@ -0,0 +1,85 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateTaskSchemas(t *testing.T) {
+	orderSchema := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"order_id": map[string]interface{}{"type": "string"},
+			"quantity": map[string]interface{}{"type": "integer", "minimum": 1},
+		},
+		"required": []interface{}{"order_id"},
+	}
+	spec := map[string]interface{}{
+		"tasks": []interface{}{
+			map[string]interface{}{"id": "order", "description": "Order", "input_schema": orderSchema,
+				"output_schema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{
+					"status": map[string]interface{}{"type": "str"},
+				}}},
+			map[string]interface{}{"id": "refund", "description": "Refund", "input_schema": map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{"type": map[string]interface{}{"type": "string"}},
+				"required":   []interface{}{"type", "amount"},
+			}},
+			map[string]interface{}{"id": "search", "description": "Search", "input_schema": map[string]interface{}{"minLength": "ten"}},
+			map[string]interface{}{"id": "lookup", "description": "Lookup", "output_schema": "string"},
+		},
+		"evaluation": map[string]interface{}{"test_cases": []interface{}{
+			map[string]interface{}{"id": "valid_order", "task": "order", "input": map[string]interface{}{"order_id": "A1", "quantity": 2}},
+			map[string]interface{}{"id": "bad_order", "task": "order", "input": map[string]interface{}{"quantity": 0}},
+			map[string]interface{}{"id": "lost", "task": "shipping", "input": "Where is my parcel?"},
+		}},
+	}
+
+	validator := NewAPAIValidator()
+	validator.ValidateSpec(spec)
+	want := map[string]string{
+		"Task order output_schema.properties.status.type is not a JSON Schema type: str":                                  "INVALID_TASK_SCHEMA",
+		"Task refund input_schema.required lists amount, which is not in properties":                                      "INVALID_TASK_SCHEMA",
+		"Task lookup output_schema must be an object":                                                                     "INVALID_TYPE",
+		"Test case lost references unknown task: shipping":                                                                "UNKNOWN_REFERENCE",
+		"Test case bad_order input does not match the input_schema of task order at root: missing properties: 'order_id'": "TASK_SCHEMA_MISMATCH",
+		"Test case bad_order input does not match the input_schema of task order at quantity: must be >= 1 but found 0":   "TASK_SCHEMA_MISMATCH",
+	}
+	for message, code := range want {
+		if !containsString(validator.Errors, message) {
+			t.Errorf("missing %q in %v", message, validator.Errors)
+		}
+		if rule, _ := MatchRule(message); rule.Code != code {
+			t.Errorf("expected %s for %q, got %q", code, message, rule.Code)
+		}
+	}
+
+	// Metaschema violations name the schema path
+	found := false
+	for _, message := range validator.Errors {
+		if strings.HasPrefix(message, "Task search input_schema.minLength is not a valid JSON Schema: ") {
+			found = true
+			if rule, _ := MatchRule(message); rule.Code != "INVALID_TASK_SCHEMA" {
+				t.Errorf("expected INVALID_TASK_SCHEMA for %q, got %q", message, rule.Code)
+			}
+		}
+		if strings.Contains(message, "valid_order") || strings.Contains(message, "task refund") {
+			t.Errorf("unexpected %q", message)
+		}
+	}
+	if !found {
+		t.Errorf("missing metaschema violation of task search in %v", validator.Errors)
+	}
+
+	// Runtimes reuse the compiled input schemas
+	if issues := validator.ValidateAgainstTaskSchema("order", map[string]interface{}{"order_id": "A2"}); len(issues) != 0 {
+		t.Errorf("unexpected issues: %v", issues)
+	}
+	issues := validator.ValidateAgainstTaskSchema("order", map[string]interface{}{"order_id": 7})
+	if len(issues) != 1 || issues[0].Message != "Input does not match the input_schema of task order at order_id: expected string, but got number" || issues[0].Code != "TASK_SCHEMA_MISMATCH" {
+		t.Errorf("unexpected issues: %v", issues)
+	}
+	if issues := validator.ValidateAgainstTaskSchema("refund", nil); len(issues) != 1 || issues[0].Severity != "error" {
+		t.Errorf("expected an error for a task without a valid input_schema, got %v", issues)
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// uncodedFinding stands for the code of findings no rule matches
+const uncodedFinding = "UNCODED"
+
+// serverMetrics are the Prometheus metrics of a server. They are
+// registered on a registry of their own, so that a host application
+// embedding the server keeps its default registry to itself.
+type serverMetrics struct {
+	registry    *prometheus.Registry
+	validations *prometheus.CounterVec
+	duration    prometheus.Histogram
+	specSize    prometheus.Histogram
+	findings    *prometheus.CounterVec
+}
+
+// newServerMetrics creates the metrics of a server, reading the cache
+// counters of its validator when scraped
+func newServerMetrics(server *Server) *serverMetrics {
+	metrics := &serverMetrics{
+		registry: prometheus.NewRegistry(),
+		validations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "apai_validations_total",
+			Help: "Validations performed, by outcome: valid, invalid or error.",
+		}, []string{"outcome"}),
+		duration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "apai_validation_duration_seconds",
+			Help:    "Time taken to validate a specification.",
+			Buckets: prometheus.ExponentialBuckets(0.001, 4, 8),
+		}),
+		specSize: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "apai_spec_size_bytes",
+			Help:    "Size of the specifications validated.",
+			Buckets: prometheus.ExponentialBuckets(1024, 4, 8),
+		}),
+		findings: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "apai_findings_total",
+			Help: "Findings reported by validations, by rule code and severity.",
+		}, []string{"code", "severity"}),
+	}
+	metrics.registry.MustRegister(
+		metrics.validations,
+		metrics.duration,
+		metrics.specSize,
+		metrics.findings,
+		&cacheCollector{server: server},
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return metrics
+}
+
+// observeValidation records a validation of a specification of size bytes
+// that took elapsed and produced result, or failed with err
+func (m *serverMetrics) observeValidation(result ValidationResult, err error, size int, elapsed time.Duration) {
+	m.duration.Observe(elapsed.Seconds())
+	m.specSize.Observe(float64(size))
+	switch {
+	case err != nil:
+		m.validations.WithLabelValues("error").Inc()
+		return
+	case result.Valid:
+		m.validations.WithLabelValues("valid").Inc()
+	default:
+		m.validations.WithLabelValues("invalid").Inc()
+	}
+
+	for severity, messages := range map[string][]string{"error": result.Errors, "warning": result.Warnings} {
+		for _, message := range messages {
+			code := result.issue(severity, message).Code
+			if code == "" {
+				code = uncodedFinding
+			}
+			m.findings.WithLabelValues(code, severity).Inc()
+		}
+	}
+}
+
+// cacheDescriptions describe the cache counters, labeled by the kind of
+// entry looked up, parsed or merged
+var cacheDescriptions = struct {
+	hits, misses *prometheus.Desc
+}{
+	hits:   prometheus.NewDesc("apai_cache_hits_total", "Loads of inherited specifications served by the cache, by kind of entry.", []string{"kind"}, nil),
+	misses: prometheus.NewDesc("apai_cache_misses_total", "Loads of inherited specifications the cache could not serve, by kind of entry.", []string{"kind"}, nil),
+}
+
+// cacheCollector reports the lookups of the cache of a server's
+// validator, which has none until the server is ready or when caching is
+// off
+type cacheCollector struct {
+	server *Server
+}
+
+// Describe sends the descriptions of the cache counters
+func (c *cacheCollector) Describe(descriptions chan<- *prometheus.Desc) {
+	descriptions <- cacheDescriptions.hits
+	descriptions <- cacheDescriptions.misses
+}
+
+// Collect sends the cache counters of each kind of entry
+func (c *cacheCollector) Collect(metrics chan<- prometheus.Metric) {
+	validator := c.server.validator.Load()
+	if validator == nil || validator.cache == nil {
+		return
+	}
+	for _, kind := range specCacheKinds {
+		hits, misses := validator.cache.Lookups(kind)
+		metrics <- prometheus.MustNewConstMetric(cacheDescriptions.hits, prometheus.CounterValue, float64(hits), kind)
+		metrics <- prometheus.MustNewConstMetric(cacheDescriptions.misses, prometheus.CounterValue, float64(misses), kind)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// scrapeMetrics returns the value of each series served by /metrics
+func scrapeMetrics(t *testing.T, handler http.Handler) map[string]float64 {
+	t.Helper()
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if response.Code != http.StatusOK {
+		t.Fatalf("expected status 200 from /metrics, got %d", response.Code)
+	}
+	series := make(map[string]float64)
+	scanner := bufio.NewScanner(response.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		separator := strings.LastIndex(line, " ")
+		if strings.HasPrefix(line, "#") || separator < 0 {
+			continue
+		}
+		value, err := strconv.ParseFloat(line[separator+1:], 64)
+		if err != nil {
+			t.Fatalf("unparseable sample %q: %v", line, err)
+		}
+		series[line[:separator]] = value
+	}
+	return series
+}
+
+func TestServerMetrics(t *testing.T) {
+	content, err := os.ReadFile("../../examples/core/customer-support.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache, _ := NewSpecCache(t.TempDir())
+	handler := NewServer(NewAPAIValidator(WithSpecCache(cache))).Handler()
+
+	bodies := []string{string(content), string(content), "apai: \"0.1.0\"\n"}
+	outcomes := make(map[string]float64)
+	codes := make(map[string]float64)
+	size := 0
+	for _, body := range bodies {
+		request := httptest.NewRequest(http.MethodPost, "/validate", strings.NewReader(body))
+		request.Header.Set("Content-Type", "application/yaml")
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, request)
+		var result ValidationResult
+		if err := json.Unmarshal(response.Body.Bytes(), &result); err != nil {
+			t.Fatal(err)
+		}
+		if result.Valid {
+			outcomes["valid"]++
+		} else {
+			outcomes["invalid"]++
+		}
+		for _, message := range result.Errors {
+			codes[newIssue("error", message).Code]++
+		}
+		size += len(body)
+	}
+	if codes["MISSING_SECTION"] == 0 {
+		t.Fatalf("expected missing sections to be reported, got %v", codes)
+	}
+
+	// Resolving files fills the cache the server's validator shares. Only
+	// the team has parents of its own, so only its merge is cached: stored
+	// by the first resolution, reused by the second.
+	leaves := writeHierarchy(t, t.TempDir(), 1, 1, 1)
+	resolveAll(t, NewAPAIValidator(WithSpecCache(cache)), leaves)
+	resolveAll(t, NewAPAIValidator(WithSpecCache(cache)), leaves)
+
+	series := scrapeMetrics(t, handler)
+	for name, want := range map[string]float64{
+		`apai_validations_total{outcome="valid"}`:                      outcomes["valid"],
+		`apai_validations_total{outcome="invalid"}`:                    outcomes["invalid"],
+		`apai_validation_duration_seconds_count`:                       3,
+		`apai_spec_size_bytes_count`:                                   3,
+		`apai_spec_size_bytes_sum`:                                     float64(size),
+		`apai_findings_total{code="MISSING_SECTION",severity="error"}`: codes["MISSING_SECTION"],
+		`apai_cache_misses_total{kind="merged"}`:                       1,
+		`apai_cache_hits_total{kind="merged"}`:                         1,
+	} {
+		if series[name] != want {
+			t.Errorf("expected %s %v, got %v", name, want, series[name])
+		}
+	}
+
+	// The metrics stay out of the default registry of the host
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, family := range families {
+		if strings.HasPrefix(family.GetName(), "apai_") {
+			t.Errorf("unexpected %s in the default registry", family.GetName())
+		}
+	}
+}
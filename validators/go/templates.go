@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// validatePromptTemplate checks the template of a prompt, inline or read
+// from template_file, and that its {{variable}} placeholders are declared
+func (v *APAIValidator) validatePromptTemplate(f *sectionFindings, promptMap map[string]interface{}, promptIndex int) {
+	value, _ := currentField(promptMap, "prompts[].template")
+	template, _ := value.(string)
+
+	if templateFile, exists := promptMap["template_file"]; exists {
+		name := elementName(promptIndex, promptMap)
+		if _, hasTemplate := promptMap["template"]; hasTemplate {
+			f.addError("TEMPLATE_CONFLICT", fmt.Sprintf("Prompt %s has both template and template_file", name))
+			return
+		}
+
+		filePath, ok := templateFile.(string)
+		if !ok || filePath == "" {
+			f.addError("TEMPLATE_FILE_NOT_FOUND", fmt.Sprintf("Prompt %s template_file must be a file path", name))
+			return
+		}
+		content, err := v.readFile(filePath)
+		if err != nil {
+			f.addError("TEMPLATE_FILE_NOT_FOUND", fmt.Sprintf("Prompt %s template_file not found: %s", name, filePath))
+			return
+		}
+		if strings.TrimSpace(string(content)) == "" {
+			f.addError("EMPTY_TEMPLATE_FILE", fmt.Sprintf("Prompt %s template_file is empty: %s", name, filePath))
+			return
+		}
+		template = string(content)
+	}
+
+	for _, name := range exampleVariables(template) {
+		if !v.variables.resolves(promptMap, name) {
+			f.addError("UNDECLARED_VARIABLE", fmt.Sprintf("Prompt %d template references undeclared variable: %s", promptIndex, name))
+		}
+	}
+}
+
+// variableScope holds the variables prompts may use besides their own: the
+// global context.variables, and the inputs of the tasks running each prompt
+type variableScope struct {
+	global map[string]interface{}
+	// taskInputs maps prompt ids to the inputs of the tasks whose steps use them
+	taskInputs map[string]map[string]bool
+}
+
+// newVariableScope collects the variables shared by the prompts of a spec
+func newVariableScope(spec map[string]interface{}) *variableScope {
+	scope := &variableScope{taskInputs: make(map[string]map[string]bool)}
+	if context, ok := spec["context"].(map[string]interface{}); ok {
+		scope.global, _ = context["variables"].(map[string]interface{})
+	}
+	objectsAt(spec, "tasks[]", "", func(task map[string]interface{}, _ string) {
+		scope.addTask(task)
+	})
+	return scope
+}
+
+// addTask shares the inputs of a task with the prompts its steps use
+func (s *variableScope) addTask(task map[string]interface{}) {
+	inputs, _ := task["input"].(map[string]interface{})
+	if len(inputs) == 0 {
+		return
+	}
+	objectsAt(task, "steps[]", "", func(step map[string]interface{}, _ string) {
+		promptID, ok := step["prompt"].(string)
+		if !ok {
+			return
+		}
+		if s.taskInputs[promptID] == nil {
+			s.taskInputs[promptID] = make(map[string]bool)
+		}
+		for name := range inputs {
+			s.taskInputs[promptID][name] = true
+		}
+	})
+}
+
+// resolves reports whether a prompt can use a variable: it declares it,
+// or the variable is global or an input of a task running the prompt
+func (s *variableScope) resolves(promptMap map[string]interface{}, name string) bool {
+	if declared, _ := promptMap["variables"].(map[string]interface{}); declared != nil {
+		if _, ok := declared[name]; ok {
+			return true
+		}
+	}
+	if s == nil {
+		return false
+	}
+	if _, ok := s.global[name]; ok {
+		return true
+	}
+	id, _ := promptMap["id"].(string)
+	return s.taskInputs[id][name]
+}
+
+// promptTemplate returns the template of a prompt: the content of its
+// template_file when it can be read, its template otherwise
+func (v *APAIValidator) promptTemplate(promptMap map[string]interface{}) string {
+	value, _ := currentField(promptMap, "prompts[].template")
+	template, _ := value.(string)
+	if templateFile, ok := promptMap["template_file"].(string); ok && templateFile != "" {
+		if content, err := v.readFile(templateFile); err == nil {
+			template = string(content)
+		}
+	}
+	return template
+}
+
+// validateGlobalVariables warns about context.variables that no prompt
+// template or example uses
+func (v *APAIValidator) validateGlobalVariables(spec map[string]interface{}) {
+	if v.variables == nil || len(v.variables.global) == 0 {
+		return
+	}
+	used := make(map[string]bool)
+	objectsAt(spec, "prompts[]", "", func(promptMap map[string]interface{}, _ string) {
+		for _, name := range exampleVariables(v.promptTemplate(promptMap)) {
+			used[name] = true
+		}
+		examples, _ := promptMap["examples"].([]interface{})
+		for _, example := range examples {
+			exampleMap, _ := example.(map[string]interface{})
+			for _, name := range exampleVariables(exampleMap["input"]) {
+				used[name] = true
+			}
+		}
+	})
+	for _, name := range sortedKeys(v.variables.global) {
+		if !used[name] {
+			v.addWarning("UNUSED_VARIABLE", fmt.Sprintf("Context variable %s is not used by any prompt", name))
+		}
+	}
+}
+
+// inlineTemplateFiles replaces the template_file of each prompt with the
+// content of the file as template
+func (v *APAIValidator) inlineTemplateFiles(spec map[string]interface{}) error {
+	var inlineErr error
+	objectsAt(spec, "prompts[]", "", func(promptMap map[string]interface{}, location string) {
+		templateFile, ok := promptMap["template_file"].(string)
+		if !ok || templateFile == "" {
+			return
+		}
+		content, err := v.readFile(templateFile)
+		if err != nil {
+			if inlineErr == nil {
+				id, _ := promptMap["id"].(string)
+				inlineErr = fmt.Errorf("prompt %s template_file not found: %s", id, templateFile)
+			}
+			return
+		}
+		promptMap["template"] = string(content)
+		delete(promptMap, "template_file")
+	})
+	return inlineErr
+}
@@ -0,0 +1,51 @@
+// Command provider-allowlist is an example rule plugin. It reads a
+// specification as JSON from stdin and reports, as a JSON array of issues
+// on stdout, every model whose provider is not on the allowlist.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// approvedProviders are the model providers the organization allows
+var approvedProviders = []string{"openai", "anthropic"}
+
+type issue struct {
+	Code     string `json:"code"`
+	Severity string `json:"severity"`
+	Path     string `json:"path"`
+	Message  string `json:"message"`
+}
+
+func main() {
+	var spec struct {
+		Models []struct {
+			ID       string `json:"id"`
+			Provider string `json:"provider"`
+		} `json:"models"`
+	}
+	if err := json.NewDecoder(os.Stdin).Decode(&spec); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid specification: %v\n", err)
+		os.Exit(1)
+	}
+
+	issues := make([]issue, 0)
+	for i, model := range spec.Models {
+		approved := false
+		for _, provider := range approvedProviders {
+			approved = approved || strings.EqualFold(model.Provider, provider)
+		}
+		if !approved {
+			issues = append(issues, issue{
+				Code:     "ORG_PROVIDER_NOT_APPROVED",
+				Severity: "error",
+				Path:     fmt.Sprintf("models[%d].provider", i),
+				Message:  fmt.Sprintf("provider %s of model %s is not approved", model.Provider, model.ID),
+			})
+		}
+	}
+	json.NewEncoder(os.Stdout).Encode(issues)
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// translationFields are the prompt fields mapping language tags to
+// localized variants of the template
+var translationFields = []string{"translations", "variants"}
+
+// autoLanguage is the language of prompts answering in the language of the
+// user, whatever it is; it is consistent with every other language
+const autoLanguage = "auto"
+
+// parseLanguageTag parses a BCP-47 language tag such as en, de-CH,
+// zh-Hant-TW or es-419. It returns the problem with the tag when it is not
+// well-formed or holds a subtag no registry defines, and whether it is
+// well-formed.
+func parseLanguageTag(text string) (language.Tag, string, bool) {
+	if strings.Contains(text, "_") {
+		// BCP-47 separates subtags with hyphens, though Parse accepts en_US
+		return language.Und, fmt.Sprintf("is not a well-formed BCP-47 tag: %s", text), false
+	}
+	tag, err := language.Parse(text)
+	var unknown language.ValueError
+	switch {
+	case errors.As(err, &unknown):
+		return tag, fmt.Sprintf("has an unknown subtag %s: %s", unknown.Subtag(), text), true
+	case err != nil:
+		return tag, fmt.Sprintf("is not a well-formed BCP-47 tag: %s", text), false
+	}
+	return tag, "", true
+}
+
+// hasRegion reports whether a language tag names a region itself, as de-CH
+// or es-419 do, rather than one inferred from its language
+func hasRegion(tag language.Tag) bool {
+	_, _, region := tag.Raw()
+	return region != language.Region{}
+}
+
+// promptLanguage parses the language of a prompt as a BCP-47 tag. It
+// returns the problem with the language when it is not a known tag, and
+// false when the prompt declares no language or auto.
+func promptLanguage(promptMap map[string]interface{}) (language.Tag, string, bool) {
+	value, exists := promptMap["language"]
+	if !exists || value == autoLanguage {
+		return language.Und, "", false
+	}
+	text, ok := value.(string)
+	if !ok {
+		return language.Und, fmt.Sprintf("is not a well-formed BCP-47 tag: %v", value), true
+	}
+	tag, problem, _ := parseLanguageTag(text)
+	return tag, problem, true
+}
+
+// validatePromptLanguage warns when the language of a prompt is not a
+// BCP-47 tag, or holds subtags no registry defines
+func validatePromptLanguage(f *sectionFindings, promptMap map[string]interface{}, promptIndex int) {
+	if _, problem, _ := promptLanguage(promptMap); problem != "" {
+		f.addWarning("INVALID_PROMPT_LANGUAGE", fmt.Sprintf("Prompt %s language %s", elementName(promptIndex, promptMap), problem))
+	}
+}
+
+// validateChainLanguages warns about prompts composed through chain and
+// next that declare different languages, which switch the language of a
+// conversation midway. Languages are compared by their base language, so
+// en and en-GB agree, and prompts without a valid language are left out.
+func validateChainLanguages(f *sectionFindings, prompts []interface{}) {
+	// Prompts linked by chain or next, in either direction, form a group
+	group := make(map[string]string)
+	var root func(id string) string
+	root = func(id string) string {
+		parent, exists := group[id]
+		if !exists || parent == id {
+			return id
+		}
+		group[id] = root(parent)
+		return group[id]
+	}
+	ids := make([]string, 0)
+	languages := make(map[string]string)
+	for _, prompt := range prompts {
+		promptMap, ok := prompt.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		id, ok := promptMap["id"].(string)
+		if !ok {
+			continue
+		}
+		referenced, _ := promptChain(promptMap)
+		for _, other := range referenced {
+			if root(other) != root(id) {
+				group[root(other)] = root(id)
+			}
+		}
+		tag, problem, declared := promptLanguage(promptMap)
+		if !declared || problem != "" {
+			continue
+		}
+		if base, confidence := tag.Base(); confidence == language.Exact {
+			if _, seen := languages[id]; !seen {
+				ids = append(ids, id)
+			}
+			languages[id] = base.String()
+		}
+	}
+
+	members := make(map[string][]string)
+	roots := make([]string, 0)
+	for _, id := range ids {
+		groupRoot := root(id)
+		if _, seen := members[groupRoot]; !seen {
+			roots = append(roots, groupRoot)
+		}
+		members[groupRoot] = append(members[groupRoot], id)
+	}
+	for _, groupRoot := range roots {
+		distinct := make(map[string]bool)
+		declared := make([]string, 0, len(members[groupRoot]))
+		for _, id := range members[groupRoot] {
+			distinct[languages[id]] = true
+			declared = append(declared, fmt.Sprintf("%s (%s)", id, languages[id]))
+		}
+		if len(distinct) > 1 {
+			f.addWarning("INCONSISTENT_CHAIN_LANGUAGES", fmt.Sprintf("Chained prompts declare different languages: %s", strings.Join(declared, ", ")))
+		}
+	}
+}
+
+// promptTranslations returns the language tags a prompt is translated
+// into, from all its translation fields, in sorted order; keys that are
+// not well-formed language tags are left out
+func promptTranslations(promptMap map[string]interface{}) []string {
+	languages := make([]string, 0)
+	for _, field := range translationFields {
+		variants, _ := promptMap[field].(map[string]interface{})
+		for language := range variants {
+			if _, _, wellFormed := parseLanguageTag(language); wellFormed && !containsString(languages, language) {
+				languages = append(languages, language)
+			}
+		}
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+// validatePromptTranslations checks the localized variants of a prompt:
+// their keys must be BCP-47 language tags, parsed like the language of a
+// prompt, and each variant must use the
+// same {{variable}} placeholders as the default template
+func (v *APAIValidator) validatePromptTranslations(f *sectionFindings, promptMap map[string]interface{}, promptIndex int) {
+	name := elementName(promptIndex, promptMap)
+	defaultVariables := exampleVariables(v.promptTemplate(promptMap))
+
+	for _, field := range translationFields {
+		value, exists := promptMap[field]
+		if !exists {
+			continue
+		}
+		variants, ok := value.(map[string]interface{})
+		if !ok {
+			f.addError("INVALID_TYPE", fmt.Sprintf("Prompt %s %s must be an object keyed by language tag", name, field))
+			continue
+		}
+
+		regioned, regionless := false, false
+		for _, language := range sortedKeys(variants) {
+			tag, problem, wellFormed := parseLanguageTag(language)
+			if !wellFormed {
+				f.addError("INVALID_LANGUAGE_TAG", fmt.Sprintf("Prompt %s %s key is not a BCP-47 language tag: %s", name, field, language))
+				continue
+			}
+			if problem != "" {
+				f.addWarning("UNKNOWN_LANGUAGE_TAG", fmt.Sprintf("Prompt %s %s key %s", name, field, problem))
+			}
+			if hasRegion(tag) {
+				regioned = true
+			} else {
+				regionless = true
+			}
+
+			template, ok := variants[language].(string)
+			if variantMap, isMap := variants[language].(map[string]interface{}); isMap {
+				template, ok = variantMap["template"].(string)
+			}
+			if !ok {
+				f.addError("INVALID_TYPE", fmt.Sprintf("Prompt %s %s.%s must be a string or an object with a template", name, field, language))
+				continue
+			}
+
+			variables := exampleVariables(template)
+			if missing := missingStrings(defaultVariables, variables); len(missing) > 0 {
+				f.addError("TRANSLATION_VARIABLE_MISMATCH", fmt.Sprintf("Prompt %s translation %s is missing variables of the default template: %s", name, language, strings.Join(missing, ", ")))
+			}
+			if extra := missingStrings(variables, defaultVariables); len(extra) > 0 {
+				f.addError("TRANSLATION_VARIABLE_MISMATCH", fmt.Sprintf("Prompt %s translation %s uses variables the default template does not: %s", name, language, strings.Join(extra, ", ")))
+			}
+		}
+		if regioned && regionless {
+			f.addWarning("MIXED_LANGUAGE_TAGS", fmt.Sprintf("Prompt %s %s mix language tags with and without a region: %s", name, field, strings.Join(sortedKeys(variants), ", ")))
+		}
+	}
+}
+
+// validateTranslationCoverage warns about translated prompts lacking a
+// language other prompts are translated into. The language of a prompt's
+// default template counts as covered.
+func validateTranslationCoverage(f *sectionFindings, prompts []interface{}) {
+	languages := make([]string, 0)
+	covered := make(map[string][]string)
+	names := make([]string, 0)
+	for index, prompt := range prompts {
+		promptMap, ok := prompt.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		translations := promptTranslations(promptMap)
+		if len(translations) == 0 {
+			continue
+		}
+		name := elementName(index, promptMap)
+		names = append(names, name)
+		for _, language := range translations {
+			if !containsFold(languages, language) {
+				languages = append(languages, language)
+			}
+		}
+		if language, ok := promptMap["language"].(string); ok && language != "" {
+			translations = append(translations, language)
+		}
+		covered[name] = translations
+	}
+	sort.Strings(languages)
+
+	for _, name := range names {
+		lacking := make([]string, 0)
+		for _, language := range languages {
+			if !containsFold(covered[name], language) {
+				lacking = append(lacking, language)
+			}
+		}
+		if len(lacking) > 0 {
+			f.addWarning("MISSING_TRANSLATION", fmt.Sprintf("Prompt %s lacks translations other prompts have: %s", name, strings.Join(lacking, ", ")))
+		}
+	}
+}
+
+// missingStrings returns the values of want that are not in have
+func missingStrings(want, have []string) []string {
+	missing := make([]string, 0)
+	for _, value := range want {
+		if !containsString(have, value) {
+			missing = append(missing, value)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPromptTranslations(t *testing.T) {
+	prompts := []interface{}{
+		map[string]interface{}{
+			"id": "greeting", "role": "system", "language": "en",
+			"template":  "Hello {{user_name}}, welcome to {{company_name}}",
+			"variables": map[string]interface{}{"user_name": map[string]interface{}{"type": "string"}, "company_name": map[string]interface{}{"type": "string"}},
+			"translations": map[string]interface{}{
+				"it":      "Ciao {{user_name}}, benvenuto in {{company_name}}",
+				"de-CH":   map[string]interface{}{"template": "Grüezi {{user_name}}"},
+				"fr":      "Bonjour {{user_name}}, bienvenue chez {{company_name}} ({{order_id}})",
+				"english": "Hello {{user_name}}",
+				"en_US":   "Hello {{user_name}}, welcome to {{company_name}}",
+				"xx-YY":   "Hello {{user_name}}, welcome to {{company_name}}",
+				"es":      42,
+			},
+		},
+		map[string]interface{}{
+			"id": "closing", "role": "system", "template": "Goodbye",
+			"variants": map[string]interface{}{"it": "Arrivederci"},
+		},
+		map[string]interface{}{"id": "plain", "role": "system", "template": "Untranslated"},
+	}
+
+	var findings sectionFindings
+	NewAPAIValidator().validatePrompts(&findings, prompts)
+	wantErrors := []string{
+		"Prompt greeting translation de-CH is missing variables of the default template: company_name",
+		"Prompt greeting translations key is not a BCP-47 language tag: en_US",
+		"Prompt greeting translations key is not a BCP-47 language tag: english",
+		"Prompt greeting translations.es must be a string or an object with a template",
+		"Prompt greeting translation fr uses variables the default template does not: order_id",
+	}
+	wantWarnings := []string{
+		"Prompt greeting translations key has an unknown subtag xx: xx-YY",
+		"Prompt greeting translations mix language tags with and without a region: de-CH, en_US, english, es, fr, it, xx-YY",
+		"Prompt closing lacks translations other prompts have: de-CH, es, fr, xx-YY",
+	}
+	if !reflect.DeepEqual(findings.Errors, wantErrors) {
+		t.Errorf("errors = %v, want %v", findings.Errors, wantErrors)
+	}
+	if !reflect.DeepEqual(findings.Warnings, wantWarnings) {
+		t.Errorf("warnings = %v, want %v", findings.Warnings, wantWarnings)
+	}
+
+	codes := map[string]string{
+		wantErrors[0]:   "TRANSLATION_VARIABLE_MISMATCH",
+		wantErrors[1]:   "INVALID_LANGUAGE_TAG",
+		wantErrors[3]:   "INVALID_TYPE",
+		wantErrors[4]:   "TRANSLATION_VARIABLE_MISMATCH",
+		wantWarnings[0]: "UNKNOWN_LANGUAGE_TAG",
+		wantWarnings[1]: "MIXED_LANGUAGE_TAGS",
+		wantWarnings[2]: "MISSING_TRANSLATION",
+	}
+	for message, want := range codes {
+		if rule, ok := MatchRule(message); !ok || rule.Code != want {
+			t.Errorf("expected %s for %q, got %q", want, message, rule.Code)
+		}
+	}
+
+	// Keys and prompt languages are parsed alike
+	for _, tag := range []string{"en", "de-CH", "zh-Hant-TW", "es-419", "sl-rozaj"} {
+		if _, problem, wellFormed := parseLanguageTag(tag); !wellFormed || problem != "" {
+			t.Errorf("expected %s to be a language tag, got %q", tag, problem)
+		}
+	}
+	for _, tag := range []string{"english", "en_US", "e", "en-"} {
+		if _, _, wellFormed := parseLanguageTag(tag); wellFormed {
+			t.Errorf("expected %s not to be a language tag", tag)
+		}
+	}
+}
+
+func TestPromptLanguages(t *testing.T) {
+	prompt := func(id, language string, links ...string) map[string]interface{} {
+		promptMap := map[string]interface{}{"id": id, "role": "system", "template": "Hello", "language": language}
+		if len(links) > 0 {
+			promptMap["next"] = links[0]
+		}
+		return promptMap
+	}
+	prompts := []interface{}{
+		prompt("draft", "en", "review"),
+		prompt("review", "it"),
+		prompt("summary", "en-GB", "closing"),
+		prompt("closing", "EN"),
+		prompt("router", "auto", "answer"),
+		prompt("answer", "de-CH"),
+		prompt("legacy", "en_US"),
+		prompt("typo", "english", "unknown"),
+		prompt("unknown", "xx"),
+		prompt("region", "xx-YY"),
+		map[string]interface{}{"id": "untagged", "role": "system", "template": "Hello", "chain": []interface{}{"draft"}},
+	}
+
+	var findings sectionFindings
+	NewAPAIValidator().validatePrompts(&findings, prompts)
+	wantWarnings := []string{
+		"Prompt legacy language is not a well-formed BCP-47 tag: en_US",
+		"Prompt typo language is not a well-formed BCP-47 tag: english",
+		"Prompt unknown language has an unknown subtag xx: xx",
+		"Prompt region language has an unknown subtag xx: xx-YY",
+		"Chained prompts declare different languages: draft (en), review (it)",
+	}
+	if len(findings.Errors) != 0 {
+		t.Errorf("unexpected errors: %v", findings.Errors)
+	}
+	if !reflect.DeepEqual(findings.Warnings, wantWarnings) {
+		t.Errorf("warnings = %v, want %v", findings.Warnings, wantWarnings)
+	}
+
+	codes := map[string]string{
+		wantWarnings[0]: "INVALID_PROMPT_LANGUAGE",
+		wantWarnings[2]: "INVALID_PROMPT_LANGUAGE",
+		wantWarnings[3]: "INVALID_PROMPT_LANGUAGE",
+		wantWarnings[4]: "INCONSISTENT_CHAIN_LANGUAGES",
+	}
+	for message, want := range codes {
+		if rule, ok := MatchRule(message); !ok || rule.Code != want {
+			t.Errorf("expected %s for %q, got %q", want, message, rule.Code)
+		}
+	}
+}
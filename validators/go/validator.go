@@ -1,14 +1,27 @@
 package main
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"hash"
+	"io"
+	"io/fs"
 	"io/ioutil"
+	"net/mail"
+	"net/url"
+	"os"
+	"path"
 	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
-	"gopkg.in/yaml.v3"
+	"github.com/santhosh-tekuri/jsonschema/v5"
 )
 
 // APAIValidator represents the main validator struct
@@ -16,10 +29,96 @@ type APAIValidator struct {
 	Errors      []string
 	Warnings    []string
 	SchemaVersion string
+	Config        Config
 	
 	// Hierarchical composition properties
 	inheritedSpecs map[string]map[string]interface{}
 	mergeCache     map[string]map[string]interface{}
+	inheritance    *inheritanceState
+
+	// fsys, when set, is the filesystem specifications are read from
+	// instead of the OS filesystem
+	fsys fs.FS
+
+	// inputFormat, when set, is the format validated files are parsed as
+	// instead of the one of their extension
+	inputFormat string
+
+	// parallel runs the section validators concurrently
+	parallel bool
+
+	// issueHandler receives each finding as it is produced; reported counts
+	// the findings of the current run already delivered
+	issueHandler func(Issue)
+	reported     issueCount
+
+	// codes maps the findings of the current run to the code of the rule
+	// that reported them
+	codes map[string]string
+
+	// compliance lists the profiles whose requirements are enforced
+	compliance []*ComplianceProfile
+
+	// plugins lists the executables run on every specification, each
+	// limited to pluginTimeout
+	plugins       []string
+	pluginTimeout time.Duration
+
+	// envLookup, when set, resolves ${VAR} placeholders before validation;
+	// envSubstituted records the locations of the current run it filled in
+	envLookup       func(string) (string, bool)
+	allowMissingEnv bool
+	envSubstituted  map[string]bool
+
+	// schemas lists external JSON Schemas the document must also satisfy
+	schemas []*JSONSchema
+
+	// approvedModels, when set, lists the only models specifications may use
+	approvedModels *ApprovedModels
+
+	// validatingMerged is set while validating a spec merged with its
+	// parents, whose hierarchy levels were compared while resolving them
+	validatingMerged bool
+
+	// variables holds the variables shared by the prompts of the current run
+	variables *variableScope
+
+	// taskSchemas holds the compiled input_schema of each task of the last run
+	taskSchemas map[string]*jsonschema.Schema
+
+	// workspace, when set, resolves references to other specifications;
+	// workspaceMember is the id of the member being validated, if any
+	workspace       *Workspace
+	workspaceMember string
+
+	// cache, when set, keeps parsed and merged specifications on disk;
+	// fileDigests holds the digest of each loaded file and the files it
+	// includes, which readDigest accumulates while it is loaded
+	cache       *SpecCache
+	fileDigests map[string]string
+	readDigest  hash.Hash
+}
+
+// inheritanceState tracks a single inheritance resolution run
+type inheritanceState struct {
+	// explored maps each loaded parent to the longest chain it was explored with
+	explored map[string]int
+	failed   bool
+
+	// depth is the longest chain of parents loaded
+	depth int
+
+	// ctx stops the run between inherited files; err records why it stopped
+	ctx context.Context
+	err error
+}
+
+// newInheritanceState creates an empty inheritance resolution state
+func newInheritanceState() *inheritanceState {
+	return &inheritanceState{
+		explored: make(map[string]int),
+		ctx:      context.Background(),
+	}
 }
 
 // ValidationResult represents the result of validation
@@ -27,395 +126,1035 @@ type ValidationResult struct {
 	Valid    bool     `json:"valid"`
 	Errors   []string `json:"errors"`
 	Warnings []string `json:"warnings"`
+
+	// codes maps the findings to the code of the rule that reported them
+	codes map[string]string
+}
+
+// issue creates an issue for a finding of the result
+func (r ValidationResult) issue(severity, message string) Issue {
+	return codedIssue(r.codes, severity, message)
+}
+
+// ErrorsOnly returns the result with its warnings left out, for consumers
+// that treat warnings as non-actionable
+func (r ValidationResult) ErrorsOnly() ValidationResult {
+	r.Warnings = make([]string, 0)
+	return r
 }
 
 // NewAPAIValidator creates a new validator instance
-func NewAPAIValidator() *APAIValidator {
-	return &APAIValidator{
+func NewAPAIValidator(opts ...Option) *APAIValidator {
+	v := &APAIValidator{
 		Errors:        make([]string, 0),
 		Warnings:      make([]string, 0),
 		SchemaVersion: "0.1.0",
+		Config:        DefaultConfig(),
 		inheritedSpecs: make(map[string]map[string]interface{}),
 		mergeCache:     make(map[string]map[string]interface{}),
+		inheritance:    newInheritanceState(),
+		parallel:       true,
+	}
+
+	for _, opt := range opts {
+		opt(v)
 	}
+	// Without a usable cache directory, specifications are parsed as usual
+	if v.cache == nil && (v.Config.Cache || v.Config.CacheDir != "") {
+		v.cache, _ = NewSpecCache(v.Config.CacheDir)
+	}
+	return v
 }
 
 // ValidateFile validates an APAI specification file
 func (v *APAIValidator) ValidateFile(filePath string) (bool, error) {
-	content, err := ioutil.ReadFile(filePath)
+	return v.ValidateFileContext(context.Background(), filePath)
+}
+
+// ValidateFileContext validates an APAI specification file, stopping with
+// the context's error, wrapped, once ctx is done. Only the first document
+// of a multi-document YAML file is validated; ValidateDocumentsContext
+// validates each.
+func (v *APAIValidator) ValidateFileContext(ctx context.Context, filePath string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, fmt.Errorf("validating %s: %w", filePath, err)
+	}
+	if v.workspace != nil {
+		v.workspaceMember = v.workspace.memberID(filePath)
+		defer func() { v.workspaceMember = "" }()
+	}
+
+	content, err := v.readFile(filePath)
 	if err != nil {
 		return false, fmt.Errorf("file not found: %s", filePath)
 	}
 
-	var spec map[string]interface{}
-	ext := strings.ToLower(filepath.Ext(filePath))
+	format := v.inputFormat
+	if format == "" {
+		ext := strings.ToLower(filepath.Ext(filePath))
+		if format = formatOfExtension(ext); format == "" {
+			return false, fmt.Errorf("unsupported file format: %s", ext)
+		}
+	}
+	spec, err := decodeSpec(content, format)
+	if err != nil {
+		if v.inputFormat != "" {
+			return false, fmt.Errorf("%s: %v (input format forced to %s)", filePath, err, format)
+		}
+		return false, err
+	}
+
+	spec, err = v.resolveIncludes(spec, filePath)
+	if err != nil {
+		return false, err
+	}
+	v.rebaseFileReferences(spec, filePath)
+
+	valid, err := v.ValidateSpecContext(ctx, spec)
+	if err != nil {
+		return false, fmt.Errorf("validating %s: %w", filePath, err)
+	}
+	return valid, nil
+}
+
+// ValidateBytes validates a specification given as content in format,
+// yaml or json. Includes and file references resolve against the current
+// directory.
+func (v *APAIValidator) ValidateBytes(ctx context.Context, content []byte, format string) (bool, error) {
+	spec, err := decodeSpec(content, format)
+	if err != nil {
+		return false, err
+	}
+	spec, err = v.resolveIncludes(spec, "")
+	if err != nil {
+		return false, err
+	}
+	return v.ValidateSpecContext(ctx, spec)
+}
 
-	switch ext {
+// ValidateReader validates a specification read from r in format, yaml or
+// json, such as one piped to standard input
+func (v *APAIValidator) ValidateReader(ctx context.Context, r io.Reader, format string) (bool, error) {
+	content, err := ioutil.ReadAll(r)
+	if err != nil {
+		return false, fmt.Errorf("cannot read specification: %v", err)
+	}
+	return v.ValidateBytes(ctx, content, format)
+}
+
+// formatOfExtension returns the format of a specification file extension,
+// or "" when it is not one
+func formatOfExtension(ext string) string {
+	switch strings.ToLower(ext) {
 	case ".yaml", ".yml":
-		err = yaml.Unmarshal(content, &spec)
-		if err != nil {
-			return false, fmt.Errorf("YAML parsing error: %v", err)
-		}
+		return "yaml"
 	case ".json":
-		err = json.Unmarshal(content, &spec)
-		if err != nil {
-			return false, fmt.Errorf("JSON parsing error: %v", err)
+		return "json"
+	}
+	return ""
+}
+
+// decodeSpec parses a specification in format, yaml or json
+func decodeSpec(content []byte, format string) (map[string]interface{}, error) {
+	var spec map[string]interface{}
+	switch format {
+	case "yaml":
+		if err := decodeYAML(content, &spec); err != nil {
+			return nil, fmt.Errorf("YAML parsing error: %v", err)
+		}
+	case "json":
+		if err := json.Unmarshal(content, &spec); err != nil {
+			return nil, fmt.Errorf("JSON parsing error: %v", err)
 		}
 	default:
-		return false, fmt.Errorf("unsupported file format: %s", ext)
+		return nil, fmt.Errorf("unsupported input format: %s (expected yaml or json)", format)
 	}
-
-	return v.ValidateSpec(spec), nil
+	return spec, nil
 }
 
 // ValidateSpec validates an APAI specification map
 func (v *APAIValidator) ValidateSpec(spec map[string]interface{}) bool {
+	valid, _ := v.ValidateSpecContext(context.Background(), spec)
+	return valid
+}
+
+// ValidateSpecContext validates an APAI specification map, checking ctx
+// between sections and returning its error, wrapped, once it is done
+func (v *APAIValidator) ValidateSpecContext(ctx context.Context, spec map[string]interface{}) (bool, error) {
 	v.Errors = make([]string, 0)
 	v.Warnings = make([]string, 0)
 
-	// Validate required sections
-	v.validateRequiredSections(spec)
+	v.reported = issueCount{}
+	v.codes = nil
 
-	// Validate each section
-	if apai, exists := spec["apai"]; exists {
-		v.validateAPAIVersion(apai)
+	// Relaxed and promoted codes no rule reports; findings are added with
+	// the severity their code resolves to from here on
+	v.validateReclassifiedCodes()
+	v.reportIssues()
+
+	// External schemas apply to the document as written
+	document := spec
+
+	// Environment placeholders, replaced as they are when deployed
+	if v.envLookup != nil {
+		spec = v.substituteEnv(spec)
+		v.reportIssues()
 	}
 
-	if info, exists := spec["info"]; exists {
-		v.validateInfo(info)
+	// Resolve internal references
+	spec, refErrs := resolveSpecRefs(spec)
+	for _, refErr := range refErrs {
+		code := "UNRESOLVED_REF"
+		if len(refErr.Cycle) > 0 {
+			code = "CIRCULAR_REF"
+		}
+		v.addError(code, refErr.Error())
 	}
+	v.reportIssues()
+	v.variables = newVariableScope(spec)
+
+	// Validate required sections
+	v.validateRequiredSections(spec)
+	v.reportIssues()
 
-	if models, exists := spec["models"]; exists {
-		v.validateModels(models)
+	// Deprecated fields
+	v.validateDeprecations(spec)
+	v.reportIssues()
+
+	// Extension and unknown fields
+	v.validateExtensions(spec)
+	v.reportIssues()
+
+	if len(v.schemas) > 0 {
+		v.validateJSONSchemas(document)
+		v.reportIssues()
 	}
 
-	if prompts, exists := spec["prompts"]; exists {
-		v.validatePrompts(prompts)
+	// Validate each section
+	err := v.validateSections(ctx, spec, func(findings sectionFindings) {
+		v.appendFindings(findings)
+		v.reportIssues()
+	})
+	if err != nil {
+		return false, err
 	}
 
-	if constraints, exists := spec["constraints"]; exists {
-		v.validateConstraints(constraints)
+	// Type strictness
+	v.validateNumericFields(spec, "")
+	v.validateBooleanFields(spec, "")
+	v.reportIssues()
+
+	// Cross-validation
+	if err := ctx.Err(); err != nil {
+		return false, fmt.Errorf("cross-validation: %w", err)
 	}
+	v.crossValidate(spec)
+	v.reportIssues()
+
+	// Compliance profiles
+	v.validateCompliance(spec)
+	v.reportIssues()
+
+	// Organization allowlist of models
+	v.validateApprovedModels(spec)
+	v.reportIssues()
 
-	if tasks, exists := spec["tasks"]; exists {
-		v.validateTasks(tasks)
+	// Complexity budget
+	v.validateComplexityBudget(spec)
+	v.reportIssues()
+
+	// External rules
+	if len(v.plugins) > 0 {
+		if err := ctx.Err(); err != nil {
+			return false, fmt.Errorf("plugins: %w", err)
+		}
+		v.runPlugins(ctx, spec)
+		if err := ctx.Err(); err != nil {
+			return false, fmt.Errorf("plugins: %w", err)
+		}
+		v.reportIssues()
 	}
 
-	if context, exists := spec["context"]; exists {
-		v.validateContext(context)
+	// Referenced files, only on request so validation stays hermetic
+	if v.Config.CheckFiles {
+		if err := ctx.Err(); err != nil {
+			return false, fmt.Errorf("file checks: %w", err)
+		}
+		v.validateFileReferences(ctx, spec)
+		if err := ctx.Err(); err != nil {
+			return false, fmt.Errorf("file checks: %w", err)
+		}
+		v.reportIssues()
 	}
 
-	if evaluation, exists := spec["evaluation"]; exists {
-		v.validateEvaluation(evaluation)
+	return len(v.Errors) == 0, nil
+}
+
+// Validate validates a specification map with a copy of the validator and
+// returns the result. Unlike ValidateSpec it leaves the validator unchanged,
+// so one validator can serve concurrent callers; the issue handler is not
+// called.
+func (v *APAIValidator) Validate(ctx context.Context, spec map[string]interface{}) (ValidationResult, error) {
+	run := v.newRun()
+	if _, err := run.ValidateSpecContext(ctx, spec); err != nil {
+		return ValidationResult{}, err
 	}
+	return run.GetResults(), nil
+}
 
-	// Cross-validation
-	v.crossValidate(spec)
+// newRun returns a copy of the validator with the state of a run reset,
+// which concurrent callers can use without affecting each other
+func (v *APAIValidator) newRun() *APAIValidator {
+	run := *v
+	run.Errors = make([]string, 0)
+	run.Warnings = make([]string, 0)
+	run.inheritedSpecs = make(map[string]map[string]interface{})
+	run.mergeCache = make(map[string]map[string]interface{})
+	run.inheritance = newInheritanceState()
+	run.issueHandler = nil
+	run.reported = issueCount{}
+	run.codes = nil
+	run.envSubstituted = nil
+	run.workspaceMember = ""
+	run.validatingMerged = false
+	run.fileDigests = nil
+	run.readDigest = nil
+	return &run
+}
 
-	return len(v.Errors) == 0
+// requiredSections lists the top-level sections every specification must have
+var requiredSections = []string{
+	"apai", "info", "models", "prompts",
+	"constraints", "tasks", "context", "evaluation",
 }
 
 // validateRequiredSections validates that all required sections are present
 func (v *APAIValidator) validateRequiredSections(spec map[string]interface{}) {
-	requiredSections := []string{
-		"apai", "info", "models", "prompts",
-		"constraints", "tasks", "context", "evaluation",
+	for _, section := range missingSections(spec) {
+		v.addError("MISSING_SECTION", fmt.Sprintf("Missing required section: %s", section))
 	}
+}
 
+// missingSections returns the required sections absent from a specification
+func missingSections(spec map[string]interface{}) []string {
+	missing := make([]string, 0)
 	for _, section := range requiredSections {
 		if _, exists := spec[section]; !exists {
-			v.Errors = append(v.Errors, fmt.Sprintf("Missing required section: %s", section))
+			missing = append(missing, section)
 		}
 	}
+	return missing
+}
+
+// sectionFindings holds the errors and warnings found in one section
+type sectionFindings struct {
+	Errors   []string
+	Warnings []string
+	// Codes maps each finding to the code of the rule that reported it
+	Codes map[string]string
+}
+
+// addError adds an error reported by the rule with the given code
+func (f *sectionFindings) addError(code, message string) {
+	f.Errors = append(f.Errors, message)
+	f.Codes = withCode(f.Codes, code, message)
+}
+
+// addWarning adds a warning reported by the rule with the given code
+func (f *sectionFindings) addWarning(code, message string) {
+	f.Warnings = append(f.Warnings, message)
+	f.Codes = withCode(f.Codes, code, message)
+}
+
+// addError adds an error reported by the rule with the given code
+func (v *APAIValidator) addError(code, message string) {
+	v.addFinding("error", code, message)
+}
+
+// addWarning adds a warning reported by the rule with the given code
+func (v *APAIValidator) addWarning(code, message string) {
+	v.addFinding("warning", code, message)
+}
+
+// addFinding adds a finding with the severity its code resolves to, so
+// that it is final before the finding is reported or decides validity
+func (v *APAIValidator) addFinding(severity, code, message string) {
+	v.codes = withCode(v.codes, code, message)
+	if v.resolveSeverity(severity, code) == "error" {
+		v.Errors = append(v.Errors, message)
+	} else {
+		v.Warnings = append(v.Warnings, message)
+	}
+}
+
+// hasFinding reports whether a message was already added, whatever its
+// severity resolved to
+func (v *APAIValidator) hasFinding(message string) bool {
+	return containsString(v.Errors, message) || containsString(v.Warnings, message)
+}
+
+// withCode records the code of a finding, allocating codes on first use;
+// findings without a code are left to the rule patterns
+func withCode(codes map[string]string, code, message string) map[string]string {
+	if code == "" {
+		return codes
+	}
+	if codes == nil {
+		codes = make(map[string]string)
+	}
+	codes[message] = code
+	return codes
+}
+
+// mergeCodes records the codes of findings added from elsewhere
+func (v *APAIValidator) mergeCodes(codes map[string]string) {
+	for message, code := range codes {
+		v.codes = withCode(v.codes, code, message)
+	}
+}
+
+// sectionValidator validates the value of one top-level section
+type sectionValidator func(v *APAIValidator, f *sectionFindings, value interface{})
+
+// sectionValidators lists the independent section validators in report order
+var sectionValidators = []struct {
+	section  string
+	validate sectionValidator
+}{
+	{"apai", (*APAIValidator).validateAPAIVersion},
+	{"info", (*APAIValidator).validateInfo},
+	{"models", (*APAIValidator).validateModels},
+	{"prompts", (*APAIValidator).validatePrompts},
+	{"constraints", (*APAIValidator).validateConstraints},
+	{"tasks", (*APAIValidator).validateTasks},
+	{"context", (*APAIValidator).validateContext},
+	{"evaluation", (*APAIValidator).validateEvaluation},
+}
+
+// validateSections runs the section validators and passes their findings
+// to collect in sectionValidators order, each as soon as it and every
+// earlier section are done. Validators only read the validator and write
+// to their own findings, so they run concurrently unless disabled; collect
+// is always called from the calling goroutine.
+func (v *APAIValidator) validateSections(ctx context.Context, spec map[string]interface{}, collect func(sectionFindings)) error {
+	results := make([]sectionFindings, len(sectionValidators))
+	done := make([]chan struct{}, len(sectionValidators))
+	for i, check := range sectionValidators {
+		done[i] = make(chan struct{})
+		value, exists := spec[check.section]
+		if !exists {
+			close(done[i])
+			continue
+		}
+
+		if err := ctx.Err(); err != nil {
+			for j := 0; j < i; j++ {
+				<-done[j]
+			}
+			return fmt.Errorf("validating section %s: %w", check.section, err)
+		}
+
+		if !v.parallel {
+			check.validate(v, &results[i], value)
+			close(done[i])
+			continue
+		}
+
+		go func(i int, validate sectionValidator, value interface{}) {
+			defer close(done[i])
+			validate(v, &results[i], value)
+		}(i, check.validate, value)
+	}
+
+	for i := range sectionValidators {
+		<-done[i]
+		collect(results[i])
+	}
+	return nil
 }
 
 // validateAPAIVersion validates the APAI version
-func (v *APAIValidator) validateAPAIVersion(version interface{}) {
+func (v *APAIValidator) validateAPAIVersion(f *sectionFindings, version interface{}) {
 	versionStr, ok := version.(string)
 	if !ok {
-		v.Errors = append(v.Errors, "apai version must be a string")
+		f.addError("INVALID_TYPE", "apai version must be a string")
 		return
 	}
 
 	matched, _ := regexp.MatchString(`^0\.1\.\d+$`, versionStr)
 	if !matched {
-		v.Warnings = append(v.Warnings, fmt.Sprintf("Version %s may not be supported", versionStr))
+		f.addWarning("UNSUPPORTED_VERSION", fmt.Sprintf("Version %s may not be supported", versionStr))
 	}
 }
 
 // validateInfo validates the info section
-func (v *APAIValidator) validateInfo(info interface{}) {
+func (v *APAIValidator) validateInfo(f *sectionFindings, info interface{}) {
 	infoMap, ok := info.(map[string]interface{})
 	if !ok {
-		v.Errors = append(v.Errors, "info must be an object")
+		f.addError("INVALID_TYPE", "info must be an object")
 		return
 	}
 
 	requiredFields := []string{"title", "version", "description", "author", "license"}
 	for _, field := range requiredFields {
 		if _, exists := infoMap[field]; !exists {
-			v.Errors = append(v.Errors, fmt.Sprintf("Missing required field in info: %s", field))
+			f.addError("MISSING_FIELD", fmt.Sprintf("Missing required field in info: %s", field))
+		} else if isBlankString(infoMap[field]) {
+			f.addError("EMPTY_FIELD", fmt.Sprintf("Required field in info is empty: %s", field))
+		}
+	}
+
+	if id, exists := infoMap["id"]; exists {
+		if _, ok := id.(string); !ok {
+			f.addError("INVALID_TYPE", "info.id must be a string")
+		}
+	}
+
+	// The people responsible: the author, a contact and, for shared
+	// specs, owners, of whom at least one can be reached
+	reachable := false
+	if author, exists := infoMap["author"]; exists {
+		reachable = v.validatePerson(f, author, "info.author", true) || reachable
+	}
+	if contact, exists := infoMap["contact"]; exists {
+		reachable = v.validatePerson(f, contact, "info.contact", false) || reachable
+	}
+	if owners, exists := infoMap["owners"]; exists {
+		if ownersSlice, ok := owners.([]interface{}); ok {
+			for i, owner := range ownersSlice {
+				reachable = v.validatePerson(f, owner, fmt.Sprintf("info.owners[%d]", i), true) || reachable
+			}
+		} else {
+			f.addError("INVALID_TYPE", "info.owners must be an array")
 		}
 	}
+	if !reachable {
+		f.addWarning("NO_CONTACT", "No email or url in info.author, info.contact or info.owners to reach the people responsible for the specification")
+	}
 
 	if aiMetadata, exists := infoMap["ai_metadata"]; exists {
-		v.validateAIMetadata(aiMetadata)
+		v.validateAIMetadata(f, aiMetadata)
 	}
 }
 
+// validatePerson validates info.author, info.contact or an entry of
+// info.owners: a plain string, or an object with name, email, url and team
+// whose name is required when requireName is set. It reports whether the
+// person can be reached, by a valid email or url, or a string that is one.
+func (v *APAIValidator) validatePerson(f *sectionFindings, person interface{}, location string, requireName bool) bool {
+	switch typed := person.(type) {
+	case string:
+		if address, err := mail.ParseAddress(typed); err == nil && address.Address != "" {
+			return true
+		}
+		return isValidURL(typed)
+	case map[string]interface{}:
+		if _, exists := typed["name"]; !exists && requireName {
+			f.addError("MISSING_FIELD", fmt.Sprintf("%s object missing required field: name", location))
+		} else if exists && isBlankString(typed["name"]) {
+			f.addError("EMPTY_FIELD", fmt.Sprintf("%s object required field is empty: name", location))
+		}
+		for _, field := range []string{"name", "team"} {
+			if value, exists := typed[field]; exists {
+				if _, ok := value.(string); !ok {
+					f.addError("INVALID_TYPE", fmt.Sprintf("%s.%s must be a string", location, field))
+				}
+			}
+		}
+		return v.validateContactFields(f, typed, location)
+	default:
+		f.addError("INVALID_TYPE", fmt.Sprintf("%s must be a string or an object", location))
+		return false
+	}
+}
+
+// validateContactFields warns about malformed email and url fields, and
+// reports whether one of them is valid
+func (v *APAIValidator) validateContactFields(f *sectionFindings, contact map[string]interface{}, location string) bool {
+	reachable := false
+	if email, exists := contact["email"]; exists {
+		emailStr, ok := email.(string)
+		if !ok || !isValidEmail(emailStr) {
+			f.addWarning("INVALID_CONTACT", fmt.Sprintf("%s.email is not a valid email address: %v", location, email))
+		} else {
+			reachable = true
+		}
+	}
+
+	if contactURL, exists := contact["url"]; exists {
+		urlStr, ok := contactURL.(string)
+		if !ok || !isValidURL(urlStr) {
+			f.addWarning("INVALID_CONTACT", fmt.Sprintf("%s.url is not a valid URL: %v", location, contactURL))
+		} else {
+			reachable = true
+		}
+	}
+	return reachable
+}
+
+// isBlankString reports whether a value is a string holding only whitespace
+func isBlankString(value interface{}) bool {
+	str, ok := value.(string)
+	return ok && strings.TrimSpace(str) == ""
+}
+
+// isValidEmail reports whether s is a bare email address
+func isValidEmail(s string) bool {
+	address, err := mail.ParseAddress(s)
+	return err == nil && address.Address == s
+}
+
+// isValidURL reports whether s is an absolute http(s) URL with a host
+func isValidURL(s string) bool {
+	parsed, err := url.Parse(s)
+	if err != nil {
+		return false
+	}
+	return (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
+
 // validateAIMetadata validates AI-specific metadata
-func (v *APAIValidator) validateAIMetadata(metadata interface{}) {
+func (v *APAIValidator) validateAIMetadata(f *sectionFindings, metadata interface{}) {
 	metadataMap, ok := metadata.(map[string]interface{})
 	if !ok {
 		return
 	}
 
 	if _, exists := metadataMap["domain"]; !exists {
-		v.Warnings = append(v.Warnings, "ai_metadata.domain is recommended")
+		f.addWarning("RECOMMENDED_FIELD", "ai_metadata.domain is recommended")
 	}
 
 	if complexity, exists := metadataMap["complexity"]; exists {
 		complexityStr, ok := complexity.(string)
 		if ok {
-			validComplexities := []string{"low", "medium", "high"}
-			valid := false
-			for _, validComplexity := range validComplexities {
-				if complexityStr == validComplexity {
-					valid = true
-					break
-				}
-			}
-			if !valid {
-				v.Errors = append(v.Errors, fmt.Sprintf("Invalid complexity: %s", complexityStr))
+			if _, valid := matchEnum(f, complexityLevels, complexityStr, "info.ai_metadata.complexity"); !valid {
+				f.addError("INVALID_ENUM", fmt.Sprintf("Invalid complexity: %s", complexityStr))
 			}
 		}
 	}
+
+	if hierarchyInfo, exists := metadataMap["hierarchy_info"]; exists {
+		v.validateHierarchyInfo(f, hierarchyInfo)
+	}
 }
 
 // validateModels validates the models section
-func (v *APAIValidator) validateModels(models interface{}) {
+func (v *APAIValidator) validateModels(f *sectionFindings, models interface{}) {
 	modelsSlice, ok := models.([]interface{})
 	if !ok {
-		v.Errors = append(v.Errors, "models must be an array")
+		f.addError("INVALID_TYPE", "models must be an array")
 		return
 	}
 
 	if len(modelsSlice) == 0 {
-		v.Errors = append(v.Errors, "At least one model is required")
+		f.addError("MISSING_MODEL", "At least one model is required")
 		return
 	}
 
 	modelIds := make(map[string]bool)
+	currencies := make(map[string][]string)
 	for i, model := range modelsSlice {
-		modelMap, ok := model.(map[string]interface{})
-		if !ok {
-			v.Errors = append(v.Errors, fmt.Sprintf("Model %d must be an object", i))
-			continue
+		v.validateModel(f, model, i, modelIds, currencies)
+	}
+	validateCostCurrencies(f, currencies)
+	validateSharedRateLimits(f, modelsSlice)
+}
+
+// validateModel validates model i of the models section, recording its id
+// in modelIds and the currency of its cost in currencies
+func (v *APAIValidator) validateModel(f *sectionFindings, model interface{}, i int, modelIds map[string]bool, currencies map[string][]string) {
+	modelMap, ok := model.(map[string]interface{})
+	if !ok {
+		f.addError("INVALID_TYPE", fmt.Sprintf("Model %d must be an object", i))
+		return
+	}
+
+	// Validate required fields
+	requiredFields := []string{"id", "type", "provider", "name", "purpose"}
+	for _, field := range requiredFields {
+		if _, exists := modelMap[field]; !exists && !hasReplacement(modelMap, "models[]."+field) {
+			f.addError("MISSING_FIELD", fmt.Sprintf("Model %d missing required field: %s", i, field))
+		} else if isBlankString(modelMap[field]) {
+			f.addError("EMPTY_FIELD", fmt.Sprintf("Model %d required field is empty: %s", i, field))
 		}
+	}
 
-		// Validate required fields
-		requiredFields := []string{"id", "type", "provider", "name", "purpose"}
-		for _, field := range requiredFields {
-			if _, exists := modelMap[field]; !exists {
-				v.Errors = append(v.Errors, fmt.Sprintf("Model %d missing required field: %s", i, field))
+	// Check for duplicate IDs
+	if id, exists := modelMap["id"]; exists {
+		idStr, ok := id.(string)
+		if ok {
+			if modelIds[idStr] {
+				f.addError("DUPLICATE_ID", fmt.Sprintf("Duplicate model ID: %s", idStr))
 			}
+			modelIds[idStr] = true
 		}
+	}
 
-		// Check for duplicate IDs
-		if id, exists := modelMap["id"]; exists {
-			idStr, ok := id.(string)
-			if ok {
-				if modelIds[idStr] {
-					v.Errors = append(v.Errors, fmt.Sprintf("Duplicate model ID: %s", idStr))
-				}
-				modelIds[idStr] = true
+	// Validate model type
+	if modelType, exists := modelMap["type"]; exists {
+		typeStr, ok := modelType.(string)
+		if ok {
+			if _, valid := matchEnum(f, modelTypes, typeStr, fmt.Sprintf("models[%d].type", i)); !valid {
+				f.addWarning("UNKNOWN_VALUE", fmt.Sprintf("Unknown model type: %s", typeStr))
 			}
 		}
+	}
+	v.validateModelCapabilities(f, modelMap, i)
+	validateModelParameters(f, modelMap, i)
+	validateModelLimits(f, modelMap, i)
+	validateModelRouting(f, modelMap, i)
 
-		// Validate model type
-		if modelType, exists := modelMap["type"]; exists {
-			typeStr, ok := modelType.(string)
-			if ok {
-				validTypes := []string{"LLM", "Vision", "Audio", "Multimodal", "Classification", "Embedding"}
-				valid := false
-				for _, validType := range validTypes {
-					if typeStr == validType {
-						valid = true
-						break
-					}
-				}
-				if !valid {
-					v.Warnings = append(v.Warnings, fmt.Sprintf("Unknown model type: %s", typeStr))
-				}
-			}
+	if cost, exists := modelMap["cost"]; exists {
+		name := elementName(i, modelMap)
+		if currency := validateModelCost(f, cost, name); currency != "" {
+			currencies[currency] = append(currencies[currency], name)
 		}
 	}
 }
 
 // validatePrompts validates the prompts section
-func (v *APAIValidator) validatePrompts(prompts interface{}) {
+func (v *APAIValidator) validatePrompts(f *sectionFindings, prompts interface{}) {
 	promptsSlice, ok := prompts.([]interface{})
 	if !ok {
-		v.Errors = append(v.Errors, "prompts must be an array")
+		f.addError("INVALID_TYPE", "prompts must be an array")
 		return
 	}
 
 	promptIds := make(map[string]bool)
 	for i, prompt := range promptsSlice {
-		promptMap, ok := prompt.(map[string]interface{})
+		v.validatePrompt(f, prompt, i, promptIds)
+	}
+	validateTranslationCoverage(f, promptsSlice)
+	validateChainLanguages(f, promptsSlice)
+}
+
+// validatePrompt validates prompt i of the prompts section, recording its
+// id in promptIds
+func (v *APAIValidator) validatePrompt(f *sectionFindings, prompt interface{}, i int, promptIds map[string]bool) {
+	promptMap, ok := prompt.(map[string]interface{})
+	if !ok {
+		f.addError("INVALID_TYPE", fmt.Sprintf("Prompt %d must be an object", i))
+		return
+	}
+
+	// Validate required fields; template_file replaces template
+	requiredFields := []string{"id", "role", "template"}
+	if _, exists := promptMap["template_file"]; exists {
+		requiredFields = requiredFields[:2]
+	}
+	for _, field := range requiredFields {
+		if value, exists := currentField(promptMap, "prompts[]."+field); !exists {
+			f.addError("MISSING_FIELD", fmt.Sprintf("Prompt %d missing required field: %s", i, field))
+		} else if isBlankString(value) {
+			f.addError("EMPTY_FIELD", fmt.Sprintf("Prompt %d required field is empty: %s", i, field))
+		}
+	}
+
+	// Check for duplicate IDs
+	if id, exists := promptMap["id"]; exists {
+		idStr, ok := id.(string)
+		if ok {
+			if promptIds[idStr] {
+				f.addError("DUPLICATE_ID", fmt.Sprintf("Duplicate prompt ID: %s", idStr))
+			}
+			promptIds[idStr] = true
+		}
+	}
+
+	// Validate role
+	if role, exists := promptMap["role"]; exists {
+		roleStr, ok := role.(string)
+		if ok {
+			if _, valid := matchEnum(f, promptRoles, roleStr, fmt.Sprintf("prompts[%d].role", i)); !valid {
+				f.addError("INVALID_ENUM", fmt.Sprintf("Invalid prompt role: %s", roleStr))
+			}
+		}
+	}
+
+	v.validatePromptTemplate(f, promptMap, i)
+
+	// Prompts composing others name them in next or chain
+	if next, exists := promptMap["next"]; exists {
+		if _, ok := next.(string); !ok {
+			f.addError("INVALID_TYPE", fmt.Sprintf("Prompt %d next must be a string", i))
+		}
+	}
+	if _, ok := promptChain(promptMap); !ok {
+		f.addError("INVALID_TYPE", fmt.Sprintf("Prompt %d chain must be an array of prompt IDs", i))
+	}
+
+	if examples, exists := promptMap["examples"]; exists {
+		v.validatePromptExamples(f, examples, promptMap, i)
+	}
+	validatePromptLanguage(f, promptMap, i)
+	v.validatePromptTranslations(f, promptMap, i)
+	validatePromptParameters(f, promptMap, i)
+}
+
+// templateVariablePattern matches {{variable}} placeholders
+var templateVariablePattern = regexp.MustCompile(`\{\{\s*(\w+)\s*\}\}`)
+
+// maxPromptExamples is the number of few-shot examples above which a
+// prompt is reported, as every example is sent with each request
+const maxPromptExamples = 20
+
+// validatePromptExamples validates the few-shot examples of a prompt: each
+// must be an object with input and output, or user and assistant, whose
+// values are non-empty strings or objects, and may only use variables the
+// prompt declares or shares with the others
+func (v *APAIValidator) validatePromptExamples(f *sectionFindings, examples interface{}, promptMap map[string]interface{}, promptIndex int) {
+	name := elementName(promptIndex, promptMap)
+	examplesSlice, ok := examples.([]interface{})
+	if !ok {
+		f.addError("INVALID_TYPE", fmt.Sprintf("Prompt %s examples must be an array", name))
+		return
+	}
+	if len(examplesSlice) == 0 {
+		f.addWarning("EMPTY_EXAMPLES", fmt.Sprintf("Prompt %s examples is empty", name))
+		return
+	}
+	if len(examplesSlice) > maxPromptExamples {
+		f.addWarning("TOO_MANY_EXAMPLES", fmt.Sprintf("Prompt %s has %d examples (about %d tokens with the template), more than %d are sent with every request",
+			name, len(examplesSlice), estimateTokens(promptText(promptMap)), maxPromptExamples))
+	}
+
+	for j, example := range examplesSlice {
+		exampleMap, ok := example.(map[string]interface{})
 		if !ok {
-			v.Errors = append(v.Errors, fmt.Sprintf("Prompt %d must be an object", i))
+			f.addError("INVALID_TYPE", fmt.Sprintf("Prompt %s example %d must be an object", name, j))
 			continue
 		}
 
-		// Validate required fields
-		requiredFields := []string{"id", "role", "template"}
-		for _, field := range requiredFields {
-			if _, exists := promptMap[field]; !exists {
-				v.Errors = append(v.Errors, fmt.Sprintf("Prompt %d missing required field: %s", i, field))
+		inputField, outputField := exampleFields(exampleMap)
+		input, exists := exampleMap[inputField]
+		if !exists {
+			f.addError("MISSING_FIELD", fmt.Sprintf("Prompt %s example %d missing required field: %s", name, j, inputField))
+		}
+		if _, exists := exampleMap[outputField]; !exists {
+			f.addWarning("EXAMPLE_MISSING_OUTPUT", fmt.Sprintf("Prompt %s example %d missing output", name, j))
+		}
+		for _, field := range []string{inputField, outputField} {
+			value, exists := exampleMap[field]
+			if !exists {
+				continue
+			}
+			switch typed := value.(type) {
+			case string:
+				if strings.TrimSpace(typed) == "" {
+					f.addError("EMPTY_FIELD", fmt.Sprintf("Prompt %s example %d field is empty: %s", name, j, field))
+				}
+			case map[string]interface{}:
+			default:
+				f.addError("INVALID_TYPE", fmt.Sprintf("Prompt %s example %d %s must be a string or an object", name, j, field))
 			}
 		}
 
-		// Check for duplicate IDs
-		if id, exists := promptMap["id"]; exists {
-			idStr, ok := id.(string)
-			if ok {
-				if promptIds[idStr] {
-					v.Errors = append(v.Errors, fmt.Sprintf("Duplicate prompt ID: %s", idStr))
-				}
-				promptIds[idStr] = true
+		for _, variable := range exampleVariables(input) {
+			if !v.variables.resolves(promptMap, variable) {
+				f.addError("UNDECLARED_VARIABLE", fmt.Sprintf("Prompt %s example %d references undeclared variable: %s", name, j, variable))
 			}
 		}
+	}
+}
+
+// exampleFields returns the fields holding the input and output of a
+// few-shot example: user and assistant for examples written as chat
+// turns, input and output otherwise
+func exampleFields(example map[string]interface{}) (string, string) {
+	_, hasInput := example["input"]
+	_, hasOutput := example["output"]
+	_, hasUser := example["user"]
+	_, hasAssistant := example["assistant"]
+	if !hasInput && !hasOutput && (hasUser || hasAssistant) {
+		return "user", "assistant"
+	}
+	return "input", "output"
+}
+
+// validateExamplePrompts warns about system prompts carrying few-shot
+// examples that no task step or prompt chain uses
+func (v *APAIValidator) validateExamplePrompts(spec map[string]interface{}) {
+	used := make(map[string]bool)
+	objectsAt(spec, "tasks[].steps[]", "", func(step map[string]interface{}, _ string) {
+		if promptID, ok := step["prompt"].(string); ok {
+			used[promptID] = true
+		}
+	})
+	objectsAt(spec, "prompts[]", "", func(promptMap map[string]interface{}, _ string) {
+		referenced, _ := promptChain(promptMap)
+		for _, id := range referenced {
+			used[id] = true
+		}
+	})
+
+	objectsAt(spec, "prompts[]", "", func(promptMap map[string]interface{}, _ string) {
+		id, _ := promptMap["id"].(string)
+		role, _ := promptMap["role"].(string)
+		examples, _ := promptMap["examples"].([]interface{})
+		if id == "" || used[id] || len(examples) == 0 || !strings.EqualFold(role, "system") {
+			return
+		}
+		v.addWarning("UNUSED_EXAMPLES", fmt.Sprintf("Prompt %s has examples but is a system prompt no task uses", id))
+	})
+}
 
-		// Validate role
-		if role, exists := promptMap["role"]; exists {
-			roleStr, ok := role.(string)
-			if ok {
-				validRoles := []string{"system", "user", "assistant"}
-				valid := false
-				for _, validRole := range validRoles {
-					if roleStr == validRole {
-						valid = true
-						break
-					}
-				}
-				if !valid {
-					v.Errors = append(v.Errors, fmt.Sprintf("Invalid prompt role: %s", roleStr))
-				}
+// exampleVariables returns the variables an example input refers to: the
+// keys of an object input, or the {{variable}} placeholders of a string
+func exampleVariables(input interface{}) []string {
+	names := make([]string, 0)
+	switch typed := input.(type) {
+	case map[string]interface{}:
+		for name := range typed {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	case string:
+		for _, match := range templateVariablePattern.FindAllStringSubmatch(typed, -1) {
+			if !containsString(names, match[1]) {
+				names = append(names, match[1])
 			}
 		}
 	}
+	return names
 }
 
 // validateConstraints validates the constraints section
-func (v *APAIValidator) validateConstraints(constraints interface{}) {
+func (v *APAIValidator) validateConstraints(f *sectionFindings, constraints interface{}) {
 	constraintsSlice, ok := constraints.([]interface{})
 	if !ok {
-		v.Errors = append(v.Errors, "constraints must be an array")
+		f.addError("INVALID_TYPE", "constraints must be an array")
 		return
 	}
 
 	constraintIds := make(map[string]bool)
 	for i, constraint := range constraintsSlice {
-		constraintMap, ok := constraint.(map[string]interface{})
-		if !ok {
-			v.Errors = append(v.Errors, fmt.Sprintf("Constraint %d must be an object", i))
-			continue
-		}
+		v.validateConstraint(f, constraint, i, constraintIds)
+	}
 
-		// Validate required fields
-		requiredFields := []string{"id", "rule", "severity"}
-		for _, field := range requiredFields {
-			if _, exists := constraintMap[field]; !exists {
-				v.Errors = append(v.Errors, fmt.Sprintf("Constraint %d missing required field: %s", i, field))
-			}
+	validateConstraintContradictions(f, constraintsSlice)
+}
+
+// validateConstraint validates constraint i of the constraints section,
+// recording its id in constraintIds
+func (v *APAIValidator) validateConstraint(f *sectionFindings, constraint interface{}, i int, constraintIds map[string]bool) {
+	constraintMap, ok := constraint.(map[string]interface{})
+	if !ok {
+		f.addError("INVALID_TYPE", fmt.Sprintf("Constraint %d must be an object", i))
+		return
+	}
+
+	// Validate required fields
+	requiredFields := []string{"id", "rule", "severity"}
+	for _, field := range requiredFields {
+		if _, exists := constraintMap[field]; !exists {
+			f.addError("MISSING_FIELD", fmt.Sprintf("Constraint %d missing required field: %s", i, field))
+		} else if isBlankString(constraintMap[field]) {
+			f.addError("EMPTY_FIELD", fmt.Sprintf("Constraint %d required field is empty: %s", i, field))
 		}
+	}
 
-		// Check for duplicate IDs
-		if id, exists := constraintMap["id"]; exists {
-			idStr, ok := id.(string)
-			if ok {
-				if constraintIds[idStr] {
-					v.Errors = append(v.Errors, fmt.Sprintf("Duplicate constraint ID: %s", idStr))
-				}
-				constraintIds[idStr] = true
+	// Check for duplicate IDs
+	if id, exists := constraintMap["id"]; exists {
+		idStr, ok := id.(string)
+		if ok {
+			if constraintIds[idStr] {
+				f.addError("DUPLICATE_ID", fmt.Sprintf("Duplicate constraint ID: %s", idStr))
 			}
+			constraintIds[idStr] = true
 		}
+	}
 
-		// Validate severity
-		if severity, exists := constraintMap["severity"]; exists {
-			severityStr, ok := severity.(string)
-			if ok {
-				validSeverities := []string{"low", "medium", "high", "critical"}
-				valid := false
-				for _, validSeverity := range validSeverities {
-					if severityStr == validSeverity {
-						valid = true
-						break
-					}
-				}
-				if !valid {
-					v.Errors = append(v.Errors, fmt.Sprintf("Invalid constraint severity: %s", severityStr))
-				}
+	// Validate severity
+	if severity, exists := constraintMap["severity"]; exists {
+		severityStr, ok := severity.(string)
+		if ok {
+			if _, valid := matchEnum(f, constraintSeverities, severityStr, fmt.Sprintf("constraints[%d].severity", i)); !valid {
+				f.addError("INVALID_ENUM", fmt.Sprintf("Invalid constraint severity: %s", severityStr))
 			}
 		}
 	}
+
+	validateRegexConstraint(f, constraintMap, i)
 }
 
 // validateTasks validates the tasks section
-func (v *APAIValidator) validateTasks(tasks interface{}) {
+func (v *APAIValidator) validateTasks(f *sectionFindings, tasks interface{}) {
 	tasksSlice, ok := tasks.([]interface{})
 	if !ok {
-		v.Errors = append(v.Errors, "tasks must be an array")
+		f.addError("INVALID_TYPE", "tasks must be an array")
 		return
 	}
 
 	taskIds := make(map[string]bool)
 	for i, task := range tasksSlice {
-		taskMap, ok := task.(map[string]interface{})
-		if !ok {
-			v.Errors = append(v.Errors, fmt.Sprintf("Task %d must be an object", i))
-			continue
+		v.validateTask(f, task, i, taskIds)
+	}
+}
+
+// validateTask validates task i of the tasks section, recording its id in
+// taskIds
+func (v *APAIValidator) validateTask(f *sectionFindings, task interface{}, i int, taskIds map[string]bool) {
+	taskMap, ok := task.(map[string]interface{})
+	if !ok {
+		f.addError("INVALID_TYPE", fmt.Sprintf("Task %d must be an object", i))
+		return
+	}
+
+	// Validate required fields
+	requiredFields := []string{"id", "description"}
+	for _, field := range requiredFields {
+		if _, exists := taskMap[field]; !exists {
+			f.addError("MISSING_FIELD", fmt.Sprintf("Task %d missing required field: %s", i, field))
+		} else if isBlankString(taskMap[field]) {
+			f.addError("EMPTY_FIELD", fmt.Sprintf("Task %d required field is empty: %s", i, field))
 		}
+	}
 
-		// Validate required fields
-		requiredFields := []string{"id", "description"}
-		for _, field := range requiredFields {
-			if _, exists := taskMap[field]; !exists {
-				v.Errors = append(v.Errors, fmt.Sprintf("Task %d missing required field: %s", i, field))
+	// Check for duplicate IDs
+	if id, exists := taskMap["id"]; exists {
+		idStr, ok := id.(string)
+		if ok {
+			if taskIds[idStr] {
+				f.addError("DUPLICATE_ID", fmt.Sprintf("Duplicate task ID: %s", idStr))
 			}
+			taskIds[idStr] = true
 		}
+	}
 
-		// Check for duplicate IDs
-		if id, exists := taskMap["id"]; exists {
-			idStr, ok := id.(string)
-			if ok {
-				if taskIds[idStr] {
-					v.Errors = append(v.Errors, fmt.Sprintf("Duplicate task ID: %s", idStr))
-				}
-				taskIds[idStr] = true
+	// Abstract tasks are templates for inheriting specs and need no steps
+	abstract := false
+	if value, exists := taskMap["abstract"]; exists {
+		// Strings are reported by the type strictness checks
+		if !isStringValue(value) {
+			if abstract, ok = value.(bool); !ok {
+				f.addError("INVALID_TYPE", fmt.Sprintf("Task %d abstract must be a boolean", i))
 			}
 		}
+	}
+	steps, exists := taskMap["steps"]
+	if stepsSlice, isSlice := steps.([]interface{}); !abstract && (!exists || isSlice && len(stepsSlice) == 0) {
+		f.addWarning("TASK_WITHOUT_STEPS", fmt.Sprintf("Task %s has no steps; declare abstract: true if it is a template", elementName(i, taskMap)))
+	}
 
-		// Validate task steps if present
-		if steps, exists := taskMap["steps"]; exists {
-			v.validateTaskSteps(steps, i)
-		}
+	// Validate task steps if present
+	if exists {
+		v.validateTaskSteps(f, steps, i)
+		validateTaskDataFlow(f, taskMap, i)
 	}
 }
 
 // validateTaskSteps validates task steps
-func (v *APAIValidator) validateTaskSteps(steps interface{}, taskIndex int) {
+func (v *APAIValidator) validateTaskSteps(f *sectionFindings, steps interface{}, taskIndex int) {
 	stepsSlice, ok := steps.([]interface{})
 	if !ok {
-		v.Errors = append(v.Errors, fmt.Sprintf("Task %d steps must be an array", taskIndex))
+		f.addError("INVALID_TYPE", fmt.Sprintf("Task %d steps must be an array", taskIndex))
 		return
 	}
 
 	for stepIndex, step := range stepsSlice {
 		stepMap, ok := step.(map[string]interface{})
 		if !ok {
-			v.Errors = append(v.Errors, fmt.Sprintf("Task %d step %d must be an object", taskIndex, stepIndex))
+			f.addError("INVALID_TYPE", fmt.Sprintf("Task %d step %d must be an object", taskIndex, stepIndex))
 			continue
 		}
 
@@ -423,75 +1162,148 @@ func (v *APAIValidator) validateTaskSteps(steps interface{}, taskIndex int) {
 		requiredFields := []string{"name", "action"}
 		for _, field := range requiredFields {
 			if _, exists := stepMap[field]; !exists {
-				v.Errors = append(v.Errors, fmt.Sprintf("Task %d step %d missing required field: %s", taskIndex, stepIndex, field))
+				f.addError("MISSING_FIELD", fmt.Sprintf("Task %d step %d missing required field: %s", taskIndex, stepIndex, field))
+			} else if isBlankString(stepMap[field]) {
+				f.addError("EMPTY_FIELD", fmt.Sprintf("Task %d step %d required field is empty: %s", taskIndex, stepIndex, field))
 			}
 		}
 
 		// Validate action type
 		if action, exists := stepMap["action"]; exists {
 			if actionStr, ok := action.(string); ok {
-				validActions := []string{"analyze", "generate", "validate", "search", "escalate", "classify", "mcp_tool", "mcp_resource"}
-				isValid := false
-				for _, validAction := range validActions {
-					if actionStr == validAction {
-						isValid = true
-						break
-					}
-				}
-				if !isValid {
-					v.Warnings = append(v.Warnings, fmt.Sprintf("Task %d step %d unknown action: %s", taskIndex, stepIndex, actionStr))
+				if _, valid := matchEnum(f, stepActions, actionStr, fmt.Sprintf("tasks[%d].steps[%d].action", taskIndex, stepIndex)); !valid {
+					f.addWarning("UNKNOWN_VALUE", fmt.Sprintf("Task %d step %d unknown action: %s", taskIndex, stepIndex, actionStr))
 				}
 			}
 		}
 
+		v.validateStepOperations(f, stepMap, taskIndex, stepIndex)
+		v.validateStepModality(f, stepMap, taskIndex, stepIndex)
+
 		// Validate MCP-specific fields
 		if action, exists := stepMap["action"]; exists {
 			if actionStr, ok := action.(string); ok {
 				if actionStr == "mcp_tool" || actionStr == "mcp_resource" {
 					if _, exists := stepMap["mcp_server"]; !exists {
-						v.Errors = append(v.Errors, fmt.Sprintf("Task %d step %d MCP action missing mcp_server field", taskIndex, stepIndex))
+						f.addError("MISSING_FIELD", fmt.Sprintf("Task %d step %d MCP action missing mcp_server field", taskIndex, stepIndex))
 					}
 
 					if actionStr == "mcp_tool" {
 						if _, exists := stepMap["mcp_tool"]; !exists {
-							v.Errors = append(v.Errors, fmt.Sprintf("Task %d step %d mcp_tool action missing mcp_tool field", taskIndex, stepIndex))
+							f.addError("MISSING_FIELD", fmt.Sprintf("Task %d step %d mcp_tool action missing mcp_tool field", taskIndex, stepIndex))
 						}
 					}
 
 					if actionStr == "mcp_resource" {
 						if _, exists := stepMap["mcp_resource"]; !exists {
-							v.Errors = append(v.Errors, fmt.Sprintf("Task %d step %d mcp_resource action missing mcp_resource field", taskIndex, stepIndex))
+							f.addError("MISSING_FIELD", fmt.Sprintf("Task %d step %d mcp_resource action missing mcp_resource field", taskIndex, stepIndex))
 						}
 					}
 				}
 			}
 		}
+
+		validateStepActionFields(f, stepMap, taskIndex, stepIndex)
+	}
+}
+
+// Fields that apply only to MCP actions, and those MCP actions ignore
+var (
+	mcpStepFields   = []string{"mcp_server", "mcp_tool", "mcp_resource"}
+	modelStepFields = []string{"model", "prompt"}
+)
+
+// validateStepActionFields warns about MCP action steps setting a model or
+// prompt, and about other steps setting MCP fields, which the runtime
+// ignores and usually mean the action is not the one intended
+func validateStepActionFields(f *sectionFindings, stepMap map[string]interface{}, taskIndex, stepIndex int) {
+	action, _ := stepMap["action"].(string)
+	canonical, known := canonicalEnumValue(stepActions, action)
+	if !known {
+		return
+	}
+	if canonical == "mcp_tool" || canonical == "mcp_resource" {
+		for _, field := range modelStepFields {
+			if _, exists := stepMap[field]; exists {
+				f.addWarning("MISAPPLIED_STEP_FIELD", fmt.Sprintf("Task %d step %d %s action sets %s, which MCP actions ignore", taskIndex, stepIndex, canonical, field))
+			}
+		}
+		return
+	}
+	for _, field := range mcpStepFields {
+		if _, exists := stepMap[field]; exists {
+			f.addWarning("MISAPPLIED_STEP_FIELD", fmt.Sprintf("Task %d step %d %s action sets %s, which only MCP actions use", taskIndex, stepIndex, canonical, field))
+		}
+	}
+}
+
+// maxStepRetries and maxStepTimeout bound the operational settings of a
+// step before they are reported as unusual
+const (
+	maxStepRetries = 10
+	maxStepTimeout = time.Hour
+)
+
+// validateStepOperations validates the retry count and timeout of a step
+func (v *APAIValidator) validateStepOperations(f *sectionFindings, stepMap map[string]interface{}, taskIndex, stepIndex int) {
+	if retry, exists := stepMap["retry"]; exists {
+		count, ok := retry.(int)
+		if number, isFloat := retry.(float64); isFloat && number == float64(int(number)) {
+			count, ok = int(number), true
+		}
+		if !ok || count < 0 {
+			f.addError("INVALID_OPERATION_SETTING", fmt.Sprintf("Task %d step %d retry must be a non-negative integer, got %v", taskIndex, stepIndex, retry))
+		} else if count > maxStepRetries {
+			f.addWarning("UNUSUAL_OPERATION_SETTING", fmt.Sprintf("Task %d step %d retry of %d is unusually high (over %d)", taskIndex, stepIndex, count, maxStepRetries))
+		}
+	}
+
+	if timeout, exists := stepMap["timeout"]; exists {
+		timeoutStr, _ := timeout.(string)
+		duration, err := time.ParseDuration(timeoutStr)
+		if err != nil || duration <= 0 {
+			f.addError("INVALID_OPERATION_SETTING", fmt.Sprintf("Task %d step %d timeout must be a positive duration such as \"30s\" or \"5m\", got %v", taskIndex, stepIndex, timeout))
+		} else if duration > maxStepTimeout {
+			f.addWarning("UNUSUAL_OPERATION_SETTING", fmt.Sprintf("Task %d step %d timeout of %s is unusually long (over 1h)", taskIndex, stepIndex, timeoutStr))
+		}
 	}
 }
 
 // validateContext validates the context section
-func (v *APAIValidator) validateContext(context interface{}) {
+func (v *APAIValidator) validateContext(f *sectionFindings, context interface{}) {
 	contextMap, ok := context.(map[string]interface{})
 	if !ok {
-		v.Errors = append(v.Errors, "context must be an object")
+		f.addError("INVALID_TYPE", "context must be an object")
 		return
 	}
 
 	if _, exists := contextMap["memory"]; !exists {
-		v.Warnings = append(v.Warnings, "context.memory is recommended")
+		f.addWarning("RECOMMENDED_FIELD", "context.memory is recommended")
+	}
+
+	if variables, exists := contextMap["variables"]; exists {
+		if _, ok := variables.(map[string]interface{}); !ok {
+			f.addError("INVALID_TYPE", "context.variables must be an object")
+		}
 	}
 
 	// Validate MCP servers if present
 	if mcpServers, exists := contextMap["mcp_servers"]; exists {
-		v.validateMcpServers(mcpServers)
+		v.validateMcpServers(f, mcpServers)
 	}
+
+	if knowledgeBase, exists := contextMap["knowledge_base"]; exists {
+		validateKnowledgeBase(f, knowledgeBase)
+	}
+
+	v.validatePersistence(f, contextMap)
 }
 
 // validateMcpServers validates MCP servers section
-func (v *APAIValidator) validateMcpServers(mcpServers interface{}) {
+func (v *APAIValidator) validateMcpServers(f *sectionFindings, mcpServers interface{}) {
 	mcpServersSlice, ok := mcpServers.([]interface{})
 	if !ok {
-		v.Errors = append(v.Errors, "mcp_servers must be an array")
+		f.addError("INVALID_TYPE", "mcp_servers must be an array")
 		return
 	}
 
@@ -499,7 +1311,7 @@ func (v *APAIValidator) validateMcpServers(mcpServers interface{}) {
 	for index, server := range mcpServersSlice {
 		serverMap, ok := server.(map[string]interface{})
 		if !ok {
-			v.Errors = append(v.Errors, fmt.Sprintf("MCP server %d must be an object", index))
+			f.addError("INVALID_TYPE", fmt.Sprintf("MCP server %d must be an object", index))
 			continue
 		}
 
@@ -507,7 +1319,9 @@ func (v *APAIValidator) validateMcpServers(mcpServers interface{}) {
 		requiredFields := []string{"id", "name", "description", "version", "transport", "capabilities", "authentication"}
 		for _, field := range requiredFields {
 			if _, exists := serverMap[field]; !exists {
-				v.Errors = append(v.Errors, fmt.Sprintf("MCP server %d missing required field: %s", index, field))
+				f.addError("MISSING_FIELD", fmt.Sprintf("MCP server %d missing required field: %s", index, field))
+			} else if isBlankString(serverMap[field]) {
+				f.addError("EMPTY_FIELD", fmt.Sprintf("MCP server %d required field is empty: %s", index, field))
 			}
 		}
 
@@ -515,7 +1329,7 @@ func (v *APAIValidator) validateMcpServers(mcpServers interface{}) {
 		if id, exists := serverMap["id"]; exists {
 			if idStr, ok := id.(string); ok {
 				if serverIds[idStr] {
-					v.Errors = append(v.Errors, fmt.Sprintf("Duplicate MCP server ID: %s", idStr))
+					f.addError("DUPLICATE_ID", fmt.Sprintf("Duplicate MCP server ID: %s", idStr))
 				}
 				serverIds[idStr] = true
 			}
@@ -523,104 +1337,245 @@ func (v *APAIValidator) validateMcpServers(mcpServers interface{}) {
 
 		// Validate transport configuration
 		if transport, exists := serverMap["transport"]; exists {
-			v.validateMcpTransport(transport, index)
+			v.validateMcpTransport(f, transport, index)
 		}
 
 		// Validate authentication configuration
 		if auth, exists := serverMap["authentication"]; exists {
-			v.validateMcpAuthentication(auth, index)
+			v.validateMcpAuthentication(f, auth, index)
 		}
 	}
 }
 
 // validateMcpTransport validates MCP transport configuration
-func (v *APAIValidator) validateMcpTransport(transport interface{}, serverIndex int) {
+func (v *APAIValidator) validateMcpTransport(f *sectionFindings, transport interface{}, serverIndex int) {
 	transportMap, ok := transport.(map[string]interface{})
 	if !ok {
-		v.Errors = append(v.Errors, fmt.Sprintf("MCP server %d transport must be an object", serverIndex))
+		f.addError("INVALID_TYPE", fmt.Sprintf("MCP server %d transport must be an object", serverIndex))
 		return
 	}
 
 	if transportType, exists := transportMap["type"]; exists {
 		if typeStr, ok := transportType.(string); ok {
-			validTypes := []string{"stdio", "sse", "websocket"}
-			isValid := false
-			for _, validType := range validTypes {
-				if typeStr == validType {
-					isValid = true
-					break
-				}
-			}
-			if !isValid {
-				v.Errors = append(v.Errors, fmt.Sprintf("MCP server %d invalid transport type: %s", serverIndex, typeStr))
+			if canonical, valid := matchEnum(f, transportTypes, typeStr, fmt.Sprintf("context.mcp_servers[%d].transport.type", serverIndex)); valid {
+				typeStr = canonical
+			} else {
+				f.addError("INVALID_ENUM", fmt.Sprintf("MCP server %d invalid transport type: %s", serverIndex, typeStr))
 			}
 
 			// Validate transport-specific fields
 			if typeStr == "stdio" {
 				if _, exists := transportMap["command"]; !exists {
-					v.Errors = append(v.Errors, fmt.Sprintf("MCP server %d stdio transport missing command", serverIndex))
+					f.addError("MISSING_FIELD", fmt.Sprintf("MCP server %d stdio transport missing command", serverIndex))
 				}
 			} else if typeStr == "sse" || typeStr == "websocket" {
 				if _, exists := transportMap["url"]; !exists {
-					v.Errors = append(v.Errors, fmt.Sprintf("MCP server %d %s transport missing url", serverIndex, typeStr))
+					f.addError("MISSING_FIELD", fmt.Sprintf("MCP server %d %s transport missing url", serverIndex, typeStr))
 				}
 			}
 		}
 	} else {
-		v.Errors = append(v.Errors, fmt.Sprintf("MCP server %d transport missing required field: type", serverIndex))
+		f.addError("MISSING_FIELD", fmt.Sprintf("MCP server %d transport missing required field: type", serverIndex))
 	}
 }
 
 // validateMcpAuthentication validates MCP authentication configuration
-func (v *APAIValidator) validateMcpAuthentication(auth interface{}, serverIndex int) {
+func (v *APAIValidator) validateMcpAuthentication(f *sectionFindings, auth interface{}, serverIndex int) {
 	authMap, ok := auth.(map[string]interface{})
 	if !ok {
-		v.Errors = append(v.Errors, fmt.Sprintf("MCP server %d authentication must be an object", serverIndex))
+		f.addError("INVALID_TYPE", fmt.Sprintf("MCP server %d authentication must be an object", serverIndex))
 		return
 	}
 
-	if authType, exists := authMap["type"]; exists {
-		if typeStr, ok := authType.(string); ok {
-			validTypes := []string{"none", "api_key", "oauth", "custom"}
-			isValid := false
-			for _, validType := range validTypes {
-				if typeStr == validType {
-					isValid = true
-					break
-				}
-			}
-			if !isValid {
-				v.Errors = append(v.Errors, fmt.Sprintf("MCP server %d invalid authentication type: %s", serverIndex, typeStr))
-			}
+	if authType, exists := authMap["type"]; exists {
+		if typeStr, ok := authType.(string); ok {
+			if canonical, valid := matchEnum(f, authenticationTypes, typeStr, fmt.Sprintf("context.mcp_servers[%d].authentication.type", serverIndex)); valid {
+				typeStr = canonical
+			} else {
+				f.addError("INVALID_ENUM", fmt.Sprintf("MCP server %d invalid authentication type: %s", serverIndex, typeStr))
+			}
+
+			// Validate authentication-specific fields
+			if typeStr == "api_key" {
+				if _, exists := authMap["api_key"]; !exists {
+					f.addWarning("MCP_AUTH_INCOMPLETE", fmt.Sprintf("MCP server %d api_key authentication missing api_key field", serverIndex))
+				}
+			}
+			if typeStr == "oauth" {
+				if _, exists := authMap["token"]; !exists {
+					f.addWarning("MCP_AUTH_INCOMPLETE", fmt.Sprintf("MCP server %d oauth authentication missing token field", serverIndex))
+				}
+			}
+		}
+	} else {
+		f.addError("MISSING_FIELD", fmt.Sprintf("MCP server %d authentication missing required field: type", serverIndex))
+	}
+
+	// Credentials must come from the environment or a secret store
+	for _, field := range []string{"api_key", "token"} {
+		location := fmt.Sprintf("context.mcp_servers[%d].authentication.%s", serverIndex, field)
+		if value, ok := authMap[field].(string); ok && value != "" && !isSecretReference(value) && !v.envSubstituted[location] {
+			f.addWarning("HARDCODED_SECRET", fmt.Sprintf("MCP server %d authentication %s looks like a literal secret, use an ${ENV} reference or vault:// placeholder", serverIndex, field))
+		}
+	}
+}
+
+// secretReferencePattern matches an ${ENV} reference
+var secretReferencePattern = regexp.MustCompile(`\$\{[A-Za-z_][A-Za-z0-9_]*\}`)
+
+// isSecretReference reports whether a credential refers to a secret instead
+// of holding it: it is an ${ENV} reference as a whole or a vault://
+// placeholder. A redacted value holds no secret either, while a literal
+// with a reference inside, such as "sk-live-abc${SUFFIX}", still does.
+func isSecretReference(value string) bool {
+	if reference := secretReferencePattern.FindString(value); reference != "" && reference == value {
+		return true
+	}
+	return strings.HasPrefix(value, "vault://") || value == redactedValue
+}
+
+// validateEvaluation validates the evaluation section
+func (v *APAIValidator) validateEvaluation(f *sectionFindings, evaluation interface{}) {
+	evaluationMap, ok := evaluation.(map[string]interface{})
+	if !ok {
+		f.addError("INVALID_TYPE", "evaluation must be an object")
+		return
+	}
+
+	if experiments, exists := evaluationMap["experiments"]; exists {
+		validateExperiments(f, experiments)
+	}
+
+	metrics, exists := evaluationMap["metrics"]
+	if !exists {
+		f.addWarning("RECOMMENDED_FIELD", "evaluation.metrics is recommended")
+		return
+	}
+
+	forEachMetric(metrics, func(metricMap map[string]interface{}, location string) {
+		validateMetric(f, metricMap, location)
+	})
+}
+
+// numericFields lists fields that must hold numbers wherever they appear
+var numericFields = map[string]bool{
+	"temperature":          true,
+	"top_p":                true,
+	"max_tokens":           true,
+	"context_window":       true,
+	"input_per_1k_tokens":  true,
+	"output_per_1k_tokens": true,
+	"threshold":            true,
+	"ttl":                  true,
+}
+
+// isNumericField reports whether a key must hold a number
+func isNumericField(key string) bool {
+	return numericFields[key] || strings.HasSuffix(key, "_threshold")
+}
+
+// validateNumericFields reports numeric fields written as strings, which
+// usually come from quoting mistakes in YAML
+func (v *APAIValidator) validateNumericFields(value interface{}, path string) {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(typed))
+		for key := range typed {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			fieldPath := key
+			if path != "" {
+				fieldPath = path + "." + key
+			}
+
+			if str, ok := typed[key].(string); ok && isNumericField(key) && !containsString(persistenceTTLPaths, fieldPath) {
+				v.addError("NUMERIC_STRING", numericStringError(fieldPath, str))
+				continue
+			}
+			v.validateNumericFields(typed[key], fieldPath)
+		}
+	case []interface{}:
+		for i, item := range typed {
+			v.validateNumericFields(item, fmt.Sprintf("%s[%d]", path, i))
+		}
+	}
+}
+
+// numericStringError describes a number written as a string, suggesting
+// the number to write when the string is quoted or a rate with a unit
+func numericStringError(fieldPath, str string) string {
+	if _, err := strconv.ParseFloat(strings.TrimSpace(str), 64); err == nil {
+		return fmt.Sprintf("%s must be a number, got string \"%s\" (remove the quotes)", fieldPath, str)
+	}
+	if rate, ok := perMinuteRate(str); ok && strings.HasSuffix(fieldPath, "_per_minute") {
+		return fmt.Sprintf("%s must be a number, got string \"%s\" (write %s, the rate per minute)", fieldPath, str, strconv.FormatFloat(rate, 'f', -1, 64))
+	}
+	return fmt.Sprintf("%s must be a number, got string \"%s\"", fieldPath, str)
+}
+
+// booleanFields lists fields that must hold booleans wherever they appear
+var booleanFields = map[string]bool{
+	"abstract":             true,
+	"standalone":           true,
+	"enabled":              true,
+	"parallel":             true,
+	"required":             true,
+	"multilingual":         true,
+	"audio_processing":     true,
+	"real_time_processing": true,
+}
+
+// isBooleanField reports whether a key must hold a boolean; capability
+// flags such as vision_support and requirements such as testing_required
+// are booleans too
+func isBooleanField(key string) bool {
+	return booleanFields[key] || strings.HasSuffix(key, "_support") || strings.HasSuffix(key, "_required")
+}
 
-			// Validate authentication-specific fields
-			if typeStr == "api_key" {
-				if _, exists := authMap["api_key"]; !exists {
-					v.Warnings = append(v.Warnings, fmt.Sprintf("MCP server %d api_key authentication missing api_key field", serverIndex))
-				}
+// validateBooleanFields reports boolean fields written as strings, which
+// runtimes may read as false whatever they say
+func (v *APAIValidator) validateBooleanFields(value interface{}, path string) {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for _, key := range sortedKeys(typed) {
+			fieldPath := key
+			if path != "" {
+				fieldPath = path + "." + key
 			}
-			if typeStr == "oauth" {
-				if _, exists := authMap["token"]; !exists {
-					v.Warnings = append(v.Warnings, fmt.Sprintf("MCP server %d oauth authentication missing token field", serverIndex))
-				}
+
+			if str, ok := typed[key].(string); ok && isBooleanField(key) {
+				v.addError("BOOLEAN_STRING", booleanStringError(fieldPath, str))
+				continue
 			}
+			v.validateBooleanFields(typed[key], fieldPath)
+		}
+	case []interface{}:
+		for i, item := range typed {
+			v.validateBooleanFields(item, fmt.Sprintf("%s[%d]", path, i))
 		}
-	} else {
-		v.Errors = append(v.Errors, fmt.Sprintf("MCP server %d authentication missing required field: type", serverIndex))
 	}
 }
 
-// validateEvaluation validates the evaluation section
-func (v *APAIValidator) validateEvaluation(evaluation interface{}) {
-	evaluationMap, ok := evaluation.(map[string]interface{})
-	if !ok {
-		v.Errors = append(v.Errors, "evaluation must be an object")
-		return
-	}
+// isStringValue reports whether a value is a string; checks of boolean
+// fields leave strings to validateBooleanFields
+func isStringValue(value interface{}) bool {
+	_, ok := value.(string)
+	return ok
+}
 
-	if _, exists := evaluationMap["metrics"]; !exists {
-		v.Warnings = append(v.Warnings, "evaluation.metrics is recommended")
+// booleanStringError describes a boolean written as a string, suggesting
+// the unquoted boolean when the string spells one
+func booleanStringError(fieldPath, str string) string {
+	switch strings.ToLower(strings.TrimSpace(str)) {
+	case "true", "yes", "y", "on", "1":
+		return fmt.Sprintf("%s must be a boolean, got string \"%s\" (write true, without quotes)", fieldPath, str)
+	case "false", "no", "n", "off", "0":
+		return fmt.Sprintf("%s must be a boolean, got string \"%s\" (write false, without quotes)", fieldPath, str)
 	}
+	return fmt.Sprintf("%s must be a boolean, got string \"%s\"", fieldPath, str)
 }
 
 // crossValidate performs cross-validation between sections
@@ -651,7 +1606,7 @@ func (v *APAIValidator) crossValidate(spec map[string]interface{}) {
 										if model, exists := stepMap["model"]; exists {
 											if modelStr, ok := model.(string); ok {
 												if !modelIds[modelStr] {
-													v.Errors = append(v.Errors, fmt.Sprintf("Task references unknown model: %s", modelStr))
+													v.addError("UNKNOWN_REFERENCE", fmt.Sprintf("Task references unknown model: %s", modelStr))
 												}
 											}
 										}
@@ -691,7 +1646,7 @@ func (v *APAIValidator) crossValidate(spec map[string]interface{}) {
 										if prompt, exists := stepMap["prompt"]; exists {
 											if promptStr, ok := prompt.(string); ok {
 												if !promptIds[promptStr] {
-													v.Errors = append(v.Errors, fmt.Sprintf("Task references unknown prompt: %s", promptStr))
+													v.addError("UNKNOWN_REFERENCE", fmt.Sprintf("Task references unknown prompt: %s", promptStr))
 												}
 											}
 										}
@@ -706,35 +1661,35 @@ func (v *APAIValidator) crossValidate(spec map[string]interface{}) {
 	}
 
 	// Validate that referenced MCP servers exist
-	if tasks, tasksExists := spec["tasks"]; tasksExists {
-		if context, contextExists := spec["context"]; contextExists {
-			if contextMap, ok := context.(map[string]interface{}); ok {
-				if mcpServers, mcpServersExists := contextMap["mcp_servers"]; mcpServersExists {
-					mcpServerIds := make(map[string]bool)
-					if mcpServersSlice, ok := mcpServers.([]interface{}); ok {
-						for _, server := range mcpServersSlice {
-							if serverMap, ok := server.(map[string]interface{}); ok {
-								if id, exists := serverMap["id"]; exists {
-									if idStr, ok := id.(string); ok {
-										mcpServerIds[idStr] = true
-									}
+	if context, contextExists := spec["context"]; contextExists {
+		if contextMap, ok := context.(map[string]interface{}); ok {
+			if mcpServers, mcpServersExists := contextMap["mcp_servers"]; mcpServersExists {
+				mcpServerIds := make(map[string]bool)
+				if mcpServersSlice, ok := mcpServers.([]interface{}); ok {
+					for _, server := range mcpServersSlice {
+						if serverMap, ok := server.(map[string]interface{}); ok {
+							if id, exists := serverMap["id"]; exists {
+								if idStr, ok := id.(string); ok {
+									mcpServerIds[idStr] = true
 								}
 							}
 						}
 					}
+				}
 
-					if tasksSlice, ok := tasks.([]interface{}); ok {
-						for _, task := range tasksSlice {
-							if taskMap, ok := task.(map[string]interface{}); ok {
-								if steps, exists := taskMap["steps"]; exists {
-									if stepsSlice, ok := steps.([]interface{}); ok {
-										for _, step := range stepsSlice {
-											if stepMap, ok := step.(map[string]interface{}); ok {
-												if mcpServer, exists := stepMap["mcp_server"]; exists {
-													if mcpServerStr, ok := mcpServer.(string); ok {
-														if !mcpServerIds[mcpServerStr] {
-															v.Errors = append(v.Errors, fmt.Sprintf("Task references unknown MCP server: %s", mcpServerStr))
-														}
+				referencedServers := make(map[string]bool)
+				if tasksSlice, ok := spec["tasks"].([]interface{}); ok {
+					for _, task := range tasksSlice {
+						if taskMap, ok := task.(map[string]interface{}); ok {
+							if steps, exists := taskMap["steps"]; exists {
+								if stepsSlice, ok := steps.([]interface{}); ok {
+									for _, step := range stepsSlice {
+										if stepMap, ok := step.(map[string]interface{}); ok {
+											if mcpServer, exists := stepMap["mcp_server"]; exists {
+												if mcpServerStr, ok := mcpServer.(string); ok {
+													referencedServers[mcpServerStr] = true
+													if !mcpServerIds[mcpServerStr] && !isWorkspaceReference(mcpServerStr) {
+														v.addError("UNKNOWN_REFERENCE", fmt.Sprintf("Task references unknown MCP server: %s", mcpServerStr))
 													}
 												}
 											}
@@ -745,8 +1700,149 @@ func (v *APAIValidator) crossValidate(spec map[string]interface{}) {
 						}
 					}
 				}
+
+				// Warn about declared MCP servers that no step references
+				if v.Config.WarnUnusedMCPServers {
+					if mcpServersSlice, ok := mcpServers.([]interface{}); ok {
+						for _, server := range mcpServersSlice {
+							if serverMap, ok := server.(map[string]interface{}); ok {
+								if idStr, ok := serverMap["id"].(string); ok && !referencedServers[idStr] && !v.serverUsedByWorkspace(idStr) {
+									v.addWarning("UNUSED_MCP_SERVER", fmt.Sprintf("MCP server '%s' is declared but never used", idStr))
+								}
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+
+	v.validateTaskReferences(spec)
+	v.validateStepModels(spec)
+	v.validateModelRoutes(spec)
+	v.validatePromptChains(spec)
+	v.validateExamplePrompts(spec)
+	v.validateGlobalVariables(spec)
+	v.validateTaskSchemas(spec)
+	v.validateKnowledgeReferences(spec)
+	v.validatePersistenceRetention(spec)
+	v.validateExperimentReferences(spec)
+	v.validateContextWindows(spec)
+	v.validateMetricLinks(spec)
+	v.validateBroadestLevel(spec)
+	if v.workspace != nil {
+		v.validateWorkspaceReferences(spec)
+	}
+}
+
+// validateTaskReferences checks steps that run another task: the task must
+// exist and must not be abstract. Tasks of other specifications are checked
+// by validateWorkspaceReferences.
+func (v *APAIValidator) validateTaskReferences(spec map[string]interface{}) {
+	abstractTasks := make(map[string]bool)
+	objectsAt(spec, "tasks[]", "", func(task map[string]interface{}, _ string) {
+		if id, ok := task["id"].(string); ok {
+			abstract, _ := task["abstract"].(bool)
+			abstractTasks[id] = abstract
+		}
+	})
+
+	objectsAt(spec, "tasks[].steps[]", "", func(step map[string]interface{}, location string) {
+		taskID, ok := step["task"].(string)
+		if !ok || isWorkspaceReference(taskID) {
+			return
+		}
+		if abstract, exists := abstractTasks[taskID]; !exists {
+			v.addError("UNKNOWN_REFERENCE", fmt.Sprintf("Task references unknown task: %s", taskID))
+		} else if abstract {
+			v.addError("ABSTRACT_TASK_RUN", fmt.Sprintf("Abstract task %s cannot be run by %s", taskID, location))
+		}
+	})
+}
+
+// promptChain returns the prompts a prompt runs after itself: those of its
+// chain, in order, then its next prompt. It reports false when chain is not
+// an array of strings.
+func promptChain(prompt map[string]interface{}) ([]string, bool) {
+	referenced := make([]string, 0)
+	chain, exists := prompt["chain"]
+	chainSlice, ok := chain.([]interface{})
+	if exists && !ok {
+		return referenced, false
+	}
+	for _, item := range chainSlice {
+		id, ok := item.(string)
+		if !ok {
+			return referenced, false
+		}
+		referenced = append(referenced, id)
+	}
+	if next, ok := prompt["next"].(string); ok {
+		referenced = append(referenced, next)
+	}
+	return referenced, true
+}
+
+// validatePromptChains checks the prompts named by chain and next: they
+// must exist, and following them must never lead back to a prompt, which
+// would loop forever at runtime
+func (v *APAIValidator) validatePromptChains(spec map[string]interface{}) {
+	ids := make([]string, 0)
+	references := make(map[string][]string)
+	objectsAt(spec, "prompts[]", "", func(prompt map[string]interface{}, _ string) {
+		id, ok := prompt["id"].(string)
+		if !ok {
+			return
+		}
+		if _, seen := references[id]; !seen {
+			ids = append(ids, id)
+		}
+		referenced, _ := promptChain(prompt)
+		references[id] = append(references[id], referenced...)
+	})
+
+	for _, id := range ids {
+		for _, referenced := range references[id] {
+			if _, exists := references[referenced]; !exists {
+				v.addError("UNKNOWN_REFERENCE", fmt.Sprintf("Prompt %s references unknown prompt: %s", id, referenced))
+			}
+		}
+	}
+
+	// Depth-first search, reporting each cycle once from its first prompt
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int)
+	stack := make([]string, 0)
+	var visit func(id string)
+	visit = func(id string) {
+		state[id] = visiting
+		stack = append(stack, id)
+		for _, referenced := range references[id] {
+			switch state[referenced] {
+			case unvisited:
+				if _, exists := references[referenced]; exists {
+					visit(referenced)
+				}
+			case visiting:
+				for i := range stack {
+					if stack[i] == referenced {
+						cycle := append(append([]string{}, stack[i:]...), referenced)
+						v.addError("CIRCULAR_PROMPT_CHAIN", fmt.Sprintf("Circular prompt chain: %s", strings.Join(cycle, " -> ")))
+					}
+				}
 			}
 		}
+		stack = stack[:len(stack)-1]
+		state[id] = visited
+	}
+	for _, id := range ids {
+		if state[id] == unvisited {
+			visit(id)
+		}
 	}
 }
 
@@ -789,82 +1885,258 @@ func (v *APAIValidator) GetResults() ValidationResult {
 		Valid:    len(v.Errors) == 0,
 		Errors:   v.Errors,
 		Warnings: v.Warnings,
+		codes:    v.codes,
 	}
 }
 
+// ShouldFail reports whether the last validation fails at the configured fail level
+func (v *APAIValidator) ShouldFail() bool {
+	return v.Config.FailOn.Fails(v.GetResults())
+}
+
 // ============================================================================
 // HIERARCHICAL COMPOSITION METHODS
 // ============================================================================
 
 // ValidateWithInheritance validates specification with inheritance support
 func (v *APAIValidator) ValidateWithInheritance(filePath string) (bool, error) {
-	content, err := ioutil.ReadFile(filePath)
+	return v.ValidateWithInheritanceContext(context.Background(), filePath)
+}
+
+// ValidateWithInheritanceContext validates a specification with inheritance
+// support, stopping with the context's error, wrapped, once ctx is done
+func (v *APAIValidator) ValidateWithInheritanceContext(ctx context.Context, filePath string) (bool, error) {
+	if v.workspace != nil {
+		v.workspaceMember = v.workspace.memberID(filePath)
+		defer func() { v.workspaceMember = "" }()
+	}
+	mergedSpec, err := v.ResolveSpecContext(ctx, filePath)
 	if err != nil {
-		return false, fmt.Errorf("file not found: %s", filePath)
+		return false, err
 	}
+	return v.validateResolved(ctx, mergedSpec, filePath)
+}
 
-	var spec map[string]interface{}
-	ext := strings.ToLower(filepath.Ext(filePath))
+// validateResolved validates a specification merged with its parents by
+// resolveSpec, keeping the findings of resolving them
+func (v *APAIValidator) validateResolved(ctx context.Context, mergedSpec map[string]interface{}, filePath string) (bool, error) {
+	inheritanceErrors, inheritanceWarnings, inheritanceCodes := v.Errors, v.Warnings, v.codes
 
-	switch ext {
-	case ".yaml", ".yml":
-		err = yaml.Unmarshal(content, &spec)
-		if err != nil {
-			return false, fmt.Errorf("invalid YAML: %v", err)
-		}
-	case ".json":
-		err = json.Unmarshal(content, &spec)
-		if err != nil {
-			return false, fmt.Errorf("invalid JSON: %v", err)
+	// Validate merged specification, keeping findings raised while resolving parents
+	v.validatingMerged = true
+	_, err := v.ValidateSpecContext(ctx, mergedSpec)
+	v.validatingMerged = false
+	if err != nil {
+		return false, fmt.Errorf("validating %s: %w", filePath, err)
+	}
+	v.Errors = append(inheritanceErrors, v.Errors...)
+	v.Warnings = append(inheritanceWarnings, v.Warnings...)
+	v.mergeCodes(inheritanceCodes)
+
+	if v.Config.ValidateParents {
+		if err := v.validateParents(ctx, inheritanceErrors, inheritanceWarnings); err != nil {
+			return false, err
 		}
-	default:
-		return false, fmt.Errorf("unsupported file format: %s", ext)
+		v.reportIssues()
 	}
+	return len(v.Errors) == 0, nil
+}
 
-	// Load and merge inherited specifications
-	mergedSpec := v.mergeInheritedSpecifications(spec, filePath)
+// ResolveSpecContext loads a specification and merges its inherited
+// specifications into it. Problems with parents are recorded as errors;
+// the returned error covers unreadable files and a done ctx.
+func (v *APAIValidator) ResolveSpecContext(ctx context.Context, filePath string) (map[string]interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("resolving %s: %w", filePath, err)
+	}
 
-	// Validate merged specification
-	isValid := v.ValidateSpec(mergedSpec)
-	return isValid, nil
+	spec, err := v.loadSpecAs(filePath, v.inputFormat)
+	if err != nil {
+		return nil, err
+	}
+	v.rebaseFileReferences(spec, filePath)
+	return v.resolveSpec(ctx, spec, filePath)
+}
+
+// resolveSpec merges the inherited specifications of spec, loaded from
+// filePath, into it
+func (v *APAIValidator) resolveSpec(ctx context.Context, spec map[string]interface{}, filePath string) (map[string]interface{}, error) {
+	// Load and merge inherited specifications
+	v.Errors = make([]string, 0)
+	v.Warnings = make([]string, 0)
+	v.reported = issueCount{}
+	v.codes = nil
+	merged, err := v.mergeInheritedSpecificationsContext(ctx, spec, filePath)
+	v.reportIssues()
+	return merged, err
 }
 
 // loadSpec loads specification from file (for hierarchical use)
 func (v *APAIValidator) loadSpec(filePath string) (map[string]interface{}, error) {
-	content, err := ioutil.ReadFile(filePath)
+	return v.loadSpecAs(filePath, "")
+}
+
+// loadSpecAs loads a specification from file, parsing it as format, or as
+// the format of its extension when format is empty
+func (v *APAIValidator) loadSpecAs(filePath, format string) (map[string]interface{}, error) {
+	if v.cache != nil {
+		v.readDigest = sha256.New()
+		defer func() {
+			if v.fileDigests == nil {
+				v.fileDigests = make(map[string]string)
+			}
+			v.fileDigests[filePath] = hex.EncodeToString(v.readDigest.Sum(nil))
+			v.readDigest = nil
+		}()
+	}
+
+	content, err := v.readFile(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("file not found: %s", filePath)
 	}
 
-	var spec map[string]interface{}
-	ext := strings.ToLower(filepath.Ext(filePath))
+	forced := format != ""
+	if !forced {
+		ext := strings.ToLower(filepath.Ext(filePath))
+		if format = formatOfExtension(ext); format == "" {
+			return nil, fmt.Errorf("unsupported file format: %s", ext)
+		}
+	}
 
-	switch ext {
-	case ".yaml", ".yml":
-		err = yaml.Unmarshal(content, &spec)
-		if err != nil {
-			return nil, fmt.Errorf("invalid YAML: %v", err)
+	var spec map[string]interface{}
+	cached, cacheKey := false, ""
+	if v.cache != nil {
+		cacheKey = v.cache.key(parsedCacheKind, format, string(content))
+		spec, cached = v.cache.load(parsedCacheKind, cacheKey)
+	}
+	if !cached {
+		switch format {
+		case "yaml":
+			err = decodeYAML(content, &spec)
+			if err != nil {
+				err = fmt.Errorf("invalid YAML: %v", err)
+			}
+		case "json":
+			err = json.Unmarshal(content, &spec)
+			if err != nil {
+				err = fmt.Errorf("invalid JSON: %v", err)
+			}
+		default:
+			err = fmt.Errorf("unsupported input format: %s (expected yaml or json)", format)
 		}
-	case ".json":
-		err = json.Unmarshal(content, &spec)
 		if err != nil {
-			return nil, fmt.Errorf("invalid JSON: %v", err)
+			if forced {
+				return nil, fmt.Errorf("%s: %v (input format forced to %s)", filePath, err, format)
+			}
+			return nil, err
+		}
+		// Stored before includes are resolved into the parsed map
+		if v.cache != nil && spec != nil {
+			v.cache.store(parsedCacheKind, cacheKey, spec)
 		}
-	default:
-		return nil, fmt.Errorf("unsupported file format: %s", ext)
 	}
 
-	return spec, nil
+	return v.resolveIncludes(spec, filePath)
 }
 
 // resolveInheritancePath resolves inheritance path to absolute path
-func (v *APAIValidator) resolveInheritancePath(inheritPath, currentSpecPath string) string {
-	currentDir := filepath.Dir(currentSpecPath)
-	return filepath.Join(currentDir, inheritPath)
+func (v *APAIValidator) resolveInheritancePath(inheritPath, currentSpecPath string) (string, error) {
+	if isRegistryReference(inheritPath) {
+		return v.resolveRegistryReference(inheritPath)
+	}
+
+	return v.joinPath(v.dirPath(currentSpecPath), inheritPath), nil
+}
+
+// SetFS reads specifications and inherited files from fsys from now on, as
+// WithFS does at construction; a nil fsys restores the OS filesystem
+func (v *APAIValidator) SetFS(fsys fs.FS) {
+	v.fsys = fsys
+	v.inheritedSpecs = make(map[string]map[string]interface{})
+	v.mergeCache = make(map[string]map[string]interface{})
+}
+
+// joinPath joins path elements, using slash-separated paths when reading
+// from an fs.FS, whose paths are always slash-separated
+func (v *APAIValidator) joinPath(elems ...string) string {
+	if v.fsys != nil {
+		return path.Join(elems...)
+	}
+	return filepath.Join(elems...)
+}
+
+// dirPath returns the directory of a specification path
+func (v *APAIValidator) dirPath(filePath string) string {
+	if v.fsys != nil {
+		return path.Dir(filePath)
+	}
+	return filepath.Dir(filePath)
+}
+
+// fsPath converts a path to the unrooted form an fs.FS expects
+func fsPath(filePath string) string {
+	return path.Clean(strings.TrimPrefix(filepath.ToSlash(filePath), "/"))
+}
+
+// readFile reads a specification file from the configured filesystem, or
+// from the OS filesystem when none is set
+func (v *APAIValidator) readFile(filePath string) ([]byte, error) {
+	var content []byte
+	var err error
+	if v.fsys != nil {
+		content, err = fs.ReadFile(v.fsys, fsPath(filePath))
+	} else {
+		content, err = ioutil.ReadFile(filePath)
+	}
+	if v.readDigest != nil && err == nil {
+		fmt.Fprintf(v.readDigest, "%s\x00%d\x00", filePath, len(content))
+		v.readDigest.Write(content)
+	}
+	return content, err
+}
+
+// openFile opens a file of the configured filesystem, or of the OS
+// filesystem when none is set, for reading it a part at a time
+func (v *APAIValidator) openFile(filePath string) (io.ReadCloser, error) {
+	if v.fsys != nil {
+		return v.fsys.Open(fsPath(filePath))
+	}
+	return os.Open(filePath)
+}
+
+// glob returns the files of the configured filesystem, or of the OS
+// filesystem when none is set, matching a pattern
+func (v *APAIValidator) glob(pattern string) ([]string, error) {
+	if v.fsys != nil {
+		return fs.Glob(v.fsys, fsPath(pattern))
+	}
+	return filepath.Glob(pattern)
+}
+
+// readDir lists a directory of the configured filesystem, or of the OS
+// filesystem when none is set
+func (v *APAIValidator) readDir(dir string) ([]fs.DirEntry, error) {
+	if v.fsys != nil {
+		return fs.ReadDir(v.fsys, fsPath(dir))
+	}
+	return os.ReadDir(dir)
+}
+
+// fileExists reports whether a regular file exists on the configured filesystem
+func (v *APAIValidator) fileExists(filePath string) bool {
+	var info fs.FileInfo
+	var err error
+	if v.fsys != nil {
+		info, err = fs.Stat(v.fsys, fsPath(filePath))
+	} else {
+		info, err = os.Stat(filePath)
+	}
+	return err == nil && !info.IsDir()
 }
 
-// loadInheritedSpecs loads all inherited specifications
-func (v *APAIValidator) loadInheritedSpecs(spec map[string]interface{}, specPath string) {
+// loadInheritedSpecs loads all inherited specifications, enforcing the
+// configured depth and fan-out limits along the given inheritance chain
+func (v *APAIValidator) loadInheritedSpecs(spec map[string]interface{}, specPath string, chain []string) {
 	inherits, exists := spec["inherits"]
 	if !exists {
 		return
@@ -875,29 +2147,111 @@ func (v *APAIValidator) loadInheritedSpecs(spec map[string]interface{}, specPath
 		return
 	}
 
-	for _, inheritPath := range inheritsSlice {
+	duplicates := v.duplicateInherits(inheritsSlice, specPath)
+	for i, inheritPath := range inheritsSlice {
+		if v.inheritance.err != nil {
+			return
+		}
+		if err := v.inheritance.ctx.Err(); err != nil {
+			v.inheritance.err = fmt.Errorf("resolving inherits of %s: %w", specPath, err)
+			v.inheritance.failed = true
+			return
+		}
+
 		inheritPathStr, ok := inheritPath.(string)
 		if !ok {
 			continue
 		}
 
-		resolvedPath := v.resolveInheritancePath(inheritPathStr, specPath)
+		resolvedPath, err := v.resolveInheritancePath(inheritPathStr, specPath)
+		if err != nil {
+			v.addError("INHERITANCE_NOT_FOUND", fmt.Sprintf("Inherited specification not found: %v", err))
+			continue
+		}
+
+		if duplicates[i] {
+			warning := fmt.Sprintf("Duplicate inherits entry in %s: %s (merged once, at its last position)", specPath, inheritPathStr)
+			if !v.hasFinding(warning) {
+				v.addWarning("DUPLICATE_INHERITS", warning)
+			}
+			continue
+		}
+
+		nextChain := append(append(make([]string, 0, len(chain)+1), chain...), resolvedPath)
 
-		if _, exists := v.inheritedSpecs[resolvedPath]; exists {
-			continue // Already loaded
+		if resolvedPath == v.joinPath(specPath) {
+			v.addError("SELF_INHERITANCE", fmt.Sprintf("Specification inherits from itself: %s", specPath))
+			v.inheritance.failed = true
+			continue
 		}
 
-		inheritedSpec, err := v.loadSpec(resolvedPath)
-		if err != nil {
-			v.Errors = append(v.Errors, fmt.Sprintf("Inherited specification not found: %s", inheritPathStr))
+		if containsString(chain, resolvedPath) {
+			v.addError("CIRCULAR_INHERITANCE", fmt.Sprintf("Circular inheritance: %s", strings.Join(nextChain, " -> ")))
+			v.inheritance.failed = true
+			continue
+		}
+
+		if len(nextChain)-1 > v.Config.MaxInheritanceDepth {
+			v.addError("INHERITANCE_LIMIT", fmt.Sprintf("Inheritance depth limit of %d exceeded: %s", v.Config.MaxInheritanceDepth, strings.Join(nextChain, " -> ")))
+			v.inheritance.failed = true
+			continue
+		}
+
+		if len(nextChain)-1 > v.inheritance.depth {
+			v.inheritance.depth = len(nextChain) - 1
+		}
+
+		// Skip specs already explored through an equally long or longer chain
+		explored, seen := v.inheritance.explored[resolvedPath]
+		if seen && len(nextChain) <= explored {
 			continue
 		}
 
-		v.inheritedSpecs[resolvedPath] = inheritedSpec
+		if !seen && len(v.inheritance.explored) >= v.Config.MaxInheritedSpecs {
+			v.addError("INHERITANCE_LIMIT", fmt.Sprintf("Inherited specification limit of %d exceeded: %s", v.Config.MaxInheritedSpecs, strings.Join(nextChain, " -> ")))
+			v.inheritance.failed = true
+			continue
+		}
+		v.inheritance.explored[resolvedPath] = len(nextChain)
+
+		inheritedSpec, loaded := v.inheritedSpecs[resolvedPath]
+		if !loaded {
+			inheritedSpec, err = v.loadSpec(resolvedPath)
+			if err != nil {
+				v.addError("INHERITANCE_NOT_FOUND", fmt.Sprintf("Inherited specification not found: %s", inheritPathStr))
+				continue
+			}
+			v.rebaseFileReferences(inheritedSpec, resolvedPath)
+			v.inheritedSpecs[resolvedPath] = inheritedSpec
+		}
+		v.validateHierarchyLevels(spec, specPath, inheritedSpec, resolvedPath)
 
 		// Recursively load inherited specs
-		v.loadInheritedSpecs(inheritedSpec, resolvedPath)
+		v.loadInheritedSpecs(inheritedSpec, resolvedPath, nextChain)
+	}
+}
+
+// duplicateInherits returns the positions of inherits entries resolving
+// to the same specification as a later entry. Only the last occurrence is
+// merged, which keeps the precedence the list would have without them.
+func (v *APAIValidator) duplicateInherits(inherits []interface{}, specPath string) map[int]bool {
+	duplicates := make(map[int]bool)
+	seen := make(map[string]bool)
+	for i := len(inherits) - 1; i >= 0; i-- {
+		inheritPath, ok := inherits[i].(string)
+		if !ok {
+			continue
+		}
+		resolvedPath, err := v.resolveInheritancePath(inheritPath, specPath)
+		if err != nil {
+			continue
+		}
+		if seen[resolvedPath] {
+			duplicates[i] = true
+		}
+		seen[resolvedPath] = true
 	}
+	return duplicates
 }
 
 // deepMerge performs deep merge of two maps
@@ -927,12 +2281,56 @@ func (v *APAIValidator) deepMerge(base, override map[string]interface{}) map[str
 
 // mergeInheritedSpecifications merges specifications based on inheritance
 func (v *APAIValidator) mergeInheritedSpecifications(spec map[string]interface{}, specPath string) map[string]interface{} {
+	merged, _ := v.mergeInheritedSpecificationsContext(context.Background(), spec, specPath)
+	return merged
+}
+
+// mergeInheritedSpecificationsContext merges specifications based on
+// inheritance, stopping between inherited files once ctx is done
+func (v *APAIValidator) mergeInheritedSpecificationsContext(ctx context.Context, spec map[string]interface{}, specPath string) (map[string]interface{}, error) {
+	// Load inherited specifications, even for a cached merge, so that the
+	// findings about the hierarchy are reported again
+	v.inheritance = newInheritanceState()
+	v.inheritance.ctx = ctx
+	v.loadInheritedSpecs(spec, specPath, []string{specPath})
+	if v.inheritance.err != nil {
+		v.inheritedSpecs = make(map[string]map[string]interface{})
+		v.mergeCache = make(map[string]map[string]interface{})
+		return nil, v.inheritance.err
+	}
+
+	merged := v.mergeInheritedChain(spec, specPath, []string{specPath})
+	v.validateOverrides(spec, specPath, []string{specPath}, make(map[string]map[string]sectionDefinition))
+
+	// A run that hit a limit or a cycle only merged part of the hierarchy,
+	// so nothing it produced may be reused by later validations
+	if v.inheritance.failed {
+		v.inheritedSpecs = make(map[string]map[string]interface{})
+		v.mergeCache = make(map[string]map[string]interface{})
+	}
+
+	return merged, nil
+}
+
+// mergeInheritedChain merges the loaded parents of a specification
+func (v *APAIValidator) mergeInheritedChain(spec map[string]interface{}, specPath string, chain []string) map[string]interface{} {
 	if cached, exists := v.mergeCache[specPath]; exists {
 		return cached
 	}
 
-	// Load inherited specifications
-	v.loadInheritedSpecs(spec, specPath)
+	// Merged parents with parents of their own are also kept in the on-disk
+	// cache. The specification being validated is not: callers may change
+	// it before merging.
+	cacheKey := ""
+	if v.cache != nil && len(chain) > 1 && spec["inherits"] != nil && !v.inheritance.failed {
+		cacheKey = v.mergedCacheKey(spec, specPath, chain)
+		if cacheKey != "" {
+			if cached, ok := v.cache.load(mergedCacheKind, cacheKey); ok {
+				v.mergeCache[specPath] = cached
+				return cached
+			}
+		}
+	}
 
 	// Start with base specification
 	merged := make(map[string]interface{})
@@ -944,12 +2342,20 @@ func (v *APAIValidator) mergeInheritedSpecifications(spec map[string]interface{}
 	if inherits, exists := spec["inherits"]; exists {
 		if inheritsSlice, ok := inherits.([]interface{}); ok {
 			// Reverse the slice
+			duplicates := v.duplicateInherits(inheritsSlice, specPath)
 			for i := len(inheritsSlice) - 1; i >= 0; i-- {
-				inheritPath := inheritsSlice[i].(string)
-				resolvedPath := v.resolveInheritancePath(inheritPath, specPath)
+				inheritPath, ok := inheritsSlice[i].(string)
+				if !ok || duplicates[i] {
+					continue
+				}
+				resolvedPath, err := v.resolveInheritancePath(inheritPath, specPath)
+				if err != nil || containsString(chain, resolvedPath) {
+					continue
+				}
 				if inheritedSpec, exists := v.inheritedSpecs[resolvedPath]; exists {
 					// Recursively merge inherited spec
-					inheritedMerged := v.mergeInheritedSpecifications(inheritedSpec, resolvedPath)
+					nextChain := append(append(make([]string, 0, len(chain)+1), chain...), resolvedPath)
+					inheritedMerged := v.mergeInheritedChain(inheritedSpec, resolvedPath, nextChain)
 					merged = v.deepMerge(inheritedMerged, merged)
 				}
 			}
@@ -958,9 +2364,64 @@ func (v *APAIValidator) mergeInheritedSpecifications(spec map[string]interface{}
 
 	// Cache the result
 	v.mergeCache[specPath] = merged
+	if cacheKey != "" {
+		v.cache.store(mergedCacheKind, cacheKey, merged)
+	}
 	return merged
 }
 
+// mergedCacheKey returns the on-disk cache key of the merge of a loaded
+// parent with its own parents, made of their paths and the digests of their
+// files, or "" when one of them was not loaded from a file
+func (v *APAIValidator) mergedCacheKey(spec map[string]interface{}, specPath string, chain []string) string {
+	digest, ok := v.fileDigests[specPath]
+	if !ok {
+		return ""
+	}
+	parts := []string{specPath, digest}
+	inherits, _ := spec["inherits"].([]interface{})
+	duplicates := v.duplicateInherits(inherits, specPath)
+	for i, entry := range inherits {
+		inheritPath, ok := entry.(string)
+		if !ok || duplicates[i] {
+			continue
+		}
+		resolvedPath, err := v.resolveInheritancePath(inheritPath, specPath)
+		if err != nil || containsString(chain, resolvedPath) {
+			continue
+		}
+		inheritedSpec, exists := v.inheritedSpecs[resolvedPath]
+		if !exists {
+			return ""
+		}
+		nextChain := append(append(make([]string, 0, len(chain)+1), chain...), resolvedPath)
+		parentKey := v.mergedCacheKey(inheritedSpec, resolvedPath, nextChain)
+		if parentKey == "" {
+			return ""
+		}
+		parts = append(parts, parentKey)
+	}
+	return v.cache.key(mergedCacheKind, parts...)
+}
+
+// containsString reports whether a slice contains the given string
+func containsString(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+// elementName names an element in findings by its id, or its index without one
+func elementName(index int, element map[string]interface{}) string {
+	if id, ok := element["id"].(string); ok && id != "" {
+		return id
+	}
+	return fmt.Sprintf("%d", index)
+}
+
 // getHierarchyInfo extracts hierarchy information from specification
 func (v *APAIValidator) getHierarchyInfo(spec map[string]interface{}) map[string]interface{} {
 	info, exists := spec["info"]
@@ -998,60 +2459,53 @@ func (v *APAIValidator) getHierarchyInfo(spec map[string]interface{}) map[string
 
 // PrintHierarchyTree prints hierarchy tree for a specification
 func (v *APAIValidator) PrintHierarchyTree(specPath string, level int) {
-	indent := strings.Repeat("  ", level)
-
-	spec, err := v.loadSpec(specPath)
+	node, err := v.BuildHierarchyTree(specPath)
 	if err != nil {
-		fmt.Printf("%s❌ Error loading %s: %v\n", indent, specPath, err)
+		fmt.Printf("%s❌ Error loading %s: %v\n", strings.Repeat("  ", level), specPath, err)
 		return
 	}
+	printHierarchyNode(node, level)
+}
 
-	title := "Unknown"
-	if info, exists := spec["info"]; exists {
-		if infoMap, ok := info.(map[string]interface{}); ok {
-			if titleValue, exists := infoMap["title"]; exists {
-				if titleStr, ok := titleValue.(string); ok {
-					title = titleStr
-				}
-			}
-		}
+// printHierarchyNode prints a node of a hierarchy tree and its children
+func printHierarchyNode(node *HierarchyNode, level int) {
+	indent := strings.Repeat("  ", level)
+	if node.Error != "" {
+		fmt.Printf("%s❌ Error loading %s: %s\n", indent, node.Path, node.Error)
+		return
 	}
 
-	hierarchyInfo := v.getHierarchyInfo(spec)
-	levelName := "unknown"
-	scope := "unknown"
-
-	if levelValue, exists := hierarchyInfo["level"]; exists {
-		if levelStr, ok := levelValue.(string); ok {
-			levelName = levelStr
-		}
+	title, levelName, scope := node.Title, node.Level, node.Scope
+	if title == "" {
+		title = "Unknown"
 	}
-
-	if scopeValue, exists := hierarchyInfo["scope"]; exists {
-		if scopeStr, ok := scopeValue.(string); ok {
-			scope = scopeStr
-		}
+	if levelName == "" {
+		levelName = "unknown"
+	}
+	if scope == "" {
+		scope = "unknown"
 	}
-
 	fmt.Printf("%s📄 %s (%s/%s)\n", indent, title, levelName, scope)
-	fmt.Printf("%s   Path: %s\n", indent, specPath)
-
-	if inherits, exists := spec["inherits"]; exists {
-		if inheritsSlice, ok := inherits.([]interface{}); ok {
-			for _, inheritPath := range inheritsSlice {
-				if inheritPathStr, ok := inheritPath.(string); ok {
-					resolvedPath := v.resolveInheritancePath(inheritPathStr, specPath)
-					v.PrintHierarchyTree(resolvedPath, level+1)
-				}
-			}
-		}
+	fmt.Printf("%s   Path: %s\n", indent, node.Path)
+	for _, child := range node.Children {
+		printHierarchyNode(child, level+1)
 	}
 }
 
-// MergeSpecifications merges multiple specifications
+// MergeSpecifications merges multiple specifications and writes the result
 func (v *APAIValidator) MergeSpecifications(specs []map[string]interface{}, outputPath, format string) error {
+	merged, err := v.Merge(specs)
+	if err != nil {
+		return err
+	}
+
+	return WriteSpec(merged, outputPath, format)
+}
+
+// Merge deep-merges specifications, later ones overriding earlier ones
+func (v *APAIValidator) Merge(specs []map[string]interface{}) (map[string]interface{}, error) {
 	if len(specs) == 0 {
-		return fmt.Errorf("no specifications to merge")
+		return nil, fmt.Errorf("no specifications to merge")
 	}
 
 	// Start with first specification
@@ -1065,14 +2519,18 @@ func (v *APAIValidator) MergeSpecifications(specs []map[string]interface{}, outp
 		merged = v.deepMerge(merged, specs[i])
 	}
 
-	// Save merged specification
+	return merged, nil
+}
+
+// WriteSpec writes a specification to a file in canonical order
+func WriteSpec(spec map[string]interface{}, outputPath, format string) error {
 	var content []byte
 	var err error
 
 	if format == "yaml" {
-		content, err = yaml.Marshal(merged)
+		content, err = MarshalCanonicalYAML(spec)
 	} else {
-		content, err = json.MarshalIndent(merged, "", "  ")
+		content, err = MarshalCanonicalJSON(spec)
 	}
 
 	if err != nil {
@@ -1086,3 +2544,39 @@ func (v *APAIValidator) MergeSpecifications(specs []map[string]interface{}, outp
 
 	return nil
 }
+
+// looksLikeSpec performs a quick structural sniff of a document: it must
+// declare the apai version, or at least use known sections without looking
+// like another kind of manifest
+func looksLikeSpec(spec map[string]interface{}) bool {
+	return specRejection(spec) == ""
+}
+
+// specRejection returns why looksLikeSpec rejects a document, or "" when
+// it looks like a specification
+func specRejection(spec map[string]interface{}) string {
+	if _, exists := spec["apai"]; exists {
+		return ""
+	}
+	if len(spec) == 0 {
+		return "empty document"
+	}
+	for _, field := range []string{"apiVersion", "kind"} {
+		if _, exists := spec[field]; exists {
+			return fmt.Sprintf("no 'apai' key, and a top-level '%s' as in Kubernetes and other manifests", field)
+		}
+	}
+
+	for _, section := range sectionOrder {
+		if _, exists := spec[section]; exists {
+			return ""
+		}
+	}
+	return "no 'apai' key or known section such as info, models or tasks"
+}
+
+// sectionLength returns the number of entries in an array section
+func sectionLength(spec map[string]interface{}, section string) int {
+	sectionSlice, _ := spec[section].([]interface{})
+	return len(sectionSlice)
+}
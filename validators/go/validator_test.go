@@ -0,0 +1,1484 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+//go:embed testdata/embedded
+var embeddedSpecs embed.FS
+
+func loadExampleSpecs(t *testing.T, paths ...string) []map[string]interface{} {
+	t.Helper()
+
+	validator := NewAPAIValidator()
+	specs := make([]map[string]interface{}, 0, len(paths))
+	for _, path := range paths {
+		spec, err := validator.loadSpec(filepath.Join("..", "..", "examples", path))
+		if err != nil {
+			t.Fatalf("loading %s: %v", path, err)
+		}
+		specs = append(specs, spec)
+	}
+	return specs
+}
+
+func TestMergeSpecificationsWritesCanonicalOutput(t *testing.T) {
+	var base, override map[string]interface{}
+	if err := decodeYAML([]byte(`
+x-owner: platform
+tasks:
+  - steps: [{name: answer, action: generate}]
+    id: support
+models:
+  - purpose: conversation
+    id: main
+    name: gpt-4
+info: {version: "1.0.0", title: Base}
+apai: "0.1.0"
+`), &base); err != nil {
+		t.Fatal(err)
+	}
+	if err := decodeYAML([]byte(`
+models:
+  - {type: LLM, name: claude, id: fallback}
+  - {purpose: conversation, id: main, name: gpt-4}
+info: {title: Team}
+extensions: {zeta: true, alpha: false}
+`), &override); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"yaml": `apai: 0.1.0
+info:
+  title: Team
+  version: 1.0.0
+models:
+  - id: fallback
+    name: claude
+    type: LLM
+  - id: main
+    name: gpt-4
+    purpose: conversation
+tasks:
+  - id: support
+    steps:
+      - name: answer
+        action: generate
+extensions:
+  alpha: false
+  zeta: true
+x-owner: platform
+`,
+		"json": `{
+  "apai": "0.1.0",
+  "info": {
+    "title": "Team",
+    "version": "1.0.0"
+  },
+  "models": [
+    {
+      "id": "fallback",
+      "name": "claude",
+      "type": "LLM"
+    },
+    {
+      "id": "main",
+      "name": "gpt-4",
+      "purpose": "conversation"
+    }
+  ],
+  "tasks": [
+    {
+      "id": "support",
+      "steps": [
+        {
+          "name": "answer",
+          "action": "generate"
+        }
+      ]
+    }
+  ],
+  "extensions": {
+    "alpha": false,
+    "zeta": true
+  },
+  "x-owner": "platform"
+}`,
+	}
+
+	for _, format := range []string{"yaml", "json"} {
+		t.Run(format, func(t *testing.T) {
+			// Sections come in schema order, id and name lead their objects,
+			// and arrays keep the order of the spec they come from, whatever
+			// the order of the maps holding them
+			for run := 0; run < 2; run++ {
+				outputPath := filepath.Join(t.TempDir(), "merged."+format)
+				if err := NewAPAIValidator().MergeSpecifications([]map[string]interface{}{base, override}, outputPath, format); err != nil {
+					t.Fatalf("merge failed: %v", err)
+				}
+				content, err := ioutil.ReadFile(outputPath)
+				if err != nil {
+					t.Fatalf("reading merged output: %v", err)
+				}
+				if string(content) != want[format] {
+					t.Errorf("run %d wrote\n%s\nwant\n%s", run, content, want[format])
+				}
+			}
+		})
+	}
+}
+
+// largeSpec builds a synthetic specification with n entries per section,
+// every tenth entry carrying an issue
+func largeSpec(n int) map[string]interface{} {
+	models := make([]interface{}, 0, n)
+	prompts := make([]interface{}, 0, n)
+	constraints := make([]interface{}, 0, n)
+	tasks := make([]interface{}, 0, n)
+	for i := 0; i < n; i++ {
+		modelType := "LLM"
+		if i%10 == 0 {
+			modelType = "unknown"
+		}
+		models = append(models, map[string]interface{}{
+			"id": fmt.Sprintf("model_%d", i), "type": modelType, "provider": "openai",
+			"name": "gpt-4", "purpose": "conversation",
+		})
+		prompts = append(prompts, map[string]interface{}{
+			"id": fmt.Sprintf("prompt_%d", i), "role": "system", "template": "You are assistant {{name}}",
+		})
+		constraints = append(constraints, map[string]interface{}{
+			"id": fmt.Sprintf("constraint_%d", i), "rule": "output NOT contains harmful_content", "severity": "critical",
+		})
+		tasks = append(tasks, map[string]interface{}{
+			"id": fmt.Sprintf("task_%d", i), "description": "Process user queries",
+			"steps": []interface{}{
+				map[string]interface{}{"name": "answer", "action": "generate",
+					"model": fmt.Sprintf("model_%d", i), "prompt": fmt.Sprintf("prompt_%d", i)},
+			},
+		})
+	}
+
+	return map[string]interface{}{
+		"apai": "0.1.0",
+		"info": map[string]interface{}{
+			"title": "Large", "version": "1.0.0", "description": "Synthetic spec",
+			"author": "AI Team", "license": "MIT",
+		},
+		"models":      models,
+		"prompts":     prompts,
+		"constraints": constraints,
+		"tasks":       tasks,
+		"context":     map[string]interface{}{"memory": map[string]interface{}{}},
+		"evaluation":  map[string]interface{}{"metrics": []interface{}{}},
+	}
+}
+
+func TestParallelValidationMatchesSequential(t *testing.T) {
+	spec := largeSpec(200)
+
+	sequential := NewAPAIValidator(WithParallelValidation(false))
+	sequential.ValidateSpec(spec)
+
+	for run := 0; run < 5; run++ {
+		parallel := NewAPAIValidator()
+		parallel.ValidateSpec(spec)
+		if !reflect.DeepEqual(sequential.GetResults(), parallel.GetResults()) {
+			t.Fatalf("parallel results differ from sequential on run %d", run)
+		}
+	}
+	if len(sequential.Warnings) == 0 {
+		t.Error("expected warnings from the synthetic spec")
+	}
+}
+
+func BenchmarkValidateSpec(b *testing.B) {
+	spec := largeSpec(5000)
+	for _, parallel := range []bool{false, true} {
+		name := "sequential"
+		if parallel {
+			name = "parallel"
+		}
+		b.Run(name, func(b *testing.B) {
+			validator := NewAPAIValidator(WithParallelValidation(parallel))
+			for i := 0; i < b.N; i++ {
+				validator.ValidateSpec(spec)
+			}
+		})
+	}
+}
+
+func TestValidateWithInheritanceFromEmbedFS(t *testing.T) {
+	validator := NewAPAIValidator(WithFS(embeddedSpecs))
+
+	valid, err := validator.ValidateWithInheritance("testdata/embedded/team/app.yaml")
+	if err != nil {
+		t.Fatalf("validation failed: %v", err)
+	}
+	if !valid {
+		t.Fatalf("expected valid spec, got errors: %v", validator.GetErrors())
+	}
+	if len(validator.inheritedSpecs) != 1 {
+		t.Fatalf("expected 1 inherited spec, got %d", len(validator.inheritedSpecs))
+	}
+	if _, ok := validator.inheritedSpecs["testdata/embedded/org/base.yaml"]; !ok {
+		t.Errorf("inherits not resolved with slash paths: %v", validator.inheritedSpecs)
+	}
+}
+
+func TestValidatePromptExamples(t *testing.T) {
+	tests := []struct {
+		name     string
+		examples interface{}
+		errors   []string
+		warnings []string
+	}{
+		{
+			name: "valid",
+			examples: []interface{}{
+				map[string]interface{}{"input": map[string]interface{}{"company_name": "Acme"}, "output": "Hello from Acme"},
+				map[string]interface{}{"input": "Hi {{company_name}}", "output": "Hello"},
+				map[string]interface{}{"user": "Hi {{company_name}}", "assistant": map[string]interface{}{"greeting": "Hello"}},
+			},
+		},
+		{
+			name:     "empty",
+			examples: []interface{}{},
+			warnings: []string{"Prompt system_prompt examples is empty"},
+		},
+		{
+			name:     "not an array",
+			examples: "Hi",
+			errors:   []string{"Prompt system_prompt examples must be an array"},
+		},
+		{
+			name: "malformed entries",
+			examples: []interface{}{
+				"Hi",
+				map[string]interface{}{"output": "Hello"},
+				map[string]interface{}{"input": "Hi"},
+				nil,
+				map[string]interface{}{"assistant": "Hello"},
+				map[string]interface{}{"input": " ", "output": 42},
+			},
+			errors: []string{
+				"Prompt system_prompt example 0 must be an object",
+				"Prompt system_prompt example 1 missing required field: input",
+				"Prompt system_prompt example 3 must be an object",
+				"Prompt system_prompt example 4 missing required field: user",
+				"Prompt system_prompt example 5 field is empty: input",
+				"Prompt system_prompt example 5 output must be a string or an object",
+			},
+			warnings: []string{"Prompt system_prompt example 2 missing output"},
+		},
+		{
+			name:     "too many",
+			examples: repeatExamples(21),
+			warnings: []string{"Prompt system_prompt has 21 examples (about 60 tokens with the template), more than 20 are sent with every request"},
+		},
+		{
+			name: "undeclared variables",
+			examples: []interface{}{
+				map[string]interface{}{"input": map[string]interface{}{"user_name": "Ann"}, "output": "Hello Ann"},
+				map[string]interface{}{"input": "Order {{order_id}}", "output": "Shipped"},
+			},
+			errors: []string{
+				"Prompt system_prompt example 0 references undeclared variable: user_name",
+				"Prompt system_prompt example 1 references undeclared variable: order_id",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prompt := map[string]interface{}{
+				"id":        "system_prompt",
+				"role":      "system",
+				"template":  "You are a helpful assistant for {{company_name}}",
+				"variables": map[string]interface{}{"company_name": map[string]interface{}{"type": "string"}},
+				"examples":  tt.examples,
+			}
+
+			var findings sectionFindings
+			NewAPAIValidator().validatePrompts(&findings, []interface{}{prompt})
+			if !reflect.DeepEqual(findings.Errors, tt.errors) {
+				t.Errorf("errors = %v, want %v", findings.Errors, tt.errors)
+			}
+			if !reflect.DeepEqual(findings.Warnings, tt.warnings) {
+				t.Errorf("warnings = %v, want %v", findings.Warnings, tt.warnings)
+			}
+		})
+	}
+}
+
+func TestUnusedExamplePrompts(t *testing.T) {
+	examples := []interface{}{map[string]interface{}{"input": "Hi", "output": "Hello"}}
+	spec := map[string]interface{}{
+		"prompts": []interface{}{
+			map[string]interface{}{"id": "used", "role": "system", "template": "Answer", "examples": examples, "next": "chained"},
+			map[string]interface{}{"id": "chained", "role": "system", "template": "Summarize", "examples": examples},
+			map[string]interface{}{"id": "orphan", "role": "system", "template": "Greet", "examples": examples},
+			map[string]interface{}{"id": "user_turn", "role": "user", "template": "Hi", "examples": examples},
+		},
+		"tasks": []interface{}{
+			map[string]interface{}{"id": "support", "steps": []interface{}{
+				map[string]interface{}{"name": "answer", "prompt": "used"},
+			}},
+		},
+	}
+
+	validator := NewAPAIValidator()
+	validator.ValidateSpec(spec)
+	unused := make([]string, 0)
+	for _, warning := range validator.Warnings {
+		if rule, _ := MatchRule(warning); rule.Code == "UNUSED_EXAMPLES" {
+			unused = append(unused, warning)
+		}
+	}
+	if want := []string{"Prompt orphan has examples but is a system prompt no task uses"}; !reflect.DeepEqual(unused, want) {
+		t.Errorf("got %v, want %v", unused, want)
+	}
+}
+
+// repeatExamples returns n identical few-shot examples
+func repeatExamples(n int) []interface{} {
+	examples := make([]interface{}, n)
+	for i := range examples {
+		examples[i] = map[string]interface{}{"input": "Hi", "output": "Hello"}
+	}
+	return examples
+}
+
+func TestInputFormat(t *testing.T) {
+	base, err := embeddedSpecs.ReadFile("testdata/embedded/org/base.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys := fstest.MapFS{
+		"spec.txt":      {Data: base},
+		"child.tmp":     {Data: []byte("inherits: [\"org/base.yaml\"]\n")},
+		"org/base.yaml": {Data: base},
+	}
+
+	validator := NewAPAIValidator(WithFS(fsys))
+	if _, err := validator.ValidateFile("spec.txt"); err == nil || err.Error() != "unsupported file format: .txt" {
+		t.Errorf("expected the extension to be rejected, got %v", err)
+	}
+
+	validator = NewAPAIValidator(WithFS(fsys), WithInputFormat("yaml"))
+	if valid, err := validator.ValidateFile("spec.txt"); err != nil || !valid {
+		t.Errorf("expected spec.txt to be valid as YAML, got %v %v %v", valid, err, validator.Errors)
+	}
+	// Only the validated file is forced; its parents are parsed by extension
+	if valid, err := validator.ValidateWithInheritance("child.tmp"); err != nil || !valid {
+		t.Errorf("expected child.tmp to be valid as YAML, got %v %v %v", valid, err, validator.Errors)
+	}
+
+	validator = NewAPAIValidator(WithFS(fsys), WithInputFormat("json"))
+	_, err = validator.ValidateFile("spec.txt")
+	if err == nil || !strings.HasPrefix(err.Error(), "spec.txt: JSON parsing error: ") || !strings.HasSuffix(err.Error(), "(input format forced to json)") {
+		t.Errorf("expected a forced JSON parsing error, got %v", err)
+	}
+
+	validator = NewAPAIValidator()
+	if valid, err := validator.ValidateReader(context.Background(), bytes.NewReader(base), "yaml"); err != nil || !valid {
+		t.Errorf("expected the YAML reader to be valid, got %v %v %v", valid, err, validator.Errors)
+	}
+	if _, err := validator.ValidateBytes(context.Background(), []byte(`{"apai": "0.1.0"}`), "json"); err != nil || len(validator.Errors) == 0 {
+		t.Errorf("expected findings for an incomplete JSON spec, got %v %v", err, validator.Errors)
+	}
+	if _, err := validator.ValidateBytes(context.Background(), base, "toml"); err == nil || err.Error() != "unsupported input format: toml (expected yaml or json)" {
+		t.Errorf("expected an unsupported format error, got %v", err)
+	}
+}
+
+func TestValidateContextCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	validator := NewAPAIValidator(WithFS(embeddedSpecs))
+	if _, err := validator.ValidateFileContext(ctx, "testdata/embedded/org/base.yaml"); !errors.Is(err, context.Canceled) {
+		t.Errorf("ValidateFileContext error = %v, want context.Canceled", err)
+	}
+	if _, err := validator.ValidateWithInheritanceContext(ctx, "testdata/embedded/team/app.yaml"); !errors.Is(err, context.Canceled) {
+		t.Errorf("ValidateWithInheritanceContext error = %v, want context.Canceled", err)
+	}
+
+	checks, err := validator.CheckMCPServersContext(ctx, map[string]interface{}{
+		"context": map[string]interface{}{
+			"mcp_servers": []interface{}{
+				map[string]interface{}{"id": "files", "transport": map[string]interface{}{"type": "stdio", "command": "go"}},
+			},
+		},
+	})
+	if !errors.Is(err, context.Canceled) || len(checks) != 0 {
+		t.Errorf("CheckMCPServersContext = %v, %v, want no checks and context.Canceled", checks, err)
+	}
+
+	// A batch stops after the file in progress
+	results := make([]FileResult, 0)
+	for result := range validator.ValidateFilesStreamContext(ctx, []string{"testdata/embedded/org/base.yaml", "testdata/embedded/team/app.yaml"}) {
+		results = append(results, result)
+	}
+	if len(results) != 1 || !errors.Is(results[0].Err, context.Canceled) {
+		t.Errorf("ValidateFilesStreamContext = %+v, want one result with context.Canceled", results)
+	}
+
+	if files, err := SpecFiles(ctx, []string{"testdata"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("SpecFiles = %v, %v, want context.Canceled", files, err)
+	}
+}
+
+func TestResolveSpecContextStopsBetweenInheritedFiles(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Cancel once the child is loaded, before its parent is read
+	validator := NewAPAIValidator(WithFS(cancelOnRead{FS: embeddedSpecs, cancel: cancel}))
+	_, err := validator.ResolveSpecContext(ctx, "testdata/embedded/team/app.yaml")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("error = %v, want context.Canceled", err)
+	}
+	if len(validator.inheritedSpecs) != 0 {
+		t.Errorf("partial inheritance was cached: %v", validator.inheritedSpecs)
+	}
+}
+
+// cancelOnRead cancels a context when the first file is opened
+type cancelOnRead struct {
+	fs.FS
+	cancel context.CancelFunc
+}
+
+func (c cancelOnRead) Open(name string) (fs.File, error) {
+	c.cancel()
+	return c.FS.Open(name)
+}
+
+func TestIssueHandlerMatchesFinalResult(t *testing.T) {
+	for _, hierarchical := range []bool{false, true} {
+		issues := make([]Issue, 0)
+		validator := NewAPAIValidator(WithFS(embeddedSpecs), WithIssueHandler(func(issue Issue) {
+			issues = append(issues, issue)
+		}))
+
+		var err error
+		if hierarchical {
+			_, err = validator.ValidateWithInheritance("testdata/embedded/team/app.yaml")
+		} else {
+			_, err = validator.ValidateFile("testdata/embedded/team/app.yaml")
+		}
+		if err != nil {
+			t.Fatalf("validation failed: %v", err)
+		}
+
+		streamed := ValidationResult{Valid: true, Errors: make([]string, 0), Warnings: make([]string, 0)}
+		for _, issue := range issues {
+			if issue.Severity == "error" {
+				streamed.Errors = append(streamed.Errors, issue.Message)
+				streamed.Valid = false
+			} else {
+				streamed.Warnings = append(streamed.Warnings, issue.Message)
+			}
+			streamed.codes = withCode(streamed.codes, issue.Code, issue.Message)
+		}
+		if !reflect.DeepEqual(streamed, validator.GetResults()) {
+			t.Errorf("hierarchical=%t: streamed issues %v differ from result %v", hierarchical, streamed, validator.GetResults())
+		}
+	}
+}
+
+func TestRelaxedCodes(t *testing.T) {
+	spec := loadExampleSpecs(t, "templates/basic-template.yaml")[0]
+	delete(spec, "evaluation")
+	spec["prompts"].([]interface{})[0].(map[string]interface{})["template"] = "Hello {{customer}}"
+
+	issues := make([]Issue, 0)
+	validator := NewAPAIValidator(WithRelaxedCodes("missing_section"), WithIssueHandler(func(issue Issue) {
+		issues = append(issues, issue)
+	}))
+	validator.ValidateSpec(spec)
+	relaxed := "Missing required section: evaluation"
+	if want := []string{"Prompt 0 template references undeclared variable: customer"}; !reflect.DeepEqual(validator.Errors, want) {
+		t.Errorf("expected %v, got %v", want, validator.Errors)
+	}
+	if !containsString(validator.Warnings, relaxed) {
+		t.Errorf("missing %q in %v", relaxed, validator.Warnings)
+	}
+	for _, issue := range issues {
+		if issue.Message == relaxed && (issue.Severity != "warning" || issue.Code != "MISSING_SECTION") {
+			t.Errorf("expected the relaxed finding to be streamed as a MISSING_SECTION warning, got %+v", issue)
+		}
+	}
+
+	// A code matching no rule relaxes nothing and says so
+	validator = NewAPAIValidator(WithRelaxedCodes("NO_SUCH_RULE"))
+	if validator.ValidateSpec(spec) {
+		t.Error("expected the unrelaxed error to fail validation")
+	}
+	if want := "Relaxed code matches no rule: NO_SUCH_RULE"; !containsString(validator.Warnings, want) {
+		t.Errorf("missing %q in %v", want, validator.Warnings)
+	}
+	if !containsString(validator.Errors, relaxed) {
+		t.Errorf("missing %q in %v", relaxed, validator.Errors)
+	}
+
+	for codes, want := range map[string]string{
+		"MISSING_SECTION,undeclared_variable": "",
+		"NO_SUCH_RULE":                        "",
+		"UNUSED_MCP_SERVER":                   "--relax: UNUSED_MCP_SERVER is already a warning",
+	} {
+		config, err := loadCLIConfig([]string{"--relax", codes, "--config", "testdata/relax.yaml"})
+		if want == "" {
+			if wantRelax := append([]string{"SCHEMA_VIOLATION"}, strings.Split(codes, ",")...); err != nil || !reflect.DeepEqual(config.Relax, wantRelax) {
+				t.Errorf("--relax %s: got %v, %v", codes, config.Relax, err)
+			}
+		} else if err == nil || err.Error() != want {
+			t.Errorf("--relax %s: expected %q, got %v", codes, want, err)
+		}
+	}
+}
+
+func TestSeverityResolvedBeforeValidity(t *testing.T) {
+	spec := loadExampleSpecs(t, "templates/basic-template.yaml")[0]
+	delete(spec, "evaluation")
+
+	// Without an issue handler, relaxed findings are warnings all the same
+	validator := NewAPAIValidator(WithRelaxedCodes("MISSING_SECTION"))
+	if !validator.ValidateSpec(spec) {
+		t.Errorf("expected the relaxed error to leave the spec valid, got %v", validator.Errors)
+	}
+	result, err := validator.Validate(context.Background(), spec)
+	if err != nil || !result.Valid || !containsString(result.Warnings, "Missing required section: evaluation") {
+		t.Errorf("Validate: got %+v, %v", result, err)
+	}
+}
+
+func TestPromotedCodes(t *testing.T) {
+	spec := loadExampleSpecs(t, "templates/basic-template.yaml")[0]
+	delete(spec["info"].(map[string]interface{}), "contact")
+	noContact := "No email or url in info.author, info.contact or info.owners to reach the people responsible for the specification"
+
+	issues := make([]Issue, 0)
+	validator := NewAPAIValidator(WithPromotedCodes("no_contact"), WithIssueHandler(func(issue Issue) {
+		issues = append(issues, issue)
+	}))
+	if validator.ValidateSpec(spec) {
+		t.Error("expected the promoted warning to fail validation")
+	}
+	if want := []string{noContact}; !reflect.DeepEqual(validator.Errors, want) {
+		t.Errorf("expected %v, got %v", want, validator.Errors)
+	}
+	if containsString(validator.Warnings, noContact) {
+		t.Errorf("unexpected warning %q", noContact)
+	}
+	for _, issue := range issues {
+		if issue.Message == noContact && (issue.Severity != "error" || issue.Code != "NO_CONTACT") {
+			t.Errorf("expected the promoted finding to be streamed as a NO_CONTACT error, got %+v", issue)
+		}
+	}
+
+	// A code matching no rule promotes nothing and says so
+	validator = NewAPAIValidator(WithPromotedCodes("NO_SUCH_RULE"))
+	if !validator.ValidateSpec(spec) {
+		t.Errorf("unexpected errors %v", validator.Errors)
+	}
+	for _, want := range []string{noContact, "Promoted code matches no rule: NO_SUCH_RULE"} {
+		if !containsString(validator.Warnings, want) {
+			t.Errorf("missing %q in %v", want, validator.Warnings)
+		}
+	}
+
+	config, err := loadCLIConfig([]string{"--error-on", "NO_CONTACT", "--error-on", "unused_mcp_server", "--warn-on", "MISSING_SECTION", "--config", "testdata/relax.yaml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"NO_CONTACT", "unused_mcp_server"}; !reflect.DeepEqual(config.Promote, want) {
+		t.Errorf("promote: expected %v, got %v", want, config.Promote)
+	}
+	if want := []string{"SCHEMA_VIOLATION", "MISSING_SECTION"}; !reflect.DeepEqual(config.Relax, want) {
+		t.Errorf("relax: expected %v, got %v", want, config.Relax)
+	}
+	want := "--error-on: MISSING_SECTION is both promoted to an error and relaxed to a warning"
+	if _, err := loadCLIConfig([]string{"--error-on", "MISSING_SECTION", "--warn-on", "missing_section"}); err == nil || err.Error() != want {
+		t.Errorf("expected %q, got %v", want, err)
+	}
+	// Unknown codes warn when validating, whichever flag names them
+	config, err = loadCLIConfig([]string{"--warn-on", "NO_SUCH_RULE"})
+	if err != nil || !containsString(config.Relax, "NO_SUCH_RULE") {
+		t.Errorf("--warn-on NO_SUCH_RULE: got %v, %v", config.Relax, err)
+	}
+}
+
+func TestValidateFilesStream(t *testing.T) {
+	paths := []string{"testdata/embedded/org/base.yaml", "testdata/embedded/team/app.yaml", "testdata/embedded/missing.yaml"}
+	validator := NewAPAIValidator(WithFS(embeddedSpecs))
+
+	results := make([]FileResult, 0, len(paths))
+	for result := range validator.ValidateFilesStream(paths) {
+		results = append(results, result)
+	}
+
+	if len(results) != len(paths) {
+		t.Fatalf("got %d results, want %d", len(results), len(paths))
+	}
+	for i, result := range results {
+		if result.Path != paths[i] {
+			t.Errorf("result %d path = %s, want %s", i, result.Path, paths[i])
+		}
+	}
+	if !results[0].Valid || results[1].Valid || results[2].Err == nil {
+		t.Errorf("unexpected results: %+v", results)
+	}
+}
+
+func TestRequiredFieldsMustNotBeBlank(t *testing.T) {
+	spec := loadExampleSpecs(t, "templates/basic-template.yaml")[0]
+	spec["info"].(map[string]interface{})["title"] = "   "
+	spec["models"].([]interface{})[0].(map[string]interface{})["purpose"] = ""
+	spec["prompts"].([]interface{})[0].(map[string]interface{})["template"] = "\n"
+	spec["tasks"].([]interface{})[0].(map[string]interface{})["description"] = ""
+
+	validator := NewAPAIValidator()
+	if validator.ValidateSpec(spec) {
+		t.Fatal("expected blank required fields to fail validation")
+	}
+	for _, want := range []string{
+		"Required field in info is empty: title",
+		"Model 0 required field is empty: purpose",
+		"Prompt 0 required field is empty: template",
+		"Task 0 required field is empty: description",
+	} {
+		if !containsString(validator.Errors, want) {
+			t.Errorf("missing %q in %v", want, validator.Errors)
+		}
+	}
+}
+
+func TestIncludeFragments(t *testing.T) {
+	base, err := embeddedSpecs.ReadFile("testdata/embedded/org/base.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys := fstest.MapFS{
+		"org/base.yaml":      {Data: base},
+		"app/spec.yaml":      {Data: []byte("$include: ../org/base.yaml\nmodels: !include ../shared/models.yaml\n")},
+		"shared/models.yaml": {Data: []byte("- id: main_model\n  type: LLM\n  provider: openai\n  name: gpt-4-turbo\n  purpose: conversation\n")},
+		"cycle/a.yaml":       {Data: []byte("models: !include b.yaml\n")},
+		"cycle/b.yaml":       {Data: []byte("$include: a.yaml\n")},
+	}
+	validator := NewAPAIValidator(WithFS(fsys))
+
+	spec, err := validator.loadSpec("app/spec.yaml")
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	models, _ := spec["models"].([]interface{})
+	if len(models) != 1 || models[0].(map[string]interface{})["name"] != "gpt-4-turbo" {
+		t.Errorf("models fragment not inlined over the base: %v", spec["models"])
+	}
+	if valid, err := validator.ValidateFile("app/spec.yaml"); err != nil || !valid {
+		t.Errorf("expected composed spec to be valid, got %v %v %v", valid, err, validator.Errors)
+	}
+
+	_, err = validator.loadSpec("cycle/a.yaml")
+	if err == nil || !strings.Contains(err.Error(), "include cycle: cycle/a.yaml -> cycle/b.yaml -> cycle/a.yaml") {
+		t.Errorf("expected include cycle error, got %v", err)
+	}
+}
+
+func TestMCPCredentialsMustNotBeLiteral(t *testing.T) {
+	spec := loadExampleSpecs(t, "automation/mcp-integration.yaml")[0]
+	servers := spec["context"].(map[string]interface{})["mcp_servers"].([]interface{})
+	servers[0].(map[string]interface{})["authentication"].(map[string]interface{})["api_key"] = "sk-live-4f9a8b7c6d5e"
+	servers[1].(map[string]interface{})["authentication"].(map[string]interface{})["token"] = "vault://kb/access_token"
+
+	validator := NewAPAIValidator()
+	validator.ValidateSpec(spec)
+	want := "MCP server 0 authentication api_key looks like a literal secret, use an ${ENV} reference or vault:// placeholder"
+	if !containsString(validator.Warnings, want) {
+		t.Errorf("missing %q in %v", want, validator.Warnings)
+	}
+	for _, warning := range validator.Warnings {
+		if strings.HasPrefix(warning, "MCP server 1 authentication token") {
+			t.Errorf("vault placeholder reported as a literal secret: %s", warning)
+		}
+	}
+
+	// A reference inside a literal leaves the rest of the secret in the spec
+	servers[0].(map[string]interface{})["authentication"].(map[string]interface{})["api_key"] = "sk-live-abc${X}"
+	validator.ValidateSpec(spec)
+	if !containsString(validator.Warnings, want) {
+		t.Errorf("embedded reference: missing %q in %v", want, validator.Warnings)
+	}
+}
+
+func TestIsSecretReference(t *testing.T) {
+	for value, want := range map[string]bool{
+		"${MCP_API_KEY}":       true,
+		"vault://mcp/api_key":  true,
+		redactedValue:          true,
+		"sk-live-abc${X}":      false,
+		"${PREFIX}sk-live-abc": false,
+		"${A}${B}":             false,
+		"sk-live-4f9a8b7c6d5e": false,
+		"":                     false,
+	} {
+		if got := isSecretReference(value); got != want {
+			t.Errorf("isSecretReference(%q) = %t, want %t", value, got, want)
+		}
+	}
+}
+
+func TestPromptTemplateFiles(t *testing.T) {
+	base, err := embeddedSpecs.ReadFile("testdata/embedded/org/base.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec := strings.Replace(string(base), `template: "You are a helpful AI assistant for {{company_name}}"`, `template_file: "prompts/system.md"`, 1)
+	fsys := fstest.MapFS{
+		"specs/app.yaml":          {Data: []byte(spec)},
+		"specs/prompts/system.md": {Data: []byte("You are a support assistant for {{company_name}} in {{region}}.\n")},
+	}
+
+	validator := NewAPAIValidator(WithFS(fsys))
+	if valid, err := validator.ValidateFile("specs/app.yaml"); err != nil || valid {
+		t.Fatalf("expected undeclared template variable to fail, got %v %v", valid, err)
+	}
+	if want := "Prompt 0 template references undeclared variable: region"; !reflect.DeepEqual(validator.Errors, []string{want}) {
+		t.Errorf("expected only %q, got %v", want, validator.Errors)
+	}
+
+	delete(fsys, "specs/prompts/system.md")
+	validator.ValidateFile("specs/app.yaml")
+	if want := "Prompt system_prompt template_file not found: specs/prompts/system.md"; !containsString(validator.Errors, want) {
+		t.Errorf("missing %q in %v", want, validator.Errors)
+	}
+
+	fsys["specs/app.yaml"] = &fstest.MapFile{Data: []byte(strings.Replace(spec, `template_file:`, "template: \"Hi\"\n    template_file:", 1))}
+	validator.ValidateFile("specs/app.yaml")
+	if want := "Prompt system_prompt has both template and template_file"; !containsString(validator.Errors, want) {
+		t.Errorf("missing %q in %v", want, validator.Errors)
+	}
+}
+
+func TestSharedTemplateVariables(t *testing.T) {
+	spec := loadExampleSpecs(t, "templates/basic-template.yaml")[0]
+	prompt := spec["prompts"].([]interface{})[0].(map[string]interface{})
+	prompt["template"] = "You are an assistant for {{company_name}} in {{region}}. Answer: {{user_message}} {{ticket}}"
+	prompt["examples"] = []interface{}{map[string]interface{}{"input": "{{region}} {{locale}}", "output": "ok"}}
+	spec["context"].(map[string]interface{})["variables"] = map[string]interface{}{
+		"region":   map[string]interface{}{"type": "string"},
+		"locale":   map[string]interface{}{"type": "string"},
+		"timezone": map[string]interface{}{"type": "string"},
+	}
+
+	validator := NewAPAIValidator()
+	validator.ValidateSpec(spec)
+	// company_name is declared, region global and user_message an input of the task
+	if want := []string{"Prompt 0 template references undeclared variable: ticket"}; !reflect.DeepEqual(validator.Errors, want) {
+		t.Errorf("expected %v, got %v", want, validator.Errors)
+	}
+	want := "Context variable timezone is not used by any prompt"
+	if !containsString(validator.Warnings, want) {
+		t.Errorf("missing %q in %v", want, validator.Warnings)
+	}
+	if code := newIssue("warning", want).Code; code != "UNUSED_VARIABLE" {
+		t.Errorf("expected UNUSED_VARIABLE, got %q", code)
+	}
+	for _, warning := range validator.Warnings {
+		if strings.HasPrefix(warning, "Context variable ") && warning != want {
+			t.Errorf("unexpected %q", warning)
+		}
+	}
+
+	spec["context"].(map[string]interface{})["variables"] = []interface{}{"region"}
+	validator.ValidateSpec(spec)
+	if !containsString(validator.Errors, "context.variables must be an object") {
+		t.Errorf("expected non-object variables to fail, got %v", validator.Errors)
+	}
+}
+
+func TestStepOperationSettings(t *testing.T) {
+	spec := loadExampleSpecs(t, "templates/basic-template.yaml")[0]
+	steps := spec["tasks"].([]interface{})[0].(map[string]interface{})["steps"].([]interface{})
+	steps[0].(map[string]interface{})["retry"] = -1
+	steps[0].(map[string]interface{})["timeout"] = 30
+	steps[1].(map[string]interface{})["retry"] = 25
+	steps[1].(map[string]interface{})["timeout"] = "2h"
+
+	validator := NewAPAIValidator()
+	validator.ValidateSpec(spec)
+	for _, want := range []string{
+		"Task 0 step 0 retry must be a non-negative integer, got -1",
+		`Task 0 step 0 timeout must be a positive duration such as "30s" or "5m", got 30`,
+	} {
+		if !containsString(validator.Errors, want) {
+			t.Errorf("missing %q in %v", want, validator.Errors)
+		}
+	}
+	for _, want := range []string{
+		"Task 0 step 1 retry of 25 is unusually high (over 10)",
+		"Task 0 step 1 timeout of 2h is unusually long (over 1h)",
+	} {
+		if !containsString(validator.Warnings, want) {
+			t.Errorf("missing %q in %v", want, validator.Warnings)
+		}
+	}
+}
+
+func TestSetFSSwitchesFilesystem(t *testing.T) {
+	validator := NewAPAIValidator(WithFS(embeddedSpecs))
+	if valid, err := validator.ValidateWithInheritance("testdata/embedded/team/app.yaml"); err != nil || !valid {
+		t.Fatalf("expected embedded spec to be valid, got %v %v %v", valid, err, validator.Errors)
+	}
+
+	// The same paths in another filesystem must not hit the inheritance cache
+	validator.SetFS(fstest.MapFS{
+		"testdata/embedded/team/app.yaml": {Data: []byte("inherits:\n  - ../org/base.yaml\n")},
+	})
+	valid, err := validator.ValidateWithInheritance("testdata/embedded/team/app.yaml")
+	if err != nil || valid {
+		t.Fatalf("expected missing parent to fail, got %v %v", valid, err)
+	}
+	if want := "Inherited specification not found: ../org/base.yaml"; !containsString(validator.Errors, want) {
+		t.Errorf("missing %q in %v", want, validator.Errors)
+	}
+}
+
+func TestHierarchyLevelConsistency(t *testing.T) {
+	spec := func(level string, parents ...string) *fstest.MapFile {
+		content := fmt.Sprintf("apai: \"0.1.0\"\ninfo:\n  ai_metadata:\n    hierarchy_info:\n      level: %q\n", level)
+		if len(parents) > 0 {
+			content += "inherits: [\"" + strings.Join(parents, "\", \"") + "\"]\n"
+		}
+		return &fstest.MapFile{Data: []byte(content)}
+	}
+	fsys := fstest.MapFS{
+		"global.yaml":     spec("global"),
+		"department.yaml": spec("department", "global.yaml"),
+		"team.yaml":       spec("team", "department.yaml"),
+		"feature.yaml":    spec("feature", "team.yaml"),
+		"inverted.yaml":   spec("department", "team.yaml"),
+		"custom.yaml":     spec("agent", "team.yaml"),
+		"rooted.yaml":     spec("global", "custom.yaml"),
+	}
+	validator := NewAPAIValidator(WithFS(fsys))
+
+	tests := []struct {
+		path     string
+		warnings []string
+	}{
+		{"team.yaml", []string{"Hierarchy level skip: department.yaml (department) inherits from global.yaml (global), skipping regional"}},
+		{"feature.yaml", []string{
+			"Hierarchy level skip: feature.yaml (feature) inherits from team.yaml (team), skipping sprint",
+			"Hierarchy level skip: department.yaml (department) inherits from global.yaml (global), skipping regional",
+		}},
+		{"inverted.yaml", []string{
+			"Hierarchy level inversion: inverted.yaml (department) inherits from team.yaml (team)",
+			"Hierarchy level skip: department.yaml (department) inherits from global.yaml (global), skipping regional",
+		}},
+		{"custom.yaml", []string{"Hierarchy level skip: department.yaml (department) inherits from global.yaml (global), skipping regional"}},
+		{"rooted.yaml", []string{
+			"Hierarchy level inversion: rooted.yaml (global) inherits from custom.yaml, but global is the broadest level",
+			"Hierarchy level skip: department.yaml (department) inherits from global.yaml (global), skipping regional",
+		}},
+		// A cached merge reports the same findings
+		{"team.yaml", []string{"Hierarchy level skip: department.yaml (department) inherits from global.yaml (global), skipping regional"}},
+	}
+	for _, tt := range tests {
+		validator.ValidateWithInheritance(tt.path)
+		for _, want := range tt.warnings {
+			if !containsString(validator.Warnings, want) {
+				t.Errorf("%s: missing %q in %v", tt.path, want, validator.Warnings)
+			}
+		}
+		for _, warning := range validator.Warnings {
+			if strings.HasPrefix(warning, "Hierarchy level ") && !containsString(tt.warnings, warning) {
+				t.Errorf("%s: unexpected %q", tt.path, warning)
+			}
+		}
+	}
+}
+
+func TestBuildHierarchyTree(t *testing.T) {
+	fsys := fstest.MapFS{
+		"org.yaml":    {Data: []byte("info:\n  title: Org\n  ai_metadata:\n    hierarchy_info:\n      level: global\n      scope: organization\n")},
+		"team.yaml":   {Data: []byte("info:\n  title: Team\ninherits: [\"org.yaml\", \"missing.yaml\"]\n")},
+		"a.yaml":      {Data: []byte("info:\n  title: A\ninherits: [\"b.yaml\"]\n")},
+		"b.yaml":      {Data: []byte("info:\n  title: B\ninherits: [\"a.yaml\"]\n")},
+		"broken.yaml": {Data: []byte("inherits: [\"bad.yaml\"]\n")},
+		"bad.yaml":    {Data: []byte("info: [\n")},
+	}
+	validator := NewAPAIValidator(WithFS(fsys))
+
+	tree, err := validator.BuildHierarchyTree("team.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tree.Title != "Team" || tree.Path != "team.yaml" || len(tree.Children) != 2 {
+		t.Fatalf("unexpected root %+v", tree)
+	}
+	org := tree.Children[0]
+	if org.Title != "Org" || org.Level != "global" || org.Scope != "organization" || org.Path != "org.yaml" || org.Error != "" || len(org.Children) != 0 {
+		t.Errorf("unexpected parent %+v", org)
+	}
+	if missing := tree.Children[1]; missing.Path != "missing.yaml" || missing.Error == "" {
+		t.Errorf("expected an unresolved parent, got %+v", missing)
+	}
+
+	tree, err = validator.BuildHierarchyTree("a.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tree.Children) != 1 || len(tree.Children[0].Children) != 1 {
+		t.Fatalf("unexpected tree %+v", tree)
+	}
+	if cycle := tree.Children[0].Children[0]; cycle.Path != "a.yaml" || cycle.Error != "circular inheritance" {
+		t.Errorf("expected a circular inheritance node, got %+v", cycle)
+	}
+
+	tree, err = validator.BuildHierarchyTree("broken.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tree.Children) != 1 || tree.Children[0].Path != "bad.yaml" || tree.Children[0].Error == "" {
+		t.Errorf("expected a parent that fails to load, got %+v", tree.Children)
+	}
+
+	if _, err := validator.BuildHierarchyTree("nothing.yaml"); err == nil {
+		t.Error("expected an error for a missing root")
+	}
+}
+
+func TestHierarchyInfoFields(t *testing.T) {
+	spec := map[string]interface{}{
+		"inherits": []interface{}{"../team.yaml"},
+		"info": map[string]interface{}{
+			"ai_metadata": map[string]interface{}{
+				"hierarchy_info": map[string]interface{}{"level": "organisation", "scope": " ", "parent": 3, "extends": "base"},
+			},
+		},
+	}
+	validator := NewAPAIValidator()
+	validator.ValidateSpec(spec)
+	for _, want := range []string{
+		"Field is empty: info.ai_metadata.hierarchy_info.scope",
+		"info.ai_metadata.hierarchy_info.parent must be a string",
+	} {
+		if !containsString(validator.Errors, want) {
+			t.Errorf("missing %q in %v", want, validator.Errors)
+		}
+	}
+	if want := "Unknown hierarchy level: organisation (expected one of global, regional, department, team, sprint, feature, environment)"; !containsString(validator.Warnings, want) {
+		t.Errorf("missing %q in %v", want, validator.Warnings)
+	}
+	for _, message := range validator.Errors {
+		if strings.Contains(message, "hierarchy_info.extends") {
+			t.Errorf("unexpected %q", message)
+		}
+	}
+
+	// Without its parents, a spec at the broadest level cannot inherit at all
+	spec["info"].(map[string]interface{})["ai_metadata"] = map[string]interface{}{
+		"hierarchy_info": map[string]interface{}{"level": "Global", "scope": "organization"},
+	}
+	validator.ValidateSpec(spec)
+	for _, want := range []string{
+		`Non-canonical casing for info.ai_metadata.hierarchy_info.level: "Global", use "global"`,
+		"Hierarchy level inversion: the spec (Global) inherits from ../team.yaml, but Global is the broadest level",
+	} {
+		if !containsString(validator.Warnings, want) {
+			t.Errorf("missing %q in %v", want, validator.Warnings)
+		}
+	}
+}
+
+func TestStructuredAuthor(t *testing.T) {
+	for _, test := range []struct {
+		author   interface{}
+		errors   []string
+		warnings []string
+	}{
+		{author: "AI Team"},
+		{author: map[string]interface{}{"name": "AI Team", "email": "ai@example.com", "url": "https://example.com/ai"}},
+		{
+			author:   map[string]interface{}{"name": "AI Team", "email": "ai at example.com", "url": "ftp://example.com"},
+			warnings: []string{"info.author.email is not a valid email address: ai at example.com", "info.author.url is not a valid URL: ftp://example.com"},
+		},
+		{
+			author:   map[string]interface{}{"name": "AI Team", "email": "AI Team <ai@example.com>", "url": 42},
+			warnings: []string{"info.author.email is not a valid email address: AI Team <ai@example.com>", "info.author.url is not a valid URL: 42"},
+		},
+		{
+			author: map[string]interface{}{"name": " ", "email": "ai@example.com"},
+			errors: []string{"info.author object required field is empty: name"},
+		},
+		{
+			author: []interface{}{"AI Team"},
+			errors: []string{"info.author must be a string or an object"},
+		},
+	} {
+		info := map[string]interface{}{
+			"title": "Test", "version": "1.0.0", "description": "Test", "license": "MIT",
+			"author": test.author, "contact": map[string]interface{}{"email": "support@example.com"},
+		}
+		f := sectionFindings{}
+		NewAPAIValidator().validateInfo(&f, info)
+		if len(f.Errors) != len(test.errors) || len(f.Warnings) != len(test.warnings) {
+			t.Errorf("author %v: got errors %v and warnings %v, want %v and %v", test.author, f.Errors, f.Warnings, test.errors, test.warnings)
+			continue
+		}
+		for _, want := range test.errors {
+			if !containsString(f.Errors, want) {
+				t.Errorf("author %v: missing %q in %v", test.author, want, f.Errors)
+			}
+		}
+		for _, want := range test.warnings {
+			if !containsString(f.Warnings, want) {
+				t.Errorf("author %v: missing %q in %v", test.author, want, f.Warnings)
+			}
+		}
+	}
+}
+
+func TestInfoPeople(t *testing.T) {
+	noContact := "No email or url in info.author, info.contact or info.owners to reach the people responsible for the specification"
+	info := map[string]interface{}{
+		"author":  map[string]interface{}{"email": "team@example.com", "team": 7},
+		"contact": map[string]interface{}{"url": "example.com"},
+		"owners": []interface{}{
+			"Platform Team",
+			map[string]interface{}{"name": "Billing", "url": "https://billing.example.com"},
+			map[string]interface{}{"email": "ops@example.com"},
+			42,
+		},
+	}
+	validator := NewAPAIValidator()
+	validator.ValidateSpec(map[string]interface{}{"info": info})
+	for _, want := range []string{
+		"info.author object missing required field: name",
+		"info.author.team must be a string",
+		"info.owners[2] object missing required field: name",
+		"info.owners[3] must be a string or an object",
+	} {
+		if !containsString(validator.Errors, want) {
+			t.Errorf("missing %q in %v", want, validator.Errors)
+		}
+	}
+	if want := "info.contact.url is not a valid URL: example.com"; !containsString(validator.Warnings, want) {
+		t.Errorf("missing %q in %v", want, validator.Warnings)
+	}
+	if containsString(validator.Warnings, noContact) {
+		t.Errorf("unexpected %q", noContact)
+	}
+
+	// Names alone give no way to reach anyone, unless a string is an address
+	for _, contact := range []interface{}{"Support Team", map[string]interface{}{"name": "Support"}, "Support <support@example.com>", "https://example.com/support"} {
+		info := map[string]interface{}{"author": "AI Team", "contact": contact}
+		validator.ValidateSpec(map[string]interface{}{"info": info})
+		reachable := !containsString(validator.Warnings, noContact)
+		if want := isValidURL(fmt.Sprint(contact)) || strings.Contains(fmt.Sprint(contact), "@"); reachable != want {
+			t.Errorf("contact %v: reachable = %v, want %v", contact, reachable, want)
+		}
+		for _, message := range validator.Errors {
+			if strings.HasPrefix(message, "info.contact") {
+				t.Errorf("unexpected %q", message)
+			}
+		}
+	}
+}
+
+func TestDuplicateAndSelfInherits(t *testing.T) {
+	fsys := fstest.MapFS{
+		"base.yaml": {Data: []byte("apai: \"0.1.0\"\ninfo:\n  title: \"Base\"\n  version: \"1.0.0\"\n")},
+		"team.yaml": {Data: []byte("info:\n  title: \"Team\"\n")},
+		"app.yaml":  {Data: []byte("inherits: [\"base.yaml\", \"team.yaml\", \"./base.yaml\"]\n")},
+		"self.yaml": {Data: []byte("inherits: [\"self.yaml\"]\n")},
+	}
+	validator := NewAPAIValidator(WithFS(fsys))
+
+	merged, err := validator.ResolveSpecContext(context.Background(), "app.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Duplicate inherits entry in app.yaml: base.yaml (merged once, at its last position)"; !reflect.DeepEqual(validator.Warnings, []string{want}) {
+		t.Errorf("expected %q, got %v", want, validator.Warnings)
+	}
+	// The last occurrence of base.yaml takes precedence over team.yaml
+	if title := merged["info"].(map[string]interface{})["title"]; title != "Base" {
+		t.Errorf("expected the last base.yaml entry to win, got title %v", title)
+	}
+
+	validator.ResolveSpecContext(context.Background(), "self.yaml")
+	if want := "Specification inherits from itself: self.yaml"; !reflect.DeepEqual(validator.Errors, []string{want}) {
+		t.Errorf("expected %q, got %v", want, validator.Errors)
+	}
+}
+
+func TestTasksWithoutSteps(t *testing.T) {
+	var spec map[string]interface{}
+	err := decodeYAML([]byte(`
+tasks:
+  - id: "base_support"
+    description: "Template for support tasks"
+    abstract: true
+  - id: "draft"
+    description: "Not written yet"
+    steps: []
+  - id: "support"
+    description: "Answer customers"
+    steps:
+      - name: "handle"
+        action: "generate"
+        task: "base_support"
+      - name: "escalate"
+        action: "escalate"
+        task: "missing"
+`), &spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	validator := NewAPAIValidator()
+	validator.crossValidate(spec)
+	f := sectionFindings{}
+	validator.validateTasks(&f, spec["tasks"])
+
+	if want := []string{"Task draft has no steps; declare abstract: true if it is a template"}; !reflect.DeepEqual(f.Warnings, want) {
+		t.Errorf("expected %v, got %v", want, f.Warnings)
+	}
+	want := []string{
+		"Abstract task base_support cannot be run by tasks[2].steps[0]",
+		"Task references unknown task: missing",
+	}
+	if !reflect.DeepEqual(validator.Errors, want) {
+		t.Errorf("expected %v, got %v", want, validator.Errors)
+	}
+}
+
+func TestPromptChains(t *testing.T) {
+	var spec map[string]interface{}
+	err := decodeYAML([]byte(`
+prompts:
+  - id: "outline"
+    role: "user"
+    template: "Outline {{topic}}"
+    chain: ["draft", "review"]
+  - id: "draft"
+    role: "user"
+    template: "Draft the outline"
+    next: "polish"
+  - id: "review"
+    role: "user"
+    template: "Review the draft"
+    next: "draft"
+  - id: "polish"
+    role: "user"
+    template: "Polish the draft"
+    next: "review"
+  - id: "loop"
+    role: "user"
+    template: "Again"
+    next: "loop"
+`), &spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	validator := NewAPAIValidator()
+	validator.crossValidate(spec)
+	want := []string{
+		"Circular prompt chain: draft -> polish -> review -> draft",
+		"Circular prompt chain: loop -> loop",
+	}
+	if !reflect.DeepEqual(validator.Errors, want) {
+		t.Errorf("expected %v, got %v", want, validator.Errors)
+	}
+
+	spec["prompts"] = append(spec["prompts"].([]interface{}), map[string]interface{}{"id": "summary", "next": "missing"})
+	validator.Errors = nil
+	validator.crossValidate(spec)
+	if !containsString(validator.Errors, "Prompt summary references unknown prompt: missing") {
+		t.Errorf("expected the dangling reference to be reported, got %v", validator.Errors)
+	}
+}
+
+func TestNumericFieldsMustNotBeStrings(t *testing.T) {
+	var spec map[string]interface{}
+	err := decodeYAML([]byte(`
+models:
+  - id: "main"
+    parameters:
+      temperature: "0.7"
+      max_tokens: 2000
+      top_p: " 0.9 "
+    rate_limit:
+      requests_per_minute: "60/min"
+evaluation:
+  metrics:
+    - name: "accuracy"
+      threshold: "high"
+      warning_threshold: "0.8"
+context:
+  persistence:
+    ttl: "30d"
+  session:
+    ttl: 3600
+`), &spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	validator := NewAPAIValidator()
+	validator.validateNumericFields(spec, "")
+	want := []string{
+		`evaluation.metrics[0].threshold must be a number, got string "high"`,
+		`evaluation.metrics[0].warning_threshold must be a number, got string "0.8" (remove the quotes)`,
+		`models[0].parameters.temperature must be a number, got string "0.7" (remove the quotes)`,
+		`models[0].parameters.top_p must be a number, got string " 0.9 " (remove the quotes)`,
+	}
+	if !reflect.DeepEqual(validator.Errors, want) {
+		t.Errorf("expected %v, got %v", want, validator.Errors)
+	}
+	for _, message := range want {
+		if rule, _ := MatchRule(message); rule.Code != "NUMERIC_STRING" {
+			t.Errorf("expected NUMERIC_STRING for %q, got %q", message, rule.Code)
+		}
+	}
+
+	if message := numericStringError("models[0].rate_limit.requests_per_minute", "60/min"); message != `models[0].rate_limit.requests_per_minute must be a number, got string "60/min" (write 60, the rate per minute)` {
+		t.Errorf("unexpected rate suggestion: %s", message)
+	}
+}
+
+func TestBooleanFieldsMustNotBeStrings(t *testing.T) {
+	var spec map[string]interface{}
+	err := decodeYAML([]byte(`
+tasks:
+  - id: "base_support"
+    description: "Template for support tasks"
+    abstract: "yes"
+context:
+  persistence:
+    enabled: "false"
+    store: "memory"
+extensions:
+  vision_support: "true"
+  multilingual: true
+governance:
+  testing_required: "sometimes"
+`), &spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	validator := NewAPAIValidator()
+	validator.validateBooleanFields(spec, "")
+	want := []string{
+		`context.persistence.enabled must be a boolean, got string "false" (write false, without quotes)`,
+		`extensions.vision_support must be a boolean, got string "true" (write true, without quotes)`,
+		`governance.testing_required must be a boolean, got string "sometimes"`,
+		`tasks[0].abstract must be a boolean, got string "yes" (write true, without quotes)`,
+	}
+	if !reflect.DeepEqual(validator.Errors, want) {
+		t.Errorf("expected %v, got %v", want, validator.Errors)
+	}
+	for _, message := range want {
+		if rule, _ := MatchRule(message); rule.Code != "BOOLEAN_STRING" {
+			t.Errorf("expected BOOLEAN_STRING for %q, got %q", message, rule.Code)
+		}
+	}
+
+	// The section checks leave strings to the type strictness checks
+	f := sectionFindings{}
+	validator.validateTasks(&f, spec["tasks"])
+	if containsString(f.Errors, "Task 0 abstract must be a boolean") {
+		t.Errorf("string abstract reported twice: %v", f.Errors)
+	}
+}
+
+func TestStepFieldsMatchAction(t *testing.T) {
+	var tasks interface{}
+	err := decodeYAML([]byte(`
+- id: "lookup"
+  description: "Look up a customer"
+  steps:
+    - name: "find"
+      action: "mcp_tool"
+      mcp_server: "crm"
+      mcp_tool: "find_customer"
+      model: "main_model"
+    - name: "read"
+      action: "MCP_Resource"
+      mcp_server: "crm"
+      mcp_resource: "customers"
+      prompt: "system_prompt"
+    - name: "answer"
+      action: "generate"
+      model: "main_model"
+      mcp_tool: "find_customer"
+    - name: "guess"
+      action: "teleport"
+      mcp_server: "crm"
+`), &tasks)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := sectionFindings{}
+	NewAPAIValidator().validateTasks(&f, tasks)
+	want := []string{
+		"Task 0 step 0 mcp_tool action sets model, which MCP actions ignore",
+		"Task 0 step 1 mcp_resource action sets prompt, which MCP actions ignore",
+		"Task 0 step 2 generate action sets mcp_tool, which only MCP actions use",
+	}
+	for _, message := range want {
+		if !containsString(f.Warnings, message) {
+			t.Errorf("missing %q in %v", message, f.Warnings)
+		}
+		if rule, _ := MatchRule(message); rule.Code != "MISAPPLIED_STEP_FIELD" {
+			t.Errorf("expected MISAPPLIED_STEP_FIELD for %q, got %q", message, rule.Code)
+		}
+	}
+	for _, message := range f.Warnings {
+		if strings.Contains(message, "step 3") && strings.Contains(message, "action sets") {
+			t.Errorf("unexpected warning for an unknown action: %q", message)
+		}
+	}
+}
+
+func TestUnusedMCPServersWithoutTasks(t *testing.T) {
+	spec := loadExampleSpecs(t, "automation/mcp-integration.yaml")[0]
+	delete(spec, "tasks")
+
+	validator := NewAPAIValidator()
+	validator.ValidateSpec(spec)
+	for _, id := range []string{"customer-db-server", "knowledge-base-server", "filesystem-server"} {
+		want := fmt.Sprintf("MCP server '%s' is declared but never used", id)
+		if !containsString(validator.Warnings, want) {
+			t.Errorf("missing %q in %v", want, validator.Warnings)
+		}
+	}
+
+	validator = NewAPAIValidator()
+	validator.Config.WarnUnusedMCPServers = false
+	validator.ValidateSpec(spec)
+	for _, warning := range validator.Warnings {
+		if strings.HasSuffix(warning, "is declared but never used") {
+			t.Errorf("unused server reported with the check disabled: %s", warning)
+		}
+	}
+}
+
+// inheritanceChainFS holds app.yaml inheriting team.yaml, dept.yaml and
+// org.yaml in turn, and hub.yaml inheriting three parents directly
+func inheritanceChainFS() fstest.MapFS {
+	return fstest.MapFS{
+		"app.yaml":  {Data: []byte("inherits: [team.yaml]\ntasks: [{id: answer}]\n")},
+		"team.yaml": {Data: []byte("inherits: [dept.yaml]\nprompts: [{id: team_prompt}]\n")},
+		"dept.yaml": {Data: []byte("inherits: [org.yaml]\nconstraints: [{id: dept_rule}]\n")},
+		"org.yaml":  {Data: []byte("models: [{id: org_model}]\n")},
+		"hub.yaml":  {Data: []byte("inherits: [a.yaml, b.yaml, c.yaml]\n")},
+		"a.yaml":    {Data: []byte("prompts: [{id: a}]\n")},
+		"b.yaml":    {Data: []byte("constraints: [{id: b}]\n")},
+		"c.yaml":    {Data: []byte("models: [{id: c}]\n")},
+	}
+}
+
+func TestInheritanceDepthLimit(t *testing.T) {
+	cache, err := NewSpecCache(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	validator := NewAPAIValidator(WithFS(inheritanceChainFS()), WithSpecCache(cache), WithMaxInheritanceDepth(2))
+
+	if _, err := validator.ResolveSpecContext(context.Background(), "app.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	want := "Inheritance depth limit of 2 exceeded: app.yaml -> team.yaml -> dept.yaml -> org.yaml"
+	if !containsString(validator.Errors, want) {
+		t.Errorf("missing %q in %v", want, validator.Errors)
+	}
+
+	// Nothing of the partial hierarchy may be reused by the next run
+	if len(validator.inheritedSpecs) != 0 || len(validator.mergeCache) != 0 {
+		t.Errorf("failed run left %d loaded and %d merged specs", len(validator.inheritedSpecs), len(validator.mergeCache))
+	}
+	if stats, err := cache.Stats(); err != nil || stats.Entries[mergedCacheKind] != 0 {
+		t.Errorf("failed run stored merged cache entries: %+v %v", stats, err)
+	}
+
+	validator.Config.MaxInheritanceDepth = 3
+	merged, err := validator.ResolveSpecContext(context.Background(), "app.yaml")
+	if err != nil || len(validator.Errors) != 0 {
+		t.Fatalf("expected the chain to resolve within the limit, got %v %v", err, validator.Errors)
+	}
+	for _, section := range []string{"models", "prompts", "constraints", "tasks"} {
+		if _, exists := merged[section]; !exists {
+			t.Errorf("merged spec lacks %s inherited along the chain: %v", section, merged)
+		}
+	}
+	if stats, err := cache.Stats(); err != nil || stats.Entries[mergedCacheKind] == 0 {
+		t.Errorf("expected merged cache entries after a complete run: %+v %v", stats, err)
+	}
+}
+
+func TestInheritedSpecsLimit(t *testing.T) {
+	validator := NewAPAIValidator(WithFS(inheritanceChainFS()), WithMaxInheritedSpecs(2))
+
+	valid, err := validator.ValidateWithInheritance("hub.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Inherited specification limit of 2 exceeded: hub.yaml -> c.yaml"
+	if valid || !containsString(validator.Errors, want) {
+		t.Errorf("expected %q, got valid=%v %v", want, valid, validator.Errors)
+	}
+	if len(validator.inheritedSpecs) != 0 || len(validator.mergeCache) != 0 {
+		t.Errorf("failed run left %d loaded and %d merged specs", len(validator.inheritedSpecs), len(validator.mergeCache))
+	}
+
+	validator.Config.MaxInheritedSpecs = 3
+	merged, err := validator.ResolveSpecContext(context.Background(), "hub.yaml")
+	if err != nil || len(validator.Errors) != 0 {
+		t.Fatalf("expected the parents to resolve within the limit, got %v %v", err, validator.Errors)
+	}
+	// Each parent supplies its own section
+	for section, id := range map[string]string{"prompts": "a", "constraints": "b", "models": "c"} {
+		if elements, _ := merged[section].([]interface{}); len(elements) != 1 || elements[0].(map[string]interface{})["id"] != id {
+			t.Errorf("unexpected merged %s %v", section, merged[section])
+		}
+	}
+}
+
+func TestSpecRejection(t *testing.T) {
+	for _, test := range []struct {
+		spec map[string]interface{}
+		want string
+	}{
+		{map[string]interface{}{"apai": "0.1.0", "kind": "Agent"}, ""},
+		{map[string]interface{}{"models": []interface{}{}}, ""},
+		{map[string]interface{}{}, "empty document"},
+		{map[string]interface{}{"apiVersion": "v1", "models": []interface{}{}}, "no 'apai' key, and a top-level 'apiVersion' as in Kubernetes and other manifests"},
+		{map[string]interface{}{"kind": "ConfigMap"}, "no 'apai' key, and a top-level 'kind' as in Kubernetes and other manifests"},
+		{map[string]interface{}{"owner": "platform"}, "no 'apai' key or known section such as info, models or tasks"},
+	} {
+		if got := specRejection(test.spec); got != test.want {
+			t.Errorf("specRejection(%v) = %q, want %q", test.spec, got, test.want)
+		}
+		if looksLikeSpec(test.spec) != (test.want == "") {
+			t.Errorf("looksLikeSpec(%v) disagrees with %q", test.spec, test.want)
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// WorkspaceFile is the file listing the members of a workspace directory
+const WorkspaceFile = "workspace.yaml"
+
+// Workspace is a set of specifications, such as one per agent of a larger
+// system, whose steps run tasks and call MCP servers of the others with
+// references of the form spec-id#entity-id. Its index of members is built
+// once by LoadWorkspace and shared by every validation using it.
+type Workspace struct {
+	Members []WorkspaceMember
+	// Roots are the ids of entry points, which no other member needs to reference
+	Roots []string
+
+	index map[string]*workspaceSpec
+	paths map[string]string
+	// referenced holds the ids of members other members reference, and
+	// usedServers their MCP servers called by other members, as spec-id#server-id
+	referenced  map[string]bool
+	usedServers map[string]bool
+}
+
+// WorkspaceMember is a specification of a workspace; its id is info.id,
+// or the file name without extension when the spec has none
+type WorkspaceMember struct {
+	ID   string
+	Path string
+}
+
+// workspaceSpec is the index of a member: whether each of its tasks is
+// abstract, and its MCP servers
+type workspaceSpec struct {
+	// loaded is false when the file cannot be parsed; references into it
+	// are not checked, since its own validation reports the problem
+	loaded  bool
+	tasks   map[string]bool
+	servers map[string]bool
+}
+
+// workspaceManifest is the content of a workspace file
+type workspaceManifest struct {
+	// Members are spec files or glob patterns, relative to the workspace file
+	Members []string `yaml:"members"`
+	Roots   []string `yaml:"roots"`
+}
+
+// LoadWorkspace loads and indexes the workspace at path: a workspace file,
+// a directory containing one, or a directory whose specifications are all
+// members
+func LoadWorkspace(ctx context.Context, workspacePath string) (*Workspace, error) {
+	stat, err := os.Stat(workspacePath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read workspace: %v", err)
+	}
+	manifestPath := workspacePath
+	if stat.IsDir() {
+		manifestPath = filepath.Join(workspacePath, WorkspaceFile)
+	}
+
+	var files, roots []string
+	discovered := false
+	if _, err := os.Stat(manifestPath); err == nil {
+		files, roots, err = readWorkspaceManifest(manifestPath)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		files, err = SpecFiles(ctx, []string{workspacePath})
+		if err != nil {
+			return nil, err
+		}
+		discovered = true
+	}
+
+	workspace := &Workspace{
+		Members:     make([]WorkspaceMember, 0, len(files)),
+		Roots:       roots,
+		index:       make(map[string]*workspaceSpec),
+		paths:       make(map[string]string),
+		referenced:  make(map[string]bool),
+		usedServers: make(map[string]bool),
+	}
+	loader := NewAPAIValidator()
+	specs := make(map[string]map[string]interface{})
+	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("loading workspace: %w", err)
+		}
+		spec, err := loader.loadSpec(file)
+		// Discovered files that are not specifications are not members
+		if discovered && err == nil && !looksLikeSpec(spec) {
+			continue
+		}
+
+		id := workspaceSpecID(spec, file)
+		for _, member := range workspace.Members {
+			if member.ID == id {
+				return nil, fmt.Errorf("duplicate spec id %s in workspace: %s and %s", id, member.Path, file)
+			}
+		}
+		workspace.Members = append(workspace.Members, WorkspaceMember{ID: id, Path: file})
+		workspace.paths[absolutePath(file)] = id
+		workspace.index[id] = indexWorkspaceSpec(spec, err == nil)
+		if err == nil {
+			specs[id] = spec
+		}
+	}
+
+	for _, root := range roots {
+		if _, exists := workspace.index[root]; !exists {
+			return nil, fmt.Errorf("workspace root %s is not a member", root)
+		}
+	}
+	for id, spec := range specs {
+		workspaceReferences(spec, func(reference, field, _ string) {
+			if target, _, _ := strings.Cut(reference, "#"); target != id {
+				workspace.referenced[target] = true
+			}
+			if field == "mcp_server" {
+				workspace.usedServers[reference] = true
+			}
+		})
+	}
+	return workspace, nil
+}
+
+// readWorkspaceManifest returns the member files and roots listed by a
+// workspace file, expanding glob patterns
+func readWorkspaceManifest(manifestPath string) ([]string, []string, error) {
+	content, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot read workspace: %v", err)
+	}
+	var manifest workspaceManifest
+	if err := decodeYAML(content, &manifest); err != nil {
+		return nil, nil, fmt.Errorf("invalid workspace %s: %v", manifestPath, err)
+	}
+	if len(manifest.Members) == 0 {
+		return nil, nil, fmt.Errorf("invalid workspace %s: no members", manifestPath)
+	}
+
+	files := make([]string, 0, len(manifest.Members))
+	seen := make(map[string]bool)
+	for _, pattern := range manifest.Members {
+		matches, err := filepath.Glob(filepath.Join(filepath.Dir(manifestPath), pattern))
+		if err != nil || len(matches) == 0 {
+			return nil, nil, fmt.Errorf("invalid workspace %s: member %s matches no files", manifestPath, pattern)
+		}
+		for _, match := range matches {
+			if !seen[absolutePath(match)] {
+				seen[absolutePath(match)] = true
+				files = append(files, match)
+			}
+		}
+	}
+	return files, manifest.Roots, nil
+}
+
+// workspaceSpecID returns the id of a specification in a workspace
+func workspaceSpecID(spec map[string]interface{}, filePath string) string {
+	if info, ok := spec["info"].(map[string]interface{}); ok {
+		if id, ok := info["id"].(string); ok && id != "" {
+			return id
+		}
+	}
+	return strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath))
+}
+
+// indexWorkspaceSpec records the tasks and MCP servers a member declares
+func indexWorkspaceSpec(spec map[string]interface{}, loaded bool) *workspaceSpec {
+	indexed := &workspaceSpec{loaded: loaded, tasks: make(map[string]bool), servers: make(map[string]bool)}
+	if !loaded {
+		return indexed
+	}
+	spec, _ = resolveSpecRefs(spec)
+	objectsAt(spec, "tasks[]", "", func(task map[string]interface{}, _ string) {
+		if id, ok := task["id"].(string); ok {
+			abstract, _ := task["abstract"].(bool)
+			indexed.tasks[id] = abstract
+		}
+	})
+	objectsAt(spec, "context.mcp_servers[]", "", func(server map[string]interface{}, _ string) {
+		if id, ok := server["id"].(string); ok {
+			indexed.servers[id] = true
+		}
+	})
+	return indexed
+}
+
+// isWorkspaceReference reports whether a step reference names an entity
+// of another specification, as spec-id#entity-id
+func isWorkspaceReference(reference string) bool {
+	return strings.Contains(reference, "#")
+}
+
+// workspaceReferences calls visit with each reference of a step to a task
+// or MCP server of another specification, the field holding it and the
+// step's location
+func workspaceReferences(spec map[string]interface{}, visit func(reference, field, location string)) {
+	objectsAt(spec, "tasks[].steps[]", "", func(step map[string]interface{}, location string) {
+		for _, field := range []string{"task", "mcp_server"} {
+			if reference, ok := step[field].(string); ok && isWorkspaceReference(reference) {
+				visit(reference, field, location)
+			}
+		}
+	})
+}
+
+// memberID returns the id of the member stored in filePath, or "" when
+// the file is not a member
+func (w *Workspace) memberID(filePath string) string {
+	return w.paths[absolutePath(filePath)]
+}
+
+// serverUsedByWorkspace reports whether other members call an MCP server
+// of the member being validated
+func (v *APAIValidator) serverUsedByWorkspace(serverID string) bool {
+	return v.workspace != nil && v.workspaceMember != "" && v.workspace.usedServers[v.workspaceMember+"#"+serverID]
+}
+
+// validateWorkspaceReferences checks the references of a specification to
+// other workspace members, and warns when the member being validated is
+// referenced by no other member
+func (v *APAIValidator) validateWorkspaceReferences(spec map[string]interface{}) {
+	workspaceReferences(spec, func(reference, field, location string) {
+		specID, entityID, _ := strings.Cut(reference, "#")
+		target, exists := v.workspace.index[specID]
+		switch {
+		case !exists:
+			v.addError("UNKNOWN_REFERENCE", fmt.Sprintf("Task references unknown spec: %s (%s)", specID, reference))
+		case !target.loaded:
+			// Its own validation reports why it cannot be loaded
+		case field == "mcp_server":
+			if !target.servers[entityID] {
+				v.addError("UNKNOWN_REFERENCE", fmt.Sprintf("Task references unknown MCP server: %s", reference))
+			}
+		default:
+			if abstract, exists := target.tasks[entityID]; !exists {
+				v.addError("UNKNOWN_REFERENCE", fmt.Sprintf("Task references unknown task: %s", reference))
+			} else if abstract {
+				v.addError("ABSTRACT_TASK_RUN", fmt.Sprintf("Abstract task %s cannot be run by %s", reference, location))
+			}
+		}
+	})
+
+	member := v.workspaceMember
+	if member != "" && !v.workspace.referenced[member] && !containsString(v.workspace.Roots, member) {
+		v.addWarning("UNREFERENCED_SPEC", fmt.Sprintf("Spec %s is not referenced by any other spec in the workspace", member))
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWorkspaceReferences(t *testing.T) {
+	workspace, err := LoadWorkspace(context.Background(), "testdata/workspace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(workspace.Members) != 2 || workspace.Members[0].ID != "billing" || workspace.Members[1].ID != "triage" {
+		t.Fatalf("expected members billing and triage, got %+v", workspace.Members)
+	}
+
+	validator := NewAPAIValidator(WithWorkspace(workspace))
+	for _, member := range workspace.Members {
+		if valid, err := validator.ValidateFile(member.Path); err != nil || !valid {
+			t.Errorf("expected %s to be valid, got %v %v", member.Path, err, validator.Errors)
+		}
+		// The payments server is called by triage, and triage is a root
+		if len(validator.Warnings) > 0 {
+			t.Errorf("unexpected warnings for %s: %v", member.Path, validator.Warnings)
+		}
+	}
+
+	// Outside the workspace, references to other specs are left unchecked
+	validator = NewAPAIValidator()
+	if valid, err := validator.ValidateFile("testdata/workspace/agents/triage.yaml"); err != nil || !valid {
+		t.Errorf("expected triage to be valid on its own, got %v %v", err, validator.Errors)
+	}
+}
+
+func TestWorkspaceReferenceErrors(t *testing.T) {
+	dir := t.TempDir()
+	copyWorkspaceSpec(t, "billing.yaml", filepath.Join(dir, "billing.yaml"), "\n    description: \"Handle", "\n    abstract: true\n    description: \"Handle")
+	copyWorkspaceSpec(t, "triage.yaml", filepath.Join(dir, "triage.yaml"), "billing#payments", "crm#payments")
+	copyWorkspaceSpec(t, "triage.yaml", filepath.Join(dir, "helpdesk.yaml"), "id: \"triage\"", "id: \"helpdesk\"", "billing#escalate", "billing#refund")
+	if err := os.WriteFile(filepath.Join(dir, "notes.yaml"), []byte("owner: support\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	workspace, err := LoadWorkspace(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(workspace.Members) != 3 {
+		t.Fatalf("expected the three specifications as members, got %+v", workspace.Members)
+	}
+
+	validator := NewAPAIValidator(WithWorkspace(workspace))
+	expectations := map[string][]string{
+		"triage.yaml": {
+			"Abstract task billing#escalate cannot be run by tasks[0].steps[1]",
+			"Task references unknown spec: crm (crm#payments)",
+			"Spec triage is not referenced by any other spec in the workspace",
+		},
+		"helpdesk.yaml": {
+			"Task references unknown task: billing#refund",
+			"Spec helpdesk is not referenced by any other spec in the workspace",
+		},
+	}
+	codes := []string{"ABSTRACT_TASK_RUN", "UNKNOWN_REFERENCE", "UNREFERENCED_SPEC"}
+	for file, want := range expectations {
+		if _, err := validator.ValidateFile(filepath.Join(dir, file)); err != nil {
+			t.Fatal(err)
+		}
+		findings := append(validator.GetErrors(), validator.GetWarnings()...)
+		for _, message := range want {
+			if !containsString(findings, message) {
+				t.Errorf("expected %q for %s in %v", message, file, findings)
+			}
+			if rule, _ := MatchRule(message); !containsString(codes, rule.Code) {
+				t.Errorf("unexpected rule %q for %q", rule.Code, message)
+			}
+		}
+	}
+}
+
+func TestWorkspaceDuplicateIDs(t *testing.T) {
+	dir := t.TempDir()
+	copyWorkspaceSpec(t, "billing.yaml", filepath.Join(dir, "billing.yaml"))
+	copyWorkspaceSpec(t, "billing.yaml", filepath.Join(dir, "billing-v2.yaml"))
+	if _, err := LoadWorkspace(context.Background(), dir); err == nil || !strings.Contains(err.Error(), "duplicate spec id billing") {
+		t.Errorf("expected a duplicate id error, got %v", err)
+	}
+}
+
+// copyWorkspaceSpec copies a specification of testdata/workspace/agents,
+// applying old, new replacement pairs
+func copyWorkspaceSpec(t *testing.T, name, destination string, replacements ...string) {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join("testdata/workspace/agents", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := strings.NewReplacer(replacements...).Replace(string(content))
+	if err := os.WriteFile(destination, []byte(text), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}